@@ -0,0 +1,100 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadMaxBodySize(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.App.MaxBodySize != 10*1024*1024 {
+		t.Errorf("default max body size = %d, want 10MB", cfg.App.MaxBodySize)
+	}
+
+	t.Setenv("APP_MAX_BODY_SIZE", "2048")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.App.MaxBodySize != 2048 {
+		t.Errorf("max body size = %d, want 2048 from APP_MAX_BODY_SIZE", cfg.App.MaxBodySize)
+	}
+}
+
+func TestLoadAITemperature(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.AI.Temperature == nil || *cfg.AI.Temperature != 0.7 {
+		t.Errorf("default temperature = %v, want 0.7", cfg.AI.Temperature)
+	}
+
+	t.Setenv("AI_TEMPERATURE", "0")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.AI.Temperature == nil || *cfg.AI.Temperature != 0 {
+		t.Errorf("temperature = %v, want 0 from AI_TEMPERATURE", cfg.AI.Temperature)
+	}
+}
+
+func TestLoadAIInsightSettings(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.AI.InsightCount != 6 || cfg.AI.TrendMonths != 3 || cfg.AI.RecentTransactions != 5 {
+		t.Errorf("defaults = %d insights, %d months, %d transactions", cfg.AI.InsightCount, cfg.AI.TrendMonths, cfg.AI.RecentTransactions)
+	}
+
+	t.Setenv("AI_INSIGHT_COUNT", "4")
+	t.Setenv("AI_TREND_MONTHS", "6")
+	t.Setenv("AI_RECENT_TRANSACTIONS", "10")
+	if cfg, _ = Load(); cfg.AI.InsightCount != 4 || cfg.AI.TrendMonths != 6 || cfg.AI.RecentTransactions != 10 {
+		t.Errorf("from the environment = %d insights, %d months, %d transactions", cfg.AI.InsightCount, cfg.AI.TrendMonths, cfg.AI.RecentTransactions)
+	}
+}
+
+func TestLoadDatabaseDSN(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Database.DSN != DefaultDatabaseDSN {
+		t.Errorf("default DSN = %q, want the development default", cfg.Database.DSN)
+	}
+
+	t.Setenv("DB_DSN", "postgres://legacy@db:5432/legacy")
+	if cfg, _ = Load(); cfg.Database.DSN != "postgres://legacy@db:5432/legacy" {
+		t.Errorf("DSN = %q, want DB_DSN", cfg.Database.DSN)
+	}
+
+	t.Setenv("DATABASE_DSN", "postgres://app@db:5432/expenses")
+	if cfg, _ = Load(); cfg.Database.DSN != "postgres://app@db:5432/expenses" {
+		t.Errorf("DSN = %q, want DATABASE_DSN to win", cfg.Database.DSN)
+	}
+}
+
+func TestLoadRateLimit(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.RateLimit.RPS != 200 || cfg.RateLimit.Burst != 500 || cfg.RateLimit.AuthBurst != 10 || cfg.RateLimit.IdleTTL != 10*time.Minute || cfg.RateLimit.MaxClients != 10000 {
+		t.Errorf("defaults = %+v", cfg.RateLimit)
+	}
+	if cfg.RateLimit.AuthRPS >= cfg.RateLimit.RPS {
+		t.Errorf("auth rate %v isn't stricter than %v", cfg.RateLimit.AuthRPS, cfg.RateLimit.RPS)
+	}
+
+	t.Setenv("RATE_LIMIT_ALLOWLIST", "10.0.0.0/8,127.0.0.1")
+	t.Setenv("RATE_LIMIT_AUTH_BURST", "3")
+	if cfg, _ = Load(); len(cfg.RateLimit.Allowlist) != 2 || cfg.RateLimit.Allowlist[1] != "127.0.0.1" || cfg.RateLimit.AuthBurst != 3 {
+		t.Errorf("from the environment = %+v", cfg.RateLimit)
+	}
+}
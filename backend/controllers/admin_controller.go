@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/services"
+	"github.com/your-github/expense-tracker-backend/utils"
+	"gorm.io/gorm"
+)
+
+// AdminController serves operational endpoints under /api/admin
+type AdminController struct {
+	Expenses  *services.ExpenseService
+	Caches    map[string]*utils.LRUCache
+	DB        *gorm.DB  // optional; its pool stats are included in diagnostics
+	StartedAt time.Time // optional; reported as uptime in diagnostics
+}
+
+// Diagnostics reports cache statistics, Go runtime figures and the
+// database connection pool state for debugging a running instance
+func (c *AdminController) Diagnostics(ctx *gin.Context) {
+	caches := gin.H{}
+	for name, cache := range c.Caches {
+		caches[name] = cache.GetStats()
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	runtimeStats := gin.H{
+		"goroutines":     runtime.NumGoroutine(),
+		"go_version":     runtime.Version(),
+		"num_cpu":        runtime.NumCPU(),
+		"heap_alloc":     mem.HeapAlloc,
+		"heap_objects":   mem.HeapObjects,
+		"sys":            mem.Sys,
+		"num_gc":         mem.NumGC,
+		"pause_total_ns": mem.PauseTotalNs,
+	}
+	if !c.StartedAt.IsZero() {
+		runtimeStats["uptime"] = time.Since(c.StartedAt).Round(time.Second).String()
+	}
+
+	response := gin.H{"caches": caches, "runtime": runtimeStats}
+	if c.DB != nil {
+		sqlDB, err := c.DB.DB()
+		if err != nil {
+			response["database"] = gin.H{"error": err.Error()}
+		} else {
+			stats := sqlDB.Stats()
+			response["database"] = gin.H{
+				"max_open_connections": stats.MaxOpenConnections,
+				"open_connections":     stats.OpenConnections,
+				"in_use":               stats.InUse,
+				"idle":                 stats.Idle,
+				"wait_count":           stats.WaitCount,
+				"wait_duration":        stats.WaitDuration.String(),
+				"max_idle_closed":      stats.MaxIdleClosed,
+				"max_idle_time_closed": stats.MaxIdleTimeClosed,
+				"max_lifetime_closed":  stats.MaxLifetimeClosed,
+			}
+		}
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// CacheStats reports size and hit/miss statistics for each in-memory cache
+func (c *AdminController) CacheStats(ctx *gin.Context) {
+	stats := gin.H{}
+	for name, cache := range c.Caches {
+		stats[name] = cache.GetStats()
+	}
+	ctx.JSON(http.StatusOK, gin.H{"caches": stats})
+}
+
+// ResetCacheStats zeroes the hit/miss counters of every cache, or only the
+// one named by ?cache=
+func (c *AdminController) ResetCacheStats(ctx *gin.Context) {
+	if name := ctx.Query("cache"); name != "" {
+		cache, ok := c.Caches[name]
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Unknown cache"})
+			return
+		}
+		cache.ResetStats()
+		ctx.JSON(http.StatusOK, gin.H{"message": "Cache stats reset", "caches": []string{name}})
+		return
+	}
+
+	names := make([]string, 0, len(c.Caches))
+	for name, cache := range c.Caches {
+		cache.ResetStats()
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Cache stats reset", "caches": names})
+}
+
+// MigrateExpenses mirrors expenses into transaction records. With
+// ?user_id= only that user is migrated; otherwise every user is, one at a
+// time. Re-running is safe: already-mirrored expenses are skipped.
+func (c *AdminController) MigrateExpenses(ctx *gin.Context) {
+	if userIDStr := ctx.Query("user_id"); userIDStr != "" {
+		userID, err := strconv.ParseUint(userIDStr, 10, 32)
+		if err != nil || userID == 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+			return
+		}
+		result, err := c.Expenses.MigrateUserExpensesToTransactions(ctx.Request.Context(), uint(userID))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "result": result})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"message": "Migration completed successfully", "result": result})
+		return
+	}
+
+	result, err := c.Expenses.MigrateAllExpensesToTransactions(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "result": result})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Migration completed successfully", "result": result})
+}
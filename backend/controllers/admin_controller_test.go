@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+	"github.com/your-github/expense-tracker-backend/middleware"
+	"github.com/your-github/expense-tracker-backend/services"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+func TestMigrateExpensesScopesToTheGivenUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, stub := testutil.NewStubDB(t)
+	expenses := services.NewExpenseService(db, 1)
+	t.Cleanup(expenses.Close)
+	r := gin.New()
+	r.POST("/api/admin/migrate-expenses", (&AdminController{Expenses: expenses}).MigrateExpenses)
+
+	for _, userID := range []string{"abc", "0", "-3"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/admin/migrate-expenses?user_id="+userID, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("user_id=%s = %d, want 400", userID, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/admin/migrate-expenses?user_id=8", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"users":1`) {
+		t.Errorf("user 8 = %d %s", w.Code, w.Body)
+	}
+	if len(stub.Ran(`SELECT DISTINCT`)) != 0 {
+		t.Error("a single-user migration listed every user")
+	}
+	if pages := stub.Ran(`FROM "expenses"`); len(pages) != 1 || pages[0].Args[0] != uint(8) {
+		t.Errorf("expense pages = %+v, want one read of user 8's", pages)
+	}
+}
+
+func TestCacheStatsEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	expenses := utils.NewLRUCache(10, time.Minute)
+	summary := utils.NewLRUCache(10, time.Minute)
+	expenses.Set("a", 1)
+	expenses.Get("a")
+	summary.Get("missing")
+	admin := &AdminController{Caches: map[string]*utils.LRUCache{"expenses": expenses, "summary": summary}}
+	r := gin.New()
+	r.GET("/api/admin/cache-stats", admin.CacheStats)
+	r.POST("/api/admin/cache-stats/reset", admin.ResetCacheStats)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/cache-stats", nil))
+	var body struct {
+		Caches map[string]struct {
+			Hits     uint64  `json:"hits"`
+			Misses   uint64  `json:"misses"`
+			HitRatio float64 `json:"hit_ratio"`
+		} `json:"caches"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("cache-stats = %d %s", w.Code, w.Body)
+	}
+	if got := body.Caches["expenses"]; got.Hits != 1 || got.HitRatio != 1 {
+		t.Errorf("expenses stats = %+v", got)
+	}
+	if got := body.Caches["summary"]; got.Misses != 1 || got.HitRatio != 0 {
+		t.Errorf("summary stats = %+v", got)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/admin/cache-stats/reset?cache=nope", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown cache reset = %d, want 404", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/admin/cache-stats/reset?cache=summary", nil))
+	if w.Code != http.StatusOK || summary.GetStats()["misses"] != uint64(0) || expenses.GetStats()["hits"] != uint64(1) {
+		t.Errorf("resetting summary = %d, summary %v, expenses %v", w.Code, summary.GetStats(), expenses.GetStats())
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/admin/cache-stats/reset", nil))
+	if !strings.Contains(w.Body.String(), `"caches":["expenses","summary"]`) || expenses.GetStats()["hits"] != uint64(0) {
+		t.Errorf("resetting all = %s", w.Body)
+	}
+}
+
+func TestDiagnosticsReportsCachesRuntimeAndPool(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _ := testutil.NewStubDB(t)
+	cache := utils.NewLRUCache(10, time.Minute)
+	cache.Set("a", 1)
+	admin := &AdminController{Caches: map[string]*utils.LRUCache{"expenses": cache}, DB: db, StartedAt: time.Now().Add(-time.Minute)}
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set(middleware.ContextRole, c.GetHeader("X-Test-Role")) })
+	r.GET("/api/admin/diagnostics", middleware.RequireRole(utils.RoleAdmin), admin.Diagnostics)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/diagnostics", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("non-admin = %d, want 403", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/diagnostics", nil)
+	req.Header.Set("X-Test-Role", utils.RoleAdmin)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var body struct {
+		Caches map[string]struct {
+			Size     int     `json:"size"`
+			Capacity int     `json:"capacity"`
+			HitRatio float64 `json:"hit_ratio"`
+		} `json:"caches"`
+		Runtime  map[string]interface{} `json:"runtime"`
+		Database map[string]interface{} `json:"database"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("diagnostics = %d %s", w.Code, w.Body)
+	}
+	if got, ok := body.Caches["expenses"]; !ok || got.Size != 1 || got.Capacity != 10 {
+		t.Errorf("expenses cache = %+v, want size 1 of 10", got)
+	}
+	for _, key := range []string{"goroutines", "go_version", "heap_alloc", "num_gc", "uptime"} {
+		if _, ok := body.Runtime[key]; !ok {
+			t.Errorf("runtime is missing %q: %v", key, body.Runtime)
+		}
+	}
+	if goroutines, _ := body.Runtime["goroutines"].(float64); goroutines < 1 {
+		t.Errorf("goroutines = %v", body.Runtime["goroutines"])
+	}
+	for _, key := range []string{"max_open_connections", "open_connections", "in_use", "idle", "wait_count"} {
+		if _, ok := body.Database[key]; !ok {
+			t.Errorf("database is missing %q: %v", key, body.Database)
+		}
+	}
+}
@@ -1,69 +1,150 @@
 package controllers
 
 import (
-	"fmt"
+	"errors"
+	"log"
 	"net/http"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/config"
 	"github.com/your-github/expense-tracker-backend/database"
 	"github.com/your-github/expense-tracker-backend/models"
+	"github.com/your-github/expense-tracker-backend/services"
 	"github.com/your-github/expense-tracker-backend/utils"
 )
 
-type AIController struct{}
+type AIController struct {
+	Config       *config.Config
+	Rates        utils.RateProvider
+	Insights     *services.InsightsService
+	Transactions *services.TransactionService // optional; source of recurring charges
+}
+
+// noDataInsights is returned when the user has no expenses to analyze
+var noDataInsights = []gin.H{
+	{
+		"type":        "info",
+		"title":       "No Data Available",
+		"description": "Add some transactions to get AI-powered insights.",
+		"suggestion":  "Start by adding your income and expenses to receive personalized financial advice.",
+	},
+}
 
-// GetAIInsights generates AI-powered insights using OpenAI GPT-3.5
+// GetAIInsights generates AI-powered insights using the configured OpenAI
+// model. ?count= overrides the configured number of insights.
 func (c *AIController) GetAIInsights(ctx *gin.Context) {
 	userID := ctx.GetUint("userID")
 
-	// Get user's expenses and income
-	var expenses []models.Expense
-	database.DB.Where("user_id = ?", userID).Order("date desc, created_at desc").Find(&expenses)
+	aiConfig := c.aiConfig()
+	if countParam := ctx.Query("count"); countParam != "" {
+		count, err := strconv.Atoi(countParam)
+		if err != nil || !utils.ValidInsightCount(count) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": utils.ErrInvalidInsightCount.Error()})
+			return
+		}
+		aiConfig.InsightCount = count
+	}
 
-	if len(expenses) == 0 {
-		ctx.JSON(http.StatusOK, gin.H{
-			"insights": []gin.H{
-				{
-					"type":        "info",
-					"title":       "No Data Available",
-					"description": "Add some transactions to get AI-powered insights.",
-					"suggestion":  "Start by adding your income and expenses to receive personalized financial advice.",
-				},
-			},
-		})
+	financialData, ok := c.financialData(ctx, userID)
+	if !ok {
+		ctx.JSON(http.StatusOK, gin.H{"insights": noDataInsights})
 		return
 	}
 
-	// Calculate financial data
-	financialData := c.calculateFinancialData(expenses)
-
 	// Generate AI insights
-	insights, err := utils.GenerateAIInsights(financialData)
+	insights, err := utils.GenerateAIInsights(ctx.Request.Context(), aiConfig, financialData)
 	if err != nil {
-		// If AI fails, return fallback insights
+		// If AI fails, return the locally generated insights. Provider errors
+		// can carry request details, so the client only gets a stable code.
+		log.Printf("AI insights for user %d failed: %v", userID, err)
 		ctx.JSON(http.StatusOK, gin.H{
-			"insights": c.generateFallbackInsights(financialData),
-			"ai_error": err.Error(),
+			"insights": insightsResponse(c.Insights.Generate(financialData)),
+			"ai_error": aiErrorCode(err),
 		})
 		return
 	}
 
-	// Convert insights to the expected format
-	var responseInsights []gin.H
+	ctx.JSON(http.StatusOK, gin.H{
+		"insights":     insightsResponse(insights),
+		"ai_generated": true,
+	})
+}
+
+// GetLocalInsights returns insights from the built-in rules only, without
+// calling an AI provider
+func (c *AIController) GetLocalInsights(ctx *gin.Context) {
+	financialData, ok := c.financialData(ctx, ctx.GetUint("userID"))
+	if !ok {
+		ctx.JSON(http.StatusOK, gin.H{"insights": noDataInsights})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"insights":     insightsResponse(c.Insights.Generate(financialData)),
+		"ai_generated": false,
+	})
+}
+
+// financialData gathers the user's financial data in their display
+// currency; ok is false when the user has no expenses
+func (c *AIController) financialData(ctx *gin.Context, userID uint) (utils.FinancialData, bool) {
+	var expenses []models.Expense
+	database.DB.Where("user_id = ?", userID).Order("date desc, created_at desc").Find(&expenses)
+	if len(expenses) == 0 {
+		return utils.FinancialData{}, false
+	}
+
+	currency := c.convertToDisplayCurrency(userID, expenses)
+	financialData := c.calculateFinancialData(expenses)
+	financialData.Currency = currency
+
+	// Recurring charges are an optional extra; insights go ahead without them
+	if c.Transactions != nil {
+		if recurring, err := c.Transactions.DetectRecurring(ctx.Request.Context(), userID); err == nil {
+			for _, charge := range recurring {
+				financialData.RecurringTotal += charge.AverageAmount
+			}
+			financialData.RecurringCount = len(recurring)
+		}
+	}
+	return financialData, true
+}
+
+// insightsResponse converts insights to the response format
+func insightsResponse(insights []utils.Insight) []gin.H {
+	response := make([]gin.H, 0, len(insights))
 	for _, insight := range insights {
-		responseInsights = append(responseInsights, gin.H{
+		response = append(response, gin.H{
 			"type":        insight.Type,
 			"title":       insight.Title,
 			"description": insight.Description,
 			"suggestion":  insight.Suggestion,
 		})
 	}
+	return response
+}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"insights":     responseInsights,
-		"ai_generated": true,
-	})
+// convertToDisplayCurrency rewrites expense amounts in place into the user's
+// preferred currency and returns that currency. Amounts that cannot be
+// converted are left as-is.
+func (c *AIController) convertToDisplayCurrency(userID uint, expenses []models.Expense) string {
+	var user models.User
+	database.DB.Select("currency").First(&user, userID)
+	display := utils.NormalizeCurrency(user.Currency)
+	if c.Rates == nil {
+		return display
+	}
+
+	for i := range expenses {
+		if converted, err := utils.ConvertAmount(c.Rates, expenses[i].Amount, expenses[i].Currency, display); err == nil {
+			expenses[i].Amount = converted
+			expenses[i].Currency = display
+		}
+	}
+	return display
 }
 
 // calculateFinancialData prepares financial data for AI analysis
@@ -71,6 +152,7 @@ func (c *AIController) calculateFinancialData(expenses []models.Expense) utils.F
 	var totalIncome, totalExpenses float64
 	categorySpending := make(map[string]float64)
 	monthlyData := make(map[string]utils.MonthlyData)
+	monthlyCategorySpending := make(map[string]map[string]float64)
 
 	// Calculate totals and category spending
 	for _, expense := range expenses {
@@ -85,6 +167,13 @@ func (c *AIController) calculateFinancialData(expenses []models.Expense) utils.F
 		date, _ := time.Parse("2006-01-02", expense.Date)
 		monthKey := date.Format("2006-01")
 
+		if expense.Type != "income" {
+			if monthlyCategorySpending[monthKey] == nil {
+				monthlyCategorySpending[monthKey] = make(map[string]float64)
+			}
+			monthlyCategorySpending[monthKey][expense.Category] += expense.Amount
+		}
+
 		if monthData, exists := monthlyData[monthKey]; exists {
 			if expense.Type == "income" {
 				monthData.Income += expense.Amount
@@ -108,21 +197,23 @@ func (c *AIController) calculateFinancialData(expenses []models.Expense) utils.F
 	}
 
 	// Calculate savings rate
-	savingsRate := 0.0
-	if totalIncome > 0 {
-		savingsRate = ((totalIncome - totalExpenses) / totalIncome) * 100
-	}
+	savingsRate := utils.SavingsRate(totalIncome, totalExpenses)
 
-	// Convert monthly data to slice
+	// Convert monthly data to slice, newest month first
+	monthKeys := make([]string, 0, len(monthlyData))
+	for monthKey := range monthlyData {
+		monthKeys = append(monthKeys, monthKey)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(monthKeys)))
 	var monthlyTrends []utils.MonthlyData
-	for _, monthData := range monthlyData {
-		monthlyTrends = append(monthlyTrends, monthData)
+	for _, monthKey := range monthKeys {
+		monthlyTrends = append(monthlyTrends, monthlyData[monthKey])
 	}
 
-	// Get recent transactions (last 5)
+	// Get recent transactions; the prompt keeps the configured number of them
 	var recentTransactions []utils.Transaction
 	for i, expense := range expenses {
-		if i >= 5 {
+		if i >= utils.MaxAIRecentTransactions {
 			break
 		}
 		recentTransactions = append(recentTransactions, utils.Transaction{
@@ -134,71 +225,88 @@ func (c *AIController) calculateFinancialData(expenses []models.Expense) utils.F
 		})
 	}
 
-	return utils.FinancialData{
+	data := utils.FinancialData{
 		TotalIncome:        totalIncome,
 		TotalExpenses:      totalExpenses,
 		SavingsRate:        savingsRate,
 		CategorySpending:   categorySpending,
 		MonthlyTrends:      monthlyTrends,
 		RecentTransactions: recentTransactions,
+		Anomalies:          utils.DetectSpendingAnomalies(monthlyCategorySpending, time.Now(), c.anomalyThreshold()),
 	}
+	data.WeekdayAverage, data.WeekendAverage = weekdayAverages(expenses)
+	return data
 }
 
-// generateFallbackInsights creates basic insights when AI is not available
-func (c *AIController) generateFallbackInsights(data utils.FinancialData) []gin.H {
-	var insights []gin.H
-
-	// Savings rate insight
-	if data.SavingsRate >= 20 {
-		insights = append(insights, gin.H{
-			"type":        "success",
-			"title":       "Excellent Savings Rate",
-			"description": "Your savings rate is " + fmt.Sprintf("%.1f", data.SavingsRate) + "%.",
-			"suggestion":  "Keep up the great work! Consider investing your savings for long-term growth.",
-		})
-	} else {
-		insights = append(insights, gin.H{
-			"type":        "warning",
-			"title":       "Savings Rate Alert",
-			"description": "Your savings rate is " + fmt.Sprintf("%.1f", data.SavingsRate) + "%.",
-			"suggestion":  "Consider reducing expenses or increasing income to improve your savings rate.",
-		})
+// weekdayAverages returns the average expense spend per weekday and per
+// weekend day over every calendar day from the first to the last expense
+func weekdayAverages(expenses []models.Expense) (weekday, weekend float64) {
+	var first, last time.Time
+	var weekdaySpend, weekendSpend float64
+	for _, expense := range expenses {
+		date, err := time.Parse("2006-01-02", expense.Date)
+		if err != nil || expense.Type == "income" {
+			continue
+		}
+		if first.IsZero() || date.Before(first) {
+			first = date
+		}
+		if date.After(last) {
+			last = date
+		}
+		if isWeekend(date) {
+			weekendSpend += expense.Amount
+		} else {
+			weekdaySpend += expense.Amount
+		}
+	}
+	if first.IsZero() {
+		return 0, 0
 	}
 
-	// Category spending insight
-	if len(data.CategorySpending) > 0 {
-		var topCategory string
-		var topAmount float64
-		for category, amount := range data.CategorySpending {
-			if amount > topAmount {
-				topAmount = amount
-				topCategory = category
-			}
+	var weekdays, weekends int
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		if isWeekend(d) {
+			weekends++
+		} else {
+			weekdays++
 		}
+	}
+	if weekdays > 0 {
+		weekday = weekdaySpend / float64(weekdays)
+	}
+	if weekends > 0 {
+		weekend = weekendSpend / float64(weekends)
+	}
+	return weekday, weekend
+}
 
-		percentage := (topAmount / data.TotalExpenses) * 100
-		if percentage > 40 {
-			insights = append(insights, gin.H{
-				"type":        "warning",
-				"title":       "High Category Concentration",
-				"description": topCategory + " accounts for " + fmt.Sprintf("%.1f", percentage) + "% of your spending.",
-				"suggestion":  "Consider diversifying your spending across different categories.",
-			})
-		}
+func isWeekend(date time.Time) bool {
+	return date.Weekday() == time.Saturday || date.Weekday() == time.Sunday
+}
+
+// aiErrorCode classifies AI failures so the frontend can explain the
+// fallback: "rate_limited" when the provider throttled us, "upstream_error"
+// for anything else
+func aiErrorCode(err error) string {
+	if errors.Is(err, utils.ErrAIRateLimited) {
+		return "rate_limited"
 	}
+	return "upstream_error"
+}
 
-	// Basic financial health insight
-	if data.TotalIncome > 0 {
-		expenseRatio := (data.TotalExpenses / data.TotalIncome) * 100
-		if expenseRatio < 80 {
-			insights = append(insights, gin.H{
-				"type":        "success",
-				"title":       "Good Financial Health",
-				"description": "Your expenses are " + fmt.Sprintf("%.1f", expenseRatio) + "% of your income.",
-				"suggestion":  "You have a healthy balance between income and expenses.",
-			})
-		}
+// aiConfig returns the configured AI settings; unset fields fall back to defaults
+func (c *AIController) aiConfig() config.AIConfig {
+	if c.Config == nil {
+		return config.AIConfig{}
 	}
+	return c.Config.AI
+}
 
-	return insights
+// anomalyThreshold returns the configured anomaly percentage, or the default
+func (c *AIController) anomalyThreshold() float64 {
+	if c.Config == nil || c.Config.AI.AnomalyThreshold <= 0 {
+		return utils.DefaultAnomalyThreshold
+	}
+	return c.Config.AI.AnomalyThreshold
 }
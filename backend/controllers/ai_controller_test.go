@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/your-github/expense-tracker-backend/models"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+func TestAIInsightsRejectsCountOutOfRange(t *testing.T) {
+	controller := &AIController{}
+	for _, count := range []string{"0", "13", "-1", "six"} {
+		w := getAsUser(controller.GetAIInsights, "/api/transactions?count="+count)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "between 1 and 12") {
+			t.Errorf("count=%s = %d %s, want 400", count, w.Code, w.Body)
+		}
+	}
+}
+
+func TestWeekdayAverages(t *testing.T) {
+	// Monday 2025-03-03 to Sunday 2025-03-09: five weekdays and a weekend
+	expenses := []models.Expense{
+		{Date: "2025-03-03", Type: "expense", Amount: 300},
+		{Date: "2025-03-07", Type: "expense", Amount: 200},
+		{Date: "2025-03-08", Type: "expense", Amount: 500},
+		{Date: "2025-03-09", Type: "expense", Amount: 300},
+		{Date: "2025-03-05", Type: "income", Amount: 50000},
+		{Date: "not a date", Type: "expense", Amount: 999},
+	}
+	weekday, weekend := weekdayAverages(expenses)
+	if weekday != 100 || weekend != 400 {
+		t.Errorf("averages = %v per weekday and %v per weekend day, want 100 and 400", weekday, weekend)
+	}
+	if weekday, weekend := weekdayAverages(nil); weekday != 0 || weekend != 0 {
+		t.Errorf("without expenses = %v and %v, want zero", weekday, weekend)
+	}
+}
+
+func TestAIErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&utils.AIAPIError{StatusCode: http.StatusTooManyRequests, Message: "slow down", Attempts: 3}, "rate_limited"},
+		{fmt.Errorf("%w: %w", context.DeadlineExceeded, &utils.AIAPIError{StatusCode: http.StatusTooManyRequests}), "rate_limited"},
+		{&utils.AIAPIError{StatusCode: http.StatusBadGateway, Message: "bad gateway"}, "upstream_error"},
+		{&utils.AIAPIError{StatusCode: http.StatusUnauthorized, Message: "invalid key sk-123"}, "upstream_error"},
+		{errors.New("AI API key not set"), "upstream_error"},
+	}
+	for _, tt := range tests {
+		if got := aiErrorCode(tt.err); got != tt.want {
+			t.Errorf("aiErrorCode(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/your-github/expense-tracker-backend/config"
@@ -25,11 +27,14 @@ type registerDTO struct {
 func (c *AuthController) Register(ctx *gin.Context) {
 	var in registerDTO
 	if err := ctx.ShouldBindJSON(&in); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(ctx, err)
 		return
 	}
 	u := models.User{Name: in.Name, Email: in.Email, Password: in.Password, Budget: in.Budget}
 	if err := c.S.Register(&u); err != nil {
+		if respondOTPRateLimited(ctx, err) {
+			return
+		}
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -44,7 +49,7 @@ type loginDTO struct {
 func (c *AuthController) Login(ctx *gin.Context) {
 	var in loginDTO
 	if err := ctx.ShouldBindJSON(&in); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(ctx, err)
 		return
 	}
 	user, err := c.S.Login(in.Email, in.Password)
@@ -52,7 +57,7 @@ func (c *AuthController) Login(ctx *gin.Context) {
 		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
-	token, _ := utils.GenerateToken(user.ID, c.Config.JWT.Secret)
+	token, _ := utils.GenerateToken(user.ID, user.Role, c.Config.JWT.Secret)
 	ctx.JSON(http.StatusOK, gin.H{
 		"token": token,
 		"user":  user,
@@ -67,7 +72,7 @@ type verifyOTPDTO struct {
 func (c *AuthController) VerifyOTP(ctx *gin.Context) {
 	var in verifyOTPDTO
 	if err := ctx.ShouldBindJSON(&in); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(ctx, err)
 		return
 	}
 
@@ -86,14 +91,32 @@ type resendOTPDTO struct {
 func (c *AuthController) ResendOTP(ctx *gin.Context) {
 	var in resendOTPDTO
 	if err := ctx.ShouldBindJSON(&in); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(ctx, err)
 		return
 	}
 
 	if err := c.S.ResendOTP(in.Email); err != nil {
+		if respondOTPRateLimited(ctx, err) {
+			return
+		}
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "OTP resent successfully"})
 }
+
+// respondOTPRateLimited writes a 429 with a retry hint if err is an OTP throttle error
+func respondOTPRateLimited(ctx *gin.Context, err error) bool {
+	var rl *services.OTPRateLimitError
+	if !errors.As(err, &rl) {
+		return false
+	}
+	retryAfter := rl.RetryAfterSeconds()
+	ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+	ctx.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       err.Error(),
+		"retry_after": retryAfter,
+	})
+	return true
+}
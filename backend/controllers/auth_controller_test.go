@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/services"
+)
+
+func TestRespondOTPRateLimited(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	throttled := fmt.Errorf("resend: %w", &services.OTPRateLimitError{RetryAfter: 41500 * time.Millisecond})
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	if !respondOTPRateLimited(ctx, throttled) {
+		t.Fatal("a wrapped OTPRateLimitError was not answered")
+	}
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "42" {
+		t.Errorf("Retry-After = %q, want 42", got)
+	}
+	var body struct {
+		Error      string `json:"error"`
+		RetryAfter int    `json:"retry_after"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.RetryAfter != 42 || body.Error == "" {
+		t.Errorf("body = %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	ctx, _ = gin.CreateTestContext(w)
+	if respondOTPRateLimited(ctx, errors.New("user not found")) {
+		t.Error("an unrelated error was answered as rate limited")
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("wrote %q for an unrelated error", w.Body.String())
+	}
+}
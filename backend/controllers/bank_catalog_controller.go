@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/models"
+	"github.com/your-github/expense-tracker-backend/services"
+)
+
+// BankCatalogController serves the public list of banks and lets admins
+// manage it
+type BankCatalogController struct{ S *services.BankService }
+
+type bankRequest struct {
+	ID         string `json:"id"`
+	Name       string `json:"name" binding:"required"`
+	Logo       string `json:"logo"`
+	Type       string `json:"type" binding:"required"`
+	IFSCPrefix string `json:"ifsc_prefix"`
+}
+
+func (r bankRequest) bank() models.Bank {
+	return models.Bank{ID: r.ID, Name: r.Name, Logo: r.Logo, Type: r.Type, IFSCPrefix: r.IFSCPrefix}
+}
+
+// List returns a page of banks, optionally filtered by ?type= and a name
+// search ?q=
+func (c *BankCatalogController) List(ctx *gin.Context) {
+	filter := services.BankFilter{
+		Type:  ctx.Query("type"),
+		Query: ctx.Query("q"),
+		Limit: services.DefaultBankListLimit,
+	}
+	if raw := ctx.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > services.MaxBankListLimit {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(services.MaxBankListLimit)})
+			return
+		}
+		filter.Limit = limit
+	}
+	if raw := ctx.Query("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative integer"})
+			return
+		}
+		filter.Offset = offset
+	}
+
+	banks, total, err := c.S.List(filter)
+	if errors.Is(err, services.ErrInvalidBankType) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch banks"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"banks":    banks,
+		"count":    len(banks),
+		"total":    total,
+		"limit":    filter.Limit,
+		"offset":   filter.Offset,
+		"has_more": int64(filter.Offset+len(banks)) < total,
+	})
+}
+
+// Create adds a bank to the list
+func (c *BankCatalogController) Create(ctx *gin.Context) {
+	var in bankRequest
+	if err := ctx.ShouldBindJSON(&in); err != nil {
+		respondBindingError(ctx, err)
+		return
+	}
+
+	bank, err := c.S.Create(in.bank())
+	if errors.Is(err, services.ErrBankExists) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "bank": bank})
+		return
+	}
+	if err != nil {
+		respondBankError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, bank)
+}
+
+// Update replaces a bank's details; the ID in the path wins over the body
+func (c *BankCatalogController) Update(ctx *gin.Context) {
+	var in bankRequest
+	if err := ctx.ShouldBindJSON(&in); err != nil {
+		respondBindingError(ctx, err)
+		return
+	}
+
+	bank, err := c.S.Update(ctx.Param("id"), in.bank())
+	if err != nil {
+		respondBankError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, bank)
+}
+
+// Delete removes a bank from the list
+func (c *BankCatalogController) Delete(ctx *gin.Context) {
+	if err := c.S.Delete(ctx.Param("id")); err != nil {
+		respondBankError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+func respondBankError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidBank), errors.Is(err, services.ErrInvalidBankType):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrBankNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+	"github.com/your-github/expense-tracker-backend/services"
+)
+
+func TestBankCatalogList(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`SELECT count(*) FROM "banks"`, []string{"count"}, []driver.Value{int64(3)})
+	stub.On(`SELECT * FROM "banks"`, []string{"id", "name", "type"},
+		[]driver.Value{"axis", "Axis Bank", "private"},
+		[]driver.Value{"hdfc", "HDFC Bank", "private"})
+	controller := &BankCatalogController{S: services.NewBankService(db)}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/banks", controller.List)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	w := get("/api/banks?type=private&q=bank&limit=2")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d %s", w.Code, w.Body)
+	}
+	for _, want := range []string{`"count":2`, `"total":3`, `"limit":2`, `"has_more":true`, `"id":"hdfc"`} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("body %s missing %s", w.Body, want)
+		}
+	}
+	page := stub.Ran(`SELECT * FROM "banks"`)[0]
+	if !strings.Contains(page.SQL, "type = $1") || !strings.Contains(page.SQL, "LIMIT 2") {
+		t.Errorf("page query %s doesn't apply the filters", page.SQL)
+	}
+
+	for _, target := range []string{"/api/banks?type=cooperative", "/api/banks?limit=0", "/api/banks?limit=501", "/api/banks?offset=-1"} {
+		if w := get(target); w.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", target, w.Code)
+		}
+	}
+}
@@ -1,11 +1,17 @@
 package controllers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/middleware"
 	"github.com/your-github/expense-tracker-backend/models"
 	"github.com/your-github/expense-tracker-backend/services"
 	"gorm.io/gorm"
@@ -64,9 +70,9 @@ func (c *BankController) GetBankAccounts(ctx *gin.Context) {
 			ID:                account.ID,
 			BankID:            account.BankID,
 			BankName:          getBankName(account.BankID),
-			AccountNumber:     maskAccountNumber(account.AccountNumber),
+			AccountNumber:     services.MaskAccountNumber(account.AccountNumber),
 			AccountHolderName: account.AccountHolderName,
-			MobileNumber:      maskMobileNumber(account.MobileNumber),
+			MobileNumber:      services.MaskMobileNumber(account.MobileNumber),
 			Status:            account.Status,
 			AccountType:       account.AccountType,
 			IFSCCode:          account.IFSCCode,
@@ -88,9 +94,25 @@ func (c *BankController) AddBankAccount(ctx *gin.Context) {
 
 	var req AddBankAccountRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		respondBindingError(ctx, err)
 		return
 	}
+	req.AccountNumber = services.NormalizeAccountNumber(req.AccountNumber)
+	if strings.EqualFold(req.BankID, services.ManualBankID) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "The manual entry account is created automatically"})
+		return
+	}
+
+	// A retried request with the same Idempotency-Key gets the original result
+	// instead of triggering another verification
+	idempotencyKey := strings.TrimSpace(ctx.GetHeader("Idempotency-Key"))
+	requestHash := req.hash()
+	if idempotencyKey != "" {
+		if account, ok := c.findIdempotentBankAccount(userID, idempotencyKey); ok {
+			c.replayBankAccount(ctx, account, requestHash)
+			return
+		}
+	}
 
 	// Check if bank account already exists for this user
 	var existingAccount models.BankAccount
@@ -106,8 +128,8 @@ func (c *BankController) AddBankAccount(ctx *gin.Context) {
 		return
 	}
 
-	if !services.ValidateAccountNumber(req.AccountNumber) {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account number format"})
+	if err := services.ValidateAccountNumberForBank(req.BankID, req.AccountNumber); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account number format: " + err.Error()})
 		return
 	}
 
@@ -119,8 +141,12 @@ func (c *BankController) AddBankAccount(ctx *gin.Context) {
 		AccountHolder: req.AccountHolderName,
 	}
 
-	// Use mock verification for now (replace with real API call in production)
-	verificationResp, err := c.VerificationService.MockVerifyBankAccount(verificationReq)
+	// Dispatch to the configured verification provider
+	verificationResp, err := c.VerificationService.VerifyBankAccount(verificationReq)
+	if errors.Is(err, services.ErrVerificationUnavailable) {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Bank verification is temporarily unavailable, please try again later"})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify bank account"})
 		return
@@ -145,8 +171,19 @@ func (c *BankController) AddBankAccount(ctx *gin.Context) {
 		BranchName:        verificationResp.BranchName,
 		VerifiedAt:        &now,
 	}
+	if idempotencyKey != "" {
+		bankAccount.IdempotencyKey = &idempotencyKey
+		bankAccount.IdempotencyHash = &requestHash
+	}
 
 	if err := c.DB.Create(&bankAccount).Error; err != nil {
+		// A concurrent request with the same key may have won the race
+		if idempotencyKey != "" {
+			if account, ok := c.findIdempotentBankAccount(userID, idempotencyKey); ok {
+				c.replayBankAccount(ctx, account, requestHash)
+				return
+			}
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add bank account"})
 		return
 	}
@@ -157,6 +194,53 @@ func (c *BankController) AddBankAccount(ctx *gin.Context) {
 	})
 }
 
+// idempotencyKeyTTL is how long an Idempotency-Key replays its original result
+const idempotencyKeyTTL = 24 * time.Hour
+
+// findIdempotentBankAccount returns the account created with key within the
+// TTL. Keys older than the TTL are released so they can be reused.
+func (c *BankController) findIdempotentBankAccount(userID uint, key string) (models.BankAccount, bool) {
+	var account models.BankAccount
+	if err := c.DB.Where("user_id = ? AND idempotency_key = ?", userID, key).First(&account).Error; err != nil {
+		return account, false
+	}
+	if time.Since(account.CreatedAt) > idempotencyKeyTTL {
+		if err := c.DB.Model(&account).Updates(map[string]interface{}{
+			"idempotency_key":  nil,
+			"idempotency_hash": nil,
+		}).Error; err != nil {
+			// The key stays taken, so the create below will fail on the
+			// unique index rather than replay a stale result
+			log.Printf("Failed to release expired idempotency key for bank account %d: %v", account.ID, err)
+		}
+		return account, false
+	}
+	return account, true
+}
+
+// replayBankAccount answers a retried request with the account its key
+// created. A key reused with a different body is rejected instead, since
+// the client would otherwise believe the new details were saved.
+func (c *BankController) replayBankAccount(ctx *gin.Context, account models.BankAccount, requestHash string) {
+	if account.IdempotencyHash != nil && *account.IdempotencyHash != requestHash {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request"})
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Bank account added successfully",
+		"id":      account.ID,
+	})
+}
+
+// hash identifies the request body an Idempotency-Key is used with, after
+// the account number has been normalized
+func (r AddBankAccountRequest) hash() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		r.BankID, r.AccountNumber, r.AccountHolderName, r.MobileNumber,
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
 // UpdateBankAccount updates an existing bank account
 func (c *BankController) UpdateBankAccount(ctx *gin.Context) {
 	userID := getUserIDFromContext(ctx)
@@ -173,7 +257,7 @@ func (c *BankController) UpdateBankAccount(ctx *gin.Context) {
 
 	var req AddBankAccountRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		respondBindingError(ctx, err)
 		return
 	}
 
@@ -183,10 +267,24 @@ func (c *BankController) UpdateBankAccount(ctx *gin.Context) {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Bank account not found"})
 		return
 	}
+	if isManualAccount(ctx, bankAccount) {
+		return
+	}
+
+	if strings.EqualFold(req.BankID, services.ManualBankID) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "The manual entry account is created automatically"})
+		return
+	}
+
+	accountNumber := services.NormalizeAccountNumber(req.AccountNumber)
+	if err := services.ValidateAccountNumberForBank(req.BankID, accountNumber); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account number format: " + err.Error()})
+		return
+	}
 
 	// Update bank account
 	bankAccount.BankID = req.BankID
-	bankAccount.AccountNumber = req.AccountNumber
+	bankAccount.AccountNumber = accountNumber
 	bankAccount.AccountHolderName = req.AccountHolderName
 
 	if err := c.DB.Save(&bankAccount).Error; err != nil {
@@ -197,7 +295,9 @@ func (c *BankController) UpdateBankAccount(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "Bank account updated successfully"})
 }
 
-// DeleteBankAccount deletes a bank account
+// DeleteBankAccount soft-deletes a bank account. Its transactions are hidden
+// from history while the account is deleted; with ?delete_transactions=true
+// they are soft-deleted as well. Either way RestoreBankAccount brings both back.
 func (c *BankController) DeleteBankAccount(ctx *gin.Context) {
 	userID := getUserIDFromContext(ctx)
 	if userID == 0 {
@@ -211,19 +311,103 @@ func (c *BankController) DeleteBankAccount(ctx *gin.Context) {
 		return
 	}
 
+	deleteTransactions := false
+	if raw := ctx.Query("delete_transactions"); raw != "" {
+		deleteTransactions, err = strconv.ParseBool(raw)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "delete_transactions must be true or false"})
+			return
+		}
+	}
+
 	// Check if bank account exists and belongs to user
 	var bankAccount models.BankAccount
 	if err := c.DB.Where("id = ? AND user_id = ?", accountID, userID).First(&bankAccount).Error; err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Bank account not found"})
 		return
 	}
+	if isManualAccount(ctx, bankAccount) {
+		return
+	}
 
-	if err := c.DB.Delete(&bankAccount).Error; err != nil {
+	// The account and its cascaded transactions share one deleted_at so a
+	// restore can tell them apart from transactions deleted individually.
+	// Postgres keeps microseconds, so truncate to compare equal later.
+	deletedAt := time.Now().Truncate(time.Microsecond)
+	var transactionsDeleted int64
+	err = c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&bankAccount).Update("deleted_at", deletedAt).Error; err != nil {
+			return err
+		}
+		if !deleteTransactions {
+			return nil
+		}
+		result := tx.Model(&models.Transaction{}).
+			Where("bank_account_id = ? AND user_id = ?", bankAccount.ID, userID).
+			Update("deleted_at", deletedAt)
+		transactionsDeleted = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete bank account"})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Bank account deleted successfully"})
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":              "Bank account deleted successfully",
+		"transactions_deleted": transactionsDeleted,
+	})
+}
+
+// RestoreBankAccount undoes DeleteBankAccount, restoring the account and any
+// transactions deleted along with it
+func (c *BankController) RestoreBankAccount(ctx *gin.Context) {
+	userID := getUserIDFromContext(ctx)
+	if userID == 0 {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	accountID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	var bankAccount models.BankAccount
+	if err := c.DB.Unscoped().Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", accountID, userID).First(&bankAccount).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Deleted bank account not found"})
+		return
+	}
+
+	// The account may have been added again since it was deleted
+	var existingAccount models.BankAccount
+	if err := c.DB.Where("user_id = ? AND bank_id = ? AND account_number = ?",
+		userID, bankAccount.BankID, bankAccount.AccountNumber).First(&existingAccount).Error; err == nil {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Bank account already exists"})
+		return
+	}
+
+	var transactionsRestored int64
+	err = c.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&models.Transaction{}).
+			Where("bank_account_id = ? AND user_id = ? AND deleted_at = ?", bankAccount.ID, userID, bankAccount.DeletedAt.Time).
+			Update("deleted_at", nil)
+		if result.Error != nil {
+			return result.Error
+		}
+		transactionsRestored = result.RowsAffected
+		return tx.Unscoped().Model(&bankAccount).Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore bank account"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":               "Bank account restored successfully",
+		"transactions_restored": transactionsRestored,
+	})
 }
 
 // FetchTransactions fetches transactions for a specific bank account
@@ -246,6 +430,9 @@ func (c *BankController) FetchTransactions(ctx *gin.Context) {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Bank account not found"})
 		return
 	}
+	if isManualAccount(ctx, bankAccount) {
+		return
+	}
 
 	// Generate mock transactions (20 transactions for demo)
 	mockTransactions, err := c.TransactionService.GenerateMockTransactions(uint(accountID), userID, 20)
@@ -255,7 +442,7 @@ func (c *BankController) FetchTransactions(ctx *gin.Context) {
 	}
 
 	// Store transactions in database
-	if err := c.TransactionService.StoreTransactions(mockTransactions, uint(accountID), userID); err != nil {
+	if err := c.TransactionService.StoreTransactions(ctx.Request.Context(), mockTransactions, uint(accountID), userID); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store transactions"})
 		return
 	}
@@ -269,6 +456,17 @@ func (c *BankController) FetchTransactions(ctx *gin.Context) {
 }
 
 // Helper functions
+
+// isManualAccount rejects changes to the synthetic manual entry account,
+// which only exists to hold mirrors of manual expenses
+func isManualAccount(ctx *gin.Context, account models.BankAccount) bool {
+	if account.BankID != services.ManualBankID {
+		return false
+	}
+	ctx.JSON(http.StatusBadRequest, gin.H{"error": "The manual entry account cannot be changed"})
+	return true
+}
+
 func getBankName(bankID string) string {
 	bankNames := map[string]string{
 		"hdfc":   "HDFC Bank",
@@ -288,22 +486,10 @@ func getBankName(bankID string) string {
 	return "Unknown Bank"
 }
 
-func maskAccountNumber(accountNumber string) string {
-	if len(accountNumber) <= 4 {
-		return accountNumber
-	}
-	return "****" + accountNumber[len(accountNumber)-4:]
-}
-
-func maskMobileNumber(mobileNumber string) string {
-	if len(mobileNumber) <= 4 {
-		return mobileNumber
-	}
-	return "****" + mobileNumber[len(mobileNumber)-4:]
-}
-
+// getUserIDFromContext returns the numeric user ID set by middleware.Auth, or 0
+// when the token carried a UUID subject from the AA stack
 func getUserIDFromContext(c *gin.Context) uint {
-	userID, exists := c.Get("userID")
+	userID, exists := c.Get(middleware.ContextUserID)
 	if !exists {
 		return 0
 	}
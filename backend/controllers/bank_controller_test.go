@@ -0,0 +1,475 @@
+package controllers
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+	"github.com/your-github/expense-tracker-backend/middleware"
+	"github.com/your-github/expense-tracker-backend/services"
+	"gorm.io/gorm"
+)
+
+// roundTripFunc lets a function stand in for the verification provider
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// bankAccountTable keeps the bank_accounts rows AddBankAccount writes
+type bankAccountTable struct {
+	mu   sync.Mutex
+	rows []map[string]driver.Value
+}
+
+var bankAccountColumns = []string{"id", "created_at", "user_id", "bank_id", "account_number", "idempotency_key", "idempotency_hash"}
+
+func newBankAccountTable(stub *testutil.StubDB) *bankAccountTable {
+	table := &bankAccountTable{}
+	stub.Handle(`INSERT INTO "bank_accounts"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		row := testutil.InsertedValues(query, args)
+		row["id"] = int64(len(table.rows) + 1)
+		table.rows = append(table.rows, row)
+		return testutil.StubResult{Columns: []string{"id"}, Rows: [][]driver.Value{{row["id"]}}}, nil
+	})
+	stub.Handle(`idempotency_key = `, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		result := testutil.StubResult{Columns: bankAccountColumns}
+		for _, row := range table.rows {
+			if key, ok := row["idempotency_key"].(*string); ok && key != nil && *key == args[1] {
+				result.Rows = append(result.Rows, []driver.Value{
+					row["id"], row["created_at"], row["user_id"], row["bank_id"], row["account_number"],
+					*key, *row["idempotency_hash"].(*string),
+				})
+			}
+		}
+		return result, nil
+	})
+	return table
+}
+
+func (b *bankAccountTable) count() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.rows)
+}
+
+// newBankFixture returns a bank controller whose provider verifies every
+// account, with a counter of the verification calls
+func newBankFixture(t *testing.T) (*BankController, *bankAccountTable, *testutil.StubDB, *int) {
+	t.Helper()
+	db, stub := testutil.NewStubDB(t)
+	calls := 0
+	verification := services.NewBankVerificationService("key", "", "karza")
+	verification.Client = &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"status":"success","data":{"verified":true,"accountType":"SAVINGS"}}`)),
+		}, nil
+	})}
+	controller := &BankController{DB: db, VerificationService: verification}
+	return controller, newBankAccountTable(stub), stub, &calls
+}
+
+// addBankAccount posts body to AddBankAccount as user 7 with the given
+// Idempotency-Key
+func addBankAccount(controller *BankController, key, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/", func(ctx *gin.Context) {
+		ctx.Set(middleware.ContextUserID, uint(7))
+		controller.AddBankAccount(ctx)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// hdfcBody is a request body for an HDFC account with accountNumber
+func hdfcBody(accountNumber string) string {
+	return fmt.Sprintf(`{"bankId": "hdfc", "accountNumber": %q, "accountHolderName": "Asha", "mobileNumber": "9876543210"}`, accountNumber)
+}
+
+func TestAddBankAccountReplaysIdempotencyKey(t *testing.T) {
+	controller, table, _, calls := newBankFixture(t)
+
+	first := addBankAccount(controller, "retry-1", hdfcBody("1234 5678 9012"))
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request = %d %s", first.Code, first.Body)
+	}
+	// The retry spells the account number differently but is the same request
+	replay := addBankAccount(controller, "retry-1", hdfcBody("1234-5678-9012"))
+	if replay.Code != http.StatusCreated {
+		t.Fatalf("replay = %d %s", replay.Code, replay.Body)
+	}
+
+	var a, b struct{ ID uint }
+	json.Unmarshal(first.Body.Bytes(), &a)
+	json.Unmarshal(replay.Body.Bytes(), &b)
+	if a.ID == 0 || a.ID != b.ID {
+		t.Errorf("replay returned account %d, want %d", b.ID, a.ID)
+	}
+	if table.count() != 1 {
+		t.Errorf("created %d rows, want 1", table.count())
+	}
+	if *calls != 1 {
+		t.Errorf("verified %d times, want 1", *calls)
+	}
+	if got := table.rows[0]["account_number"]; got != "123456789012" {
+		t.Errorf("stored account number %v, want it normalized", got)
+	}
+}
+
+func TestAddBankAccountRejectsKeyReuseWithDifferentBody(t *testing.T) {
+	controller, table, _, calls := newBankFixture(t)
+
+	if w := addBankAccount(controller, "retry-1", hdfcBody("123456789012")); w.Code != http.StatusCreated {
+		t.Fatalf("first request = %d %s", w.Code, w.Body)
+	}
+	w := addBankAccount(controller, "retry-1", hdfcBody("999988887777"))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key = %d %s, want 422", w.Code, w.Body)
+	}
+	if table.count() != 1 || *calls != 1 {
+		t.Errorf("reused key created %d rows after %d verifications", table.count(), *calls)
+	}
+}
+
+func TestAddBankAccountReleasesExpiredKey(t *testing.T) {
+	controller, table, stub, calls := newBankFixture(t)
+
+	if w := addBankAccount(controller, "retry-1", hdfcBody("123456789012")); w.Code != http.StatusCreated {
+		t.Fatalf("first request = %d %s", w.Code, w.Body)
+	}
+	table.mu.Lock()
+	table.rows[0]["created_at"] = time.Now().Add(-idempotencyKeyTTL - time.Minute)
+	table.mu.Unlock()
+
+	if w := addBankAccount(controller, "retry-1", hdfcBody("999988887777")); w.Code != http.StatusCreated {
+		t.Fatalf("after the TTL = %d %s, want a new account", w.Code, w.Body)
+	}
+	if len(stub.Ran(`SET "idempotency_hash"=$1,"idempotency_key"=$2`)) != 1 {
+		t.Errorf("the expired key wasn't released: %+v", stub.Ran(`UPDATE "bank_accounts"`))
+	}
+	if table.count() != 2 || *calls != 2 {
+		t.Errorf("created %d rows after %d verifications, want 2", table.count(), *calls)
+	}
+}
+
+func TestAddBankAccountRejectsMalformedNumberBeforeVerifying(t *testing.T) {
+	controller, table, _, calls := newBankFixture(t)
+
+	w := addBankAccount(controller, "", hdfcBody("12345678901"))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "12-14 digits") {
+		t.Errorf("11 digit HDFC account = %d %s, want 400 naming the format", w.Code, w.Body)
+	}
+	if *calls != 0 || table.count() != 0 {
+		t.Errorf("malformed account made %d verification calls and %d rows", *calls, table.count())
+	}
+}
+
+// ledger keeps bank accounts and their transactions with soft deletes and
+// answers the SQL DeleteBankAccount, RestoreBankAccount and transaction
+// history run against them; every row belongs to user 7
+type ledger struct {
+	mu           sync.Mutex
+	accounts     map[int64]*ledgerAccount
+	transactions []*ledgerTransaction
+}
+
+type ledgerAccount struct {
+	id                    int64
+	bankID, accountNumber string
+	deletedAt             *time.Time
+}
+
+type ledgerTransaction struct {
+	id, accountID int64
+	deletedAt     *time.Time
+}
+
+// placeholderArg returns the argument bound to the placeholder after
+// condition in query; condition must start a word, so "id =" doesn't match
+// "user_id ="
+func placeholderArg(query, condition string, args []driver.Value) (driver.Value, bool) {
+	m := regexp.MustCompile(`(?:^|\W)` + regexp.QuoteMeta(condition) + ` \$(\d+)`).FindStringSubmatch(query)
+	if m == nil {
+		return nil, false
+	}
+	n, _ := strconv.Atoi(m[1])
+	return args[n-1], true
+}
+
+// deletedAtArg converts the value written to deleted_at
+func deletedAtArg(v driver.Value) *time.Time {
+	switch t := v.(type) {
+	case time.Time:
+		return &t
+	case *time.Time:
+		return t
+	}
+	return nil
+}
+
+func newLedger(stub *testutil.StubDB) *ledger {
+	l := &ledger{accounts: make(map[int64]*ledgerAccount)}
+
+	stub.Handle(`FROM "bank_accounts"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		result := testutil.StubResult{Columns: []string{"id", "user_id", "bank_id", "account_number", "deleted_at"}}
+		wantDeleted := strings.Contains(query, "deleted_at IS NOT NULL")
+		id, byID := placeholderArg(query, "id =", args)
+		bankID, byNumber := placeholderArg(query, "bank_id =", args)
+		number, _ := placeholderArg(query, "account_number =", args)
+		for _, a := range l.accounts {
+			if (a.deletedAt != nil) != wantDeleted {
+				continue
+			}
+			if byID && a.id != toInt64(id) {
+				continue
+			}
+			if byNumber && (a.bankID != bankID || a.accountNumber != number) {
+				continue
+			}
+			var deletedAt driver.Value
+			if a.deletedAt != nil {
+				deletedAt = *a.deletedAt
+			}
+			result.Rows = append(result.Rows, []driver.Value{a.id, int64(7), a.bankID, a.accountNumber, deletedAt})
+		}
+		return result, nil
+	})
+	stub.Handle(`UPDATE "bank_accounts" SET "deleted_at"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		id, _ := placeholderArg(query, `"id" =`, args)
+		account := l.accounts[toInt64(id)]
+		if account == nil {
+			return testutil.StubResult{}, nil
+		}
+		account.deletedAt = deletedAtArg(args[0])
+		return testutil.StubResult{Affected: 1}, nil
+	})
+	stub.Handle(`UPDATE "transactions" SET "deleted_at"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		accountID, _ := placeholderArg(query, "bank_account_id =", args)
+		deletedAt, restoring := placeholderArg(query, "deleted_at =", args)
+		var affected int64
+		for _, txn := range l.transactions {
+			if txn.accountID != toInt64(accountID) {
+				continue
+			}
+			if restoring && (txn.deletedAt == nil || !txn.deletedAt.Equal(deletedAt.(time.Time))) {
+				continue
+			}
+			if !restoring && txn.deletedAt != nil {
+				continue
+			}
+			txn.deletedAt = deletedAtArg(args[0])
+			affected++
+		}
+		return testutil.StubResult{Affected: affected}, nil
+	})
+	stub.Handle(`FROM "transactions"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		activeAccounts := strings.Contains(query, "NOT EXISTS")
+		var rows [][]driver.Value
+		for _, txn := range l.transactions {
+			if txn.deletedAt != nil {
+				continue
+			}
+			if activeAccounts && l.accounts[txn.accountID].deletedAt != nil {
+				continue
+			}
+			rows = append(rows, []driver.Value{txn.id, int64(7), txn.accountID})
+		}
+		if strings.Contains(query, "count(*)") {
+			return testutil.StubResult{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(len(rows))}}}, nil
+		}
+		return testutil.StubResult{Columns: []string{"id", "user_id", "bank_account_id"}, Rows: rows}, nil
+	})
+	return l
+}
+
+func toInt64(v driver.Value) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case uint:
+		return int64(n)
+	case uint64:
+		return int64(n)
+	}
+	return -1
+}
+
+// historyIDs returns the IDs of user 7's transaction history
+func historyIDs(t *testing.T, db *gorm.DB) []uint {
+	t.Helper()
+	transactions, total, err := (&services.TransactionService{DB: db}).GetTransactions(context.Background(), 7, services.TransactionFilter{})
+	if err != nil {
+		t.Fatalf("GetTransactions: %v", err)
+	}
+	ids := []uint{}
+	for _, txn := range transactions {
+		ids = append(ids, txn.ID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if total != int64(len(ids)) {
+		t.Errorf("history total %d, but %d rows", total, len(ids))
+	}
+	return ids
+}
+
+// bankAccountRequest serves method on target to DeleteBankAccount or
+// RestoreBankAccount as user 7
+func bankAccountRequest(controller *BankController, method, target string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(ctx *gin.Context) { ctx.Set(middleware.ContextUserID, uint(7)) })
+	r.DELETE("/api/bank/accounts/:id", controller.DeleteBankAccount)
+	r.POST("/api/bank/accounts/:id/restore", controller.RestoreBankAccount)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+func TestDeleteAndRestoreBankAccount(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	l := newLedger(stub)
+	earlier := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	l.accounts[1] = &ledgerAccount{id: 1, bankID: "hdfc", accountNumber: "123456789012"}
+	l.accounts[2] = &ledgerAccount{id: 2, bankID: "sbi", accountNumber: "12345678901"}
+	l.transactions = []*ledgerTransaction{
+		{id: 1, accountID: 1},
+		{id: 2, accountID: 1},
+		{id: 3, accountID: 2},
+		// Deleted on its own before the account was
+		{id: 4, accountID: 1, deletedAt: &earlier},
+	}
+	controller := &BankController{DB: db}
+
+	if got := historyIDs(t, db); fmt.Sprint(got) != "[1 2 3]" {
+		t.Fatalf("history before = %v", got)
+	}
+
+	// Without the cascade the transactions stay but drop out of history
+	if w := bankAccountRequest(controller, http.MethodDelete, "/api/bank/accounts/1"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"transactions_deleted":0`) {
+		t.Fatalf("delete = %d %s", w.Code, w.Body)
+	}
+	if l.transactions[0].deletedAt != nil {
+		t.Error("deleting the account without the cascade deleted its transactions")
+	}
+	if got := historyIDs(t, db); fmt.Sprint(got) != "[3]" {
+		t.Errorf("history after delete = %v, want only the other account's", got)
+	}
+	if w := bankAccountRequest(controller, http.MethodPost, "/api/bank/accounts/1/restore"); w.Code != http.StatusOK {
+		t.Fatalf("restore = %d %s", w.Code, w.Body)
+	}
+	if got := historyIDs(t, db); fmt.Sprint(got) != "[1 2 3]" {
+		t.Errorf("history after restore = %v", got)
+	}
+
+	// With the cascade the live transactions are deleted alongside
+	w := bankAccountRequest(controller, http.MethodDelete, "/api/bank/accounts/1?delete_transactions=true")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"transactions_deleted":2`) {
+		t.Fatalf("cascading delete = %d %s", w.Code, w.Body)
+	}
+	if got := historyIDs(t, db); fmt.Sprint(got) != "[3]" {
+		t.Errorf("history after cascading delete = %v", got)
+	}
+	w = bankAccountRequest(controller, http.MethodPost, "/api/bank/accounts/1/restore")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"transactions_restored":2`) {
+		t.Fatalf("restore = %d %s", w.Code, w.Body)
+	}
+	if got := historyIDs(t, db); fmt.Sprint(got) != "[1 2 3]" {
+		t.Errorf("history after restore = %v", got)
+	}
+	if l.transactions[3].deletedAt == nil || !l.transactions[3].deletedAt.Equal(earlier) {
+		t.Error("restoring the account revived a transaction deleted on its own")
+	}
+
+	if w := bankAccountRequest(controller, http.MethodPost, "/api/bank/accounts/1/restore"); w.Code != http.StatusNotFound {
+		t.Errorf("restoring a live account = %d, want 404", w.Code)
+	}
+	if w := bankAccountRequest(controller, http.MethodDelete, "/api/bank/accounts/2?delete_transactions=maybe"); w.Code != http.StatusBadRequest {
+		t.Errorf("delete_transactions=maybe = %d, want 400", w.Code)
+	}
+}
+
+func TestRestoreBankAccountConflictsWithReaddedAccount(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	l := newLedger(stub)
+	deleted := time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)
+	l.accounts[1] = &ledgerAccount{id: 1, bankID: "hdfc", accountNumber: "123456789012", deletedAt: &deleted}
+	l.accounts[2] = &ledgerAccount{id: 2, bankID: "hdfc", accountNumber: "123456789012"}
+
+	if w := bankAccountRequest(&BankController{DB: db}, http.MethodPost, "/api/bank/accounts/1/restore"); w.Code != http.StatusConflict {
+		t.Errorf("restore over a re-added account = %d %s, want 409", w.Code, w.Body)
+	}
+	if l.accounts[1].deletedAt == nil {
+		t.Error("the conflicting account was restored")
+	}
+}
+
+func TestManualAccountShowsInHistoryAndCannotBeDeleted(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	l := newLedger(stub)
+	l.accounts[1] = &ledgerAccount{id: 1, bankID: "hdfc", accountNumber: "123456789012"}
+	l.accounts[50] = &ledgerAccount{id: 50, bankID: services.ManualBankID, accountNumber: services.ManualBankID}
+	l.transactions = []*ledgerTransaction{{id: 1, accountID: 1}, {id: 2, accountID: 50}}
+
+	w := getAsUser((&TransactionController{TransactionService: &services.TransactionService{DB: db}}).GetTransactionHistory, "/api/transactions")
+	var body struct {
+		Transactions []TransactionResponse `json:"transactions"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || len(body.Transactions) != 2 {
+		t.Fatalf("history = %d %s", w.Code, w.Body)
+	}
+	for _, txn := range body.Transactions {
+		account := txn.BankAccount
+		switch txn.ID {
+		case 1:
+			if account.ID != 1 || account.BankName != "HDFC Bank" || account.AccountNumber != "****9012" {
+				t.Errorf("bank transaction account = %+v", account)
+			}
+		case 2:
+			if account.ID != 50 || account.BankName != "Manual Entry" || account.AccountNumber != "Manual Entry" {
+				t.Errorf("manual transaction account = %+v, want the Manual Entry account", account)
+			}
+		}
+	}
+
+	if w := bankAccountRequest(&BankController{DB: db}, http.MethodDelete, "/api/bank/accounts/50"); w.Code != http.StatusBadRequest {
+		t.Errorf("deleting the manual account = %d %s, want 400", w.Code, w.Body)
+	}
+	if l.accounts[50].deletedAt != nil {
+		t.Error("the manual account was deleted")
+	}
+}
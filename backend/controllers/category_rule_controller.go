@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/services"
+)
+
+type CategoryRuleController struct{ S *services.CategoryRuleService }
+
+// List returns the user's category rules in the order they are applied
+func (c *CategoryRuleController) List(ctx *gin.Context) {
+	rules, err := c.S.List(ctx.GetUint("userID"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, rules)
+}
+
+// Create adds a category rule. Rules apply to expenses created or edited
+// without a category, and to POST /expenses/recategorize.
+func (c *CategoryRuleController) Create(ctx *gin.Context) {
+	var in struct {
+		Matcher     string `json:"matcher" binding:"required"`
+		Category    string `json:"category" binding:"required"`
+		Subcategory string `json:"subcategory"`
+	}
+	if err := ctx.ShouldBindJSON(&in); err != nil {
+		respondBindingError(ctx, err)
+		return
+	}
+
+	rule, err := c.S.Create(ctx.GetUint("userID"), in.Matcher, in.Category, in.Subcategory)
+	switch {
+	case errors.Is(err, services.ErrInvalidCategoryRule), errors.Is(err, services.ErrInvalidRuleRegex):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusCreated, rule)
+	}
+}
+
+// Delete removes a category rule
+func (c *CategoryRuleController) Delete(ctx *gin.Context) {
+	ruleID, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	err := c.S.Delete(ctx.GetUint("userID"), ruleID)
+	switch {
+	case errors.Is(err, services.ErrCategoryRuleNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusOK, gin.H{"message": "deleted"})
+	}
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/database"
+	"github.com/your-github/expense-tracker-backend/models"
+	"github.com/your-github/expense-tracker-backend/services"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+type CurrencyController struct {
+	Rates   utils.RateProvider
+	Summary *services.SummaryService
+}
+
+// GetRates returns current exchange rates for ?base= (defaults to the user's currency)
+func (c *CurrencyController) GetRates(ctx *gin.Context) {
+	base := ctx.Query("base")
+	if base == "" {
+		var user models.User
+		if err := database.DB.Select("currency").First(&user, ctx.GetUint("userID")).Error; err == nil {
+			base = user.Currency
+		}
+	}
+	base = utils.NormalizeCurrency(base)
+
+	rates, err := c.Rates.Rates(base)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"base": base, "rates": rates})
+}
+
+// SetPreferred updates the currency summaries are displayed in
+func (c *CurrencyController) SetPreferred(ctx *gin.Context) {
+	var in struct {
+		Currency string `json:"currency" binding:"required,iso4217"`
+	}
+	if err := ctx.ShouldBindJSON(&in); err != nil {
+		respondBindingError(ctx, err)
+		return
+	}
+
+	// Only accept currencies we can actually convert into
+	if _, err := utils.ConvertAmount(c.Rates, 1, utils.DefaultCurrency, in.Currency); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "unsupported currency: " + in.Currency})
+		return
+	}
+
+	uid := ctx.GetUint("userID")
+	if err := database.DB.Model(&models.User{}).Where("id = ?", uid).Update("currency", in.Currency).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update currency"})
+		return
+	}
+	c.Summary.InvalidateUserCache(uid)
+
+	ctx.JSON(http.StatusOK, gin.H{"currency": in.Currency})
+}
@@ -1,12 +1,15 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/your-github/expense-tracker-backend/models"
 	"github.com/your-github/expense-tracker-backend/services"
+	"gorm.io/gorm"
 )
 
 type ExpenseController struct{ S *services.ExpenseService }
@@ -19,7 +22,7 @@ func NewExpenseController(expenseService *services.ExpenseService) *ExpenseContr
 func (c *ExpenseController) Create(ctx *gin.Context) {
 	var in models.Expense
 	if err := ctx.ShouldBindJSON(&in); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(ctx, err)
 		return
 	}
 	uid := ctx.GetUint("userID")
@@ -41,6 +44,17 @@ func (c *ExpenseController) List(ctx *gin.Context) {
 		}
 	}
 
+	// ?tags=work,reimbursable matches any tag; add &match=all to require every tag
+	if tags := ctx.Query("tags"); tags != "" {
+		data, err := c.S.ListByTags(uid, strings.Split(tags, ","), ctx.Query("match") == "all", limit)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, data)
+		return
+	}
+
 	data, _ := c.S.List(uid, limit)
 	ctx.JSON(http.StatusOK, data)
 }
@@ -49,16 +63,49 @@ func (c *ExpenseController) Update(ctx *gin.Context) {
 	id, _ := strconv.Atoi(ctx.Param("id"))
 	var in models.Expense
 	if err := ctx.ShouldBindJSON(&in); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(ctx, err)
 		return
 	}
 	if err := c.S.Update(uint(id), ctx.GetUint("userID"), &in); err != nil {
+		if errors.Is(err, services.ErrInvalidExpenseType) || errors.Is(err, services.ErrEmptyExpenseTitle) || errors.Is(err, services.ErrInvalidGeotag) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 	ctx.JSON(http.StatusOK, gin.H{"message": "updated"})
 }
 
+// Patch applies a partial update; only the fields present in the body change
+func (c *ExpenseController) Patch(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expense ID"})
+		return
+	}
+
+	var patch services.ExpensePatch
+	if err := ctx.ShouldBindJSON(&patch); err != nil {
+		respondBindingError(ctx, err)
+		return
+	}
+
+	expense, err := c.S.Patch(uint(id), ctx.GetUint("userID"), patch)
+	switch {
+	case errors.Is(err, services.ErrEmptyPatch), errors.Is(err, services.ErrInvalidExpenseType), errors.Is(err, services.ErrEmptyExpenseTitle), errors.Is(err, services.ErrInvalidGeotag):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "expense not found"})
+		return
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, expense)
+}
+
 func (c *ExpenseController) Delete(ctx *gin.Context) {
 	id, _ := strconv.Atoi(ctx.Param("id"))
 	if err := c.S.Delete(uint(id), ctx.GetUint("userID")); err != nil {
@@ -68,13 +115,82 @@ func (c *ExpenseController) Delete(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "deleted"})
 }
 
+// ListTrash returns the user's soft-deleted expenses
+func (c *ExpenseController) ListTrash(ctx *gin.Context) {
+	data, err := c.S.ListTrash(ctx.GetUint("userID"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, data)
+}
+
+// Restore brings a soft-deleted expense back out of the trash
+func (c *ExpenseController) Restore(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid expense id"})
+		return
+	}
+	exp, err := c.S.Restore(uint(id), ctx.GetUint("userID"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "expense not found in trash"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, exp)
+}
+
+// Recategorize re-runs categorization over uncategorized expenses and
+// reports how many were matched, updated and failed
 func (c *ExpenseController) Recategorize(ctx *gin.Context) {
 	uid := ctx.GetUint("userID")
-	if err := c.S.RecategorizeAll(uid); err != nil {
+	result, err := c.S.RecategorizeAll(uid)
+	if err != nil && result.Updated == 0 && result.Synced == 0 {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{"message": "Transactions recategorized successfully"})
+	response := gin.H{
+		"message": "Transactions recategorized successfully",
+		"matched": result.Matched,
+		"updated": result.Updated,
+		"failed":  result.Failed,
+		"synced":  result.Synced,
+	}
+	if err != nil {
+		// Some batches were saved; report the rest instead of failing the request
+		response["message"] = "Transactions partially recategorized"
+		response["error"] = err.Error()
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// RecategorizeByRule moves every expense matching a matcher into a category,
+// optionally saving the matcher as a category rule
+func (c *ExpenseController) RecategorizeByRule(ctx *gin.Context) {
+	var in struct {
+		Matcher     string `json:"matcher" binding:"required"`
+		Category    string `json:"category" binding:"required"`
+		Subcategory string `json:"subcategory"`
+		SaveRule    bool   `json:"save_rule"`
+	}
+	if err := ctx.ShouldBindJSON(&in); err != nil {
+		respondBindingError(ctx, err)
+		return
+	}
+
+	result, err := c.S.RecategorizeByRule(ctx.GetUint("userID"), in.Matcher, in.Category, in.Subcategory, in.SaveRule)
+	switch {
+	case errors.Is(err, services.ErrInvalidCategoryRule), errors.Is(err, services.ErrInvalidRuleRegex):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusOK, result)
+	}
 }
 
 // GetByDateRange efficiently retrieves expenses within a date range
@@ -116,22 +232,14 @@ func (c *ExpenseController) GetByCategory(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, expenses)
 }
 
-// MigrateExpensesToTransactions migrates existing expenses to transaction records
+// MigrateExpensesToTransactions creates missing transaction records for the
+// caller's own expenses
 func (c *ExpenseController) MigrateExpensesToTransactions(ctx *gin.Context) {
-	if err := c.S.MigrateExistingExpensesToTransactions(); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	ctx.JSON(http.StatusOK, gin.H{"message": "Migration completed successfully"})
-}
-
-// MigrateAllExpensesToTransactions migrates ALL expenses to transaction records (no auth required for testing)
-func (c *ExpenseController) MigrateAllExpensesToTransactions(ctx *gin.Context) {
-	if err := c.S.MigrateExistingExpensesToTransactions(); err != nil {
+	result, err := c.S.MigrateUserExpensesToTransactions(ctx.Request.Context(), ctx.GetUint("userID"))
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Migration completed successfully"})
+	ctx.JSON(http.StatusOK, gin.H{"message": "Migration completed successfully", "result": result})
 }
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+	"github.com/your-github/expense-tracker-backend/middleware"
+	"github.com/your-github/expense-tracker-backend/services"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+func TestPatchExpenseValidatesEachField(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, stub := testutil.NewStubDB(t)
+	service := services.NewExpenseService(db, 1)
+	t.Cleanup(service.Close)
+	r := gin.New()
+	r.PATCH("/api/expenses/:id", func(ctx *gin.Context) {
+		ctx.Set(middleware.ContextUserID, uint(7))
+		(&ExpenseController{S: service}).Patch(ctx)
+	})
+
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"amount": -5}`, `"rule":"gt"`},
+		{`{"type": "gift"}`, `"rule":"oneof"`},
+		{`{"date": "01/03/2025"}`, `"rule":"datetime"`},
+		{`{"title": ""}`, `"rule":"min"`},
+		{`{}`, "no fields to update"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/api/expenses/3", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("PATCH %s = %d %s, want 400 with %s", tt.body, w.Code, w.Body, tt.want)
+		}
+	}
+	if len(stub.Ran(`UPDATE "expenses"`)) != 0 {
+		t.Error("an invalid patch was written")
+	}
+}
+
+func TestRecategorizeReportsPartialSuccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`FROM "expenses"`, []string{"id", "title", "type", "category", "user_id"},
+		[]driver.Value{int64(1), "Swiggy dinner", "expense", "Other", int64(7)},
+		[]driver.Value{int64(2), "Uber to airport", "expense", "", int64(7)},
+	)
+	failing := utils.CategoryFood
+	stub.Handle(`UPDATE "expenses"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		if args[0] == failing {
+			return testutil.StubResult{}, errors.New("connection reset")
+		}
+		return testutil.StubResult{Affected: 1}, nil
+	})
+	// No mirror has drifted
+	stub.Handle(`UPDATE transactions SET category`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		return testutil.StubResult{}, nil
+	})
+	service := services.NewExpenseService(db, 1)
+	t.Cleanup(service.Close)
+	r := gin.New()
+	r.POST("/api/expenses/recategorize", func(ctx *gin.Context) {
+		ctx.Set(middleware.ContextUserID, uint(7))
+		(&ExpenseController{S: service}).Recategorize(ctx)
+	})
+	recategorize := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/expenses/recategorize", nil))
+		return w
+	}
+
+	w := recategorize()
+	if w.Code != http.StatusOK {
+		t.Fatalf("partial run = %d %s, want 200", w.Code, w.Body)
+	}
+	for _, field := range []string{`"matched":2`, `"updated":1`, `"failed":1`, `"message":"Transactions partially recategorized"`, `"error":"connection reset"`} {
+		if !strings.Contains(w.Body.String(), field) {
+			t.Errorf("partial run body %s, want %s", w.Body, field)
+		}
+	}
+
+	// With nothing saved the request fails
+	stub.Fail(`UPDATE "expenses"`, errors.New("connection reset"))
+	if w := recategorize(); w.Code != http.StatusInternalServerError {
+		t.Errorf("failed run = %d %s, want 500", w.Code, w.Body)
+	}
+}
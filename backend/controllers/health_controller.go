@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/robfig/cron/v3"
+	"github.com/your-github/expense-tracker-backend/utils"
+	"gorm.io/gorm"
+)
+
+// readinessPingTimeout bounds the database ping so probes answer quickly
+const readinessPingTimeout = 2 * time.Second
+
+type HealthController struct {
+	DB     *gorm.DB
+	Caches map[string]*utils.LRUCache
+	Cron   *cron.Cron
+}
+
+// Live reports that the process is up without touching dependencies
+func (c *HealthController) Live(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "healthy", "timestamp": time.Now().Unix()})
+}
+
+// Ready reports whether the API can serve traffic: 200 when the database
+// answers a ping, 503 otherwise. Cache and scheduler state is included for
+// diagnostics but does not affect the status.
+func (c *HealthController) Ready(ctx *gin.Context) {
+	status := http.StatusOK
+	database := gin.H{"status": "ok"}
+
+	start := time.Now()
+	if err := pingDB(ctx.Request.Context(), c.DB); err != nil {
+		status = http.StatusServiceUnavailable
+		database = gin.H{"status": "error", "error": err.Error()}
+	}
+	database["latency_ms"] = time.Since(start).Milliseconds()
+
+	caches := gin.H{}
+	for name, cache := range c.Caches {
+		caches[name] = cache.GetStats()
+	}
+
+	scheduler := gin.H{"status": "disabled"}
+	if c.Cron != nil {
+		entries := c.Cron.Entries()
+		scheduler = gin.H{"status": "ok", "jobs": len(entries)}
+		if len(entries) > 0 {
+			scheduler["next_run"] = entries[0].Next
+		}
+	}
+
+	overall := "ready"
+	if status != http.StatusOK {
+		overall = "unavailable"
+	}
+	ctx.JSON(status, gin.H{
+		"status":    overall,
+		"timestamp": time.Now().Unix(),
+		"checks": gin.H{
+			"database": database,
+			"caches":   caches,
+			"cron":     scheduler,
+		},
+	})
+}
+
+// pingDB checks the connection pool can reach the database
+func pingDB(ctx context.Context, db *gorm.DB) error {
+	ctx, cancel := context.WithTimeout(ctx, readinessPingTimeout)
+	defer cancel()
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+// probe serves a health handler and decodes its response
+func probe(t *testing.T, handler gin.HandlerFunc) (int, map[string]interface{}) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", handler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %s: %v", w.Body, err)
+	}
+	return w.Code, body
+}
+
+func TestReadyWithReachableDatabase(t *testing.T) {
+	db, _ := testutil.NewStubDB(t)
+	cache := utils.NewLRUCache(10, time.Minute)
+	c := &HealthController{DB: db, Caches: map[string]*utils.LRUCache{"expenses": cache}}
+
+	code, body := probe(t, c.Ready)
+	if code != http.StatusOK || body["status"] != "ready" {
+		t.Fatalf("Ready = %d %v, want 200 ready", code, body)
+	}
+	checks := body["checks"].(map[string]interface{})
+	if checks["database"].(map[string]interface{})["status"] != "ok" {
+		t.Errorf("database check = %v", checks["database"])
+	}
+	if _, ok := checks["caches"].(map[string]interface{})["expenses"]; !ok {
+		t.Errorf("caches check = %v, want the expenses cache", checks["caches"])
+	}
+	if checks["cron"].(map[string]interface{})["status"] != "disabled" {
+		t.Errorf("cron check = %v, want disabled without a scheduler", checks["cron"])
+	}
+}
+
+func TestReadyWithClosedPool(t *testing.T) {
+	db, _ := testutil.NewStubDB(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+	c := &HealthController{DB: db}
+
+	code, body := probe(t, c.Ready)
+	if code != http.StatusServiceUnavailable || body["status"] != "unavailable" {
+		t.Errorf("Ready = %d %v, want 503 unavailable", code, body)
+	}
+	database := body["checks"].(map[string]interface{})["database"].(map[string]interface{})
+	if database["status"] != "error" || database["error"] == "" {
+		t.Errorf("database check = %v, want the ping error", database)
+	}
+
+	// Liveness doesn't depend on the database
+	if code, _ := probe(t, c.Live); code != http.StatusOK {
+		t.Errorf("Live = %d with a closed pool, want 200", code)
+	}
+}
@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+type IFSCController struct {
+	Lookup utils.IFSCLookup
+}
+
+// Get validates an IFSC code and returns the bank and branch it belongs to
+func (c *IFSCController) Get(ctx *gin.Context) {
+	code, err := utils.ValidateIFSC(ctx.Param("code"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	details, err := c.Lookup.Lookup(code)
+	if errors.Is(err, utils.ErrIFSCNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "No bank found for IFSC " + code})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up IFSC"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, details)
+}
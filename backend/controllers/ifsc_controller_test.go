@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+func TestIFSCLookupEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/ifsc/:code", (&IFSCController{Lookup: utils.NewStaticIFSCLookup(utils.Banks)}).Get)
+
+	tests := []struct {
+		code   string
+		status int
+		body   string
+	}{
+		{"icic0000104", http.StatusOK, `"bankId":"icici"`},
+		{"ICIC0000104", http.StatusOK, `"ifsc":"ICIC0000104"`},
+		{"ICIC1000104", http.StatusBadRequest, "IFSC must be 11 characters"},
+		{"ZZZZ0000001", http.StatusNotFound, "No bank found"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/ifsc/"+tt.code, nil))
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("GET %s = %d %s, want %d containing %s", tt.code, w.Code, w.Body, tt.status, tt.body)
+		}
+	}
+}
@@ -1,15 +1,30 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/your-github/expense-tracker-backend/database"
 	"github.com/your-github/expense-tracker-backend/models"
+	"github.com/your-github/expense-tracker-backend/services"
+	"github.com/your-github/expense-tracker-backend/utils"
+	"gorm.io/gorm"
 )
 
-type ProfileController struct{}
+type ProfileController struct {
+	Auth    *services.AuthService // email changes reuse the signup OTP flow
+	Summary *services.SummaryService
+	Rates   utils.RateProvider
+	Exports *services.ExportService
+}
 
 func (c *ProfileController) Get(ctx *gin.Context) {
 	uid := ctx.GetUint("userID")
@@ -39,19 +54,276 @@ func (c *ProfileController) Get(ctx *gin.Context) {
 	}
 
 	profileData := gin.H{
-		"id":                 user.ID,
-		"name":               user.Name,
-		"email":              user.Email,
-		"budget":             user.Budget,
-		"created_at":         user.CreatedAt,
-		"member_since":       memberSince,
-		"total_transactions": totalTransactions,
-		"account_status":     "Active",
+		"id":                  user.ID,
+		"name":                user.Name,
+		"email":               user.Email,
+		"pending_email":       user.PendingEmail,
+		"budget":              user.Budget,
+		"budget_period":       services.UserBudgetPeriod(user).Type,
+		"budget_period_start": user.BudgetPeriodStart,
+		"budget_period_days":  user.BudgetPeriodDays,
+		"currency":            utils.NormalizeCurrency(user.Currency),
+		"timezone":            utils.Location(user.Timezone).String(),
+		"report_opt_in":       user.ReportOptIn,
+		"report_cadence":      reportCadence(user),
+		"created_at":          user.CreatedAt,
+		"member_since":        memberSince,
+		"total_transactions":  totalTransactions,
+		"account_status":      "Active",
 	}
 
 	ctx.JSON(http.StatusOK, profileData)
 }
 
+// reportCadence returns the user's report cadence, monthly when unset
+func reportCadence(user models.User) string {
+	if user.ReportCadence == "" {
+		return services.ReportCadenceMonthly
+	}
+	return user.ReportCadence
+}
+
+// profileUpdate is the body of PUT /profile. Omitted fields are left as they are.
+type profileUpdate struct {
+	Name          *string  `json:"name" binding:"omitempty,max=100"`
+	Budget        *float64 `json:"budget" binding:"omitempty,gt=0"`
+	Timezone      *string  `json:"timezone"`
+	Currency      *string  `json:"currency" binding:"omitempty,iso4217"`
+	ReportOptIn   *bool    `json:"report_opt_in"`
+	ReportCadence *string  `json:"report_cadence" binding:"omitempty,oneof=weekly monthly"`
+
+	// The budget period is validated as a whole, with omitted parts taken
+	// from the current settings
+	BudgetPeriod      *string `json:"budget_period"`
+	BudgetPeriodStart *string `json:"budget_period_start"`
+	BudgetPeriodDays  *int    `json:"budget_period_days"`
+}
+
+// profileUpdateFields are the JSON fields PUT /profile accepts. Email and
+// password have their own flows and are rejected along with any other field.
+var profileUpdateFields = map[string]bool{
+	"name": true, "budget": true, "timezone": true, "currency": true,
+	"report_opt_in": true, "report_cadence": true,
+	"budget_period": true, "budget_period_start": true, "budget_period_days": true,
+}
+
+// Update changes the user's name, budget and preferences, returning the
+// updated profile
+func (c *ProfileController) Update(ctx *gin.Context) {
+	var raw map[string]json.RawMessage
+	if err := ctx.ShouldBindBodyWith(&raw, binding.JSON); err != nil {
+		respondBindingError(ctx, err)
+		return
+	}
+	var rejected []string
+	for field := range raw {
+		if !profileUpdateFields[field] {
+			rejected = append(rejected, field)
+		}
+	}
+	if len(rejected) > 0 {
+		sort.Strings(rejected)
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":  "these fields cannot be updated here: " + strings.Join(rejected, ", "),
+			"fields": rejected,
+		})
+		return
+	}
+
+	var in profileUpdate
+	if err := ctx.ShouldBindBodyWith(&in, binding.JSON); err != nil {
+		respondBindingError(ctx, err)
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if in.Name != nil {
+		name := utils.SanitizeText(*in.Name, false)
+		if name == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+			return
+		}
+		updates["name"] = name
+	}
+	if in.Budget != nil {
+		updates["budget"] = *in.Budget
+	}
+	if in.Timezone != nil {
+		if !utils.ValidTimezone(*in.Timezone) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "unknown timezone: " + *in.Timezone})
+			return
+		}
+		updates["timezone"] = *in.Timezone
+	}
+	if in.Currency != nil {
+		// Only accept currencies we can actually convert into
+		if _, err := utils.ConvertAmount(c.Rates, 1, utils.DefaultCurrency, *in.Currency); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "unsupported currency: " + *in.Currency})
+			return
+		}
+		updates["currency"] = *in.Currency
+	}
+	if in.ReportOptIn != nil {
+		updates["report_opt_in"] = *in.ReportOptIn
+	}
+	if in.ReportCadence != nil {
+		updates["report_cadence"] = *in.ReportCadence
+	}
+
+	uid := ctx.GetUint("userID")
+	if in.BudgetPeriod != nil || in.BudgetPeriodStart != nil || in.BudgetPeriodDays != nil {
+		var user models.User
+		if err := database.DB.Select("budget_period", "budget_period_start", "budget_period_days").First(&user, uid).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+			return
+		}
+		periodType, start, days := user.BudgetPeriod, user.BudgetPeriodStart, user.BudgetPeriodDays
+		if in.BudgetPeriod != nil {
+			periodType = *in.BudgetPeriod
+		}
+		if in.BudgetPeriodStart != nil {
+			start = *in.BudgetPeriodStart
+		}
+		if in.BudgetPeriodDays != nil {
+			days = *in.BudgetPeriodDays
+		}
+		period, err := services.NewBudgetPeriod(periodType, start, days)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// Only the parts the period type uses are kept
+		updates["budget_period"] = period.Type
+		updates["budget_period_start"] = ""
+		updates["budget_period_days"] = 0
+		if period.Type != services.BudgetPeriodMonthly && start != "" {
+			updates["budget_period_start"] = period.Start.Format("2006-01-02")
+		}
+		if period.Type == services.BudgetPeriodCustom {
+			updates["budget_period_days"] = period.Days
+		}
+	}
+	if len(updates) > 0 {
+		if err := database.DB.Model(&models.User{}).Where("id = ?", uid).Updates(updates).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+			return
+		}
+	}
+	// Summaries are cached per budget, currency and timezone
+	if in.Budget != nil || in.Currency != nil || in.Timezone != nil {
+		c.Summary.InvalidateUserCache(uid)
+	}
+
+	c.Get(ctx)
+}
+
+// SetTimezone updates the IANA timezone the user's dates are interpreted
+// in, which decides the current month and day for summaries and filters
+func (c *ProfileController) SetTimezone(ctx *gin.Context) {
+	var in struct {
+		Timezone string `json:"timezone" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&in); err != nil {
+		respondBindingError(ctx, err)
+		return
+	}
+	if !utils.ValidTimezone(in.Timezone) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "unknown timezone: " + in.Timezone})
+		return
+	}
+
+	uid := ctx.GetUint("userID")
+	if err := database.DB.Model(&models.User{}).Where("id = ?", uid).Update("timezone", in.Timezone).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update timezone"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"timezone": in.Timezone})
+}
+
+// RequestEmailChange starts switching the user's email: the new address is
+// checked for uniqueness and sent an OTP, and the current email stays in
+// use until VerifyEmailChange
+func (c *ProfileController) RequestEmailChange(ctx *gin.Context) {
+	var in struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := ctx.ShouldBindJSON(&in); err != nil {
+		respondBindingError(ctx, err)
+		return
+	}
+
+	err := c.Auth.RequestEmailChange(ctx.GetUint("userID"), in.Email)
+	if respondOTPRateLimited(ctx, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrEmailInUse):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSameEmail):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start email change"})
+	default:
+		ctx.JSON(http.StatusAccepted, gin.H{
+			"message":       "Verification code sent to the new email. Your current email stays active until it is verified.",
+			"pending_email": strings.TrimSpace(in.Email),
+		})
+	}
+}
+
+// VerifyEmailChange commits the pending email change once the OTP sent to
+// the new address is confirmed
+func (c *ProfileController) VerifyEmailChange(ctx *gin.Context) {
+	var in struct {
+		OTP string `json:"otp" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&in); err != nil {
+		respondBindingError(ctx, err)
+		return
+	}
+
+	user, err := c.Auth.ConfirmEmailChange(ctx.GetUint("userID"), in.OTP)
+	switch {
+	case errors.Is(err, services.ErrEmailInUse):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case err != nil:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusOK, gin.H{"message": "Email updated successfully", "email": user.Email})
+	}
+}
+
+// Export downloads everything stored about the user as one JSON file
+func (c *ProfileController) Export(ctx *gin.Context) {
+	uid := ctx.GetUint("userID")
+	filename := fmt.Sprintf("expense-tracker-export-%s.json", time.Now().Format("2006-01-02"))
+	ctx.Header("Content-Type", "application/json; charset=utf-8")
+	ctx.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	err := c.Exports.Export(ctx.Request.Context(), uid, ctx.Writer)
+	if err == nil {
+		return
+	}
+	if ctx.Writer.Written() {
+		// The download has started, so a truncated body is all that can
+		// signal the failure
+		log.Printf("Export for user %d failed after streaming began: %v", uid, err)
+		ctx.Abort()
+		return
+	}
+	ctx.Writer.Header().Del("Content-Disposition")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export account data"})
+}
+
 func (c *ProfileController) Delete(ctx *gin.Context) {
 	uid := ctx.GetUint("userID")
 
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/database"
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+	"github.com/your-github/expense-tracker-backend/middleware"
+	"github.com/your-github/expense-tracker-backend/services"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+func TestEmailChangeStatuses(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`FROM "users"`, []string{"id", "email", "pending_email"}, []driver.Value{int64(7), "asha@example.com", ""})
+	stub.On(`SELECT count(*) FROM "users"`, []string{"count"}, []driver.Value{int64(1)})
+	controller := &ProfileController{Auth: &services.AuthService{DB: db, EmailSvc: &services.EmailService{}}}
+
+	tests := []struct {
+		name    string
+		handler gin.HandlerFunc
+		body    string
+		want    int
+	}{
+		{"invalid email", controller.RequestEmailChange, `{"email":"not-an-email"}`, http.StatusBadRequest},
+		{"current email", controller.RequestEmailChange, `{"email":"asha@example.com"}`, http.StatusBadRequest},
+		{"email in use", controller.RequestEmailChange, `{"email":"ravi@example.com"}`, http.StatusConflict},
+		{"nothing pending", controller.VerifyEmailChange, `{"otp":"482913"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if code, body := postJSON(t, tt.handler, tt.body); code != tt.want {
+			t.Errorf("%s = %d %+v, want %d", tt.name, code, body, tt.want)
+		}
+	}
+	if len(stub.Ran(`UPDATE "users"`)) != 0 {
+		t.Error("a rejected request changed the user")
+	}
+}
+
+// newProfileFixture serves user 7 from a stub database installed as
+// database.DB; their cached summary is under "summary:7:2025-03"
+func newProfileFixture(t *testing.T) (*ProfileController, *testutil.StubDB) {
+	t.Helper()
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`FROM "users"`, []string{"id", "name", "email", "budget", "currency", "timezone"},
+		[]driver.Value{int64(7), "Asha", "asha@example.com", 20000.0, "INR", "Asia/Kolkata"})
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() { database.DB = previous })
+
+	summary := services.NewSummaryService(db, nil, 1)
+	t.Cleanup(summary.Close)
+	summary.Cache.Set("summary:7:2025-03", "cached")
+	return &ProfileController{Summary: summary, Rates: utils.StaticRateProvider{}}, stub
+}
+
+func hasArg(args []driver.Value, want driver.Value) bool {
+	for _, arg := range args {
+		if arg == want {
+			return true
+		}
+	}
+	return false
+}
+
+// putProfile sends body to PUT /api/profile as user 7
+func putProfile(controller *ProfileController, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PUT("/api/profile", func(ctx *gin.Context) {
+		ctx.Set(middleware.ContextUserID, uint(7))
+		controller.Update(ctx)
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/api/profile", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestProfileUpdate(t *testing.T) {
+	controller, stub := newProfileFixture(t)
+
+	w := putProfile(controller, `{"name":"  Asha R\u0000 ","report_opt_in":true,"report_cadence":"weekly"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"email":"asha@example.com"`) {
+		t.Fatalf("update = %d %s, want the profile back", w.Code, w.Body)
+	}
+	updates := stub.Ran(`UPDATE "users"`)
+	if len(updates) != 1 || !hasArg(updates[0].Args, "Asha R") || !hasArg(updates[0].Args, true) || !hasArg(updates[0].Args, "weekly") {
+		t.Fatalf("updates = %+v, want the sanitized name and report settings", updates)
+	}
+	if _, cached := controller.Summary.Cache.Get("summary:7:2025-03"); !cached {
+		t.Error("a name change dropped the cached summary")
+	}
+
+	w = putProfile(controller, `{"budget":25000,"currency":"USD","timezone":"Europe/London"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update = %d %s", w.Code, w.Body)
+	}
+	updates = stub.Ran(`UPDATE "users"`)
+	if len(updates) != 2 || !hasArg(updates[1].Args, 25000.0) || !hasArg(updates[1].Args, "USD") || !hasArg(updates[1].Args, "Europe/London") {
+		t.Errorf("updates = %+v, want budget, currency and timezone written", updates[1:])
+	}
+	if _, cached := controller.Summary.Cache.Get("summary:7:2025-03"); cached {
+		t.Error("the cached summary survived a budget change")
+	}
+}
+
+func TestProfileUpdateRejections(t *testing.T) {
+	controller, stub := newProfileFixture(t)
+
+	tests := []struct {
+		name, body string
+	}{
+		{"email", `{"email":"new@example.com"}`},
+		{"password alongside allowed fields", `{"name":"Asha","password":"hunter22"}`},
+		{"role", `{"role":"admin"}`},
+		{"empty name", `{"name":" \u202e "}`},
+		{"non-positive budget", `{"budget":-5}`},
+		{"unknown timezone", `{"timezone":"Mars/Olympus"}`},
+		{"unknown currency", `{"currency":"XYZ"}`},
+		{"unknown cadence", `{"report_cadence":"daily"}`},
+		{"not an object", `["name"]`},
+	}
+	for _, tt := range tests {
+		if w := putProfile(controller, tt.body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d %s, want 400", tt.name, w.Code, w.Body)
+		}
+	}
+	if len(stub.Ran(`UPDATE "users"`)) != 0 {
+		t.Error("a rejected update was written")
+	}
+
+	w := putProfile(controller, `{"email":"new@example.com","password":"x"}`)
+	if !strings.Contains(w.Body.String(), `"fields":["email","password"]`) {
+		t.Errorf("body = %s, want the immutable fields named", w.Body)
+	}
+}
+
+func TestProfileUpdateBudgetPeriod(t *testing.T) {
+	controller, stub := newProfileFixture(t)
+
+	tests := []struct {
+		name string
+		body string
+		want []driver.Value // period type, start and days written
+	}{
+		{"custom", `{"budget_period":"custom","budget_period_start":"2025-03-05","budget_period_days":10}`, []driver.Value{"custom", "2025-03-05", 10}},
+		{"weekly drops the days", `{"budget_period":"weekly","budget_period_days":10}`, []driver.Value{"weekly", "", 0}},
+		{"weekly from a start date", `{"budget_period":"weekly","budget_period_start":"2025-03-06"}`, []driver.Value{"weekly", "2025-03-06", 0}},
+		{"monthly drops the start", `{"budget_period":"monthly","budget_period_start":"2025-03-06"}`, []driver.Value{"monthly", "", 0}},
+	}
+	for _, tt := range tests {
+		w := putProfile(controller, tt.body)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s = %d %s, want 200", tt.name, w.Code, w.Body)
+			continue
+		}
+		updates := stub.Ran(`UPDATE "users"`)
+		last := updates[len(updates)-1]
+		for _, want := range tt.want {
+			if !hasArg(last.Args, want) {
+				t.Errorf("%s wrote %v, want %v", tt.name, last.Args, tt.want)
+				break
+			}
+		}
+	}
+
+	written := len(stub.Ran(`UPDATE "users"`))
+	for _, body := range []string{
+		`{"budget_period":"daily"}`,
+		`{"budget_period":"custom","budget_period_days":10}`,
+		`{"budget_period":"custom","budget_period_start":"2025-03-05","budget_period_days":400}`,
+		`{"budget_period":"weekly","budget_period_start":"05/03/2025"}`,
+	} {
+		if w := putProfile(controller, body); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid budget period") {
+			t.Errorf("%s = %d %s, want 400", body, w.Code, w.Body)
+		}
+	}
+	if len(stub.Ran(`UPDATE "users"`)) != written {
+		t.Error("an invalid budget period was written")
+	}
+}
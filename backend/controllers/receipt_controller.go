@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/services"
+)
+
+// multipartOverhead is the slack allowed on top of the file size limit for
+// multipart boundaries and headers
+const multipartOverhead = 64 * 1024
+
+type ReceiptController struct{ S *services.ReceiptService }
+
+// Upload attaches a receipt (multipart field "file") to an expense
+func (c *ReceiptController) Upload(ctx *gin.Context) {
+	expenseID, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, c.S.MaxSize+multipartOverhead)
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrReceiptTooLarge.Error()})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "multipart field \"file\" is required"})
+		return
+	}
+	if fileHeader.Size > c.S.MaxSize {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrReceiptTooLarge.Error()})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "could not read uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	receipt, err := c.S.Upload(ctx.Request.Context(), ctx.GetUint("userID"), expenseID, fileHeader.Filename, file)
+	if err != nil {
+		respondReceiptError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, receipt)
+}
+
+// List returns the receipts attached to an expense
+func (c *ReceiptController) List(ctx *gin.Context) {
+	expenseID, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	receipts, err := c.S.List(ctx.GetUint("userID"), expenseID)
+	if err != nil {
+		respondReceiptError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, receipts)
+}
+
+// Download streams a receipt back to its owner
+func (c *ReceiptController) Download(ctx *gin.Context) {
+	expenseID, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	receiptID, ok := parseIDParam(ctx, "receiptId")
+	if !ok {
+		return
+	}
+
+	receipt, body, err := c.S.Open(ctx.Request.Context(), ctx.GetUint("userID"), expenseID, receiptID)
+	if err != nil {
+		respondReceiptError(ctx, err)
+		return
+	}
+	defer body.Close()
+
+	ctx.DataFromReader(http.StatusOK, receipt.Size, receipt.ContentType, body, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": receipt.Filename}),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+func respondReceiptError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrReceiptTooLarge):
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrReceiptType):
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrReceiptEmpty):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrReceiptExpenseNotFound), errors.Is(err, services.ErrReceiptNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+	"github.com/your-github/expense-tracker-backend/middleware"
+	"github.com/your-github/expense-tracker-backend/services"
+)
+
+// newReceiptRouter serves the receipt routes as user 7, who owns every
+// expense; uploaded rows are kept in memory
+func newReceiptRouter(t *testing.T, maxSize int64) *gin.Engine {
+	t.Helper()
+	db, stub := testutil.NewStubDB(t)
+	var rows []map[string]driver.Value
+	stub.On(`FROM "expenses"`, []string{"count"}, []driver.Value{int64(1)})
+	stub.Handle(`INSERT INTO "receipts"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		row := testutil.InsertedValues(query, args)
+		row["id"] = int64(len(rows) + 1)
+		rows = append(rows, row)
+		return testutil.StubResult{Columns: []string{"id"}, Rows: [][]driver.Value{{row["id"]}}}, nil
+	})
+	stub.Handle(`FROM "receipts"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		result := testutil.StubResult{Columns: []string{"id", "expense_id", "user_id", "filename", "content_type", "storage_key", "size"}}
+		for _, row := range rows {
+			if row["id"] == int64(args[0].(uint)) {
+				result.Rows = append(result.Rows, []driver.Value{
+					row["id"], row["expense_id"], row["user_id"], row["filename"], row["content_type"], row["storage_key"], row["size"],
+				})
+			}
+		}
+		return result, nil
+	})
+
+	controller := &ReceiptController{S: services.NewReceiptService(db, &services.LocalReceiptStore{Dir: t.TempDir()}, maxSize)}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(ctx *gin.Context) { ctx.Set(middleware.ContextUserID, uint(7)) })
+	r.POST("/api/expenses/:id/receipts", controller.Upload)
+	r.GET("/api/expenses/:id/receipts/:receiptId", controller.Download)
+	return r
+}
+
+// uploadReceipt posts data as the multipart field "file"
+func uploadReceipt(t *testing.T, r *gin.Engine, filename string, data []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/expenses/3/receipts", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestReceiptUploadDownloadRoundTrip(t *testing.T) {
+	r := newReceiptRouter(t, 1024)
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{1}, 100)...)
+
+	w := uploadReceipt(t, r, "taxi.png", png)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("upload = %d %s", w.Code, w.Body)
+	}
+	var receipt struct {
+		ID          uint   `json:"ID"`
+		ContentType string `json:"content_type"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &receipt)
+	if receipt.ID == 0 || receipt.ContentType != "image/png" {
+		t.Fatalf("upload returned %s", w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/expenses/3/receipts/1", nil))
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), png) {
+		t.Fatalf("download = %d with %d bytes, want the uploaded file", w.Code, w.Body.Len())
+	}
+	if got := w.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename=taxi.png` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	if got := w.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("X-Content-Type-Options = %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/expenses/3/receipts/2", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown receipt = %d, want 404", w.Code)
+	}
+}
+
+func TestReceiptUploadRejectsOversizedAndUnsupportedFiles(t *testing.T) {
+	r := newReceiptRouter(t, 1024)
+
+	oversized := append([]byte("%PDF-1.4\n"), bytes.Repeat([]byte("x"), 2048)...)
+	if w := uploadReceipt(t, r, "big.pdf", oversized); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized upload = %d %s, want 413", w.Code, w.Body)
+	}
+	// Far past the multipart slack the body is cut off while parsing
+	huge := bytes.Repeat([]byte("x"), 2*multipartOverhead)
+	if w := uploadReceipt(t, r, "huge.pdf", huge); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("huge upload = %d %s, want 413", w.Code, w.Body)
+	}
+	if w := uploadReceipt(t, r, "notes.txt", []byte("just some text")); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("text upload = %d %s, want 415", w.Code, w.Body)
+	}
+}
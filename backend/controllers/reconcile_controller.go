@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/services"
+	"gorm.io/gorm"
+)
+
+type ReconcileController struct{ S *services.ReconcileService }
+
+// List returns manual expenses that likely duplicate an imported bank
+// transaction. ?amount_tolerance= and ?days= widen or narrow the match.
+func (c *ReconcileController) List(ctx *gin.Context) {
+	amountTolerance := services.DefaultReconcileAmountTolerance
+	if raw := ctx.Query("amount_tolerance"); raw != "" {
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil || parsed < 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "amount_tolerance must be a non-negative number"})
+			return
+		}
+		amountTolerance = parsed
+	}
+	days := services.DefaultReconcileDayTolerance
+	if raw := ctx.Query("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 || parsed > services.MaxReconcileDayTolerance {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 0 and " + strconv.Itoa(services.MaxReconcileDayTolerance)})
+			return
+		}
+		days = parsed
+	}
+
+	duplicates, err := c.S.FindDuplicates(ctx.Request.Context(), ctx.GetUint("userID"), amountTolerance, days)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"duplicates": duplicates, "count": len(duplicates)})
+}
+
+// Resolve settles a duplicate pair: "merge" keeps the expense with the bank
+// transaction's amount and date, "keep_expense" drops the bank transaction
+// and "keep_transaction" moves the expense to the trash
+func (c *ReconcileController) Resolve(ctx *gin.Context) {
+	var in struct {
+		ExpenseID     uint   `json:"expense_id" binding:"required"`
+		TransactionID uint   `json:"transaction_id" binding:"required"`
+		Action        string `json:"action" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&in); err != nil {
+		respondBindingError(ctx, err)
+		return
+	}
+
+	err := c.S.Resolve(ctx.Request.Context(), ctx.GetUint("userID"), in.ExpenseID, in.TransactionID, in.Action)
+	switch {
+	case errors.Is(err, services.ErrInvalidReconcileAction), errors.Is(err, services.ErrNotBankTransaction):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "expense or transaction not found"})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusOK, gin.H{"message": "Duplicate resolved", "action": in.Action})
+	}
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"strings"
+	"testing"
+
+	aaservices "github.com/your-github/expense-tracker-backend/internal/core/services"
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+	"github.com/your-github/expense-tracker-backend/services"
+)
+
+func newReconcileController(t *testing.T) (*ReconcileController, *testutil.StubDB) {
+	t.Helper()
+	db, stub := testutil.NewStubDB(t)
+	expenses := services.NewExpenseService(db, 1)
+	t.Cleanup(expenses.Close)
+	return &ReconcileController{S: services.NewReconcileService(db, expenses, aaservices.NewNormalizer())}, stub
+}
+
+func TestReconcileListValidatesTolerances(t *testing.T) {
+	controller, stub := newReconcileController(t)
+
+	w := getAsUser(controller.List, "/api/transactions?amount_tolerance=0.5&days=3")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"duplicates":[]`) || !strings.Contains(w.Body.String(), `"count":0`) {
+		t.Errorf("list = %d %s, want an empty list", w.Code, w.Body)
+	}
+	if len(stub.Ran(`FROM "expenses"`)) != 1 {
+		t.Error("the expenses weren't looked up")
+	}
+
+	for _, query := range []string{"amount_tolerance=-1", "amount_tolerance=lots", "days=-1", "days=8", "days=two"} {
+		if w := getAsUser(controller.List, "/api/transactions?"+query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", query, w.Code)
+		}
+	}
+}
+
+func TestReconcileResolveStatuses(t *testing.T) {
+	controller, stub := newReconcileController(t)
+
+	if code, _ := postJSON(t, controller.Resolve, `{"expense_id":1,"transaction_id":11,"action":"both"}`); code != http.StatusBadRequest {
+		t.Errorf("unknown action = %d, want 400", code)
+	}
+	if code, out := postJSON(t, controller.Resolve, `{"expense_id":1,"action":"merge"}`); code != http.StatusBadRequest || rulesByField(out.Errors)["transaction_id"] != "required" {
+		t.Errorf("missing transaction = %d %+v, want a required error", code, out)
+	}
+	if code, _ := postJSON(t, controller.Resolve, `{"expense_id":1,"transaction_id":11,"action":"merge"}`); code != http.StatusNotFound {
+		t.Errorf("unknown expense = %d, want 404", code)
+	}
+
+	stub.On(`FROM "expenses"`, []string{"id", "user_id", "title", "amount", "date", "type"}, []driver.Value{int64(1), int64(7), "Swiggy", 450.0, "2025-03-01", "expense"})
+	stub.On(`FROM "transactions"`, []string{"id", "user_id", "transaction_id", "amount", "type"}, []driver.Value{int64(11), int64(7), "TXN11", 450.0, "debit"})
+	if code, _ := postJSON(t, controller.Resolve, `{"expense_id":1,"transaction_id":11,"action":"keep_expense"}`); code != http.StatusOK {
+		t.Errorf("keep_expense = %d, want 200", code)
+	}
+}
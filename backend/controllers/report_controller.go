@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/database"
+	"github.com/your-github/expense-tracker-backend/models"
+)
+
+type ReportController struct{}
+
+// GetSettings returns the user's spending report preferences
+func (c *ReportController) GetSettings(ctx *gin.Context) {
+	var user models.User
+	if err := database.DB.First(&user, ctx.GetUint("userID")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"opt_in": user.ReportOptIn, "cadence": reportCadence(user)})
+}
+
+// UpdateSettings toggles the spending report and picks its cadence
+func (c *ReportController) UpdateSettings(ctx *gin.Context) {
+	var in struct {
+		OptIn   *bool  `json:"opt_in" binding:"required"`
+		Cadence string `json:"cadence" binding:"omitempty,oneof=weekly monthly"`
+	}
+	if err := ctx.ShouldBindJSON(&in); err != nil {
+		respondBindingError(ctx, err)
+		return
+	}
+
+	updates := map[string]interface{}{"report_opt_in": *in.OptIn}
+	if in.Cadence != "" {
+		updates["report_cadence"] = in.Cadence
+	}
+
+	uid := ctx.GetUint("userID")
+	if err := database.DB.Model(&models.User{}).Where("id = ?", uid).Updates(updates).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update report settings"})
+		return
+	}
+
+	c.GetSettings(ctx)
+}
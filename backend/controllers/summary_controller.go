@@ -1,7 +1,9 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
@@ -20,27 +22,76 @@ func NewSummaryController(summaryService *services.SummaryService) *SummaryContr
 }
 
 func (c *SummaryController) Get(ctx *gin.Context) {
-	now := time.Now()
 	uid := ctx.GetUint("userID")
+	now := time.Now().In(services.UserLocation(database.DB, uid))
 	budget := ctx.DefaultQuery("budget", "")
 	if budget == "" {
 		var user models.User
 		database.DB.First(&user, uid)
 		budget = fmt.Sprintf("%f", user.Budget)
 	}
+	refresh, ok := parseRefresh(ctx)
+	if !ok {
+		return
+	}
 	// parse budget
 	bud, _ := strconv.ParseFloat(budget, 64)
-	summary, _ := c.S.Monthly(uid, bud, now.Year(), now.Month())
+	summary, _ := c.S.Monthly(uid, bud, now.Year(), now.Month(), refresh)
+	ctx.JSON(http.StatusOK, summary)
+}
+
+// GetPeriod returns the totals of the user's active budget period (a month,
+// a week or a custom run of days) against their budget
+func (c *SummaryController) GetPeriod(ctx *gin.Context) {
+	uid := ctx.GetUint("userID")
+	refresh, ok := parseRefresh(ctx)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, uid).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load budget"})
+		return
+	}
+
+	summary, err := c.S.ActivePeriod(uid, user.Budget, services.UserBudgetPeriod(user), refresh)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	ctx.JSON(http.StatusOK, summary)
 }
 
 func (c *SummaryController) GetLifetime(ctx *gin.Context) {
 	uid := ctx.GetUint("userID")
-	summary, _ := c.S.Lifetime(uid)
+	refresh, ok := parseRefresh(ctx)
+	if !ok {
+		return
+	}
+	summary, _ := c.S.Lifetime(uid, refresh)
 	ctx.JSON(http.StatusOK, summary)
 }
 
-// GetCategoryBreakdown efficiently gets expense breakdown by category
+// parseRefresh reads ?refresh=true, which makes a summary skip the cache and
+// store a freshly computed value. It responds 400 and returns false when the
+// value isn't a boolean.
+func parseRefresh(ctx *gin.Context) (bool, bool) {
+	param := ctx.Query("refresh")
+	if param == "" {
+		return false, true
+	}
+	refresh, err := strconv.ParseBool(param)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "refresh must be true or false"})
+		return false, false
+	}
+	return refresh, true
+}
+
+// GetCategoryBreakdown efficiently gets expense breakdown by category.
+// ?top_n= keeps the N largest categories and ?min_amount= drops those below
+// it; what is left out is added to "Other".
 func (c *SummaryController) GetCategoryBreakdown(ctx *gin.Context) {
 	uid := ctx.GetUint("userID")
 	startDate := ctx.Query("start_date")
@@ -51,7 +102,30 @@ func (c *SummaryController) GetCategoryBreakdown(ctx *gin.Context) {
 		return
 	}
 
-	breakdown, err := c.S.GetCategoryBreakdown(uid, startDate, endDate)
+	refresh, ok := parseRefresh(ctx)
+	if !ok {
+		return
+	}
+
+	var rollup services.CategoryRollup
+	if raw := ctx.Query("top_n"); raw != "" {
+		topN, err := strconv.Atoi(raw)
+		if err != nil || topN < 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "top_n must be a positive integer"})
+			return
+		}
+		rollup.TopN = topN
+	}
+	if raw := ctx.Query("min_amount"); raw != "" {
+		minAmount, err := strconv.ParseFloat(raw, 64)
+		if err != nil || minAmount < 0 || math.IsInf(minAmount, 0) || math.IsNaN(minAmount) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "min_amount must be a non-negative number"})
+			return
+		}
+		rollup.MinAmount = minAmount
+	}
+
+	breakdown, err := c.S.GetCategoryBreakdown(uid, startDate, endDate, rollup, refresh)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -59,3 +133,90 @@ func (c *SummaryController) GetCategoryBreakdown(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, breakdown)
 }
+
+// GetLocations returns the user's spending per city, optionally limited to
+// ?start_date= and ?end_date= (YYYY-MM-DD)
+func (c *SummaryController) GetLocations(ctx *gin.Context) {
+	startDate := ctx.Query("start_date")
+	endDate := ctx.Query("end_date")
+	for _, date := range []string{startDate, endDate} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "start_date and end_date must be YYYY-MM-DD"})
+			return
+		}
+	}
+
+	spend, err := c.S.SpendByCity(ctx.GetUint("userID"), startDate, endDate)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, spend)
+}
+
+// maxTrendMonths bounds how far back GetTrends looks
+const maxTrendMonths = 36
+
+// GetTrends returns income, expenses and net savings per month or week
+func (c *SummaryController) GetTrends(ctx *gin.Context) {
+	uid := ctx.GetUint("userID")
+
+	granularity := ctx.DefaultQuery("granularity", services.TrendGranularityMonth)
+	if granularity != services.TrendGranularityMonth && granularity != services.TrendGranularityWeek {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "granularity must be month or week"})
+		return
+	}
+
+	months, err := strconv.Atoi(ctx.DefaultQuery("months", "12"))
+	if err != nil || months < 1 || months > maxTrendMonths {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("months must be between 1 and %d", maxTrendMonths)})
+		return
+	}
+
+	trends, err := c.S.Trends(uid, granularity, months)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, trends)
+}
+
+// Compare returns total and per-category spending deltas between the
+// months period_a and period_b (YYYY-MM), by default last month and this month
+func (c *SummaryController) Compare(ctx *gin.Context) {
+	uid := ctx.GetUint("userID")
+
+	comparison, err := c.S.Compare(uid, ctx.Query("period_a"), ctx.Query("period_b"))
+	if errors.Is(err, services.ErrInvalidPeriod) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, comparison)
+}
+
+// GetHealth returns the savings rate, expense ratio, category concentration
+// and composite health score computed from the user's lifetime totals
+func (c *SummaryController) GetHealth(ctx *gin.Context) {
+	uid := ctx.GetUint("userID")
+	refresh, ok := parseRefresh(ctx)
+	if !ok {
+		return
+	}
+
+	health, err := c.S.FinancialHealth(uid, refresh)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, health)
+}
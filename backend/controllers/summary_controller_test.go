@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/your-github/expense-tracker-backend/database"
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+	"github.com/your-github/expense-tracker-backend/services"
+)
+
+func TestSummaryRefreshParam(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`SELECT "currency" FROM "users"`, []string{"currency"}, []driver.Value{"INR"})
+	spent := 500.0
+	stub.Handle(`FROM expenses`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		return testutil.StubResult{
+			Columns: []string{"type", "category", "currency", "total"},
+			Rows:    [][]driver.Value{{"expense", "Travel", "INR", spent}},
+		}, nil
+	})
+	summary := services.NewSummaryService(db, nil, 1)
+	t.Cleanup(summary.Close)
+	controller := &SummaryController{S: summary}
+
+	if w := getAsUser(controller.GetLifetime, "/api/transactions"); !strings.Contains(w.Body.String(), `"total_expenses":500`) {
+		t.Fatalf("first read = %d %s", w.Code, w.Body)
+	}
+	spent = 800
+	if w := getAsUser(controller.GetLifetime, "/api/transactions"); !strings.Contains(w.Body.String(), `"total_expenses":500`) {
+		t.Errorf("cached read = %s, want the cached total", w.Body)
+	}
+	if w := getAsUser(controller.GetLifetime, "/api/transactions?refresh=true"); !strings.Contains(w.Body.String(), `"total_expenses":800`) {
+		t.Errorf("refresh = %s, want the fresh total", w.Body)
+	}
+	for _, bad := range []string{"maybe", "2"} {
+		w := getAsUser(controller.GetLifetime, "/api/transactions?refresh="+bad)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("refresh=%s = %d, want 400", bad, w.Code)
+		}
+	}
+}
+
+func TestGetHealth(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`SELECT "currency" FROM "users"`, []string{"currency"}, []driver.Value{"INR"})
+	stub.On(`FROM expenses`, []string{"type", "category", "currency", "total"},
+		[]driver.Value{"expense", "Travel", "INR", 60000.0},
+		[]driver.Value{"income", "Salary", "INR", 100000.0},
+	)
+	summary := services.NewSummaryService(db, nil, 1)
+	t.Cleanup(summary.Close)
+	controller := &SummaryController{S: summary}
+
+	w := getAsUser(controller.GetHealth, "/api/transactions")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d %s", w.Code, w.Body)
+	}
+	for _, field := range []string{`"savings_rate":40`, `"category_concentration":1`, `"score":75`, `"grade":"B"`} {
+		if !strings.Contains(w.Body.String(), field) {
+			t.Errorf("body %s, want %s", w.Body, field)
+		}
+	}
+	if w := getAsUser(controller.GetHealth, "/api/transactions?refresh=maybe"); w.Code != http.StatusBadRequest {
+		t.Errorf("refresh=maybe = %d, want 400", w.Code)
+	}
+}
+
+func TestCompareEndpoint(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`SELECT "currency" FROM "users"`, []string{"currency"}, []driver.Value{"INR"})
+	stub.Handle(`FROM expenses`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		result := testutil.StubResult{Columns: []string{"type", "category", "currency", "total"}}
+		if args[1] == "2025-03-01" {
+			result.Rows = [][]driver.Value{{"expense", "Travel", "INR", 400.0}}
+		}
+		return result, nil
+	})
+	summary := services.NewSummaryService(db, nil, 1)
+	t.Cleanup(summary.Close)
+	controller := &SummaryController{S: summary}
+
+	w := getAsUser(controller.Compare, "/api/transactions?period_a=2025-02&period_b=2025-03")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d %s", w.Code, w.Body)
+	}
+	for _, field := range []string{`"total_b":400`, `"delta":400`, `"delta_percent":null`, `"category":"Travel"`} {
+		if !strings.Contains(w.Body.String(), field) {
+			t.Errorf("body is missing %s: %s", field, w.Body)
+		}
+	}
+
+	if w := getAsUser(controller.Compare, "/api/transactions?period_a=last-month"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid period = %d, want 400", w.Code)
+	}
+}
+
+func TestCategoryBreakdownRollupParams(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`SELECT "currency" FROM "users"`, []string{"currency"}, []driver.Value{"INR"})
+	stub.On(`FROM expenses`, []string{"type", "category", "currency", "total"},
+		[]driver.Value{"expense", "Food & Dining", "INR", 4000.0},
+		[]driver.Value{"expense", "Travel", "INR", 1000.0},
+		[]driver.Value{"expense", "Education", "INR", 150.0},
+	)
+	summary := services.NewSummaryService(db, nil, 1)
+	t.Cleanup(summary.Close)
+	controller := &SummaryController{S: summary}
+	const dates = "/api/transactions?start_date=2025-03-01&end_date=2025-03-31"
+
+	w := getAsUser(controller.GetCategoryBreakdown, dates+"&top_n=1")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"Food \u0026 Dining":4000`) || !strings.Contains(w.Body.String(), `"Other":1150`) {
+		t.Errorf("top_n=1 = %d %s", w.Code, w.Body)
+	}
+	w = getAsUser(controller.GetCategoryBreakdown, dates+"&min_amount=500")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"Travel":1000`) || !strings.Contains(w.Body.String(), `"Other":150`) {
+		t.Errorf("min_amount=500 = %d %s", w.Code, w.Body)
+	}
+
+	for _, bad := range []string{"top_n=0", "top_n=two", "min_amount=-1", "min_amount=NaN", "min_amount=Inf"} {
+		if w := getAsUser(controller.GetCategoryBreakdown, dates+"&"+bad); w.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", bad, w.Code)
+		}
+	}
+}
+
+func TestGetLocations(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`SELECT "currency" FROM "users"`, []string{"currency"}, []driver.Value{"INR"})
+	stub.On(`FROM "expenses"`, []string{"city_key", "city", "currency", "total", "count"},
+		[]driver.Value{"pune", "Pune", "INR", 500.0, int64(1)},
+		[]driver.Value{"mumbai", "Mumbai", "INR", 1200.0, int64(2)},
+	)
+	summary := services.NewSummaryService(db, nil, 1)
+	t.Cleanup(summary.Close)
+	controller := &SummaryController{S: summary}
+
+	w := getAsUser(controller.GetLocations, "/api/transactions?start_date=2025-03-01")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"cities":[{"city":"Mumbai","total":1200,"count":2},{"city":"Pune","total":500,"count":1}]`) {
+		t.Errorf("locations = %d %s, want Mumbai then Pune", w.Code, w.Body)
+	}
+	for _, bad := range []string{"start_date=01/03/2025", "end_date=2025-3-1"} {
+		if w := getAsUser(controller.GetLocations, "/api/transactions?"+bad); w.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", bad, w.Code)
+		}
+	}
+}
+
+func TestGetPeriod(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`FROM "users"`, []string{"id", "budget", "timezone", "budget_period", "budget_period_start", "budget_period_days"},
+		[]driver.Value{int64(7), 5000.0, "UTC", "custom", "2025-03-05", int64(10)})
+	stub.On(`SELECT "currency" FROM "users"`, []string{"currency"}, []driver.Value{"INR"})
+	stub.On(`FROM expenses`, []string{"type", "category", "currency", "total"}, []driver.Value{"expense", "Travel", "INR", 1200.0})
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() { database.DB = previous })
+	summary := services.NewSummaryService(db, nil, 1)
+	t.Cleanup(summary.Close)
+	controller := &SummaryController{S: summary}
+
+	w := getAsUser(controller.GetPeriod, "/api/transactions")
+	if w.Code != http.StatusOK {
+		t.Fatalf("period = %d %s, want 200", w.Code, w.Body)
+	}
+	for _, field := range []string{`"period":"custom"`, `"days":10`, `"total_expenses":1200`, `"remaining_budget":3800`} {
+		if !strings.Contains(w.Body.String(), field) {
+			t.Errorf("period body %s, want %s", w.Body, field)
+		}
+	}
+
+	if w := getAsUser(controller.GetPeriod, "/api/transactions?refresh=maybe"); w.Code != http.StatusBadRequest {
+		t.Errorf("refresh=maybe = %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/services"
+	"gorm.io/gorm"
+)
+
+type TagController struct{ S *services.TagService }
+
+type tagIDsRequest struct {
+	TagIDs []uint `json:"tag_ids" binding:"required,min=1"`
+}
+
+// List returns the user's tags
+func (c *TagController) List(ctx *gin.Context) {
+	tags, err := c.S.List(ctx.GetUint("userID"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, tags)
+}
+
+// Create adds a new tag
+func (c *TagController) Create(ctx *gin.Context) {
+	var in struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&in); err != nil {
+		respondBindingError(ctx, err)
+		return
+	}
+
+	tag, err := c.S.Create(ctx.GetUint("userID"), in.Name)
+	switch {
+	case errors.Is(err, services.ErrInvalidTagName):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTagExists):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "tag": tag})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusCreated, tag)
+	}
+}
+
+// Delete removes a tag and detaches it everywhere
+func (c *TagController) Delete(ctx *gin.Context) {
+	tagID, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	if err := c.S.Delete(ctx.GetUint("userID"), tagID); err != nil {
+		respondTagError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+// AttachToExpense tags an expense
+func (c *TagController) AttachToExpense(ctx *gin.Context) {
+	expenseID, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	var in tagIDsRequest
+	if err := ctx.ShouldBindJSON(&in); err != nil {
+		respondBindingError(ctx, err)
+		return
+	}
+	if err := c.S.AttachToExpense(ctx.GetUint("userID"), expenseID, in.TagIDs); err != nil {
+		respondTagError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "tags attached"})
+}
+
+// DetachFromExpense removes a tag from an expense
+func (c *TagController) DetachFromExpense(ctx *gin.Context) {
+	expenseID, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	tagID, ok := parseIDParam(ctx, "tagId")
+	if !ok {
+		return
+	}
+	if err := c.S.DetachFromExpense(ctx.GetUint("userID"), expenseID, tagID); err != nil {
+		respondTagError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "tag detached"})
+}
+
+// AttachToTransaction tags a transaction
+func (c *TagController) AttachToTransaction(ctx *gin.Context) {
+	transactionID, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	var in tagIDsRequest
+	if err := ctx.ShouldBindJSON(&in); err != nil {
+		respondBindingError(ctx, err)
+		return
+	}
+	if err := c.S.AttachToTransaction(ctx.GetUint("userID"), transactionID, in.TagIDs); err != nil {
+		respondTagError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "tags attached"})
+}
+
+// DetachFromTransaction removes a tag from a transaction
+func (c *TagController) DetachFromTransaction(ctx *gin.Context) {
+	transactionID, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	tagID, ok := parseIDParam(ctx, "tagId")
+	if !ok {
+		return
+	}
+	if err := c.S.DetachFromTransaction(ctx.GetUint("userID"), transactionID, tagID); err != nil {
+		respondTagError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "tag detached"})
+}
+
+// parseIDParam reads a positive numeric path parameter, responding 400 if it is invalid
+func parseIDParam(ctx *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param(name), 10, 32)
+	if err != nil || id == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondTagError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTagNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "record not found"})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -1,8 +1,10 @@
 package controllers
 
 import (
+	"math"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/your-github/expense-tracker-backend/services"
@@ -32,7 +34,9 @@ type TransactionResponse struct {
 	} `json:"bank_account"`
 }
 
-// GetTransactionHistory returns all transactions for the authenticated user
+// GetTransactionHistory returns the authenticated user's bank and manual
+// transactions, optionally filtered by date range, type, category, amount
+// range and merchant
 func (c *TransactionController) GetTransactionHistory(ctx *gin.Context) {
 	userID := getUserIDFromContext(ctx)
 	if userID == 0 {
@@ -59,14 +63,42 @@ func (c *TransactionController) GetTransactionHistory(ctx *gin.Context) {
 		}
 	}
 
-	transactions, err := c.TransactionService.GetTransactions(userID, limit, offset)
+	filter := services.TransactionFilter{
+		Limit:    limit,
+		Offset:   offset,
+		SortBy:   ctx.DefaultQuery("sort", "date"),
+		Type:     ctx.Query("type"),
+		Category: ctx.Query("category"),
+	}
+	if filter.SortBy != "date" && filter.SortBy != "amount" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "sort must be date or amount"})
+		return
+	}
+	switch order := ctx.DefaultQuery("order", "desc"); order {
+	case "asc":
+		filter.Asc = true
+	case "desc":
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "order must be asc or desc"})
+		return
+	}
+	if filter.Type != "" && filter.Type != "credit" && filter.Type != "debit" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "type must be credit or debit"})
+		return
+	}
+	if !parseTransactionRangeFilters(ctx, services.UserLocation(c.TransactionService.DB, userID), &filter) {
+		return
+	}
+
+	transactions, total, err := c.TransactionService.GetTransactions(ctx.Request.Context(), userID, filter)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transactions"})
 		return
 	}
 
 	// Convert to response format
-	var response []TransactionResponse
+	// An offset past the last page yields an empty list rather than null
+	response := make([]TransactionResponse, 0, len(transactions))
 	for _, txn := range transactions {
 		response = append(response, TransactionResponse{
 			ID:              txn.ID,
@@ -92,7 +124,7 @@ func (c *TransactionController) GetTransactionHistory(ctx *gin.Context) {
 					if txn.BankAccount.BankID == "MANUAL" {
 						return "Manual Entry"
 					}
-					return maskAccountNumber(txn.BankAccount.AccountNumber)
+					return services.MaskAccountNumber(txn.BankAccount.AccountNumber)
 				}(),
 			},
 		})
@@ -101,11 +133,62 @@ func (c *TransactionController) GetTransactionHistory(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{
 		"transactions": response,
 		"count":        len(response),
+		"total":        total,
 		"limit":        limit,
 		"offset":       offset,
+		"has_more":     int64(offset+len(response)) < total,
 	})
 }
 
+// parseTransactionRangeFilters reads the from/to (YYYY-MM-DD, inclusive,
+// as calendar dates in loc), min_amount/max_amount and merchant query
+// params into filter, responding 400 and returning false on invalid input
+func parseTransactionRangeFilters(ctx *gin.Context, loc *time.Location, filter *services.TransactionFilter) bool {
+	if fromStr := ctx.Query("from"); fromStr != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", fromStr, loc)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from, expected YYYY-MM-DD"})
+			return false
+		}
+		filter.From = parsed
+	}
+	if toStr := ctx.Query("to"); toStr != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", toStr, loc)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to, expected YYYY-MM-DD"})
+			return false
+		}
+		filter.To = parsed.AddDate(0, 0, 1)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return false
+	}
+
+	for _, param := range []struct {
+		name   string
+		target **float64
+	}{{"min_amount", &filter.MinAmount}, {"max_amount", &filter.MaxAmount}} {
+		raw := ctx.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		amount, err := strconv.ParseFloat(raw, 64)
+		if err != nil || amount < 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param.name})
+			return false
+		}
+		*param.target = &amount
+	}
+	if filter.MinAmount != nil && filter.MaxAmount != nil && *filter.MinAmount > *filter.MaxAmount {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "min_amount must not be greater than max_amount"})
+		return false
+	}
+
+	filter.Merchant = ctx.Query("merchant")
+	return true
+}
+
 // GetTransactionsByBankAccount returns transactions for a specific bank account
 func (c *TransactionController) GetTransactionsByBankAccount(ctx *gin.Context) {
 	userID := getUserIDFromContext(ctx)
@@ -139,7 +222,7 @@ func (c *TransactionController) GetTransactionsByBankAccount(ctx *gin.Context) {
 		}
 	}
 
-	transactions, err := c.TransactionService.GetTransactionsByBankAccount(userID, uint(accountID), limit, offset)
+	transactions, err := c.TransactionService.GetTransactionsByBankAccount(ctx.Request.Context(), userID, uint(accountID), limit, offset)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transactions"})
 		return
@@ -172,7 +255,7 @@ func (c *TransactionController) GetTransactionsByBankAccount(ctx *gin.Context) {
 					if txn.BankAccount.BankID == "MANUAL" {
 						return "Manual Entry"
 					}
-					return maskAccountNumber(txn.BankAccount.AccountNumber)
+					return services.MaskAccountNumber(txn.BankAccount.AccountNumber)
 				}(),
 			},
 		})
@@ -186,3 +269,82 @@ func (c *TransactionController) GetTransactionsByBankAccount(ctx *gin.Context) {
 		"offset":       offset,
 	})
 }
+
+// GetRecurringTransactions returns charges detected as recurring (subscriptions, EMIs)
+func (c *TransactionController) GetRecurringTransactions(ctx *gin.Context) {
+	userID := getUserIDFromContext(ctx)
+	if userID == 0 {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	recurring, err := c.TransactionService.DetectRecurring(ctx.Request.Context(), userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to detect recurring transactions"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"recurring": recurring,
+		"count":     len(recurring),
+	})
+}
+
+// GetMerchantBreakdown returns spending per merchant, largest first.
+// start_date and end_date (YYYY-MM-DD, inclusive) default to the current month.
+func (c *TransactionController) GetMerchantBreakdown(ctx *gin.Context) {
+	userID := getUserIDFromContext(ctx)
+	if userID == 0 {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Dates are calendar dates in the user's timezone
+	loc := services.UserLocation(c.TransactionService.DB, userID)
+	now := time.Now().In(loc)
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 1, 0)
+
+	if startStr := ctx.Query("start_date"); startStr != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", startStr, loc)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date, expected YYYY-MM-DD"})
+			return
+		}
+		start = parsed
+	}
+	if endStr := ctx.Query("end_date"); endStr != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", endStr, loc)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date, expected YYYY-MM-DD"})
+			return
+		}
+		end = parsed.AddDate(0, 0, 1)
+	}
+	if !start.Before(end) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "start_date must not be after end_date"})
+		return
+	}
+
+	limit := 10
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = l
+	}
+
+	merchants, err := c.TransactionService.MerchantBreakdown(ctx.Request.Context(), userID, start, end, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get merchant breakdown"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"merchants":  merchants,
+		"start_date": start.Format("2006-01-02"),
+		"end_date":   end.AddDate(0, 0, -1).Format("2006-01-02"),
+	})
+}
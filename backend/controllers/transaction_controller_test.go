@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+	"github.com/your-github/expense-tracker-backend/middleware"
+	"github.com/your-github/expense-tracker-backend/services"
+)
+
+// getAsUser serves a GET of target to handler as the authenticated user 7
+func getAsUser(handler gin.HandlerFunc, target string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/transactions", func(ctx *gin.Context) {
+		ctx.Set(middleware.ContextUserID, uint(7))
+		handler(ctx)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func TestTransactionHistoryEnvelope(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`SELECT count(*)`, []string{"count"}, []driver.Value{int64(12)})
+	controller := &TransactionController{TransactionService: &services.TransactionService{DB: db}}
+
+	// Past the last page the list is empty but the total is still reported
+	w := getAsUser(controller.GetTransactionHistory, "/api/transactions?limit=5&offset=20&sort=amount&order=asc&type=debit")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d %s", w.Code, w.Body)
+	}
+	var body struct {
+		Transactions []TransactionResponse `json:"transactions"`
+		Count        int                   `json:"count"`
+		Total        int64                 `json:"total"`
+		Limit        int                   `json:"limit"`
+		Offset       int                   `json:"offset"`
+		HasMore      bool                  `json:"has_more"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Transactions == nil || body.Count != 0 || body.Total != 12 || body.Limit != 5 || body.Offset != 20 || body.HasMore {
+		t.Errorf("envelope = %s", w.Body)
+	}
+	if ran := stub.Ran(`ORDER BY amount ASC`); len(ran) != 1 {
+		t.Errorf("sort and order weren't passed to the query")
+	}
+
+	if w := getAsUser(controller.GetTransactionHistory, "/api/transactions"); !strings.Contains(w.Body.String(), `"limit":50`) {
+		t.Errorf("default page = %s, want a limit of 50", w.Body)
+	}
+}
+
+func TestTransactionHistoryRejectsBadParams(t *testing.T) {
+	db, _ := testutil.NewStubDB(t)
+	controller := &TransactionController{TransactionService: &services.TransactionService{DB: db}}
+	for _, query := range []string{"sort=merchant", "order=sideways", "type=refund"} {
+		if w := getAsUser(controller.GetTransactionHistory, "/api/transactions?"+query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", query, w.Code)
+		}
+	}
+}
+
+func TestTransactionHistoryRangeParams(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	controller := &TransactionController{TransactionService: &services.TransactionService{DB: db}}
+
+	for _, query := range []string{
+		"from=2025-13-01", "to=yesterday", "from=2025-03-10&to=2025-03-01",
+		"min_amount=abc", "max_amount=-5", "min_amount=NaN", "min_amount=500&max_amount=100",
+	} {
+		if w := getAsUser(controller.GetTransactionHistory, "/api/transactions?"+query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", query, w.Code)
+		}
+	}
+	if len(stub.Ran(`FROM "transactions"`)) != 0 {
+		t.Error("invalid params reached the database")
+	}
+
+	w := getAsUser(controller.GetTransactionHistory, "/api/transactions?from=2025-03-01&to=2025-03-01&min_amount=100&max_amount=100&merchant=Swiggy")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d %s", w.Code, w.Body)
+	}
+	counts := stub.Ran(`count(*)`)
+	if len(counts) != 1 {
+		t.Fatalf("ran %d counts, want 1", len(counts))
+	}
+	args := counts[0].Args
+	from, to := args[1].(time.Time), args[2].(time.Time)
+	// A single day: to is inclusive, so the bound is the next midnight
+	if to.Sub(from) != 24*time.Hour || from.Format("2006-01-02") != "2025-03-01" {
+		t.Errorf("date bounds %v to %v, want all of 2025-03-01", from, to)
+	}
+	if args[3] != 100.0 || args[4] != 100.0 || args[5] != "%swiggy%" {
+		t.Errorf("filter args = %v", args)
+	}
+}
+
+func TestTransactionHistoryRangeIsInTheUsersTimezone(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`SELECT "timezone" FROM "users"`, []string{"timezone"}, []driver.Value{"Asia/Kolkata"})
+	controller := &TransactionController{TransactionService: &services.TransactionService{DB: db}}
+
+	w := getAsUser(controller.GetTransactionHistory, "/api/transactions?from=2025-04-01&to=2025-04-30")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d %s", w.Code, w.Body)
+	}
+	counts := stub.Ran(`count(*)`)
+	if len(counts) != 1 {
+		t.Fatalf("ran %d counts, want 1", len(counts))
+	}
+	// April starts and ends at midnight in Kolkata, 5:30 ahead of UTC
+	from, to := counts[0].Args[1].(time.Time), counts[0].Args[2].(time.Time)
+	if want := time.Date(2025, time.March, 31, 18, 30, 0, 0, time.UTC); !from.Equal(want) {
+		t.Errorf("from = %v, want %v", from.UTC(), want)
+	}
+	if want := time.Date(2025, time.April, 30, 18, 30, 0, 0, time.UTC); !to.Equal(want) {
+		t.Errorf("to = %v, want %v", to.UTC(), want)
+	}
+}
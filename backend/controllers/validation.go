@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes one failed validation rule on a request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func init() {
+	// Report JSON field names (e.g. "payment_method") rather than Go struct field names
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return f.Name
+			}
+			return name
+		})
+	}
+}
+
+// respondBindingError writes a 400 for a failed ShouldBind* call. Validation
+// failures are returned as {"error": ..., "errors": [{field, rule, message}]};
+// malformed bodies get a generic message instead of the decoder's error text.
+func respondBindingError(ctx *gin.Context, err error) {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	fieldErrors := make([]FieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: validationMessage(fe),
+		})
+	}
+
+	ctx.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "errors": fieldErrors})
+}
+
+// validationMessage renders a human-readable message for a failed rule
+func validationMessage(fe validator.FieldError) string {
+	field := fe.Field()
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "email":
+		return fmt.Sprintf("%s must be a valid email address", field)
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", field, fe.Param())
+	case "gte", "min":
+		return fmt.Sprintf("%s must be at least %s", field, fe.Param())
+	case "lte", "max":
+		return fmt.Sprintf("%s must be at most %s", field, fe.Param())
+	case "len":
+		return fmt.Sprintf("%s must be exactly %s characters", field, fe.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, strings.Join(strings.Fields(fe.Param()), ", "))
+	case "datetime":
+		return fmt.Sprintf("%s must be a date in the format %s", field, fe.Param())
+	case "iso4217":
+		return fmt.Sprintf("%s must be a valid ISO 4217 currency code", field)
+	case "numeric":
+		return fmt.Sprintf("%s must contain only digits", field)
+	default:
+		return fmt.Sprintf("%s failed the %s rule", field, fe.Tag())
+	}
+}
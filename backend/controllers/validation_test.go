@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/middleware"
+)
+
+// validationBody is the JSON body respondBindingError writes
+type validationBody struct {
+	Error  string       `json:"error"`
+	Errors []FieldError `json:"errors"`
+}
+
+// postJSON serves body to handler as the authenticated user 7 and decodes
+// the response
+func postJSON(t *testing.T, handler gin.HandlerFunc, body string) (int, validationBody) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/", func(ctx *gin.Context) {
+		ctx.Set(middleware.ContextUserID, uint(7))
+		handler(ctx)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	var out validationBody
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode %s: %v", w.Body, err)
+	}
+	return w.Code, out
+}
+
+// rulesByField indexes field errors as field -> rule
+func rulesByField(errs []FieldError) map[string]string {
+	rules := make(map[string]string, len(errs))
+	for _, fe := range errs {
+		rules[fe.Field] = fe.Rule
+	}
+	return rules
+}
+
+func TestStructuredValidationErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler gin.HandlerFunc
+		body    string
+		want    map[string]string
+	}{
+		{
+			name:    "expense",
+			handler: (&ExpenseController{}).Create,
+			body:    `{"amount": -5, "date": "01/03/2025", "type": "gift", "currency": "RUPEES"}`,
+			want:    map[string]string{"title": "required", "amount": "gt", "date": "datetime", "type": "oneof", "currency": "iso4217"},
+		},
+		{
+			name:    "register",
+			handler: (&AuthController{}).Register,
+			body:    `{"email": "not-an-email", "password": "123"}`,
+			want:    map[string]string{"name": "required", "email": "email", "password": "min"},
+		},
+		{
+			name:    "bank account",
+			handler: (&BankController{}).AddBankAccount,
+			body:    `{"bankId": "hdfc"}`,
+			want:    map[string]string{"accountNumber": "required", "accountHolderName": "required", "mobileNumber": "required"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := postJSON(t, tt.handler, tt.body)
+			if code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", code)
+			}
+			if body.Error != "Validation failed" {
+				t.Errorf("error = %q", body.Error)
+			}
+			got := rulesByField(body.Errors)
+			if len(got) != len(tt.want) {
+				t.Errorf("errors = %+v, want %v", body.Errors, tt.want)
+			}
+			for field, rule := range tt.want {
+				if got[field] != rule {
+					t.Errorf("%s failed %q, want %q", field, got[field], rule)
+				}
+			}
+			for _, fe := range body.Errors {
+				if !strings.HasPrefix(fe.Message, fe.Field+" ") {
+					t.Errorf("message %q doesn't name the field %s", fe.Message, fe.Field)
+				}
+			}
+		})
+	}
+}
+
+func TestValidationMessages(t *testing.T) {
+	_, body := postJSON(t, (&ExpenseController{}).Create, `{"amount": -5, "date": "01/03/2025", "type": "gift"}`)
+	messages := make(map[string]string)
+	for _, fe := range body.Errors {
+		messages[fe.Field] = fe.Message
+	}
+	want := map[string]string{
+		"title":  "title is required",
+		"amount": "amount must be greater than 0",
+		"date":   "date must be a date in the format 2006-01-02",
+		"type":   "type must be one of: income, expense",
+	}
+	for field, message := range want {
+		if messages[field] != message {
+			t.Errorf("%s: %q, want %q", field, messages[field], message)
+		}
+	}
+}
+
+func TestMalformedBodyHidesDecoderError(t *testing.T) {
+	code, body := postJSON(t, (&ExpenseController{}).Create, `{"title": "Lunch", "amount": "twelve"`)
+	if code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", code)
+	}
+	if body.Error != "Invalid request body" || len(body.Errors) != 0 {
+		t.Errorf("body = %+v, want the generic message only", body)
+	}
+}
@@ -3,22 +3,32 @@ package database
 import (
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
+	"github.com/your-github/expense-tracker-backend/config"
 	"github.com/your-github/expense-tracker-backend/models"
+	"github.com/your-github/expense-tracker-backend/utils"
 )
 
 var DB *gorm.DB
 
-func Connect() {
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		"localhost", "postgres", "aayush001", "expense_tracker", "5432")
+// Connect opens the database configured by cfg.Database.DSN and stores it in
+// DB. Production refuses to start without an explicitly configured DSN.
+func Connect(cfg *config.Config) error {
+	dsn := cfg.Database.DSN
+	if dsn == "" {
+		return fmt.Errorf("database DSN is not configured: set DATABASE_DSN")
+	}
+	if cfg.App.Env == "production" && dsn == config.DefaultDatabaseDSN {
+		return fmt.Errorf("DATABASE_DSN must be set in production; refusing to use the local development default")
+	}
 
-	log.Printf("Connecting to database with DSN: %s", dsn)
+	log.Println("Connecting to database...")
 
 	// Enhanced database configuration for performance
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
@@ -26,15 +36,14 @@ func Connect() {
 		PrepareStmt:            true,                                 // Enable prepared statements
 		SkipDefaultTransaction: true,                                 // Skip default transaction for better performance
 	})
-
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
 	// Configure connection pool for better performance
 	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatalf("Failed to get underlying sql.DB: %v", err)
+		return fmt.Errorf("failed to get underlying sql.DB: %w", err)
 	}
 
 	// Optimized connection pool settings
@@ -45,6 +54,7 @@ func Connect() {
 
 	log.Println("Database connected successfully with optimized settings")
 	DB = db
+	return nil
 }
 
 func Migrate(db *gorm.DB) {
@@ -69,16 +79,36 @@ func Migrate(db *gorm.DB) {
 	db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_google_id
                 ON users(google_id) WHERE google_id IS NOT NULL`)
 
+	log.Println("Migrating Tag model...")
+	if err := db.AutoMigrate(&models.Tag{}); err != nil {
+		log.Fatalf("Tag migration error: %v", err)
+	}
+
+	log.Println("Migrating CategoryRule model...")
+	if err := db.AutoMigrate(&models.CategoryRule{}); err != nil {
+		log.Fatalf("CategoryRule migration error: %v", err)
+	}
+
 	log.Println("Migrating Expense model...")
 	if err := db.AutoMigrate(&models.Expense{}); err != nil {
 		log.Fatalf("Expense migration error: %v", err)
 	}
 
+	// Several OTPs may exist per email (resends), so the old unique index must go
+	log.Println("Dropping OTP email unique index if exists...")
+	db.Exec("DROP INDEX IF EXISTS idx_otps_email")
+
 	log.Println("Migrating OTP model...")
 	if err := db.AutoMigrate(&models.OTP{}); err != nil {
 		log.Fatalf("OTP migration error: %v", err)
 	}
 
+	log.Println("Migrating Bank model...")
+	if err := db.AutoMigrate(&models.Bank{}); err != nil {
+		log.Fatalf("Bank migration error: %v", err)
+	}
+	seedBanks(db)
+
 	log.Println("Migrating BankAccount model...")
 	if err := db.AutoMigrate(&models.BankAccount{}); err != nil {
 		log.Fatalf("BankAccount migration error: %v", err)
@@ -89,6 +119,21 @@ func Migrate(db *gorm.DB) {
 		log.Fatalf("Transaction migration error: %v", err)
 	}
 
+	rekeyManualMirrors(db)
+	linkManualAccounts(db)
+
+	log.Println("Migrating Receipt model...")
+	if err := db.AutoMigrate(&models.Receipt{}); err != nil {
+		log.Fatalf("Receipt migration error: %v", err)
+	}
+
+	log.Println("Migrating BudgetAlert model...")
+	if err := db.AutoMigrate(&models.BudgetAlert{}); err != nil {
+		log.Fatalf("BudgetAlert migration error: %v", err)
+	}
+
+	remapCategories(db)
+
 	// Create performance indexes
 	log.Println("Creating performance indexes...")
 
@@ -130,6 +175,10 @@ func Migrate(db *gorm.DB) {
 	db.Exec(`CREATE INDEX IF NOT EXISTS idx_bank_accounts_status
                 ON bank_accounts(status)`)
 
+	// Idempotency keys are unique per user so replays resolve to one row
+	db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_bank_accounts_user_idempotency_key
+                ON bank_accounts(user_id, idempotency_key) WHERE idempotency_key IS NOT NULL`)
+
 	// Transaction indexes for better performance
 	db.Exec(`CREATE INDEX IF NOT EXISTS idx_transactions_user_date
                 ON transactions(user_id, transaction_date DESC)`)
@@ -143,6 +192,100 @@ func Migrate(db *gorm.DB) {
 	db.Exec(`CREATE INDEX IF NOT EXISTS idx_transactions_amount
                 ON transactions(amount) WHERE amount > 0`)
 
+	// Tag join table lookups by tag for filtering
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_expense_tags_tag_id
+                ON expense_tags(tag_id)`)
+
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_transaction_tags_tag_id
+                ON transaction_tags(tag_id)`)
+
 	log.Println("Performance indexes created successfully")
 	log.Println("Database migrations completed successfully")
 }
+
+// seedBanks fills an empty banks table from the built-in list. Once seeded
+// the table is left alone so banks removed by an admin stay removed.
+func seedBanks(db *gorm.DB) {
+	var count int64
+	if err := db.Model(&models.Bank{}).Count(&count).Error; err != nil {
+		log.Printf("Failed to count banks: %v", err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+
+	log.Println("Seeding banks...")
+	banks := make([]models.Bank, len(utils.Banks))
+	for i, bank := range utils.Banks {
+		banks[i] = models.Bank{
+			ID:         bank.ID,
+			Name:       bank.Name,
+			Logo:       bank.Logo,
+			Type:       bank.Type,
+			IFSCPrefix: bank.IFSCPrefix,
+		}
+	}
+	if err := db.Create(&banks).Error; err != nil {
+		log.Printf("Failed to seed banks: %v", err)
+	}
+}
+
+// rekeyManualMirrors moves manual expense mirrors from the old
+// MANUAL_<expenseID> keys to the user-scoped MANUAL_<userID>_<expenseID>.
+// Rows already on the new key don't match the pattern, so reruns are no-ops.
+func rekeyManualMirrors(db *gorm.DB) {
+	log.Println("Rekeying manual expense mirrors...")
+	if err := db.Exec(`UPDATE transactions
+                SET transaction_id = 'MANUAL_' || user_id || '_' || substring(transaction_id from 8)
+                WHERE transaction_id ~ '^MANUAL_[0-9]+$'`).Error; err != nil {
+		log.Printf("Failed to rekey manual expense mirrors: %v", err)
+	}
+}
+
+// linkManualAccounts gives every user with manual expense mirrors a MANUAL
+// bank account and moves the mirrors, which used bank_account_id 0, onto it
+func linkManualAccounts(db *gorm.DB) {
+	log.Println("Linking manual transactions to MANUAL bank accounts...")
+
+	// One manual account per user, soft-deleted or not
+	if err := db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_bank_accounts_user_manual
+                ON bank_accounts(user_id) WHERE bank_id = 'MANUAL'`).Error; err != nil {
+		log.Printf("Failed to create manual bank account index: %v", err)
+		return
+	}
+
+	if err := db.Exec(`INSERT INTO bank_accounts
+                (created_at, updated_at, user_id, bank_id, account_number, account_holder_name, mobile_number, status, account_type)
+                SELECT DISTINCT now(), now(), user_id, 'MANUAL', 'MANUAL', 'Manual Entry', '', 'ACTIVE', 'MANUAL'
+                FROM transactions
+                WHERE bank_account_id = 0 AND transaction_id LIKE 'MANUAL\_%'
+                ON CONFLICT (user_id) WHERE bank_id = 'MANUAL' DO NOTHING`).Error; err != nil {
+		log.Printf("Failed to create manual bank accounts: %v", err)
+		return
+	}
+
+	if err := db.Exec(`UPDATE transactions t
+                SET bank_account_id = b.id
+                FROM bank_accounts b
+                WHERE b.user_id = t.user_id AND b.bank_id = 'MANUAL'
+                  AND t.bank_account_id = 0 AND t.transaction_id LIKE 'MANUAL\_%'`).Error; err != nil {
+		log.Printf("Failed to link manual transactions: %v", err)
+	}
+}
+
+// remapCategories rewrites stored categories that are aliases of, or differ
+// only in case from, a canonical category (e.g. "Transportation",
+// "food") to that category. Custom user categories are left alone.
+func remapCategories(db *gorm.DB) {
+	log.Println("Remapping categories onto the canonical taxonomy...")
+	for category, labels := range utils.CategoryAliases() {
+		labels = append(labels, strings.ToLower(category))
+		for _, table := range []string{"expenses", "transactions"} {
+			if err := db.Exec("UPDATE "+table+" SET category = ? WHERE LOWER(TRIM(category)) IN ? AND category <> ?",
+				category, labels, category).Error; err != nil {
+				log.Printf("Failed to remap %s categories to %q: %v", table, category, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+
+	"github.com/your-github/expense-tracker-backend/config"
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+)
+
+func TestConnectRequiresADSN(t *testing.T) {
+	cfg := &config.Config{}
+	if err := Connect(cfg); err == nil || !strings.Contains(err.Error(), "DATABASE_DSN") {
+		t.Errorf("Connect without a DSN = %v, want an error naming DATABASE_DSN", err)
+	}
+}
+
+func TestConnectRefusesTheDefaultDSNInProduction(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.App.Env = "production"
+	cfg.Database.DSN = config.DefaultDatabaseDSN
+	if err := Connect(cfg); err == nil || !strings.Contains(err.Error(), "production") {
+		t.Errorf("Connect = %v, want a refusal of the development default", err)
+	}
+}
+
+func TestConnectUsesTheConfiguredDSN(t *testing.T) {
+	// Opening the pool doesn't dial, so the closed port is never reached
+	dsn := "postgres://configured_user@127.0.0.1:1/configured_db?sslmode=disable"
+	cfg := &config.Config{}
+	cfg.Database.DSN = dsn
+	if err := Connect(cfg); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+		DB = nil
+	})
+
+	dialector, ok := DB.Dialector.(*postgres.Dialector)
+	if !ok {
+		t.Fatalf("dialector = %T, want postgres", DB.Dialector)
+	}
+	if dialector.Config.DSN != dsn {
+		t.Errorf("opened %q, want the configured DSN", dialector.Config.DSN)
+	}
+}
+
+func TestRekeyManualMirrorsOnlyTouchesOldKeys(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	rekeyManualMirrors(db)
+
+	ran := stub.Ran(`UPDATE transactions`)
+	if len(ran) != 1 {
+		t.Fatalf("ran %d rekey statements, want 1", len(ran))
+	}
+	m := regexp.MustCompile(`transaction_id ~ '([^']+)'`).FindStringSubmatch(ran[0].SQL)
+	if m == nil {
+		t.Fatalf("rekey isn't limited to old keys:\n%s", ran[0].SQL)
+	}
+	oldKey := regexp.MustCompile(m[1])
+	for key, want := range map[string]bool{
+		"MANUAL_3":    true,
+		"MANUAL_42":   true,
+		"MANUAL_7_3":  false, // already rekeyed, so reruns leave it alone
+		"AA_TXN_3":    false,
+		"MANUAL_":     false,
+		"XMANUAL_3":   false,
+		"MANUAL_3_x":  false,
+		"MANUAL_3abc": false,
+	} {
+		if oldKey.MatchString(key) != want {
+			t.Errorf("%s matched = %v, want %v", key, !want, want)
+		}
+	}
+	// Postgres strings are 1-indexed, so from 8 drops exactly "MANUAL_"
+	if !strings.Contains(ran[0].SQL, "'MANUAL_' || user_id || '_' || substring(transaction_id from 8)") {
+		t.Errorf("rekey doesn't keep the expense ID:\n%s", ran[0].SQL)
+	}
+}
+
+func TestRekeyManualMirrorsLogsFailures(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.Fail(`UPDATE transactions`, errors.New("permission denied"))
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	rekeyManualMirrors(db)
+	if !strings.Contains(logs.String(), "Failed to rekey manual expense mirrors: permission denied") {
+		t.Errorf("logs = %q, want the failure reported", logs.String())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,10 +14,15 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/your-github/expense-tracker-backend/config"
+	"github.com/your-github/expense-tracker-backend/internal/core/ports"
 	"github.com/your-github/expense-tracker-backend/internal/core/services"
 	"github.com/your-github/expense-tracker-backend/internal/http/handlers"
 	"github.com/your-github/expense-tracker-backend/internal/http/middleware"
 	"github.com/your-github/expense-tracker-backend/internal/repo"
+	"github.com/your-github/expense-tracker-backend/metrics"
+	"github.com/your-github/expense-tracker-backend/requestid"
+	legacyservices "github.com/your-github/expense-tracker-backend/services"
+	"github.com/your-github/expense-tracker-backend/utils"
 )
 
 // App represents the main application
@@ -33,27 +39,51 @@ type App struct {
 // NewApp creates a new application instance
 func NewApp(cfg *config.Config, repositories *repo.Repositories, logger *zap.Logger) *App {
 	logger.Info("Initializing application...")
+	if cfg.Categories.TaxonomyFile != "" {
+		if err := utils.LoadTaxonomyFile(cfg.Categories.TaxonomyFile); err != nil {
+			logger.Error("Failed to load category taxonomy, using built-in defaults", zap.Error(err), zap.String("path", cfg.Categories.TaxonomyFile))
+		}
+	}
+
 	// Initialize services
 	normalizer := services.NewNormalizer()
+	normalizer.ReviewThreshold = cfg.Normalizer.ReviewThreshold
+	if cfg.Normalizer.RulesFile != "" {
+		if err := normalizer.LoadRulesFile(cfg.Normalizer.RulesFile); err != nil {
+			logger.Error("Failed to load merchant rules, using built-in defaults", zap.Error(err), zap.String("path", cfg.Normalizer.RulesFile))
+		}
+	}
 	deduplicator := services.NewDeduplicator()
 
-	// Initialize AA client (mock for now)
-	aaClient := services.NewMockAAClient()
+	// Initialize AA client for the configured provider
+	aaClient := newAAClient(cfg, logger)
 
 	// Initialize AA service
 	aaService := services.NewAAService(aaClient, repositories, normalizer, deduplicator, logger)
+	aaService.FetchChunkDays = cfg.AA.FetchChunkDays
+	aaService.IngestWorkers = cfg.AA.IngestWorkers
+	aaService.LargeTransactionThreshold = cfg.AA.LargeTransactionThreshold
+	aaService.UnusualStdDevs = cfg.AA.UnusualStdDevs
+	aaService.RefreshWindowDays = cfg.AA.RefreshWindowDays
+	aaService.RefreshCooldown = cfg.AA.RefreshCooldown
+	aaService.Rates = utils.NewRateProvider(cfg.Currency.RatesURL)
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(repositories, cfg)
 	aaHandler := handlers.NewAAHandler(aaService, repositories, cfg, logger)
-
-	// Setup router
-	logger.Info("Setting up router...")
-	router := setupRouter(cfg, authHandler, aaHandler, logger)
+	transactionHandler := handlers.NewTransactionHandler(repositories, logger)
+	transactionHandler.Normalizer = normalizer
 
 	// Setup cron jobs
 	logger.Info("Setting up cron jobs...")
-	cronJobs := setupCronJobs(aaService, logger)
+	emailService := legacyservices.NewEmailService(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.User, cfg.SMTP.Pass)
+	expiryNotifier := services.NewConsentExpiryNotifier(repositories, emailService, cfg.AA.ConsentExpiryWarning, logger)
+	cronJobs := setupCronJobs(aaService, expiryNotifier, logger)
+	healthHandler := handlers.NewHealthHandler(repositories, cronJobs, logger)
+
+	// Setup router
+	logger.Info("Setting up router...")
+	router := setupRouter(cfg, authHandler, aaHandler, transactionHandler, healthHandler, repositories.User, logger)
 
 	app := &App{
 		config:       cfg,
@@ -114,8 +144,45 @@ func (a *App) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// newAAClient selects the AA client implementation from cfg.AA.Provider.
+// The mock client is used when the provider is "mock", unset, or has no
+// base URL. Either client verifies webhooks with the configured signer; if
+// it cannot be built, every webhook is rejected rather than trusted.
+func newAAClient(cfg *config.Config, logger *zap.Logger) ports.AAClient {
+	webhookSigner, err := services.NewWebhookSigner(cfg)
+	if err != nil {
+		logger.Error("Failed to configure AA webhook signature verification", zap.Error(err))
+	}
+	newMock := func() ports.AAClient {
+		mock := services.NewMockAAClient()
+		mock.WebhookSigner = webhookSigner
+		return mock
+	}
+
+	provider := strings.ToLower(strings.TrimSpace(cfg.AA.Provider))
+	if provider == "" || provider == "mock" {
+		logger.Info("Using mock AA client")
+		return newMock()
+	}
+
+	if cfg.AA.BaseURL == "" {
+		logger.Warn("AA provider configured without base URL, falling back to mock client", zap.String("provider", provider))
+		return newMock()
+	}
+
+	client, err := services.NewHTTPAAClient(cfg.AA)
+	if err != nil {
+		logger.Error("Failed to configure AA request signing, falling back to mock client", zap.String("provider", provider), zap.Error(err))
+		return newMock()
+	}
+	client.WebhookSigner = webhookSigner
+
+	logger.Info("Using HTTP AA client", zap.String("provider", provider), zap.String("base_url", cfg.AA.BaseURL))
+	return client
+}
+
 // setupRouter configures the HTTP router
-func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler, aaHandler *handlers.AAHandler, logger *zap.Logger) *gin.Engine {
+func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler, aaHandler *handlers.AAHandler, transactionHandler *handlers.TransactionHandler, healthHandler *handlers.HealthHandler, users repo.UserRepository, logger *zap.Logger) *gin.Engine {
 	// Set Gin mode
 	if cfg.App.Env == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -125,14 +192,18 @@ func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler, aaHandle
 
 	// Middleware
 	router.Use(gin.Recovery())
+	router.Use(requestid.Middleware())
 	router.Use(middleware.Logger(logger))
 	router.Use(middleware.CORS())
 	router.Use(middleware.RateLimit())
+	if cfg.Metrics.Enabled {
+		router.Use(metrics.GinMiddleware())
+		router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	}
 
-	// Health check
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok", "timestamp": time.Now().Unix()})
-	})
+	// Health checks: liveness is static, readiness pings the database
+	router.GET("/health", healthHandler.Live)
+	router.GET("/health/ready", healthHandler.Ready)
 
 	// Simple ping endpoint for basic connectivity
 	router.GET("/ping", func(c *gin.Context) {
@@ -168,24 +239,49 @@ func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler, aaHandle
 			aaProtected.Use(middleware.Auth(cfg.JWT.Secret))
 			{
 				aaProtected.POST("/consents/initiate", aaHandler.InitiateConsent)
+				aaProtected.GET("/consents/:bankLinkID/status", aaHandler.GetConsentStatus)
 				aaProtected.POST("/fetch", aaHandler.FetchTransactions)
 				aaProtected.GET("/bank-links", aaHandler.GetBankLinks)
+				aaProtected.POST("/bank-links/:id/refresh", aaHandler.RefreshBankLink)
 				aaProtected.POST("/consents/revoke", aaHandler.RevokeConsent)
 			}
 		}
 
+		// Admin routes
+		admin := api.Group("/admin")
+		admin.Use(middleware.Auth(cfg.JWT.Secret), middleware.RequireCurrentRole(users, utils.RoleAdmin))
+		{
+			admin.GET("/webhook-events", aaHandler.ListWebhookEvents)
+			admin.POST("/webhook-events/:id/replay", aaHandler.ReplayWebhookEvent)
+			admin.GET("/merchant-aliases", aaHandler.ListGlobalMerchantAliases)
+			admin.POST("/merchant-aliases", aaHandler.CreateGlobalMerchantAlias)
+			admin.DELETE("/merchant-aliases/:id", aaHandler.DeleteGlobalMerchantAlias)
+		}
+
+		// Transaction routes (protected)
+		transactions := api.Group("/transactions")
+		transactions.Use(middleware.Auth(cfg.JWT.Secret))
+		{
+			transactions.GET("/balance-history", transactionHandler.GetBalanceHistory)
+			transactions.GET("/by-source-meta", transactionHandler.GetTransactionsBySourceMeta)
+			transactions.GET("/needs-review", transactionHandler.GetNeedsReview)
+			transactions.GET("/flagged", transactionHandler.GetFlagged)
+			transactions.GET("/search", transactionHandler.SearchTransactions)
+			transactions.POST("/renormalize", aaHandler.RenormalizeTransactions)
+			transactions.PATCH("/:id", transactionHandler.UpdateTransaction)
+			transactions.POST("/:id/split", transactionHandler.SplitTransaction)
+			transactions.DELETE("/:id/split", transactionHandler.UnsplitTransaction)
+		}
+
 		// User routes (protected)
 		me := api.Group("/me")
 		me.Use(middleware.Auth(cfg.JWT.Secret))
 		{
-			me.GET("/transactions", func(c *gin.Context) {
-				// TODO: Implement transaction listing
-				c.JSON(200, gin.H{"message": "Transactions endpoint - to be implemented"})
-			})
-			me.GET("/summary", func(c *gin.Context) {
-				// TODO: Implement summary endpoint
-				c.JSON(200, gin.H{"message": "Summary endpoint - to be implemented"})
-			})
+			me.GET("/transactions", transactionHandler.ListTransactions)
+			me.GET("/summary", transactionHandler.GetSummary)
+			me.GET("/merchant-aliases", aaHandler.ListMerchantAliases)
+			me.POST("/merchant-aliases", aaHandler.CreateMerchantAlias)
+			me.DELETE("/merchant-aliases/:id", aaHandler.DeleteMerchantAlias)
 			me.POST("/categorize/override", func(c *gin.Context) {
 				// TODO: Implement category override
 				c.JSON(200, gin.H{"message": "Category override endpoint - to be implemented"})
@@ -197,7 +293,7 @@ func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler, aaHandle
 }
 
 // setupCronJobs configures background cron jobs
-func setupCronJobs(aaService *services.AAService, logger *zap.Logger) *cron.Cron {
+func setupCronJobs(aaService *services.AAService, expiryNotifier *services.ConsentExpiryNotifier, logger *zap.Logger) *cron.Cron {
 	c := cron.New(cron.WithLocation(time.UTC))
 
 	// Daily transaction fetch job (2:00 AM IST = 8:30 PM UTC)
@@ -217,5 +313,39 @@ func setupCronJobs(aaService *services.AAService, logger *zap.Logger) *cron.Cron
 		logger.Error("Failed to schedule daily fetch job", zap.Error(err))
 	}
 
+	// Consent status refresh job (every 15 minutes)
+	_, err = c.AddFunc("*/15 * * * *", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+
+		updated, err := aaService.RefreshConsentStatuses(ctx)
+		if err != nil {
+			logger.Error("Consent status refresh job failed", zap.Error(err))
+			return
+		}
+		logger.Info("Completed consent status refresh job", zap.Int("updated", updated))
+	})
+
+	if err != nil {
+		logger.Error("Failed to schedule consent refresh job", zap.Error(err))
+	}
+
+	// Consent expiry reminder job (daily at 9:00 AM IST = 3:30 AM UTC)
+	_, err = c.AddFunc("30 3 * * *", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+
+		sent, err := expiryNotifier.SendReminders(ctx)
+		if err != nil {
+			logger.Error("Consent expiry reminder job failed", zap.Error(err))
+			return
+		}
+		logger.Info("Completed consent expiry reminder job", zap.Int("sent", sent))
+	})
+
+	if err != nil {
+		logger.Error("Failed to schedule consent expiry reminder job", zap.Error(err))
+	}
+
 	return c
 }
@@ -0,0 +1,40 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/your-github/expense-tracker-backend/config"
+	"github.com/your-github/expense-tracker-backend/internal/core/services"
+	"go.uber.org/zap"
+)
+
+func TestNewAAClientSelectsProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		aa       config.AAConfig
+		wantHTTP bool
+	}{
+		{"unset", config.AAConfig{}, false},
+		{"mock", config.AAConfig{Provider: "mock", BaseURL: "https://aa.example"}, false},
+		{"real", config.AAConfig{Provider: "Setu", BaseURL: "https://aa.example", ClientSecret: "secret"}, true},
+		{"real without base URL", config.AAConfig{Provider: "setu"}, false},
+		{"RSA signing without a key", config.AAConfig{Provider: "setu", BaseURL: "https://aa.example", SigningScheme: "rsa"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newAAClient(&config.Config{AA: tt.aa}, zap.NewNop())
+			switch client.(type) {
+			case *services.HTTPAAClient:
+				if !tt.wantHTTP {
+					t.Error("got the HTTP client, want the mock")
+				}
+			case *services.MockAAClient:
+				if tt.wantHTTP {
+					t.Error("got the mock client, want the HTTP client")
+				}
+			default:
+				t.Errorf("unexpected client %T", client)
+			}
+		})
+	}
+}
@@ -14,6 +14,8 @@ type User struct {
 	ID           uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	Email        string         `gorm:"uniqueIndex;not null" json:"email"`
 	PasswordHash string         `gorm:"column:password_hash;not null" json:"-"`
+	Currency     string         `gorm:"not null;default:'INR'" json:"currency"` // base currency transactions are converted to
+	Role         string         `gorm:"not null;default:'user'" json:"role"`    // "user" or "admin"
 	CreatedAt    time.Time      `gorm:"default:now()" json:"created_at"`
 	UpdatedAt    time.Time      `gorm:"default:now()" json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
@@ -26,15 +28,17 @@ func (User) TableName() string {
 
 // BankLink represents a user's bank account link via AA
 type BankLink struct {
-	ID          uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	UserID      uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
-	AAConsentID string         `gorm:"not null;index" json:"aa_consent_id"`
-	FIType      string         `gorm:"not null" json:"fi_type"`      // "SAVINGS", "CURRENT", etc.
-	Status      string         `gorm:"not null;index" json:"status"` // "PENDING", "ACTIVE", "REVOKED"
-	ValidTill   *time.Time     `json:"valid_till"`
-	CreatedAt   time.Time      `gorm:"default:now()" json:"created_at"`
-	UpdatedAt   time.Time      `gorm:"default:now()" json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                   uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID               uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+	AAConsentID          string         `gorm:"not null;index" json:"aa_consent_id"`
+	FIType               string         `gorm:"not null" json:"fi_type"`      // "SAVINGS", "CURRENT", etc.
+	Status               string         `gorm:"not null;index" json:"status"` // "PENDING", "ACTIVE", "REVOKED"
+	ValidTill            *time.Time     `json:"valid_till"`
+	ExpiryReminderSentAt *time.Time     `json:"expiry_reminder_sent_at,omitempty"` // set once the user was warned the consent is about to expire
+	LastRefreshedAt      *time.Time     `json:"last_refreshed_at,omitempty"`       // last on-demand refresh, for its cooldown
+	CreatedAt            time.Time      `gorm:"default:now()" json:"created_at"`
+	UpdatedAt            time.Time      `gorm:"default:now()" json:"updated_at"`
+	DeletedAt            gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Relationships
 	User         User          `gorm:"foreignKey:UserID" json:"user,omitempty"`
@@ -48,36 +52,80 @@ func (BankLink) TableName() string {
 
 // Transaction represents a bank transaction
 type Transaction struct {
-	ID             uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	UserID         uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
-	BankLinkID     *uuid.UUID     `gorm:"type:uuid;index" json:"bank_link_id"`
-	PostedAt       time.Time      `gorm:"not null;index" json:"posted_at"`
-	ValueDate      *time.Time     `json:"value_date"`
-	Amount         float64        `gorm:"type:numeric(14,2);not null" json:"amount"`
-	Currency       string         `gorm:"default:'INR'" json:"currency"`
-	TxnType        string         `gorm:"not null;index" json:"txn_type"` // "DEBIT" | "CREDIT"
-	BalanceAfter   *float64       `gorm:"type:numeric(14,2)" json:"balance_after"`
-	DescriptionRaw string         `json:"description_raw"`
-	MerchantName   string         `json:"merchant_name"`
-	AccountRef     string         `json:"account_ref"` // masked account / VPA
-	Category       string         `json:"category"`
-	Subcategory    string         `json:"subcategory"`
-	HashDedupe     string         `gorm:"uniqueIndex;not null" json:"hash_dedupe"`
-	SourceMeta     JSONB          `gorm:"type:jsonb;default:'{}'::jsonb" json:"source_meta"`
-	CreatedAt      time.Time      `gorm:"default:now()" json:"created_at"`
-	UpdatedAt      time.Time      `gorm:"default:now()" json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                 uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID             uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+	BankLinkID         *uuid.UUID     `gorm:"type:uuid;index" json:"bank_link_id"`
+	PostedAt           time.Time      `gorm:"not null;index" json:"posted_at"`
+	ValueDate          *time.Time     `json:"value_date"`
+	Amount             float64        `gorm:"type:numeric(14,2);not null" json:"amount"`
+	Currency           string         `gorm:"default:'INR'" json:"currency"`
+	BaseAmount         float64        `gorm:"type:numeric(14,2);not null;default:0" json:"base_amount"` // Amount in BaseCurrency
+	BaseCurrency       string         `gorm:"not null;default:'INR'" json:"base_currency"`              // the user's currency at import
+	TxnType            string         `gorm:"not null;index" json:"txn_type"`                           // "DEBIT" | "CREDIT"
+	BalanceAfter       *float64       `gorm:"type:numeric(14,2)" json:"balance_after"`
+	BalanceReported    bool           `gorm:"not null;default:false" json:"balance_reported"` // BalanceAfter came from the provider rather than a recompute
+	DescriptionRaw     string         `json:"description_raw"`
+	MerchantName       string         `json:"merchant_name"`
+	AccountRef         string         `json:"account_ref"` // masked account / VPA
+	Category           string         `json:"category"`
+	Subcategory        string         `json:"subcategory"`
+	UserCategory       string         `json:"user_category"` // set by the user; overrides Category
+	UserNote           string         `json:"user_note"`
+	CategoryConfidence float64        `gorm:"not null;default:0" json:"category_confidence"` // 0-1, from the normalizer
+	NeedsReview        bool           `gorm:"not null;default:false" json:"needs_review"`    // low confidence or unknown merchant, cleared by UserCategory
+	FlagReason         string         `gorm:"not null;default:''" json:"flag_reason"`        // why the amount is unusual; empty when it isn't
+	ReversalOf         *uuid.UUID     `gorm:"type:uuid" json:"reversal_of,omitempty"`        // the other leg of a reversal pair
+	HashDedupe         string         `gorm:"uniqueIndex;not null" json:"hash_dedupe"`
+	SourceMeta         JSONB          `gorm:"type:jsonb;default:'{}'::jsonb" json:"source_meta"`
+	CreatedAt          time.Time      `gorm:"default:now()" json:"created_at"`
+	UpdatedAt          time.Time      `gorm:"default:now()" json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Relationships
-	User     User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	BankLink *BankLink `gorm:"foreignKey:BankLinkID" json:"bank_link,omitempty"`
+	User     User               `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	BankLink *BankLink          `gorm:"foreignKey:BankLinkID" json:"bank_link,omitempty"`
+	Splits   []TransactionSplit `gorm:"foreignKey:TransactionID" json:"splits,omitempty"`
 }
 
+// Reasons a transaction is flagged as unusually large
+const (
+	FlagLargeAmount     = "LARGE_AMOUNT"     // above the absolute threshold
+	FlagCategoryOutlier = "CATEGORY_OUTLIER" // far above the user's norm for the category
+)
+
 // TableName specifies the table name for Transaction
 func (Transaction) TableName() string {
 	return "transactions"
 }
 
+// EffectiveCategory returns the user's category when set, otherwise the
+// normalizer's
+func (t *Transaction) EffectiveCategory() string {
+	if t.UserCategory != "" {
+		return t.UserCategory
+	}
+	return t.Category
+}
+
+// TransactionSplit is one part of a transaction divided across categories.
+// The parts of a transaction add up to its amount and take the place of its
+// category in summaries.
+type TransactionSplit struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TransactionID uuid.UUID `gorm:"type:uuid;not null;index" json:"transaction_id"`
+	Amount        float64   `gorm:"type:numeric(14,2);not null" json:"amount"`
+	BaseAmount    float64   `gorm:"type:numeric(14,2);not null" json:"base_amount"` // Amount in the transaction's BaseCurrency
+	Category      string    `gorm:"not null" json:"category"`
+	Subcategory   string    `json:"subcategory"`
+	Note          string    `json:"note"`
+	CreatedAt     time.Time `gorm:"default:now()" json:"created_at"`
+}
+
+// TableName specifies the table name for TransactionSplit
+func (TransactionSplit) TableName() string {
+	return "transaction_splits"
+}
+
 // CategoryOverride represents user-defined category rules
 type CategoryOverride struct {
 	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
@@ -96,6 +144,86 @@ func (CategoryOverride) TableName() string {
 	return "category_overrides"
 }
 
+// MerchantAlias maps transaction descriptions matching Pattern to a
+// canonical merchant name. Aliases without a user apply to everyone; a
+// user's own aliases take precedence over them.
+type MerchantAlias struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID    *uuid.UUID `gorm:"type:uuid;index" json:"user_id"` // nil for a global alias
+	Pattern   string     `gorm:"not null" json:"pattern"`        // "/regex/" or case-insensitive substring
+	Merchant  string     `gorm:"not null" json:"merchant"`
+	CreatedAt time.Time  `gorm:"default:now()" json:"created_at"`
+}
+
+// TableName specifies the table name for MerchantAlias
+func (MerchantAlias) TableName() string {
+	return "merchant_aliases"
+}
+
+// ProcessedSession records an AA data session whose transactions have been ingested
+type ProcessedSession struct {
+	SessionID        string     `gorm:"primaryKey" json:"session_id"`
+	BankLinkID       *uuid.UUID `gorm:"type:uuid;index" json:"bank_link_id"`
+	TransactionCount int        `gorm:"not null;default:0" json:"transaction_count"`
+	ProcessedAt      time.Time  `gorm:"default:now()" json:"processed_at"`
+}
+
+// TableName specifies the table name for ProcessedSession
+func (ProcessedSession) TableName() string {
+	return "processed_sessions"
+}
+
+// DataSession records who an AA data session was created for, so its
+// DATA_READY webhook can be ingested into the right user's bank link
+type DataSession struct {
+	SessionID  string    `gorm:"primaryKey" json:"session_id"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
+	BankLinkID uuid.UUID `gorm:"type:uuid;not null" json:"bank_link_id"`
+	DryRun     bool      `gorm:"not null;default:false" json:"dry_run"`
+	CreatedAt  time.Time `gorm:"default:now()" json:"created_at"`
+}
+
+// TableName specifies the table name for DataSession
+func (DataSession) TableName() string {
+	return "data_sessions"
+}
+
+// Webhook event types
+const (
+	WebhookEventConsentCallback = "CONSENT_CALLBACK"
+	WebhookEventDataReady       = "DATA_READY"
+	WebhookEventConsentRevoked  = "CONSENT_REVOKED"
+)
+
+// Webhook event statuses. An event is PENDING while being handled and
+// REPLAYING while an admin replay is in flight.
+const (
+	WebhookEventPending   = "PENDING"
+	WebhookEventProcessed = "PROCESSED"
+	WebhookEventFailed    = "FAILED"
+	WebhookEventReplaying = "REPLAYING"
+)
+
+// WebhookEvent is a verified AA webhook delivery and the outcome of handling it
+type WebhookEvent struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	EventType   string     `gorm:"not null" json:"event_type"`
+	ConsentID   string     `gorm:"not null;default:''" json:"consent_id,omitempty"`
+	SessionID   string     `gorm:"not null;default:''" json:"session_id,omitempty"`
+	Payload     string     `gorm:"not null" json:"payload"` // raw body exactly as signed
+	Signature   string     `gorm:"not null;default:''" json:"signature"`
+	Status      string     `gorm:"not null;default:'PENDING'" json:"status"`
+	Error       string     `gorm:"not null;default:''" json:"error,omitempty"`
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	ReceivedAt  time.Time  `gorm:"default:now()" json:"received_at"`
+	ProcessedAt *time.Time `json:"processed_at,omitempty"`
+}
+
+// TableName specifies the table name for WebhookEvent
+func (WebhookEvent) TableName() string {
+	return "webhook_events"
+}
+
 // JSONB is a custom type for PostgreSQL JSONB
 type JSONB map[string]interface{}
 
@@ -84,6 +84,9 @@ type AAClient interface {
 	
 	// RevokeConsent revokes an active consent
 	RevokeConsent(consentID string) error
+
+	// VerifySignature verifies the signature of an inbound webhook body
+	VerifySignature(payload []byte, signature string) bool
 }
 
 // WebhookEvent represents an incoming webhook event from AA
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+)
+
+func ptrFloat(v float64) *float64 { return &v }
+
+// addLinkTransaction stores a transaction on the bank link posted on the
+// given day of January 2025
+func addLinkTransaction(store *memStore, link *domain.BankLink, day int, txnType string, amount float64, reported *float64) *domain.Transaction {
+	txn := &domain.Transaction{
+		ID:              uuid.New(),
+		UserID:          link.UserID,
+		BankLinkID:      &link.ID,
+		PostedAt:        time.Date(2025, time.January, day, 10, 0, 0, 0, time.UTC),
+		Amount:          amount,
+		TxnType:         txnType,
+		BalanceAfter:    reported,
+		BalanceReported: reported != nil,
+		HashDedupe:      uuid.NewString(),
+	}
+	store.mu.Lock()
+	store.transactions[txn.ID] = txn
+	store.mu.Unlock()
+	return txn
+}
+
+func balanceOf(t *testing.T, store *memStore, id uuid.UUID) float64 {
+	t.Helper()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	txn := store.transactions[id]
+	if txn.BalanceAfter == nil {
+		t.Fatalf("transaction %s has no balance", id)
+	}
+	return *txn.BalanceAfter
+}
+
+func TestRecomputeBalancesInterleavedDebitsAndCredits(t *testing.T) {
+	store := newMemStore()
+	service, _ := newTestAAService(t, store)
+	user := store.addUser("INR")
+	link := store.addBankLink(user.ID, "consent-1", "ACTIVE")
+
+	opening := addLinkTransaction(store, link, 1, "CREDIT", 1000, ptrFloat(1000))
+	debit := addLinkTransaction(store, link, 2, "DEBIT", 250.50, nil)
+	credit := addLinkTransaction(store, link, 3, "CREDIT", 100.25, nil)
+	debit2 := addLinkTransaction(store, link, 4, "DEBIT", 49.75, nil)
+
+	if err := service.recomputeBalances(context.Background(), link.ID, debit.PostedAt); err != nil {
+		t.Fatalf("recomputeBalances: %v", err)
+	}
+
+	want := map[uuid.UUID]float64{opening.ID: 1000, debit.ID: 749.50, credit.ID: 849.75, debit2.ID: 800}
+	for id, balance := range want {
+		if got := balanceOf(t, store, id); got != balance {
+			t.Errorf("balance of %s = %.2f, want %.2f", id, got, balance)
+		}
+	}
+}
+
+func TestRecomputeBalancesKeepsStoredProviderBalances(t *testing.T) {
+	store := newMemStore()
+	service, _ := newTestAAService(t, store)
+	user := store.addUser("INR")
+	link := store.addBankLink(user.ID, "consent-1", "ACTIVE")
+
+	// An earlier import stored a provider-reported balance on the 3rd
+	reported := addLinkTransaction(store, link, 3, "CREDIT", 500, ptrFloat(5000))
+	after := addLinkTransaction(store, link, 4, "DEBIT", 200, nil)
+	if err := service.recomputeBalances(context.Background(), link.ID, reported.PostedAt); err != nil {
+		t.Fatal(err)
+	}
+
+	// A late delivery for the 2nd arrives without balances; recomputing from
+	// it must not replace the provider balance with a running total
+	late := addLinkTransaction(store, link, 2, "DEBIT", 100, nil)
+	if err := service.recomputeBalances(context.Background(), link.ID, late.PostedAt); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := balanceOf(t, store, late.ID); got != -100 {
+		t.Errorf("late balance = %.2f, want -100.00", got)
+	}
+	if got := balanceOf(t, store, reported.ID); got != 5000 {
+		t.Errorf("provider balance was overwritten with %.2f", got)
+	}
+	if got := balanceOf(t, store, after.ID); got != 4800 {
+		t.Errorf("balance after the anchor = %.2f, want 4800.00", got)
+	}
+}
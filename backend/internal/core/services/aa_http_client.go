@@ -0,0 +1,188 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your-github/expense-tracker-backend/config"
+	"github.com/your-github/expense-tracker-backend/internal/core/ports"
+)
+
+// Headers attached to every outbound AA request
+const (
+	aaHeaderAPIKey    = "X-API-Key"
+	aaHeaderClientID  = "X-Client-ID"
+	aaHeaderTimestamp = "X-Timestamp"
+	aaHeaderSignature = "X-Signature"
+)
+
+// HTTPAAClient implements the AAClient interface against a provider's REST API
+type HTTPAAClient struct {
+	baseURL    string
+	apiKey     string
+	clientID   string
+	signer     Signer
+	httpClient *http.Client
+
+	// WebhookSigner verifies inbound webhooks; nil rejects all of them
+	WebhookSigner Signer
+}
+
+// NewHTTPAAClient creates a new AA client for the configured provider,
+// signing requests with the configured scheme
+func NewHTTPAAClient(cfg config.AAConfig) (*HTTPAAClient, error) {
+	signer, err := NewRequestSigner(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &HTTPAAClient{
+		baseURL:    strings.TrimRight(cfg.BaseURL, "/"),
+		apiKey:     cfg.APIKey,
+		clientID:   cfg.ClientID,
+		signer:     signer,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// consentStatusResponse is the provider payload for consent lookups
+type consentStatusResponse struct {
+	ConsentID string `json:"consent_id"`
+	Status    string `json:"status"`
+}
+
+// sessionStatusResponse is the provider payload for session lookups
+type sessionStatusResponse struct {
+	SessionID string `json:"session_id"`
+	Status    string `json:"status"`
+}
+
+// transactionsResponse is the provider payload for a session's transactions
+type transactionsResponse struct {
+	Transactions []ports.FITransaction `json:"transactions"`
+}
+
+// CreateConsent creates a consent request with the provider
+func (c *HTTPAAClient) CreateConsent(req ports.ConsentRequest) (ports.ConsentHandle, error) {
+	var handle ports.ConsentHandle
+	if err := c.do(http.MethodPost, "/consents", req, &handle); err != nil {
+		return ports.ConsentHandle{}, err
+	}
+	return handle, nil
+}
+
+// GetConsentStatus retrieves the current status of a consent
+func (c *HTTPAAClient) GetConsentStatus(consentID string) (ports.ConsentStatus, error) {
+	var resp consentStatusResponse
+	if err := c.do(http.MethodGet, "/consents/"+url.PathEscape(consentID), nil, &resp); err != nil {
+		return "", err
+	}
+	return ports.ConsentStatus(strings.ToUpper(resp.Status)), nil
+}
+
+// CreateDataSession creates a data fetch session for an approved consent
+func (c *HTTPAAClient) CreateDataSession(consentID string, fromISO, toISO string) (ports.DataSession, error) {
+	body := map[string]string{
+		"consent_id": consentID,
+		"from":       fromISO,
+		"to":         toISO,
+	}
+
+	var session ports.DataSession
+	if err := c.do(http.MethodPost, "/sessions", body, &session); err != nil {
+		return ports.DataSession{}, err
+	}
+	return session, nil
+}
+
+// GetSessionStatus retrieves the current status of a data session
+func (c *HTTPAAClient) GetSessionStatus(sessionID string) (ports.SessionStatus, error) {
+	var resp sessionStatusResponse
+	if err := c.do(http.MethodGet, "/sessions/"+url.PathEscape(sessionID), nil, &resp); err != nil {
+		return "", err
+	}
+	return ports.SessionStatus(strings.ToUpper(resp.Status)), nil
+}
+
+// FetchTransactions fetches the transactions delivered for a session
+func (c *HTTPAAClient) FetchTransactions(sessionID string) ([]ports.FITransaction, error) {
+	var resp transactionsResponse
+	if err := c.do(http.MethodGet, "/sessions/"+url.PathEscape(sessionID)+"/transactions", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Transactions, nil
+}
+
+// RevokeConsent revokes an active consent
+func (c *HTTPAAClient) RevokeConsent(consentID string) error {
+	return c.do(http.MethodPost, "/consents/"+url.PathEscape(consentID)+"/revoke", nil, nil)
+}
+
+// VerifySignature verifies a webhook body with WebhookSigner
+func (c *HTTPAAClient) VerifySignature(payload []byte, signature string) bool {
+	return verifyWebhook(c.WebhookSigner, payload, signature)
+}
+
+// do sends a signed JSON request and decodes the response into out when non-nil
+func (c *HTTPAAClient) do(method, path string, in, out interface{}) error {
+	var payload []byte
+	if in != nil {
+		var err error
+		payload, err = json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to marshal AA request: %w", err)
+		}
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create AA request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature, err := c.signer.Sign(RequestSigningPayload(method, path, timestamp, payload))
+	if err != nil {
+		return fmt.Errorf("failed to sign AA request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set(aaHeaderAPIKey, c.apiKey)
+	req.Header.Set(aaHeaderClientID, c.clientID)
+	req.Header.Set(aaHeaderTimestamp, timestamp)
+	req.Header.Set(aaHeaderSignature, signature)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: AA request %s %s failed: %w", ErrProviderUnavailable, method, path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%w: failed to read AA response: %w", ErrProviderUnavailable, err)
+	}
+
+	// Throttling and server-side failures are the provider's, not the caller's
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return fmt.Errorf("%w: AA request %s %s returned status %d: %s", ErrProviderUnavailable, method, path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("AA request %s %s returned status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if out == nil || len(body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse AA response: %w", err)
+	}
+	return nil
+}
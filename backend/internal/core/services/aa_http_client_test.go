@@ -0,0 +1,205 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/your-github/expense-tracker-backend/config"
+	"github.com/your-github/expense-tracker-backend/internal/core/ports"
+)
+
+const testAAClientSecret = "aa-client-secret"
+
+// fakeProvider emulates an AA provider's REST API. It rejects requests
+// whose signature doesn't verify with the client secret and records the
+// requests it accepted.
+type fakeProvider struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []string
+	status   int // when set, every request is answered with it
+}
+
+func (p *fakeProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	signer := NewHMACSigner(testAAClientSecret)
+	payload := RequestSigningPayload(r.Method, r.URL.Path, r.Header.Get(aaHeaderTimestamp), body)
+	if !signer.Verify(payload, r.Header.Get(aaHeaderSignature)) {
+		http.Error(w, "bad signature", http.StatusUnauthorized)
+		return
+	}
+	if r.Header.Get(aaHeaderAPIKey) != "aa-api-key" || r.Header.Get(aaHeaderClientID) != "aa-client-id" {
+		http.Error(w, "unknown client", http.StatusForbidden)
+		return
+	}
+
+	p.mu.Lock()
+	p.requests = append(p.requests, r)
+	p.bodies = append(p.bodies, string(body))
+	status := p.status
+	p.mu.Unlock()
+	if status != 0 {
+		http.Error(w, "provider says no", status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/consents":
+		io.WriteString(w, `{"consent_id":"consent-42","redirect_url":"https://aa.example/approve/42","status":"PENDING"}`)
+	case r.Method == http.MethodGet && r.URL.Path == "/consents/consent-42":
+		io.WriteString(w, `{"consent_id":"consent-42","status":"active"}`)
+	case r.Method == http.MethodPost && r.URL.Path == "/consents/consent-42/revoke":
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && r.URL.Path == "/sessions":
+		io.WriteString(w, `{"session_id":"session-7","status":"PENDING"}`)
+	case r.Method == http.MethodGet && r.URL.Path == "/sessions/session-7":
+		io.WriteString(w, `{"session_id":"session-7","status":"ready"}`)
+	case r.Method == http.MethodGet && r.URL.Path == "/sessions/session-7/transactions":
+		io.WriteString(w, `{"transactions":[
+			{"posted_at":"2025-03-01T10:00:00Z","amount":499,"currency":"INR","type":"DEBIT","description_raw":"NETFLIX","account_ref":"XX1234","balance_after":10501,"source_meta":{"txn_ref":"R1"}},
+			{"posted_at":"2025-03-02T10:00:00Z","amount":50000,"currency":"INR","type":"CREDIT","description_raw":"SALARY","account_ref":"XX1234"}
+		]}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestHTTPAAClient(t *testing.T) (*HTTPAAClient, *fakeProvider) {
+	t.Helper()
+	provider := &fakeProvider{}
+	server := httptest.NewServer(provider)
+	t.Cleanup(server.Close)
+
+	client, err := NewHTTPAAClient(config.AAConfig{
+		BaseURL:      server.URL + "/",
+		APIKey:       "aa-api-key",
+		ClientID:     "aa-client-id",
+		ClientSecret: testAAClientSecret,
+		Provider:     "sandbox",
+	})
+	if err != nil {
+		t.Fatalf("NewHTTPAAClient: %v", err)
+	}
+	return client, provider
+}
+
+func TestHTTPAAClientConsentFlow(t *testing.T) {
+	client, provider := newTestHTTPAAClient(t)
+
+	handle, err := client.CreateConsent(ports.ConsentRequest{UserID: "user-1", FIType: "DEPOSIT", Purpose: "budgeting"})
+	if err != nil {
+		t.Fatalf("CreateConsent: %v", err)
+	}
+	if handle.ConsentID != "consent-42" || handle.RedirectURL != "https://aa.example/approve/42" {
+		t.Errorf("handle = %+v", handle)
+	}
+	var sent ports.ConsentRequest
+	if err := json.Unmarshal([]byte(provider.bodies[0]), &sent); err != nil || sent.UserID != "user-1" || sent.FIType != "DEPOSIT" {
+		t.Errorf("consent request body = %s", provider.bodies[0])
+	}
+
+	status, err := client.GetConsentStatus("consent-42")
+	if err != nil {
+		t.Fatalf("GetConsentStatus: %v", err)
+	}
+	if status != ports.ConsentStatusActive {
+		t.Errorf("status = %q, want ACTIVE", status)
+	}
+
+	if err := client.RevokeConsent("consent-42"); err != nil {
+		t.Fatalf("RevokeConsent: %v", err)
+	}
+}
+
+func TestHTTPAAClientFetchFlow(t *testing.T) {
+	client, provider := newTestHTTPAAClient(t)
+
+	session, err := client.CreateDataSession("consent-42", "2025-03-01", "2025-03-31")
+	if err != nil {
+		t.Fatalf("CreateDataSession: %v", err)
+	}
+	if session.SessionID != "session-7" {
+		t.Errorf("session = %+v", session)
+	}
+	if !strings.Contains(provider.bodies[0], `"from":"2025-03-01"`) || !strings.Contains(provider.bodies[0], `"consent_id":"consent-42"`) {
+		t.Errorf("session request body = %s", provider.bodies[0])
+	}
+
+	status, err := client.GetSessionStatus("session-7")
+	if err != nil || status != ports.SessionStatusReady {
+		t.Fatalf("GetSessionStatus = %q, %v", status, err)
+	}
+
+	transactions, err := client.FetchTransactions("session-7")
+	if err != nil {
+		t.Fatalf("FetchTransactions: %v", err)
+	}
+	if len(transactions) != 2 {
+		t.Fatalf("got %d transactions, want 2", len(transactions))
+	}
+	first := transactions[0]
+	if first.Amount != 499 || first.Type != "DEBIT" || first.BalanceAfter == nil || *first.BalanceAfter != 10501 || first.SourceMeta["txn_ref"] != "R1" {
+		t.Errorf("first transaction = %+v", first)
+	}
+	if transactions[1].BalanceAfter != nil {
+		t.Error("a transaction without a balance got one")
+	}
+}
+
+func TestHTTPAAClientErrors(t *testing.T) {
+	tests := []struct {
+		status      int
+		unavailable bool
+	}{
+		{http.StatusBadRequest, false},
+		{http.StatusNotFound, false},
+		{http.StatusTooManyRequests, true},
+		{http.StatusBadGateway, true},
+	}
+	for _, tt := range tests {
+		client, provider := newTestHTTPAAClient(t)
+		provider.status = tt.status
+
+		_, err := client.GetConsentStatus("consent-42")
+		if err == nil {
+			t.Errorf("status %d: expected an error", tt.status)
+			continue
+		}
+		if errors.Is(err, ErrProviderUnavailable) != tt.unavailable {
+			t.Errorf("status %d: err = %v, provider unavailable should be %v", tt.status, err, tt.unavailable)
+		}
+	}
+
+	// Nothing listening at all
+	client, err := NewHTTPAAClient(config.AAConfig{BaseURL: "http://127.0.0.1:1", ClientSecret: testAAClientSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetSessionStatus("session-7"); !errors.Is(err, ErrProviderUnavailable) {
+		t.Errorf("err = %v, want ErrProviderUnavailable", err)
+	}
+}
+
+func TestHTTPAAClientRejectedWithWrongSecret(t *testing.T) {
+	provider := &fakeProvider{}
+	server := httptest.NewServer(provider)
+	defer server.Close()
+	client, err := NewHTTPAAClient(config.AAConfig{BaseURL: server.URL, APIKey: "aa-api-key", ClientID: "aa-client-id", ClientSecret: "wrong"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.GetConsentStatus("consent-42"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("err = %v, want the provider's 401", err)
+	}
+	if len(provider.requests) != 0 {
+		t.Error("the provider accepted a request signed with the wrong secret")
+	}
+}
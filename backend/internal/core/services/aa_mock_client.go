@@ -1,9 +1,6 @@
 package services
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
 	"math/rand"
 	"strings"
@@ -14,11 +11,35 @@ import (
 	"github.com/your-github/expense-tracker-backend/internal/core/ports"
 )
 
+// Mock data session readiness
+const (
+	// DefaultMockSessionReadyDelay is how long a mock data session stays
+	// PENDING before it turns READY
+	DefaultMockSessionReadyDelay = 2 * time.Second
+	// MockSessionNeverReady keeps data sessions PENDING until
+	// SimulateSessionReady is called
+	MockSessionNeverReady time.Duration = -1
+)
+
 // MockAAClient implements the AAClient interface for testing
 type MockAAClient struct {
 	consents map[string]*MockConsent
 	sessions map[string]*MockSession
 	mu       sync.RWMutex
+
+	// readyDelay and now decide when a data session turns READY; both are
+	// guarded by mu
+	readyDelay time.Duration
+	now        func() time.Time
+
+	// rng drives generated transactions; rand.Rand isn't safe for
+	// concurrent use, so it has its own lock
+	rng   *rand.Rand
+	rngMu sync.Mutex
+
+	// WebhookSigner verifies inbound webhooks exactly as the HTTP client
+	// does; nil rejects all of them
+	WebhookSigner Signer
 }
 
 // MockConsent represents a mock consent in memory
@@ -40,13 +61,53 @@ type MockSession struct {
 	FromDate  string
 	ToDate    string
 	CreatedAt time.Time
+	ReadyAt   time.Time // zero when the session only turns READY through SimulateSessionReady
+
+	// transactions are generated on the first fetch and returned unchanged
+	// afterwards, as a real AA serves the same data for a session
+	transactions []ports.FITransaction
 }
 
-// NewMockAAClient creates a new mock AA client
+// NewMockAAClient creates a new mock AA client seeded from the clock
 func NewMockAAClient() *MockAAClient {
+	return NewMockAAClientWithRand(rand.New(rand.NewSource(time.Now().UnixNano())))
+}
+
+// NewMockAAClientWithRand creates a mock AA client whose generated
+// transactions come from rng, so a fixed seed gives reproducible data
+func NewMockAAClientWithRand(rng *rand.Rand) *MockAAClient {
 	return &MockAAClient{
-		consents: make(map[string]*MockConsent),
-		sessions: make(map[string]*MockSession),
+		consents:   make(map[string]*MockConsent),
+		sessions:   make(map[string]*MockSession),
+		readyDelay: DefaultMockSessionReadyDelay,
+		now:        time.Now,
+		rng:        rng,
+	}
+}
+
+// SetSessionReadyDelay sets how long data sessions created from now on stay
+// PENDING. Zero makes them READY as soon as they are created, and
+// MockSessionNeverReady (or any negative delay) keeps them PENDING until
+// SimulateSessionReady.
+func (m *MockAAClient) SetSessionReadyDelay(delay time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.readyDelay = delay
+}
+
+// SetClock replaces the clock that session readiness is checked against,
+// so delayed sessions can be tested without sleeping
+func (m *MockAAClient) SetClock(now func() time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = now
+}
+
+// refreshSession turns session READY once its ready time has passed.
+// The caller must hold mu for writing.
+func (m *MockAAClient) refreshSession(session *MockSession) {
+	if session.Status == ports.SessionStatusPending && !session.ReadyAt.IsZero() && !m.now().Before(session.ReadyAt) {
+		session.Status = ports.SessionStatusReady
 	}
 }
 
@@ -77,16 +138,21 @@ func (m *MockAAClient) CreateConsent(req ports.ConsentRequest) (ports.ConsentHan
 	}, nil
 }
 
-// GetConsentStatus retrieves the status of a mock consent
+// GetConsentStatus retrieves the status of a mock consent, expiring it once
+// its validity window has passed
 func (m *MockAAClient) GetConsentStatus(consentID string) (ports.ConsentStatus, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	consent, exists := m.consents[consentID]
 	if !exists {
 		return "", fmt.Errorf("consent not found: %s", consentID)
 	}
 
+	if consent.Status == ports.ConsentStatusActive && time.Now().After(consent.ValidTill) {
+		consent.Status = ports.ConsentStatusExpired
+	}
+
 	return consent.Status, nil
 }
 
@@ -102,31 +168,29 @@ func (m *MockAAClient) CreateDataSession(consentID string, fromISO, toISO string
 	}
 
 	if consent.Status != ports.ConsentStatusActive {
-		return ports.DataSession{}, fmt.Errorf("consent is not active: %s", consentID)
+		return ports.DataSession{}, fmt.Errorf("%w: %s", ErrConsentNotActive, consentID)
 	}
 
 	sessionID := fmt.Sprintf("session_%s", uuid.New().String()[:8])
 
+	now := m.now()
 	session := &MockSession{
 		SessionID: sessionID,
 		ConsentID: consentID,
 		Status:    ports.SessionStatusPending,
 		FromDate:  fromISO,
 		ToDate:    toISO,
-		CreatedAt: time.Now(),
+		CreatedAt: now,
 	}
 
-	m.sessions[sessionID] = session
+	// Simulate async processing: the session turns READY once readyDelay
+	// has passed, checked whenever it is looked at
+	if m.readyDelay >= 0 {
+		session.ReadyAt = now.Add(m.readyDelay)
+	}
+	m.refreshSession(session)
 
-	// Simulate async processing - change status to READY after a short delay
-	go func() {
-		time.Sleep(2 * time.Second)
-		m.mu.Lock()
-		if s, exists := m.sessions[sessionID]; exists {
-			s.Status = ports.SessionStatusReady
-		}
-		m.mu.Unlock()
-	}()
+	m.sessions[sessionID] = session
 
 	return ports.DataSession{
 		SessionID: sessionID,
@@ -136,34 +200,45 @@ func (m *MockAAClient) CreateDataSession(consentID string, fromISO, toISO string
 
 // GetSessionStatus retrieves the status of a mock session
 func (m *MockAAClient) GetSessionStatus(sessionID string) (ports.SessionStatus, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	session, exists := m.sessions[sessionID]
 	if !exists {
 		return "", fmt.Errorf("session not found: %s", sessionID)
 	}
 
+	m.refreshSession(session)
 	return session.Status, nil
 }
 
 // FetchTransactions generates mock transactions for a session
 func (m *MockAAClient) FetchTransactions(sessionID string) ([]ports.FITransaction, error) {
-	m.mu.RLock()
+	m.mu.Lock()
 	session, exists := m.sessions[sessionID]
-	m.mu.RUnlock()
+	var status ports.SessionStatus
+	if exists {
+		m.refreshSession(session)
+		status = session.Status
+	}
+	m.mu.Unlock()
 
 	if !exists {
 		return nil, fmt.Errorf("session not found: %s", sessionID)
 	}
 
-	if session.Status != ports.SessionStatusReady {
+	if status != ports.SessionStatusReady {
 		return nil, fmt.Errorf("session is not ready: %s", sessionID)
 	}
 
-	// Generate mock transactions
-	transactions := generateMockTransactions(session.FromDate, session.ToDate)
-	return transactions, nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if session.transactions == nil {
+		m.rngMu.Lock()
+		session.transactions = generateMockTransactions(m.rng, session.FromDate, session.ToDate)
+		m.rngMu.Unlock()
+	}
+	return append([]ports.FITransaction(nil), session.transactions...), nil
 }
 
 // RevokeConsent revokes a mock consent
@@ -194,8 +269,23 @@ func (m *MockAAClient) SimulateConsentApproval(consentID string) error {
 	return nil
 }
 
+// SimulateSessionReady simulates the AA finishing a data session (for
+// testing), whatever its ready delay
+func (m *MockAAClient) SimulateSessionReady(sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, exists := m.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	session.Status = ports.SessionStatusReady
+	return nil
+}
+
 // generateMockTransactions creates realistic mock transactions
-func generateMockTransactions(fromDate, toDate string) []ports.FITransaction {
+func generateMockTransactions(rng *rand.Rand, fromDate, toDate string) []ports.FITransaction {
 	transactions := []ports.FITransaction{}
 
 	// Parse date range
@@ -206,28 +296,28 @@ func generateMockTransactions(fromDate, toDate string) []ports.FITransaction {
 	current := from
 	for current.Before(to) || current.Equal(to) {
 		// Generate 1-5 transactions per day
-		numTxns := rand.Intn(5) + 1
+		numTxns := rng.Intn(5) + 1
 
 		for i := 0; i < numTxns; i++ {
 			// Random time during the day
-			hour := rand.Intn(24)
-			minute := rand.Intn(60)
+			hour := rng.Intn(24)
+			minute := rng.Intn(60)
 			postedAt := time.Date(current.Year(), current.Month(), current.Day(), hour, minute, 0, 0, time.UTC)
 
 			// Random amount between 100 and 10000
-			amount := float64(rand.Intn(9900) + 100)
+			amount := float64(rng.Intn(9900) + 100)
 
 			// Random transaction type
 			txnType := "DEBIT"
-			if rand.Float32() < 0.3 { // 30% chance of credit
+			if rng.Float32() < 0.3 { // 30% chance of credit
 				txnType = "CREDIT"
 			}
 
 			// Generate realistic descriptions
-			description, merchant := generateMockDescription(txnType)
+			description, merchant := generateMockDescription(rng, txnType)
 
 			// Generate account reference
-			accountRef := generateMockAccountRef()
+			accountRef := generateMockAccountRef(rng)
 
 			transaction := ports.FITransaction{
 				PostedAt:       postedAt.Format(time.RFC3339),
@@ -255,7 +345,7 @@ func generateMockTransactions(fromDate, toDate string) []ports.FITransaction {
 }
 
 // generateMockDescription creates realistic transaction descriptions
-func generateMockDescription(txnType string) (string, string) {
+func generateMockDescription(rng *rand.Rand, txnType string) (string, string) {
 	var merchants []string
 
 	// Select merchants based on transaction type
@@ -272,39 +362,39 @@ func generateMockDescription(txnType string) (string, string) {
 		}
 	}
 
-	merchant := merchants[rand.Intn(len(merchants))]
+	merchant := merchants[rng.Intn(len(merchants))]
 
 	var description string
 	switch merchant {
 	case "SWIGGY", "ZOMATO":
-		description = fmt.Sprintf("%s/ORDER/%s", merchant, strings.ToUpper(generateRandomString(8)))
+		description = fmt.Sprintf("%s/ORDER/%s", merchant, strings.ToUpper(generateRandomString(rng, 8)))
 	case "AMAZON", "FLIPKART":
-		description = fmt.Sprintf("%s/PAYMENT/%s", merchant, strings.ToUpper(generateRandomString(8)))
+		description = fmt.Sprintf("%s/PAYMENT/%s", merchant, strings.ToUpper(generateRandomString(rng, 8)))
 	case "UBER", "OLA":
-		description = fmt.Sprintf("%s/RIDE/%s", merchant, strings.ToUpper(generateRandomString(8)))
+		description = fmt.Sprintf("%s/RIDE/%s", merchant, strings.ToUpper(generateRandomString(rng, 8)))
 	case "PAYTM", "PHONEPE", "GOOGLE PAY":
-		description = fmt.Sprintf("%s/UPI/%s@%s", merchant, generateRandomString(6), generateRandomString(3))
+		description = fmt.Sprintf("%s/UPI/%s@%s", merchant, generateRandomString(rng, 6), generateRandomString(rng, 3))
 	case "ATM WITHDRAWAL":
 		description = "ATM WITHDRAWAL/XXXX1234/BRANCH"
 	case "NEFT", "IMPS":
-		description = fmt.Sprintf("%s/TRANSFER/%s", merchant, generateRandomString(8))
+		description = fmt.Sprintf("%s/TRANSFER/%s", merchant, generateRandomString(rng, 8))
 	case "UPI":
-		description = fmt.Sprintf("UPI/%s@%s", generateRandomString(6), generateRandomString(3))
+		description = fmt.Sprintf("UPI/%s@%s", generateRandomString(rng, 6), generateRandomString(rng, 3))
 	case "INTEREST CREDIT":
 		description = "INTEREST CREDIT/SAVINGS ACCOUNT"
 	case "SALARY CREDIT":
 		description = "SALARY CREDIT/COMPANY NAME"
 	case "REFUND":
-		description = fmt.Sprintf("REFUND/%s", strings.ToUpper(generateRandomString(8)))
+		description = fmt.Sprintf("REFUND/%s", strings.ToUpper(generateRandomString(rng, 8)))
 	default:
-		description = fmt.Sprintf("%s/%s", merchant, strings.ToUpper(generateRandomString(8)))
+		description = fmt.Sprintf("%s/%s", merchant, strings.ToUpper(generateRandomString(rng, 8)))
 	}
 
 	return description, merchant
 }
 
 // generateMockAccountRef creates realistic account references
-func generateMockAccountRef() string {
+func generateMockAccountRef(rng *rand.Rand) string {
 	patterns := []string{
 		"XXXX1234",     // Masked account number
 		"user@upi",     // UPI VPA
@@ -313,28 +403,27 @@ func generateMockAccountRef() string {
 		"user@ybl",     // UPI VPA
 	}
 
-	return patterns[rand.Intn(len(patterns))]
+	return patterns[rng.Intn(len(patterns))]
 }
 
 // generateRandomString creates a random string of given length
-func generateRandomString(length int) string {
+func generateRandomString(rng *rand.Rand, length int) string {
 	const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	result := make([]byte, length)
 	for i := range result {
-		result[i] = charset[rand.Intn(len(charset))]
+		result[i] = charset[rng.Intn(len(charset))]
 	}
 	return string(result)
 }
 
-// VerifySignature verifies webhook signature (mock implementation)
+// VerifySignature verifies a webhook body with WebhookSigner
 func (m *MockAAClient) VerifySignature(payload []byte, signature string) bool {
-	// Mock signature verification - always returns true for testing
-	return true
+	return verifyWebhook(m.WebhookSigner, payload, signature)
 }
 
-// GenerateSignature generates a mock signature for webhooks
+// GenerateSignature signs a webhook body with the HMAC secret, as the
+// provider would, so mock webhooks pass VerifySignature
 func (m *MockAAClient) GenerateSignature(payload []byte, secret string) string {
-	h := hmac.New(sha256.New, []byte(secret))
-	h.Write(payload)
-	return hex.EncodeToString(h.Sum(nil))
+	signature, _ := NewHMACSigner(secret).Sign(payload)
+	return signature
 }
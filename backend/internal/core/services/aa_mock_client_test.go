@@ -0,0 +1,189 @@
+package services
+
+import (
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/your-github/expense-tracker-backend/internal/core/ports"
+)
+
+// fetchSeeded fetches a March 2025 session from a mock client seeded with
+// seed, dropping the generation timestamp from each row
+func fetchSeeded(t *testing.T, seed int64) []ports.FITransaction {
+	t.Helper()
+	client := NewMockAAClientWithRand(rand.New(rand.NewSource(seed)))
+	client.SetSessionReadyDelay(0)
+	handle, err := client.CreateConsent(ports.ConsentRequest{UserID: "user-1", FIType: "DEPOSIT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SimulateConsentApproval(handle.ConsentID); err != nil {
+		t.Fatal(err)
+	}
+	session, err := client.CreateDataSession(handle.ConsentID, "2025-03-01", "2025-03-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	txns, err := client.FetchTransactions(session.SessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range txns {
+		txns[i].SourceMeta = nil
+	}
+	return txns
+}
+
+func TestMockAAClientSeedReproducesTransactions(t *testing.T) {
+	first := fetchSeeded(t, 42)
+	if len(first) < 31 {
+		t.Fatalf("generated %d transactions for 31 days, want at least one a day", len(first))
+	}
+	if again := fetchSeeded(t, 42); !reflect.DeepEqual(first, again) {
+		t.Error("the same seed generated different transactions")
+	}
+	if other := fetchSeeded(t, 7); reflect.DeepEqual(first, other) {
+		t.Error("different seeds generated the same transactions")
+	}
+}
+
+func TestAAClientsShareWebhookVerification(t *testing.T) {
+	mock := NewMockAAClient()
+	body := []byte(`{"event_type":"DATA_READY","session_id":"session_1"}`)
+	signature := mock.GenerateSignature(body, "webhook-secret")
+
+	// Without a signer nothing is trusted, not even a valid signature
+	if mock.VerifySignature(body, signature) {
+		t.Error("the mock trusted a webhook without a signer")
+	}
+
+	mock.WebhookSigner = NewHMACSigner("webhook-secret")
+	httpClient := &HTTPAAClient{WebhookSigner: NewHMACSigner("webhook-secret")}
+	for _, client := range []ports.AAClient{mock, httpClient} {
+		if !client.VerifySignature(body, signature) {
+			t.Errorf("%T rejected the mock's signature", client)
+		}
+		if client.VerifySignature([]byte(`{"event_type":"DATA_READY","session_id":"session_2"}`), signature) {
+			t.Errorf("%T accepted a tampered body", client)
+		}
+		if client.VerifySignature(body, mock.GenerateSignature(body, "other-secret")) {
+			t.Errorf("%T accepted a signature made with another secret", client)
+		}
+		if client.VerifySignature(body, "") {
+			t.Errorf("%T accepted an unsigned body", client)
+		}
+	}
+}
+
+// newReadyingSession opens a data session on a fresh mock client whose
+// sessions turn READY after delay
+func newReadyingSession(t *testing.T, client *MockAAClient, delay time.Duration) string {
+	t.Helper()
+	client.SetSessionReadyDelay(delay)
+	handle, err := client.CreateConsent(ports.ConsentRequest{UserID: "user-1", FIType: "DEPOSIT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SimulateConsentApproval(handle.ConsentID); err != nil {
+		t.Fatal(err)
+	}
+	session, err := client.CreateDataSession(handle.ConsentID, "2025-03-01", "2025-03-07")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return session.SessionID
+}
+
+func sessionStatus(t *testing.T, client *MockAAClient, sessionID string) ports.SessionStatus {
+	t.Helper()
+	status, err := client.GetSessionStatus(sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+func TestMockSessionNeverReadyStaysPending(t *testing.T) {
+	client := NewMockAAClient()
+	clock := time.Date(2025, time.March, 10, 9, 0, 0, 0, time.UTC)
+	client.SetClock(func() time.Time { return clock })
+	sessionID := newReadyingSession(t, client, MockSessionNeverReady)
+
+	clock = clock.Add(24 * time.Hour)
+	if status := sessionStatus(t, client, sessionID); status != ports.SessionStatusPending {
+		t.Fatalf("status a day later = %s, want PENDING", status)
+	}
+	if _, err := client.FetchTransactions(sessionID); err == nil {
+		t.Error("fetched a PENDING session")
+	}
+
+	if err := client.SimulateSessionReady(sessionID); err != nil {
+		t.Fatal(err)
+	}
+	if status := sessionStatus(t, client, sessionID); status != ports.SessionStatusReady {
+		t.Errorf("status = %s after SimulateSessionReady, want READY", status)
+	}
+	if _, err := client.FetchTransactions(sessionID); err != nil {
+		t.Errorf("fetching the ready session: %v", err)
+	}
+	if err := client.SimulateSessionReady("session_missing"); err == nil {
+		t.Error("readied an unknown session")
+	}
+}
+
+func TestMockSessionTurnsReadyAfterTheDelay(t *testing.T) {
+	client := NewMockAAClient()
+	clock := time.Date(2025, time.March, 10, 9, 0, 0, 0, time.UTC)
+	client.SetClock(func() time.Time { return clock })
+	sessionID := newReadyingSession(t, client, 5*time.Second)
+
+	clock = clock.Add(4 * time.Second)
+	if status := sessionStatus(t, client, sessionID); status != ports.SessionStatusPending {
+		t.Fatalf("status after 4s = %s, want PENDING", status)
+	}
+	clock = clock.Add(time.Second)
+	if status := sessionStatus(t, client, sessionID); status != ports.SessionStatusReady {
+		t.Errorf("status after 5s = %s, want READY", status)
+	}
+
+	// A later delay doesn't change sessions already open
+	client.SetSessionReadyDelay(MockSessionNeverReady)
+	if status := sessionStatus(t, client, sessionID); status != ports.SessionStatusReady {
+		t.Errorf("status = %s after changing the delay, want READY", status)
+	}
+}
+
+func TestMockSessionImmediatelyReady(t *testing.T) {
+	client := NewMockAAClient()
+	sessionID := newReadyingSession(t, client, 0)
+	if status := sessionStatus(t, client, sessionID); status != ports.SessionStatusReady {
+		t.Fatalf("status = %s, want READY straight away", status)
+	}
+	if _, err := client.FetchTransactions(sessionID); err != nil {
+		t.Errorf("fetching the session: %v", err)
+	}
+}
+
+func TestMockSessionReadinessIsConcurrencySafe(t *testing.T) {
+	client := NewMockAAClient()
+	sessionID := newReadyingSession(t, client, MockSessionNeverReady)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.SetSessionReadyDelay(time.Duration(i) * time.Millisecond)
+			client.GetSessionStatus(sessionID)
+			client.SimulateSessionReady(sessionID)
+			client.FetchTransactions(sessionID)
+		}()
+	}
+	wg.Wait()
+	if status := sessionStatus(t, client, sessionID); status != ports.SessionStatusReady {
+		t.Errorf("status = %s, want READY", status)
+	}
+}
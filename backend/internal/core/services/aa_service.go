@@ -2,14 +2,22 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/your-github/expense-tracker-backend/internal/core/domain"
 	"github.com/your-github/expense-tracker-backend/internal/core/ports"
 	"github.com/your-github/expense-tracker-backend/internal/repo"
+	"github.com/your-github/expense-tracker-backend/metrics"
+	"github.com/your-github/expense-tracker-backend/requestid"
+	"github.com/your-github/expense-tracker-backend/utils"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // AAService orchestrates Account Aggregator operations
@@ -19,8 +27,50 @@ type AAService struct {
 	normalizer   *Normalizer
 	deduplicator *Deduplicator
 	logger       *zap.Logger
+
+	// FetchChunkDays caps the days covered by one data session; longer
+	// fetches are split into sequential sessions. Zero means DefaultFetchChunkDays.
+	FetchChunkDays int
+	// Rates converts transactions into the user's currency; nil uses the
+	// built-in reference rates
+	Rates utils.RateProvider
+	// IngestWorkers bounds how many transactions of a session are
+	// normalized and stored concurrently. Zero means DefaultIngestWorkers.
+	IngestWorkers int
+	// LargeTransactionThreshold flags new transactions whose amount in the
+	// user's currency exceeds it. Zero disables the absolute check.
+	LargeTransactionThreshold float64
+	// UnusualStdDevs flags new transactions this many standard deviations
+	// above the user's norm for their category. Zero means DefaultUnusualStdDevs.
+	UnusualStdDevs float64
+	// RefreshWindowDays is how many days back an on-demand refresh of a
+	// bank link fetches. Zero means DefaultRefreshWindowDays.
+	RefreshWindowDays int
+	// RefreshCooldown is the minimum time between on-demand refreshes of a
+	// bank link. Zero means DefaultRefreshCooldown.
+	RefreshCooldown time.Duration
 }
 
+var (
+	// ErrInvalidCurrency marks a provider transaction with a malformed
+	// currency code; such transactions are skipped
+	ErrInvalidCurrency = errors.New("invalid currency code")
+	// ErrCurrencyConversion means a transaction could not be converted into
+	// the user's currency; the session is left unprocessed so it can be retried
+	ErrCurrencyConversion = errors.New("currency conversion failed")
+	// ErrConsentNotActive means the bank link's consent does not currently
+	// allow data access
+	ErrConsentNotActive = errors.New("consent is not active")
+	// ErrUnauthorized means the bank link belongs to another user
+	ErrUnauthorized = errors.New("unauthorized access to bank link")
+	// ErrProviderUnavailable means the AA provider could not be reached or
+	// failed on its side; the request may be retried
+	ErrProviderUnavailable = errors.New("AA provider unavailable")
+	// ErrUnknownDataSession means a DATA_READY webhook named a session that
+	// wasn't created through FetchTransactions
+	ErrUnknownDataSession = errors.New("unknown data session")
+)
+
 // NewAAService creates a new AA service
 func NewAAService(
 	aaClient ports.AAClient,
@@ -38,12 +88,17 @@ func NewAAService(
 	}
 }
 
+// log returns the service logger tagged with the request ID carried by ctx
+func (s *AAService) log(ctx context.Context) *zap.Logger {
+	return requestid.Logger(ctx, s.logger)
+}
+
 // InitiateConsent initiates a new consent request
 func (s *AAService) InitiateConsent(ctx context.Context, userID uuid.UUID, req ports.ConsentRequest) (*domain.BankLink, error) {
 	// Create consent via AA client
 	consentHandle, err := s.aaClient.CreateConsent(req)
 	if err != nil {
-		s.logger.Error("Failed to create consent", zap.Error(err), zap.String("user_id", userID.String()))
+		s.log(ctx).Error("Failed to create consent", zap.Error(err), zap.String("user_id", userID.String()))
 		return nil, fmt.Errorf("failed to create consent: %w", err)
 	}
 
@@ -59,11 +114,12 @@ func (s *AAService) InitiateConsent(ctx context.Context, userID uuid.UUID, req p
 
 	err = s.repositories.BankLink.Create(ctx, bankLink)
 	if err != nil {
-		s.logger.Error("Failed to create bank link", zap.Error(err), zap.String("consent_id", consentHandle.ConsentID))
+		s.log(ctx).Error("Failed to create bank link", zap.Error(err), zap.String("consent_id", consentHandle.ConsentID))
 		return nil, fmt.Errorf("failed to create bank link: %w", err)
 	}
 
-	s.logger.Info("Consent initiated successfully",
+	metrics.AAConsentsInitiated.Inc()
+	s.log(ctx).Info("Consent initiated successfully",
 		zap.String("consent_id", consentHandle.ConsentID),
 		zap.String("user_id", userID.String()),
 		zap.String("redirect_url", consentHandle.RedirectURL))
@@ -71,19 +127,37 @@ func (s *AAService) InitiateConsent(ctx context.Context, userID uuid.UUID, req p
 	return bankLink, nil
 }
 
-// HandleConsentCallback handles consent status updates from AA
+// isTerminalConsentStatus reports whether a consent can no longer change
+// status; a revoked or expired consent needs a new consent to become usable
+func isTerminalConsentStatus(status string) bool {
+	return status == string(ports.ConsentStatusRevoked) || status == string(ports.ConsentStatusExpired)
+}
+
+// HandleConsentCallback handles consent status updates from AA. Callbacks
+// can arrive out of order, so a link in a terminal state keeps it and a late
+// update for it is acknowledged without being applied.
 func (s *AAService) HandleConsentCallback(ctx context.Context, consentID, status string) error {
 	// Find bank link by consent ID
 	bankLink, err := s.repositories.BankLink.GetByConsentID(ctx, consentID)
 	if err != nil {
-		s.logger.Error("Failed to find bank link for consent", zap.Error(err), zap.String("consent_id", consentID))
+		s.log(ctx).Error("Failed to find bank link for consent", zap.Error(err), zap.String("consent_id", consentID))
 		return fmt.Errorf("failed to find bank link: %w", err)
 	}
 
+	if isTerminalConsentStatus(bankLink.Status) {
+		if status != bankLink.Status {
+			s.log(ctx).Warn("Ignoring status update for a closed consent",
+				zap.String("consent_id", consentID),
+				zap.String("status", bankLink.Status),
+				zap.String("ignored_status", status))
+		}
+		return nil
+	}
+
 	// Update status
 	err = s.repositories.BankLink.UpdateStatus(ctx, bankLink.ID, status)
 	if err != nil {
-		s.logger.Error("Failed to update bank link status", zap.Error(err), zap.String("consent_id", consentID))
+		s.log(ctx).Error("Failed to update bank link status", zap.Error(err), zap.String("consent_id", consentID))
 		return fmt.Errorf("failed to update bank link status: %w", err)
 	}
 
@@ -94,11 +168,11 @@ func (s *AAService) HandleConsentCallback(ctx context.Context, consentID, status
 		bankLink.Status = status
 		err = s.repositories.BankLink.Update(ctx, bankLink)
 		if err != nil {
-			s.logger.Error("Failed to update bank link validity", zap.Error(err), zap.String("consent_id", consentID))
+			s.log(ctx).Error("Failed to update bank link validity", zap.Error(err), zap.String("consent_id", consentID))
 		}
 	}
 
-	s.logger.Info("Consent status updated",
+	s.log(ctx).Info("Consent status updated",
 		zap.String("consent_id", consentID),
 		zap.String("status", status),
 		zap.String("user_id", bankLink.UserID.String()))
@@ -106,175 +180,642 @@ func (s *AAService) HandleConsentCallback(ctx context.Context, consentID, status
 	return nil
 }
 
+// HandleConsentRevokedWebhook marks the bank link of a consent revoked on
+// the AA's side as REVOKED, which stops any further fetches for it. A link
+// that is already revoked is left as it is.
+func (s *AAService) HandleConsentRevokedWebhook(ctx context.Context, consentID string) error {
+	bankLink, err := s.repositories.BankLink.GetByConsentID(ctx, consentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: consent %s", ErrBankLinkNotFound, consentID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find bank link: %w", err)
+	}
+	if bankLink.Status == string(ports.ConsentStatusRevoked) {
+		return nil
+	}
+
+	if err := s.repositories.BankLink.UpdateStatus(ctx, bankLink.ID, string(ports.ConsentStatusRevoked)); err != nil {
+		s.log(ctx).Error("Failed to update bank link status", zap.Error(err), zap.String("consent_id", consentID))
+		return fmt.Errorf("failed to update bank link status: %w", err)
+	}
+
+	metrics.AAConsentsRevoked.Inc()
+	s.log(ctx).Info("Consent revoked by AA",
+		zap.String("consent_id", consentID),
+		zap.String("previous_status", bankLink.Status),
+		zap.String("user_id", bankLink.UserID.String()))
+
+	return nil
+}
+
+// RefreshConsentStatuses polls the AA for every pending or active consent,
+// syncs the local status and expires links whose validity has lapsed.
+// It returns the number of links whose status changed.
+func (s *AAService) RefreshConsentStatuses(ctx context.Context) (int, error) {
+	bankLinks, err := s.repositories.BankLink.GetNeedingStatusRefresh(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get bank links to refresh: %w", err)
+	}
+
+	now := time.Now()
+	updated := 0
+	for _, bankLink := range bankLinks {
+		changed, err := s.refreshConsentStatus(ctx, bankLink, now)
+		if err != nil {
+			continue
+		}
+		if changed {
+			updated++
+		}
+	}
+
+	return updated, nil
+}
+
+// refreshConsentStatus polls the AA for one link's consent (or expires it
+// once its validity has lapsed) and saves the link if the status changed.
+// Errors are logged here; callers only need to know whether it worked.
+func (s *AAService) refreshConsentStatus(ctx context.Context, bankLink *domain.BankLink, now time.Time) (bool, error) {
+	newStatus := bankLink.Status
+
+	if bankLink.ValidTill != nil && now.After(*bankLink.ValidTill) {
+		newStatus = string(ports.ConsentStatusExpired)
+	} else {
+		status, err := s.aaClient.GetConsentStatus(bankLink.AAConsentID)
+		if err != nil {
+			s.log(ctx).Warn("Failed to poll consent status", zap.Error(err), zap.String("consent_id", bankLink.AAConsentID))
+			return false, fmt.Errorf("failed to poll consent status: %w", err)
+		}
+		newStatus = string(status)
+	}
+
+	if newStatus == bankLink.Status {
+		return false, nil
+	}
+
+	previousStatus := bankLink.Status
+	bankLink.Status = newStatus
+	if newStatus == string(ports.ConsentStatusActive) && bankLink.ValidTill == nil {
+		validTill := now.AddDate(0, 1, 0) // 1 month validity
+		bankLink.ValidTill = &validTill
+	}
+
+	if err := s.repositories.BankLink.Update(ctx, bankLink); err != nil {
+		s.log(ctx).Error("Failed to update bank link status", zap.Error(err), zap.String("consent_id", bankLink.AAConsentID))
+		bankLink.Status = previousStatus
+		return false, fmt.Errorf("failed to update bank link status: %w", err)
+	}
+
+	s.log(ctx).Info("Consent status refreshed",
+		zap.String("consent_id", bankLink.AAConsentID),
+		zap.String("from", previousStatus),
+		zap.String("to", newStatus))
+	return true, nil
+}
+
+// ErrBankLinkNotFound is returned when a bank link does not exist or
+// belongs to another user
+var ErrBankLinkNotFound = errors.New("bank link not found")
+
+// GetConsentStatus returns one of the user's bank links. With refresh the
+// consent status is first polled from the AA; a failed poll is logged and
+// the stored status returned, so callers can keep polling.
+func (s *AAService) GetConsentStatus(ctx context.Context, userID, bankLinkID uuid.UUID, refresh bool) (*domain.BankLink, error) {
+	bankLink, err := s.repositories.BankLink.GetByID(ctx, bankLinkID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBankLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bank link: %w", err)
+	}
+	if bankLink.UserID != userID {
+		return nil, ErrBankLinkNotFound
+	}
+
+	// Only consents that can still change are worth polling
+	if refresh && (bankLink.Status == string(ports.ConsentStatusPending) || bankLink.Status == string(ports.ConsentStatusActive)) {
+		_, _ = s.refreshConsentStatus(ctx, bankLink, time.Now())
+	}
+	return bankLink, nil
+}
+
 // FetchTransactions fetches transactions for a bank link
-func (s *AAService) FetchTransactions(ctx context.Context, userID uuid.UUID, bankLinkID uuid.UUID, fromDate, toDate string) (*DataFetchResult, error) {
+func (s *AAService) FetchTransactions(ctx context.Context, userID uuid.UUID, bankLinkID uuid.UUID, fromDate, toDate string, dryRun bool) (*DataFetchResult, error) {
 	// Get bank link
 	bankLink, err := s.repositories.BankLink.GetByID(ctx, bankLinkID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBankLinkNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get bank link: %w", err)
 	}
 
 	// Verify ownership
 	if bankLink.UserID != userID {
-		return nil, fmt.Errorf("unauthorized access to bank link")
+		return nil, ErrUnauthorized
 	}
 
 	// Verify consent is active
 	if bankLink.Status != "ACTIVE" {
-		return nil, fmt.Errorf("consent is not active: %s", bankLink.Status)
+		return nil, fmt.Errorf("%w: %s", ErrConsentNotActive, bankLink.Status)
 	}
 
-	// Create data session
-	dataSession, err := s.aaClient.CreateDataSession(bankLink.AAConsentID, fromDate, toDate)
+	windows, err := splitFetchRange(fromDate, toDate, s.FetchChunkDays)
 	if err != nil {
-		s.logger.Error("Failed to create data session", zap.Error(err), zap.String("consent_id", bankLink.AAConsentID))
-		return nil, fmt.Errorf("failed to create data session: %w", err)
-	}
-
-	result := &DataFetchResult{
-		SessionID: dataSession.SessionID,
-		Status:    dataSession.Status,
+		return nil, err
 	}
 
-	// If session is ready, fetch transactions immediately
-	if dataSession.Status == "READY" {
-		transactions, err := s.fetchAndProcessTransactions(ctx, dataSession.SessionID, userID, bankLinkID)
+	// One data session per window, created in order. Rows on either side of
+	// a window seam are deduplicated by hash when each session is ingested.
+	result := &DataFetchResult{Processed: true, DryRun: dryRun}
+	seen := make(map[uuid.UUID]bool)
+	previewed := make(map[string]bool)
+	for _, window := range windows {
+		dataSession, err := s.aaClient.CreateDataSession(bankLink.AAConsentID, window.From, window.To)
 		if err != nil {
-			return nil, err
+			s.log(ctx).Error("Failed to create data session", zap.Error(err),
+				zap.String("consent_id", bankLink.AAConsentID),
+				zap.String("from", window.From), zap.String("to", window.To))
+			return nil, fmt.Errorf("failed to create data session for %s..%s: %w", window.From, window.To, err)
 		}
-		result.Transactions = transactions
-		result.Processed = true
+
+		// Remember who the session is for: if it isn't ready yet it is
+		// ingested when its DATA_READY webhook arrives
+		if err := s.repositories.DataSession.Create(ctx, &domain.DataSession{
+			SessionID:  dataSession.SessionID,
+			UserID:     userID,
+			BankLinkID: bankLinkID,
+			DryRun:     dryRun,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to record data session %s: %w", dataSession.SessionID, err)
+		}
+
+		chunk := DataFetchChunk{
+			FetchWindow: window,
+			SessionID:   dataSession.SessionID,
+			Status:      dataSession.Status,
+		}
+
+		// If session is ready, fetch transactions immediately
+		if dataSession.Status == "READY" {
+			var transactions []*domain.Transaction
+			if dryRun {
+				var skipped []*domain.Transaction
+				transactions, skipped, err = s.previewTransactions(ctx, dataSession.SessionID, userID, bankLinkID)
+				// A seam row an earlier window already previewed would be
+				// skipped by the real import, so it is reported as skipped here
+				fresh := transactions[:0]
+				for _, txn := range transactions {
+					if previewed[txn.HashDedupe] {
+						skipped = append(skipped, txn)
+						continue
+					}
+					previewed[txn.HashDedupe] = true
+					fresh = append(fresh, txn)
+				}
+				transactions = fresh
+				result.Skipped = append(result.Skipped, skipped...)
+				chunk.Skipped = len(skipped)
+			} else {
+				transactions, err = s.fetchAndProcessTransactions(ctx, dataSession.SessionID, userID, bankLinkID)
+			}
+			if err != nil {
+				return nil, err
+			}
+			chunk.Created = len(transactions)
+			for _, txn := range transactions {
+				if !seen[txn.ID] {
+					seen[txn.ID] = true
+					result.Transactions = append(result.Transactions, txn)
+				}
+			}
+			chunk.Processed = true
+		} else {
+			result.Processed = false
+		}
+
+		// The first session not yet ready decides the overall status
+		if result.SessionID == "" || (result.Status == "READY" && chunk.Status != "READY") {
+			result.SessionID = chunk.SessionID
+			result.Status = chunk.Status
+		}
+		result.Chunks = append(result.Chunks, chunk)
 	}
 
 	return result, nil
 }
 
+// VerifyWebhookSignature checks an inbound webhook body against its
+// signature through the AA client
+func (s *AAService) VerifyWebhookSignature(payload []byte, signature string) bool {
+	return s.aaClient.VerifySignature(payload, signature)
+}
+
 // HandleDataReadyWebhook handles data ready webhook from AA
 func (s *AAService) HandleDataReadyWebhook(ctx context.Context, sessionID string) error {
-	s.logger.Info("Processing data ready webhook", zap.String("session_id", sessionID))
+	s.log(ctx).Info("Processing data ready webhook", zap.String("session_id", sessionID))
 
 	// Get session status to verify it's ready
 	status, err := s.aaClient.GetSessionStatus(sessionID)
 	if err != nil {
-		s.logger.Error("Failed to get session status", zap.Error(err), zap.String("session_id", sessionID))
+		s.log(ctx).Error("Failed to get session status", zap.Error(err), zap.String("session_id", sessionID))
 		return fmt.Errorf("failed to get session status: %w", err)
 	}
 
 	if status != ports.SessionStatusReady {
-		s.logger.Warn("Session is not ready", zap.String("session_id", sessionID), zap.String("status", string(status)))
+		s.log(ctx).Warn("Session is not ready", zap.String("session_id", sessionID), zap.String("status", string(status)))
 		return fmt.Errorf("session is not ready: %s", status)
 	}
 
-	// Fetch and process transactions
-	// Note: We need to determine user_id and bank_link_id from session
-	// This is a simplified implementation - in production, you'd store session metadata
-	_, err = s.fetchAndProcessTransactions(ctx, sessionID, uuid.Nil, uuid.Nil)
+	// Ingest into the user and bank link the session was created for
+	session, err := s.repositories.DataSession.GetBySessionID(ctx, sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: %s", ErrUnknownDataSession, sessionID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get data session: %w", err)
+	}
+	if session.DryRun {
+		s.log(ctx).Info("Ignoring data ready webhook for a dry run session", zap.String("session_id", sessionID))
+		return nil
+	}
+
+	_, err = s.fetchAndProcessTransactions(ctx, sessionID, session.UserID, session.BankLinkID)
 	return err
 }
 
 // fetchAndProcessTransactions fetches transactions and processes them
 func (s *AAService) fetchAndProcessTransactions(ctx context.Context, sessionID string, userID uuid.UUID, bankLinkID uuid.UUID) ([]*domain.Transaction, error) {
+	// A session is only ingested once; repeated DATA_READY deliveries are acknowledged as no-ops
+	processed, err := s.repositories.ProcessedSession.Exists(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check processed session: %w", err)
+	}
+	if processed {
+		s.log(ctx).Info("Session already processed, skipping", zap.String("session_id", sessionID))
+		metrics.AAFetches.WithLabelValues("skipped").Inc()
+		return nil, nil
+	}
+
 	// Fetch transactions from AA
 	fiTransactions, err := s.aaClient.FetchTransactions(sessionID)
 	if err != nil {
-		s.logger.Error("Failed to fetch transactions", zap.Error(err), zap.String("session_id", sessionID))
+		s.log(ctx).Error("Failed to fetch transactions", zap.Error(err), zap.String("session_id", sessionID))
+		metrics.AAFetches.WithLabelValues("error").Inc()
 		return nil, fmt.Errorf("failed to fetch transactions: %w", err)
 	}
 
-	s.logger.Info("Fetched transactions from AA",
+	s.log(ctx).Info("Fetched transactions from AA",
 		zap.String("session_id", sessionID),
 		zap.Int("count", len(fiTransactions)))
 
 	// Deduplicate transactions
 	uniqueTransactions := s.deduplicator.DeduplicateTransactions(fiTransactions)
-	s.logger.Info("Deduplicated transactions",
+	s.log(ctx).Info("Deduplicated transactions",
 		zap.Int("original", len(fiTransactions)),
 		zap.Int("unique", len(uniqueTransactions)))
 
-	// Get existing transaction hashes for this user
-	existingHashes := make(map[string]bool)
-	existingTransactions, _, err := s.repositories.Transaction.GetByUserID(ctx, userID, nil, nil, 1000, 0)
+	// Skip transactions already stored, looked up in batches by hash
+	hashes, alreadyStored, err := s.storedTransactions(ctx, uniqueTransactions)
 	if err != nil {
-		s.logger.Error("Failed to get existing transactions", zap.Error(err))
-		return nil, fmt.Errorf("failed to get existing transactions: %w", err)
+		return nil, err
+	}
+	var fresh []ports.FITransaction
+	var freshHashes []string
+	for i, fiTxn := range uniqueTransactions {
+		if !alreadyStored[i] {
+			fresh = append(fresh, fiTxn)
+			freshHashes = append(freshHashes, hashes[i])
+		}
 	}
 
-	for _, txn := range existingTransactions {
-		existingHashes[txn.HashDedupe] = true
+	settings, err := s.loadIngestSettings(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Process and store new transactions
-	var newTransactions []*domain.Transaction
-	for _, fiTxn := range uniqueTransactions {
-		// Generate hash for deduplication
-		hash := s.deduplicator.GenerateHash(fiTxn)
+	// Build every new transaction before storing any, so a failed currency
+	// conversion leaves the session untouched for a retry
+	pending, err := s.buildTransactions(ctx, fresh, freshHashes, userID, bankLinkID, settings)
+	if err != nil {
+		metrics.AAFetches.WithLabelValues("error").Inc()
+		return nil, err
+	}
 
-		// Skip if already exists
-		if existingHashes[hash] {
-			continue
+	// Store new transactions on the worker pool. Insert order doesn't
+	// matter: balances are recomputed below in posted_at order.
+	stored := make([]bool, len(pending))
+	var failed atomic.Int64
+	runPool(ctx, s.ingestWorkers(), len(pending), func(i int) {
+		transaction := pending[i]
+		if transaction == nil {
+			return
+		}
+		// A hash collision means another delivery already stored it
+		err := s.repositories.Transaction.Create(ctx, transaction)
+		if errors.Is(err, repo.ErrDuplicate) {
+			return
 		}
-
-		// Normalize transaction
-		normalized := s.normalizer.NormalizeTransaction(fiTxn)
-
-		// Parse posted_at
-		postedAt, err := time.Parse(time.RFC3339, fiTxn.PostedAt)
 		if err != nil {
-			s.logger.Warn("Failed to parse posted_at", zap.Error(err), zap.String("posted_at", fiTxn.PostedAt))
-			postedAt = time.Now()
+			s.log(ctx).Error("Failed to create transaction", zap.Error(err), zap.String("hash", transaction.HashDedupe))
+			failed.Add(1)
+			return // Continue with other transactions
 		}
+		stored[i] = true
+	})
 
-		// Parse value_date if available
-		var valueDate *time.Time
-		if fiTxn.ValueDate != "" {
-			if parsed, err := time.Parse("2006-01-02", fiTxn.ValueDate); err == nil {
-				valueDate = &parsed
-			}
+	var newTransactions []*domain.Transaction
+	var earliestPostedAt time.Time
+	for i, transaction := range pending {
+		if !stored[i] {
+			continue
 		}
-
-		// Create domain transaction
-		transaction := &domain.Transaction{
-			ID:             uuid.New(),
-			UserID:         userID,
-			BankLinkID:     &bankLinkID,
-			PostedAt:       postedAt,
-			ValueDate:      valueDate,
-			Amount:         fiTxn.Amount,
-			Currency:       fiTxn.Currency,
-			TxnType:        fiTxn.Type,
-			BalanceAfter:   fiTxn.BalanceAfter,
-			DescriptionRaw: normalized.DescriptionRaw,
-			MerchantName:   normalized.MerchantName,
-			AccountRef:     normalized.AccountRef,
-			Category:       normalized.Category,
-			Subcategory:    normalized.Subcategory,
-			HashDedupe:     hash,
-			SourceMeta:     domain.JSONB(fiTxn.SourceMeta),
+		newTransactions = append(newTransactions, transaction)
+		metrics.AATransactionsStored.Inc()
+		if earliestPostedAt.IsZero() || transaction.PostedAt.Before(earliestPostedAt) {
+			earliestPostedAt = transaction.PostedAt
 		}
+	}
 
-		// Store transaction
-		err = s.repositories.Transaction.Create(ctx, transaction)
-		if err != nil {
-			s.logger.Error("Failed to create transaction", zap.Error(err), zap.String("hash", hash))
-			continue // Continue with other transactions
+	if len(newTransactions) > 0 {
+		s.linkReversals(ctx, newTransactions)
+		s.flagUnusualTransactions(ctx, userID, newTransactions)
+	}
+
+	// Rebuild running balances from the earliest new transaction onwards so
+	// late or out-of-order deliveries also correct the rows posted after them
+	if len(newTransactions) > 0 && bankLinkID != uuid.Nil {
+		if err := s.recomputeBalances(ctx, bankLinkID, earliestPostedAt); err != nil {
+			s.log(ctx).Error("Failed to recompute balances", zap.Error(err), zap.String("bank_link_id", bankLinkID.String()))
 		}
+	}
 
-		newTransactions = append(newTransactions, transaction)
+	// The session is only marked processed once every row is stored, so a
+	// redelivery or replay retries the failed ones; the stored rows are
+	// skipped by hash then
+	if n := failed.Load(); n > 0 {
+		metrics.AAFetches.WithLabelValues("error").Inc()
+		return newTransactions, fmt.Errorf("failed to store %d of %d transactions from session %s", n, len(pending), sessionID)
 	}
 
-	s.logger.Info("Processed transactions",
+	s.log(ctx).Info("Processed transactions",
 		zap.String("session_id", sessionID),
 		zap.Int("new_transactions", len(newTransactions)))
+	metrics.AAFetches.WithLabelValues("success").Inc()
+
+	processedSession := &domain.ProcessedSession{
+		SessionID:        sessionID,
+		TransactionCount: len(newTransactions),
+	}
+	if bankLinkID != uuid.Nil {
+		processedSession.BankLinkID = &bankLinkID
+	}
+	if err := s.repositories.ProcessedSession.Create(ctx, processedSession); err != nil && !errors.Is(err, repo.ErrDuplicate) {
+		s.log(ctx).Error("Failed to record processed session", zap.Error(err), zap.String("session_id", sessionID))
+	}
 
 	return newTransactions, nil
 }
 
+// ingestSettings is the per-user state applied to every transaction of an
+// ingestion
+type ingestSettings struct {
+	overrides    []CategoryOverride
+	aliases      []MerchantAlias
+	baseCurrency string
+}
+
+// loadIngestSettings loads the user's category overrides, merchant aliases
+// and base currency
+func (s *AAService) loadIngestSettings(ctx context.Context, userID uuid.UUID) (ingestSettings, error) {
+	overrides, err := s.loadCategoryOverrides(ctx, userID)
+	if err != nil {
+		return ingestSettings{}, err
+	}
+	aliases, err := s.loadMerchantAliases(ctx, userID)
+	if err != nil {
+		return ingestSettings{}, err
+	}
+	user, err := s.repositories.User.GetByID(ctx, userID)
+	if err != nil {
+		return ingestSettings{}, fmt.Errorf("failed to get user: %w", err)
+	}
+	return ingestSettings{overrides: overrides, aliases: aliases, baseCurrency: utils.NormalizeCurrency(user.Currency)}, nil
+}
+
+func (s *AAService) rates() utils.RateProvider {
+	if s.Rates == nil {
+		return utils.StaticRateProvider{}
+	}
+	return s.Rates
+}
+
+// buildTransaction normalizes a provider transaction into the domain model,
+// letting the user's category overrides take precedence over the
+// normalizer's own categorization and converting the amount into the
+// user's currency. The original amount and currency are kept alongside.
+func (s *AAService) buildTransaction(ctx context.Context, fiTxn ports.FITransaction, hash string, userID, bankLinkID uuid.UUID, settings ingestSettings) (*domain.Transaction, error) {
+	// Normalize transaction
+	normalized := s.normalizer.NormalizeTransaction(fiTxn, settings.aliases)
+	s.normalizer.ApplyUserOverrides(&normalized, settings.overrides)
+
+	if !utils.ValidCurrencyCode(normalized.Currency) {
+		return nil, fmt.Errorf("%w %q", ErrInvalidCurrency, fiTxn.Currency)
+	}
+	baseAmount, err := utils.ConvertAmount(s.rates(), fiTxn.Amount, normalized.Currency, settings.baseCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCurrencyConversion, err)
+	}
+
+	// Parse posted_at
+	postedAt, err := time.Parse(time.RFC3339, fiTxn.PostedAt)
+	if err != nil {
+		s.log(ctx).Warn("Failed to parse posted_at", zap.Error(err), zap.String("posted_at", fiTxn.PostedAt))
+		postedAt = time.Now()
+	}
+
+	// Parse value_date if available
+	var valueDate *time.Time
+	if fiTxn.ValueDate != "" {
+		if parsed, err := time.Parse("2006-01-02", fiTxn.ValueDate); err == nil {
+			valueDate = &parsed
+		}
+	}
+
+	return &domain.Transaction{
+		ID:             uuid.New(),
+		UserID:         userID,
+		BankLinkID:     &bankLinkID,
+		PostedAt:       postedAt,
+		ValueDate:      valueDate,
+		Amount:         fiTxn.Amount,
+		Currency:       normalized.Currency,
+		BaseAmount:     baseAmount,
+		BaseCurrency:   settings.baseCurrency,
+		TxnType:        normalized.TxnType,
+		BalanceAfter:   fiTxn.BalanceAfter,
+		DescriptionRaw: normalized.DescriptionRaw,
+		MerchantName:   normalized.MerchantName,
+		AccountRef:     normalized.AccountRef,
+		Category:       normalized.Category,
+		Subcategory:    normalized.Subcategory,
+		HashDedupe:     hash,
+		SourceMeta:     domain.JSONB(fiTxn.SourceMeta),
+
+		BalanceReported:    fiTxn.BalanceAfter != nil,
+		CategoryConfidence: normalized.Confidence,
+		NeedsReview:        s.normalizer.NeedsReview(normalized),
+	}, nil
+}
+
+// storedTransactions hashes transactions and reports which of them are
+// already stored, looked up in batches. A transaction with a provider
+// reference is also looked up by the content hash rows were stored under
+// before references were hashed, so rows imported earlier aren't inserted
+// again on the next fetch.
+func (s *AAService) storedTransactions(ctx context.Context, transactions []ports.FITransaction) ([]string, []bool, error) {
+	hashes := make([]string, len(transactions))
+	legacyHashes := make([]string, len(transactions))
+	lookup := make([]string, 0, len(transactions))
+	for i, fiTxn := range transactions {
+		hashes[i] = s.deduplicator.GenerateHash(fiTxn)
+		lookup = append(lookup, hashes[i])
+		if legacy, ok := s.deduplicator.LegacyHash(fiTxn); ok {
+			legacyHashes[i] = legacy
+			lookup = append(lookup, legacy)
+		}
+	}
+	existingHashes, err := s.repositories.Transaction.ExistingHashes(ctx, lookup)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check existing transactions: %w", err)
+	}
+
+	stored := make([]bool, len(transactions))
+	for i := range transactions {
+		stored[i] = existingHashes[hashes[i]] || (legacyHashes[i] != "" && existingHashes[legacyHashes[i]])
+	}
+	return hashes, stored, nil
+}
+
+// previewTransactions runs a session's transactions through the same
+// normalization and dedup as fetchAndProcessTransactions without writing
+// anything, returning what would be created and what would be skipped
+func (s *AAService) previewTransactions(ctx context.Context, sessionID string, userID uuid.UUID, bankLinkID uuid.UUID) ([]*domain.Transaction, []*domain.Transaction, error) {
+	processed, err := s.repositories.ProcessedSession.Exists(ctx, sessionID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check processed session: %w", err)
+	}
+	if processed {
+		return nil, nil, nil
+	}
+
+	fiTransactions, err := s.aaClient.FetchTransactions(sessionID)
+	if err != nil {
+		s.log(ctx).Error("Failed to fetch transactions", zap.Error(err), zap.String("session_id", sessionID))
+		return nil, nil, fmt.Errorf("failed to fetch transactions: %w", err)
+	}
+
+	hashes, alreadyStored, err := s.storedTransactions(ctx, fiTransactions)
+	if err != nil {
+		return nil, nil, err
+	}
+	settings, err := s.loadIngestSettings(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	transactions, err := s.buildTransactions(ctx, fiTransactions, hashes, userID, bankLinkID, settings)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var created, skipped []*domain.Transaction
+	seen := make(map[string]bool)
+	for i, transaction := range transactions {
+		if transaction == nil {
+			continue
+		}
+		if alreadyStored[i] || seen[hashes[i]] {
+			skipped = append(skipped, transaction)
+			continue
+		}
+
+		seen[hashes[i]] = true
+		created = append(created, transaction)
+	}
+
+	return created, skipped, nil
+}
+
+// recomputeBalances fills BalanceAfter for every transaction on the bank link
+// posted at or after since. The running balance starts from the last stored
+// balance before since (or zero when the link has none) and is reset at every
+// transaction whose balance was reported by the provider, which is never
+// overwritten whichever import it arrived in.
+func (s *AAService) recomputeBalances(ctx context.Context, bankLinkID uuid.UUID, since time.Time) error {
+	var running float64
+	anchor, err := s.repositories.Transaction.GetLastBalanceBefore(ctx, bankLinkID, since)
+	if err != nil {
+		return fmt.Errorf("failed to get opening balance: %w", err)
+	}
+	if anchor != nil {
+		running = *anchor.BalanceAfter
+	}
+
+	transactions, err := s.repositories.Transaction.GetByBankLinkSince(ctx, bankLinkID, since)
+	if err != nil {
+		return fmt.Errorf("failed to get transactions to rebalance: %w", err)
+	}
+
+	for _, txn := range transactions {
+		if txn.BalanceReported && txn.BalanceAfter != nil {
+			running = *txn.BalanceAfter
+			continue
+		}
+
+		switch strings.ToUpper(txn.TxnType) {
+		case "CREDIT":
+			running += txn.Amount
+		case "DEBIT":
+			running -= txn.Amount
+		}
+		running = math.Round(running*100) / 100
+
+		if txn.BalanceAfter != nil && *txn.BalanceAfter == running {
+			continue
+		}
+		balance := running
+		txn.BalanceAfter = &balance
+		if err := s.repositories.Transaction.Update(ctx, txn); err != nil {
+			return fmt.Errorf("failed to update balance for transaction %s: %w", txn.ID, err)
+		}
+	}
+
+	return nil
+}
+
 // DataFetchResult represents the result of a data fetch operation
 type DataFetchResult struct {
 	SessionID    string                `json:"session_id"`
 	Status       string                `json:"status"`
 	Processed    bool                  `json:"processed"`
 	Transactions []*domain.Transaction `json:"transactions,omitempty"`
+	Chunks       []DataFetchChunk      `json:"chunks"`
+
+	// DryRun results are never persisted: Transactions holds the rows that
+	// would be created and Skipped the ones that would be dropped as duplicates
+	DryRun  bool                  `json:"dry_run,omitempty"`
+	Skipped []*domain.Transaction `json:"skipped,omitempty"`
+}
+
+// DataFetchChunk is the data session created for one window of a fetch
+type DataFetchChunk struct {
+	FetchWindow
+	SessionID string `json:"session_id"`
+	Status    string `json:"status"`
+	Processed bool   `json:"processed"`
+	Created   int    `json:"created"`
+	Skipped   int    `json:"skipped"`
 }
 
 // GetActiveBankLinks returns active bank links for a user
@@ -286,30 +827,34 @@ func (s *AAService) GetActiveBankLinks(ctx context.Context, userID uuid.UUID) ([
 func (s *AAService) RevokeConsent(ctx context.Context, userID uuid.UUID, bankLinkID uuid.UUID) error {
 	// Get bank link
 	bankLink, err := s.repositories.BankLink.GetByID(ctx, bankLinkID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrBankLinkNotFound
+	}
 	if err != nil {
 		return fmt.Errorf("failed to get bank link: %w", err)
 	}
 
 	// Verify ownership
 	if bankLink.UserID != userID {
-		return fmt.Errorf("unauthorized access to bank link")
+		return ErrUnauthorized
 	}
 
 	// Revoke consent via AA client
 	err = s.aaClient.RevokeConsent(bankLink.AAConsentID)
 	if err != nil {
-		s.logger.Error("Failed to revoke consent", zap.Error(err), zap.String("consent_id", bankLink.AAConsentID))
+		s.log(ctx).Error("Failed to revoke consent", zap.Error(err), zap.String("consent_id", bankLink.AAConsentID))
 		return fmt.Errorf("failed to revoke consent: %w", err)
 	}
 
 	// Update status locally
 	err = s.repositories.BankLink.UpdateStatus(ctx, bankLinkID, "REVOKED")
 	if err != nil {
-		s.logger.Error("Failed to update bank link status", zap.Error(err), zap.String("consent_id", bankLink.AAConsentID))
+		s.log(ctx).Error("Failed to update bank link status", zap.Error(err), zap.String("consent_id", bankLink.AAConsentID))
 		return fmt.Errorf("failed to update bank link status: %w", err)
 	}
 
-	s.logger.Info("Consent revoked successfully",
+	metrics.AAConsentsRevoked.Inc()
+	s.log(ctx).Info("Consent revoked successfully",
 		zap.String("consent_id", bankLink.AAConsentID),
 		zap.String("user_id", userID.String()))
 
@@ -0,0 +1,412 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/internal/core/ports"
+	"github.com/your-github/expense-tracker-backend/requestid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestDuplicateDataReadyDeliveryIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	service, client := newTestAAService(t, store)
+	core, logs := observer.New(zapcore.ErrorLevel)
+	service.logger = zap.New(core)
+	user, link := activeConsent(t, store, client)
+
+	result, err := service.FetchTransactions(ctx, user.ID, link.ID, "2025-02-01", "2025-02-03", false)
+	if err != nil {
+		t.Fatalf("FetchTransactions: %v", err)
+	}
+	if err := client.SimulateSessionReady(result.SessionID); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := service.HandleDataReadyWebhook(ctx, result.SessionID); err != nil {
+			t.Fatalf("delivery %d: %v", i+1, err)
+		}
+		// Forget the session so the second delivery reaches the inserts and
+		// is deduplicated by hash alone
+		delete(store.processed, result.SessionID)
+	}
+
+	stored := store.linkTransactions(link.ID)
+	hashes := make(map[string]bool)
+	for _, txn := range stored {
+		if hashes[txn.HashDedupe] {
+			t.Fatalf("duplicate row for hash %s", txn.HashDedupe)
+		}
+		hashes[txn.HashDedupe] = true
+	}
+	if logs.Len() != 0 {
+		t.Errorf("expected no error logs, got %v", logs.All())
+	}
+}
+
+func TestPartialStoreFailureLeavesSessionForRetry(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	service, client := newTestAAService(t, store)
+	user, link := activeConsent(t, store, client)
+
+	result, err := service.FetchTransactions(ctx, user.ID, link.ID, "2025-03-01", "2025-03-04", false)
+	if err != nil {
+		t.Fatalf("FetchTransactions: %v", err)
+	}
+	if err := client.SimulateSessionReady(result.SessionID); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first insert fails with something other than a duplicate
+	failOnce := true
+	store.createErr = func(*domain.Transaction) error {
+		if failOnce {
+			failOnce = false
+			return errors.New("connection reset")
+		}
+		return nil
+	}
+
+	if err := service.HandleDataReadyWebhook(ctx, result.SessionID); err == nil {
+		t.Fatal("expected an error when a row could not be stored")
+	}
+	if _, ok := store.processed[result.SessionID]; ok {
+		t.Fatal("session was marked processed although a row failed")
+	}
+	partial := len(store.linkTransactions(link.ID))
+
+	// The retry stores the row that failed and skips the rest by hash
+	if err := service.HandleDataReadyWebhook(ctx, result.SessionID); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if _, ok := store.processed[result.SessionID]; !ok {
+		t.Error("session was not marked processed after the retry")
+	}
+	if got := len(store.linkTransactions(link.ID)); got != partial+1 {
+		t.Errorf("after retry %d transactions are stored, want %d", got, partial+1)
+	}
+}
+
+func TestRefreshConsentStatuses(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	service, client := newTestAAService(t, store)
+	user := store.addUser("INR")
+
+	newConsent := func() string {
+		handle, err := client.CreateConsent(ports.ConsentRequest{UserID: user.ID.String(), FIType: "SAVINGS"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return handle.ConsentID
+	}
+
+	// Approved at the AA since the last poll
+	approvedID := newConsent()
+	approved := store.addBankLink(user.ID, approvedID, "PENDING")
+	if err := client.SimulateConsentApproval(approvedID); err != nil {
+		t.Fatal(err)
+	}
+	// Still waiting for the user
+	waiting := store.addBankLink(user.ID, newConsent(), "PENDING")
+	// Past its validity; expired locally without asking the AA
+	lapsed := store.addBankLink(user.ID, "consent-unknown-to-aa", "ACTIVE")
+	validTill := time.Now().Add(-time.Hour)
+	lapsed.ValidTill = &validTill
+	// Revoked at the AA
+	revokedID := newConsent()
+	if err := client.SimulateConsentApproval(revokedID); err != nil {
+		t.Fatal(err)
+	}
+	revoked := store.addBankLink(user.ID, revokedID, "ACTIVE")
+	if err := client.RevokeConsent(revokedID); err != nil {
+		t.Fatal(err)
+	}
+	// The AA no longer knows this consent; the poll fails and is skipped
+	broken := store.addBankLink(user.ID, "consent-missing", "PENDING")
+	// Terminal links are never polled
+	done := store.addBankLink(user.ID, "consent-done", "REVOKED")
+
+	updated, err := service.RefreshConsentStatuses(ctx)
+	if err != nil {
+		t.Fatalf("RefreshConsentStatuses: %v", err)
+	}
+	if updated != 3 {
+		t.Errorf("updated %d links, want 3", updated)
+	}
+
+	want := map[*domain.BankLink]string{
+		approved: "ACTIVE",
+		waiting:  "PENDING",
+		lapsed:   "EXPIRED",
+		revoked:  "REVOKED",
+		broken:   "PENDING",
+		done:     "REVOKED",
+	}
+	for link, status := range want {
+		if got := store.bankLinks[link.ID].Status; got != status {
+			t.Errorf("link %s is %s, want %s", link.AAConsentID, got, status)
+		}
+	}
+	if store.bankLinks[approved.ID].ValidTill == nil {
+		t.Error("approved link has no validity")
+	}
+
+	// Nothing changed since, so a second run updates nothing
+	if updated, err := service.RefreshConsentStatuses(ctx); err != nil || updated != 0 {
+		t.Errorf("second run updated %d links, err %v", updated, err)
+	}
+}
+
+func TestIngestStoresUppercaseTxnType(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	service, client := newTestAAService(t, store)
+	user, link := activeConsent(t, store, client)
+
+	result, err := service.FetchTransactions(ctx, user.ID, link.ID, "2025-04-01", "2025-04-02", false)
+	if err != nil {
+		t.Fatalf("FetchTransactions: %v", err)
+	}
+	if err := client.SimulateSessionReady(result.SessionID); err != nil {
+		t.Fatal(err)
+	}
+	// A provider that reports types in mixed case
+	client.sessions[result.SessionID].transactions = []ports.FITransaction{
+		{AccountRef: "XX1234", PostedAt: "2025-04-01T10:00:00Z", Amount: 250, Currency: "INR", Type: "debit", DescriptionRaw: "UPI/SWIGGY"},
+		{AccountRef: "XX1234", PostedAt: "2025-04-01T12:00:00Z", Amount: 5000, Currency: "INR", Type: " Credit ", DescriptionRaw: "NEFT/SALARY"},
+		{AccountRef: "XX1234", PostedAt: "2025-04-02T09:00:00Z", Amount: 99, Currency: "INR", Type: "DEBIT", DescriptionRaw: "POS/NETFLIX"},
+	}
+
+	if err := service.HandleDataReadyWebhook(ctx, result.SessionID); err != nil {
+		t.Fatalf("HandleDataReadyWebhook: %v", err)
+	}
+
+	got := make(map[float64]string)
+	for _, txn := range store.linkTransactions(link.ID) {
+		got[txn.Amount] = txn.TxnType
+	}
+	want := map[float64]string{250: "DEBIT", 5000: "CREDIT", 99: "DEBIT"}
+	for amount, txnType := range want {
+		if got[amount] != txnType {
+			t.Errorf("transaction of %.0f stored as %q, want %q", amount, got[amount], txnType)
+		}
+	}
+}
+
+func TestAALogsCarryTheRequestID(t *testing.T) {
+	ctx := requestid.NewContext(context.Background(), "req-42")
+	store := newMemStore()
+	service, client := newTestAAService(t, store)
+	core, logs := observer.New(zapcore.InfoLevel)
+	service.logger = zap.New(core)
+	user, link := activeConsent(t, store, client)
+
+	result, err := service.FetchTransactions(ctx, user.ID, link.ID, "2025-02-01", "2025-02-03", false)
+	if err != nil {
+		t.Fatalf("FetchTransactions: %v", err)
+	}
+	if err := client.SimulateSessionReady(result.SessionID); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.HandleDataReadyWebhook(ctx, result.SessionID); err != nil {
+		t.Fatalf("HandleDataReadyWebhook: %v", err)
+	}
+	if err := service.RevokeConsent(ctx, user.ID, link.ID); err != nil {
+		t.Fatalf("RevokeConsent: %v", err)
+	}
+
+	if logs.Len() < 3 {
+		t.Fatalf("the AA flow logged %d entries, want the webhook, processing and revoke logs", logs.Len())
+	}
+	for _, entry := range logs.All() {
+		if id := entry.ContextMap()["request_id"]; id != "req-42" {
+			t.Errorf("%q logged request_id %v, want req-42", entry.Message, id)
+		}
+	}
+}
+
+func TestGetConsentStatusReflectsApproval(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	service, client := newTestAAService(t, store)
+	user := store.addUser("INR")
+	handle, err := client.CreateConsent(ports.ConsentRequest{UserID: user.ID.String(), FIType: "SAVINGS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	link := store.addBankLink(user.ID, handle.ConsentID, "PENDING")
+
+	got, err := service.GetConsentStatus(ctx, user.ID, link.ID, true)
+	if err != nil || got.Status != "PENDING" {
+		t.Fatalf("before approval = %+v, %v; want PENDING", got, err)
+	}
+
+	if err := client.SimulateConsentApproval(handle.ConsentID); err != nil {
+		t.Fatal(err)
+	}
+	// Without refresh the stored status is returned as is
+	if got, _ := service.GetConsentStatus(ctx, user.ID, link.ID, false); got.Status != "PENDING" {
+		t.Errorf("unrefreshed status = %s, want PENDING", got.Status)
+	}
+	got, err = service.GetConsentStatus(ctx, user.ID, link.ID, true)
+	if err != nil || got.Status != "ACTIVE" || got.ValidTill == nil {
+		t.Fatalf("after approval = %+v, %v; want ACTIVE with a validity", got, err)
+	}
+	if stored := store.bankLinks[link.ID]; stored.Status != "ACTIVE" {
+		t.Errorf("stored status = %s, want the refresh saved", stored.Status)
+	}
+
+	if _, err := service.GetConsentStatus(ctx, store.addUser("INR").ID, link.ID, true); !errors.Is(err, ErrBankLinkNotFound) {
+		t.Errorf("another user's link: %v, want ErrBankLinkNotFound", err)
+	}
+	if _, err := service.GetConsentStatus(ctx, user.ID, uuid.New(), true); !errors.Is(err, ErrBankLinkNotFound) {
+		t.Errorf("unknown link: %v, want ErrBankLinkNotFound", err)
+	}
+}
+
+func TestGetConsentStatusKeepsStoredStatusWhenPollFails(t *testing.T) {
+	store := newMemStore()
+	service, _ := newTestAAService(t, store)
+	user := store.addUser("INR")
+	// The AA doesn't know this consent, so every poll fails
+	link := store.addBankLink(user.ID, "consent-missing", "PENDING")
+
+	got, err := service.GetConsentStatus(context.Background(), user.ID, link.ID, true)
+	if err != nil || got.Status != "PENDING" {
+		t.Errorf("failed poll = %+v, %v; want the stored PENDING status", got, err)
+	}
+}
+
+func TestIngestedTransactionsFollowUserOverrides(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	service, client := newTestAAService(t, store)
+	user, link := activeConsent(t, store, client)
+	// A regex override matching every description outranks the normalizer's
+	// merchant and keyword categories
+	store.overrides = append(store.overrides, &domain.CategoryOverride{
+		ID: uuid.New(), UserID: user.ID, Matcher: "/.*/", Category: "travel", Subcategory: "Trip",
+	})
+
+	result, err := service.FetchTransactions(ctx, user.ID, link.ID, "2025-02-01", "2025-02-03", false)
+	if err != nil {
+		t.Fatalf("FetchTransactions: %v", err)
+	}
+	if err := client.SimulateSessionReady(result.SessionID); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.HandleDataReadyWebhook(ctx, result.SessionID); err != nil {
+		t.Fatalf("HandleDataReadyWebhook: %v", err)
+	}
+
+	stored := store.linkTransactions(link.ID)
+	if len(stored) == 0 {
+		t.Fatal("nothing was ingested")
+	}
+	for _, txn := range stored {
+		if txn.Category != "Travel" || txn.Subcategory != "Trip" || txn.CategoryConfidence != ConfidenceRule {
+			t.Errorf("%s = %s/%s at %v, want the user's override", txn.DescriptionRaw, txn.Category, txn.Subcategory, txn.CategoryConfidence)
+		}
+	}
+}
+
+// usdRates quotes USD at 83 INR; every other pair fails
+type usdRates struct{ err error }
+
+func (r usdRates) Rates(base string) (map[string]float64, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if base != "USD" {
+		return nil, errors.New("unsupported base " + base)
+	}
+	return map[string]float64{"USD": 1, "INR": 83}, nil
+}
+
+// foreignCardPayment is a provider transaction in currency
+func foreignCardPayment(ref, currency string, amount float64) ports.FITransaction {
+	return ports.FITransaction{
+		PostedAt:       "2025-05-02T10:00:00Z",
+		Amount:         amount,
+		Currency:       currency,
+		Type:           "DEBIT",
+		DescriptionRaw: "POS/AMAZON/" + ref,
+		AccountRef:     "XXXX1234",
+		SourceMeta:     map[string]interface{}{"txn_ref": ref},
+	}
+}
+
+func TestIngestConvertsMixedCurrenciesIntoTheUsersCurrency(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	user := store.addUser("INR")
+	link := store.addBankLink(user.ID, "consent-fx", "ACTIVE")
+	client := &fixedAAClient{MockAAClient: NewMockAAClient(), transactions: []ports.FITransaction{
+		foreignCardPayment("INR-1", "INR", 500),
+		foreignCardPayment("USD-1", " usd ", 12.5),
+		foreignCardPayment("BAD-1", "US$", 10),
+	}}
+	service := NewAAService(client, store.repositories(), NewNormalizer(), NewDeduplicator(), zap.NewNop())
+	service.Rates = usdRates{}
+
+	created, err := service.fetchAndProcessTransactions(ctx, "session-fx", user.ID, link.ID)
+	if err != nil {
+		t.Fatalf("fetchAndProcessTransactions: %v", err)
+	}
+	if len(created) != 2 {
+		t.Fatalf("created %d transactions, want the INR and USD ones with the malformed currency skipped", len(created))
+	}
+	// Original amounts are kept; the base amount is in the user's INR
+	want := map[string]struct{ amount, baseAmount float64 }{
+		"INR": {500, 500},
+		"USD": {12.5, 1037.5},
+	}
+	for _, txn := range store.linkTransactions(link.ID) {
+		expected, ok := want[txn.Currency]
+		if !ok {
+			t.Errorf("unexpected %s transaction %s", txn.Currency, txn.DescriptionRaw)
+			continue
+		}
+		if txn.Amount != expected.amount || txn.BaseAmount != expected.baseAmount || txn.BaseCurrency != "INR" {
+			t.Errorf("%s transaction = %v (%v %s), want %v (%v INR)",
+				txn.Currency, txn.Amount, txn.BaseAmount, txn.BaseCurrency, expected.amount, expected.baseAmount)
+		}
+	}
+}
+
+func TestIngestStoresNothingWhenConversionFails(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	user := store.addUser("INR")
+	link := store.addBankLink(user.ID, "consent-fx", "ACTIVE")
+	client := &fixedAAClient{MockAAClient: NewMockAAClient(), transactions: []ports.FITransaction{
+		foreignCardPayment("INR-1", "INR", 500),
+		foreignCardPayment("USD-1", "USD", 12.5),
+	}}
+	service := NewAAService(client, store.repositories(), NewNormalizer(), NewDeduplicator(), zap.NewNop())
+	service.Rates = usdRates{err: errors.New("rates unavailable")}
+
+	if _, err := service.fetchAndProcessTransactions(ctx, "session-fx", user.ID, link.ID); !errors.Is(err, ErrCurrencyConversion) {
+		t.Fatalf("err = %v, want ErrCurrencyConversion", err)
+	}
+	if got := len(store.linkTransactions(link.ID)); got != 0 {
+		t.Errorf("%d transactions stored although one couldn't be converted; want none so the session can be retried", got)
+	}
+
+	// Once rates are back the retry stores both
+	service.Rates = usdRates{}
+	if created, err := service.fetchAndProcessTransactions(ctx, "session-fx", user.ID, link.ID); err != nil || len(created) != 2 {
+		t.Errorf("retry created %d, %v; want both transactions", len(created), err)
+	}
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/your-github/expense-tracker-backend/config"
+)
+
+// Signing schemes selectable with AA_SIGNING_SCHEME
+const (
+	SigningSchemeHMAC = "hmac"
+	SigningSchemeRSA  = "rsa"
+)
+
+// Signer signs and verifies AA messages. The HTTP client signs outbound
+// requests and the webhook handler verifies inbound deliveries with the
+// same implementations so both sides agree on the format.
+type Signer interface {
+	Sign(payload []byte) (string, error)
+	Verify(payload []byte, signature string) bool
+}
+
+// RequestSigningPayload is the message signed for an outbound AA request:
+// the timestamp, method and path on their own lines followed by the body
+func RequestSigningPayload(method, path, timestamp string, body []byte) []byte {
+	payload := []byte(timestamp + "\n" + method + "\n" + path + "\n")
+	return append(payload, body...)
+}
+
+// HMACSigner produces hex-encoded HMAC-SHA256 signatures with a shared secret
+type HMACSigner struct {
+	secret []byte
+}
+
+// NewHMACSigner creates an HMAC-SHA256 signer
+func NewHMACSigner(secret string) *HMACSigner {
+	return &HMACSigner{secret: []byte(secret)}
+}
+
+// Sign returns the hex HMAC of payload
+func (s *HMACSigner) Sign(payload []byte) (string, error) {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Verify reports whether signature is the hex HMAC of payload, in constant time
+func (s *HMACSigner) Verify(payload []byte, signature string) bool {
+	if len(s.secret) == 0 {
+		return false
+	}
+	got, err := hex.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return false
+	}
+	h := hmac.New(sha256.New, s.secret)
+	h.Write(payload)
+	return hmac.Equal(got, h.Sum(nil))
+}
+
+// RSASigner produces base64 RSASSA-PKCS1-v1_5 SHA-256 signatures. Signing
+// needs the private key and verifying the public key; either may be nil
+// when the signer is only used one way.
+type RSASigner struct {
+	privateKey *rsa.PrivateKey
+	publicKey  *rsa.PublicKey
+}
+
+// NewRSASigner parses PEM encoded keys. Either key may be empty. Escaped
+// "\n" sequences are accepted so keys can be passed through env vars.
+func NewRSASigner(privateKeyPEM, publicKeyPEM string) (*RSASigner, error) {
+	signer := &RSASigner{}
+	if privateKeyPEM != "" {
+		key, err := parseRSAPrivateKey(privateKeyPEM)
+		if err != nil {
+			return nil, err
+		}
+		signer.privateKey = key
+		signer.publicKey = &key.PublicKey
+	}
+	if publicKeyPEM != "" {
+		key, err := parseRSAPublicKey(publicKeyPEM)
+		if err != nil {
+			return nil, err
+		}
+		signer.publicKey = key
+	}
+	return signer, nil
+}
+
+// Sign returns the base64 RSA signature of payload
+func (s *RSASigner) Sign(payload []byte) (string, error) {
+	if s.privateKey == nil {
+		return "", errors.New("no RSA private key configured")
+	}
+	digest := sha256.Sum256(payload)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign payload: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// Verify reports whether signature is a valid base64 RSA signature of payload
+func (s *RSASigner) Verify(payload []byte, signature string) bool {
+	if s.publicKey == nil {
+		return false
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return false
+	}
+	digest := sha256.Sum256(payload)
+	return rsa.VerifyPKCS1v15(s.publicKey, crypto.SHA256, digest[:], sig) == nil
+}
+
+// NewRequestSigner returns the signer for outbound AA requests: RSA with our
+// private key, or HMAC with the client secret
+func NewRequestSigner(cfg config.AAConfig) (Signer, error) {
+	if strings.EqualFold(cfg.SigningScheme, SigningSchemeRSA) {
+		if cfg.EncPrivateKey == "" {
+			return nil, errors.New("AA RSA signing requires AA_ENC_PRIVATE_KEY")
+		}
+		return NewRSASigner(cfg.EncPrivateKey, "")
+	}
+	return NewHMACSigner(cfg.ClientSecret), nil
+}
+
+// verifyWebhook checks a webhook body against its signature. A missing
+// signer or signature rejects the webhook rather than trusting it.
+func verifyWebhook(signer Signer, payload []byte, signature string) bool {
+	if signer == nil || signature == "" {
+		return false
+	}
+	return signer.Verify(payload, signature)
+}
+
+// NewWebhookSigner returns the signer used to verify inbound AA webhooks:
+// RSA with the provider's public key, or HMAC with the webhook secret
+func NewWebhookSigner(cfg *config.Config) (Signer, error) {
+	if strings.EqualFold(cfg.AA.SigningScheme, SigningSchemeRSA) {
+		if cfg.AA.EncPublicKey == "" {
+			return nil, errors.New("AA RSA webhook verification requires AA_ENC_PUBLIC_KEY")
+		}
+		return NewRSASigner("", cfg.AA.EncPublicKey)
+	}
+	return NewHMACSigner(cfg.Webhook.Secret), nil
+}
+
+func decodePEM(keyPEM string) (*pem.Block, error) {
+	block, _ := pem.Decode([]byte(strings.ReplaceAll(keyPEM, `\n`, "\n")))
+	if block == nil {
+		return nil, errors.New("invalid PEM key")
+	}
+	return block, nil
+}
+
+func parseRSAPrivateKey(keyPEM string) (*rsa.PrivateKey, error) {
+	block, err := decodePEM(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse RSA private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return key, nil
+}
+
+func parseRSAPublicKey(keyPEM string) (*rsa.PublicKey, error) {
+	block, err := decodePEM(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse RSA public key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an RSA key")
+	}
+	return key, nil
+}
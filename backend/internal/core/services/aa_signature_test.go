@@ -0,0 +1,160 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/your-github/expense-tracker-backend/config"
+)
+
+// testRSAKeys returns a fresh key pair as PEM, the private key in PKCS#1
+// and the public key in PKIX form
+func testRSAKeys(t *testing.T) (privatePEM, publicPEM string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privatePEM = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	publicPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}))
+	return privatePEM, publicPEM
+}
+
+func TestHMACSignerKnownVector(t *testing.T) {
+	signer := NewHMACSigner("key")
+	payload := []byte("The quick brown fox jumps over the lazy dog")
+	const want = "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+
+	got, err := signer.Sign(payload)
+	if err != nil || got != want {
+		t.Fatalf("Sign = %q, %v; want %q", got, err, want)
+	}
+	if !signer.Verify(payload, want) || !signer.Verify(payload, strings.ToUpper(want)+"\n") {
+		t.Error("a valid signature didn't verify")
+	}
+	for name, check := range map[string]bool{
+		"tampered payload": signer.Verify([]byte("The quick brown fox jumps over the lazy cat"), want),
+		"other secret":     NewHMACSigner("other").Verify(payload, want),
+		"no secret":        NewHMACSigner("").Verify(payload, want),
+		"not hex":          signer.Verify(payload, "not-a-signature"),
+	} {
+		if check {
+			t.Errorf("%s verified", name)
+		}
+	}
+}
+
+func TestRSASignerRoundTrip(t *testing.T) {
+	privatePEM, publicPEM := testRSAKeys(t)
+	signing, err := NewRSASigner(privatePEM, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keys passed through env vars carry escaped newlines
+	verifying, err := NewRSASigner("", strings.ReplaceAll(publicPEM, "\n", `\n`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := RequestSigningPayload("POST", "/consents", "1700000000", []byte(`{"fi_type":"SAVINGS"}`))
+	signature, err := signing.Sign(payload)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if !verifying.Verify(payload, signature) || !signing.Verify(payload, signature) {
+		t.Fatal("signature didn't verify with the public key")
+	}
+	tampered := RequestSigningPayload("POST", "/consents", "1700000001", []byte(`{"fi_type":"SAVINGS"}`))
+	if verifying.Verify(tampered, signature) {
+		t.Error("signature verified for a different timestamp")
+	}
+
+	_, otherPublic := testRSAKeys(t)
+	other, err := NewRSASigner("", otherPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Verify(payload, signature) {
+		t.Error("signature verified with another key")
+	}
+	if _, err := verifying.Sign(payload); err == nil {
+		t.Error("signed without a private key")
+	}
+	if _, err := NewRSASigner("not a key", ""); err == nil {
+		t.Error("accepted an invalid PEM key")
+	}
+}
+
+func TestSignerSelection(t *testing.T) {
+	privatePEM, publicPEM := testRSAKeys(t)
+
+	if _, err := NewRequestSigner(config.AAConfig{SigningScheme: SigningSchemeRSA}); err == nil {
+		t.Error("RSA request signing without a private key was accepted")
+	}
+	if signer, err := NewRequestSigner(config.AAConfig{ClientSecret: "secret"}); err != nil {
+		t.Errorf("default scheme: %v", err)
+	} else if _, ok := signer.(*HMACSigner); !ok {
+		t.Errorf("default request signer = %T, want HMAC", signer)
+	}
+
+	cfg := &config.Config{}
+	cfg.AA.SigningScheme = "RSA"
+	if _, err := NewWebhookSigner(cfg); err == nil {
+		t.Error("RSA webhook verification without a public key was accepted")
+	}
+	cfg.AA.EncPublicKey = publicPEM
+	webhooks, err := NewWebhookSigner(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests, err := NewRequestSigner(config.AAConfig{SigningScheme: "rsa", EncPrivateKey: privatePEM})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, _ := requests.Sign([]byte("body"))
+	if !verifyWebhook(webhooks, []byte("body"), signature) {
+		t.Error("the webhook signer didn't verify what the request signer signed")
+	}
+	if verifyWebhook(nil, []byte("body"), signature) || verifyWebhook(webhooks, []byte("body"), "") {
+		t.Error("a missing signer or signature was trusted")
+	}
+}
+
+func TestHTTPAAClientSignsWithRSA(t *testing.T) {
+	privatePEM, publicPEM := testRSAKeys(t)
+	verifier, err := NewRSASigner("", publicPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		payload := RequestSigningPayload(r.Method, r.URL.Path, r.Header.Get(aaHeaderTimestamp), body)
+		verified = verifier.Verify(payload, r.Header.Get(aaHeaderSignature))
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"consent_id":"consent-42","status":"active"}`)
+	}))
+	defer server.Close()
+
+	client, err := NewHTTPAAClient(config.AAConfig{BaseURL: server.URL, SigningScheme: "rsa", EncPrivateKey: privatePEM})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetConsentStatus("consent-42"); err != nil {
+		t.Fatalf("GetConsentStatus: %v", err)
+	}
+	if !verified {
+		t.Error("the provider couldn't verify the request with our public key")
+	}
+}
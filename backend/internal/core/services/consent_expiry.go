@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/your-github/expense-tracker-backend/internal/repo"
+	"go.uber.org/zap"
+)
+
+// ConsentReminderSender delivers consent renewal reminders to users
+type ConsentReminderSender interface {
+	SendConsentExpiryReminder(email, fiType string, validTill time.Time) error
+}
+
+// ConsentExpiryNotifier warns users whose active bank link consents are
+// about to expire so fetching doesn't silently stop
+type ConsentExpiryNotifier struct {
+	repositories *repo.Repositories
+	sender       ConsentReminderSender
+	window       time.Duration
+	logger       *zap.Logger
+}
+
+// NewConsentExpiryNotifier creates a notifier that reminds users window
+// before their consent's ValidTill
+func NewConsentExpiryNotifier(repositories *repo.Repositories, sender ConsentReminderSender, window time.Duration, logger *zap.Logger) *ConsentExpiryNotifier {
+	return &ConsentExpiryNotifier{
+		repositories: repositories,
+		sender:       sender,
+		window:       window,
+		logger:       logger,
+	}
+}
+
+// SendReminders emails every user with a consent expiring within the window
+// that hasn't been reminded yet, returning how many reminders were sent.
+// Links are only marked once their email went out, so failed sends are
+// retried on the next run.
+func (n *ConsentExpiryNotifier) SendReminders(ctx context.Context) (int, error) {
+	bankLinks, err := n.repositories.BankLink.GetExpiringWithoutReminder(ctx, time.Now().Add(n.window))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get expiring bank links: %w", err)
+	}
+
+	sent := 0
+	for _, bankLink := range bankLinks {
+		if bankLink.ValidTill == nil || bankLink.User.Email == "" {
+			continue
+		}
+
+		if err := n.sender.SendConsentExpiryReminder(bankLink.User.Email, bankLink.FIType, *bankLink.ValidTill); err != nil {
+			n.logger.Error("Failed to send consent expiry reminder", zap.Error(err), zap.String("bank_link_id", bankLink.ID.String()))
+			continue
+		}
+
+		if err := n.repositories.BankLink.MarkExpiryReminderSent(ctx, bankLink.ID, time.Now()); err != nil {
+			n.logger.Error("Failed to record consent expiry reminder", zap.Error(err), zap.String("bank_link_id", bankLink.ID.String()))
+			continue
+		}
+		sent++
+	}
+
+	return sent, nil
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// reminderOutbox records the consent reminders sent, failing while err is set
+type reminderOutbox struct {
+	sent []string
+	err  error
+}
+
+func (o *reminderOutbox) SendConsentExpiryReminder(email, fiType string, validTill time.Time) error {
+	if o.err != nil {
+		return o.err
+	}
+	o.sent = append(o.sent, email)
+	return nil
+}
+
+func TestConsentExpiryRemindersWithinWindow(t *testing.T) {
+	store := newMemStore()
+	outbox := &reminderOutbox{}
+	notifier := NewConsentExpiryNotifier(store.repositories(), outbox, 7*24*time.Hour, zap.NewNop())
+
+	now := time.Now()
+	links := []struct {
+		status    string
+		validTill time.Duration
+		reminded  bool
+		want      bool
+	}{
+		{"ACTIVE", -time.Hour, false, false}, // already expired
+		{"ACTIVE", 2 * 24 * time.Hour, false, true},
+		{"ACTIVE", 6 * 24 * time.Hour, false, true},
+		{"ACTIVE", 10 * 24 * time.Hour, false, false}, // outside the window
+		{"ACTIVE", 3 * 24 * time.Hour, true, false},
+		{"REVOKED", 3 * 24 * time.Hour, false, false},
+	}
+	want := make(map[string]bool)
+	for _, l := range links {
+		user := store.addUser("INR")
+		link := store.addBankLink(user.ID, "consent-"+user.Email, l.status)
+		validTill := now.Add(l.validTill)
+		link.ValidTill = &validTill
+		if l.reminded {
+			link.ExpiryReminderSentAt = &now
+		}
+		if l.want {
+			want[user.Email] = true
+		}
+	}
+
+	sent, err := notifier.SendReminders(context.Background())
+	if err != nil {
+		t.Fatalf("SendReminders: %v", err)
+	}
+	if sent != len(want) || len(outbox.sent) != len(want) {
+		t.Fatalf("sent %d reminders to %v, want %d", sent, outbox.sent, len(want))
+	}
+	for _, email := range outbox.sent {
+		if !want[email] {
+			t.Errorf("reminded %s, whose link isn't expiring within the window", email)
+		}
+	}
+
+	// A second run must not remind the same users again
+	if again, _ := notifier.SendReminders(context.Background()); again != 0 || len(outbox.sent) != len(want) {
+		t.Errorf("second run sent %d more reminders", again)
+	}
+}
+
+func TestConsentExpiryReminderRetriesFailedSends(t *testing.T) {
+	store := newMemStore()
+	outbox := &reminderOutbox{err: errors.New("smtp unavailable")}
+	notifier := NewConsentExpiryNotifier(store.repositories(), outbox, 7*24*time.Hour, zap.NewNop())
+
+	user := store.addUser("INR")
+	link := store.addBankLink(user.ID, "consent-1", "ACTIVE")
+	validTill := time.Now().Add(24 * time.Hour)
+	link.ValidTill = &validTill
+
+	if sent, err := notifier.SendReminders(context.Background()); err != nil || sent != 0 {
+		t.Fatalf("failed send = %d, %v; want 0 sent and no error", sent, err)
+	}
+	if link.ExpiryReminderSentAt != nil {
+		t.Fatal("link marked as reminded although the email failed")
+	}
+
+	outbox.err = nil
+	if sent, _ := notifier.SendReminders(context.Background()); sent != 1 || link.ExpiryReminderSentAt == nil {
+		t.Errorf("retry sent %d, reminded at %v; want the reminder recorded", sent, link.ExpiryReminderSentAt)
+	}
+}
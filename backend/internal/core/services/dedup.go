@@ -19,27 +19,72 @@ func NewDeduplicator() *Deduplicator {
 	return &Deduplicator{}
 }
 
-// GenerateHash generates a unique hash for transaction deduplication
+// providerRefKeys are the SourceMeta keys checked, in order, for a stable
+// provider-supplied transaction reference
+var providerRefKeys = []string{"txn_ref", "txn_id", "reference_number"}
+
+// providerReference returns the provider's transaction reference, if any
+func providerReference(sourceMeta map[string]interface{}) string {
+	for _, key := range providerRefKeys {
+		if value, ok := sourceMeta[key]; ok && value != nil {
+			if ref := strings.TrimSpace(fmt.Sprint(value)); ref != "" {
+				return ref
+			}
+		}
+	}
+	return ""
+}
+
+// hashProviderReference hashes a transaction by its provider reference,
+// scoped to the account so references from different banks cannot collide
+func hashProviderReference(accountRef, ref string) string {
+	hash := sha256.Sum256([]byte(strings.Join([]string{"ref", strings.ToLower(accountRef), ref}, "|")))
+	return fmt.Sprintf("%x", hash)
+}
+
+// GenerateHash generates a unique hash for transaction deduplication. A
+// provider reference identifies the transaction exactly; without one the
+// hash falls back to account, minute, amount and cleaned description, which
+// cannot tell apart same-minute, same-amount payments to one merchant.
 func (d *Deduplicator) GenerateHash(txn ports.FITransaction) string {
+	if ref := providerReference(txn.SourceMeta); ref != "" {
+		return hashProviderReference(txn.AccountRef, ref)
+	}
+	return d.contentHash(txn.AccountRef, txn.PostedAt, txn.Amount, txn.DescriptionRaw)
+}
+
+// LegacyHash returns the content hash a transaction with a provider
+// reference was stored under before references were hashed, and false when
+// the transaction has no reference and GenerateHash already is that hash
+func (d *Deduplicator) LegacyHash(txn ports.FITransaction) (string, bool) {
+	if providerReference(txn.SourceMeta) == "" {
+		return "", false
+	}
+	return d.contentHash(txn.AccountRef, txn.PostedAt, txn.Amount, txn.DescriptionRaw), true
+}
+
+// contentHash hashes a transaction by account, minute, amount and cleaned
+// description
+func (d *Deduplicator) contentHash(accountRef, postedAtRaw string, amount float64, description string) string {
 	// Parse posted_at to get consistent format
-	postedAt, err := time.Parse(time.RFC3339, txn.PostedAt)
+	postedAt, err := time.Parse(time.RFC3339, postedAtRaw)
 	if err != nil {
 		// Fallback to original string if parsing fails
-		postedAt, _ = time.Parse("2006-01-02", txn.PostedAt)
+		postedAt, _ = time.Parse("2006-01-02", postedAtRaw)
 	}
 
 	// Format: YYYY-MM-DDTHH:MM
 	formattedTime := postedAt.Format("2006-01-02T15:04")
 
 	// Round amount to 2 decimal places to handle floating point precision issues
-	roundedAmount := math.Round(txn.Amount*100) / 100
+	roundedAmount := math.Round(amount*100) / 100
 
 	// Clean description for consistent hashing
-	cleanDesc := d.cleanDescriptionForHash(txn.DescriptionRaw)
+	cleanDesc := d.cleanDescriptionForHash(description)
 
 	// Create hash components
 	components := []string{
-		strings.ToLower(txn.AccountRef),
+		strings.ToLower(accountRef),
 		formattedTime,
 		fmt.Sprintf("%.2f", roundedAmount),
 		cleanDesc,
@@ -112,34 +157,8 @@ func (d *Deduplicator) DeduplicateTransactions(transactions []ports.FITransactio
 
 // GenerateHashForNormalized generates hash for normalized transaction
 func (d *Deduplicator) GenerateHashForNormalized(txn NormalizedTransaction) string {
-	// Parse posted_at to get consistent format
-	postedAt, err := time.Parse(time.RFC3339, txn.PostedAt)
-	if err != nil {
-		// Fallback to original string if parsing fails
-		postedAt, _ = time.Parse("2006-01-02", txn.PostedAt)
-	}
-
-	// Format: YYYY-MM-DDTHH:MM
-	formattedTime := postedAt.Format("2006-01-02T15:04")
-
-	// Round amount to 2 decimal places
-	roundedAmount := math.Round(txn.Amount*100) / 100
-
-	// Clean description for consistent hashing
-	cleanDesc := d.cleanDescriptionForHash(txn.DescriptionRaw)
-
-	// Create hash components
-	components := []string{
-		strings.ToLower(txn.AccountRef),
-		formattedTime,
-		fmt.Sprintf("%.2f", roundedAmount),
-		cleanDesc,
+	if ref := providerReference(txn.SourceMeta); ref != "" {
+		return hashProviderReference(txn.AccountRef, ref)
 	}
-
-	// Join components with separator
-	hashString := strings.Join(components, "|")
-
-	// Generate SHA256 hash
-	hash := sha256.Sum256([]byte(hashString))
-	return fmt.Sprintf("%x", hash)
+	return d.contentHash(txn.AccountRef, txn.PostedAt, txn.Amount, txn.DescriptionRaw)
 }
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/internal/core/ports"
+	"go.uber.org/zap"
+)
+
+// fixedAAClient serves the same transactions for every data session
+type fixedAAClient struct {
+	*MockAAClient
+	transactions []ports.FITransaction
+}
+
+func (c *fixedAAClient) FetchTransactions(sessionID string) ([]ports.FITransaction, error) {
+	return append([]ports.FITransaction(nil), c.transactions...), nil
+}
+
+// splitBillPayment is one of two payments to the same merchant for the same
+// amount within the same minute
+func splitBillPayment(ref string) ports.FITransaction {
+	txn := ports.FITransaction{
+		PostedAt:       "2025-04-12T19:45:10Z",
+		Amount:         450,
+		Currency:       "INR",
+		Type:           "DEBIT",
+		DescriptionRaw: "UPI/SWIGGY/ORDER 99812",
+		AccountRef:     "XXXX1234",
+		SourceMeta:     map[string]interface{}{},
+	}
+	if ref != "" {
+		txn.SourceMeta["txn_ref"] = ref
+	}
+	return txn
+}
+
+func TestSameMinuteTransactionsWithReferencesBothSurvive(t *testing.T) {
+	d := NewDeduplicator()
+	first, second := splitBillPayment("UPI-510211"), splitBillPayment("UPI-510212")
+	second.PostedAt = "2025-04-12T19:45:40Z"
+
+	if d.GenerateHash(first) == d.GenerateHash(second) {
+		t.Fatal("distinct provider references hashed the same")
+	}
+	if got := d.DeduplicateTransactions([]ports.FITransaction{first, second}); len(got) != 2 {
+		t.Errorf("kept %d transactions, want both", len(got))
+	}
+
+	// Without references they can't be told apart and collapse as before
+	if got := d.DeduplicateTransactions([]ports.FITransaction{splitBillPayment(""), splitBillPayment("")}); len(got) != 1 {
+		t.Errorf("kept %d unreferenced transactions, want 1", len(got))
+	}
+}
+
+func TestReferenceHashIgnoresDescriptionChanges(t *testing.T) {
+	d := NewDeduplicator()
+	original := splitBillPayment("UPI-510211")
+	redelivered := splitBillPayment("UPI-510211")
+	redelivered.DescriptionRaw = "UPI-SWIGGY BANGALORE"
+
+	if d.GenerateHash(original) != d.GenerateHash(redelivered) {
+		t.Error("the same reference hashed differently")
+	}
+
+	// The reference is scoped to the account
+	other := splitBillPayment("UPI-510211")
+	other.AccountRef = "XXXX9876"
+	if d.GenerateHash(original) == d.GenerateHash(other) {
+		t.Error("the same reference on another account hashed the same")
+	}
+}
+
+func TestLegacyHashIsTheContentHash(t *testing.T) {
+	d := NewDeduplicator()
+	if _, ok := d.LegacyHash(splitBillPayment("")); ok {
+		t.Error("a transaction without a reference has a legacy hash")
+	}
+
+	legacy, ok := d.LegacyHash(splitBillPayment("UPI-510211"))
+	if !ok {
+		t.Fatal("no legacy hash for a referenced transaction")
+	}
+	if want := d.GenerateHash(splitBillPayment("")); legacy != want {
+		t.Errorf("legacy hash = %s, want the content hash %s", legacy, want)
+	}
+}
+
+func TestGenerateHashForNormalizedMatchesGenerateHash(t *testing.T) {
+	d := NewDeduplicator()
+	for _, ref := range []string{"", "UPI-510211"} {
+		txn := splitBillPayment(ref)
+		normalized := NormalizedTransaction{
+			PostedAt:       txn.PostedAt,
+			Amount:         txn.Amount,
+			DescriptionRaw: txn.DescriptionRaw,
+			AccountRef:     txn.AccountRef,
+			SourceMeta:     txn.SourceMeta,
+		}
+		if d.GenerateHashForNormalized(normalized) != d.GenerateHash(txn) {
+			t.Errorf("ref %q: normalized and FI hashes differ", ref)
+		}
+	}
+}
+
+func TestReimportOfRowStoredUnderContentHash(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	user := store.addUser("INR")
+	link := store.addBankLink(user.ID, "consent-1", "ACTIVE")
+
+	// Stored before provider references were hashed
+	stored := splitBillPayment("UPI-510211")
+	legacy, _ := NewDeduplicator().LegacyHash(stored)
+	id := uuid.New()
+	store.transactions[id] = &domain.Transaction{
+		ID:         id,
+		UserID:     user.ID,
+		BankLinkID: &link.ID,
+		PostedAt:   time.Date(2025, time.April, 12, 19, 45, 10, 0, time.UTC),
+		Amount:     stored.Amount,
+		TxnType:    "DEBIT",
+		HashDedupe: legacy,
+	}
+
+	later := splitBillPayment("UPI-510299")
+	later.PostedAt = "2025-04-13T08:00:00Z"
+	client := &fixedAAClient{MockAAClient: NewMockAAClient(), transactions: []ports.FITransaction{stored, later}}
+	service := NewAAService(client, store.repositories(), NewNormalizer(), NewDeduplicator(), zap.NewNop())
+
+	created, err := service.fetchAndProcessTransactions(ctx, "session-reimport", user.ID, link.ID)
+	if err != nil {
+		t.Fatalf("fetchAndProcessTransactions: %v", err)
+	}
+	if len(created) != 1 || created[0].PostedAt.Day() != 13 {
+		t.Fatalf("created %d transactions, want only the new one", len(created))
+	}
+	if got := len(store.linkTransactions(link.ID)); got != 2 {
+		t.Errorf("%d rows on the link, want the old row and the new one", got)
+	}
+
+	// A dry run previews the same split
+	preview, skipped, err := service.previewTransactions(ctx, "session-preview", user.ID, link.ID)
+	if err != nil {
+		t.Fatalf("previewTransactions: %v", err)
+	}
+	if len(preview) != 0 || len(skipped) != 2 {
+		t.Errorf("preview created %d and skipped %d, want 0 and 2", len(preview), len(skipped))
+	}
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestDryRunFetchPersistsNothingAndMatchesTheRealRun(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	client := &windowedAAClient{}
+	service := NewAAService(client, store.repositories(), NewNormalizer(), NewDeduplicator(), zap.NewNop())
+	service.FetchChunkDays = 30
+	user := store.addUser("INR")
+	link := store.addBankLink(user.ID, "consent-1", "ACTIVE")
+
+	// An earlier import already stored 2024-12-31 through 2025-01-10
+	if _, err := service.FetchTransactions(ctx, user.ID, link.ID, "2025-01-01", "2025-01-10", false); err != nil {
+		t.Fatalf("initial import: %v", err)
+	}
+	before := len(store.linkTransactions(link.ID))
+
+	preview, err := service.FetchTransactions(ctx, user.ID, link.ID, "2025-01-01", "2025-03-31", true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if after := len(store.linkTransactions(link.ID)); after != before {
+		t.Fatalf("dry run stored %d rows", after-before)
+	}
+	for _, chunk := range preview.Chunks {
+		if store.processed[chunk.SessionID] != nil {
+			t.Errorf("dry run marked session %s as processed", chunk.SessionID)
+		}
+	}
+	// 91 days are returned; the 11 stored ones and the two seam repeats are skipped
+	if !preview.DryRun || len(preview.Transactions) != 80 || len(preview.Skipped) != 13 {
+		t.Errorf("preview created %d and skipped %d, want 80 and 13", len(preview.Transactions), len(preview.Skipped))
+	}
+	created, skipped := 0, 0
+	for _, chunk := range preview.Chunks {
+		created += chunk.Created
+		skipped += chunk.Skipped
+	}
+	if created != len(preview.Transactions) || skipped != len(preview.Skipped) {
+		t.Errorf("chunk counts %d/%d disagree with the preview %d/%d", created, skipped, len(preview.Transactions), len(preview.Skipped))
+	}
+
+	real, err := service.FetchTransactions(ctx, user.ID, link.ID, "2025-01-01", "2025-03-31", false)
+	if err != nil {
+		t.Fatalf("real run: %v", err)
+	}
+	stored := len(store.linkTransactions(link.ID)) - before
+	if stored != len(preview.Transactions) || len(real.Transactions) != len(preview.Transactions) {
+		t.Errorf("real run stored %d and returned %d, preview promised %d", stored, len(real.Transactions), len(preview.Transactions))
+	}
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultFetchChunkDays is the data session window used when none is configured
+const DefaultFetchChunkDays = 30
+
+// FetchWindow is one inclusive YYYY-MM-DD date range requested as its own
+// data session
+type FetchWindow struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// splitFetchRange cuts the inclusive range [from, to] into consecutive
+// windows of at most chunkDays days. Windows never overlap: each starts the
+// day after the previous one ends.
+func splitFetchRange(from, to string, chunkDays int) ([]FetchWindow, error) {
+	if chunkDays <= 0 {
+		chunkDays = DefaultFetchChunkDays
+	}
+
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from date %q: %w", from, err)
+	}
+	end, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to date %q: %w", to, err)
+	}
+	if start.After(end) {
+		return nil, fmt.Errorf("from date %s is after to date %s", from, to)
+	}
+
+	var windows []FetchWindow
+	for windowStart := start; !windowStart.After(end); {
+		windowEnd := windowStart.AddDate(0, 0, chunkDays-1)
+		if windowEnd.After(end) {
+			windowEnd = end
+		}
+		windows = append(windows, FetchWindow{
+			From: windowStart.Format("2006-01-02"),
+			To:   windowEnd.Format("2006-01-02"),
+		})
+		windowStart = windowEnd.AddDate(0, 0, 1)
+	}
+	return windows, nil
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/your-github/expense-tracker-backend/internal/core/ports"
+	"go.uber.org/zap"
+)
+
+func TestSplitFetchRange(t *testing.T) {
+	tests := []struct {
+		from, to  string
+		chunkDays int
+		want      []FetchWindow
+	}{
+		{"2025-01-10", "2025-01-10", 30, []FetchWindow{{"2025-01-10", "2025-01-10"}}},
+		{"2025-01-01", "2025-01-30", 30, []FetchWindow{{"2025-01-01", "2025-01-30"}}},
+		{"2025-01-01", "2025-01-31", 30, []FetchWindow{{"2025-01-01", "2025-01-30"}, {"2025-01-31", "2025-01-31"}}},
+		{"2025-01-01", "2025-04-15", 0, []FetchWindow{
+			{"2025-01-01", "2025-01-30"}, {"2025-01-31", "2025-03-01"}, {"2025-03-02", "2025-03-31"}, {"2025-04-01", "2025-04-15"},
+		}},
+		// Leap day and year end inside a window
+		{"2023-12-20", "2024-03-05", 31, []FetchWindow{{"2023-12-20", "2024-01-19"}, {"2024-01-20", "2024-02-19"}, {"2024-02-20", "2024-03-05"}}},
+		{"2025-03-01", "2025-03-03", 1, []FetchWindow{{"2025-03-01", "2025-03-01"}, {"2025-03-02", "2025-03-02"}, {"2025-03-03", "2025-03-03"}}},
+	}
+	for _, tt := range tests {
+		got, err := splitFetchRange(tt.from, tt.to, tt.chunkDays)
+		if err != nil {
+			t.Errorf("%s..%s: %v", tt.from, tt.to, err)
+			continue
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s..%s by %d = %v, want %v", tt.from, tt.to, tt.chunkDays, got, tt.want)
+		}
+	}
+
+	for _, bad := range [][2]string{{"2025-02-01", "2025-01-01"}, {"2025/01/01", "2025-02-01"}, {"2025-01-01", ""}} {
+		if _, err := splitFetchRange(bad[0], bad[1], 30); err == nil {
+			t.Errorf("%s..%s was accepted", bad[0], bad[1])
+		}
+	}
+}
+
+// windowedAAClient serves one READY session per requested window. Like
+// providers that treat both bounds loosely, every session also returns the
+// day before its window, so adjacent sessions overlap at the seam.
+type windowedAAClient struct {
+	ports.AAClient
+	windows []FetchWindow
+}
+
+func (c *windowedAAClient) CreateDataSession(consentID string, fromISO, toISO string) (ports.DataSession, error) {
+	c.windows = append(c.windows, FetchWindow{From: fromISO, To: toISO})
+	return ports.DataSession{SessionID: fmt.Sprintf("session-%d", len(c.windows)), Status: "READY"}, nil
+}
+
+func (c *windowedAAClient) FetchTransactions(sessionID string) ([]ports.FITransaction, error) {
+	var n int
+	fmt.Sscanf(sessionID, "session-%d", &n)
+	window := c.windows[n-1]
+	from, _ := time.Parse("2006-01-02", window.From)
+	to, _ := time.Parse("2006-01-02", window.To)
+
+	var transactions []ports.FITransaction
+	for day := from.AddDate(0, 0, -1); !day.After(to); day = day.AddDate(0, 0, 1) {
+		posted := day.Add(9 * time.Hour)
+		transactions = append(transactions, ports.FITransaction{
+			PostedAt:       posted.Format(time.RFC3339),
+			ValueDate:      day.Format("2006-01-02"),
+			Amount:         100,
+			Currency:       "INR",
+			Type:           "DEBIT",
+			DescriptionRaw: "UPI/SWIGGY/" + day.Format("20060102"),
+			SourceMeta:     map[string]interface{}{"txn_ref": "REF" + day.Format("20060102")},
+		})
+	}
+	return transactions, nil
+}
+
+func TestFetchTransactionsAcrossChunksHasNoSeamDuplicates(t *testing.T) {
+	store := newMemStore()
+	client := &windowedAAClient{}
+	service := NewAAService(client, store.repositories(), NewNormalizer(), NewDeduplicator(), zap.NewNop())
+	service.FetchChunkDays = 30
+	user := store.addUser("INR")
+	link := store.addBankLink(user.ID, "consent-1", "ACTIVE")
+
+	result, err := service.FetchTransactions(context.Background(), user.ID, link.ID, "2025-01-01", "2025-03-31", false)
+	if err != nil {
+		t.Fatalf("FetchTransactions: %v", err)
+	}
+
+	wantWindows := []FetchWindow{{"2025-01-01", "2025-01-30"}, {"2025-01-31", "2025-03-01"}, {"2025-03-02", "2025-03-31"}}
+	if fmt.Sprint(client.windows) != fmt.Sprint(wantWindows) {
+		t.Fatalf("sessions requested for %v, want %v", client.windows, wantWindows)
+	}
+	if len(result.Chunks) != 3 || !result.Processed || result.Status != "READY" {
+		t.Errorf("result = %+v, want three processed chunks", result)
+	}
+
+	// 90 days plus the day before the range, each stored once
+	stored := store.linkTransactions(link.ID)
+	days := make(map[string]int)
+	for _, txn := range stored {
+		days[txn.PostedAt.Format("2006-01-02")]++
+	}
+	for day, count := range days {
+		if count != 1 {
+			t.Errorf("%s stored %d times", day, count)
+		}
+	}
+	if len(stored) != 91 || len(result.Transactions) != 91 {
+		t.Errorf("stored %d and returned %d transactions, want 91", len(stored), len(result.Transactions))
+	}
+}
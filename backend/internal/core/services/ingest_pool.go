@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/internal/core/ports"
+	"go.uber.org/zap"
+)
+
+// DefaultIngestWorkers is the ingestion worker pool size used when none is configured
+const DefaultIngestWorkers = 4
+
+// ingestWorkers returns the configured worker pool size
+func (s *AAService) ingestWorkers() int {
+	if s.IngestWorkers <= 0 {
+		return DefaultIngestWorkers
+	}
+	return s.IngestWorkers
+}
+
+// runPool calls fn for every index in [0, n) on at most workers goroutines
+// and waits for them all. fn writes its result at index i, so results keep
+// the input order however the work interleaves. Indices not yet started
+// when ctx is cancelled are skipped.
+func runPool(ctx context.Context, workers, n int, fn func(i int)) {
+	if workers > n {
+		workers = n
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// buildTransactions normalizes fiTransactions on the worker pool. The result
+// is in input order, with nil for transactions skipped for an invalid
+// currency. Any other error aborts the build so nothing is stored.
+func (s *AAService) buildTransactions(ctx context.Context, fiTransactions []ports.FITransaction, hashes []string, userID, bankLinkID uuid.UUID, settings ingestSettings) ([]*domain.Transaction, error) {
+	transactions := make([]*domain.Transaction, len(fiTransactions))
+	errs := make([]error, len(fiTransactions))
+	runPool(ctx, s.ingestWorkers(), len(fiTransactions), func(i int) {
+		transactions[i], errs[i] = s.buildTransaction(ctx, fiTransactions[i], hashes[i], userID, bankLinkID, settings)
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, err := range errs {
+		if errors.Is(err, ErrInvalidCurrency) {
+			s.log(ctx).Warn("Skipping transaction with invalid currency", zap.Error(err), zap.String("hash", hashes[i]))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return transactions, nil
+}
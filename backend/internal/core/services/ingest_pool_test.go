@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/internal/core/ports"
+	"go.uber.org/zap"
+)
+
+func TestRunPoolKeepsInputOrderWithinTheBound(t *testing.T) {
+	var running, peak atomic.Int64
+	results := make([]int, 50)
+	runPool(context.Background(), 3, len(results), func(i int) {
+		now := running.Add(1)
+		for {
+			seen := peak.Load()
+			if now <= seen || peak.CompareAndSwap(seen, now) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		results[i] = i * i
+		running.Add(-1)
+	})
+
+	for i, got := range results {
+		if got != i*i {
+			t.Fatalf("results[%d] = %d, want %d", i, got, i*i)
+		}
+	}
+	if peak.Load() > 3 {
+		t.Errorf("%d calls ran at once, want at most 3", peak.Load())
+	}
+}
+
+func TestRunPoolStopsFeedingWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var ran atomic.Int64
+	runPool(ctx, 1, 100, func(i int) {
+		ran.Add(1)
+		cancel()
+	})
+	if ran.Load() == 100 {
+		t.Error("every index ran although the context was cancelled after the first")
+	}
+}
+
+// statementLine is the i-th of a statement's transactions: an opening
+// credit with a reported balance, then debits of 10 a minute apart
+func statementLine(i int) ports.FITransaction {
+	txn := ports.FITransaction{
+		PostedAt:       time.Date(2025, time.May, 1, 9, 0, 0, 0, time.UTC).Add(time.Duration(i) * time.Minute).Format(time.RFC3339),
+		Amount:         10,
+		Currency:       "INR",
+		Type:           "DEBIT",
+		DescriptionRaw: fmt.Sprintf("UPI/SWIGGY/ORDER%d", i),
+		AccountRef:     "XXXX1234",
+		SourceMeta:     map[string]interface{}{"txn_ref": fmt.Sprintf("REF-%d", i)},
+	}
+	if i == 0 {
+		txn.Amount, txn.Type, txn.BalanceAfter = 10000, "CREDIT", ptrFloat(10000)
+	}
+	return txn
+}
+
+// statement returns n statement lines newest first, the reverse of the
+// order their balances have to be computed in
+func statement(n int) []ports.FITransaction {
+	lines := make([]ports.FITransaction, n)
+	for i := range lines {
+		lines[i] = statementLine(n - 1 - i)
+	}
+	return lines
+}
+
+func TestIngestOnTheWorkerPool(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	user := store.addUser("INR")
+	link := store.addBankLink(user.ID, "consent-pool", "ACTIVE")
+	client := &fixedAAClient{MockAAClient: NewMockAAClient(), transactions: statement(200)}
+	service := NewAAService(client, store.repositories(), NewNormalizer(), NewDeduplicator(), zap.NewNop())
+	service.IngestWorkers = 8
+
+	// One line is already stored, long before the rest of the user's history
+	stored := &domain.Transaction{
+		ID: uuid.New(), UserID: user.ID, BankLinkID: &link.ID, PostedAt: time.Date(2025, time.May, 1, 9, 5, 0, 0, time.UTC),
+		Amount: 10, TxnType: "DEBIT", HashDedupe: NewDeduplicator().GenerateHash(statementLine(5)),
+	}
+	store.transactions[stored.ID] = stored
+
+	created, err := service.fetchAndProcessTransactions(ctx, "session-pool", user.ID, link.ID)
+	if err != nil {
+		t.Fatalf("fetchAndProcessTransactions: %v", err)
+	}
+	if len(created) != 199 {
+		t.Fatalf("created %d transactions, want 199 with the stored one skipped", len(created))
+	}
+	if got := len(store.linkTransactions(link.ID)); got != 200 {
+		t.Errorf("bank link has %d transactions, want 200", got)
+	}
+
+	// Balances run in posted order although the lines arrived newest first
+	// and were stored concurrently
+	var latest *domain.Transaction
+	for _, txn := range store.linkTransactions(link.ID) {
+		if latest == nil || txn.PostedAt.After(latest.PostedAt) {
+			latest = txn
+		}
+	}
+	if latest.BalanceAfter == nil || *latest.BalanceAfter != 10000-199*10 {
+		t.Errorf("latest balance = %v, want %d", latest.BalanceAfter, 10000-199*10)
+	}
+}
+
+func BenchmarkFetchAndProcessTransactions(b *testing.B) {
+	ctx := context.Background()
+	lines := statement(3000)
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		store := newMemStore()
+		user := store.addUser("INR")
+		link := store.addBankLink(user.ID, "consent-bench", "ACTIVE")
+		client := &fixedAAClient{MockAAClient: NewMockAAClient(), transactions: lines}
+		service := NewAAService(client, store.repositories(), NewNormalizer(), NewDeduplicator(), zap.NewNop())
+		b.StartTimer()
+
+		created, err := service.fetchAndProcessTransactions(ctx, fmt.Sprintf("session-%d", i), user.ID, link.ID)
+		if err != nil || len(created) != len(lines) {
+			b.Fatalf("created %d, %v; want %d", len(created), err, len(lines))
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/utils"
+	"gorm.io/gorm"
+)
+
+// Merchant alias field limits
+const (
+	MaxMerchantAliasPatternLength  = 200
+	MaxMerchantAliasMerchantLength = 100
+)
+
+var (
+	ErrMerchantAliasNotFound = errors.New("merchant alias not found")
+	ErrInvalidMerchantAlias  = errors.New("invalid merchant alias")
+)
+
+// ListMerchantAliases returns the aliases applied to the user's
+// transactions, the user's own first and then the global ones
+func (s *AAService) ListMerchantAliases(ctx context.Context, userID uuid.UUID) ([]*domain.MerchantAlias, error) {
+	return s.repositories.MerchantAlias.GetForUser(ctx, userID)
+}
+
+// ListGlobalMerchantAliases returns the aliases applied to every user
+func (s *AAService) ListGlobalMerchantAliases(ctx context.Context) ([]*domain.MerchantAlias, error) {
+	return s.repositories.MerchantAlias.GetGlobal(ctx)
+}
+
+// CreateMerchantAlias stores an alias owned by userID, or a global alias
+// when userID is nil. Existing transactions keep their merchant until they
+// are renormalized.
+func (s *AAService) CreateMerchantAlias(ctx context.Context, userID *uuid.UUID, pattern, merchant string) (*domain.MerchantAlias, error) {
+	pattern = strings.TrimSpace(utils.SanitizeText(pattern, false))
+	merchant = strings.TrimSpace(utils.SanitizeText(merchant, false))
+	if pattern == "" || merchant == "" {
+		return nil, fmt.Errorf("%w: pattern and merchant are required", ErrInvalidMerchantAlias)
+	}
+	if len(pattern) > MaxMerchantAliasPatternLength {
+		return nil, fmt.Errorf("%w: pattern must be at most %d characters", ErrInvalidMerchantAlias, MaxMerchantAliasPatternLength)
+	}
+	if len(merchant) > MaxMerchantAliasMerchantLength {
+		return nil, fmt.Errorf("%w: merchant must be at most %d characters", ErrInvalidMerchantAlias, MaxMerchantAliasMerchantLength)
+	}
+	if _, isRegex, err := utils.RuleRegex(pattern); isRegex && err != nil {
+		return nil, fmt.Errorf("%w: invalid pattern regex: %v", ErrInvalidMerchantAlias, err)
+	}
+
+	alias := &domain.MerchantAlias{UserID: userID, Pattern: pattern, Merchant: merchant}
+	if err := s.repositories.MerchantAlias.Create(ctx, alias); err != nil {
+		return nil, fmt.Errorf("failed to create merchant alias: %w", err)
+	}
+	return alias, nil
+}
+
+// DeleteMerchantAlias removes an alias owned by userID, or a global alias
+// when userID is nil. Aliases with a different owner are reported as not
+// found.
+func (s *AAService) DeleteMerchantAlias(ctx context.Context, userID *uuid.UUID, id uuid.UUID) error {
+	alias, err := s.repositories.MerchantAlias.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrMerchantAliasNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get merchant alias: %w", err)
+	}
+	if !sameOwner(alias.UserID, userID) {
+		return ErrMerchantAliasNotFound
+	}
+	if err := s.repositories.MerchantAlias.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete merchant alias: %w", err)
+	}
+	return nil
+}
+
+// sameOwner reports whether two optional user IDs are both nil or equal
+func sameOwner(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
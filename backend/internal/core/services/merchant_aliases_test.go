@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/internal/core/ports"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+func TestMerchantAliasesCanonicalizeTheMerchant(t *testing.T) {
+	n := NewNormalizer()
+	if err := n.SetRules([]MerchantRule{{Matcher: "instamart", Merchant: "Instamart", Category: utils.CategoryShopping, Subcategory: "Groceries"}}); err != nil {
+		t.Fatal(err)
+	}
+	aliases := []MerchantAlias{{Pattern: "/^SWIGGY[* ]/", Merchant: "Swiggy"}, {Pattern: "sharma stores", Merchant: "Sharma Kirana"}}
+
+	tests := []struct {
+		description string
+		want        string
+	}{
+		{"SWIGGY*ORDER 4411", "Swiggy"},
+		{"SWIGGY INSTAMART BLR", "Swiggy"}, // the alias wins over the rule's merchant
+		{"POS 1102 Sharma Stores Indiranagar", "Sharma Kirana"},
+		{"UPI/ZOMATO/ORDER 1", "Food Delivery"},
+	}
+	for _, tt := range tests {
+		got := n.NormalizeTransaction(ports.FITransaction{
+			PostedAt: "2025-03-01T10:00:00Z", Amount: 300, Currency: "INR", Type: "DEBIT", DescriptionRaw: tt.description,
+		}, aliases)
+		if got.MerchantName != tt.want {
+			t.Errorf("%q: merchant %q, want %q", tt.description, got.MerchantName, tt.want)
+		}
+	}
+
+	// The rule's category still applies to an aliased merchant
+	got := n.NormalizeTransaction(ports.FITransaction{
+		PostedAt: "2025-03-01T10:00:00Z", Amount: 300, Currency: "INR", Type: "DEBIT", DescriptionRaw: "SWIGGY INSTAMART BLR",
+	}, aliases)
+	if got.Category != utils.CategoryShopping || got.Subcategory != "Groceries" {
+		t.Errorf("aliased instamart order in %s/%s, want the rule's category", got.Category, got.Subcategory)
+	}
+}
+
+func TestUserMerchantAliasOverridesGlobal(t *testing.T) {
+	store := newMemStore()
+	service, _ := newTestAAService(t, store)
+	owner := store.addUser("INR")
+	other := store.addUser("INR")
+	ctx := context.Background()
+
+	if _, err := service.CreateMerchantAlias(ctx, nil, "kirana", "Neighbourhood Store"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.CreateMerchantAlias(ctx, &owner.ID, "kirana", "Sharma Kirana"); err != nil {
+		t.Fatal(err)
+	}
+
+	txns := make(map[uuid.UUID]*domain.Transaction)
+	for _, user := range []*domain.User{owner, other} {
+		txn := &domain.Transaction{
+			ID: uuid.New(), UserID: user.ID, PostedAt: time.Date(2025, time.March, 1, 9, 0, 0, 0, time.UTC),
+			Amount: -180, Currency: "INR", TxnType: "DEBIT", DescriptionRaw: "POS 7731 KIRANA CORNER", MerchantName: "Unknown",
+		}
+		store.transactions[txn.ID] = txn
+		txns[user.ID] = txn
+		if _, err := service.RenormalizeTransactions(ctx, user.ID); err != nil {
+			t.Fatalf("RenormalizeTransactions: %v", err)
+		}
+	}
+
+	if got := txns[owner.ID].MerchantName; got != "Sharma Kirana" {
+		t.Errorf("owner's merchant = %q, want their own alias", got)
+	}
+	if got := txns[other.ID].MerchantName; got != "Neighbourhood Store" {
+		t.Errorf("other user's merchant = %q, want the global alias", got)
+	}
+
+	listed, _ := service.ListMerchantAliases(ctx, owner.ID)
+	if len(listed) != 2 || listed[0].UserID == nil || listed[1].UserID != nil {
+		t.Errorf("listed %d aliases, want the owner's before the global one", len(listed))
+	}
+}
+
+func TestCreateMerchantAliasRejections(t *testing.T) {
+	store := newMemStore()
+	service, _ := newTestAAService(t, store)
+	user := store.addUser("INR")
+
+	tests := []struct {
+		name, pattern, merchant string
+	}{
+		{"empty pattern", "  ", "Swiggy"},
+		{"empty merchant", "swiggy", ""},
+		{"bad regex", "/swiggy(/", "Swiggy"},
+		{"long pattern", strings.Repeat("a", MaxMerchantAliasPatternLength+1), "Swiggy"},
+		{"long merchant", "swiggy", strings.Repeat("a", MaxMerchantAliasMerchantLength+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.CreateMerchantAlias(context.Background(), &user.ID, tt.pattern, tt.merchant); !errors.Is(err, ErrInvalidMerchantAlias) {
+				t.Errorf("CreateMerchantAlias: %v, want ErrInvalidMerchantAlias", err)
+			}
+		})
+	}
+	if len(store.aliases) != 0 {
+		t.Errorf("rejected aliases stored %d rows", len(store.aliases))
+	}
+}
+
+func TestDeleteMerchantAliasOnlyRemovesOwnAliases(t *testing.T) {
+	store := newMemStore()
+	service, _ := newTestAAService(t, store)
+	owner := store.addUser("INR")
+	other := store.addUser("INR")
+	ctx := context.Background()
+
+	own, _ := service.CreateMerchantAlias(ctx, &owner.ID, "kirana", "Sharma Kirana")
+	global, _ := service.CreateMerchantAlias(ctx, nil, "kirana", "Neighbourhood Store")
+
+	if err := service.DeleteMerchantAlias(ctx, &other.ID, own.ID); !errors.Is(err, ErrMerchantAliasNotFound) {
+		t.Errorf("another user deleting the alias: %v, want ErrMerchantAliasNotFound", err)
+	}
+	if err := service.DeleteMerchantAlias(ctx, &owner.ID, global.ID); !errors.Is(err, ErrMerchantAliasNotFound) {
+		t.Errorf("a user deleting a global alias: %v, want ErrMerchantAliasNotFound", err)
+	}
+	if err := service.DeleteMerchantAlias(ctx, nil, own.ID); !errors.Is(err, ErrMerchantAliasNotFound) {
+		t.Errorf("the admin endpoint deleting a user's alias: %v, want ErrMerchantAliasNotFound", err)
+	}
+	if len(store.aliases) != 2 {
+		t.Fatalf("%d aliases left, want both", len(store.aliases))
+	}
+
+	if err := service.DeleteMerchantAlias(ctx, &owner.ID, own.ID); err != nil {
+		t.Errorf("owner deleting their alias: %v", err)
+	}
+	if err := service.DeleteMerchantAlias(ctx, nil, global.ID); err != nil {
+		t.Errorf("deleting the global alias: %v", err)
+	}
+	if len(store.aliases) != 0 {
+		t.Errorf("%d aliases left after deleting both", len(store.aliases))
+	}
+}
@@ -1,17 +1,60 @@
 package services
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/your-github/expense-tracker-backend/internal/core/ports"
+	"github.com/your-github/expense-tracker-backend/utils"
 )
 
 // Normalizer normalizes transaction data from AA providers
 type Normalizer struct {
 	merchantPatterns map[string]string
 	upiPatterns      []*regexp.Regexp
+
+	// rules are user/admin merchant rules layered over the built-in
+	// patterns; they can be swapped at runtime via SetRules or Reload
+	rules     []compiledMerchantRule
+	rulesPath string
+	rulesMu   sync.RWMutex
+
+	// ReviewThreshold is the category confidence below which a transaction
+	// is flagged for review. Zero means DefaultReviewThreshold.
+	ReviewThreshold float64
+}
+
+// DefaultReviewThreshold flags transactions categorized by the fallback
+// rule alone, while keyword and merchant matches pass
+const DefaultReviewThreshold = 0.5
+
+// Category confidences assigned by the normalizer, by how the category was
+// chosen
+const (
+	ConfidenceRule        = 1.0 // merchant rule or user override
+	ConfidenceMerchant    = 0.9 // known merchant
+	ConfidenceDescription = 0.6 // keyword in the description
+	ConfidenceFallback    = 0.0 // nothing matched; category is Other
+)
+
+// MerchantRule maps a description matcher to a merchant and category.
+// Matcher uses the same syntax as category overrides: "/expr/" is a
+// regular expression, anything else is a case-insensitive substring.
+type MerchantRule struct {
+	Matcher     string `json:"matcher"`
+	Merchant    string `json:"merchant"`
+	Category    string `json:"category"`
+	Subcategory string `json:"subcategory"`
+}
+
+// compiledMerchantRule is a MerchantRule with its regex precompiled
+type compiledMerchantRule struct {
+	MerchantRule
+	re *regexp.Regexp
 }
 
 // NewNormalizer creates a new transaction normalizer
@@ -45,31 +88,137 @@ func NewNormalizer() *Normalizer {
 	}
 }
 
-// NormalizeTransaction normalizes a single transaction
-func (n *Normalizer) NormalizeTransaction(txn ports.FITransaction) NormalizedTransaction {
+// NormalizeTransaction normalizes a single transaction. aliases canonicalize
+// the merchant name ahead of every other merchant rule; pass the user's
+// aliases before the global ones so they take precedence.
+func (n *Normalizer) NormalizeTransaction(txn ports.FITransaction, aliases []MerchantAlias) NormalizedTransaction {
 	normalized := NormalizedTransaction{
 		PostedAt:       txn.PostedAt,
 		ValueDate:      txn.ValueDate,
 		Amount:         txn.Amount,
-		Currency:       txn.Currency,
-		TxnType:        txn.Type,
+		Currency:       utils.NormalizeCurrency(txn.Currency),
+		TxnType:        strings.ToUpper(strings.TrimSpace(txn.Type)),
 		BalanceAfter:   txn.BalanceAfter,
 		SourceMeta:     txn.SourceMeta,
 	}
 
 	// Normalize description and extract merchant
 	normalized.DescriptionRaw = n.cleanDescription(txn.DescriptionRaw)
-	normalized.MerchantName = n.extractMerchant(txn.DescriptionRaw)
+	var aliased bool
+	normalized.MerchantName, aliased = n.extractMerchant(txn.DescriptionRaw, aliases)
 	
 	// Extract account reference
 	normalized.AccountRef = n.extractAccountRef(txn.AccountRef, txn.DescriptionRaw)
 	
 	// Categorize transaction
-	normalized.Category = n.categorizeTransaction(normalized.DescriptionRaw, normalized.MerchantName)
-	
+	normalized.Category, normalized.Confidence = n.categorizeTransaction(normalized.DescriptionRaw, normalized.MerchantName)
+
+	// Configured rules take precedence over the built-in defaults
+	if rule, ok := n.matchRule(txn.DescriptionRaw, normalized.DescriptionRaw); ok {
+		if rule.Merchant != "" && !aliased {
+			normalized.MerchantName = rule.Merchant
+		}
+		if rule.Category != "" {
+			normalized.Category = utils.NormalizeCategory(rule.Category)
+			normalized.Confidence = ConfidenceRule
+		}
+		normalized.Subcategory = rule.Subcategory
+	}
+
 	return normalized
 }
 
+// SetRules replaces the configured merchant rules. Rules are evaluated in
+// order and the first match wins. If any regex fails to compile the
+// existing rules are left untouched.
+func (n *Normalizer) SetRules(rules []MerchantRule) error {
+	compiled := make([]compiledMerchantRule, 0, len(rules))
+	for _, rule := range rules {
+		if strings.TrimSpace(rule.Matcher) == "" {
+			return fmt.Errorf("merchant rule has an empty matcher")
+		}
+		cr := compiledMerchantRule{MerchantRule: rule}
+		if pattern, ok := regexMatcher(rule.Matcher); ok {
+			re, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return fmt.Errorf("invalid merchant rule regex %q: %w", rule.Matcher, err)
+			}
+			cr.re = re
+		}
+		compiled = append(compiled, cr)
+	}
+
+	n.rulesMu.Lock()
+	n.rules = compiled
+	n.rulesMu.Unlock()
+	return nil
+}
+
+// LoadRulesFile reads merchant rules from a JSON file (an array of
+// MerchantRule) and remembers the path for Reload
+func (n *Normalizer) LoadRulesFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read merchant rules: %w", err)
+	}
+
+	var rules []MerchantRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("failed to parse merchant rules: %w", err)
+	}
+
+	if err := n.SetRules(rules); err != nil {
+		return err
+	}
+
+	n.rulesMu.Lock()
+	n.rulesPath = path
+	n.rulesMu.Unlock()
+	return nil
+}
+
+// Reload re-reads the rules file last passed to LoadRulesFile
+func (n *Normalizer) Reload() error {
+	n.rulesMu.RLock()
+	path := n.rulesPath
+	n.rulesMu.RUnlock()
+
+	if path == "" {
+		return fmt.Errorf("no merchant rules file configured")
+	}
+	return n.LoadRulesFile(path)
+}
+
+// matchRule returns the first configured rule matching any of the descriptions
+func (n *Normalizer) matchRule(descriptions ...string) (MerchantRule, bool) {
+	n.rulesMu.RLock()
+	defer n.rulesMu.RUnlock()
+
+	for _, rule := range n.rules {
+		for _, description := range descriptions {
+			if description == "" {
+				continue
+			}
+			if rule.re != nil {
+				if rule.re.MatchString(description) {
+					return rule.MerchantRule, true
+				}
+			} else if strings.Contains(strings.ToLower(description), strings.ToLower(rule.Matcher)) {
+				return rule.MerchantRule, true
+			}
+		}
+	}
+	return MerchantRule{}, false
+}
+
+// regexMatcher reports whether matcher is a "/expr/" regex and returns expr
+func regexMatcher(matcher string) (string, bool) {
+	if len(matcher) >= 2 && strings.HasPrefix(matcher, "/") && strings.HasSuffix(matcher, "/") {
+		return matcher[1 : len(matcher)-1], true
+	}
+	return "", false
+}
+
 // NormalizedTransaction represents a normalized transaction
 type NormalizedTransaction struct {
 	PostedAt       string                 `json:"posted_at"`
@@ -83,9 +232,89 @@ type NormalizedTransaction struct {
 	AccountRef     string                 `json:"account_ref"`
 	Category       string                 `json:"category"`
 	Subcategory    string                 `json:"subcategory"`
+	Confidence     float64                `json:"confidence"` // 0-1, how the category was chosen
 	SourceMeta     map[string]interface{} `json:"source_meta"`
 }
 
+// reviewThreshold returns ReviewThreshold or its default
+func (n *Normalizer) reviewThreshold() float64 {
+	if n.ReviewThreshold <= 0 {
+		return DefaultReviewThreshold
+	}
+	return n.ReviewThreshold
+}
+
+// NeedsReview reports whether the user should check a normalized
+// transaction: its category confidence is below the review threshold or
+// no merchant could be identified
+func (n *Normalizer) NeedsReview(transaction NormalizedTransaction) bool {
+	return transaction.Confidence < n.reviewThreshold() || isUnknownMerchant(transaction.MerchantName)
+}
+
+// minMerchantKeyContains is the shortest merchant key SameMerchant matches
+// as a substring of another, so "a" doesn't match every merchant
+const minMerchantKeyContains = 3
+
+// SameMerchant reports whether two descriptions name the same merchant:
+// both resolve to the same configured merchant rule or built-in merchant
+// pattern, or their cleaned descriptions are equal or one contains the other
+func (n *Normalizer) SameMerchant(a, b string) bool {
+	keyA, keyB := n.merchantKey(a), n.merchantKey(b)
+	if keyA == "" || keyB == "" {
+		return false
+	}
+	if keyA == keyB {
+		return true
+	}
+	if len(keyA) < minMerchantKeyContains || len(keyB) < minMerchantKeyContains {
+		return false
+	}
+	return strings.Contains(keyA, keyB) || strings.Contains(keyB, keyA)
+}
+
+// paymentChannels are the built-in merchants naming how a payment was made
+// rather than who was paid, so they don't identify a merchant
+var paymentChannels = map[string]bool{"Digital Payments": true, "Bank Transfer": true}
+
+// merchantKey reduces a description to a comparable merchant: the merchant
+// of a matching rule, else the built-in merchant pattern it contains, else
+// the cleaned description. Patterns are compared rather than the merchants
+// they map to, which group several brands such as "Food Delivery".
+func (n *Normalizer) merchantKey(description string) string {
+	cleaned := n.cleanDescription(description)
+	if rule, ok := n.matchRule(description, cleaned); ok && rule.Merchant != "" {
+		return strings.ToLower(rule.Merchant)
+	}
+	if pattern := n.merchantPattern(description, paymentChannels); pattern != "" {
+		return pattern
+	}
+	return strings.ToLower(cleaned)
+}
+
+// MerchantAlias maps descriptions matching Pattern to the canonical merchant
+// name Merchant. Pattern uses the category override syntax: "/expr/" is a
+// regular expression, anything else a case-insensitive substring.
+type MerchantAlias struct {
+	Pattern  string `json:"pattern"`
+	Merchant string `json:"merchant"`
+}
+
+// matchAlias returns the merchant of the first alias matching description
+func matchAlias(aliases []MerchantAlias, description string) (string, bool) {
+	for _, alias := range aliases {
+		if (utils.CategoryRule{Matcher: alias.Pattern}).Matches(description) {
+			return alias.Merchant, true
+		}
+	}
+	return "", false
+}
+
+// isUnknownMerchant reports whether extractMerchant found no merchant
+func isUnknownMerchant(merchant string) bool {
+	merchant = strings.TrimSpace(merchant)
+	return merchant == "" || strings.EqualFold(merchant, "Unknown")
+}
+
 // cleanDescription cleans and standardizes transaction descriptions
 func (n *Normalizer) cleanDescription(description string) string {
 	if description == "" {
@@ -119,41 +348,62 @@ func (n *Normalizer) cleanDescription(description string) string {
 	return strings.TrimSpace(cleaned)
 }
 
-// extractMerchant extracts merchant name from description
-func (n *Normalizer) extractMerchant(description string) string {
+// extractMerchant extracts merchant name from description. The first
+// matching alias wins over the built-in patterns; the bool reports whether
+// one matched.
+func (n *Normalizer) extractMerchant(description string, aliases []MerchantAlias) (string, bool) {
 	if description == "" {
-		return ""
+		return "", false
+	}
+	if alias, ok := matchAlias(aliases, description); ok {
+		return alias, true
 	}
 
 	desc := strings.ToLower(description)
 
 	// Check for known merchant patterns
-	for pattern, merchant := range n.merchantPatterns {
-		if strings.Contains(desc, pattern) {
-			return merchant
-		}
+	if pattern := n.merchantPattern(description, nil); pattern != "" {
+		return n.merchantPatterns[pattern], false
 	}
 
 	// Extract from UPI patterns
 	for _, pattern := range n.upiPatterns {
 		matches := pattern.FindStringSubmatch(description)
 		if len(matches) >= 3 {
-			return strings.ToUpper(matches[2]) // Return the handle part
+			return strings.ToUpper(matches[2]), false // Return the handle part
 		}
 	}
 
 	// Try to extract from common patterns
 	if strings.Contains(desc, "atm") {
-		return "ATM"
+		return "ATM", false
 	}
 	if strings.Contains(desc, "salary") {
-		return "Salary"
+		return "Salary", false
 	}
 	if strings.Contains(desc, "interest") {
-		return "Interest"
+		return "Interest", false
 	}
 
-	return "Unknown"
+	return "Unknown", false
+}
+
+// merchantPattern returns the built-in merchant pattern description
+// contains, skipping patterns whose merchant is in skip. The longest
+// matching pattern wins so "UPI/SWIGGY/..." is Swiggy rather than UPI
+// whatever the map order, keeping renormalization stable from run to run.
+func (n *Normalizer) merchantPattern(description string, skip map[string]bool) string {
+	desc := strings.ToLower(description)
+	best := ""
+	for pattern, merchant := range n.merchantPatterns {
+		if skip[merchant] || !strings.Contains(desc, pattern) {
+			continue
+		}
+		if len(pattern) > len(best) || (len(pattern) == len(best) && pattern < best) {
+			best = pattern
+		}
+	}
+	return best
 }
 
 // extractAccountRef extracts account reference from description or account ref
@@ -180,78 +430,62 @@ func (n *Normalizer) extractAccountRef(accountRef, description string) string {
 	return ""
 }
 
-// categorizeTransaction categorizes transaction based on description and merchant
-func (n *Normalizer) categorizeTransaction(description, merchant string) string {
+// categorizeTransaction categorizes transaction based on description and
+// merchant, returning the category and the confidence in it
+func (n *Normalizer) categorizeTransaction(description, merchant string) (string, float64) {
 	desc := strings.ToLower(description)
 	merchantLower := strings.ToLower(merchant)
 
-	// Check merchant-based categorization first
+	// Known merchants carry their category; UPI handles and other
+	// free-form merchants fall through to the description checks
 	if merchantLower != "unknown" {
-		return merchant
+		if category, ok := utils.LookupCategory(merchant); ok {
+			return category, ConfidenceMerchant
+		}
 	}
 
 	// Check description-based categorization
 	if strings.Contains(desc, "food") || strings.Contains(desc, "restaurant") {
-		return "Food & Dining"
+		return utils.CategoryFood, ConfidenceDescription
 	}
 	if strings.Contains(desc, "fuel") || strings.Contains(desc, "petrol") || strings.Contains(desc, "diesel") {
-		return "Transport"
+		return utils.CategoryTransport, ConfidenceDescription
 	}
 	if strings.Contains(desc, "medical") || strings.Contains(desc, "hospital") || strings.Contains(desc, "pharmacy") {
-		return "Healthcare"
+		return utils.CategoryHealthcare, ConfidenceDescription
 	}
 	if strings.Contains(desc, "education") || strings.Contains(desc, "school") || strings.Contains(desc, "college") {
-		return "Education"
+		return utils.CategoryEducation, ConfidenceDescription
 	}
 	if strings.Contains(desc, "rent") || strings.Contains(desc, "electricity") || strings.Contains(desc, "water") {
-		return "Utilities"
+		return utils.CategoryBills, ConfidenceDescription
 	}
 	if strings.Contains(desc, "salary") || strings.Contains(desc, "income") {
-		return "Income"
+		return utils.CategoryIncome, ConfidenceDescription
 	}
 	if strings.Contains(desc, "interest") {
-		return "Income"
+		return utils.CategoryIncome, ConfidenceDescription
 	}
 	if strings.Contains(desc, "atm") || strings.Contains(desc, "withdrawal") {
-		return "Cash Withdrawal"
+		return utils.CategoryCashWithdrawal, ConfidenceDescription
 	}
 	if strings.Contains(desc, "transfer") || strings.Contains(desc, "neft") || strings.Contains(desc, "imps") {
-		return "Bank Transfer"
+		return utils.CategoryTransfers, ConfidenceDescription
 	}
 
-	return "Uncategorized"
+	return utils.CategoryOther, ConfidenceFallback
 }
 
-// ApplyUserOverrides applies user-defined category overrides
+// ApplyUserOverrides applies the first of the user's category rules that
+// matches the description. User rules outrank the normalizer's own merchant
+// rules and keyword guesses; see utils.Categorizer.
 func (n *Normalizer) ApplyUserOverrides(transaction *NormalizedTransaction, overrides []CategoryOverride) {
-	for _, override := range overrides {
-		if n.matchesOverride(transaction.DescriptionRaw, override.Matcher) {
-			transaction.Category = override.Category
-			transaction.Subcategory = override.Subcategory
-			break
-		}
+	if rule, ok := (utils.Categorizer{Rules: overrides}).Rule(transaction.DescriptionRaw); ok {
+		transaction.Category = utils.NormalizeCategory(rule.Category)
+		transaction.Subcategory = rule.Subcategory
+		transaction.Confidence = ConfidenceRule
 	}
 }
 
 // CategoryOverride represents a user-defined category rule
-type CategoryOverride struct {
-	Matcher    string `json:"matcher"`
-	Category   string `json:"category"`
-	Subcategory string `json:"subcategory"`
-}
-
-// matchesOverride checks if transaction description matches the override pattern
-func (n *Normalizer) matchesOverride(description, matcher string) bool {
-	// Check if it's a regex pattern
-	if strings.HasPrefix(matcher, "/") && strings.HasSuffix(matcher, "/") {
-		pattern := matcher[1 : len(matcher)-1]
-		re, err := regexp.Compile(pattern)
-		if err != nil {
-			return false
-		}
-		return re.MatchString(description)
-	}
-
-	// Simple contains match
-	return strings.Contains(strings.ToLower(description), strings.ToLower(matcher))
-} 
\ No newline at end of file
+type CategoryOverride = utils.CategoryRule
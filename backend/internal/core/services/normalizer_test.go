@@ -0,0 +1,246 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/your-github/expense-tracker-backend/internal/core/ports"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+func normalizeDescription(n *Normalizer, description string) NormalizedTransaction {
+	return n.NormalizeTransaction(ports.FITransaction{
+		PostedAt:       "2025-03-01T10:00:00Z",
+		Amount:         500,
+		Currency:       "INR",
+		Type:           "DEBIT",
+		DescriptionRaw: description,
+	}, nil)
+}
+
+func TestNormalizerRulesTakeEffectWithoutRestart(t *testing.T) {
+	n := NewNormalizer()
+	const description = "POS 4021 CULTFIT HSR BANGALORE"
+
+	before := normalizeDescription(n, description)
+	if before.Confidence == ConfidenceRule {
+		t.Fatalf("matched a rule before any was configured: %+v", before)
+	}
+
+	if err := n.SetRules([]MerchantRule{{Matcher: "cultfit", Merchant: "Cult.fit", Category: utils.CategoryHealthcare, Subcategory: "Gym"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	after := normalizeDescription(n, description)
+	if after.MerchantName != "Cult.fit" || after.Category != utils.CategoryHealthcare || after.Subcategory != "Gym" || after.Confidence != ConfidenceRule {
+		t.Errorf("after adding a rule = %+v", after)
+	}
+}
+
+func TestNormalizerRegexAndSubstringRulesCoexist(t *testing.T) {
+	n := NewNormalizer()
+	err := n.SetRules([]MerchantRule{
+		{Matcher: `/^ACH\s+D-\s*\w+\s+HDFC\s*LIFE/`, Merchant: "HDFC Life", Category: utils.CategoryBills, Subcategory: "Insurance"},
+		{Matcher: "bigbasket", Merchant: "BigBasket", Category: utils.CategoryFood, Subcategory: "Groceries"},
+		// Shadowed by the substring rule above, which comes first
+		{Matcher: "/big.*basket/", Merchant: "Wrong", Category: utils.CategoryShopping},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		description string
+		merchant    string
+		subcategory string
+	}{
+		{"ACH D- 99812 HDFC LIFE PREMIUM", "HDFC Life", "Insurance"},
+		{"ach d-  77  hdfclife", "HDFC Life", "Insurance"}, // regex rules are case-insensitive
+		{"UPI/BIGBASKET/ORDER 1182", "BigBasket", "Groceries"},
+	}
+	for _, tt := range tests {
+		got := normalizeDescription(n, tt.description)
+		if got.MerchantName != tt.merchant || got.Subcategory != tt.subcategory {
+			t.Errorf("%q = %s/%s, want %s/%s", tt.description, got.MerchantName, got.Subcategory, tt.merchant, tt.subcategory)
+		}
+	}
+
+	// The regex must match from the start; elsewhere it doesn't apply
+	if got := normalizeDescription(n, "REFUND ACH D- 1 HDFC LIFE"); got.MerchantName == "HDFC Life" {
+		t.Errorf("anchored regex matched mid-description: %+v", got)
+	}
+}
+
+func TestNormalizerRulesOverrideBuiltInDefaults(t *testing.T) {
+	n := NewNormalizer()
+	builtIn := normalizeDescription(n, "NETFLIX.COM SUBSCRIPTION")
+
+	if err := n.SetRules([]MerchantRule{{Matcher: "netflix", Category: utils.CategoryBills, Subcategory: "Subscriptions"}}); err != nil {
+		t.Fatal(err)
+	}
+	got := normalizeDescription(n, "NETFLIX.COM SUBSCRIPTION")
+	if got.Category != utils.CategoryBills || got.Subcategory != "Subscriptions" {
+		t.Errorf("category = %s/%s, want the rule's", got.Category, got.Subcategory)
+	}
+	if got.MerchantName != builtIn.MerchantName {
+		t.Errorf("merchant = %q; a rule without a merchant should keep %q", got.MerchantName, builtIn.MerchantName)
+	}
+}
+
+func TestNormalizerSetRulesRejectsBadRules(t *testing.T) {
+	n := NewNormalizer()
+	if err := n.SetRules([]MerchantRule{{Matcher: "cultfit", Merchant: "Cult.fit"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rules := range [][]MerchantRule{
+		{{Matcher: "/([a-z/", Merchant: "Broken"}},
+		{{Matcher: "  ", Merchant: "Empty"}},
+	} {
+		if err := n.SetRules(rules); err == nil {
+			t.Errorf("SetRules(%+v) succeeded", rules)
+		}
+	}
+	if got := normalizeDescription(n, "CULTFIT"); got.MerchantName != "Cult.fit" {
+		t.Errorf("a rejected update replaced the existing rules: %+v", got)
+	}
+}
+
+func TestNormalizerReloadRulesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "merchant_rules.json")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`[{"matcher":"cultfit","merchant":"Cult.fit","category":"Healthcare"}]`)
+
+	n := NewNormalizer()
+	if err := n.Reload(); err == nil {
+		t.Error("Reload without a rules file succeeded")
+	}
+	if err := n.LoadRulesFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := normalizeDescription(n, "DUNZO DAILY"); got.MerchantName == "Dunzo" {
+		t.Fatalf("rule applied before it was added: %+v", got)
+	}
+
+	write(`[{"matcher":"cultfit","merchant":"Cult.fit","category":"Healthcare"},{"matcher":"/dunzo\\s+daily/","merchant":"Dunzo","category":"Food & Dining"}]`)
+	if err := n.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := normalizeDescription(n, "DUNZO DAILY"); got.MerchantName != "Dunzo" {
+		t.Errorf("reloaded rule not applied: %+v", got)
+	}
+
+	// A broken file keeps the rules that were loaded
+	write(`not json`)
+	if err := n.Reload(); err == nil {
+		t.Error("Reload of an invalid file succeeded")
+	}
+	if got := normalizeDescription(n, "CULTFIT"); got.MerchantName != "Cult.fit" {
+		t.Errorf("rules lost after a failed reload: %+v", got)
+	}
+}
+
+func TestNormalizerProducesCanonicalCategories(t *testing.T) {
+	n := NewNormalizer()
+	canonical := make(map[string]bool)
+	for _, category := range utils.Categories() {
+		canonical[category] = true
+	}
+
+	descriptions := []string{"POS RESTAURANT MG ROAD", "HP FUEL STATION", "APOLLO PHARMACY", "SCHOOL FEES", "ELECTRICITY BILL", "INTEREST CREDIT", "ATM WDL", "FUNDS TRANSFER", "misc"}
+	for pattern := range n.merchantPatterns {
+		descriptions = append(descriptions, "POS "+pattern+" BANGALORE")
+	}
+	for _, description := range descriptions {
+		if got := normalizeDescription(n, description); !canonical[got.Category] {
+			t.Errorf("%q categorized as %q, not a canonical category", description, got.Category)
+		}
+	}
+
+	// Rule categories go through the taxonomy too
+	if err := n.SetRules([]MerchantRule{{Matcher: "cultfit", Category: "health"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := normalizeDescription(n, "CULTFIT HSR"); got.Category != utils.CategoryHealthcare {
+		t.Errorf("rule category = %q, want %q", got.Category, utils.CategoryHealthcare)
+	}
+}
+
+func TestNormalizerFlagsUncertainTransactionsForReview(t *testing.T) {
+	n := NewNormalizer()
+	if err := n.SetRules([]MerchantRule{{Matcher: "cultfit", Merchant: "Cult.fit", Category: utils.CategoryHealthcare}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		description    string
+		wantConfidence float64
+		wantReview     bool
+	}{
+		{"UPI/SWIGGY/ORDER1", ConfidenceMerchant, false},
+		{"POS CULTFIT HSR", ConfidenceRule, false},
+		{"HP FUEL STATION", ConfidenceDescription, true}, // keyword, but no merchant
+		{"misc", ConfidenceFallback, true},
+	}
+	for _, tt := range tests {
+		got := normalizeDescription(n, tt.description)
+		if got.Confidence != tt.wantConfidence || n.NeedsReview(got) != tt.wantReview {
+			t.Errorf("%q: confidence %v, review %v (merchant %q); want %v, %v",
+				tt.description, got.Confidence, n.NeedsReview(got), got.MerchantName, tt.wantConfidence, tt.wantReview)
+		}
+	}
+
+	// A confident category with no identifiable merchant still needs review
+	if !n.NeedsReview(NormalizedTransaction{MerchantName: "Unknown", Confidence: ConfidenceRule}) {
+		t.Error("unknown merchant not flagged")
+	}
+
+	// The threshold is configurable: at 0.7 keyword matches need review
+	// even with a merchant
+	keyword := NormalizedTransaction{MerchantName: "HP Petrol", Confidence: ConfidenceDescription}
+	if n.NeedsReview(keyword) {
+		t.Error("keyword match flagged at the default threshold")
+	}
+	n.ReviewThreshold = 0.7
+	if !n.NeedsReview(keyword) {
+		t.Error("keyword match passed a 0.7 threshold")
+	}
+	if got := normalizeDescription(n, "UPI/SWIGGY/ORDER1"); n.NeedsReview(got) {
+		t.Errorf("merchant match at confidence %v flagged at a 0.7 threshold", got.Confidence)
+	}
+}
+
+func TestSameMerchant(t *testing.T) {
+	n := NewNormalizer()
+	if err := n.SetRules([]MerchantRule{{Matcher: "cultfit", Merchant: "Cult.fit"}, {Matcher: "/^cult gym/", Merchant: "Cult.fit"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Swiggy dinner", "UPI/SWIGGY/ORDER 99812", true},
+		{"zomato", "ZOMATO ORDER", true},
+		{"Cult gym membership", "POS 4021 CULTFIT HSR", true},
+		{"Blue Tokai", "POS BLUE TOKAI COFFEE", true},
+		// Both are food delivery, but not the same merchant
+		{"Swiggy dinner", "UPI/ZOMATO/ORDER 1", false},
+		// A shared payment channel doesn't make the payee the same
+		{"UPI/rahul@okhdfc", "UPI/SWIGGY/ORDER 1", false},
+		{"Bookstore", "AMAZON PAY", false},
+		{"Ta", "TATA SKY", false}, // too short to match as part of a name
+		{"", "SWIGGY", false},
+	}
+	for _, tt := range tests {
+		if got := n.SameMerchant(tt.a, tt.b); got != tt.want {
+			t.Errorf("SameMerchant(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
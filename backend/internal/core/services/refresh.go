@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// On-demand refresh defaults
+const (
+	// DefaultRefreshWindowDays is how many days back a refresh fetches
+	DefaultRefreshWindowDays = 7
+	// DefaultRefreshCooldown is how long a bank link must wait between refreshes
+	DefaultRefreshCooldown = 15 * time.Minute
+)
+
+// ErrRefreshCooldown matches a RefreshCooldownError
+var ErrRefreshCooldown = errors.New("bank link was refreshed recently")
+
+// RefreshCooldownError is returned when a bank link is refreshed again
+// before its cooldown has passed
+type RefreshCooldownError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RefreshCooldownError) Error() string {
+	return fmt.Sprintf("%v, retry in %s", ErrRefreshCooldown, e.RetryAfter.Round(time.Second))
+}
+
+func (e *RefreshCooldownError) Is(target error) bool {
+	return target == ErrRefreshCooldown
+}
+
+// RefreshResult is the outcome of an on-demand refresh of one bank link.
+// When the AA has not prepared the data yet Processed is false and the
+// transactions are stored once its DATA_READY webhook arrives.
+type RefreshResult struct {
+	FetchWindow
+	SessionID    string                `json:"session_id"`
+	Status       string                `json:"status"`
+	Processed    bool                  `json:"processed"`
+	Created      int                   `json:"created"`
+	Transactions []*domain.Transaction `json:"transactions,omitempty"`
+}
+
+func (s *AAService) refreshWindowDays() int {
+	if s.RefreshWindowDays > 0 {
+		return s.RefreshWindowDays
+	}
+	return DefaultRefreshWindowDays
+}
+
+func (s *AAService) refreshCooldown() time.Duration {
+	if s.RefreshCooldown > 0 {
+		return s.RefreshCooldown
+	}
+	return DefaultRefreshCooldown
+}
+
+// RefreshBankLink fetches the last refreshWindowDays() days of one of the
+// user's bank links through the regular fetch pipeline, so transactions
+// already stored are skipped. A link can be refreshed once per
+// refreshCooldown(); an earlier attempt fails with a RefreshCooldownError.
+// A failed fetch doesn't count towards the cooldown.
+func (s *AAService) RefreshBankLink(ctx context.Context, userID, bankLinkID uuid.UUID) (*RefreshResult, error) {
+	bankLink, err := s.repositories.BankLink.GetByID(ctx, bankLinkID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBankLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bank link: %w", err)
+	}
+	if bankLink.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	if bankLink.Status != "ACTIVE" {
+		return nil, fmt.Errorf("%w: %s", ErrConsentNotActive, bankLink.Status)
+	}
+
+	now := time.Now()
+	cooldown := s.refreshCooldown()
+	claimed, err := s.repositories.BankLink.ClaimRefresh(ctx, bankLinkID, now, now.Add(-cooldown))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim bank link refresh: %w", err)
+	}
+	if !claimed {
+		return nil, &RefreshCooldownError{RetryAfter: refreshRetryAfter(bankLink, cooldown, now)}
+	}
+
+	window := FetchWindow{
+		From: now.UTC().AddDate(0, 0, -s.refreshWindowDays()).Format("2006-01-02"),
+		To:   now.UTC().Format("2006-01-02"),
+	}
+	result, err := s.FetchTransactions(ctx, userID, bankLinkID, window.From, window.To, false)
+	if err != nil {
+		if releaseErr := s.repositories.BankLink.SetLastRefreshedAt(ctx, bankLinkID, bankLink.LastRefreshedAt); releaseErr != nil {
+			s.log(ctx).Warn("Failed to release bank link refresh", zap.Error(releaseErr), zap.String("bank_link_id", bankLinkID.String()))
+		}
+		return nil, err
+	}
+
+	s.log(ctx).Info("Refreshed bank link",
+		zap.String("bank_link_id", bankLinkID.String()),
+		zap.String("from", window.From),
+		zap.Int("created", len(result.Transactions)))
+	return &RefreshResult{
+		FetchWindow:  window,
+		SessionID:    result.SessionID,
+		Status:       result.Status,
+		Processed:    result.Processed,
+		Created:      len(result.Transactions),
+		Transactions: result.Transactions,
+	}, nil
+}
+
+// refreshRetryAfter returns how long until bankLink may be refreshed again.
+// The stored time may be older than the refresh that won the claim, so at
+// least a second is always reported.
+func refreshRetryAfter(bankLink *domain.BankLink, cooldown time.Duration, now time.Time) time.Duration {
+	retryAfter := cooldown
+	if bankLink.LastRefreshedAt != nil {
+		retryAfter = bankLink.LastRefreshedAt.Add(cooldown).Sub(now)
+	}
+	return max(retryAfter, time.Second)
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/your-github/expense-tracker-backend/internal/core/ports"
+	"go.uber.org/zap"
+)
+
+// flakyWindowedAAClient fails to create sessions while err is set
+type flakyWindowedAAClient struct {
+	windowedAAClient
+	err error
+}
+
+func (c *flakyWindowedAAClient) CreateDataSession(consentID string, fromISO, toISO string) (ports.DataSession, error) {
+	if c.err != nil {
+		return ports.DataSession{}, c.err
+	}
+	return c.windowedAAClient.CreateDataSession(consentID, fromISO, toISO)
+}
+
+func TestRefreshBankLinkFetchesOnlyNewTransactions(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	client := &flakyWindowedAAClient{}
+	service := NewAAService(client, store.repositories(), NewNormalizer(), NewDeduplicator(), zap.NewNop())
+	service.RefreshWindowDays = 3
+	user := store.addUser("INR")
+	link := store.addBankLink(user.ID, "consent-1", "ACTIVE")
+
+	first, err := service.RefreshBankLink(ctx, user.ID, link.ID)
+	if err != nil {
+		t.Fatalf("RefreshBankLink: %v", err)
+	}
+	today := time.Now().UTC().Format("2006-01-02")
+	if first.To != today || first.From != time.Now().UTC().AddDate(0, 0, -3).Format("2006-01-02") {
+		t.Errorf("window = %s..%s, want the last 3 days up to %s", first.From, first.To, today)
+	}
+	// The three days, today and the provider's extra day before the window
+	if !first.Processed || first.Created != 5 || len(store.linkTransactions(link.ID)) != 5 {
+		t.Fatalf("first refresh = %+v, want 5 transactions stored", first)
+	}
+	if link.LastRefreshedAt == nil {
+		t.Fatal("refresh not recorded for the cooldown")
+	}
+
+	// Once the cooldown has passed the same window stores nothing new
+	past := time.Now().Add(-DefaultRefreshCooldown - time.Minute)
+	link.LastRefreshedAt = &past
+	second, err := service.RefreshBankLink(ctx, user.ID, link.ID)
+	if err != nil {
+		t.Fatalf("second refresh: %v", err)
+	}
+	if second.Created != 0 || len(store.linkTransactions(link.ID)) != 5 {
+		t.Errorf("second refresh created %d, want 0", second.Created)
+	}
+}
+
+func TestRefreshBankLinkCooldown(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	client := &flakyWindowedAAClient{}
+	service := NewAAService(client, store.repositories(), NewNormalizer(), NewDeduplicator(), zap.NewNop())
+	service.RefreshCooldown = 10 * time.Minute
+	user := store.addUser("INR")
+	link := store.addBankLink(user.ID, "consent-1", "ACTIVE")
+
+	if _, err := service.RefreshBankLink(ctx, user.ID, link.ID); err != nil {
+		t.Fatalf("RefreshBankLink: %v", err)
+	}
+	_, err := service.RefreshBankLink(ctx, user.ID, link.ID)
+	var cooldown *RefreshCooldownError
+	if !errors.Is(err, ErrRefreshCooldown) || !errors.As(err, &cooldown) {
+		t.Fatalf("immediate refresh: %v, want a RefreshCooldownError", err)
+	}
+	if cooldown.RetryAfter <= 9*time.Minute || cooldown.RetryAfter > 10*time.Minute {
+		t.Errorf("retry after %s, want about 10m", cooldown.RetryAfter)
+	}
+	if len(client.windows) != 1 {
+		t.Errorf("requested %d sessions, want the refused refresh to reach no AA", len(client.windows))
+	}
+
+	// Another link of the same user has its own cooldown
+	other := store.addBankLink(user.ID, "consent-2", "ACTIVE")
+	if _, err := service.RefreshBankLink(ctx, user.ID, other.ID); err != nil {
+		t.Errorf("refreshing another link: %v", err)
+	}
+}
+
+func TestFailedRefreshDoesNotStartTheCooldown(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	client := &flakyWindowedAAClient{err: errors.New("AA unavailable")}
+	service := NewAAService(client, store.repositories(), NewNormalizer(), NewDeduplicator(), zap.NewNop())
+	user := store.addUser("INR")
+	link := store.addBankLink(user.ID, "consent-1", "ACTIVE")
+
+	if _, err := service.RefreshBankLink(ctx, user.ID, link.ID); err == nil || errors.Is(err, ErrRefreshCooldown) {
+		t.Fatalf("failing refresh: %v, want the AA error", err)
+	}
+	if link.LastRefreshedAt != nil {
+		t.Errorf("failed refresh left the cooldown running from %v", link.LastRefreshedAt)
+	}
+
+	client.err = nil
+	if _, err := service.RefreshBankLink(ctx, user.ID, link.ID); err != nil {
+		t.Errorf("retry after the failure: %v", err)
+	}
+}
+
+func TestRefreshBankLinkRejections(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	service := NewAAService(&flakyWindowedAAClient{}, store.repositories(), NewNormalizer(), NewDeduplicator(), zap.NewNop())
+	owner := store.addUser("INR")
+	other := store.addUser("INR")
+	active := store.addBankLink(owner.ID, "consent-1", "ACTIVE")
+	revoked := store.addBankLink(owner.ID, "consent-2", "REVOKED")
+
+	if _, err := service.RefreshBankLink(ctx, other.ID, active.ID); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("another user's link: %v, want ErrUnauthorized", err)
+	}
+	if _, err := service.RefreshBankLink(ctx, owner.ID, revoked.ID); !errors.Is(err, ErrConsentNotActive) {
+		t.Errorf("revoked link: %v, want ErrConsentNotActive", err)
+	}
+	if _, err := service.RefreshBankLink(ctx, owner.ID, store.addUser("INR").ID); !errors.Is(err, ErrBankLinkNotFound) {
+		t.Errorf("unknown link: %v, want ErrBankLinkNotFound", err)
+	}
+	if active.LastRefreshedAt != nil || revoked.LastRefreshedAt != nil {
+		t.Error("a rejected refresh started the cooldown")
+	}
+}
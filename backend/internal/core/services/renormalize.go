@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/internal/core/ports"
+	"github.com/your-github/expense-tracker-backend/internal/repo"
+	"go.uber.org/zap"
+)
+
+// renormalizeBatchSize is how many transactions are read and updated at a time
+const renormalizeBatchSize = 200
+
+// RenormalizeResult reports the outcome of a renormalization run
+type RenormalizeResult struct {
+	Checked int `json:"checked"`
+	Updated int `json:"updated"`
+}
+
+// RenormalizeTransactions re-runs the normalizer and the user's category
+// overrides over every stored transaction and saves the rows whose merchant,
+// account reference or category changed. The stored description and dedupe
+// hash are kept as they are so later imports still match existing rows.
+func (s *AAService) RenormalizeTransactions(ctx context.Context, userID uuid.UUID) (*RenormalizeResult, error) {
+	settings, err := s.loadIngestSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RenormalizeResult{}
+	var cursor *repo.TransactionCursor
+	for {
+		transactions, next, err := s.repositories.Transaction.GetPageByUserID(ctx, userID, nil, nil, cursor, renormalizeBatchSize)
+		if err != nil {
+			return result, fmt.Errorf("failed to get transactions: %w", err)
+		}
+
+		var changed []*domain.Transaction
+		for _, transaction := range transactions {
+			if s.renormalize(transaction, settings) {
+				changed = append(changed, transaction)
+			}
+		}
+		result.Checked += len(transactions)
+
+		if len(changed) > 0 {
+			if err := s.repositories.Transaction.UpdateNormalizedFields(ctx, changed); err != nil {
+				return result, fmt.Errorf("failed to update transactions: %w", err)
+			}
+			result.Updated += len(changed)
+		}
+
+		if next == nil {
+			break
+		}
+		cursor = next
+	}
+
+	s.log(ctx).Info("Renormalized transactions",
+		zap.String("user_id", userID.String()),
+		zap.Int("checked", result.Checked),
+		zap.Int("updated", result.Updated))
+
+	return result, nil
+}
+
+// renormalize recomputes the normalized fields of transaction in place and
+// reports whether any of them changed
+func (s *AAService) renormalize(transaction *domain.Transaction, settings ingestSettings) bool {
+	normalized := s.normalizer.NormalizeTransaction(ports.FITransaction{
+		Amount:         transaction.Amount,
+		Currency:       transaction.Currency,
+		Type:           transaction.TxnType,
+		DescriptionRaw: transaction.DescriptionRaw,
+		AccountRef:     transaction.AccountRef,
+		SourceMeta:     transaction.SourceMeta,
+	}, settings.aliases)
+	s.normalizer.ApplyUserOverrides(&normalized, settings.overrides)
+
+	// A category set by the user has already been reviewed
+	needsReview := transaction.UserCategory == "" && s.normalizer.NeedsReview(normalized)
+
+	if normalized.MerchantName == transaction.MerchantName &&
+		normalized.AccountRef == transaction.AccountRef &&
+		normalized.Category == transaction.Category &&
+		normalized.Subcategory == transaction.Subcategory &&
+		normalized.Confidence == transaction.CategoryConfidence &&
+		needsReview == transaction.NeedsReview {
+		return false
+	}
+
+	transaction.MerchantName = normalized.MerchantName
+	transaction.AccountRef = normalized.AccountRef
+	transaction.Category = normalized.Category
+	transaction.Subcategory = normalized.Subcategory
+	transaction.CategoryConfidence = normalized.Confidence
+	transaction.NeedsReview = needsReview
+	return true
+}
+
+// loadCategoryOverrides returns the user's category rules in the form the
+// normalizer applies them
+func (s *AAService) loadCategoryOverrides(ctx context.Context, userID uuid.UUID) ([]CategoryOverride, error) {
+	stored, err := s.repositories.CategoryOverride.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category overrides: %w", err)
+	}
+	overrides := make([]CategoryOverride, 0, len(stored))
+	for _, override := range stored {
+		overrides = append(overrides, CategoryOverride{
+			Matcher:     override.Matcher,
+			Category:    override.Category,
+			Subcategory: override.Subcategory,
+		})
+	}
+	return overrides, nil
+}
+
+// loadMerchantAliases returns the user's merchant aliases followed by the
+// global ones, so a user's alias wins over a global alias for the same
+// description
+func (s *AAService) loadMerchantAliases(ctx context.Context, userID uuid.UUID) ([]MerchantAlias, error) {
+	stored, err := s.repositories.MerchantAlias.GetForUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merchant aliases: %w", err)
+	}
+	aliases := make([]MerchantAlias, 0, len(stored))
+	for _, alias := range stored {
+		aliases = append(aliases, MerchantAlias{Pattern: alias.Pattern, Merchant: alias.Merchant})
+	}
+	return aliases, nil
+}
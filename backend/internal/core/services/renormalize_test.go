@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+// addStaleTransactions stores n of the user's transactions whose normalized
+// fields predate the current normalizer
+func addStaleTransactions(store *memStore, userID uuid.UUID, n int) {
+	posted := time.Date(2025, time.March, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		txn := &domain.Transaction{
+			ID:             uuid.New(),
+			UserID:         userID,
+			PostedAt:       posted.Add(time.Duration(i) * time.Hour),
+			Amount:         -250,
+			Currency:       "INR",
+			TxnType:        "DEBIT",
+			DescriptionRaw: fmt.Sprintf("UPI/SWIGGY/ORDER%d", i),
+			MerchantName:   "UNKNOWN",
+			Category:       "Other",
+			HashDedupe:     fmt.Sprintf("hash-%d", i),
+		}
+		store.transactions[txn.ID] = txn
+	}
+}
+
+func TestRenormalizeIsIdempotent(t *testing.T) {
+	store := newMemStore()
+	service, _ := newTestAAService(t, store)
+	user := store.addUser("INR")
+	// More than one page, so the cursor is followed
+	addStaleTransactions(store, user.ID, renormalizeBatchSize+5)
+
+	first, err := service.RenormalizeTransactions(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	if first.Checked != renormalizeBatchSize+5 || first.Updated != first.Checked {
+		t.Errorf("first run = %+v, want every transaction checked and updated", first)
+	}
+
+	second, err := service.RenormalizeTransactions(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if second.Checked != first.Checked || second.Updated != 0 {
+		t.Errorf("second run = %+v, want nothing updated", second)
+	}
+
+	for _, txn := range store.transactions {
+		if txn.MerchantName != "Food Delivery" || txn.Category != utils.CategoryFood {
+			t.Fatalf("%s = %s in %q, want the Swiggy rule", txn.DescriptionRaw, txn.MerchantName, txn.Category)
+		}
+		if txn.HashDedupe != fmt.Sprintf("hash-%s", txn.DescriptionRaw[len("UPI/SWIGGY/ORDER"):]) {
+			t.Fatalf("%s: dedupe hash rewritten to %q", txn.DescriptionRaw, txn.HashDedupe)
+		}
+	}
+}
+
+func TestRenormalizeReappliesOverrides(t *testing.T) {
+	store := newMemStore()
+	service, _ := newTestAAService(t, store)
+	user := store.addUser("INR")
+	addStaleTransactions(store, user.ID, 3)
+	if _, err := service.RenormalizeTransactions(context.Background(), user.ID); err != nil {
+		t.Fatalf("RenormalizeTransactions: %v", err)
+	}
+
+	store.overrides = append(store.overrides, &domain.CategoryOverride{
+		ID: uuid.New(), UserID: user.ID, Matcher: "swiggy", Category: "entertainment", Subcategory: "Takeaway",
+	})
+	result, err := service.RenormalizeTransactions(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("RenormalizeTransactions: %v", err)
+	}
+	if result.Updated != 3 {
+		t.Errorf("updated %d after adding an override, want 3", result.Updated)
+	}
+	for _, txn := range store.transactions {
+		if txn.Category != utils.CategoryEntertainment || txn.Subcategory != "Takeaway" || txn.CategoryConfidence != ConfidenceRule {
+			t.Errorf("%s = %s/%s at %v, want the override", txn.DescriptionRaw, txn.Category, txn.Subcategory, txn.CategoryConfidence)
+		}
+	}
+
+	if again, _ := service.RenormalizeTransactions(context.Background(), user.ID); again.Updated != 0 {
+		t.Errorf("rerun with the override updated %d, want 0", again.Updated)
+	}
+}
+
+func TestRenormalizeKeepsUserOverride(t *testing.T) {
+	store := newMemStore()
+	service, _ := newTestAAService(t, store)
+	user := store.addUser("INR")
+	addStaleTransactions(store, user.ID, 1)
+	var txn *domain.Transaction
+	for _, stored := range store.transactions {
+		txn = stored
+	}
+	txn.UserNote = "team lunch"
+	txn.UserCategory = "Work"
+
+	if _, err := service.RenormalizeTransactions(context.Background(), user.ID); err != nil {
+		t.Fatalf("RenormalizeTransactions: %v", err)
+	}
+	if txn.UserCategory != "Work" || txn.UserNote != "team lunch" || txn.EffectiveCategory() != "Work" {
+		t.Errorf("after renormalizing: category %q, note %q, effective %q; want the user's fields kept",
+			txn.UserCategory, txn.UserNote, txn.EffectiveCategory())
+	}
+	if txn.NeedsReview {
+		t.Error("a transaction the user categorized was flagged for review")
+	}
+}
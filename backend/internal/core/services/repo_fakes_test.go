@@ -0,0 +1,604 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/internal/core/ports"
+	"github.com/your-github/expense-tracker-backend/internal/repo"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// memStore backs in-memory fakes of the repositories the AA service uses.
+// Methods the fakes don't implement panic through the embedded interfaces,
+// so a test touching an unexpected path fails loudly.
+type memStore struct {
+	mu sync.Mutex
+
+	users         map[uuid.UUID]*domain.User
+	bankLinks     map[uuid.UUID]*domain.BankLink
+	transactions  map[uuid.UUID]*domain.Transaction
+	overrides     []*domain.CategoryOverride
+	aliases       []*domain.MerchantAlias
+	processed     map[string]*domain.ProcessedSession
+	dataSessions  map[string]*domain.DataSession
+	webhookEvents map[uuid.UUID]*domain.WebhookEvent
+
+	// createErr, when set, decides whether a transaction insert fails
+	createErr func(*domain.Transaction) error
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		users:         make(map[uuid.UUID]*domain.User),
+		bankLinks:     make(map[uuid.UUID]*domain.BankLink),
+		transactions:  make(map[uuid.UUID]*domain.Transaction),
+		processed:     make(map[string]*domain.ProcessedSession),
+		dataSessions:  make(map[string]*domain.DataSession),
+		webhookEvents: make(map[uuid.UUID]*domain.WebhookEvent),
+	}
+}
+
+// repositories returns the store wrapped in repo.Repositories
+func (m *memStore) repositories() *repo.Repositories {
+	return &repo.Repositories{
+		User:             memUsers{m},
+		BankLink:         memBankLinks{store: m},
+		Transaction:      memTransactions{store: m},
+		CategoryOverride: memOverrides{store: m},
+		MerchantAlias:    memAliases{store: m},
+		ProcessedSession: memProcessed{m},
+		DataSession:      memDataSessions{m},
+		WebhookEvent:     memWebhookEvents{store: m},
+	}
+}
+
+// addUser stores a user with the given base currency
+func (m *memStore) addUser(currency string) *domain.User {
+	user := &domain.User{ID: uuid.New(), Email: uuid.NewString() + "@example.com", Currency: currency}
+	m.users[user.ID] = user
+	return user
+}
+
+// addBankLink stores a bank link for the user's consent
+func (m *memStore) addBankLink(userID uuid.UUID, consentID, status string) *domain.BankLink {
+	link := &domain.BankLink{ID: uuid.New(), UserID: userID, AAConsentID: consentID, FIType: "SAVINGS", Status: status}
+	m.bankLinks[link.ID] = link
+	return link
+}
+
+// linkTransactions returns the bank link's stored transactions in posted order
+func (m *memStore) linkTransactions(bankLinkID uuid.UUID) []*domain.Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []*domain.Transaction
+	for _, txn := range m.transactions {
+		if txn.BankLinkID != nil && *txn.BankLinkID == bankLinkID {
+			out = append(out, txn)
+		}
+	}
+	sortByPosted(out)
+	return out
+}
+
+func sortByPosted(transactions []*domain.Transaction) {
+	sort.Slice(transactions, func(i, j int) bool {
+		if !transactions[i].PostedAt.Equal(transactions[j].PostedAt) {
+			return transactions[i].PostedAt.Before(transactions[j].PostedAt)
+		}
+		return transactions[i].ID.String() < transactions[j].ID.String()
+	})
+}
+
+type memUsers struct{ store *memStore }
+
+func (r memUsers) Create(ctx context.Context, user *domain.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.users[user.ID] = user
+	return nil
+}
+
+func (r memUsers) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	user, ok := r.store.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r memUsers) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, user := range r.store.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r memUsers) Update(ctx context.Context, user *domain.User) error { return r.Create(ctx, user) }
+
+func (r memUsers) Delete(ctx context.Context, id uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	delete(r.store.users, id)
+	return nil
+}
+
+type memBankLinks struct {
+	repo.BankLinkRepository
+	store *memStore
+}
+
+func (r memBankLinks) GetByID(ctx context.Context, id uuid.UUID) (*domain.BankLink, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	link, ok := r.store.bankLinks[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *link
+	return &copied, nil
+}
+
+func (r memBankLinks) GetByConsentID(ctx context.Context, consentID string) (*domain.BankLink, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, link := range r.store.bankLinks {
+		if link.AAConsentID == consentID {
+			copied := *link
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r memBankLinks) Update(ctx context.Context, bankLink *domain.BankLink) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	copied := *bankLink
+	r.store.bankLinks[bankLink.ID] = &copied
+	return nil
+}
+
+func (r memBankLinks) UpdateStatus(ctx context.Context, id uuid.UUID, status string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if link, ok := r.store.bankLinks[id]; ok {
+		link.Status = status
+	}
+	return nil
+}
+
+func (r memBankLinks) GetNeedingStatusRefresh(ctx context.Context) ([]*domain.BankLink, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	var out []*domain.BankLink
+	for _, link := range r.store.bankLinks {
+		if link.Status == "PENDING" || link.Status == "ACTIVE" {
+			copied := *link
+			out = append(out, &copied)
+		}
+	}
+	return out, nil
+}
+
+func (r memBankLinks) GetExpiringWithoutReminder(ctx context.Context, before time.Time) ([]*domain.BankLink, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	now := time.Now()
+	var out []*domain.BankLink
+	for _, link := range r.store.bankLinks {
+		if link.Status != "ACTIVE" || link.ExpiryReminderSentAt != nil || link.ValidTill == nil ||
+			!link.ValidTill.After(now) || link.ValidTill.After(before) {
+			continue
+		}
+		copied := *link
+		if user, ok := r.store.users[link.UserID]; ok {
+			copied.User = *user
+		}
+		out = append(out, &copied)
+	}
+	return out, nil
+}
+
+func (r memBankLinks) MarkExpiryReminderSent(ctx context.Context, id uuid.UUID, sentAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if link, ok := r.store.bankLinks[id]; ok {
+		link.ExpiryReminderSentAt = &sentAt
+	}
+	return nil
+}
+
+func (r memBankLinks) ClaimRefresh(ctx context.Context, id uuid.UUID, now, notAfter time.Time) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	link, ok := r.store.bankLinks[id]
+	if !ok || (link.LastRefreshedAt != nil && link.LastRefreshedAt.After(notAfter)) {
+		return false, nil
+	}
+	link.LastRefreshedAt = &now
+	return true, nil
+}
+
+func (r memBankLinks) SetLastRefreshedAt(ctx context.Context, id uuid.UUID, refreshedAt *time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if link, ok := r.store.bankLinks[id]; ok {
+		link.LastRefreshedAt = refreshedAt
+	}
+	return nil
+}
+
+type memTransactions struct {
+	repo.TransactionRepository
+	store *memStore
+}
+
+func (r memTransactions) Create(ctx context.Context, transaction *domain.Transaction) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if r.store.createErr != nil {
+		if err := r.store.createErr(transaction); err != nil {
+			return err
+		}
+	}
+	for _, existing := range r.store.transactions {
+		if existing.HashDedupe == transaction.HashDedupe {
+			return repo.ErrDuplicate
+		}
+	}
+	copied := *transaction
+	r.store.transactions[transaction.ID] = &copied
+	return nil
+}
+
+func (r memTransactions) Update(ctx context.Context, transaction *domain.Transaction) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	copied := *transaction
+	r.store.transactions[transaction.ID] = &copied
+	return nil
+}
+
+func (r memTransactions) ExistingHashes(ctx context.Context, hashes []string) (map[string]bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	wanted := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		wanted[hash] = true
+	}
+	existing := make(map[string]bool)
+	for _, txn := range r.store.transactions {
+		if wanted[txn.HashDedupe] {
+			existing[txn.HashDedupe] = true
+		}
+	}
+	return existing, nil
+}
+
+func (r memTransactions) GetBySourceMeta(ctx context.Context, userID uuid.UUID, path []string, value string, limit, offset int) ([]*domain.Transaction, int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	var matches []*domain.Transaction
+	for _, txn := range r.store.transactions {
+		if txn.UserID == userID && len(path) == 1 && fmt.Sprint(txn.SourceMeta[path[0]]) == value {
+			copied := *txn
+			matches = append(matches, &copied)
+		}
+	}
+	return matches, int64(len(matches)), nil
+}
+
+func (r memTransactions) GetPageByUserID(ctx context.Context, userID uuid.UUID, from, to *time.Time, after *repo.TransactionCursor, limit int) ([]*domain.Transaction, *repo.TransactionCursor, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	var matches []*domain.Transaction
+	for _, txn := range r.store.transactions {
+		if txn.UserID != userID {
+			continue
+		}
+		if after != nil && !txn.PostedAt.Before(after.PostedAt) &&
+			(!txn.PostedAt.Equal(after.PostedAt) || txn.ID.String() >= after.ID.String()) {
+			continue
+		}
+		copied := *txn
+		matches = append(matches, &copied)
+	}
+	// Newest first, as the repository pages
+	sortByPosted(matches)
+	for i, j := 0, len(matches)-1; i < j; i, j = i+1, j-1 {
+		matches[i], matches[j] = matches[j], matches[i]
+	}
+	if len(matches) <= limit {
+		return matches, nil, nil
+	}
+	last := matches[limit-1]
+	return matches[:limit], &repo.TransactionCursor{PostedAt: last.PostedAt, ID: last.ID}, nil
+}
+
+func (r memTransactions) UpdateNormalizedFields(ctx context.Context, transactions []*domain.Transaction) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, transaction := range transactions {
+		stored := r.store.transactions[transaction.ID]
+		stored.MerchantName = transaction.MerchantName
+		stored.AccountRef = transaction.AccountRef
+		stored.Category = transaction.Category
+		stored.Subcategory = transaction.Subcategory
+		stored.CategoryConfidence = transaction.CategoryConfidence
+		stored.NeedsReview = transaction.NeedsReview
+	}
+	return nil
+}
+
+func (r memTransactions) FindReversalCandidate(ctx context.Context, reversal *domain.Transaction, txnType string, since time.Time) (*domain.Transaction, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	var best *domain.Transaction
+	for _, txn := range r.store.transactions {
+		if txn.UserID != reversal.UserID || txn.ID == reversal.ID || txn.ReversalOf != nil ||
+			!strings.EqualFold(txn.TxnType, txnType) || txn.Amount != reversal.Amount ||
+			txn.PostedAt.Before(since) || txn.PostedAt.After(reversal.PostedAt) {
+			continue
+		}
+		if (txn.BankLinkID == nil) != (reversal.BankLinkID == nil) ||
+			(txn.BankLinkID != nil && *txn.BankLinkID != *reversal.BankLinkID) {
+			continue
+		}
+		if best == nil || txn.PostedAt.After(best.PostedAt) {
+			best = txn
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	copied := *best
+	return &copied, nil
+}
+
+func (r memTransactions) LinkReversal(ctx context.Context, originalID, reversalID uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.transactions[originalID].ReversalOf = &reversalID
+	r.store.transactions[reversalID].ReversalOf = &originalID
+	return nil
+}
+
+func (r memTransactions) GetAmountStats(ctx context.Context, userID uuid.UUID, since time.Time) (map[repo.AmountStatsKey]repo.AmountStats, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	stats := make(map[repo.AmountStatsKey]repo.AmountStats)
+	for _, txn := range r.store.transactions {
+		if txn.UserID != userID || txn.PostedAt.Before(since) || txn.ReversalOf != nil {
+			continue
+		}
+		key := repo.AmountStatsKey{Category: effectiveCategory(txn), TxnType: strings.ToUpper(txn.TxnType)}
+		amount := math.Abs(txn.BaseAmount)
+		s := stats[key]
+		s.Count++
+		s.Sum += amount
+		s.SumSquares += amount * amount
+		stats[key] = s
+	}
+	return stats, nil
+}
+
+func (r memTransactions) SetFlagReason(ctx context.Context, id uuid.UUID, reason string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.transactions[id].FlagReason = reason
+	return nil
+}
+
+func (r memTransactions) GetLastBalanceBefore(ctx context.Context, bankLinkID uuid.UUID, before time.Time) (*domain.Transaction, error) {
+	var last *domain.Transaction
+	for _, txn := range r.store.linkTransactions(bankLinkID) {
+		if txn.PostedAt.Before(before) && txn.BalanceAfter != nil {
+			last = txn
+		}
+	}
+	return last, nil
+}
+
+func (r memTransactions) GetByBankLinkSince(ctx context.Context, bankLinkID uuid.UUID, since time.Time) ([]*domain.Transaction, error) {
+	var out []*domain.Transaction
+	for _, txn := range r.store.linkTransactions(bankLinkID) {
+		if !txn.PostedAt.Before(since) {
+			copied := *txn
+			out = append(out, &copied)
+		}
+	}
+	return out, nil
+}
+
+type memOverrides struct {
+	repo.CategoryOverrideRepository
+	store *memStore
+}
+
+func (r memOverrides) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.CategoryOverride, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	var out []*domain.CategoryOverride
+	for _, override := range r.store.overrides {
+		if override.UserID == userID {
+			out = append(out, override)
+		}
+	}
+	return out, nil
+}
+
+type memAliases struct {
+	repo.MerchantAliasRepository
+	store *memStore
+}
+
+func (r memAliases) GetForUser(ctx context.Context, userID uuid.UUID) ([]*domain.MerchantAlias, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	var own, global []*domain.MerchantAlias
+	for _, alias := range r.store.aliases {
+		switch {
+		case alias.UserID == nil:
+			global = append(global, alias)
+		case *alias.UserID == userID:
+			own = append(own, alias)
+		}
+	}
+	return append(own, global...), nil
+}
+
+func (r memAliases) Create(ctx context.Context, alias *domain.MerchantAlias) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	alias.ID = uuid.New()
+	r.store.aliases = append(r.store.aliases, alias)
+	return nil
+}
+
+func (r memAliases) GetByID(ctx context.Context, id uuid.UUID) (*domain.MerchantAlias, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, alias := range r.store.aliases {
+		if alias.ID == id {
+			return alias, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r memAliases) Delete(ctx context.Context, id uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for i, alias := range r.store.aliases {
+		if alias.ID == id {
+			r.store.aliases = append(r.store.aliases[:i], r.store.aliases[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+type memProcessed struct{ store *memStore }
+
+func (r memProcessed) Create(ctx context.Context, session *domain.ProcessedSession) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if _, ok := r.store.processed[session.SessionID]; ok {
+		return repo.ErrDuplicate
+	}
+	r.store.processed[session.SessionID] = session
+	return nil
+}
+
+func (r memProcessed) Exists(ctx context.Context, sessionID string) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	_, ok := r.store.processed[sessionID]
+	return ok, nil
+}
+
+type memDataSessions struct{ store *memStore }
+
+func (r memDataSessions) Create(ctx context.Context, session *domain.DataSession) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.dataSessions[session.SessionID] = session
+	return nil
+}
+
+func (r memDataSessions) GetBySessionID(ctx context.Context, sessionID string) (*domain.DataSession, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	session, ok := r.store.dataSessions[sessionID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return session, nil
+}
+
+type memWebhookEvents struct {
+	repo.WebhookEventRepository
+	store *memStore
+}
+
+func (r memWebhookEvents) Create(ctx context.Context, event *domain.WebhookEvent) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	event.ID = uuid.New()
+	copied := *event
+	r.store.webhookEvents[event.ID] = &copied
+	return nil
+}
+
+func (r memWebhookEvents) GetByID(ctx context.Context, id uuid.UUID) (*domain.WebhookEvent, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	event, ok := r.store.webhookEvents[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *event
+	return &copied, nil
+}
+
+func (r memWebhookEvents) ClaimForReplay(ctx context.Context, id uuid.UUID) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	event, ok := r.store.webhookEvents[id]
+	if !ok || event.Status != domain.WebhookEventFailed {
+		return false, nil
+	}
+	event.Status = domain.WebhookEventReplaying
+	return true, nil
+}
+
+func (r memWebhookEvents) RecordResult(ctx context.Context, event *domain.WebhookEvent) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	copied := *event
+	r.store.webhookEvents[event.ID] = &copied
+	return nil
+}
+
+// newTestAAService returns an AA service over store and a mock AA client
+// whose sessions stay PENDING until SimulateSessionReady
+func newTestAAService(t *testing.T, store *memStore) (*AAService, *MockAAClient) {
+	t.Helper()
+	client := NewMockAAClient()
+	client.SetSessionReadyDelay(MockSessionNeverReady)
+	service := NewAAService(client, store.repositories(), NewNormalizer(), NewDeduplicator(), zap.NewNop())
+	return service, client
+}
+
+// activeConsent creates an approved consent on client and an active bank
+// link for it owned by a new user
+func activeConsent(t *testing.T, store *memStore, client *MockAAClient) (*domain.User, *domain.BankLink) {
+	t.Helper()
+	handle, err := client.CreateConsent(ports.ConsentRequest{UserID: uuid.NewString(), FIType: "SAVINGS"})
+	if err != nil {
+		t.Fatalf("CreateConsent: %v", err)
+	}
+	if err := client.SimulateConsentApproval(handle.ConsentID); err != nil {
+		t.Fatalf("SimulateConsentApproval: %v", err)
+	}
+	user := store.addUser("INR")
+	return user, store.addBankLink(user.ID, handle.ConsentID, "ACTIVE")
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"go.uber.org/zap"
+)
+
+// reversalWindow bounds how far back a reversal is matched to its original
+const reversalWindow = 45 * 24 * time.Hour
+
+// reversalMarker matches descriptions banks use for reversals and refunds
+var reversalMarker = regexp.MustCompile(`(?i)\b(REV|REVERSAL|REVERSED|REFUND|RFND|CHARGEBACK)\b`)
+
+// originalRefKeys are the SourceMeta keys a provider may use to point a
+// reversal at the reference of the transaction it reverses
+var originalRefKeys = []string{"original_txn_ref", "orig_txn_ref", "reversal_of"}
+
+// oppositeTxnType returns CREDIT for DEBIT and vice versa
+func oppositeTxnType(txnType string) string {
+	if strings.EqualFold(txnType, "DEBIT") {
+		return "CREDIT"
+	}
+	return "DEBIT"
+}
+
+// originalReference returns the reference of the reversed transaction when
+// the provider supplies one
+func originalReference(sourceMeta domain.JSONB) string {
+	for _, key := range originalRefKeys {
+		if value, ok := sourceMeta[key]; ok && value != nil {
+			if ref := strings.TrimSpace(fmt.Sprint(value)); ref != "" {
+				return ref
+			}
+		}
+	}
+	return ""
+}
+
+// linkReversals pairs newly stored reversals with the transactions they
+// reverse. A provider reference to the original is trusted outright; failing
+// that, a reversal/refund marker in the description plus an unlinked
+// opposite-type transaction of the same amount on the same account is
+// required. Errors are logged so one bad pair does not fail the fetch.
+func (s *AAService) linkReversals(ctx context.Context, transactions []*domain.Transaction) {
+	// Originals must be linked before anything posted after them looks for them
+	sorted := make([]*domain.Transaction, len(transactions))
+	copy(sorted, transactions)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].PostedAt.Before(sorted[j].PostedAt) })
+
+	for _, txn := range sorted {
+		if txn.ReversalOf != nil {
+			continue
+		}
+
+		original, err := s.findReversedTransaction(ctx, txn)
+		if err != nil {
+			s.log(ctx).Warn("Failed to look up reversed transaction", zap.Error(err), zap.String("transaction_id", txn.ID.String()))
+			continue
+		}
+		if original == nil {
+			continue
+		}
+
+		if err := s.repositories.Transaction.LinkReversal(ctx, original.ID, txn.ID); err != nil {
+			s.log(ctx).Warn("Failed to link reversal", zap.Error(err), zap.String("transaction_id", txn.ID.String()), zap.String("original_id", original.ID.String()))
+			continue
+		}
+		txn.ReversalOf = &original.ID
+		original.ReversalOf = &txn.ID
+
+		s.log(ctx).Info("Linked reversal",
+			zap.String("transaction_id", txn.ID.String()),
+			zap.String("original_id", original.ID.String()))
+	}
+}
+
+// findReversedTransaction returns the transaction txn reverses, or nil
+func (s *AAService) findReversedTransaction(ctx context.Context, txn *domain.Transaction) (*domain.Transaction, error) {
+	if ref := originalReference(txn.SourceMeta); ref != "" {
+		for _, key := range providerRefKeys {
+			matches, _, err := s.repositories.Transaction.GetBySourceMeta(ctx, txn.UserID, []string{key}, ref, 1, 0)
+			if err != nil {
+				return nil, err
+			}
+			if len(matches) > 0 && isReversalPair(matches[0], txn) {
+				return matches[0], nil
+			}
+		}
+	}
+
+	if !reversalMarker.MatchString(txn.DescriptionRaw) {
+		return nil, nil
+	}
+	return s.repositories.Transaction.FindReversalCandidate(ctx, txn, oppositeTxnType(txn.TxnType), txn.PostedAt.Add(-reversalWindow))
+}
+
+// isReversalPair reports whether a provider-referenced original can be paired
+// with txn: an unlinked, opposite-type transaction on the same account
+func isReversalPair(original, txn *domain.Transaction) bool {
+	if original.ID == txn.ID || original.ReversalOf != nil || strings.EqualFold(original.TxnType, txn.TxnType) {
+		return false
+	}
+	if (original.BankLinkID == nil) != (txn.BankLinkID == nil) {
+		return false
+	}
+	return original.BankLinkID == nil || *original.BankLinkID == *txn.BankLinkID
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+)
+
+// netAmount sums the user's transactions the way summaries do: credits
+// minus debits, leaving out both legs of linked reversals
+func netAmount(store *memStore) float64 {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	net := 0.0
+	for _, txn := range store.transactions {
+		if txn.ReversalOf != nil {
+			continue
+		}
+		if strings.EqualFold(txn.TxnType, "CREDIT") {
+			net += txn.Amount
+		} else {
+			net -= txn.Amount
+		}
+	}
+	return net
+}
+
+func TestDebitFollowedByReversalNetsToZero(t *testing.T) {
+	store := newMemStore()
+	service, _ := newTestAAService(t, store)
+	user := store.addUser("INR")
+	link := store.addBankLink(user.ID, "consent-1", "ACTIVE")
+
+	debit := addLinkTransaction(store, link, 2, "DEBIT", 499, nil)
+	debit.DescriptionRaw = "UPI/AMAZON/ORDER123"
+	reversal := addLinkTransaction(store, link, 4, "CREDIT", 499, nil)
+	reversal.DescriptionRaw = "UPI REVERSAL AMAZON ORDER123"
+
+	service.linkReversals(context.Background(), []*domain.Transaction{debit, reversal})
+
+	if reversal.ReversalOf == nil || *reversal.ReversalOf != debit.ID {
+		t.Fatalf("reversal links to %v, want the debit", reversal.ReversalOf)
+	}
+	if stored := store.transactions[debit.ID]; stored.ReversalOf == nil || *stored.ReversalOf != reversal.ID {
+		t.Fatalf("debit links to %v, want the reversal", stored.ReversalOf)
+	}
+	if net := netAmount(store); net != 0 {
+		t.Errorf("net = %v, want the pair to cancel out", net)
+	}
+}
+
+func TestReversalLinkedByProviderReference(t *testing.T) {
+	store := newMemStore()
+	service, _ := newTestAAService(t, store)
+	user := store.addUser("INR")
+	link := store.addBankLink(user.ID, "consent-1", "ACTIVE")
+
+	debit := addLinkTransaction(store, link, 2, "DEBIT", 1200, nil)
+	debit.SourceMeta = domain.JSONB{"txn_ref": "REF-77"}
+	// No marker in the description and a different amount: only the
+	// provider's reference ties it to the debit
+	refund := addLinkTransaction(store, link, 5, "CREDIT", 1150, nil)
+	refund.DescriptionRaw = "NEFT CR"
+	refund.SourceMeta = domain.JSONB{"original_txn_ref": "REF-77"}
+
+	service.linkReversals(context.Background(), []*domain.Transaction{refund})
+
+	if refund.ReversalOf == nil || *refund.ReversalOf != debit.ID {
+		t.Errorf("refund links to %v, want the referenced debit", refund.ReversalOf)
+	}
+}
+
+func TestReversalNotLinkedWithoutEvidence(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(store *memStore, link *domain.BankLink) *domain.Transaction
+	}{
+		{"same amount but no marker", func(store *memStore, link *domain.BankLink) *domain.Transaction {
+			addLinkTransaction(store, link, 2, "DEBIT", 300, nil)
+			credit := addLinkTransaction(store, link, 3, "CREDIT", 300, nil)
+			credit.DescriptionRaw = "IMPS FROM RAHUL"
+			return credit
+		}},
+		{"different amount", func(store *memStore, link *domain.BankLink) *domain.Transaction {
+			addLinkTransaction(store, link, 2, "DEBIT", 300, nil)
+			credit := addLinkTransaction(store, link, 3, "CREDIT", 299, nil)
+			credit.DescriptionRaw = "REFUND ORDER 9"
+			return credit
+		}},
+		{"same type", func(store *memStore, link *domain.BankLink) *domain.Transaction {
+			addLinkTransaction(store, link, 2, "CREDIT", 300, nil)
+			credit := addLinkTransaction(store, link, 3, "CREDIT", 300, nil)
+			credit.DescriptionRaw = "REFUND ORDER 9"
+			return credit
+		}},
+		{"original outside the window", func(store *memStore, link *domain.BankLink) *domain.Transaction {
+			debit := addLinkTransaction(store, link, 1, "DEBIT", 300, nil)
+			debit.PostedAt = debit.PostedAt.Add(-reversalWindow - 24*time.Hour)
+			credit := addLinkTransaction(store, link, 3, "CREDIT", 300, nil)
+			credit.DescriptionRaw = "REFUND ORDER 9"
+			return credit
+		}},
+		{"original on another account", func(store *memStore, link *domain.BankLink) *domain.Transaction {
+			other := store.addBankLink(link.UserID, "consent-2", "ACTIVE")
+			addLinkTransaction(store, other, 2, "DEBIT", 300, nil)
+			credit := addLinkTransaction(store, link, 3, "CREDIT", 300, nil)
+			credit.DescriptionRaw = "REFUND ORDER 9"
+			return credit
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemStore()
+			service, _ := newTestAAService(t, store)
+			user := store.addUser("INR")
+			link := store.addBankLink(user.ID, "consent-1", "ACTIVE")
+			credit := tt.setup(store, link)
+
+			service.linkReversals(context.Background(), []*domain.Transaction{credit})
+			for _, txn := range store.transactions {
+				if txn.ReversalOf != nil {
+					t.Errorf("%s was linked to %s", txn.ID, txn.ReversalOf)
+				}
+			}
+		})
+	}
+}
+
+func TestEachOriginalIsReversedOnce(t *testing.T) {
+	store := newMemStore()
+	service, _ := newTestAAService(t, store)
+	user := store.addUser("INR")
+	link := store.addBankLink(user.ID, "consent-1", "ACTIVE")
+
+	debit := addLinkTransaction(store, link, 2, "DEBIT", 80, nil)
+	first := addLinkTransaction(store, link, 3, "CREDIT", 80, nil)
+	first.DescriptionRaw = "REV UPI 1"
+	second := addLinkTransaction(store, link, 4, "CREDIT", 80, nil)
+	second.DescriptionRaw = "REV UPI 2"
+
+	service.linkReversals(context.Background(), []*domain.Transaction{second, first})
+
+	if first.ReversalOf == nil || *first.ReversalOf != debit.ID {
+		t.Errorf("first reversal links to %v, want the debit", first.ReversalOf)
+	}
+	if second.ReversalOf != nil {
+		t.Errorf("second reversal links to %v, but the debit was already reversed", second.ReversalOf)
+	}
+	if net := netAmount(store); net != 80 {
+		t.Errorf("net = %v, want only the unmatched reversal counted", net)
+	}
+}
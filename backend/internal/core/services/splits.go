@@ -0,0 +1,87 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+)
+
+// Split limits
+const (
+	MaxSplitParts          = 20
+	maxSplitCategoryLength = 100
+	maxSplitNoteLength     = 500
+)
+
+// ErrInvalidSplit is returned when split parts don't describe the transaction
+var ErrInvalidSplit = errors.New("invalid split")
+
+// SplitPart is a requested part of a split transaction, in the
+// transaction's currency
+type SplitPart struct {
+	Amount      float64 `json:"amount" example:"2000"`
+	Category    string  `json:"category" example:"Groceries"`
+	Subcategory string  `json:"subcategory,omitempty"`
+	Note        string  `json:"note,omitempty"`
+}
+
+// BuildSplits validates parts against txn and converts them into split rows.
+// There must be at least two parts, each with a category and a positive
+// amount, adding up to the transaction's amount to the paisa. Base amounts
+// are apportioned by each part's share, with rounding left on the last part
+// so they add up to the transaction's base amount exactly.
+func BuildSplits(txn *domain.Transaction, parts []SplitPart) ([]domain.TransactionSplit, error) {
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("%w: at least two parts are required", ErrInvalidSplit)
+	}
+	if len(parts) > MaxSplitParts {
+		return nil, fmt.Errorf("%w: at most %d parts are allowed", ErrInvalidSplit, MaxSplitParts)
+	}
+
+	total := toPaise(math.Abs(txn.Amount))
+	baseTotal := toPaise(math.Abs(txn.BaseAmount))
+	var sum int64
+	for i, part := range parts {
+		if part.Amount <= 0 {
+			return nil, fmt.Errorf("%w: part %d amount must be positive", ErrInvalidSplit, i+1)
+		}
+		category := strings.TrimSpace(part.Category)
+		if category == "" || len(category) > maxSplitCategoryLength {
+			return nil, fmt.Errorf("%w: part %d category must be 1-%d characters", ErrInvalidSplit, i+1, maxSplitCategoryLength)
+		}
+		if len(part.Subcategory) > maxSplitCategoryLength || len(part.Note) > maxSplitNoteLength {
+			return nil, fmt.Errorf("%w: part %d subcategory or note is too long", ErrInvalidSplit, i+1)
+		}
+		sum += toPaise(part.Amount)
+	}
+	if sum != total {
+		return nil, fmt.Errorf("%w: parts add up to %.2f but the transaction amount is %.2f", ErrInvalidSplit, float64(sum)/100, float64(total)/100)
+	}
+
+	splits := make([]domain.TransactionSplit, len(parts))
+	var baseAssigned int64
+	for i, part := range parts {
+		amount := toPaise(part.Amount)
+		base := baseTotal - baseAssigned
+		if i < len(parts)-1 {
+			base = int64(math.Round(float64(baseTotal) * float64(amount) / float64(total)))
+		}
+		baseAssigned += base
+		splits[i] = domain.TransactionSplit{
+			Amount:      float64(amount) / 100,
+			BaseAmount:  float64(base) / 100,
+			Category:    strings.TrimSpace(part.Category),
+			Subcategory: strings.TrimSpace(part.Subcategory),
+			Note:        strings.TrimSpace(part.Note),
+		}
+	}
+	return splits, nil
+}
+
+// toPaise converts an amount into hundredths, the precision it is stored at
+func toPaise(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+)
+
+func TestBuildSplits(t *testing.T) {
+	// A USD purchase converted into INR: the base amounts are apportioned by
+	// each part's share and add up to the converted amount exactly
+	txn := &domain.Transaction{Amount: -100, Currency: "USD", BaseAmount: -8333.33, BaseCurrency: "INR"}
+	splits, err := BuildSplits(txn, []SplitPart{
+		{Amount: 33.33, Category: " Food & Dining "},
+		{Amount: 33.33, Category: "Shopping", Subcategory: "Electronics"},
+		{Amount: 33.34, Category: "Travel", Note: "airport"},
+	})
+	if err != nil {
+		t.Fatalf("BuildSplits: %v", err)
+	}
+	var amount, base float64
+	for _, split := range splits {
+		amount += split.Amount
+		base += split.BaseAmount
+	}
+	if toPaise(amount) != 10000 || toPaise(base) != 833333 {
+		t.Errorf("parts add up to %.2f (base %.2f), want 100.00 (base 8333.33)", amount, base)
+	}
+	if splits[0].Category != "Food & Dining" || splits[0].BaseAmount != 2777.5 || splits[1].Subcategory != "Electronics" || splits[2].Note != "airport" {
+		t.Errorf("splits = %+v", splits)
+	}
+}
+
+func TestBuildSplitsRejections(t *testing.T) {
+	txn := &domain.Transaction{Amount: 5000, BaseAmount: 5000}
+	tests := []struct {
+		name  string
+		parts []SplitPart
+	}{
+		{"one part", []SplitPart{{Amount: 5000, Category: "Shopping"}}},
+		{"short of the amount", []SplitPart{{Amount: 2000, Category: "Food & Dining"}, {Amount: 2999.99, Category: "Shopping"}}},
+		{"over the amount", []SplitPart{{Amount: 2000, Category: "Food & Dining"}, {Amount: 3000.01, Category: "Shopping"}}},
+		{"negative part", []SplitPart{{Amount: 6000, Category: "Food & Dining"}, {Amount: -1000, Category: "Shopping"}}},
+		{"zero part", []SplitPart{{Amount: 5000, Category: "Food & Dining"}, {Amount: 0, Category: "Shopping"}}},
+		{"no category", []SplitPart{{Amount: 2000, Category: "Food & Dining"}, {Amount: 3000, Category: "  "}}},
+		{"long note", []SplitPart{{Amount: 2000, Category: "Food & Dining"}, {Amount: 3000, Category: "Shopping", Note: strings.Repeat("n", maxSplitNoteLength+1)}}},
+		{"too many parts", make([]SplitPart, MaxSplitParts+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := BuildSplits(txn, tt.parts); !errors.Is(err, ErrInvalidSplit) {
+				t.Errorf("BuildSplits: %v, want ErrInvalidSplit", err)
+			}
+		})
+	}
+
+	// 2000 + 3000 splits a 5000 debit however its sign is stored
+	for _, amount := range []float64{5000, -5000} {
+		debit := &domain.Transaction{Amount: amount, BaseAmount: amount}
+		if _, err := BuildSplits(debit, []SplitPart{{Amount: 2000, Category: "Food & Dining"}, {Amount: 3000, Category: "Shopping"}}); err != nil {
+			t.Errorf("splitting %v: %v", amount, err)
+		}
+	}
+}
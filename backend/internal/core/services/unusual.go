@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/internal/repo"
+	"go.uber.org/zap"
+)
+
+// Unusual transaction detection defaults
+const (
+	// DefaultUnusualStdDevs is how many standard deviations above the
+	// category mean a transaction must be to count as an outlier
+	DefaultUnusualStdDevs = 3.0
+	// unusualMinSamples is how many other transactions a category needs
+	// before its statistics are trusted
+	unusualMinSamples = 5
+	// unusualLookback bounds the history the category norms are taken from
+	unusualLookback = 180 * 24 * time.Hour
+	// unusualMinSpread keeps categories of near-identical amounts, like a
+	// fixed rent, from flagging every small increase: the standard deviation
+	// used is at least this fraction of the mean
+	unusualMinSpread = 0.1
+)
+
+// unusualStdDevs returns the configured outlier distance, falling back to
+// DefaultUnusualStdDevs
+func (s *AAService) unusualStdDevs() float64 {
+	if s.UnusualStdDevs > 0 {
+		return s.UnusualStdDevs
+	}
+	return DefaultUnusualStdDevs
+}
+
+// flagUnusualTransactions flags newly stored transactions whose base amount
+// exceeds LargeTransactionThreshold, or lies more than unusualStdDevs()
+// standard deviations above the mean of the user's other transactions of the
+// same category and type. Reversal legs are skipped. Errors are logged so
+// flagging never fails a fetch.
+func (s *AAService) flagUnusualTransactions(ctx context.Context, userID uuid.UUID, transactions []*domain.Transaction) {
+	stats, err := s.repositories.Transaction.GetAmountStats(ctx, userID, time.Now().Add(-unusualLookback))
+	if err != nil {
+		s.log(ctx).Warn("Failed to load transaction amount statistics", zap.Error(err), zap.String("user_id", userID.String()))
+		return
+	}
+
+	for _, txn := range transactions {
+		if txn.ReversalOf != nil {
+			continue
+		}
+		reason := unusualFlag(txn, stats, s.LargeTransactionThreshold, s.unusualStdDevs())
+		if reason == "" {
+			continue
+		}
+		if err := s.repositories.Transaction.SetFlagReason(ctx, txn.ID, reason); err != nil {
+			s.log(ctx).Warn("Failed to flag transaction", zap.Error(err), zap.String("transaction_id", txn.ID.String()))
+			continue
+		}
+		txn.FlagReason = reason
+
+		s.log(ctx).Info("Flagged unusual transaction",
+			zap.String("transaction_id", txn.ID.String()),
+			zap.String("reason", reason))
+	}
+}
+
+// unusualFlag returns why txn is unusual, or "" when it isn't. stats are
+// expected to include txn itself, which is taken out before comparing so a
+// single large amount cannot raise its own bar. A threshold of zero
+// disables the absolute check.
+func unusualFlag(txn *domain.Transaction, stats map[repo.AmountStatsKey]repo.AmountStats, threshold, stdDevs float64) string {
+	amount := math.Abs(txn.BaseAmount)
+	if threshold > 0 && amount > threshold {
+		return domain.FlagLargeAmount
+	}
+
+	key := repo.AmountStatsKey{Category: effectiveCategory(txn), TxnType: strings.ToUpper(txn.TxnType)}
+	others, ok := stats[key]
+	if !ok {
+		return ""
+	}
+	others.Count--
+	others.Sum -= amount
+	others.SumSquares -= amount * amount
+	if others.Count < unusualMinSamples {
+		return ""
+	}
+
+	n := float64(others.Count)
+	mean := others.Sum / n
+	variance := math.Max(others.SumSquares/n-mean*mean, 0)
+	stdDev := math.Max(math.Sqrt(variance), mean*unusualMinSpread)
+	if amount > mean+stdDevs*stdDev {
+		return domain.FlagCategoryOutlier
+	}
+	return ""
+}
+
+// effectiveCategory mirrors the repository's grouping of transactions by
+// category: the user's category, else the normalizer's, else "Other"
+func effectiveCategory(txn *domain.Transaction) string {
+	if category := txn.EffectiveCategory(); category != "" {
+		return category
+	}
+	return "Other"
+}
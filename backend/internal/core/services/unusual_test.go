@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/internal/core/ports"
+	"github.com/your-github/expense-tracker-backend/internal/repo"
+	"go.uber.org/zap"
+)
+
+// foodOrder is the i-th line of a run of food delivery orders over the past
+// weeks, each within the lookback
+func foodOrder(i int, amount float64) ports.FITransaction {
+	return ports.FITransaction{
+		PostedAt:       time.Now().Add(-time.Duration(i+1) * 24 * time.Hour).UTC().Format(time.RFC3339),
+		Amount:         amount,
+		Currency:       "INR",
+		Type:           "DEBIT",
+		DescriptionRaw: fmt.Sprintf("UPI/SWIGGY/ORDER%d", i),
+		SourceMeta:     map[string]interface{}{"txn_ref": fmt.Sprintf("FOOD-%d", i)},
+	}
+}
+
+func TestIngestFlagsOnlyTheOutlier(t *testing.T) {
+	store := newMemStore()
+	user := store.addUser("INR")
+	link := store.addBankLink(user.ID, "consent-unusual", "ACTIVE")
+
+	var lines []ports.FITransaction
+	for i, amount := range []float64{280, 310, 295, 330, 260, 305, 290, 320, 275, 300, 315, 285} {
+		lines = append(lines, foodOrder(i, amount))
+	}
+	lines = append(lines, foodOrder(len(lines), 2400))
+	client := &fixedAAClient{MockAAClient: NewMockAAClient(), transactions: lines}
+	service := NewAAService(client, store.repositories(), NewNormalizer(), NewDeduplicator(), zap.NewNop())
+
+	if _, err := service.fetchAndProcessTransactions(context.Background(), "session-unusual", user.ID, link.ID); err != nil {
+		t.Fatalf("fetchAndProcessTransactions: %v", err)
+	}
+
+	var flagged []*domain.Transaction
+	for _, txn := range store.transactions {
+		if txn.FlagReason != "" {
+			flagged = append(flagged, txn)
+		}
+	}
+	if len(flagged) != 1 || flagged[0].Amount != 2400 || flagged[0].FlagReason != domain.FlagCategoryOutlier {
+		t.Fatalf("flagged %d transactions, want only the 2400 order as a category outlier", len(flagged))
+	}
+}
+
+func TestIngestFlagsAmountsAboveTheThreshold(t *testing.T) {
+	store := newMemStore()
+	user := store.addUser("INR")
+	link := store.addBankLink(user.ID, "consent-large", "ACTIVE")
+	// No history at all: only the absolute threshold applies
+	client := &fixedAAClient{MockAAClient: NewMockAAClient(), transactions: []ports.FITransaction{foodOrder(0, 60000), foodOrder(1, 400)}}
+	service := NewAAService(client, store.repositories(), NewNormalizer(), NewDeduplicator(), zap.NewNop())
+	service.LargeTransactionThreshold = 50000
+
+	created, err := service.fetchAndProcessTransactions(context.Background(), "session-large", user.ID, link.ID)
+	if err != nil {
+		t.Fatalf("fetchAndProcessTransactions: %v", err)
+	}
+	for _, txn := range created {
+		want := ""
+		if txn.Amount == 60000 {
+			want = domain.FlagLargeAmount
+		}
+		if store.transactions[txn.ID].FlagReason != want {
+			t.Errorf("%v flagged %q, want %q", txn.Amount, store.transactions[txn.ID].FlagReason, want)
+		}
+	}
+}
+
+func TestUnusualFlag(t *testing.T) {
+	key := repo.AmountStatsKey{Category: "Bills & Utilities", TxnType: "DEBIT"}
+	// stats over n amounts of each value plus the candidate itself
+	statsWith := func(n int, value, candidate float64) map[repo.AmountStatsKey]repo.AmountStats {
+		return map[repo.AmountStatsKey]repo.AmountStats{key: {
+			Count:      int64(n + 1),
+			Sum:        float64(n)*value + candidate,
+			SumSquares: float64(n)*value*value + candidate*candidate,
+		}}
+	}
+	txn := func(amount float64) *domain.Transaction {
+		return &domain.Transaction{ID: uuid.New(), BaseAmount: amount, TxnType: "debit", Category: "Bills & Utilities"}
+	}
+
+	tests := []struct {
+		name      string
+		txn       *domain.Transaction
+		stats     map[repo.AmountStatsKey]repo.AmountStats
+		threshold float64
+		want      string
+	}{
+		{"fixed rent rising a little", txn(-26000), statsWith(6, 25000, 26000), 0, ""},
+		{"fixed rent doubling", txn(-50000), statsWith(6, 25000, 50000), 0, domain.FlagCategoryOutlier},
+		{"too little history", txn(-50000), statsWith(unusualMinSamples-1, 25000, 50000), 0, ""},
+		{"no history in the category", txn(-50000), nil, 0, ""},
+		{"above the threshold", txn(-50000), nil, 40000, domain.FlagLargeAmount},
+		{"at the threshold", txn(-40000), nil, 40000, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unusualFlag(tt.txn, tt.stats, tt.threshold, DefaultUnusualStdDevs); got != tt.want {
+				t.Errorf("unusualFlag = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
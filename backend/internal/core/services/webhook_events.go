@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrWebhookEventNotFound      = errors.New("webhook event not found")
+	ErrWebhookEventNotReplayable = errors.New("only failed webhook events can be replayed")
+)
+
+// consentCallbackPayload is the part of a consent callback body needed to
+// handle it
+type consentCallbackPayload struct {
+	ConsentID string `json:"consent_id"`
+	Status    string `json:"status"`
+}
+
+// HandleWebhookEvent records a verified webhook delivery, handles it and
+// stores the outcome. Recording is best effort: if the event cannot be
+// saved it is still handled, just without an audit trail.
+func (s *AAService) HandleWebhookEvent(ctx context.Context, event *domain.WebhookEvent) error {
+	event.Status = domain.WebhookEventPending
+	if err := s.repositories.WebhookEvent.Create(ctx, event); err != nil {
+		s.log(ctx).Error("Failed to record webhook event", zap.Error(err), zap.String("event_type", event.EventType))
+		return s.dispatchWebhookEvent(ctx, event)
+	}
+	return s.attemptWebhookEvent(ctx, event)
+}
+
+// ReplayWebhookEvent re-handles a failed event. Replaying an event that
+// already succeeded is a no-op returning the event, and concurrent replays
+// of one event are serialized by claiming it first. Handling itself is
+// idempotent: sessions are ingested once and consent updates are absolute.
+// On a failed replay the event is returned along with the error.
+func (s *AAService) ReplayWebhookEvent(ctx context.Context, id uuid.UUID) (*domain.WebhookEvent, error) {
+	event, err := s.getWebhookEvent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if event.Status == domain.WebhookEventProcessed {
+		return event, nil
+	}
+
+	claimed, err := s.repositories.WebhookEvent.ClaimForReplay(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook event: %w", err)
+	}
+	if !claimed {
+		// Another delivery or replay may have finished in the meantime
+		if event, err = s.getWebhookEvent(ctx, id); err != nil {
+			return nil, err
+		}
+		if event.Status == domain.WebhookEventProcessed {
+			return event, nil
+		}
+		return event, ErrWebhookEventNotReplayable
+	}
+
+	s.log(ctx).Info("Replaying webhook event",
+		zap.String("event_id", id.String()),
+		zap.String("event_type", event.EventType),
+		zap.Int("attempts", event.Attempts))
+
+	return event, s.attemptWebhookEvent(ctx, event)
+}
+
+// ListWebhookEvents returns recorded events newest first, optionally
+// filtered by status
+func (s *AAService) ListWebhookEvents(ctx context.Context, status string, limit, offset int) ([]*domain.WebhookEvent, int64, error) {
+	return s.repositories.WebhookEvent.List(ctx, status, limit, offset)
+}
+
+func (s *AAService) getWebhookEvent(ctx context.Context, id uuid.UUID) (*domain.WebhookEvent, error) {
+	event, err := s.repositories.WebhookEvent.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWebhookEventNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook event: %w", err)
+	}
+	return event, nil
+}
+
+// attemptWebhookEvent handles a recorded event and stores the outcome
+func (s *AAService) attemptWebhookEvent(ctx context.Context, event *domain.WebhookEvent) error {
+	err := s.dispatchWebhookEvent(ctx, event)
+
+	now := time.Now()
+	event.Attempts++
+	event.ProcessedAt = &now
+	if err != nil {
+		event.Status = domain.WebhookEventFailed
+		event.Error = err.Error()
+	} else {
+		event.Status = domain.WebhookEventProcessed
+		event.Error = ""
+	}
+
+	if recordErr := s.repositories.WebhookEvent.RecordResult(ctx, event); recordErr != nil {
+		s.log(ctx).Error("Failed to record webhook event result",
+			zap.Error(recordErr),
+			zap.String("event_id", event.ID.String()),
+			zap.String("status", event.Status))
+	}
+	return err
+}
+
+// dispatchWebhookEvent routes an event to its handler
+func (s *AAService) dispatchWebhookEvent(ctx context.Context, event *domain.WebhookEvent) error {
+	switch event.EventType {
+	case domain.WebhookEventConsentCallback:
+		var payload consentCallbackPayload
+		if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+			return fmt.Errorf("invalid consent callback payload: %w", err)
+		}
+		return s.HandleConsentCallback(ctx, payload.ConsentID, payload.Status)
+	case domain.WebhookEventDataReady:
+		return s.HandleDataReadyWebhook(ctx, event.SessionID)
+	case domain.WebhookEventConsentRevoked:
+		return s.HandleConsentRevokedWebhook(ctx, event.ConsentID)
+	default:
+		return fmt.Errorf("unknown webhook event type %q", event.EventType)
+	}
+}
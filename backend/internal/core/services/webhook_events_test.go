@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+)
+
+// dataReadyEvent is the event the webhook handler records for a DATA_READY delivery
+func dataReadyEvent(sessionID string) *domain.WebhookEvent {
+	return &domain.WebhookEvent{
+		EventType: domain.WebhookEventDataReady,
+		SessionID: sessionID,
+		Payload:   `{"event_type":"DATA_READY","session_id":"` + sessionID + `"}`,
+	}
+}
+
+func TestDataReadyWebhookReplayAfterSessionReady(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	service, client := newTestAAService(t, store)
+	user, link := activeConsent(t, store, client)
+
+	result, err := service.FetchTransactions(ctx, user.ID, link.ID, "2025-01-01", "2025-01-02", false)
+	if err != nil {
+		t.Fatalf("FetchTransactions: %v", err)
+	}
+
+	// Delivered before the session is ready: recorded as failed
+	event := dataReadyEvent(result.SessionID)
+	if err := service.HandleWebhookEvent(ctx, event); err == nil {
+		t.Fatal("expected an error for a session that isn't ready")
+	}
+	recorded := store.webhookEvents[event.ID]
+	if recorded == nil || recorded.Status != domain.WebhookEventFailed || recorded.Error == "" || recorded.Attempts != 1 {
+		t.Fatalf("recorded event = %+v, want FAILED with the error after one attempt", recorded)
+	}
+	if recorded.Payload != event.Payload {
+		t.Errorf("recorded payload %q, want the raw body", recorded.Payload)
+	}
+
+	if err := client.SimulateSessionReady(result.SessionID); err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := service.ReplayWebhookEvent(ctx, event.ID)
+	if err != nil {
+		t.Fatalf("ReplayWebhookEvent: %v", err)
+	}
+	if replayed.Status != domain.WebhookEventProcessed || replayed.Error != "" || replayed.Attempts != 2 {
+		t.Errorf("replayed = %s, error %q after %d attempts; want PROCESSED after 2", replayed.Status, replayed.Error, replayed.Attempts)
+	}
+	stored := len(store.linkTransactions(link.ID))
+	if stored == 0 {
+		t.Fatal("replay stored no transactions")
+	}
+
+	// Replaying again is a no-op
+	again, err := service.ReplayWebhookEvent(ctx, event.ID)
+	if err != nil || again.Attempts != 2 {
+		t.Errorf("second replay = %+v, %v; want the processed event unchanged", again, err)
+	}
+	if got := len(store.linkTransactions(link.ID)); got != stored {
+		t.Errorf("second replay stored %d transactions, want %d", got, stored)
+	}
+}
+
+func TestReplayWebhookEventOnlyReplaysFailedEvents(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	service, _ := newTestAAService(t, store)
+
+	if _, err := service.ReplayWebhookEvent(ctx, uuid.New()); !errors.Is(err, ErrWebhookEventNotFound) {
+		t.Errorf("unknown event: %v, want ErrWebhookEventNotFound", err)
+	}
+
+	// Still being handled by another delivery or replay
+	inFlight := &domain.WebhookEvent{ID: uuid.New(), EventType: domain.WebhookEventConsentRevoked, ConsentID: "consent-1", Status: domain.WebhookEventReplaying}
+	store.webhookEvents[inFlight.ID] = inFlight
+	event, err := service.ReplayWebhookEvent(ctx, inFlight.ID)
+	if !errors.Is(err, ErrWebhookEventNotReplayable) || event == nil || event.Status != domain.WebhookEventReplaying {
+		t.Errorf("replaying an in-flight event = %+v, %v; want ErrWebhookEventNotReplayable", event, err)
+	}
+}
+
+func TestFailedReplayKeepsTheEventFailed(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	service, _ := newTestAAService(t, store)
+
+	event := &domain.WebhookEvent{EventType: domain.WebhookEventConsentRevoked, ConsentID: "consent-unknown"}
+	if err := service.HandleWebhookEvent(ctx, event); !errors.Is(err, ErrBankLinkNotFound) {
+		t.Fatalf("HandleWebhookEvent: %v, want ErrBankLinkNotFound", err)
+	}
+
+	replayed, err := service.ReplayWebhookEvent(ctx, event.ID)
+	if !errors.Is(err, ErrBankLinkNotFound) {
+		t.Fatalf("ReplayWebhookEvent: %v, want the handler's error", err)
+	}
+	if replayed.Status != domain.WebhookEventFailed || replayed.Attempts != 2 {
+		t.Errorf("replayed = %s after %d attempts, want FAILED after 2", replayed.Status, replayed.Attempts)
+	}
+	// Failed again, so it can be replayed once more
+	if _, err := service.ReplayWebhookEvent(ctx, event.ID); errors.Is(err, ErrWebhookEventNotReplayable) {
+		t.Error("a failed replay can't be replayed again")
+	}
+}
+
+func TestDataReadyWebhookIngestsIntoSessionOwner(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	service, client := newTestAAService(t, store)
+	user, link := activeConsent(t, store, client)
+
+	result, err := service.FetchTransactions(ctx, user.ID, link.ID, "2025-01-01", "2025-01-03", false)
+	if err != nil {
+		t.Fatalf("FetchTransactions: %v", err)
+	}
+	if result.Processed {
+		t.Fatal("session should still be pending")
+	}
+	if err := client.SimulateSessionReady(result.SessionID); err != nil {
+		t.Fatal(err)
+	}
+
+	event := dataReadyEvent(result.SessionID)
+	if err := service.HandleWebhookEvent(ctx, event); err != nil {
+		t.Fatalf("HandleWebhookEvent: %v", err)
+	}
+	if event.Status != domain.WebhookEventProcessed {
+		t.Errorf("event status = %s, want %s", event.Status, domain.WebhookEventProcessed)
+	}
+
+	stored := store.linkTransactions(link.ID)
+	if len(stored) == 0 {
+		t.Fatal("no transactions were stored for the bank link")
+	}
+	for _, txn := range stored {
+		if txn.UserID != user.ID {
+			t.Fatalf("transaction stored for user %s, want %s", txn.UserID, user.ID)
+		}
+	}
+	if _, ok := store.processed[result.SessionID]; !ok {
+		t.Error("session was not recorded as processed")
+	}
+
+	// A repeated delivery is acknowledged without storing anything again
+	if err := service.HandleWebhookEvent(ctx, dataReadyEvent(result.SessionID)); err != nil {
+		t.Fatalf("repeated delivery: %v", err)
+	}
+	if got := len(store.linkTransactions(link.ID)); got != len(stored) {
+		t.Errorf("repeated delivery stored %d transactions, want %d", got, len(stored))
+	}
+}
+
+func TestDataReadyWebhookUnknownSession(t *testing.T) {
+	store := newMemStore()
+	service, _ := newTestAAService(t, store)
+
+	err := service.HandleDataReadyWebhook(context.Background(), "session_unknown")
+	if err == nil {
+		t.Fatal("expected an error for an unknown session")
+	}
+}
+
+func TestDataReadyWebhookSessionNotCreatedHere(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	service, client := newTestAAService(t, store)
+	_, link := activeConsent(t, store, client)
+
+	// A session the AA knows about but that FetchTransactions never recorded
+	session, err := client.CreateDataSession(link.AAConsentID, "2025-01-01", "2025-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SimulateSessionReady(session.SessionID); err != nil {
+		t.Fatal(err)
+	}
+
+	err = service.HandleDataReadyWebhook(ctx, session.SessionID)
+	if !errors.Is(err, ErrUnknownDataSession) {
+		t.Fatalf("err = %v, want ErrUnknownDataSession", err)
+	}
+	if len(store.transactions) != 0 {
+		t.Error("transactions were stored for an unknown session")
+	}
+}
+
+func TestDataReadyWebhookIgnoresDryRunSession(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	service, client := newTestAAService(t, store)
+	user, link := activeConsent(t, store, client)
+
+	result, err := service.FetchTransactions(ctx, user.ID, link.ID, "2025-01-01", "2025-01-02", true)
+	if err != nil {
+		t.Fatalf("FetchTransactions: %v", err)
+	}
+	if err := client.SimulateSessionReady(result.SessionID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.HandleDataReadyWebhook(ctx, result.SessionID); err != nil {
+		t.Fatalf("HandleDataReadyWebhook: %v", err)
+	}
+	if len(store.transactions) != 0 {
+		t.Errorf("dry run session stored %d transactions", len(store.transactions))
+	}
+}
+
+// consentCallbackEvent is the event the callback handler records for a status update
+func consentCallbackEvent(consentID, status string) *domain.WebhookEvent {
+	return &domain.WebhookEvent{
+		EventType: domain.WebhookEventConsentCallback,
+		ConsentID: consentID,
+		Payload:   `{"consent_id":"` + consentID + `","status":"` + status + `"}`,
+	}
+}
+
+func TestConsentRevokedWebhookRevokesLink(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	service, client := newTestAAService(t, store)
+	_, link := activeConsent(t, store, client)
+
+	event := &domain.WebhookEvent{EventType: domain.WebhookEventConsentRevoked, ConsentID: link.AAConsentID}
+	if err := service.HandleWebhookEvent(ctx, event); err != nil {
+		t.Fatalf("HandleWebhookEvent: %v", err)
+	}
+	if got := store.bankLinks[link.ID].Status; got != "REVOKED" {
+		t.Fatalf("status = %s, want REVOKED", got)
+	}
+
+	// Revoking again is a no-op
+	if err := service.HandleConsentRevokedWebhook(ctx, link.AAConsentID); err != nil {
+		t.Fatalf("repeated revoke: %v", err)
+	}
+
+	err := service.HandleConsentRevokedWebhook(ctx, "consent_unknown")
+	if !errors.Is(err, ErrBankLinkNotFound) {
+		t.Errorf("err = %v, want ErrBankLinkNotFound", err)
+	}
+}
+
+func TestLateActiveCallbackAfterRevokeKeepsLinkRevoked(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	service, client := newTestAAService(t, store)
+	_, link := activeConsent(t, store, client)
+
+	revoked := &domain.WebhookEvent{EventType: domain.WebhookEventConsentRevoked, ConsentID: link.AAConsentID}
+	if err := service.HandleWebhookEvent(ctx, revoked); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+
+	late := consentCallbackEvent(link.AAConsentID, "ACTIVE")
+	if err := service.HandleWebhookEvent(ctx, late); err != nil {
+		t.Fatalf("late callback: %v", err)
+	}
+	if late.Status != domain.WebhookEventProcessed {
+		t.Errorf("late callback status = %s, want %s", late.Status, domain.WebhookEventProcessed)
+	}
+
+	stored := store.bankLinks[link.ID]
+	if stored.Status != "REVOKED" {
+		t.Errorf("status = %s, want REVOKED", stored.Status)
+	}
+	if stored.ValidTill != nil {
+		t.Error("a late ACTIVE callback extended the validity of a revoked link")
+	}
+}
+
+func TestConsentCallbackAfterExpiryIsIgnored(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	service, _ := newTestAAService(t, store)
+	user := store.addUser("INR")
+	link := store.addBankLink(user.ID, "consent-expired", "EXPIRED")
+
+	if err := service.HandleConsentCallback(ctx, link.AAConsentID, "ACTIVE"); err != nil {
+		t.Fatalf("HandleConsentCallback: %v", err)
+	}
+	if got := store.bankLinks[link.ID].Status; got != "EXPIRED" {
+		t.Errorf("status = %s, want EXPIRED", got)
+	}
+}
+
+func TestConsentCallbackActivatesPendingLink(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	service, _ := newTestAAService(t, store)
+	user := store.addUser("INR")
+	link := store.addBankLink(user.ID, "consent-pending", "PENDING")
+
+	if err := service.HandleConsentCallback(ctx, link.AAConsentID, "ACTIVE"); err != nil {
+		t.Fatalf("HandleConsentCallback: %v", err)
+	}
+	stored := store.bankLinks[link.ID]
+	if stored.Status != "ACTIVE" || stored.ValidTill == nil {
+		t.Errorf("status = %s valid_till = %v, want ACTIVE with a validity", stored.Status, stored.ValidTill)
+	}
+
+	// A revoke arriving after activation still applies
+	if err := service.HandleConsentCallback(ctx, link.AAConsentID, "REVOKED"); err != nil {
+		t.Fatalf("HandleConsentCallback: %v", err)
+	}
+	if got := store.bankLinks[link.ID].Status; got != "REVOKED" {
+		t.Errorf("status = %s, want REVOKED", got)
+	}
+}
@@ -1,16 +1,23 @@
 package handlers
 
 import (
+	"errors"
 	"io"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
 	"github.com/your-github/expense-tracker-backend/config"
 	"github.com/your-github/expense-tracker-backend/internal/core/domain"
 	"github.com/your-github/expense-tracker-backend/internal/core/ports"
 	"github.com/your-github/expense-tracker-backend/internal/core/services"
 	"github.com/your-github/expense-tracker-backend/internal/repo"
+	"github.com/your-github/expense-tracker-backend/middleware"
+	"github.com/your-github/expense-tracker-backend/requestid"
 	"go.uber.org/zap"
 )
 
@@ -22,7 +29,11 @@ type AAHandler struct {
 	logger       *zap.Logger
 }
 
-// NewAAHandler creates a new AA handler
+// webhookSignatureHeader carries the signature of the raw webhook body
+const webhookSignatureHeader = "X-Signature"
+
+// NewAAHandler creates a new AA handler. Webhook signatures are verified
+// by the AA client behind aaService.
 func NewAAHandler(
 	aaService *services.AAService,
 	repositories *repo.Repositories,
@@ -41,7 +52,7 @@ func NewAAHandler(
 type InitiateConsentRequest struct {
 	FIType    string    `json:"fi_type" binding:"required"`    // "SAVINGS", "CURRENT", etc.
 	Purpose   string    `json:"purpose" binding:"required"`    // "EXPENSE_ANALYSIS"
-	DateRange DateRange `json:"date_range" binding:"required"` // ISO dates
+	DateRange DateRange `json:"date_range" binding:"required"` // ISO dates, at most 730 days, not in the future
 	Frequency string    `json:"frequency" binding:"required"`  // "DAILY"
 }
 
@@ -69,6 +80,7 @@ type InitiateConsentResponse struct {
 // @Success 200 {object} InitiateConsentResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /aa/consents/initiate [post]
 func (h *AAHandler) InitiateConsent(c *gin.Context) {
@@ -83,6 +95,10 @@ func (h *AAHandler) InitiateConsent(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request data: " + err.Error()})
 		return
 	}
+	if err := validateDateRange(req.DateRange.From, req.DateRange.To, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid date_range: " + err.Error()})
+		return
+	}
 
 	// Create consent request
 	consentReq := ports.ConsentRequest{
@@ -98,8 +114,8 @@ func (h *AAHandler) InitiateConsent(c *gin.Context) {
 	// Initiate consent
 	bankLink, err := h.aaService.InitiateConsent(c.Request.Context(), userID, consentReq)
 	if err != nil {
-		h.logger.Error("Failed to initiate consent", zap.Error(err), zap.String("user_id", userID.String()))
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to initiate consent"})
+		requestid.Logger(c.Request.Context(), h.logger).Error("Failed to initiate consent", zap.Error(err), zap.String("user_id", userID.String()))
+		h.writeAAError(c, err, "Failed to initiate consent")
 		return
 	}
 
@@ -115,7 +131,6 @@ func (h *AAHandler) InitiateConsent(c *gin.Context) {
 type ConsentCallbackRequest struct {
 	ConsentID string `json:"consent_id" binding:"required"`
 	Status    string `json:"status" binding:"required"`
-	Signature string `json:"signature" binding:"required"`
 }
 
 // ConsentCallback handles consent status updates from AA
@@ -124,21 +139,18 @@ type ConsentCallbackRequest struct {
 // @Tags aa
 // @Accept json
 // @Produce json
+// @Param X-Signature header string true "Signature of the raw request body"
 // @Param request body ConsentCallbackRequest true "Callback data"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /aa/consents/callback [post]
 func (h *AAHandler) ConsentCallback(c *gin.Context) {
 	var req ConsentCallbackRequest
 	var err error
 
-	if err = c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request data: " + err.Error()})
-		return
-	}
-
-	// Read request body for signature verification
+	// Read the raw body first: the signature covers the exact bytes sent
 	var bodyBytes []byte
 	bodyBytes, err = io.ReadAll(c.Request.Body)
 	if err != nil {
@@ -147,15 +159,25 @@ func (h *AAHandler) ConsentCallback(c *gin.Context) {
 	}
 
 	// Verify webhook signature
-	if !h.verifyWebhookSignature(bodyBytes, req.Signature) {
+	if !h.verifyWebhookSignature(bodyBytes, c.GetHeader(webhookSignatureHeader)) {
 		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid signature"})
 		return
 	}
 
-	// Handle consent status update
-	err = h.aaService.HandleConsentCallback(c.Request.Context(), req.ConsentID, req.Status)
+	if err = binding.JSON.BindBody(bodyBytes, &req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request data: " + err.Error()})
+		return
+	}
+
+	// Record and handle the consent status update
+	err = h.aaService.HandleWebhookEvent(c.Request.Context(), &domain.WebhookEvent{
+		EventType: domain.WebhookEventConsentCallback,
+		ConsentID: req.ConsentID,
+		Payload:   string(bodyBytes),
+		Signature: c.GetHeader(webhookSignatureHeader),
+	})
 	if err != nil {
-		h.logger.Error("Failed to handle consent callback", zap.Error(err), zap.String("consent_id", req.ConsentID))
+		requestid.Logger(c.Request.Context(), h.logger).Error("Failed to handle consent callback", zap.Error(err), zap.String("consent_id", req.ConsentID))
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to handle callback"})
 		return
 	}
@@ -163,32 +185,101 @@ func (h *AAHandler) ConsentCallback(c *gin.Context) {
 	c.JSON(http.StatusOK, map[string]string{"status": "success"})
 }
 
+// ConsentStatusResponse represents a bank link's consent status
+type ConsentStatusResponse struct {
+	BankLinkID string     `json:"bank_link_id"`
+	ConsentID  string     `json:"consent_id"`
+	Status     string     `json:"status"`
+	ValidTill  *time.Time `json:"valid_till,omitempty"`
+}
+
+// GetConsentStatus returns the consent status of one of the user's bank links
+// @Summary Get consent status
+// @Description Get the consent status of a bank link so the frontend can poll for approval after redirecting the user. Pending and active consents are re-checked with the AA unless refresh=false.
+// @Tags aa
+// @Produce json
+// @Param bankLinkID path string true "Bank link ID"
+// @Param refresh query bool false "Poll the AA before answering (default true)"
+// @Success 200 {object} ConsentStatusResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /aa/consents/{bankLinkID}/status [get]
+func (h *AAHandler) GetConsentStatus(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	bankLinkID, err := uuid.Parse(c.Param("bankLinkID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid bank link ID"})
+		return
+	}
+
+	refresh, err := strconv.ParseBool(c.DefaultQuery("refresh", "true"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid refresh, expected true or false"})
+		return
+	}
+
+	bankLink, err := h.aaService.GetConsentStatus(c.Request.Context(), userID, bankLinkID, refresh)
+	if err != nil {
+		if !errors.Is(err, services.ErrBankLinkNotFound) {
+			requestid.Logger(c.Request.Context(), h.logger).Error("Failed to get consent status", zap.Error(err), zap.String("user_id", userID.String()))
+		}
+		h.writeAAError(c, err, "Failed to get consent status")
+		return
+	}
+
+	c.JSON(http.StatusOK, ConsentStatusResponse{
+		BankLinkID: bankLink.ID.String(),
+		ConsentID:  bankLink.AAConsentID,
+		Status:     bankLink.Status,
+		ValidTill:  bankLink.ValidTill,
+	})
+}
+
 // FetchTransactionsRequest represents a transaction fetch request
 type FetchTransactionsRequest struct {
 	BankLinkID string `json:"bank_link_id" binding:"required"`
 	FromDate   string `json:"from_date" binding:"required"` // ISO date
 	ToDate     string `json:"to_date" binding:"required"`   // ISO date
+	DryRun     bool   `json:"dry_run"`                      // preview without storing transactions
 }
 
 // FetchTransactionsResponse represents a transaction fetch response
 type FetchTransactionsResponse struct {
-	SessionID    string                `json:"session_id"`
-	Status       string                `json:"status"`
-	Processed    bool                  `json:"processed"`
-	Transactions []*domain.Transaction `json:"transactions,omitempty"`
+	SessionID    string                    `json:"session_id"`
+	Status       string                    `json:"status"`
+	Processed    bool                      `json:"processed"`
+	Transactions []*domain.Transaction     `json:"transactions,omitempty"`
+	Chunks       []services.DataFetchChunk `json:"chunks"`
+	DryRun       bool                      `json:"dry_run,omitempty"`
+	Skipped      []*domain.Transaction     `json:"skipped,omitempty"` // dry run only: rows that would be dropped as duplicates
 }
 
 // FetchTransactions fetches transactions for a bank link
 // @Summary Fetch transactions
-// @Description Fetch transactions for a bank link via AA
+// @Description Fetch transactions for a bank link via AA. With dry_run no transactions are stored; the response lists the transactions that would be created and those that would be skipped as duplicates.
+// @Description Dry runs cover this AA fetch only; there is no CSV import to preview. A dry run still opens a data session with the AA provider and records a data_sessions row for each fetch window. Windows whose session isn't READY yet come back without transactions, and their DATA_READY webhook is ignored, so retry the dry run to preview them.
 // @Tags aa
 // @Accept json
 // @Produce json
+// @Param dry_run query bool false "Preview without storing transactions (same as dry_run in the body)"
 // @Param request body FetchTransactionsRequest true "Fetch details"
 // @Success 200 {object} FetchTransactionsResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /aa/fetch [post]
 func (h *AAHandler) FetchTransactions(c *gin.Context) {
@@ -203,6 +294,19 @@ func (h *AAHandler) FetchTransactions(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request data: " + err.Error()})
 		return
 	}
+	if err := validateDateRange(req.FromDate, req.ToDate, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if dryRunParam := c.Query("dry_run"); dryRunParam != "" {
+		dryRun, err := strconv.ParseBool(dryRunParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid dry_run, expected true or false"})
+			return
+		}
+		req.DryRun = req.DryRun || dryRun
+	}
 
 	// Parse bank link ID
 	bankLinkID, err := uuid.Parse(req.BankLinkID)
@@ -212,10 +316,10 @@ func (h *AAHandler) FetchTransactions(c *gin.Context) {
 	}
 
 	// Fetch transactions
-	result, err := h.aaService.FetchTransactions(c.Request.Context(), userID, bankLinkID, req.FromDate, req.ToDate)
+	result, err := h.aaService.FetchTransactions(c.Request.Context(), userID, bankLinkID, req.FromDate, req.ToDate, req.DryRun)
 	if err != nil {
-		h.logger.Error("Failed to fetch transactions", zap.Error(err), zap.String("user_id", userID.String()))
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch transactions"})
+		requestid.Logger(c.Request.Context(), h.logger).Error("Failed to fetch transactions", zap.Error(err), zap.String("user_id", userID.String()))
+		h.writeAAError(c, err, "Failed to fetch transactions")
 		return
 	}
 
@@ -224,22 +328,27 @@ func (h *AAHandler) FetchTransactions(c *gin.Context) {
 		Status:       result.Status,
 		Processed:    result.Processed,
 		Transactions: result.Transactions,
+		Chunks:       result.Chunks,
+		DryRun:       result.DryRun,
+		Skipped:      result.Skipped,
 	})
 }
 
-// DataReadyWebhookRequest represents a data ready webhook request
+// DataReadyWebhookRequest represents an AA webhook request. DATA_READY
+// events carry a session_id and CONSENT_REVOKED events a consent_id.
 type DataReadyWebhookRequest struct {
 	EventType string `json:"event_type" binding:"required"`
-	SessionID string `json:"session_id" binding:"required"`
-	Signature string `json:"signature" binding:"required"`
+	SessionID string `json:"session_id"`
+	ConsentID string `json:"consent_id"`
 }
 
-// DataReadyWebhook handles data ready webhook from AA
-// @Summary Handle data ready webhook
-// @Description Handle data ready webhook from Account Aggregator
+// DataReadyWebhook handles data ready and consent revoked webhooks from AA
+// @Summary Handle AA webhook
+// @Description Handle a webhook from the Account Aggregator. DATA_READY ingests the session's transactions; CONSENT_REVOKED marks the consent's bank link REVOKED so it is no longer fetched.
 // @Tags aa
 // @Accept json
 // @Produce json
+// @Param X-Signature header string true "Signature of the raw request body"
 // @Param request body DataReadyWebhookRequest true "Webhook data"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} ErrorResponse
@@ -250,12 +359,7 @@ func (h *AAHandler) DataReadyWebhook(c *gin.Context) {
 	var req DataReadyWebhookRequest
 	var err error
 
-	if err = c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request data: " + err.Error()})
-		return
-	}
-
-	// Read request body for signature verification
+	// Read the raw body first: the signature covers the exact bytes sent
 	var bodyBytes []byte
 	bodyBytes, err = io.ReadAll(c.Request.Body)
 	if err != nil {
@@ -264,15 +368,46 @@ func (h *AAHandler) DataReadyWebhook(c *gin.Context) {
 	}
 
 	// Verify webhook signature
-	if !h.verifyWebhookSignature(bodyBytes, req.Signature) {
+	if !h.verifyWebhookSignature(bodyBytes, c.GetHeader(webhookSignatureHeader)) {
 		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid signature"})
 		return
 	}
 
-	// Handle data ready webhook
-	err = h.aaService.HandleDataReadyWebhook(c.Request.Context(), req.SessionID)
+	if err = binding.JSON.BindBody(bodyBytes, &req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request data: " + err.Error()})
+		return
+	}
+
+	event := &domain.WebhookEvent{
+		EventType: req.EventType,
+		Payload:   string(bodyBytes),
+		Signature: c.GetHeader(webhookSignatureHeader),
+	}
+	switch req.EventType {
+	case domain.WebhookEventDataReady:
+		if req.SessionID == "" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "session_id is required for DATA_READY"})
+			return
+		}
+		event.SessionID = req.SessionID
+	case domain.WebhookEventConsentRevoked:
+		if req.ConsentID == "" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "consent_id is required for CONSENT_REVOKED"})
+			return
+		}
+		event.ConsentID = req.ConsentID
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Unsupported event_type: " + req.EventType})
+		return
+	}
+
+	// Record and handle the webhook
+	err = h.aaService.HandleWebhookEvent(c.Request.Context(), event)
 	if err != nil {
-		h.logger.Error("Failed to handle data ready webhook", zap.Error(err), zap.String("session_id", req.SessionID))
+		requestid.Logger(c.Request.Context(), h.logger).Error("Failed to handle webhook", zap.Error(err),
+			zap.String("event_type", req.EventType),
+			zap.String("session_id", req.SessionID),
+			zap.String("consent_id", req.ConsentID))
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to handle webhook"})
 		return
 	}
@@ -304,7 +439,7 @@ func (h *AAHandler) GetBankLinks(c *gin.Context) {
 
 	bankLinks, err := h.aaService.GetActiveBankLinks(c.Request.Context(), userID)
 	if err != nil {
-		h.logger.Error("Failed to get bank links", zap.Error(err), zap.String("user_id", userID.String()))
+		requestid.Logger(c.Request.Context(), h.logger).Error("Failed to get bank links", zap.Error(err), zap.String("user_id", userID.String()))
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get bank links"})
 		return
 	}
@@ -327,7 +462,10 @@ type RevokeConsentRequest struct {
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /aa/consents/revoke [post]
 func (h *AAHandler) RevokeConsent(c *gin.Context) {
@@ -353,32 +491,131 @@ func (h *AAHandler) RevokeConsent(c *gin.Context) {
 	// Revoke consent
 	err = h.aaService.RevokeConsent(c.Request.Context(), userID, bankLinkID)
 	if err != nil {
-		h.logger.Error("Failed to revoke consent", zap.Error(err), zap.String("user_id", userID.String()))
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to revoke consent"})
+		requestid.Logger(c.Request.Context(), h.logger).Error("Failed to revoke consent", zap.Error(err), zap.String("user_id", userID.String()))
+		h.writeAAError(c, err, "Failed to revoke consent")
 		return
 	}
 
 	c.JSON(http.StatusOK, map[string]string{"status": "success"})
 }
 
-// verifyWebhookSignature verifies webhook signature
+// RenormalizeTransactions re-runs normalization over the user's transactions
+// @Summary Renormalize transactions
+// @Description Re-run merchant extraction, categorization and the user's category overrides over all stored transactions, updating the rows that changed
+// @Tags transactions
+// @Produce json
+// @Success 200 {object} services.RenormalizeResult
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /transactions/renormalize [post]
+func (h *AAHandler) RenormalizeTransactions(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	result, err := h.aaService.RenormalizeTransactions(c.Request.Context(), userID)
+	if err != nil {
+		requestid.Logger(c.Request.Context(), h.logger).Error("Failed to renormalize transactions", zap.Error(err), zap.String("user_id", userID.String()))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to renormalize transactions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// RefreshBankLink fetches recent transactions of one bank link on demand
+// @Summary Refresh bank link
+// @Description Fetch the last few days (aa.refresh_window_days) of one of the user's bank links and store the new transactions; ones already stored are skipped. Each link can be refreshed once per aa.refresh_cooldown, and a 429 carries a Retry-After header. When the AA has not prepared the data yet processed is false and the transactions arrive through its webhook.
+// @Tags aa
+// @Produce json
+// @Param id path string true "Bank link ID"
+// @Success 200 {object} services.RefreshResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /aa/bank-links/{id}/refresh [post]
+func (h *AAHandler) RefreshBankLink(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	bankLinkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid bank link ID"})
+		return
+	}
+
+	result, err := h.aaService.RefreshBankLink(c.Request.Context(), userID, bankLinkID)
+	if err != nil {
+		var cooldown *services.RefreshCooldownError
+		if errors.As(err, &cooldown) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(cooldown.RetryAfter.Seconds()))))
+		} else {
+			requestid.Logger(c.Request.Context(), h.logger).Error("Failed to refresh bank link", zap.Error(err), zap.String("bank_link_id", bankLinkID.String()))
+		}
+		h.writeAAError(c, err, "Failed to refresh bank link")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// aaErrorStatus maps an AA service error onto an HTTP status and a message
+// safe to return to the client. ok is false for unexpected errors, which
+// are reported as 500s.
+func aaErrorStatus(err error) (status int, message string, ok bool) {
+	switch {
+	case errors.Is(err, services.ErrBankLinkNotFound):
+		return http.StatusNotFound, "Bank link not found", true
+	case errors.Is(err, services.ErrUnauthorized):
+		// 403 rather than 401: the caller is authenticated, and the frontend
+		// treats 401 as an expired session
+		return http.StatusForbidden, "Bank link belongs to another user", true
+	case errors.Is(err, services.ErrConsentNotActive):
+		return http.StatusForbidden, "Consent is not active", true
+	case errors.Is(err, services.ErrRefreshCooldown):
+		return http.StatusTooManyRequests, "Bank link was refreshed recently, try again later", true
+	case errors.Is(err, services.ErrProviderUnavailable):
+		return http.StatusBadGateway, "Account aggregator is unavailable, try again later", true
+	}
+	return http.StatusInternalServerError, "", false
+}
+
+// writeAAError responds with the status mapped from err, falling back to a
+// 500 carrying fallback for unexpected errors
+func (h *AAHandler) writeAAError(c *gin.Context, err error, fallback string) {
+	status, message, ok := aaErrorStatus(err)
+	if !ok {
+		message = fallback
+	}
+	c.JSON(status, ErrorResponse{Error: message})
+}
+
+// verifyWebhookSignature checks the raw webhook body against its signature
+// through the AA client, which uses the configured scheme (HMAC with the
+// webhook secret, or RSA)
 func (h *AAHandler) verifyWebhookSignature(payload []byte, signature string) bool {
-	// In production, implement proper HMAC verification
-	// For now, return true for mock implementation
-	// TODO: Implement proper signature verification using h.config.Webhook.Secret
-	_ = payload   // Use payload parameter
-	_ = signature // Use signature parameter
-	return true
+	return h.aaService.VerifyWebhookSignature(payload, signature)
 }
 
-// getUserIDFromContext extracts user ID from JWT context
+// getUserIDFromContext extracts the UUID subject set by the shared auth middleware
 func getUserIDFromContext(c *gin.Context) uuid.UUID {
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
+	userIDStr := c.GetString(middleware.ContextUserUUID)
+	if userIDStr == "" {
 		return uuid.Nil
 	}
 
-	userID, err := uuid.Parse(userIDStr.(string))
+	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
 		return uuid.Nil
 	}
@@ -0,0 +1,517 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/internal/core/ports"
+	"github.com/your-github/expense-tracker-backend/internal/core/services"
+	"github.com/your-github/expense-tracker-backend/internal/repo"
+	"github.com/your-github/expense-tracker-backend/middleware"
+	"github.com/your-github/expense-tracker-backend/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const testWebhookSecret = "test-webhook-secret"
+
+// fakeBankLinks keeps bank links in memory; methods the webhooks don't
+// use panic through the embedded nil interface
+type fakeBankLinks struct {
+	repo.BankLinkRepository
+	mu    sync.Mutex
+	links map[uuid.UUID]*domain.BankLink
+}
+
+func (r *fakeBankLinks) GetByID(ctx context.Context, id uuid.UUID) (*domain.BankLink, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	link, ok := r.links[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *link
+	return &copied, nil
+}
+
+func (r *fakeBankLinks) GetByConsentID(ctx context.Context, consentID string) (*domain.BankLink, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, link := range r.links {
+		if link.AAConsentID == consentID {
+			copied := *link
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeBankLinks) Update(ctx context.Context, bankLink *domain.BankLink) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *bankLink
+	r.links[bankLink.ID] = &copied
+	return nil
+}
+
+func (r *fakeBankLinks) UpdateStatus(ctx context.Context, id uuid.UUID, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.links[id].Status = status
+	return nil
+}
+
+func (r *fakeBankLinks) ClaimRefresh(ctx context.Context, id uuid.UUID, now, notAfter time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	link := r.links[id]
+	if link.LastRefreshedAt != nil && link.LastRefreshedAt.After(notAfter) {
+		return false, nil
+	}
+	link.LastRefreshedAt = &now
+	return true, nil
+}
+
+type fakeWebhookEvents struct {
+	repo.WebhookEventRepository
+	mu     sync.Mutex
+	events []*domain.WebhookEvent
+}
+
+func (r *fakeWebhookEvents) Create(ctx context.Context, event *domain.WebhookEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event.ID = uuid.New()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *fakeWebhookEvents) RecordResult(ctx context.Context, event *domain.WebhookEvent) error {
+	return nil
+}
+
+func (r *fakeWebhookEvents) GetByID(ctx context.Context, id uuid.UUID) (*domain.WebhookEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, event := range r.events {
+		if event.ID == id {
+			copied := *event
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeWebhookEvents) List(ctx context.Context, status string, limit, offset int) ([]*domain.WebhookEvent, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var matched []*domain.WebhookEvent
+	for _, event := range r.events {
+		if status == "" || event.Status == status {
+			matched = append(matched, event)
+		}
+	}
+	return matched, int64(len(matched)), nil
+}
+
+// newWebhookRouter serves the public AA webhooks and the admin webhook
+// event endpoints over in-memory bank links and events, with an HMAC
+// webhook signer
+func newWebhookRouter(links *fakeBankLinks, events *fakeWebhookEvents) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	client := services.NewMockAAClient()
+	client.WebhookSigner = services.NewHMACSigner(testWebhookSecret)
+	repositories := &repo.Repositories{BankLink: links, WebhookEvent: events}
+	service := services.NewAAService(client, repositories, services.NewNormalizer(), services.NewDeduplicator(), zap.NewNop())
+	handler := NewAAHandler(service, repositories, nil, zap.NewNop())
+
+	router := gin.New()
+	router.POST("/api/v1/aa/consents/callback", handler.ConsentCallback)
+	router.POST("/api/v1/aa/webhook", handler.DataReadyWebhook)
+	router.GET("/api/v1/admin/webhook-events", handler.ListWebhookEvents)
+	router.POST("/api/v1/admin/webhook-events/:id/replay", handler.ReplayWebhookEvent)
+	return router
+}
+
+func postSigned(t *testing.T, router *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	signature, err := services.NewHMACSigner(testWebhookSecret).Sign([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookSignatureHeader, signature)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestConsentRevokedWebhookThenLateActiveCallback(t *testing.T) {
+	link := &domain.BankLink{ID: uuid.New(), UserID: uuid.New(), AAConsentID: "consent-1", Status: "ACTIVE"}
+	links := &fakeBankLinks{links: map[uuid.UUID]*domain.BankLink{link.ID: link}}
+	router := newWebhookRouter(links, &fakeWebhookEvents{})
+
+	w := postSigned(t, router, "/api/v1/aa/webhook", `{"event_type":"CONSENT_REVOKED","consent_id":"consent-1"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("revoke webhook: status %d, body %s", w.Code, w.Body)
+	}
+
+	w = postSigned(t, router, "/api/v1/aa/consents/callback", `{"consent_id":"consent-1","status":"ACTIVE"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("late callback: status %d, body %s", w.Code, w.Body)
+	}
+
+	if got := links.links[link.ID].Status; got != "REVOKED" {
+		t.Errorf("status = %s, want REVOKED", got)
+	}
+}
+
+func TestDataReadyWebhookValidatesEventFields(t *testing.T) {
+	router := newWebhookRouter(&fakeBankLinks{links: map[uuid.UUID]*domain.BankLink{}}, &fakeWebhookEvents{})
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"data ready without session", `{"event_type":"DATA_READY"}`, http.StatusBadRequest},
+		{"revoke without consent", `{"event_type":"CONSENT_REVOKED"}`, http.StatusBadRequest},
+		{"unsupported event", `{"event_type":"SOMETHING_ELSE","consent_id":"c"}`, http.StatusBadRequest},
+		{"revoke of an unknown consent", `{"event_type":"CONSENT_REVOKED","consent_id":"missing"}`, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := postSigned(t, router, "/api/v1/aa/webhook", tt.body); w.Code != tt.want {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/aa/webhook", strings.NewReader(`{"event_type":"CONSENT_REVOKED","consent_id":"c"}`))
+	req.Header.Set(webhookSignatureHeader, "bad")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("unsigned webhook: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestWebhooksAreRecordedWithTheirOutcome(t *testing.T) {
+	link := &domain.BankLink{ID: uuid.New(), UserID: uuid.New(), AAConsentID: "consent-1", Status: "ACTIVE"}
+	events := &fakeWebhookEvents{}
+	router := newWebhookRouter(&fakeBankLinks{links: map[uuid.UUID]*domain.BankLink{link.ID: link}}, events)
+
+	const body = `{"event_type":"CONSENT_REVOKED","consent_id":"consent-1"}`
+	if w := postSigned(t, router, "/api/v1/aa/webhook", body); w.Code != http.StatusOK {
+		t.Fatalf("revoke webhook: status %d, body %s", w.Code, w.Body)
+	}
+	if w := postSigned(t, router, "/api/v1/aa/webhook", `{"event_type":"CONSENT_REVOKED","consent_id":"missing"}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("unknown consent: status %d, body %s", w.Code, w.Body)
+	}
+
+	if len(events.events) != 2 {
+		t.Fatalf("recorded %d events, want 2", len(events.events))
+	}
+	signature, _ := services.NewHMACSigner(testWebhookSecret).Sign([]byte(body))
+	ok := events.events[0]
+	if ok.EventType != domain.WebhookEventConsentRevoked || ok.ConsentID != "consent-1" || ok.Payload != body || ok.Signature != signature {
+		t.Errorf("recorded %+v, want the raw payload and signature", ok)
+	}
+	if ok.Status != domain.WebhookEventProcessed || ok.Attempts != 1 || ok.ProcessedAt == nil {
+		t.Errorf("handled event = %s after %d attempts", ok.Status, ok.Attempts)
+	}
+	if failed := events.events[1]; failed.Status != domain.WebhookEventFailed || failed.Error == "" {
+		t.Errorf("failed event = %s with error %q, want FAILED with the error", failed.Status, failed.Error)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/webhook-events?status=FAILED", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"total":1`) || !strings.Contains(w.Body.String(), `"consent_id":"missing"`) {
+		t.Errorf("failed events = %d %s", w.Code, w.Body)
+	}
+}
+
+func TestWebhookEventAdminEndpointsValidateInput(t *testing.T) {
+	router := newWebhookRouter(&fakeBankLinks{links: map[uuid.UUID]*domain.BankLink{}}, &fakeWebhookEvents{})
+
+	tests := []struct {
+		method, target string
+		want           int
+	}{
+		{http.MethodGet, "/api/v1/admin/webhook-events?status=DONE", http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/admin/webhook-events?limit=0", http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/admin/webhook-events?limit=201", http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/admin/webhook-events?offset=-1", http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/admin/webhook-events", http.StatusOK},
+		{http.MethodPost, "/api/v1/admin/webhook-events/not-a-uuid/replay", http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/admin/webhook-events/" + uuid.NewString() + "/replay", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s %s = %d, want %d (body %s)", tt.method, tt.target, w.Code, tt.want, w.Body)
+		}
+	}
+}
+
+func TestGetConsentStatusFollowsApproval(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client := services.NewMockAAClient()
+	handle, err := client.CreateConsent(ports.ConsentRequest{UserID: uuid.NewString(), FIType: "SAVINGS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := uuid.New()
+	link := &domain.BankLink{ID: uuid.New(), UserID: owner, AAConsentID: handle.ConsentID, Status: "PENDING"}
+	repositories := &repo.Repositories{BankLink: &fakeBankLinks{links: map[uuid.UUID]*domain.BankLink{link.ID: link}}}
+	service := services.NewAAService(client, repositories, services.NewNormalizer(), services.NewDeduplicator(), zap.NewNop())
+	handler := NewAAHandler(service, repositories, nil, zap.NewNop())
+
+	getStatus := func(userID uuid.UUID, target string) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.GET("/api/v1/aa/consents/:bankLinkID/status", func(c *gin.Context) {
+			c.Set(middleware.ContextUserUUID, userID.String())
+		}, handler.GetConsentStatus)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+	target := "/api/v1/aa/consents/" + link.ID.String() + "/status"
+
+	if w := getStatus(owner, target); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"status":"PENDING"`) {
+		t.Fatalf("before approval = %d %s", w.Code, w.Body)
+	}
+	if err := client.SimulateConsentApproval(handle.ConsentID); err != nil {
+		t.Fatal(err)
+	}
+	if w := getStatus(owner, target+"?refresh=false"); !strings.Contains(w.Body.String(), `"status":"PENDING"`) {
+		t.Errorf("without refresh = %d %s, want the stored status", w.Code, w.Body)
+	}
+	w := getStatus(owner, target)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"status":"ACTIVE"`) || !strings.Contains(w.Body.String(), `"valid_till"`) {
+		t.Fatalf("after approval = %d %s, want ACTIVE", w.Code, w.Body)
+	}
+
+	tests := []struct {
+		name   string
+		userID uuid.UUID
+		target string
+		want   int
+	}{
+		{"another user's link", uuid.New(), target, http.StatusNotFound},
+		{"unknown link", owner, "/api/v1/aa/consents/" + uuid.NewString() + "/status", http.StatusNotFound},
+		{"invalid link ID", owner, "/api/v1/aa/consents/nope/status", http.StatusBadRequest},
+		{"invalid refresh", owner, target + "?refresh=maybe", http.StatusBadRequest},
+		{"no user", uuid.Nil, target, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := getStatus(tt.userID, tt.target); w.Code != tt.want {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestGetUserIDFromContextAcrossStacks(t *testing.T) {
+	const secret = "test-jwt-secret"
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/me", middleware.Auth(secret, middleware.UUIDSubject), func(c *gin.Context) {
+		c.String(http.StatusOK, getUserIDFromContext(c).String())
+	})
+
+	userID := uuid.New()
+	internalToken, err := utils.NewToken(userID.String(), "asha@example.com", utils.RoleUser, time.Hour, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyToken, err := utils.GenerateToken(42, utils.RoleUser, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		token string
+		code  int
+		want  string
+	}{
+		{internalToken, http.StatusOK, userID.String()},
+		// A legacy numeric subject has no AA account; it's turned away
+		// before a handler could mistake it for one
+		{legacyToken, http.StatusUnauthorized, "other API"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code || !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("status %d, body %q, want %d with %q", w.Code, w.Body.String(), tt.code, tt.want)
+		}
+	}
+}
+
+// unreachableAAClient fails every data session as an unreachable provider would
+type unreachableAAClient struct {
+	*services.MockAAClient
+}
+
+func (c unreachableAAClient) CreateDataSession(consentID, fromISO, toISO string) (ports.DataSession, error) {
+	return ports.DataSession{}, fmt.Errorf("%w: dial tcp: connection refused", services.ErrProviderUnavailable)
+}
+
+func TestFetchTransactionsMapsServiceErrorsToStatuses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mock := services.NewMockAAClient()
+	// Approved by the user but not yet active at the AA
+	handle, err := mock.CreateConsent(ports.ConsentRequest{UserID: uuid.NewString(), FIType: "SAVINGS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := uuid.New()
+	active := &domain.BankLink{ID: uuid.New(), UserID: owner, AAConsentID: handle.ConsentID, Status: "ACTIVE"}
+	revoked := &domain.BankLink{ID: uuid.New(), UserID: owner, AAConsentID: "consent-revoked", Status: "REVOKED"}
+	links := &fakeBankLinks{links: map[uuid.UUID]*domain.BankLink{active.ID: active, revoked.ID: revoked}}
+	repositories := &repo.Repositories{BankLink: links}
+
+	fetch := func(client ports.AAClient, userID, bankLinkID uuid.UUID) *httptest.ResponseRecorder {
+		service := services.NewAAService(client, repositories, services.NewNormalizer(), services.NewDeduplicator(), zap.NewNop())
+		handler := NewAAHandler(service, repositories, nil, zap.NewNop())
+		router := gin.New()
+		router.POST("/api/v1/aa/fetch", asUser(userID), handler.FetchTransactions)
+		body := `{"bank_link_id":"` + bankLinkID.String() + `","from_date":"2025-01-01","to_date":"2025-01-02"}`
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/aa/fetch", strings.NewReader(body)))
+		return w
+	}
+
+	tests := []struct {
+		name       string
+		client     ports.AAClient
+		userID     uuid.UUID
+		bankLinkID uuid.UUID
+		want       int
+	}{
+		{"unknown link", mock, owner, uuid.New(), http.StatusNotFound},
+		{"another user's link", mock, uuid.New(), active.ID, http.StatusForbidden},
+		{"revoked link", mock, owner, revoked.ID, http.StatusForbidden},
+		{"consent not active at the AA", mock, owner, active.ID, http.StatusForbidden},
+		{"provider unreachable", unreachableAAClient{mock}, owner, active.ID, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := fetch(tt.client, tt.userID, tt.bankLinkID)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tt.want, w.Body)
+			}
+			if strings.Contains(w.Body.String(), "connection refused") {
+				t.Errorf("body leaks the underlying error: %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestAAErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{services.ErrBankLinkNotFound, http.StatusNotFound},
+		{services.ErrUnauthorized, http.StatusForbidden},
+		{fmt.Errorf("%w: PENDING", services.ErrConsentNotActive), http.StatusForbidden},
+		{fmt.Errorf("failed to create data session: %w", services.ErrProviderUnavailable), http.StatusBadGateway},
+		{errors.New("database is down"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		status, message, ok := aaErrorStatus(tt.err)
+		if status != tt.want {
+			t.Errorf("aaErrorStatus(%v) = %d, want %d", tt.err, status, tt.want)
+		}
+		if ok != (tt.want != http.StatusInternalServerError) || (ok && message == "") {
+			t.Errorf("aaErrorStatus(%v) = %q, %v", tt.err, message, ok)
+		}
+	}
+}
+
+func TestHandlerVerifiesWebhooksSignedByTheMock(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	link := &domain.BankLink{ID: uuid.New(), UserID: uuid.New(), AAConsentID: "consent-1", Status: "ACTIVE"}
+	links := &fakeBankLinks{links: map[uuid.UUID]*domain.BankLink{link.ID: link}}
+	repositories := &repo.Repositories{BankLink: links, WebhookEvent: &fakeWebhookEvents{}}
+	client := services.NewMockAAClient()
+	service := services.NewAAService(client, repositories, services.NewNormalizer(), services.NewDeduplicator(), zap.NewNop())
+	router := gin.New()
+	router.POST("/api/v1/aa/webhook", NewAAHandler(service, repositories, nil, zap.NewNop()).DataReadyWebhook)
+
+	body := `{"event_type":"CONSENT_REVOKED","consent_id":"consent-1"}`
+	post := func(signature string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/aa/webhook", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(webhookSignatureHeader, signature)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// In mock mode the client has no signer until one is configured
+	if code := post(client.GenerateSignature([]byte(body), testWebhookSecret)); code != http.StatusUnauthorized {
+		t.Errorf("without a signer = %d, want 401", code)
+	}
+	client.WebhookSigner = services.NewHMACSigner(testWebhookSecret)
+	if code := post(client.GenerateSignature([]byte(body), "another-secret")); code != http.StatusUnauthorized {
+		t.Errorf("signed with another secret = %d, want 401", code)
+	}
+	if got := links.links[link.ID].Status; got != "ACTIVE" {
+		t.Fatalf("a rejected webhook changed the link to %s", got)
+	}
+	if code := post(client.GenerateSignature([]byte(body), testWebhookSecret)); code != http.StatusOK {
+		t.Errorf("signed by the mock = %d, want 200", code)
+	}
+	if got := links.links[link.ID].Status; got != "REVOKED" {
+		t.Errorf("status = %s, want the verified webhook applied", got)
+	}
+}
+
+func TestRefreshBankLinkCooldownResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID := uuid.New()
+	refreshed := time.Now().Add(-5 * time.Minute)
+	link := &domain.BankLink{ID: uuid.New(), UserID: userID, AAConsentID: "consent-1", Status: "ACTIVE", LastRefreshedAt: &refreshed}
+	links := &fakeBankLinks{links: map[uuid.UUID]*domain.BankLink{link.ID: link}}
+	repositories := &repo.Repositories{BankLink: links}
+	service := services.NewAAService(services.NewMockAAClient(), repositories, services.NewNormalizer(), services.NewDeduplicator(), zap.NewNop())
+	handler := NewAAHandler(service, repositories, nil, zap.NewNop())
+	router := gin.New()
+	router.POST("/aa/bank-links/:id/refresh", asUser(userID), handler.RefreshBankLink)
+	refresh := func(id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/aa/bank-links/"+id+"/refresh", nil))
+		return w
+	}
+
+	// Refreshed five minutes into the default fifteen-minute cooldown
+	w := refresh(link.ID.String())
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("refresh during the cooldown = %d %s, want 429", w.Code, w.Body)
+	}
+	if retry, _ := strconv.Atoi(w.Header().Get("Retry-After")); retry < 595 || retry > 600 {
+		t.Errorf("Retry-After = %q, want about 600 seconds", w.Header().Get("Retry-After"))
+	}
+	if w := refresh("not-a-uuid"); w.Code != http.StatusBadRequest {
+		t.Errorf("bad ID = %d, want 400", w.Code)
+	}
+}
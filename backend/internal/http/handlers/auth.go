@@ -5,13 +5,12 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/your-github/expense-tracker-backend/config"
 	"github.com/your-github/expense-tracker-backend/internal/core/domain"
 	"github.com/your-github/expense-tracker-backend/internal/repo"
+	"github.com/your-github/expense-tracker-backend/utils"
 )
 
 // AuthHandler handles authentication requests
@@ -91,7 +90,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	// Generate JWT token
-	token, err := h.generateJWT(user.ID, user.Email)
+	token, err := h.generateJWT(user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to generate token"})
 		return
@@ -136,7 +135,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	// Generate JWT token
-	token, err := h.generateJWT(user.ID, user.Email)
+	token, err := h.generateJWT(user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to generate token"})
 		return
@@ -148,17 +147,14 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
-// generateJWT generates a JWT token for the user
-func (h *AuthHandler) generateJWT(userID uuid.UUID, email string) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID.String(),
-		"email":   email,
-		"exp":     time.Now().Add(time.Hour * 24 * 7).Unix(), // 7 days
-		"iat":     time.Now().Unix(),
+// generateJWT generates a JWT token for the user using the shared claim format
+// and the user's stored role
+func (h *AuthHandler) generateJWT(user *domain.User) (string, error) {
+	role := user.Role
+	if role == "" {
+		role = utils.RoleUser
 	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(h.config.JWT.Secret))
+	return utils.NewToken(user.ID.String(), user.Email, role, 7*24*time.Hour, h.config.JWT.Secret) // 7 days
 }
 
 // ErrorResponse represents an error response
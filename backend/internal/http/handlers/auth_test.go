@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/config"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/internal/repo"
+	"github.com/your-github/expense-tracker-backend/utils"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// usersByEmail serves a single user looked up by email
+type usersByEmail struct {
+	repo.UserRepository
+	user *domain.User
+}
+
+func (r usersByEmail) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	if r.user.Email == email {
+		return r.user, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestLoginIssuesTheStoredRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	cfg.JWT.Secret = "test-secret"
+
+	for _, role := range []string{utils.RoleAdmin, utils.RoleUser, ""} {
+		user := &domain.User{ID: uuid.New(), Email: "asha@example.com", PasswordHash: string(hash), Role: role}
+		handler := NewAuthHandler(&repo.Repositories{User: usersByEmail{user: user}}, cfg)
+		r := gin.New()
+		r.POST("/auth/login", handler.Login)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"asha@example.com","password":"secret123"}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("role %q: login = %d %s", role, w.Code, w.Body)
+		}
+
+		var body AuthResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		claims, err := utils.ParseToken(body.Token, cfg.JWT.Secret)
+		if err != nil {
+			t.Fatalf("role %q: %v", role, err)
+		}
+		want := role
+		if want == "" {
+			want = utils.RoleUser
+		}
+		if claims.Role != want || claims.UserID != user.ID.String() {
+			t.Errorf("role %q: token claims %+v, want role %q for %s", role, claims, want, user.ID)
+		}
+	}
+}
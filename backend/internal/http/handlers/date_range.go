@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+)
+
+// isoDate is the only date format the AA endpoints accept
+const isoDate = "2006-01-02"
+
+// maxAADateRangeDays caps how much history a single consent or fetch may
+// cover; providers reject or time out on longer ranges
+const maxAADateRangeDays = 730
+
+// validateDateRange checks an AA from/to pair: both must be YYYY-MM-DD, from
+// must not be after to, to must not be in the future and the range may span
+// at most maxAADateRangeDays. The returned error is safe to show to clients.
+func validateDateRange(from, to string, now time.Time) error {
+	fromDate, err := time.Parse(isoDate, from)
+	if err != nil {
+		return fmt.Errorf("invalid from date %q, expected YYYY-MM-DD", from)
+	}
+	toDate, err := time.Parse(isoDate, to)
+	if err != nil {
+		return fmt.Errorf("invalid to date %q, expected YYYY-MM-DD", to)
+	}
+
+	if fromDate.After(toDate) {
+		return fmt.Errorf("from date %s is after to date %s", from, to)
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if toDate.After(today) {
+		return fmt.Errorf("to date %s is in the future", to)
+	}
+
+	if days := int(toDate.Sub(fromDate).Hours() / 24); days > maxAADateRangeDays {
+		return fmt.Errorf("date range spans %d days, at most %d are allowed", days, maxAADateRangeDays)
+	}
+	return nil
+}
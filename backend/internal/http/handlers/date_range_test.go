@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func TestValidateDateRange(t *testing.T) {
+	now := time.Date(2025, time.June, 15, 18, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name, from, to string
+		wantErr        string
+	}{
+		{"valid", "2025-01-01", "2025-06-01", ""},
+		{"single day", "2025-06-15", "2025-06-15", ""},
+		{"exactly the maximum span", "2023-06-16", "2025-06-15", ""},
+		{"reversed", "2025-06-01", "2025-01-01", "is after to date"},
+		{"malformed from", "01/01/2025", "2025-06-01", "invalid from date"},
+		{"malformed to", "2025-01-01", "2025-6-1", "invalid to date"},
+		{"timestamp instead of date", "2025-01-01T00:00:00Z", "2025-06-01", "invalid from date"},
+		{"impossible date", "2025-02-30", "2025-06-01", "invalid from date"},
+		{"over-long", "2020-01-01", "2025-06-01", "at most 730"},
+		{"ends in the future", "2025-06-01", "2025-06-16", "in the future"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDateRange(tt.from, tt.to, now)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("got %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want an error mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAAHandlersRejectBadDateRanges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// No service: a rejected range must never reach the AA client
+	handler := NewAAHandler(nil, nil, nil, zap.NewNop())
+	router := gin.New()
+	router.Use(asUser(uuid.New()))
+	router.POST("/consents/initiate", handler.InitiateConsent)
+	router.POST("/fetch", handler.FetchTransactions)
+
+	tests := []struct {
+		name, path, body, wantErr string
+	}{
+		{"reversed consent range", "/consents/initiate",
+			`{"fi_type":"SAVINGS","purpose":"EXPENSE_ANALYSIS","frequency":"DAILY","date_range":{"from":"2025-06-01","to":"2025-01-01"}}`,
+			"is after to date"},
+		{"malformed consent range", "/consents/initiate",
+			`{"fi_type":"SAVINGS","purpose":"EXPENSE_ANALYSIS","frequency":"DAILY","date_range":{"from":"yesterday","to":"2025-01-01"}}`,
+			"invalid from date"},
+		{"over-long fetch", "/fetch",
+			`{"bank_link_id":"` + uuid.NewString() + `","from_date":"2015-01-01","to_date":"2025-01-01"}`,
+			"at most 730"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tt.wantErr) {
+				t.Errorf("got %d %s, want 400 mentioning %q", w.Code, w.Body, tt.wantErr)
+			}
+		})
+	}
+}
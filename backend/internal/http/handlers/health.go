@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/robfig/cron/v3"
+	"github.com/your-github/expense-tracker-backend/internal/repo"
+	"go.uber.org/zap"
+)
+
+// readinessPingTimeout bounds the database ping so probes answer quickly
+const readinessPingTimeout = 2 * time.Second
+
+// HealthHandler serves liveness and readiness probes
+type HealthHandler struct {
+	repositories *repo.Repositories
+	cron         *cron.Cron
+	logger       *zap.Logger
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(repositories *repo.Repositories, cron *cron.Cron, logger *zap.Logger) *HealthHandler {
+	return &HealthHandler{
+		repositories: repositories,
+		cron:         cron,
+		logger:       logger,
+	}
+}
+
+// ReadinessResponse reports the state of the service's dependencies
+type ReadinessResponse struct {
+	Status    string                 `json:"status"`
+	Timestamp int64                  `json:"timestamp"`
+	Checks    map[string]interface{} `json:"checks"`
+}
+
+// Live reports that the process is up
+// @Summary Liveness probe
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /health [get]
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "timestamp": time.Now().Unix()})
+}
+
+// Ready reports whether the service can reach its database
+// @Summary Readiness probe
+// @Description Pings the database and reports scheduler state. Returns 503 when the database is unreachable.
+// @Tags health
+// @Produce json
+// @Success 200 {object} ReadinessResponse
+// @Failure 503 {object} ReadinessResponse
+// @Router /health/ready [get]
+func (h *HealthHandler) Ready(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessPingTimeout)
+	defer cancel()
+
+	resp := ReadinessResponse{Status: "ready", Timestamp: time.Now().Unix(), Checks: map[string]interface{}{}}
+	status := http.StatusOK
+
+	start := time.Now()
+	database := gin.H{"status": "ok"}
+	if err := h.repositories.Ping(ctx); err != nil {
+		h.logger.Warn("Readiness check failed to reach database", zap.Error(err))
+		database = gin.H{"status": "error", "error": err.Error()}
+		resp.Status = "unavailable"
+		status = http.StatusServiceUnavailable
+	}
+	database["latency_ms"] = time.Since(start).Milliseconds()
+	resp.Checks["database"] = database
+
+	scheduler := gin.H{"status": "disabled"}
+	if h.cron != nil {
+		entries := h.cron.Entries()
+		scheduler = gin.H{"status": "ok", "jobs": len(entries)}
+		if len(entries) > 0 {
+			scheduler["next_run"] = entries[0].Next
+		}
+	}
+	resp.Checks["cron"] = scheduler
+
+	c.JSON(status, resp)
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/internal/repo"
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+	"go.uber.org/zap"
+)
+
+func TestReadinessProbe(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _ := testutil.NewStubDB(t)
+	h := NewHealthHandler(repo.NewRepositories(db), nil, zap.NewNop())
+	r := gin.New()
+	r.GET("/health", h.Live)
+	r.GET("/health/ready", h.Ready)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	if w := serve("/health/ready"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"status":"ready"`) {
+		t.Errorf("ready with a reachable database = %d %s", w.Code, w.Body)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+	if w := serve("/health/ready"); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), `"status":"unavailable"`) {
+		t.Errorf("ready with a closed pool = %d %s, want 503", w.Code, w.Body)
+	}
+	if w := serve("/health"); w.Code != http.StatusOK {
+		t.Errorf("live with a closed pool = %d, want 200", w.Code)
+	}
+}
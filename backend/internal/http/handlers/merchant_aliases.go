@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/internal/core/services"
+	"github.com/your-github/expense-tracker-backend/requestid"
+	"go.uber.org/zap"
+)
+
+// MerchantAliasRequest represents a request to add a merchant alias
+type MerchantAliasRequest struct {
+	Pattern  string `json:"pattern" binding:"required" example:"/^AMZN MKTP/"`
+	Merchant string `json:"merchant" binding:"required" example:"AMAZON"`
+}
+
+// MerchantAliasListResponse represents a list of merchant aliases
+type MerchantAliasListResponse struct {
+	Aliases []*domain.MerchantAlias `json:"aliases"`
+}
+
+// ListMerchantAliases returns the aliases applied to the user's transactions
+// @Summary List merchant aliases
+// @Description List the merchant aliases applied to the user's transactions: the user's own (tried first) followed by the global ones, which have no user_id
+// @Tags merchant-aliases
+// @Produce json
+// @Success 200 {object} MerchantAliasListResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /me/merchant-aliases [get]
+func (h *AAHandler) ListMerchantAliases(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	aliases, err := h.aaService.ListMerchantAliases(c.Request.Context(), userID)
+	if err != nil {
+		requestid.Logger(c.Request.Context(), h.logger).Error("Failed to list merchant aliases", zap.Error(err), zap.String("user_id", userID.String()))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to list merchant aliases"})
+		return
+	}
+
+	c.JSON(http.StatusOK, MerchantAliasListResponse{Aliases: aliases})
+}
+
+// CreateMerchantAlias adds an alias for the user's transactions
+// @Summary Create merchant alias
+// @Description Attribute transactions whose description matches pattern ("/regex/" or a case-insensitive substring) to merchant. Applies to new imports; call POST /transactions/renormalize to update stored transactions.
+// @Tags merchant-aliases
+// @Accept json
+// @Produce json
+// @Param request body MerchantAliasRequest true "Alias"
+// @Success 201 {object} domain.MerchantAlias
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /me/merchant-aliases [post]
+func (h *AAHandler) CreateMerchantAlias(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+	h.createMerchantAlias(c, &userID)
+}
+
+// DeleteMerchantAlias removes one of the user's aliases
+// @Summary Delete merchant alias
+// @Description Delete one of the user's own merchant aliases. Global aliases can only be deleted by an admin.
+// @Tags merchant-aliases
+// @Produce json
+// @Param id path string true "Merchant alias ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /me/merchant-aliases/{id} [delete]
+func (h *AAHandler) DeleteMerchantAlias(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+	h.deleteMerchantAlias(c, &userID)
+}
+
+// ListGlobalMerchantAliases returns the aliases applied to every user
+// @Summary List global merchant aliases
+// @Description List the merchant aliases applied to every user's transactions. Admin only.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} MerchantAliasListResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/merchant-aliases [get]
+func (h *AAHandler) ListGlobalMerchantAliases(c *gin.Context) {
+	aliases, err := h.aaService.ListGlobalMerchantAliases(c.Request.Context())
+	if err != nil {
+		requestid.Logger(c.Request.Context(), h.logger).Error("Failed to list global merchant aliases", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to list merchant aliases"})
+		return
+	}
+
+	c.JSON(http.StatusOK, MerchantAliasListResponse{Aliases: aliases})
+}
+
+// CreateGlobalMerchantAlias adds an alias applied to every user
+// @Summary Create global merchant alias
+// @Description Add a merchant alias applied to every user's transactions. A user's own aliases take precedence. Admin only.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body MerchantAliasRequest true "Alias"
+// @Success 201 {object} domain.MerchantAlias
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/merchant-aliases [post]
+func (h *AAHandler) CreateGlobalMerchantAlias(c *gin.Context) {
+	h.createMerchantAlias(c, nil)
+}
+
+// DeleteGlobalMerchantAlias removes a global alias
+// @Summary Delete global merchant alias
+// @Description Delete a merchant alias applied to every user. Admin only.
+// @Tags admin
+// @Produce json
+// @Param id path string true "Merchant alias ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/merchant-aliases/{id} [delete]
+func (h *AAHandler) DeleteGlobalMerchantAlias(c *gin.Context) {
+	h.deleteMerchantAlias(c, nil)
+}
+
+// createMerchantAlias stores an alias owned by userID, global when nil
+func (h *AAHandler) createMerchantAlias(c *gin.Context, userID *uuid.UUID) {
+	var req MerchantAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request data: " + err.Error()})
+		return
+	}
+
+	alias, err := h.aaService.CreateMerchantAlias(c.Request.Context(), userID, req.Pattern, req.Merchant)
+	if errors.Is(err, services.ErrInvalidMerchantAlias) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		requestid.Logger(c.Request.Context(), h.logger).Error("Failed to create merchant alias", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create merchant alias"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, alias)
+}
+
+// deleteMerchantAlias removes an alias owned by userID, global when nil
+func (h *AAHandler) deleteMerchantAlias(c *gin.Context, userID *uuid.UUID) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid merchant alias ID"})
+		return
+	}
+
+	err = h.aaService.DeleteMerchantAlias(c.Request.Context(), userID, id)
+	if errors.Is(err, services.ErrMerchantAliasNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Merchant alias not found"})
+		return
+	}
+	if err != nil {
+		requestid.Logger(c.Request.Context(), h.logger).Error("Failed to delete merchant alias", zap.Error(err), zap.String("alias_id", id.String()))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete merchant alias"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/internal/core/services"
+	"github.com/your-github/expense-tracker-backend/internal/repo"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// fakeMerchantAliases keeps merchant aliases in memory
+type fakeMerchantAliases struct {
+	repo.MerchantAliasRepository
+	aliases []*domain.MerchantAlias
+}
+
+func (r *fakeMerchantAliases) Create(ctx context.Context, alias *domain.MerchantAlias) error {
+	alias.ID = uuid.New()
+	r.aliases = append(r.aliases, alias)
+	return nil
+}
+
+func (r *fakeMerchantAliases) GetByID(ctx context.Context, id uuid.UUID) (*domain.MerchantAlias, error) {
+	for _, alias := range r.aliases {
+		if alias.ID == id {
+			return alias, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeMerchantAliases) Delete(ctx context.Context, id uuid.UUID) error {
+	for i, alias := range r.aliases {
+		if alias.ID == id {
+			r.aliases = append(r.aliases[:i], r.aliases[i+1:]...)
+		}
+	}
+	return nil
+}
+
+func newMerchantAliasRouter(userID uuid.UUID, aliases *fakeMerchantAliases) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	repositories := &repo.Repositories{MerchantAlias: aliases}
+	service := services.NewAAService(services.NewMockAAClient(), repositories, services.NewNormalizer(), services.NewDeduplicator(), zap.NewNop())
+	handler := NewAAHandler(service, repositories, nil, zap.NewNop())
+
+	router := gin.New()
+	me := router.Group("/me", asUser(userID))
+	me.POST("/merchant-aliases", handler.CreateMerchantAlias)
+	me.DELETE("/merchant-aliases/:id", handler.DeleteMerchantAlias)
+	router.POST("/admin/merchant-aliases", handler.CreateGlobalMerchantAlias)
+	return router
+}
+
+func TestMerchantAliasEndpoints(t *testing.T) {
+	userID := uuid.New()
+	aliases := &fakeMerchantAliases{}
+	router := newMerchantAliasRouter(userID, aliases)
+	send := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(http.MethodPost, "/me/merchant-aliases", `{"pattern":"/^SWIGGY[* ]/","merchant":"Swiggy"}`)
+	var own domain.MerchantAlias
+	json.Unmarshal(w.Body.Bytes(), &own)
+	if w.Code != http.StatusCreated || own.UserID == nil || *own.UserID != userID {
+		t.Fatalf("create = %d %s, want an alias owned by the user", w.Code, w.Body)
+	}
+	w = send(http.MethodPost, "/admin/merchant-aliases", `{"pattern":"kirana","merchant":"Neighbourhood Store"}`)
+	var global domain.MerchantAlias
+	json.Unmarshal(w.Body.Bytes(), &global)
+	if w.Code != http.StatusCreated || global.UserID != nil {
+		t.Fatalf("create global = %d %s, want an alias without a user", w.Code, w.Body)
+	}
+
+	for _, body := range []string{`{"pattern":"swiggy"}`, `{"pattern":"/swiggy(/","merchant":"Swiggy"}`, `not json`} {
+		if w := send(http.MethodPost, "/me/merchant-aliases", body); w.Code != http.StatusBadRequest {
+			t.Errorf("create %s = %d, want 400", body, w.Code)
+		}
+	}
+
+	if w := send(http.MethodDelete, "/me/merchant-aliases/not-a-uuid", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("delete with a bad ID = %d, want 400", w.Code)
+	}
+	if w := send(http.MethodDelete, "/me/merchant-aliases/"+global.ID.String(), ""); w.Code != http.StatusNotFound {
+		t.Errorf("user deleting a global alias = %d, want 404", w.Code)
+	}
+	if w := send(http.MethodDelete, "/me/merchant-aliases/"+own.ID.String(), ""); w.Code != http.StatusNoContent {
+		t.Errorf("delete own alias = %d, want 204", w.Code)
+	}
+	if len(aliases.aliases) != 1 || aliases.aliases[0].ID != global.ID {
+		t.Errorf("aliases left = %d, want only the global one", len(aliases.aliases))
+	}
+}
@@ -0,0 +1,731 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/internal/core/services"
+	"github.com/your-github/expense-tracker-backend/internal/repo"
+	"github.com/your-github/expense-tracker-backend/utils"
+	"go.uber.org/zap"
+)
+
+// TransactionHandler handles transaction read operations for the current user
+type TransactionHandler struct {
+	repositories *repo.Repositories
+	logger       *zap.Logger
+
+	// Normalizer decides whether a transaction whose category override is
+	// cleared goes back to needing review; nil uses the defaults
+	Normalizer *services.Normalizer
+}
+
+// NewTransactionHandler creates a new transaction handler
+func NewTransactionHandler(repositories *repo.Repositories, logger *zap.Logger) *TransactionHandler {
+	return &TransactionHandler{
+		repositories: repositories,
+		logger:       logger,
+	}
+}
+
+// GetSummary returns debit/credit totals and a category breakdown
+// @Summary Get transaction summary
+// @Description Get totals and per-category breakdown for the authenticated user. Defaults to the current month.
+// @Tags transactions
+// @Produce json
+// @Param from query string false "Start date (YYYY-MM-DD)"
+// @Param to query string false "End date (YYYY-MM-DD, inclusive)"
+// @Success 200 {object} repo.TransactionSummary
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /me/summary [get]
+func (h *TransactionHandler) GetSummary(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	from, to, err := parseSummaryRange(c.Query("from"), c.Query("to"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	summary, err := h.repositories.Transaction.GetSummary(c.Request.Context(), userID, &from, &to)
+	if err != nil {
+		h.logger.Error("Failed to get transaction summary", zap.Error(err), zap.String("user_id", userID.String()))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get transaction summary"})
+		return
+	}
+	if user, err := h.repositories.User.GetByID(c.Request.Context(), userID); err == nil {
+		summary.Currency = utils.NormalizeCurrency(user.Currency)
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// BalancePoint is a single observation of an account balance
+type BalancePoint struct {
+	TransactionID uuid.UUID `json:"transaction_id"`
+	PostedAt      time.Time `json:"posted_at"`
+	Balance       float64   `json:"balance"`
+}
+
+// BalanceHistoryResponse represents a balance history response
+type BalanceHistoryResponse struct {
+	BankLinkID string         `json:"bank_link_id"`
+	Points     []BalancePoint `json:"points"`
+}
+
+// GetBalanceHistory returns the running balance of a linked account over time
+// @Summary Get balance history
+// @Description Get the running balance after each transaction on a linked bank account
+// @Tags transactions
+// @Produce json
+// @Param bank_link_id query string true "Bank link ID"
+// @Param from query string false "Start date (YYYY-MM-DD)"
+// @Param to query string false "End date (YYYY-MM-DD, inclusive)"
+// @Success 200 {object} BalanceHistoryResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /transactions/balance-history [get]
+func (h *TransactionHandler) GetBalanceHistory(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	bankLinkID, err := uuid.Parse(c.Query("bank_link_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid bank_link_id"})
+		return
+	}
+
+	bankLink, err := h.repositories.BankLink.GetByID(c.Request.Context(), bankLinkID)
+	if err != nil || bankLink.UserID != userID {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Bank link not found"})
+		return
+	}
+
+	var from, to *time.Time
+	if fromParam := c.Query("from"); fromParam != "" {
+		parsed, err := time.Parse("2006-01-02", fromParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid from date, expected YYYY-MM-DD"})
+			return
+		}
+		from = &parsed
+	}
+	if toParam := c.Query("to"); toParam != "" {
+		parsed, err := time.Parse("2006-01-02", toParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid to date, expected YYYY-MM-DD"})
+			return
+		}
+		endOfDay := parsed.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		to = &endOfDay
+	}
+	if from != nil && to != nil && from.After(*to) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "from date must not be after to date"})
+		return
+	}
+
+	transactions, err := h.repositories.Transaction.GetBalanceHistory(c.Request.Context(), userID, bankLinkID, from, to)
+	if err != nil {
+		h.logger.Error("Failed to get balance history", zap.Error(err), zap.String("bank_link_id", bankLinkID.String()))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get balance history"})
+		return
+	}
+
+	points := make([]BalancePoint, 0, len(transactions))
+	for _, txn := range transactions {
+		points = append(points, BalancePoint{
+			TransactionID: txn.ID,
+			PostedAt:      txn.PostedAt,
+			Balance:       *txn.BalanceAfter,
+		})
+	}
+
+	c.JSON(http.StatusOK, BalanceHistoryResponse{BankLinkID: bankLinkID.String(), Points: points})
+}
+
+// Paging bounds for transaction listings
+const (
+	defaultTransactionLimit = 50
+	maxTransactionLimit     = 200
+	maxSearchQueryLength    = 100
+)
+
+// TransactionListResponse represents a page of transactions
+type TransactionListResponse struct {
+	Transactions []*domain.Transaction `json:"transactions"`
+	Total        int64                 `json:"total"`
+	Limit        int                   `json:"limit"`
+	Offset       int                   `json:"offset"`
+}
+
+// TransactionPageResponse is a cursor-paginated page of transactions.
+// NextCursor is empty on the last page.
+type TransactionPageResponse struct {
+	Transactions []*domain.Transaction `json:"transactions"`
+	Limit        int                   `json:"limit"`
+	NextCursor   string                `json:"next_cursor,omitempty"`
+}
+
+// ListTransactions lists the user's transactions newest first
+// @Summary List transactions
+// @Description List the user's transactions by posted_at, newest first. Pages are cursor-based by default: pass the returned next_cursor as cursor to get the following page. Passing offset instead switches to offset paging with a total count, which gets slower on deep pages.
+// @Tags transactions
+// @Produce json
+// @Param from query string false "Start date (YYYY-MM-DD)"
+// @Param to query string false "End date (YYYY-MM-DD, inclusive)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param offset query int false "Page offset; cannot be combined with cursor"
+// @Success 200 {object} TransactionPageResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /me/transactions [get]
+func (h *TransactionHandler) ListTransactions(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	limit, offset, err := parsePage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	from, to, err := parseListRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	_, offsetPaging := c.GetQuery("offset")
+	token := c.Query("cursor")
+	if offsetPaging && token != "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "cursor and offset cannot be combined"})
+		return
+	}
+
+	if offsetPaging {
+		transactions, total, err := h.repositories.Transaction.GetByUserID(c.Request.Context(), userID, from, to, limit, offset)
+		if err != nil {
+			h.logger.Error("Failed to list transactions", zap.Error(err), zap.String("user_id", userID.String()))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get transactions"})
+			return
+		}
+		if transactions == nil {
+			transactions = []*domain.Transaction{}
+		}
+		c.JSON(http.StatusOK, TransactionListResponse{
+			Transactions: transactions,
+			Total:        total,
+			Limit:        limit,
+			Offset:       offset,
+		})
+		return
+	}
+
+	var cursor *repo.TransactionCursor
+	if token != "" {
+		if cursor, err = repo.DecodeTransactionCursor(token); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid cursor"})
+			return
+		}
+	}
+	transactions, next, err := h.repositories.Transaction.GetPageByUserID(c.Request.Context(), userID, from, to, cursor, limit)
+	if err != nil {
+		h.logger.Error("Failed to list transactions", zap.Error(err), zap.String("user_id", userID.String()))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get transactions"})
+		return
+	}
+	if transactions == nil {
+		transactions = []*domain.Transaction{}
+	}
+
+	response := TransactionPageResponse{Transactions: transactions, Limit: limit}
+	if next != nil {
+		response.NextCursor = next.Encode()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// parseListRange parses the optional from/to dates of a listing; to is
+// inclusive. Either may be nil.
+func parseListRange(fromParam, toParam string) (*time.Time, *time.Time, error) {
+	var from, to *time.Time
+	if fromParam != "" {
+		parsed, err := time.Parse("2006-01-02", fromParam)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid from date %q, expected YYYY-MM-DD", fromParam)
+		}
+		from = &parsed
+	}
+	if toParam != "" {
+		parsed, err := time.Parse("2006-01-02", toParam)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid to date %q, expected YYYY-MM-DD", toParam)
+		}
+		end := parsed.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		to = &end
+	}
+	if from != nil && to != nil && from.After(*to) {
+		return nil, nil, fmt.Errorf("from date must not be after to date")
+	}
+	return from, to, nil
+}
+
+// GetTransactionsBySourceMeta filters transactions by a source_meta value
+// @Summary Filter transactions by source metadata
+// @Description List the user's transactions whose source_meta value at key equals value. Nested keys are separated by dots, e.g. "bank.ifsc".
+// @Tags transactions
+// @Produce json
+// @Param key query string true "source_meta key, dot-separated for nested keys"
+// @Param value query string true "Value to match, compared as text"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Page offset"
+// @Success 200 {object} TransactionListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /transactions/by-source-meta [get]
+func (h *TransactionHandler) GetTransactionsBySourceMeta(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	key := strings.TrimSpace(c.Query("key"))
+	if key == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "key is required"})
+		return
+	}
+	path := strings.Split(key, ".")
+	for _, segment := range path {
+		if segment == "" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid key, expected dot-separated names"})
+			return
+		}
+	}
+
+	value, ok := c.GetQuery("value")
+	if !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "value is required"})
+		return
+	}
+
+	limit, offset, err := parsePage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	transactions, total, err := h.repositories.Transaction.GetBySourceMeta(c.Request.Context(), userID, path, value, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to filter transactions by source meta", zap.Error(err), zap.String("user_id", userID.String()), zap.String("key", key))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get transactions"})
+		return
+	}
+	if transactions == nil {
+		transactions = []*domain.Transaction{}
+	}
+
+	c.JSON(http.StatusOK, TransactionListResponse{
+		Transactions: transactions,
+		Total:        total,
+		Limit:        limit,
+		Offset:       offset,
+	})
+}
+
+// GetNeedsReview lists transactions flagged for review
+// @Summary List transactions needing review
+// @Description List the user's transactions whose automatic category has low confidence or whose merchant is unknown, newest first. Setting a user_category clears the flag.
+// @Tags transactions
+// @Produce json
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Page offset"
+// @Success 200 {object} TransactionListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /transactions/needs-review [get]
+func (h *TransactionHandler) GetNeedsReview(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	limit, offset, err := parsePage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	transactions, total, err := h.repositories.Transaction.GetNeedsReview(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to list transactions needing review", zap.Error(err), zap.String("user_id", userID.String()))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get transactions"})
+		return
+	}
+	if transactions == nil {
+		transactions = []*domain.Transaction{}
+	}
+
+	c.JSON(http.StatusOK, TransactionListResponse{
+		Transactions: transactions,
+		Total:        total,
+		Limit:        limit,
+		Offset:       offset,
+	})
+}
+
+// GetFlagged lists transactions flagged as unusually large
+// @Summary List flagged transactions
+// @Description List the user's transactions flagged when imported because their amount was above the configured threshold (LARGE_AMOUNT) or far above the user's norm for the category (CATEGORY_OUTLIER), newest first
+// @Tags transactions
+// @Produce json
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Page offset"
+// @Success 200 {object} TransactionListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /transactions/flagged [get]
+func (h *TransactionHandler) GetFlagged(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	limit, offset, err := parsePage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	transactions, total, err := h.repositories.Transaction.GetFlagged(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to list flagged transactions", zap.Error(err), zap.String("user_id", userID.String()))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get transactions"})
+		return
+	}
+	if transactions == nil {
+		transactions = []*domain.Transaction{}
+	}
+
+	c.JSON(http.StatusOK, TransactionListResponse{
+		Transactions: transactions,
+		Total:        total,
+		Limit:        limit,
+		Offset:       offset,
+	})
+}
+
+// SearchTransactions searches transactions by description and merchant
+// @Summary Search transactions
+// @Description Search the user's transactions whose raw description or merchant name contains q, case-insensitively. By default exact merchant matches rank first, then merchants starting with q, then other matches, newest first within each; sort=recent orders all matches by date only.
+// @Tags transactions
+// @Produce json
+// @Param q query string true "Search text (1-100 characters)"
+// @Param sort query string false "relevance (default) or recent"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Page offset"
+// @Success 200 {object} TransactionListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /transactions/search [get]
+func (h *TransactionHandler) SearchTransactions(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" || len(query) > maxSearchQueryLength {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("q must be 1-%d characters", maxSearchQueryLength)})
+		return
+	}
+
+	var byRelevance bool
+	switch c.DefaultQuery("sort", "relevance") {
+	case "relevance":
+		byRelevance = true
+	case "recent":
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "sort must be relevance or recent"})
+		return
+	}
+
+	limit, offset, err := parsePage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	transactions, total, err := h.repositories.Transaction.Search(c.Request.Context(), userID, query, byRelevance, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to search transactions", zap.Error(err), zap.String("user_id", userID.String()))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to search transactions"})
+		return
+	}
+	if transactions == nil {
+		transactions = []*domain.Transaction{}
+	}
+
+	c.JSON(http.StatusOK, TransactionListResponse{
+		Transactions: transactions,
+		Total:        total,
+		Limit:        limit,
+		Offset:       offset,
+	})
+}
+
+// parsePage reads the limit and offset query params of a transaction listing
+func parsePage(c *gin.Context) (int, int, error) {
+	limit := defaultTransactionLimit
+	if limitParam := c.Query("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed < 1 || parsed > maxTransactionLimit {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxTransactionLimit)
+		}
+		limit = parsed
+	}
+
+	offset := 0
+	if offsetParam := c.Query("offset"); offsetParam != "" {
+		parsed, err := strconv.Atoi(offsetParam)
+		if err != nil || parsed < 0 {
+			return 0, 0, fmt.Errorf("offset must be a non-negative integer")
+		}
+		offset = parsed
+	}
+
+	return limit, offset, nil
+}
+
+// parseSummaryRange resolves the from/to query params into an inclusive
+// range. Missing bounds default to the month containing now; "to" is
+// extended to the end of its day so transactions posted that day count.
+func parseSummaryRange(fromParam, toParam string, now time.Time) (time.Time, time.Time, error) {
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	from := monthStart
+	if fromParam != "" {
+		parsed, err := time.Parse("2006-01-02", fromParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from date %q, expected YYYY-MM-DD", fromParam)
+		}
+		from = parsed
+	}
+
+	to := monthStart.AddDate(0, 1, 0).Add(-time.Nanosecond)
+	if toParam != "" {
+		parsed, err := time.Parse("2006-01-02", toParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to date %q, expected YYYY-MM-DD", toParam)
+		}
+		to = parsed.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from date must not be after to date")
+	}
+
+	return from, to, nil
+}
+
+// UpdateTransactionRequest represents a transaction update. Omitted fields
+// are left unchanged; an empty user_category clears the override.
+type UpdateTransactionRequest struct {
+	UserNote     *string `json:"user_note" binding:"omitempty,max=1000"`
+	UserCategory *string `json:"user_category" binding:"omitempty,max=100"`
+}
+
+// UpdateTransaction sets the user's note and category override on a transaction
+// @Summary Update transaction
+// @Description Set a note and/or a category override on one of the user's transactions. The override takes precedence over the automatic category in summaries.
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param id path string true "Transaction ID"
+// @Param request body UpdateTransactionRequest true "Fields to update"
+// @Success 200 {object} domain.Transaction
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /transactions/{id} [patch]
+func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	transactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid transaction ID"})
+		return
+	}
+
+	var req UpdateTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request data: " + err.Error()})
+		return
+	}
+	if req.UserNote == nil && req.UserCategory == nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Nothing to update"})
+		return
+	}
+
+	transaction, err := h.repositories.Transaction.GetByID(c.Request.Context(), transactionID)
+	if err != nil || transaction.UserID != userID {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Transaction not found"})
+		return
+	}
+
+	if req.UserNote != nil {
+		transaction.UserNote = strings.TrimSpace(*req.UserNote)
+	}
+	if req.UserCategory != nil {
+		transaction.UserCategory = strings.TrimSpace(*req.UserCategory)
+		transaction.NeedsReview = transaction.UserCategory == "" && h.needsReview(transaction)
+	}
+
+	if err := h.repositories.Transaction.UpdateUserFields(c.Request.Context(), transaction); err != nil {
+		h.logger.Error("Failed to update transaction", zap.Error(err), zap.String("transaction_id", transactionID.String()))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, transaction)
+}
+
+// SplitTransactionRequest represents the parts of a split transaction
+type SplitTransactionRequest struct {
+	Splits []services.SplitPart `json:"splits" binding:"required"`
+}
+
+// SplitTransaction divides a transaction across categories
+// @Summary Split transaction
+// @Description Divide one of the user's transactions into parts with their own categories. Amounts are in the transaction's currency and must add up to its amount. Splitting again replaces the previous parts. Summaries count each part under its own category instead of the transaction's.
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param id path string true "Transaction ID"
+// @Param request body SplitTransactionRequest true "Split parts"
+// @Success 200 {object} domain.Transaction
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /transactions/{id}/split [post]
+func (h *TransactionHandler) SplitTransaction(c *gin.Context) {
+	var req SplitTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request data: " + err.Error()})
+		return
+	}
+	h.replaceSplits(c, req.Splits)
+}
+
+// UnsplitTransaction removes a transaction's split parts
+// @Summary Remove transaction split
+// @Description Remove the split parts of one of the user's transactions, so summaries count it under its own category again
+// @Tags transactions
+// @Produce json
+// @Param id path string true "Transaction ID"
+// @Success 200 {object} domain.Transaction
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /transactions/{id}/split [delete]
+func (h *TransactionHandler) UnsplitTransaction(c *gin.Context) {
+	h.replaceSplits(c, nil)
+}
+
+// replaceSplits stores parts as the split of the transaction in the path;
+// no parts removes the split
+func (h *TransactionHandler) replaceSplits(c *gin.Context, parts []services.SplitPart) {
+	userID := getUserIDFromContext(c)
+	if userID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Unauthorized"})
+		return
+	}
+
+	transactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid transaction ID"})
+		return
+	}
+
+	transaction, err := h.repositories.Transaction.GetByID(c.Request.Context(), transactionID)
+	if err != nil || transaction.UserID != userID {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Transaction not found"})
+		return
+	}
+
+	var splits []domain.TransactionSplit
+	if parts != nil {
+		splits, err = services.BuildSplits(transaction, parts)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	if err := h.repositories.Transaction.ReplaceSplits(c.Request.Context(), transactionID, splits); err != nil {
+		h.logger.Error("Failed to split transaction", zap.Error(err), zap.String("transaction_id", transactionID.String()))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to split transaction"})
+		return
+	}
+
+	transaction.Splits = splits
+	c.JSON(http.StatusOK, transaction)
+}
+
+// needsReview reports whether a transaction without a category override
+// should be reviewed, judged on its stored confidence and merchant
+func (h *TransactionHandler) needsReview(transaction *domain.Transaction) bool {
+	normalizer := h.Normalizer
+	if normalizer == nil {
+		normalizer = services.NewNormalizer()
+	}
+	return normalizer.NeedsReview(services.NormalizedTransaction{
+		MerchantName: transaction.MerchantName,
+		Confidence:   transaction.CategoryConfidence,
+	})
+}
@@ -0,0 +1,614 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/internal/repo"
+	"github.com/your-github/expense-tracker-backend/middleware"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// asUser stands in for the auth middleware and authenticates every request
+// as userID
+func asUser(userID uuid.UUID) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(middleware.ContextUserUUID, userID.String())
+		c.Next()
+	}
+}
+
+// fakeUsers serves users from a map
+type fakeUsers struct {
+	repo.UserRepository
+	users map[uuid.UUID]*domain.User
+}
+
+func (r fakeUsers) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	if user, ok := r.users[id]; ok {
+		return user, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// summaryTransactions answers GetSummary and records the range it was asked for
+type summaryTransactions struct {
+	repo.TransactionRepository
+	summary  *repo.TransactionSummary
+	err      error
+	userID   uuid.UUID
+	from, to *time.Time
+}
+
+func (r *summaryTransactions) GetSummary(ctx context.Context, userID uuid.UUID, from, to *time.Time) (*repo.TransactionSummary, error) {
+	r.userID, r.from, r.to = userID, from, to
+	return r.summary, r.err
+}
+
+func newSummaryRouter(userID uuid.UUID, transactions *summaryTransactions) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	users := fakeUsers{users: map[uuid.UUID]*domain.User{userID: {ID: userID, Currency: "inr"}}}
+	handler := NewTransactionHandler(&repo.Repositories{User: users, Transaction: transactions}, zap.NewNop())
+	r := gin.New()
+	r.GET("/me/summary", asUser(userID), handler.GetSummary)
+	return r
+}
+
+func TestGetSummary(t *testing.T) {
+	userID := uuid.New()
+	transactions := &summaryTransactions{summary: &repo.TransactionSummary{
+		TotalDebit:  1500,
+		TotalCredit: 5000,
+		NetAmount:   3500,
+		CategoryBreakdown: map[string]repo.CategorySummary{
+			"Food":   {TotalDebit: 1500, NetAmount: -1500, Count: 3},
+			"Salary": {TotalCredit: 5000, NetAmount: 5000, Count: 1},
+		},
+	}}
+	r := newSummaryRouter(userID, transactions)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/me/summary?from=2025-03-01&to=2025-03-31", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+
+	var summary repo.TransactionSummary
+	if err := json.Unmarshal(w.Body.Bytes(), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.NetAmount != 3500 || summary.Currency != "INR" {
+		t.Errorf("summary = %+v, want net 3500 in INR", summary)
+	}
+	if food := summary.CategoryBreakdown["Food"]; food.Count != 3 || food.NetAmount != -1500 {
+		t.Errorf("Food = %+v", food)
+	}
+	if salary := summary.CategoryBreakdown["Salary"]; salary.Count != 1 {
+		t.Errorf("Salary = %+v", salary)
+	}
+
+	if transactions.userID != userID {
+		t.Errorf("summarised user %s, want %s", transactions.userID, userID)
+	}
+	wantFrom := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	wantTo := time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond)
+	if !transactions.from.Equal(wantFrom) || !transactions.to.Equal(wantTo) {
+		t.Errorf("range = %v..%v, want %v..%v", transactions.from, transactions.to, wantFrom, wantTo)
+	}
+}
+
+func TestGetSummaryErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		err    error
+		status int
+	}{
+		{"bad from", "?from=03/01/2025", nil, http.StatusBadRequest},
+		{"bad to", "?to=2025-02-30", nil, http.StatusBadRequest},
+		{"reversed range", "?from=2025-03-10&to=2025-03-01", nil, http.StatusBadRequest},
+		{"repository failure", "", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transactions := &summaryTransactions{summary: &repo.TransactionSummary{}, err: tt.err}
+			w := httptest.NewRecorder()
+			newSummaryRouter(uuid.New(), transactions).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/me/summary"+tt.query, nil))
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestParseSummaryRange(t *testing.T) {
+	now := time.Date(2025, time.February, 14, 15, 30, 0, 0, time.UTC)
+	endOf := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond)
+	}
+	tests := []struct {
+		name     string
+		from, to string
+		wantFrom time.Time
+		wantTo   time.Time
+	}{
+		{"defaults to the current month", "", "", time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC), endOf(2025, time.February, 28)},
+		{"only from", "2025-02-10", "", time.Date(2025, time.February, 10, 0, 0, 0, 0, time.UTC), endOf(2025, time.February, 28)},
+		{"only to", "", "2025-02-05", time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC), endOf(2025, time.February, 5)},
+		{"single day", "2024-12-31", "2024-12-31", time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC), endOf(2024, time.December, 31)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := parseSummaryRange(tt.from, tt.to, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+				t.Errorf("range = %v..%v, want %v..%v", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+
+	// A to date before the default from date is a reversed range
+	if _, _, err := parseSummaryRange("", "2025-01-31", now); err == nil {
+		t.Error("expected an error for a to date before the current month")
+	}
+}
+
+// noteTransactions stores transactions by ID and records UpdateUserFields
+type noteTransactions struct {
+	repo.TransactionRepository
+	byID    map[uuid.UUID]*domain.Transaction
+	updates int
+}
+
+func (r *noteTransactions) GetByID(ctx context.Context, id uuid.UUID) (*domain.Transaction, error) {
+	if txn, ok := r.byID[id]; ok {
+		copied := *txn
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *noteTransactions) UpdateUserFields(ctx context.Context, transaction *domain.Transaction) error {
+	r.updates++
+	stored := r.byID[transaction.ID]
+	stored.UserNote = transaction.UserNote
+	stored.UserCategory = transaction.UserCategory
+	stored.NeedsReview = transaction.NeedsReview
+	return nil
+}
+
+func TestUpdateTransaction(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID := uuid.New()
+	own := &domain.Transaction{ID: uuid.New(), UserID: userID, Category: "Other", MerchantName: "Unknown", NeedsReview: true}
+	foreign := &domain.Transaction{ID: uuid.New(), UserID: uuid.New(), Category: "Other"}
+	transactions := &noteTransactions{byID: map[uuid.UUID]*domain.Transaction{own.ID: own, foreign.ID: foreign}}
+	handler := NewTransactionHandler(&repo.Repositories{Transaction: transactions}, zap.NewNop())
+	r := gin.New()
+	r.PATCH("/transactions/:id", asUser(userID), handler.UpdateTransaction)
+
+	patch := func(id uuid.UUID, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/transactions/"+id.String(), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := patch(own.ID, `{"user_note": " team lunch ", "user_category": "Food"}`); w.Code != http.StatusOK {
+		t.Fatalf("PATCH = %d %s", w.Code, w.Body)
+	}
+	if own.UserNote != "team lunch" || own.UserCategory != "Food" || own.NeedsReview {
+		t.Errorf("stored %+v, want the trimmed note, the override and no review flag", own)
+	}
+
+	// Omitted fields are left alone
+	if w := patch(own.ID, `{"user_category": "Travel"}`); w.Code != http.StatusOK || own.UserNote != "team lunch" || own.UserCategory != "Travel" {
+		t.Errorf("category-only PATCH = %d, stored %+v", w.Code, own)
+	}
+
+	if w := patch(foreign.ID, `{"user_note": "mine now"}`); w.Code != http.StatusNotFound || foreign.UserNote != "" {
+		t.Errorf("another user's transaction = %d, note %q; want 404 and no change", w.Code, foreign.UserNote)
+	}
+	if w := patch(own.ID, `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("empty PATCH = %d, want 400", w.Code)
+	}
+	if transactions.updates != 2 {
+		t.Errorf("wrote %d updates, want 2", transactions.updates)
+	}
+}
+
+func (r *noteTransactions) GetNeedsReview(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.Transaction, int64, error) {
+	var flagged []*domain.Transaction
+	for _, txn := range r.byID {
+		if txn.UserID == userID && txn.NeedsReview {
+			flagged = append(flagged, txn)
+		}
+	}
+	return flagged, int64(len(flagged)), nil
+}
+
+func TestNeedsReviewLifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID := uuid.New()
+	uncertain := &domain.Transaction{ID: uuid.New(), UserID: userID, Category: "Other", MerchantName: "Unknown", CategoryConfidence: 0, NeedsReview: true}
+	confident := &domain.Transaction{ID: uuid.New(), UserID: userID, Category: "Food & Dining", MerchantName: "Swiggy", CategoryConfidence: 0.9}
+	transactions := &noteTransactions{byID: map[uuid.UUID]*domain.Transaction{uncertain.ID: uncertain, confident.ID: confident}}
+	handler := NewTransactionHandler(&repo.Repositories{Transaction: transactions}, zap.NewNop())
+	r := gin.New()
+	r.GET("/transactions/needs-review", asUser(userID), handler.GetNeedsReview)
+	r.PATCH("/transactions/:id", asUser(userID), handler.UpdateTransaction)
+
+	request := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+	listed := func() string {
+		w := request(http.MethodGet, "/transactions/needs-review", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("needs-review = %d %s", w.Code, w.Body)
+		}
+		return w.Body.String()
+	}
+
+	if body := listed(); !strings.Contains(body, uncertain.ID.String()) || strings.Contains(body, confident.ID.String()) {
+		t.Fatalf("needs-review = %s, want only the uncertain transaction", body)
+	}
+
+	// Categorizing it clears the flag
+	if w := request(http.MethodPatch, "/transactions/"+uncertain.ID.String(), `{"user_category": "Gifts"}`); w.Code != http.StatusOK || uncertain.NeedsReview {
+		t.Fatalf("categorized = %d, review %v", w.Code, uncertain.NeedsReview)
+	}
+	if body := listed(); !strings.Contains(body, `"transactions":[]`) {
+		t.Errorf("needs-review after categorizing = %s, want none", body)
+	}
+
+	// Clearing the category puts back the flag the normalizer would set
+	request(http.MethodPatch, "/transactions/"+uncertain.ID.String(), `{"user_category": ""}`)
+	request(http.MethodPatch, "/transactions/"+confident.ID.String(), `{"user_category": ""}`)
+	if !uncertain.NeedsReview || confident.NeedsReview {
+		t.Errorf("after clearing: uncertain review %v, confident review %v; want true, false", uncertain.NeedsReview, confident.NeedsReview)
+	}
+	if body := listed(); !strings.Contains(body, uncertain.ID.String()) {
+		t.Errorf("needs-review after clearing = %s, want the uncertain transaction back", body)
+	}
+
+	if w := request(http.MethodGet, "/transactions/needs-review?limit=0", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("limit=0 = %d, want 400", w.Code)
+	}
+}
+
+// sourceMetaTransactions records the filter GetBySourceMeta was asked for
+type sourceMetaTransactions struct {
+	repo.TransactionRepository
+	path          []string
+	value         string
+	limit, offset int
+}
+
+func (r *sourceMetaTransactions) GetBySourceMeta(ctx context.Context, userID uuid.UUID, path []string, value string, limit, offset int) ([]*domain.Transaction, int64, error) {
+	r.path, r.value, r.limit, r.offset = path, value, limit, offset
+	return nil, 0, nil
+}
+
+func TestGetTransactionsBySourceMeta(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		query    string
+		want     int
+		wantPath string
+	}{
+		{"key=bank.ifsc&value=HDFC0001&limit=10&offset=20", http.StatusOK, "[bank ifsc]"},
+		{"key=source&value=", http.StatusOK, "[source]"},
+		{"value=mock_aa", http.StatusBadRequest, ""},
+		{"key=bank..ifsc&value=x", http.StatusBadRequest, ""},
+		{"key=source", http.StatusBadRequest, ""},
+		{"key=source&value=x&limit=500", http.StatusBadRequest, ""},
+		{"key=source&value=x&offset=-1", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		transactions := &sourceMetaTransactions{}
+		handler := NewTransactionHandler(&repo.Repositories{Transaction: transactions}, zap.NewNop())
+		router := gin.New()
+		router.GET("/transactions/by-source-meta", asUser(uuid.New()), handler.GetTransactionsBySourceMeta)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/transactions/by-source-meta?"+tt.query, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s: status %d %s, want %d", tt.query, w.Code, w.Body, tt.want)
+			continue
+		}
+		if tt.want != http.StatusOK {
+			if transactions.path != nil {
+				t.Errorf("%s: rejected request still queried %v", tt.query, transactions.path)
+			}
+			continue
+		}
+		if got := fmt.Sprint(transactions.path); got != tt.wantPath {
+			t.Errorf("%s: path %s, want %s", tt.query, got, tt.wantPath)
+		}
+		if strings.Contains(tt.query, "limit=10") && (transactions.limit != 10 || transactions.offset != 20) {
+			t.Errorf("%s: paged %d/%d, want limit 10 offset 20", tt.query, transactions.limit, transactions.offset)
+		}
+		if !strings.Contains(w.Body.String(), `"transactions":[]`) {
+			t.Errorf("%s: body %s, want an empty list rather than null", tt.query, w.Body)
+		}
+	}
+}
+
+// pagedTransactions serves GetPageByUserID and GetByUserID from rows kept
+// in posted_at DESC, id DESC order
+type pagedTransactions struct {
+	repo.TransactionRepository
+	rows []*domain.Transaction
+}
+
+func (r *pagedTransactions) GetPageByUserID(ctx context.Context, userID uuid.UUID, from, to *time.Time, after *repo.TransactionCursor, limit int) ([]*domain.Transaction, *repo.TransactionCursor, error) {
+	start := 0
+	if after != nil {
+		for start < len(r.rows) && !(r.rows[start].PostedAt.Equal(after.PostedAt) && r.rows[start].ID == after.ID) {
+			start++
+		}
+		start++
+	}
+	end := min(start+limit, len(r.rows))
+	page := r.rows[start:end]
+	if end == len(r.rows) {
+		return page, nil, nil
+	}
+	last := page[len(page)-1]
+	return page, &repo.TransactionCursor{PostedAt: last.PostedAt, ID: last.ID}, nil
+}
+
+func (r *pagedTransactions) GetByUserID(ctx context.Context, userID uuid.UUID, from, to *time.Time, limit, offset int) ([]*domain.Transaction, int64, error) {
+	end := min(offset+limit, len(r.rows))
+	if offset > end {
+		return nil, int64(len(r.rows)), nil
+	}
+	return r.rows[offset:end], int64(len(r.rows)), nil
+}
+
+func newListRouter(userID uuid.UUID, n int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	transactions := &pagedTransactions{}
+	posted := time.Date(2025, time.March, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		transactions.rows = append(transactions.rows, &domain.Transaction{ID: uuid.New(), UserID: userID, PostedAt: posted.Add(-time.Duration(i) * time.Hour)})
+	}
+	handler := NewTransactionHandler(&repo.Repositories{Transaction: transactions}, zap.NewNop())
+	r := gin.New()
+	r.GET("/me/transactions", asUser(userID), handler.ListTransactions)
+	return r
+}
+
+func TestListTransactionsFollowsNextCursor(t *testing.T) {
+	router := newListRouter(uuid.New(), 7)
+
+	seen := make(map[string]bool)
+	target := "/me/transactions?limit=3"
+	for pages := 1; ; pages++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("page %d = %d %s", pages, w.Code, w.Body)
+		}
+		var page TransactionPageResponse
+		json.Unmarshal(w.Body.Bytes(), &page)
+		for _, txn := range page.Transactions {
+			if seen[txn.ID.String()] {
+				t.Fatalf("page %d repeats %s", pages, txn.ID)
+			}
+			seen[txn.ID.String()] = true
+		}
+		if page.NextCursor == "" {
+			if pages != 3 || len(seen) != 7 {
+				t.Errorf("saw %d transactions over %d pages, want 7 over 3", len(seen), pages)
+			}
+			break
+		}
+		target = "/me/transactions?limit=3&cursor=" + page.NextCursor
+	}
+}
+
+func TestListTransactionsOffsetAndBadParams(t *testing.T) {
+	router := newListRouter(uuid.New(), 7)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/me/transactions?limit=3&offset=6", nil))
+	var page TransactionListResponse
+	json.Unmarshal(w.Body.Bytes(), &page)
+	if w.Code != http.StatusOK || page.Total != 7 || page.Offset != 6 || len(page.Transactions) != 1 {
+		t.Errorf("offset page = %d %s, want the last transaction of 7", w.Code, w.Body)
+	}
+
+	cursor := repo.TransactionCursor{PostedAt: time.Now(), ID: uuid.New()}.Encode()
+	for _, query := range []string{"cursor=not-a-cursor", "cursor=" + cursor + "&offset=0", "from=2025-03-05&to=2025-03-01", "limit=0"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/me/transactions?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d %s, want 400", query, w.Code, w.Body)
+		}
+	}
+}
+
+type flaggedTransactions struct {
+	repo.TransactionRepository
+	rows []*domain.Transaction
+}
+
+func (r *flaggedTransactions) GetFlagged(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.Transaction, int64, error) {
+	var flagged []*domain.Transaction
+	for _, txn := range r.rows {
+		if txn.UserID == userID && txn.FlagReason != "" {
+			flagged = append(flagged, txn)
+		}
+	}
+	return flagged, int64(len(flagged)), nil
+}
+
+func TestGetFlagged(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID := uuid.New()
+	outlier := &domain.Transaction{ID: uuid.New(), UserID: userID, Amount: 2400, FlagReason: domain.FlagCategoryOutlier}
+	transactions := &flaggedTransactions{rows: []*domain.Transaction{
+		outlier,
+		{ID: uuid.New(), UserID: userID, Amount: 300},
+		{ID: uuid.New(), UserID: uuid.New(), Amount: 90000, FlagReason: domain.FlagLargeAmount},
+	}}
+	handler := NewTransactionHandler(&repo.Repositories{Transaction: transactions}, zap.NewNop())
+	router := gin.New()
+	router.GET("/transactions/flagged", asUser(userID), handler.GetFlagged)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/transactions/flagged", nil))
+	var page TransactionListResponse
+	json.Unmarshal(w.Body.Bytes(), &page)
+	if w.Code != http.StatusOK || page.Total != 1 || len(page.Transactions) != 1 || page.Transactions[0].ID != outlier.ID {
+		t.Fatalf("flagged = %d %s, want only the user's outlier", w.Code, w.Body)
+	}
+	if page.Transactions[0].FlagReason != domain.FlagCategoryOutlier {
+		t.Errorf("flag_reason = %q", page.Transactions[0].FlagReason)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/transactions/flagged?limit=500", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("limit=500 = %d, want 400", w.Code)
+	}
+}
+
+type splitTransactions struct {
+	repo.TransactionRepository
+	rows   map[uuid.UUID]*domain.Transaction
+	splits map[uuid.UUID][]domain.TransactionSplit
+}
+
+func (r *splitTransactions) GetByID(ctx context.Context, id uuid.UUID) (*domain.Transaction, error) {
+	txn, ok := r.rows[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *txn
+	return &copied, nil
+}
+
+func (r *splitTransactions) ReplaceSplits(ctx context.Context, transactionID uuid.UUID, splits []domain.TransactionSplit) error {
+	r.splits[transactionID] = splits
+	return nil
+}
+
+func TestSplitTransaction(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID := uuid.New()
+	own := &domain.Transaction{ID: uuid.New(), UserID: userID, Amount: 5000, BaseAmount: 5000, Category: "Shopping"}
+	other := &domain.Transaction{ID: uuid.New(), UserID: uuid.New(), Amount: 5000, BaseAmount: 5000}
+	transactions := &splitTransactions{
+		rows:   map[uuid.UUID]*domain.Transaction{own.ID: own, other.ID: other},
+		splits: make(map[uuid.UUID][]domain.TransactionSplit),
+	}
+	handler := NewTransactionHandler(&repo.Repositories{Transaction: transactions}, zap.NewNop())
+	router := gin.New()
+	router.POST("/transactions/:id/split", asUser(userID), handler.SplitTransaction)
+	router.DELETE("/transactions/:id/split", asUser(userID), handler.UnsplitTransaction)
+	request := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	const parts = `{"splits":[{"amount":2000,"category":"Food & Dining","subcategory":"Groceries"},{"amount":3000,"category":"Shopping","subcategory":"Electronics"}]}`
+
+	w := request(http.MethodPost, "/transactions/"+own.ID.String()+"/split", parts)
+	var split domain.Transaction
+	json.Unmarshal(w.Body.Bytes(), &split)
+	if w.Code != http.StatusOK || len(split.Splits) != 2 || split.Splits[0].Amount != 2000 || split.Splits[1].Category != "Shopping" {
+		t.Fatalf("split = %d %s", w.Code, w.Body)
+	}
+	if stored := transactions.splits[own.ID]; len(stored) != 2 || stored[0].BaseAmount+stored[1].BaseAmount != 5000 {
+		t.Errorf("stored splits = %+v, want two parts adding up to 5000", stored)
+	}
+
+	tests := []struct {
+		name, target, body string
+		want               int
+	}{
+		{"parts short of the amount", own.ID.String(), `{"splits":[{"amount":2000,"category":"Food & Dining"},{"amount":2500,"category":"Shopping"}]}`, http.StatusBadRequest},
+		{"no parts", own.ID.String(), `{}`, http.StatusBadRequest},
+		{"bad ID", "not-a-uuid", parts, http.StatusBadRequest},
+		{"another user's transaction", other.ID.String(), parts, http.StatusNotFound},
+		{"unknown transaction", uuid.NewString(), parts, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := request(http.MethodPost, "/transactions/"+tt.target+"/split", tt.body); w.Code != tt.want {
+			t.Errorf("%s: status %d %s, want %d", tt.name, w.Code, w.Body, tt.want)
+		}
+	}
+	if _, ok := transactions.splits[other.ID]; ok {
+		t.Error("another user's transaction was split")
+	}
+
+	if w := request(http.MethodDelete, "/transactions/"+own.ID.String()+"/split", ""); w.Code != http.StatusOK || transactions.splits[own.ID] != nil {
+		t.Errorf("unsplit = %d, splits left %+v", w.Code, transactions.splits[own.ID])
+	}
+}
+
+type searchTransactions struct {
+	repo.TransactionRepository
+	query       string
+	byRelevance bool
+	limit       int
+	offset      int
+}
+
+func (r *searchTransactions) Search(ctx context.Context, userID uuid.UUID, query string, byRelevance bool, limit, offset int) ([]*domain.Transaction, int64, error) {
+	r.query, r.byRelevance, r.limit, r.offset = query, byRelevance, limit, offset
+	return nil, 0, nil
+}
+
+func TestSearchTransactions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	transactions := &searchTransactions{}
+	handler := NewTransactionHandler(&repo.Repositories{Transaction: transactions}, zap.NewNop())
+	router := gin.New()
+	router.GET("/transactions/search", asUser(uuid.New()), handler.SearchTransactions)
+	search := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/transactions/search?"+query, nil))
+		return w
+	}
+
+	w := search("q=+Amazon+&limit=10&offset=20")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"transactions":[]`) {
+		t.Fatalf("search = %d %s, want 200 with an empty list", w.Code, w.Body)
+	}
+	if transactions.query != "Amazon" || !transactions.byRelevance || transactions.limit != 10 || transactions.offset != 20 {
+		t.Errorf("searched %+v, want the trimmed query by relevance, limit 10, offset 20", transactions)
+	}
+
+	if w := search("q=amazon&sort=recent"); w.Code != http.StatusOK || transactions.byRelevance {
+		t.Errorf("sort=recent = %d, by relevance %v", w.Code, transactions.byRelevance)
+	}
+
+	for _, query := range []string{"", "q=+++", "q=" + strings.Repeat("a", maxSearchQueryLength+1), "q=amazon&sort=amount", "q=amazon&limit=0"} {
+		if w := search(query); w.Code != http.StatusBadRequest {
+			t.Errorf("%.40s = %d %s, want 400", query, w.Code, w.Body)
+		}
+	}
+}
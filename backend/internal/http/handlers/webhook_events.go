@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/internal/core/services"
+	"github.com/your-github/expense-tracker-backend/requestid"
+	"go.uber.org/zap"
+)
+
+// WebhookEventListResponse represents a page of recorded webhook events
+type WebhookEventListResponse struct {
+	Events []*domain.WebhookEvent `json:"events"`
+	Total  int64                  `json:"total"`
+	Limit  int                    `json:"limit"`
+	Offset int                    `json:"offset"`
+}
+
+// webhookEventStatuses are the accepted values of the status filter
+var webhookEventStatuses = map[string]bool{
+	domain.WebhookEventPending:   true,
+	domain.WebhookEventProcessed: true,
+	domain.WebhookEventFailed:    true,
+	domain.WebhookEventReplaying: true,
+}
+
+// ListWebhookEvents returns recorded AA webhook events
+// @Summary List webhook events
+// @Description List recorded AA webhook events newest first, optionally filtered by status (PENDING, PROCESSED, FAILED, REPLAYING). Admin only.
+// @Tags admin
+// @Produce json
+// @Param status query string false "Only events with this status"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of events to skip"
+// @Success 200 {object} WebhookEventListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/webhook-events [get]
+func (h *AAHandler) ListWebhookEvents(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && !webhookEventStatuses[status] {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid status"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "limit must be between 1 and 200"})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "offset must be a non-negative integer"})
+		return
+	}
+
+	events, total, err := h.aaService.ListWebhookEvents(c.Request.Context(), status, limit, offset)
+	if err != nil {
+		requestid.Logger(c.Request.Context(), h.logger).Error("Failed to list webhook events", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to list webhook events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, WebhookEventListResponse{Events: events, Total: total, Limit: limit, Offset: offset})
+}
+
+// ReplayWebhookEvent re-handles a failed AA webhook event
+// @Summary Replay webhook event
+// @Description Re-handle a failed AA webhook event. Replaying an event that already succeeded returns it unchanged. If the replay fails again the event is returned with status FAILED and the new error.
+// @Tags admin
+// @Produce json
+// @Param id path string true "Webhook event ID"
+// @Success 200 {object} domain.WebhookEvent
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/webhook-events/{id}/replay [post]
+func (h *AAHandler) ReplayWebhookEvent(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid webhook event ID"})
+		return
+	}
+
+	event, err := h.aaService.ReplayWebhookEvent(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, services.ErrWebhookEventNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Webhook event not found"})
+		return
+	case errors.Is(err, services.ErrWebhookEventNotReplayable):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Webhook event is " + event.Status + "; only failed events can be replayed"})
+		return
+	case err != nil && event == nil:
+		requestid.Logger(c.Request.Context(), h.logger).Error("Failed to replay webhook event", zap.Error(err), zap.String("event_id", id.String()))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to replay webhook event"})
+		return
+	case err != nil:
+		requestid.Logger(c.Request.Context(), h.logger).Warn("Webhook event replay failed", zap.Error(err), zap.String("event_id", id.String()))
+	}
+
+	c.JSON(http.StatusOK, event)
+}
@@ -1,86 +1,48 @@
 package middleware
 
 import (
-	"net/http"
-	"strings"
+	"errors"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/your-github/expense-tracker-backend/internal/repo"
+	legacy "github.com/your-github/expense-tracker-backend/middleware"
+	"github.com/your-github/expense-tracker-backend/requestid"
 )
 
-// Auth middleware validates JWT tokens
+// Auth middleware validates JWT tokens. It delegates to the shared legacy
+// middleware so both routers accept the same claim format, and turns away
+// tokens issued to legacy numeric accounts.
 func Auth(jwtSecret string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Get Authorization header
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
-			return
-		}
-
-		// Check Bearer token format
-		tokenParts := strings.Split(authHeader, " ")
-		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
-			c.Abort()
-			return
-		}
-
-		tokenString := tokenParts[1]
+	return legacy.Auth(jwtSecret, legacy.UUIDSubject)
+}
 
-		// Parse and validate JWT token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validate signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(jwtSecret), nil
-		})
+// RequireRole restricts a route to tokens carrying one of roles; it must
+// run after Auth
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return legacy.RequireRole(roles...)
+}
 
+// RequireCurrentRole is RequireRole that also re-reads the caller's role
+// from users, so a demoted admin's token stops working immediately
+func RequireCurrentRole(users repo.UserRepository, roles ...string) gin.HandlerFunc {
+	return legacy.RequireCurrentRole(func(c *gin.Context) (string, error) {
+		id, err := uuid.Parse(c.GetString(legacy.ContextUserUUID))
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
-			return
-		}
-
-		// Check if token is valid
-		if !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
-			return
+			return "", nil
 		}
-
-		// Extract claims
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
-			return
+		user, err := users.GetByID(c.Request.Context(), id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
 		}
-
-		// Extract user information
-		userID, ok := claims["user_id"].(string)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in token"})
-			c.Abort()
-			return
-		}
-
-		email, ok := claims["email"].(string)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email in token"})
-			c.Abort()
-			return
+		if err != nil {
+			return "", err
 		}
-
-		// Set user information in context
-		c.Set("user_id", userID)
-		c.Set("email", email)
-
-		c.Next()
-	}
+		return user.Role, nil
+	}, roles...)
 }
 
 // Logger middleware for structured logging
@@ -93,6 +55,7 @@ func Logger(logger *zap.Logger) gin.HandlerFunc {
 			zap.String("ip", param.ClientIP),
 			zap.Duration("latency", param.Latency),
 			zap.String("user_agent", param.Request.UserAgent()),
+			zap.String("request_id", requestid.FromContext(param.Request.Context())),
 		)
 		return ""
 	})
@@ -102,9 +65,9 @@ func Logger(logger *zap.Logger) gin.HandlerFunc {
 func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
-		c.Header("Access-Control-Expose-Headers", "Content-Length")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Request-ID")
+		c.Header("Access-Control-Expose-Headers", "Content-Length, X-Request-ID")
 		c.Header("Access-Control-Allow-Credentials", "true")
 
 		if c.Request.Method == "OPTIONS" {
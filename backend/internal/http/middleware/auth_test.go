@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/internal/repo"
+	"github.com/your-github/expense-tracker-backend/utils"
+	"gorm.io/gorm"
+)
+
+func TestCORSAllowsPatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(CORS())
+	r.PATCH("/api/transactions/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodOptions, "/api/transactions/1", nil)
+	req.Header.Set("Access-Control-Request-Method", http.MethodPatch)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("preflight = %d, want 204", w.Code)
+	}
+	if methods := w.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(methods, "PATCH") {
+		t.Errorf("Allow-Methods = %q, want PATCH for transaction updates", methods)
+	}
+}
+
+// storedUsers serves users from a map
+type storedUsers struct {
+	repo.UserRepository
+	users map[uuid.UUID]*domain.User
+}
+
+func (r storedUsers) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	if user, ok := r.users[id]; ok {
+		return user, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestRequireCurrentRoleReadsTheStoredRole(t *testing.T) {
+	const secret = "test-secret"
+	admin := &domain.User{ID: uuid.New(), Role: utils.RoleAdmin}
+	demoted := &domain.User{ID: uuid.New(), Role: utils.RoleUser}
+	users := storedUsers{users: map[uuid.UUID]*domain.User{admin.ID: admin, demoted.ID: demoted}}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/admin/webhook-events", Auth(secret), RequireCurrentRole(users, utils.RoleAdmin),
+		func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := map[string]struct {
+		userID uuid.UUID
+		want   int
+	}{
+		"admin":         {admin.ID, http.StatusOK},
+		"demoted admin": {demoted.ID, http.StatusForbidden},
+		"deleted admin": {uuid.New(), http.StatusForbidden},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Every token claims admin; only the stored role decides
+			token, err := utils.NewToken(tt.userID.String(), "", utils.RoleAdmin, time.Hour, secret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api/admin/webhook-events", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
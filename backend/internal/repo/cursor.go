@@ -0,0 +1,46 @@
+package repo
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned for a pagination cursor that can't be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TransactionCursor is the position of the last transaction of a page in
+// posted_at DESC, id DESC order. Clients get it as an opaque string.
+type TransactionCursor struct {
+	PostedAt time.Time
+	ID       uuid.UUID
+}
+
+// Encode returns the cursor as an opaque URL-safe token
+func (c TransactionCursor) Encode() string {
+	raw := c.PostedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeTransactionCursor parses a token produced by Encode
+func DecodeTransactionCursor(token string) (*TransactionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	postedAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	cursor := &TransactionCursor{}
+	if cursor.PostedAt, err = time.Parse(time.RFC3339Nano, postedAt); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.ID, err = uuid.Parse(id); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
@@ -0,0 +1,38 @@
+package repo
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestTransactionCursorRoundTrip(t *testing.T) {
+	loc := time.FixedZone("IST", 5*3600+1800)
+	cursor := TransactionCursor{PostedAt: time.Date(2025, time.March, 1, 9, 30, 15, 123456789, loc), ID: uuid.New()}
+
+	decoded, err := DecodeTransactionCursor(cursor.Encode())
+	if err != nil {
+		t.Fatalf("DecodeTransactionCursor: %v", err)
+	}
+	if !decoded.PostedAt.Equal(cursor.PostedAt) || decoded.ID != cursor.ID {
+		t.Errorf("decoded %+v, want %+v", decoded, cursor)
+	}
+}
+
+func TestDecodeTransactionCursorRejectsGarbage(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+	for _, token := range []string{
+		"",
+		"not base64!",
+		encode("2025-03-01T09:30:00Z"),
+		encode("yesterday|" + uuid.NewString()),
+		encode("2025-03-01T09:30:00Z|not-a-uuid"),
+	} {
+		if _, err := DecodeTransactionCursor(token); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeTransactionCursor(%q) = %v, want ErrInvalidCursor", token, err)
+		}
+	}
+}
@@ -20,6 +20,7 @@ func NewDB(dsn string) (*gorm.DB, error) {
 		Logger: logger.Default.LogMode(logger.Error),
 		PrepareStmt: true,
 		SkipDefaultTransaction: true,
+		TranslateError: true, // surface unique violations as gorm.ErrDuplicatedKey
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
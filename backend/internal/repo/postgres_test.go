@@ -0,0 +1,236 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+)
+
+// The tests in this file run the repository SQL against a real Postgres,
+// which the stub database can't vouch for. They are skipped unless
+// DATABASE_URL points at a database the tests may create schemas in.
+
+// openTestPostgres connects to DATABASE_URL with a throwaway schema first on
+// the search path, holding the tables of the internal stack
+func openTestPostgres(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+
+	admin, err := NewDB(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := "repo_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := admin.Exec("DROP SCHEMA " + schema + " CASCADE").Error; err != nil {
+			t.Errorf("drop schema %s: %v", schema, err)
+		}
+		Close(admin)
+	})
+
+	db, err := NewDB(withSearchPath(dsn, schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Close(db) })
+	if err := db.AutoMigrate(&domain.User{}, &domain.BankLink{}, &domain.Transaction{}, &domain.TransactionSplit{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// withSearchPath adds a search_path run-time parameter to a URL or
+// keyword/value DSN; public stays on the path for installed extensions
+func withSearchPath(dsn, schema string) string {
+	if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		query := u.Query()
+		query.Set("search_path", schema+",public")
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+	return dsn + " search_path=" + schema + ",public"
+}
+
+// pgFixture creates a user and inserts their transactions
+type pgFixture struct {
+	t      *testing.T
+	repos  *Repositories
+	userID uuid.UUID
+}
+
+func newPGFixture(t *testing.T) *pgFixture {
+	repos := NewRepositories(openTestPostgres(t))
+	user := &domain.User{Email: "asha@example.com", PasswordHash: "x"}
+	if err := repos.User.Create(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return &pgFixture{t: t, repos: repos, userID: user.ID}
+}
+
+// add inserts a transaction with base_amount equal to amount
+func (f *pgFixture) add(txnType string, amount float64, category, description string, postedAt time.Time) *domain.Transaction {
+	f.t.Helper()
+	txn := &domain.Transaction{
+		UserID:         f.userID,
+		PostedAt:       postedAt,
+		Amount:         amount,
+		BaseAmount:     amount,
+		TxnType:        txnType,
+		Category:       category,
+		DescriptionRaw: description,
+		HashDedupe:     uuid.NewString(),
+	}
+	if err := f.repos.Transaction.Create(context.Background(), txn); err != nil {
+		f.t.Fatal(err)
+	}
+	return txn
+}
+
+func TestPostgresGetSummary(t *testing.T) {
+	f := newPGFixture(t)
+	ctx := context.Background()
+	day := time.Date(2025, time.March, 10, 9, 0, 0, 0, time.UTC)
+
+	// AA imports write txn_type uppercase, manual entries lowercase
+	f.add("DEBIT", 100, "Food", "Lunch", day)
+	f.add("debit", 50, "Food", "Tea", day)
+	f.add("credit", 1000, "Salary", "Payroll", day)
+
+	// A linked reversal pair cancels out and is left out entirely
+	original := f.add("DEBIT", 300, "Shopping", "Shoes", day)
+	refund := f.add("CREDIT", 300, "Shopping", "Shoes refund", day.Add(time.Hour))
+	if err := f.repos.Transaction.LinkReversal(ctx, original.ID, refund.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// A split transaction counts under its parts' categories
+	split := f.add("DEBIT", 400, "Shopping", "Supermarket", day)
+	if err := f.repos.Transaction.ReplaceSplits(ctx, split.ID, []domain.TransactionSplit{
+		{Amount: 250, BaseAmount: 250, Category: "Groceries"},
+		{Amount: 150, BaseAmount: 150, Category: "Household"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := f.repos.Transaction.GetSummary(ctx, f.userID, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.TotalDebit != 550 || summary.TotalCredit != 1000 || summary.NetAmount != 450 {
+		t.Errorf("totals = %v debit, %v credit, %v net; want 550, 1000, 450", summary.TotalDebit, summary.TotalCredit, summary.NetAmount)
+	}
+	want := map[string]CategorySummary{
+		"Food":      {TotalDebit: 150, NetAmount: -150, Count: 2},
+		"Salary":    {TotalCredit: 1000, NetAmount: 1000, Count: 1},
+		"Groceries": {TotalDebit: 250, NetAmount: -250, Count: 1},
+		"Household": {TotalDebit: 150, NetAmount: -150, Count: 1},
+	}
+	if fmt.Sprint(summary.CategoryBreakdown) != fmt.Sprint(want) {
+		t.Errorf("breakdown = %v, want %v", summary.CategoryBreakdown, want)
+	}
+}
+
+func TestPostgresGetPageByUserIDPagesWithoutGapsOrRepeats(t *testing.T) {
+	f := newPGFixture(t)
+	ctx := context.Background()
+	day := time.Date(2025, time.March, 10, 9, 0, 0, 0, time.UTC)
+
+	// Ties on posted_at are broken by id, so several rows share a timestamp
+	var all []*domain.Transaction
+	for i, offset := range []time.Duration{0, 0, 0, time.Hour, time.Hour, 2 * time.Hour, 3 * time.Hour} {
+		all = append(all, f.add("DEBIT", float64(i+1), "Food", "Snack", day.Add(offset)))
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if !all[i].PostedAt.Equal(all[j].PostedAt) {
+			return all[i].PostedAt.After(all[j].PostedAt)
+		}
+		return bytes.Compare(all[i].ID[:], all[j].ID[:]) > 0
+	})
+
+	var got []uuid.UUID
+	var cursor *TransactionCursor
+	for pages := 0; ; pages++ {
+		if pages > len(all) {
+			t.Fatal("pagination didn't end")
+		}
+		page, next, err := f.repos.Transaction.GetPageByUserID(ctx, f.userID, nil, nil, cursor, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, txn := range page {
+			got = append(got, txn.ID)
+		}
+		if next == nil {
+			break
+		}
+		// Clients carry the cursor as a token
+		if cursor, err = DecodeTransactionCursor(next.Encode()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(got) != len(all) {
+		t.Fatalf("paged through %d transactions, want %d", len(got), len(all))
+	}
+	for i, txn := range all {
+		if got[i] != txn.ID {
+			t.Errorf("row %d = %s, want %s", i, got[i], txn.ID)
+		}
+	}
+}
+
+func TestPostgresSearchMatchesWildcardsLiterally(t *testing.T) {
+	f := newPGFixture(t)
+	ctx := context.Background()
+	day := time.Date(2025, time.March, 10, 9, 0, 0, 0, time.UTC)
+
+	f.add("DEBIT", 1, "Food", "50% off at Blue Tokai", day)
+	f.add("DEBIT", 2, "Food", "500 off at Blue Tokai", day)
+	f.add("DEBIT", 3, "Shopping", "snake_case store", day)
+	f.add("DEBIT", 4, "Shopping", "snakeXcase store", day)
+	f.add("DEBIT", 5, "Bills", `C:\bills paid`, day)
+	f.add("DEBIT", 6, "Bills", "C:bills paid", day)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"50%", []string{"50% off at Blue Tokai"}},
+		{"BLUE tokai", []string{"50% off at Blue Tokai", "500 off at Blue Tokai"}},
+		{"snake_case", []string{"snake_case store"}},
+		{`C:\bills`, []string{`C:\bills paid`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			results, total, err := f.repos.Transaction.Search(ctx, f.userID, tt.query, false, 10, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, txn := range results {
+				got = append(got, txn.DescriptionRaw)
+			}
+			sort.Strings(got)
+			if total != int64(len(tt.want)) || strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("matched %d %q, want %q", total, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,11 +2,14 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/your-github/expense-tracker-backend/internal/core/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Repositories holds all repository interfaces
@@ -15,8 +18,17 @@ type Repositories struct {
 	BankLink         BankLinkRepository
 	Transaction      TransactionRepository
 	CategoryOverride CategoryOverrideRepository
+	MerchantAlias    MerchantAliasRepository
+	ProcessedSession ProcessedSessionRepository
+	DataSession      DataSessionRepository
+	WebhookEvent     WebhookEventRepository
+
+	db *gorm.DB
 }
 
+// ErrDuplicate is returned when an insert violates a unique constraint
+var ErrDuplicate = errors.New("record already exists")
+
 // NewRepositories creates new repository instances
 func NewRepositories(db *gorm.DB) *Repositories {
 	return &Repositories{
@@ -24,7 +36,21 @@ func NewRepositories(db *gorm.DB) *Repositories {
 		BankLink:         NewBankLinkRepository(db),
 		Transaction:      NewTransactionRepository(db),
 		CategoryOverride: NewCategoryOverrideRepository(db),
+		MerchantAlias:    NewMerchantAliasRepository(db),
+		ProcessedSession: NewProcessedSessionRepository(db),
+		DataSession:      NewDataSessionRepository(db),
+		WebhookEvent:     NewWebhookEventRepository(db),
+		db:               db,
+	}
+}
+
+// Ping checks that the database is reachable
+func (r *Repositories) Ping(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
 	}
+	return sqlDB.PingContext(ctx)
 }
 
 // UserRepository defines user data access methods
@@ -45,6 +71,11 @@ type BankLinkRepository interface {
 	Update(ctx context.Context, bankLink *domain.BankLink) error
 	UpdateStatus(ctx context.Context, id uuid.UUID, status string) error
 	GetActiveByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.BankLink, error)
+	GetNeedingStatusRefresh(ctx context.Context) ([]*domain.BankLink, error)
+	GetExpiringWithoutReminder(ctx context.Context, before time.Time) ([]*domain.BankLink, error)
+	MarkExpiryReminderSent(ctx context.Context, id uuid.UUID, sentAt time.Time) error
+	ClaimRefresh(ctx context.Context, id uuid.UUID, now, notAfter time.Time) (bool, error)
+	SetLastRefreshedAt(ctx context.Context, id uuid.UUID, refreshedAt *time.Time) error
 }
 
 // TransactionRepository defines transaction data access methods
@@ -52,10 +83,26 @@ type TransactionRepository interface {
 	Create(ctx context.Context, transaction *domain.Transaction) error
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.Transaction, error)
 	GetByUserID(ctx context.Context, userID uuid.UUID, from, to *time.Time, limit, offset int) ([]*domain.Transaction, int64, error)
+	GetPageByUserID(ctx context.Context, userID uuid.UUID, from, to *time.Time, after *TransactionCursor, limit int) ([]*domain.Transaction, *TransactionCursor, error)
+	GetBySourceMeta(ctx context.Context, userID uuid.UUID, path []string, value string, limit, offset int) ([]*domain.Transaction, int64, error)
 	GetByHashDedupe(ctx context.Context, hashDedupe string) (*domain.Transaction, error)
+	ExistingHashes(ctx context.Context, hashes []string) (map[string]bool, error)
 	Update(ctx context.Context, transaction *domain.Transaction) error
+	UpdateNormalizedFields(ctx context.Context, transactions []*domain.Transaction) error
+	GetNeedsReview(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.Transaction, int64, error)
+	UpdateUserFields(ctx context.Context, transaction *domain.Transaction) error
+	GetFlagged(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.Transaction, int64, error)
+	Search(ctx context.Context, userID uuid.UUID, query string, byRelevance bool, limit, offset int) ([]*domain.Transaction, int64, error)
+	SetFlagReason(ctx context.Context, id uuid.UUID, reason string) error
+	GetAmountStats(ctx context.Context, userID uuid.UUID, since time.Time) (map[AmountStatsKey]AmountStats, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 	GetSummary(ctx context.Context, userID uuid.UUID, from, to *time.Time) (*TransactionSummary, error)
+	GetLastBalanceBefore(ctx context.Context, bankLinkID uuid.UUID, before time.Time) (*domain.Transaction, error)
+	GetByBankLinkSince(ctx context.Context, bankLinkID uuid.UUID, since time.Time) ([]*domain.Transaction, error)
+	GetBalanceHistory(ctx context.Context, userID, bankLinkID uuid.UUID, from, to *time.Time) ([]*domain.Transaction, error)
+	FindReversalCandidate(ctx context.Context, reversal *domain.Transaction, txnType string, since time.Time) (*domain.Transaction, error)
+	LinkReversal(ctx context.Context, originalID, reversalID uuid.UUID) error
+	ReplaceSplits(ctx context.Context, transactionID uuid.UUID, splits []domain.TransactionSplit) error
 }
 
 // CategoryOverrideRepository defines category override data access methods
@@ -67,8 +114,40 @@ type CategoryOverrideRepository interface {
 	Delete(ctx context.Context, id uuid.UUID) error
 }
 
-// TransactionSummary represents transaction summary data
+// MerchantAliasRepository defines merchant alias data access methods
+type MerchantAliasRepository interface {
+	Create(ctx context.Context, alias *domain.MerchantAlias) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.MerchantAlias, error)
+	GetForUser(ctx context.Context, userID uuid.UUID) ([]*domain.MerchantAlias, error)
+	GetGlobal(ctx context.Context) ([]*domain.MerchantAlias, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// ProcessedSessionRepository defines processed AA session data access methods
+type ProcessedSessionRepository interface {
+	Create(ctx context.Context, session *domain.ProcessedSession) error
+	Exists(ctx context.Context, sessionID string) (bool, error)
+}
+
+// DataSessionRepository defines AA data session data access methods
+type DataSessionRepository interface {
+	Create(ctx context.Context, session *domain.DataSession) error
+	GetBySessionID(ctx context.Context, sessionID string) (*domain.DataSession, error)
+}
+
+// WebhookEventRepository defines webhook event data access methods
+type WebhookEventRepository interface {
+	Create(ctx context.Context, event *domain.WebhookEvent) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.WebhookEvent, error)
+	List(ctx context.Context, status string, limit, offset int) ([]*domain.WebhookEvent, int64, error)
+	ClaimForReplay(ctx context.Context, id uuid.UUID) (bool, error)
+	RecordResult(ctx context.Context, event *domain.WebhookEvent) error
+}
+
+// TransactionSummary represents transaction summary data. Amounts are in
+// the user's base currency.
 type TransactionSummary struct {
+	Currency          string                     `json:"currency,omitempty"`
 	TotalDebit        float64                    `json:"total_debit"`
 	TotalCredit       float64                    `json:"total_credit"`
 	NetAmount         float64                    `json:"net_amount"`
@@ -83,6 +162,21 @@ type CategorySummary struct {
 	Count       int64   `json:"count"`
 }
 
+// AmountStatsKey groups amount statistics by effective category and
+// transaction type
+type AmountStatsKey struct {
+	Category string
+	TxnType  string
+}
+
+// AmountStats holds running sums of transaction base amounts, enough to
+// derive their mean and standard deviation
+type AmountStats struct {
+	Count      int64
+	Sum        float64
+	SumSquares float64
+}
+
 // userRepository implements UserRepository
 type userRepository struct {
 	db *gorm.DB
@@ -173,6 +267,46 @@ func (r *bankLinkRepository) GetActiveByUserID(ctx context.Context, userID uuid.
 	return bankLinks, err
 }
 
+// GetNeedingStatusRefresh returns links whose consent may still change state
+func (r *bankLinkRepository) GetNeedingStatusRefresh(ctx context.Context) ([]*domain.BankLink, error) {
+	var bankLinks []*domain.BankLink
+	err := r.db.WithContext(ctx).Where("status IN ?", []string{"PENDING", "ACTIVE"}).Find(&bankLinks).Error
+	return bankLinks, err
+}
+
+// GetExpiringWithoutReminder returns active links, with their user, whose
+// consent expires after now but no later than before and that have not yet
+// been sent an expiry reminder
+func (r *bankLinkRepository) GetExpiringWithoutReminder(ctx context.Context, before time.Time) ([]*domain.BankLink, error) {
+	var bankLinks []*domain.BankLink
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("status = ? AND expiry_reminder_sent_at IS NULL", "ACTIVE").
+		Where("valid_till > ? AND valid_till <= ?", time.Now(), before).
+		Order("valid_till ASC").
+		Find(&bankLinks).Error
+	return bankLinks, err
+}
+
+// MarkExpiryReminderSent records that the link's expiry reminder went out
+func (r *bankLinkRepository) MarkExpiryReminderSent(ctx context.Context, id uuid.UUID, sentAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.BankLink{}).Where("id = ?", id).Update("expiry_reminder_sent_at", sentAt).Error
+}
+
+// ClaimRefresh records an on-demand refresh at now unless the link was
+// already refreshed after notAfter. It reports whether the claim succeeded,
+// so concurrent refreshes of one link cannot both pass the cooldown.
+func (r *bankLinkRepository) ClaimRefresh(ctx context.Context, id uuid.UUID, now, notAfter time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.BankLink{}).
+		Where("id = ? AND (last_refreshed_at IS NULL OR last_refreshed_at <= ?)", id, notAfter).
+		Update("last_refreshed_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *bankLinkRepository) SetLastRefreshedAt(ctx context.Context, id uuid.UUID, refreshedAt *time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.BankLink{}).Where("id = ?", id).Update("last_refreshed_at", refreshedAt).Error
+}
+
 // transactionRepository implements TransactionRepository
 type transactionRepository struct {
 	db *gorm.DB
@@ -182,13 +316,20 @@ func NewTransactionRepository(db *gorm.DB) TransactionRepository {
 	return &transactionRepository{db: db}
 }
 
+// Create inserts a transaction, returning ErrDuplicate if its dedupe hash already exists
 func (r *transactionRepository) Create(ctx context.Context, transaction *domain.Transaction) error {
-	return r.db.WithContext(ctx).Create(transaction).Error
+	err := r.db.WithContext(ctx).Create(transaction).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDuplicate
+	}
+	return err
 }
 
 func (r *transactionRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Transaction, error) {
 	var transaction domain.Transaction
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&transaction).Error
+	err := r.db.WithContext(ctx).Preload("Splits", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at, id")
+	}).Where("id = ?", id).First(&transaction).Error
 	if err != nil {
 		return nil, err
 	}
@@ -214,8 +355,74 @@ func (r *transactionRepository) GetByUserID(ctx context.Context, userID uuid.UUI
 		return nil, 0, err
 	}
 
-	// Get paginated results
-	err = query.Order("posted_at DESC").Limit(limit).Offset(offset).Find(&transactions).Error
+	// Get paginated results; id breaks posted_at ties so pages are stable
+	err = query.Order("posted_at DESC, id DESC").Limit(limit).Offset(offset).Find(&transactions).Error
+	return transactions, total, err
+}
+
+// GetPageByUserID returns up to limit of the user's transactions in
+// posted_at DESC, id DESC order, starting after the cursor (from the
+// beginning when nil). Seeking on (posted_at, id) keeps deep pages as cheap
+// as the first. The returned cursor is nil on the last page.
+func (r *transactionRepository) GetPageByUserID(ctx context.Context, userID uuid.UUID, from, to *time.Time, after *TransactionCursor, limit int) ([]*domain.Transaction, *TransactionCursor, error) {
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if from != nil {
+		query = query.Where("posted_at >= ?", from)
+	}
+	if to != nil {
+		query = query.Where("posted_at <= ?", to)
+	}
+	if after != nil {
+		query = query.Where("(posted_at, id) < (?, ?)", after.PostedAt, after.ID)
+	}
+
+	// One extra row tells whether another page follows
+	var transactions []*domain.Transaction
+	if err := query.Order("posted_at DESC, id DESC").Limit(limit + 1).Find(&transactions).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(transactions) <= limit {
+		return transactions, nil, nil
+	}
+
+	transactions = transactions[:limit]
+	last := transactions[limit-1]
+	return transactions, &TransactionCursor{PostedAt: last.PostedAt, ID: last.ID}, nil
+}
+
+// GetBySourceMeta returns the user's transactions whose source_meta value at
+// path (one key per nesting level) equals value as text. Keys and value are
+// bound as parameters, so callers may pass user input.
+func (r *transactionRepository) GetBySourceMeta(ctx context.Context, userID uuid.UUID, path []string, value string, limit, offset int) ([]*domain.Transaction, int64, error) {
+	if len(path) == 0 {
+		return nil, 0, errors.New("source_meta path must not be empty")
+	}
+
+	var transactions []*domain.Transaction
+	var total int64
+
+	// source_meta -> k1 -> k2 ->> k3 = value
+	expr := "source_meta"
+	args := make([]interface{}, 0, len(path)+1)
+	for i, key := range path {
+		if i == len(path)-1 {
+			expr += " ->> CAST(? AS text)"
+		} else {
+			expr += " -> CAST(? AS text)"
+		}
+		args = append(args, key)
+	}
+	args = append(args, value)
+
+	query := r.db.WithContext(ctx).Model(&domain.Transaction{}).
+		Where("user_id = ?", userID).
+		Where(expr+" = ?", args...)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("posted_at DESC").Limit(limit).Offset(offset).Find(&transactions).Error
 	return transactions, total, err
 }
 
@@ -228,17 +435,200 @@ func (r *transactionRepository) GetByHashDedupe(ctx context.Context, hashDedupe
 	return &transaction, nil
 }
 
+// existingHashBatchSize bounds the IN list of one ExistingHashes query
+const existingHashBatchSize = 500
+
+// ExistingHashes reports which of hashes are already stored. Soft-deleted
+// rows count, since they still hold their place in the unique index.
+func (r *transactionRepository) ExistingHashes(ctx context.Context, hashes []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	for start := 0; start < len(hashes); start += existingHashBatchSize {
+		var found []string
+		batch := hashes[start:min(start+existingHashBatchSize, len(hashes))]
+		if err := r.db.WithContext(ctx).Unscoped().Model(&domain.Transaction{}).
+			Where("hash_dedupe IN ?", batch).Pluck("hash_dedupe", &found).Error; err != nil {
+			return nil, err
+		}
+		for _, hash := range found {
+			existing[hash] = true
+		}
+	}
+	return existing, nil
+}
+
 func (r *transactionRepository) Update(ctx context.Context, transaction *domain.Transaction) error {
 	return r.db.WithContext(ctx).Save(transaction).Error
 }
 
+// UpdateNormalizedFields writes the merchant, account reference and category
+// of each transaction in a single DB transaction. Other columns, including
+// hash_dedupe, are left untouched.
+func (r *transactionRepository) UpdateNormalizedFields(ctx context.Context, transactions []*domain.Transaction) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, transaction := range transactions {
+			err := tx.Model(&domain.Transaction{}).Where("id = ?", transaction.ID).Updates(map[string]interface{}{
+				"merchant_name": transaction.MerchantName,
+				"account_ref":   transaction.AccountRef,
+				"category":      transaction.Category,
+				"subcategory":   transaction.Subcategory,
+
+				"category_confidence": transaction.CategoryConfidence,
+				"needs_review":        transaction.NeedsReview,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateUserFields writes the user's note, category override and the
+// resulting review flag
+func (r *transactionRepository) UpdateUserFields(ctx context.Context, transaction *domain.Transaction) error {
+	return r.db.WithContext(ctx).Model(&domain.Transaction{}).Where("id = ?", transaction.ID).Updates(map[string]interface{}{
+		"user_note":     transaction.UserNote,
+		"user_category": transaction.UserCategory,
+		"needs_review":  transaction.NeedsReview,
+	}).Error
+}
+
+// GetNeedsReview returns the user's transactions flagged for review, newest first
+func (r *transactionRepository) GetNeedsReview(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.Transaction, int64, error) {
+	query := r.db.WithContext(ctx).Model(&domain.Transaction{}).Where("user_id = ? AND needs_review", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var transactions []*domain.Transaction
+	err := query.Order("posted_at DESC").Order("id").Limit(limit).Offset(offset).Find(&transactions).Error
+	return transactions, total, err
+}
+
+// GetFlagged returns the user's transactions flagged as unusually large,
+// newest first
+func (r *transactionRepository) GetFlagged(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.Transaction, int64, error) {
+	query := r.db.WithContext(ctx).Model(&domain.Transaction{}).Where("user_id = ? AND flag_reason <> ''", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var transactions []*domain.Transaction
+	err := query.Order("posted_at DESC").Order("id").Limit(limit).Offset(offset).Find(&transactions).Error
+	return transactions, total, err
+}
+
+// Search returns the user's transactions whose raw description or merchant
+// name contains query, case-insensitively. With byRelevance, exact merchant
+// matches come first, then merchants starting with query, then the rest;
+// within each group, and otherwise, the newest come first.
+func (r *transactionRepository) Search(ctx context.Context, userID uuid.UUID, query string, byRelevance bool, limit, offset int) ([]*domain.Transaction, int64, error) {
+	escaped := likeEscaper.Replace(query)
+	contains := "%" + escaped + "%"
+	q := r.db.WithContext(ctx).Model(&domain.Transaction{}).
+		Where("user_id = ? AND (description_raw ILIKE ? OR merchant_name ILIKE ?)", userID, contains, contains)
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Order only takes columns in this gorm version, so the ranking with its
+	// bound patterns goes in as an ORDER BY clause expression
+	order := clause.Expr{SQL: "posted_at DESC, id"}
+	if byRelevance {
+		order = clause.Expr{
+			SQL:  "CASE WHEN merchant_name ILIKE ? THEN 0 WHEN merchant_name ILIKE ? THEN 1 ELSE 2 END, posted_at DESC, id",
+			Vars: []interface{}{escaped, escaped + "%"},
+		}
+	}
+	var transactions []*domain.Transaction
+	err := q.Clauses(clause.OrderBy{Expression: order}).Limit(limit).Offset(offset).Find(&transactions).Error
+	return transactions, total, err
+}
+
+// likeEscaper escapes the LIKE wildcards in user input so it matches literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (r *transactionRepository) SetFlagReason(ctx context.Context, id uuid.UUID, reason string) error {
+	return r.db.WithContext(ctx).Model(&domain.Transaction{}).Where("id = ?", id).Update("flag_reason", reason).Error
+}
+
+// GetAmountStats sums the absolute base amounts of the user's transactions
+// posted since the given time per effective category and transaction type.
+// Linked reversal pairs are left out.
+func (r *transactionRepository) GetAmountStats(ctx context.Context, userID uuid.UUID, since time.Time) (map[AmountStatsKey]AmountStats, error) {
+	var rows []struct {
+		Category   string
+		TxnType    string
+		Count      int64
+		Sum        float64
+		SumSquares float64
+	}
+	err := r.db.WithContext(ctx).Model(&domain.Transaction{}).
+		Select(effectiveCategorySQL+` as category,
+		UPPER(txn_type) as txn_type,
+		COUNT(*) as count,
+		COALESCE(SUM(ABS(base_amount)), 0) as sum,
+		COALESCE(SUM(base_amount * base_amount), 0) as sum_squares`).
+		Where("user_id = ? AND reversal_of IS NULL AND posted_at >= ?", userID, since).
+		Group("1, 2").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[AmountStatsKey]AmountStats, len(rows))
+	for _, row := range rows {
+		stats[AmountStatsKey{Category: row.Category, TxnType: row.TxnType}] = AmountStats{
+			Count:      row.Count,
+			Sum:        row.Sum,
+			SumSquares: row.SumSquares,
+		}
+	}
+	return stats, nil
+}
+
 func (r *transactionRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return r.db.WithContext(ctx).Delete(&domain.Transaction{}, "id = ?", id).Error
 }
 
-func (r *transactionRepository) GetSummary(ctx context.Context, userID uuid.UUID, from, to *time.Time) (*TransactionSummary, error) {
-	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+// GetLastBalanceBefore returns the most recent transaction on a bank link
+// posted before the given time that carries a balance, or nil if none exists
+func (r *transactionRepository) GetLastBalanceBefore(ctx context.Context, bankLinkID uuid.UUID, before time.Time) (*domain.Transaction, error) {
+	var transactions []*domain.Transaction
+	err := r.db.WithContext(ctx).
+		Where("bank_link_id = ? AND posted_at < ? AND balance_after IS NOT NULL", bankLinkID, before).
+		Order("posted_at DESC, created_at DESC").
+		Limit(1).
+		Find(&transactions).Error
+	if err != nil || len(transactions) == 0 {
+		return nil, err
+	}
+	return transactions[0], nil
+}
 
+// GetByBankLinkSince returns a bank link's transactions posted at or after
+// since, in chronological order
+func (r *transactionRepository) GetByBankLinkSince(ctx context.Context, bankLinkID uuid.UUID, since time.Time) ([]*domain.Transaction, error) {
+	var transactions []*domain.Transaction
+	err := r.db.WithContext(ctx).
+		Where("bank_link_id = ? AND posted_at >= ?", bankLinkID, since).
+		Order("posted_at ASC, created_at ASC").
+		Find(&transactions).Error
+	return transactions, err
+}
+
+// GetBalanceHistory returns a bank link's balance-carrying transactions in chronological order
+func (r *transactionRepository) GetBalanceHistory(ctx context.Context, userID, bankLinkID uuid.UUID, from, to *time.Time) ([]*domain.Transaction, error) {
+	var transactions []*domain.Transaction
+
+	query := r.db.WithContext(ctx).
+		Where("user_id = ? AND bank_link_id = ? AND balance_after IS NOT NULL", userID, bankLinkID)
 	if from != nil {
 		query = query.Where("posted_at >= ?", from)
 	}
@@ -246,6 +636,79 @@ func (r *transactionRepository) GetSummary(ctx context.Context, userID uuid.UUID
 		query = query.Where("posted_at <= ?", to)
 	}
 
+	err := query.Order("posted_at ASC, created_at ASC").Find(&transactions).Error
+	return transactions, err
+}
+
+// FindReversalCandidate returns the most recent unlinked transaction of
+// txnType on the reversal's account with the same amount, posted between
+// since and the reversal itself, or nil when there is none
+func (r *transactionRepository) FindReversalCandidate(ctx context.Context, reversal *domain.Transaction, txnType string, since time.Time) (*domain.Transaction, error) {
+	query := r.db.WithContext(ctx).
+		Where("user_id = ? AND id <> ? AND reversal_of IS NULL", reversal.UserID, reversal.ID).
+		Where("UPPER(txn_type) = ? AND amount = ?", strings.ToUpper(txnType), reversal.Amount).
+		Where("posted_at >= ? AND posted_at <= ?", since, reversal.PostedAt)
+	if reversal.BankLinkID != nil {
+		query = query.Where("bank_link_id = ?", *reversal.BankLinkID)
+	} else {
+		query = query.Where("bank_link_id IS NULL")
+	}
+
+	var transactions []*domain.Transaction
+	err := query.Order("posted_at DESC, created_at DESC").Limit(1).Find(&transactions).Error
+	if err != nil || len(transactions) == 0 {
+		return nil, err
+	}
+	return transactions[0], nil
+}
+
+// LinkReversal points each leg of a reversal pair at the other
+func (r *transactionRepository) LinkReversal(ctx context.Context, originalID, reversalID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.Transaction{}).Where("id = ?", originalID).Update("reversal_of", reversalID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&domain.Transaction{}).Where("id = ?", reversalID).Update("reversal_of", originalID).Error
+	})
+}
+
+// ReplaceSplits swaps the transaction's split parts for splits; no splits
+// leaves it unsplit
+func (r *transactionRepository) ReplaceSplits(ctx context.Context, transactionID uuid.UUID, splits []domain.TransactionSplit) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("transaction_id = ?", transactionID).Delete(&domain.TransactionSplit{}).Error; err != nil {
+			return err
+		}
+		if len(splits) == 0 {
+			return nil
+		}
+		for i := range splits {
+			splits[i].TransactionID = transactionID
+		}
+		return tx.Create(&splits).Error
+	})
+}
+
+// effectiveCategorySQL mirrors domain.Transaction.EffectiveCategory, with
+// blank categories reported as Other, the canonical fallback category
+const effectiveCategorySQL = "COALESCE(NULLIF(user_category, ''), NULLIF(category, ''), 'Other')"
+
+func (r *transactionRepository) GetSummary(ctx context.Context, userID uuid.UUID, from, to *time.Time) (*TransactionSummary, error) {
+	// Each query needs its own statement; reusing a chained *gorm.DB would
+	// carry the first Select/Scan state into the grouped query.
+	baseQuery := func() *gorm.DB {
+		// Linked reversal pairs cancel out, so both legs are left out
+		query := r.db.WithContext(ctx).Model(&domain.Transaction{}).
+			Where("user_id = ? AND reversal_of IS NULL", userID)
+		if from != nil {
+			query = query.Where("posted_at >= ?", from)
+		}
+		if to != nil {
+			query = query.Where("posted_at <= ?", to)
+		}
+		return query
+	}
+
 	var summary TransactionSummary
 	var categoryBreakdown []struct {
 		Category    string  `json:"category"`
@@ -254,10 +717,12 @@ func (r *transactionRepository) GetSummary(ctx context.Context, userID uuid.UUID
 		Count       int64   `json:"count"`
 	}
 
-	// Get overall totals
-	err := query.Select(`
-		SUM(CASE WHEN txn_type = 'DEBIT' THEN amount ELSE 0 END) as total_debit,
-		SUM(CASE WHEN txn_type = 'CREDIT' THEN amount ELSE 0 END) as total_credit
+	// txn_type is compared case-insensitively: AA imports write "DEBIT"/"CREDIT"
+	// while manual expenses have historically been stored lowercase. Sums use
+	// base_amount so transactions in other currencies add up correctly.
+	err := baseQuery().Select(`
+		COALESCE(SUM(CASE WHEN UPPER(txn_type) = 'DEBIT' THEN base_amount ELSE 0 END), 0) as total_debit,
+		COALESCE(SUM(CASE WHEN UPPER(txn_type) = 'CREDIT' THEN base_amount ELSE 0 END), 0) as total_credit
 	`).Scan(&summary).Error
 	if err != nil {
 		return nil, err
@@ -265,11 +730,18 @@ func (r *transactionRepository) GetSummary(ctx context.Context, userID uuid.UUID
 
 	summary.NetAmount = summary.TotalCredit - summary.TotalDebit
 
-	// Get category breakdown
-	err = query.Select(`
-		COALESCE(category, 'Uncategorized') as category,
-		SUM(CASE WHEN txn_type = 'DEBIT' THEN amount ELSE 0 END) as total_debit,
-		SUM(CASE WHEN txn_type = 'CREDIT' THEN amount ELSE 0 END) as total_credit,
+	// Get category breakdown; a user-set category wins over the normalizer's,
+	// and a split transaction counts once per part under the part's category
+	unsplit := baseQuery().
+		Select(effectiveCategorySQL + " as category, txn_type, base_amount").
+		Where("NOT EXISTS (SELECT 1 FROM transaction_splits WHERE transaction_splits.transaction_id = transactions.id)")
+	splitParts := baseQuery().
+		Joins("JOIN transaction_splits ON transaction_splits.transaction_id = transactions.id").
+		Select("COALESCE(NULLIF(transaction_splits.category, ''), 'Other') as category, txn_type, transaction_splits.base_amount")
+	err = r.db.WithContext(ctx).Table("(? UNION ALL ?) as parts", unsplit, splitParts).Select(`
+		category,
+		SUM(CASE WHEN UPPER(txn_type) = 'DEBIT' THEN base_amount ELSE 0 END) as total_debit,
+		SUM(CASE WHEN UPPER(txn_type) = 'CREDIT' THEN base_amount ELSE 0 END) as total_credit,
 		COUNT(*) as count
 	`).Group("category").Scan(&categoryBreakdown).Error
 	if err != nil {
@@ -313,7 +785,8 @@ func (r *categoryOverrideRepository) GetByID(ctx context.Context, id uuid.UUID)
 
 func (r *categoryOverrideRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.CategoryOverride, error) {
 	var overrides []*domain.CategoryOverride
-	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&overrides).Error
+	// Oldest first: the first matching override wins
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at, id").Find(&overrides).Error
 	return overrides, err
 }
 
@@ -324,3 +797,152 @@ func (r *categoryOverrideRepository) Update(ctx context.Context, override *domai
 func (r *categoryOverrideRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return r.db.WithContext(ctx).Delete(&domain.CategoryOverride{}, "id = ?", id).Error
 }
+
+// merchantAliasRepository implements MerchantAliasRepository
+type merchantAliasRepository struct {
+	db *gorm.DB
+}
+
+func NewMerchantAliasRepository(db *gorm.DB) MerchantAliasRepository {
+	return &merchantAliasRepository{db: db}
+}
+
+func (r *merchantAliasRepository) Create(ctx context.Context, alias *domain.MerchantAlias) error {
+	return r.db.WithContext(ctx).Create(alias).Error
+}
+
+func (r *merchantAliasRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.MerchantAlias, error) {
+	var alias domain.MerchantAlias
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&alias).Error
+	if err != nil {
+		return nil, err
+	}
+	return &alias, nil
+}
+
+// GetForUser returns the aliases applied to the user's transactions in the
+// order they are tried: the user's own, then the global ones, each oldest
+// first
+func (r *merchantAliasRepository) GetForUser(ctx context.Context, userID uuid.UUID) ([]*domain.MerchantAlias, error) {
+	var aliases []*domain.MerchantAlias
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? OR user_id IS NULL", userID).
+		Order("user_id IS NULL, created_at, id").
+		Find(&aliases).Error
+	return aliases, err
+}
+
+func (r *merchantAliasRepository) GetGlobal(ctx context.Context) ([]*domain.MerchantAlias, error) {
+	var aliases []*domain.MerchantAlias
+	err := r.db.WithContext(ctx).Where("user_id IS NULL").Order("created_at, id").Find(&aliases).Error
+	return aliases, err
+}
+
+func (r *merchantAliasRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&domain.MerchantAlias{}, "id = ?", id).Error
+}
+
+// processedSessionRepository implements ProcessedSessionRepository
+type processedSessionRepository struct {
+	db *gorm.DB
+}
+
+func NewProcessedSessionRepository(db *gorm.DB) ProcessedSessionRepository {
+	return &processedSessionRepository{db: db}
+}
+
+// Create records a processed session, returning ErrDuplicate if it was already recorded
+func (r *processedSessionRepository) Create(ctx context.Context, session *domain.ProcessedSession) error {
+	err := r.db.WithContext(ctx).Create(session).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (r *processedSessionRepository) Exists(ctx context.Context, sessionID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.ProcessedSession{}).Where("session_id = ?", sessionID).Count(&count).Error
+	return count > 0, err
+}
+
+// dataSessionRepository implements DataSessionRepository
+type dataSessionRepository struct {
+	db *gorm.DB
+}
+
+func NewDataSessionRepository(db *gorm.DB) DataSessionRepository {
+	return &dataSessionRepository{db: db}
+}
+
+func (r *dataSessionRepository) Create(ctx context.Context, session *domain.DataSession) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *dataSessionRepository) GetBySessionID(ctx context.Context, sessionID string) (*domain.DataSession, error) {
+	var session domain.DataSession
+	err := r.db.WithContext(ctx).Where("session_id = ?", sessionID).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// webhookEventRepository implements WebhookEventRepository
+type webhookEventRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookEventRepository(db *gorm.DB) WebhookEventRepository {
+	return &webhookEventRepository{db: db}
+}
+
+func (r *webhookEventRepository) Create(ctx context.Context, event *domain.WebhookEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *webhookEventRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.WebhookEvent, error) {
+	var event domain.WebhookEvent
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&event).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// List returns events newest first, optionally only those with status
+func (r *webhookEventRepository) List(ctx context.Context, status string, limit, offset int) ([]*domain.WebhookEvent, int64, error) {
+	query := r.db.WithContext(ctx).Model(&domain.WebhookEvent{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	events := []*domain.WebhookEvent{}
+	err := query.Order("received_at DESC").Order("id").Limit(limit).Offset(offset).Find(&events).Error
+	return events, total, err
+}
+
+// ClaimForReplay moves a FAILED event to REPLAYING. It reports false when
+// the event is not failed, e.g. because another replay already claimed it.
+func (r *webhookEventRepository) ClaimForReplay(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.WebhookEvent{}).
+		Where("id = ? AND status = ?", id, domain.WebhookEventFailed).
+		Update("status", domain.WebhookEventReplaying)
+	return result.RowsAffected > 0, result.Error
+}
+
+// RecordResult writes the outcome of an attempt: status, error, attempts
+// and processed_at
+func (r *webhookEventRepository) RecordResult(ctx context.Context, event *domain.WebhookEvent) error {
+	return r.db.WithContext(ctx).Model(&domain.WebhookEvent{}).Where("id = ?", event.ID).Updates(map[string]interface{}{
+		"status":       event.Status,
+		"error":        event.Error,
+		"attempts":     event.Attempts,
+		"processed_at": event.ProcessedAt,
+	}).Error
+}
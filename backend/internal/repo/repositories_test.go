@@ -0,0 +1,693 @@
+package repo
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+)
+
+// summarySQL picks out the two GetSummary queries: the totals come from a
+// single aggregate, the breakdown from the union of unsplit rows and split parts
+const (
+	summaryTotalsSQL    = "COALESCE(SUM(CASE"
+	summaryBreakdownSQL = "UNION ALL"
+)
+
+func TestGetSummary(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(summaryTotalsSQL, []string{"total_debit", "total_credit"}, []driver.Value{1750.0, 5000.0})
+	stub.On(summaryBreakdownSQL, []string{"category", "total_debit", "total_credit", "count"},
+		[]driver.Value{"Food", 1250.0, 0.0, int64(3)},
+		[]driver.Value{"Shopping", 500.0, 0.0, int64(1)},
+		[]driver.Value{"Salary", 0.0, 5000.0, int64(1)},
+	)
+	userID := uuid.New()
+	from := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0).Add(-time.Nanosecond)
+
+	summary, err := NewTransactionRepository(db).GetSummary(context.Background(), userID, &from, &to)
+	if err != nil {
+		t.Fatalf("GetSummary: %v", err)
+	}
+
+	if summary.TotalDebit != 1750 || summary.TotalCredit != 5000 || summary.NetAmount != 3250 {
+		t.Errorf("totals = %+v, want debit 1750, credit 5000, net 3250", summary)
+	}
+	want := map[string]CategorySummary{
+		"Food":     {TotalDebit: 1250, NetAmount: -1250, Count: 3},
+		"Shopping": {TotalDebit: 500, NetAmount: -500, Count: 1},
+		"Salary":   {TotalCredit: 5000, NetAmount: 5000, Count: 1},
+	}
+	if len(summary.CategoryBreakdown) != len(want) {
+		t.Fatalf("breakdown = %+v", summary.CategoryBreakdown)
+	}
+	for category, expected := range want {
+		if got := summary.CategoryBreakdown[category]; got != expected {
+			t.Errorf("%s = %+v, want %+v", category, got, expected)
+		}
+	}
+
+	for _, query := range []string{summaryTotalsSQL, summaryBreakdownSQL} {
+		ran := stub.Ran(query)
+		if len(ran) != 1 {
+			t.Fatalf("%q ran %d times", query, len(ran))
+		}
+		if !containsArgs(ran[0].Args, userID, from, to) {
+			t.Errorf("%q was not limited to the user and range: %v", query, ran[0].Args)
+		}
+		if !strings.Contains(ran[0].SQL, "reversal_of IS NULL") {
+			t.Errorf("%q counts reversed transactions:\n%s", query, ran[0].SQL)
+		}
+		// Foreign-currency transactions add up in the user's currency
+		if !strings.Contains(ran[0].SQL, "THEN base_amount ELSE 0") {
+			t.Errorf("%q doesn't sum base amounts:\n%s", query, ran[0].SQL)
+		}
+	}
+}
+
+func TestGetSummaryWithoutTransactions(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(summaryTotalsSQL, []string{"total_debit", "total_credit"}, []driver.Value{0.0, 0.0})
+
+	summary, err := NewTransactionRepository(db).GetSummary(context.Background(), uuid.New(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.NetAmount != 0 || summary.CategoryBreakdown == nil || len(summary.CategoryBreakdown) != 0 {
+		t.Errorf("summary = %+v, want zero totals and an empty breakdown", summary)
+	}
+}
+
+// containsArgs reports whether every wanted value is among args
+func containsArgs(args []driver.Value, wanted ...interface{}) bool {
+	for _, want := range wanted {
+		found := false
+		for _, arg := range args {
+			switch v := arg.(type) {
+			case *time.Time:
+				if w, ok := want.(time.Time); ok && v.Equal(w) {
+					found = true
+				}
+			case time.Time:
+				if w, ok := want.(time.Time); ok && v.Equal(w) {
+					found = true
+				}
+			default:
+				if arg == want {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGetSummaryMatchesTxnTypeInAnyCase(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(summaryTotalsSQL, []string{"total_debit", "total_credit"}, []driver.Value{0.0, 0.0})
+
+	if _, err := NewTransactionRepository(db).GetSummary(context.Background(), uuid.New(), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	// AA imports are stored as DEBIT/CREDIT while older manual rows are
+	// lowercase, so both queries must compare on UPPER(txn_type)
+	for _, query := range []string{summaryTotalsSQL, summaryBreakdownSQL} {
+		ran := stub.Ran(query)
+		if len(ran) != 1 {
+			t.Fatalf("%q ran %d times", query, len(ran))
+		}
+		sql := ran[0].SQL
+		for _, want := range []string{"UPPER(txn_type) = 'DEBIT'", "UPPER(txn_type) = 'CREDIT'"} {
+			if !strings.Contains(sql, want) {
+				t.Errorf("%q does not use %s:\n%s", query, want, sql)
+			}
+		}
+		if strings.Contains(strings.ReplaceAll(sql, "UPPER(txn_type)", ""), "txn_type =") {
+			t.Errorf("%q compares txn_type case-sensitively:\n%s", query, sql)
+		}
+	}
+}
+
+func TestGetExpiringWithoutReminder(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	before := time.Now().Add(7 * 24 * time.Hour)
+
+	if _, err := NewBankLinkRepository(db).GetExpiringWithoutReminder(context.Background(), before); err != nil {
+		t.Fatal(err)
+	}
+	ran := stub.Ran(`FROM "bank_links"`)
+	if len(ran) != 1 {
+		t.Fatalf("ran %d bank link queries, want 1", len(ran))
+	}
+	for _, want := range []string{"status = $1", "expiry_reminder_sent_at IS NULL", "valid_till > $2 AND valid_till <= $3"} {
+		if !strings.Contains(ran[0].SQL, want) {
+			t.Errorf("query doesn't filter on %s:\n%s", want, ran[0].SQL)
+		}
+	}
+	if !containsArgs(ran[0].Args, "ACTIVE", before) {
+		t.Errorf("args = %v, want ACTIVE links expiring by %v", ran[0].Args, before)
+	}
+}
+
+// sourceMetaTable answers GetBySourceMeta queries by evaluating the bound
+// path and value against each row's source_meta, as Postgres' -> and ->>
+// operators would
+func sourceMetaTable(stub *testutil.StubDB, userID uuid.UUID, metas map[string]string) {
+	stub.Handle(`FROM "transactions"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		path := make([]string, 0, len(args)-2)
+		for _, arg := range args[1 : len(args)-1] {
+			path = append(path, arg.(string))
+		}
+		value := args[len(args)-1]
+
+		var matched []driver.Value
+		if args[0] == userID {
+			for id, meta := range metas {
+				var node interface{}
+				json.Unmarshal([]byte(meta), &node)
+				for _, key := range path {
+					object, _ := node.(map[string]interface{})
+					node = object[key]
+				}
+				if node != nil && fmt.Sprint(node) == value {
+					matched = append(matched, id)
+				}
+			}
+		}
+		sort.Slice(matched, func(i, j int) bool { return matched[i].(string) < matched[j].(string) })
+
+		if strings.Contains(query, "count(*)") {
+			return testutil.StubResult{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(len(matched))}}}, nil
+		}
+		result := testutil.StubResult{Columns: []string{"id", "user_id", "source_meta"}}
+		for _, id := range matched {
+			result.Rows = append(result.Rows, []driver.Value{id, userID.String(), metas[id.(string)]})
+		}
+		return result, nil
+	})
+}
+
+func TestGetBySourceMeta(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	userID := uuid.New()
+	ids := []string{uuid.NewString(), uuid.NewString(), uuid.NewString(), uuid.NewString()}
+	sourceMetaTable(stub, userID, map[string]string{
+		ids[0]: `{"source":"mock_aa","bank":{"ifsc":"HDFC0001"}}`,
+		ids[1]: `{"source":"mock_aa","bank":{"ifsc":"SBIN0002"}}`,
+		ids[2]: `{"source":"setu","bank":{"ifsc":"HDFC0001"}}`,
+		ids[3]: `{"source":"setu"}`,
+	})
+	repository := NewTransactionRepository(db)
+
+	tests := []struct {
+		path  []string
+		value string
+		want  []string
+	}{
+		{[]string{"source"}, "mock_aa", []string{ids[0], ids[1]}},
+		{[]string{"bank", "ifsc"}, "HDFC0001", []string{ids[0], ids[2]}},
+		{[]string{"bank", "ifsc"}, "ICIC0003", nil},
+		{[]string{"bank", "missing"}, "HDFC0001", nil},
+	}
+	for _, tt := range tests {
+		transactions, total, err := repository.GetBySourceMeta(context.Background(), userID, tt.path, tt.value, 50, 0)
+		if err != nil {
+			t.Fatalf("%v = %s: %v", tt.path, tt.value, err)
+		}
+		var got []string
+		for _, txn := range transactions {
+			got = append(got, txn.ID.String())
+		}
+		sort.Strings(tt.want)
+		sort.Strings(got)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) || total != int64(len(tt.want)) {
+			t.Errorf("%v = %s: got %v (total %d), want %v", tt.path, tt.value, got, total, tt.want)
+		}
+	}
+
+	// Keys and values are bound, never spliced into the SQL
+	hostile := "x' OR '1'='1"
+	if _, _, err := repository.GetBySourceMeta(context.Background(), userID, []string{hostile}, hostile, 50, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, ran := range stub.Ran(`FROM "transactions"`) {
+		if strings.Contains(ran.SQL, "OR '1'") {
+			t.Errorf("user input reached the SQL text:\n%s", ran.SQL)
+		}
+	}
+	if _, _, err := repository.GetBySourceMeta(context.Background(), userID, nil, "mock_aa", 50, 0); err == nil {
+		t.Error("an empty path was accepted")
+	}
+}
+
+func TestExistingHashesLooksUpInBatches(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`FROM "transactions"`, []string{"hash_dedupe"}, []driver.Value{"hash-3"}, []driver.Value{"hash-1100"})
+	hashes := make([]string, 1100)
+	for i := range hashes {
+		hashes[i] = fmt.Sprintf("hash-%d", i)
+	}
+
+	existing, err := NewTransactionRepository(db).ExistingHashes(context.Background(), hashes)
+	if err != nil {
+		t.Fatalf("ExistingHashes: %v", err)
+	}
+	if len(existing) != 2 || !existing["hash-3"] {
+		t.Errorf("existing = %v, want the hashes the database returned", existing)
+	}
+
+	ran := stub.Ran(`FROM "transactions"`)
+	if len(ran) != 3 {
+		t.Fatalf("ran %d queries for 1100 hashes, want 3 batches", len(ran))
+	}
+	for i, want := range []int{existingHashBatchSize, existingHashBatchSize, 100} {
+		if len(ran[i].Args) != want {
+			t.Errorf("batch %d bound %d hashes, want %d", i, len(ran[i].Args), want)
+		}
+		// Soft-deleted rows still hold their hash in the unique index
+		if strings.Contains(ran[i].SQL, "deleted_at") {
+			t.Errorf("batch %d skips soft-deleted rows:\n%s", i, ran[i].SQL)
+		}
+	}
+
+	if existing, err := NewTransactionRepository(db).ExistingHashes(context.Background(), nil); err != nil || len(existing) != 0 {
+		t.Errorf("no hashes = %v, %v", existing, err)
+	}
+	if len(stub.Ran(`FROM "transactions"`)) != 3 {
+		t.Error("an empty lookup queried the database")
+	}
+}
+
+// keysetRow is a transaction as far as keyset paging is concerned
+type keysetRow struct {
+	id       uuid.UUID
+	postedAt time.Time
+}
+
+// keysetTable answers GetPageByUserID's query from rows, applying its
+// (posted_at, id) seek, order and limit the way Postgres would
+func keysetTable(stub *testutil.StubDB, rows []keysetRow) {
+	limitPattern := regexp.MustCompile(`LIMIT (\$?)(\d+)`)
+	stub.Handle(`FROM "transactions"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		sorted := append([]keysetRow(nil), rows...)
+		sort.Slice(sorted, func(i, j int) bool { return keysetBefore(sorted[i], sorted[j]) })
+
+		if strings.Contains(query, "(posted_at, id) < ($2, $3)") {
+			after := keysetRow{postedAt: args[1].(time.Time), id: uuid.MustParse(fmt.Sprint(args[2]))}
+			var rest []keysetRow
+			for _, row := range sorted {
+				if keysetBefore(after, row) {
+					rest = append(rest, row)
+				}
+			}
+			sorted = rest
+		}
+		match := limitPattern.FindStringSubmatch(query)
+		limit, _ := strconv.Atoi(match[2])
+		if match[1] == "$" {
+			limit = int(args[limit-1].(int64))
+		}
+		if len(sorted) > limit {
+			sorted = sorted[:limit]
+		}
+
+		result := testutil.StubResult{Columns: []string{"id", "posted_at"}}
+		for _, row := range sorted {
+			result.Rows = append(result.Rows, []driver.Value{row.id.String(), row.postedAt})
+		}
+		return result, nil
+	})
+}
+
+// keysetBefore reports whether a comes before b in posted_at DESC, id DESC order
+func keysetBefore(a, b keysetRow) bool {
+	if !a.postedAt.Equal(b.postedAt) {
+		return a.postedAt.After(b.postedAt)
+	}
+	return a.id.String() > b.id.String()
+}
+
+func TestGetPageByUserIDPagesWithoutGapsOrRepeats(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	// Three transactions share each posting time, so pages split ties
+	var rows []keysetRow
+	posted := time.Date(2025, time.March, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 23; i++ {
+		rows = append(rows, keysetRow{id: uuid.New(), postedAt: posted.Add(time.Duration(i/3) * time.Hour)})
+	}
+	keysetTable(stub, rows)
+	repository := NewTransactionRepository(db)
+
+	seen := make(map[uuid.UUID]bool)
+	var order []keysetRow
+	var cursor *TransactionCursor
+	for pages := 0; ; pages++ {
+		if pages > len(rows) {
+			t.Fatal("paging never reached the last page")
+		}
+		page, next, err := repository.GetPageByUserID(context.Background(), uuid.New(), nil, nil, cursor, 5)
+		if err != nil {
+			t.Fatalf("page %d: %v", pages, err)
+		}
+		for _, txn := range page {
+			if seen[txn.ID] {
+				t.Fatalf("page %d repeats %s", pages, txn.ID)
+			}
+			seen[txn.ID] = true
+			order = append(order, keysetRow{id: txn.ID, postedAt: txn.PostedAt})
+		}
+		if next == nil {
+			if len(page) != 3 {
+				t.Errorf("last page has %d transactions, want the 3 left over", len(page))
+			}
+			break
+		}
+		if len(page) != 5 {
+			t.Fatalf("page %d has %d transactions before the end", pages, len(page))
+		}
+		// The cursor round-trips through its token as a client would send it
+		if cursor, err = DecodeTransactionCursor(next.Encode()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(order) != len(rows) {
+		t.Fatalf("paged through %d of %d transactions", len(order), len(rows))
+	}
+	for i := 1; i < len(order); i++ {
+		if !keysetBefore(order[i-1], order[i]) {
+			t.Fatalf("transactions %d and %d are out of order", i-1, i)
+		}
+	}
+	for _, ran := range stub.Ran(`FROM "transactions"`) {
+		if !strings.Contains(ran.SQL, "ORDER BY posted_at DESC, id DESC") || strings.Contains(ran.SQL, "OFFSET") {
+			t.Errorf("page query isn't a keyset seek:\n%s", ran.SQL)
+		}
+	}
+}
+
+func TestClaimForReplayOnlyClaimsFailedEvents(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	events := NewWebhookEventRepository(db)
+	id := uuid.New()
+
+	if claimed, err := events.ClaimForReplay(context.Background(), id); err != nil || !claimed {
+		t.Fatalf("ClaimForReplay = %v, %v; want the event claimed", claimed, err)
+	}
+	ran := stub.Ran(`UPDATE "webhook_events"`)
+	if len(ran) != 1 || !strings.Contains(ran[0].SQL, "id = $2 AND status = $3") {
+		t.Fatalf("claim = %+v, want a conditional update", ran)
+	}
+	if !containsArgs(ran[0].Args, "REPLAYING", id, "FAILED") {
+		t.Errorf("args = %v, want FAILED moved to REPLAYING", ran[0].Args)
+	}
+
+	// A concurrent replay claimed it first, so no row matches
+	stub.Handle(`UPDATE "webhook_events"`, func(string, []driver.Value) (testutil.StubResult, error) {
+		return testutil.StubResult{Affected: 0}, nil
+	})
+	if claimed, err := events.ClaimForReplay(context.Background(), id); err != nil || claimed {
+		t.Errorf("second claim = %v, %v; want it refused", claimed, err)
+	}
+}
+
+func TestGetNeedsReview(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	userID := uuid.New()
+	stub.On(`SELECT count(*) FROM "transactions"`, []string{"count"}, []driver.Value{int64(3)})
+	stub.On(`SELECT * FROM "transactions"`, []string{"id", "user_id", "needs_review"},
+		[]driver.Value{uuid.NewString(), userID.String(), true})
+
+	transactions, total, err := NewTransactionRepository(db).GetNeedsReview(context.Background(), userID, 1, 2)
+	if err != nil {
+		t.Fatalf("GetNeedsReview: %v", err)
+	}
+	if total != 3 || len(transactions) != 1 || !transactions[0].NeedsReview {
+		t.Errorf("got %d of %d, want a page of 1 from 3 flagged", len(transactions), total)
+	}
+
+	ran := stub.Ran(`SELECT * FROM "transactions"`)
+	if len(ran) != 1 {
+		t.Fatalf("ran %d page queries, want 1", len(ran))
+	}
+	query := ran[0].SQL
+	for _, want := range []string{"user_id = $1 AND needs_review", `"deleted_at" IS NULL`, "ORDER BY posted_at DESC,id", "LIMIT 1 OFFSET 2"} {
+		if !strings.Contains(query, want) {
+			t.Errorf("page query %s\nmissing %q", query, want)
+		}
+	}
+	if !containsArgs(ran[0].Args, userID) {
+		t.Errorf("args %v don't scope to the user", ran[0].Args)
+	}
+}
+
+func TestGetFlagged(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	userID := uuid.New()
+	stub.On(`SELECT count(*) FROM "transactions"`, []string{"count"}, []driver.Value{int64(2)})
+	stub.On(`SELECT * FROM "transactions"`, []string{"id", "user_id", "flag_reason"},
+		[]driver.Value{uuid.NewString(), userID.String(), "CATEGORY_OUTLIER"})
+
+	transactions, total, err := NewTransactionRepository(db).GetFlagged(context.Background(), userID, 1, 0)
+	if err != nil {
+		t.Fatalf("GetFlagged: %v", err)
+	}
+	if total != 2 || len(transactions) != 1 || transactions[0].FlagReason != "CATEGORY_OUTLIER" {
+		t.Errorf("got %d of %d, want a page of 1 from 2 flagged", len(transactions), total)
+	}
+
+	ran := stub.Ran(`SELECT * FROM "transactions"`)
+	if len(ran) != 1 {
+		t.Fatalf("ran %d page queries, want 1", len(ran))
+	}
+	for _, want := range []string{"user_id = $1 AND flag_reason <> ''", `"deleted_at" IS NULL`, "ORDER BY posted_at DESC,id", "LIMIT 1"} {
+		if !strings.Contains(ran[0].SQL, want) {
+			t.Errorf("page query %s\nmissing %q", ran[0].SQL, want)
+		}
+	}
+}
+
+func TestGetAmountStats(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	userID := uuid.New()
+	since := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	stub.On(`FROM "transactions"`, []string{"category", "txn_type", "count", "sum", "sum_squares"},
+		[]driver.Value{"Food & Dining", "DEBIT", int64(4), 1200.0, 370000.0},
+		[]driver.Value{"Income", "CREDIT", int64(1), 50000.0, 2.5e9},
+	)
+
+	stats, err := NewTransactionRepository(db).GetAmountStats(context.Background(), userID, since)
+	if err != nil {
+		t.Fatalf("GetAmountStats: %v", err)
+	}
+	want := map[AmountStatsKey]AmountStats{
+		{Category: "Food & Dining", TxnType: "DEBIT"}: {Count: 4, Sum: 1200, SumSquares: 370000},
+		{Category: "Income", TxnType: "CREDIT"}:       {Count: 1, Sum: 50000, SumSquares: 2.5e9},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("stats = %+v", stats)
+	}
+	for key, expected := range want {
+		if stats[key] != expected {
+			t.Errorf("%+v = %+v, want %+v", key, stats[key], expected)
+		}
+	}
+
+	ran := stub.Ran(`FROM "transactions"`)
+	if len(ran) != 1 || !containsArgs(ran[0].Args, userID, since) {
+		t.Fatalf("stats query not scoped to the user and lookback: %+v", ran)
+	}
+	// Debits stored as negative amounts add to the same norm as positive ones
+	for _, want := range []string{"SUM(ABS(base_amount))", "reversal_of IS NULL", "UPPER(txn_type)"} {
+		if !strings.Contains(ran[0].SQL, want) {
+			t.Errorf("stats query %s\nmissing %q", ran[0].SQL, want)
+		}
+	}
+}
+
+func TestGetSummaryCountsSplitParts(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(summaryTotalsSQL, []string{"total_debit", "total_credit"}, []driver.Value{5000.0, 0.0})
+	// A 5000 shopping debit split into 2000 groceries and 3000 electronics
+	stub.On(summaryBreakdownSQL, []string{"category", "total_debit", "total_credit", "count"},
+		[]driver.Value{"Food & Dining", 2000.0, 0.0, int64(1)},
+		[]driver.Value{"Shopping", 3000.0, 0.0, int64(1)},
+	)
+	userID := uuid.New()
+
+	summary, err := NewTransactionRepository(db).GetSummary(context.Background(), userID, nil, nil)
+	if err != nil {
+		t.Fatalf("GetSummary: %v", err)
+	}
+	if summary.CategoryBreakdown["Food & Dining"].TotalDebit != 2000 || summary.CategoryBreakdown["Shopping"].TotalDebit != 3000 {
+		t.Errorf("breakdown = %+v, want the split parts", summary.CategoryBreakdown)
+	}
+
+	ran := stub.Ran(summaryBreakdownSQL)
+	if len(ran) != 1 {
+		t.Fatalf("breakdown ran %d times", len(ran))
+	}
+	query := ran[0].SQL
+	for _, want := range []string{
+		"NOT EXISTS (SELECT 1 FROM transaction_splits WHERE transaction_splits.transaction_id = transactions.id)",
+		"JOIN transaction_splits ON transaction_splits.transaction_id = transactions.id",
+		"transaction_splits.base_amount",
+		`GROUP BY "category"`,
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("breakdown query %s\nmissing %q", query, want)
+		}
+	}
+	// Both halves of the union are limited to the user
+	if n := strings.Count(fmt.Sprint(ran[0].Args), userID.String()); n != 2 {
+		t.Errorf("user bound %d times in %v, want once per half", n, ran[0].Args)
+	}
+}
+
+func TestReplaceSplits(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	transactionID := uuid.New()
+	splits := []domain.TransactionSplit{{Amount: 2000, BaseAmount: 2000, Category: "Food & Dining"}, {Amount: 3000, BaseAmount: 3000, Category: "Shopping"}}
+
+	if err := NewTransactionRepository(db).ReplaceSplits(context.Background(), transactionID, splits); err != nil {
+		t.Fatalf("ReplaceSplits: %v", err)
+	}
+	deleted := stub.Ran(`DELETE FROM "transaction_splits"`)
+	if len(deleted) != 1 || !containsArgs(deleted[0].Args, transactionID) {
+		t.Fatalf("old parts not deleted: %+v", deleted)
+	}
+	inserted := stub.Ran(`INSERT INTO "transaction_splits"`)
+	if len(inserted) != 1 || strings.Count(fmt.Sprint(inserted[0].Args), transactionID.String()) != 2 {
+		t.Fatalf("parts not inserted under the transaction: %+v", inserted)
+	}
+
+	// Unsplitting only deletes
+	if err := NewTransactionRepository(db).ReplaceSplits(context.Background(), transactionID, nil); err != nil {
+		t.Fatalf("ReplaceSplits: %v", err)
+	}
+	if len(stub.Ran(`DELETE FROM "transaction_splits"`)) != 2 || len(stub.Ran(`INSERT INTO "transaction_splits"`)) != 1 {
+		t.Error("unsplitting inserted parts")
+	}
+}
+
+// ilike reports whether s matches the LIKE pattern case-insensitively, with
+// backslash escapes, as Postgres' ILIKE does
+func ilike(pattern, s string) bool {
+	var expr strings.Builder
+	expr.WriteString("(?is)^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			expr.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		case c == '%':
+			expr.WriteString(".*")
+		case c == '_':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String()).MatchString(s)
+}
+
+func TestSearchMatchesPartsOfDescriptionsAndMerchantsInAnyCase(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	userID := uuid.New()
+	rows := []struct{ id, description, merchant string }{
+		{uuid.NewString(), "POS AMAZON PAY INDIA", "Amazon"},
+		{uuid.NewString(), "UPI/amzn/ORDER 123", "amazon.in"},
+		{uuid.NewString(), "NEFT REFUND", "Amazon Seller Services"},
+		{uuid.NewString(), "UPI/SWIGGY/ORDER 9", "Food Delivery"},
+		{uuid.NewString(), "100% cashback", "Paytm"},
+		{uuid.NewString(), "1000 cashback", "Paytm"},
+	}
+	matching := func(args []driver.Value) []int {
+		var matched []int
+		for i, row := range rows {
+			if ilike(args[1].(string), row.description) || ilike(args[2].(string), row.merchant) {
+				matched = append(matched, i)
+			}
+		}
+		return matched
+	}
+	stub.Handle(`SELECT count(*) FROM "transactions"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		return testutil.StubResult{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(len(matching(args)))}}}, nil
+	})
+	stub.Handle(`SELECT * FROM "transactions"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		result := testutil.StubResult{Columns: []string{"id", "user_id", "description_raw", "merchant_name"}}
+		for _, i := range matching(args) {
+			result.Rows = append(result.Rows, []driver.Value{rows[i].id, userID.String(), rows[i].description, rows[i].merchant})
+		}
+		return result, nil
+	})
+	repository := NewTransactionRepository(db)
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"amazon", 3},
+		{"AMAZON", 3},
+		{"aMaZ", 3},
+		{"order", 2},
+		{"100%", 1}, // a literal percent sign, not a wildcard
+		{"flipkart", 0},
+	}
+	for _, tt := range tests {
+		transactions, total, err := repository.Search(context.Background(), userID, tt.query, false, 50, 0)
+		if err != nil {
+			t.Fatalf("Search(%q): %v", tt.query, err)
+		}
+		if total != int64(tt.want) || len(transactions) != tt.want {
+			t.Errorf("Search(%q) = %d of %d, want %d", tt.query, len(transactions), total, tt.want)
+		}
+	}
+
+	ran := stub.Ran(`SELECT * FROM "transactions"`)
+	for _, want := range []string{"description_raw ILIKE $2 OR merchant_name ILIKE $3", `"deleted_at" IS NULL`, "ORDER BY posted_at DESC, id LIMIT 50"} {
+		if !strings.Contains(ran[0].SQL, want) {
+			t.Errorf("search query %s\nmissing %q", ran[0].SQL, want)
+		}
+	}
+	if !containsArgs(ran[0].Args, userID) {
+		t.Errorf("args %v don't scope to the user", ran[0].Args)
+	}
+}
+
+func TestSearchByRelevanceRanksMerchantMatchesFirst(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	userID := uuid.New()
+
+	if _, _, err := NewTransactionRepository(db).Search(context.Background(), userID, "amazon_", true, 10, 20); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	ran := stub.Ran(`SELECT * FROM "transactions"`)
+	if len(ran) != 1 {
+		t.Fatalf("ran %d page queries, want 1", len(ran))
+	}
+	query := ran[0].SQL
+	rank := "ORDER BY CASE WHEN merchant_name ILIKE $4 THEN 0 WHEN merchant_name ILIKE $5 THEN 1 ELSE 2 END, posted_at DESC, id"
+	if !strings.Contains(query, rank) || !strings.Contains(query, "LIMIT 10 OFFSET 20") {
+		t.Errorf("search query %s\nwant ranked by %q", query, rank)
+	}
+	// The wildcard in the input is escaped in every pattern
+	if !containsArgs(ran[0].Args, `%amazon\_%`, `amazon\_`, `amazon\_%`) {
+		t.Errorf("args %v, want the escaped patterns", ran[0].Args)
+	}
+}
@@ -0,0 +1,209 @@
+// Package testutil provides test doubles shared by the packages of the module
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// StubDB stands in for Postgres behind gorm: SQL is answered by the most
+// recently registered handler whose pattern the SQL contains, and every
+// statement is recorded so tests can assert on writes. Unmatched queries
+// return no rows and unmatched statements affect one row. Transactions are
+// recorded as BEGIN, COMMIT and ROLLBACK statements, which handlers can
+// answer like any other SQL.
+type StubDB struct {
+	mu         sync.Mutex
+	handlers   []handler
+	statements []StubStatement
+}
+
+// StubResult answers one query with columns and rows, or one statement
+// with the number of rows it affected
+type StubResult struct {
+	Columns  []string
+	Rows     [][]driver.Value
+	Affected int64
+}
+
+type handler struct {
+	contains string
+	answer   func(query string, args []driver.Value) (StubResult, error)
+}
+
+// StubStatement is a recorded statement with its arguments
+type StubStatement struct {
+	SQL  string
+	Args []driver.Value
+}
+
+// NewStubDB returns a gorm.DB on the Postgres dialect backed by a StubDB
+func NewStubDB(t testing.TB) (*gorm.DB, *StubDB) {
+	t.Helper()
+	stub := &StubDB{}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(stub)}), &gorm.Config{
+		Logger:                 logger.Discard,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("open stub database: %v", err)
+	}
+	return db, stub
+}
+
+// On answers queries containing contains with columns and rows
+func (s *StubDB) On(contains string, columns []string, rows ...[]driver.Value) {
+	s.Handle(contains, func(string, []driver.Value) (StubResult, error) {
+		return StubResult{Columns: columns, Rows: rows}, nil
+	})
+}
+
+// Fail makes SQL containing contains return err
+func (s *StubDB) Fail(contains string, err error) {
+	s.Handle(contains, func(string, []driver.Value) (StubResult, error) { return StubResult{}, err })
+}
+
+// Handle answers SQL containing contains by calling answer with the SQL and
+// its arguments
+func (s *StubDB) Handle(contains string, answer func(query string, args []driver.Value) (StubResult, error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, handler{contains: contains, answer: answer})
+}
+
+// Ran returns the recorded queries and statements containing contains.
+// Inserts with RETURNING are sent as queries.
+func (s *StubDB) Ran(contains string) []StubStatement {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []StubStatement
+	for _, statement := range s.statements {
+		if strings.Contains(statement.SQL, contains) {
+			out = append(out, statement)
+		}
+	}
+	return out
+}
+
+// run records the SQL and returns the answer of the matching handler, or
+// nil when no handler matches
+func (s *StubDB) run(query string, args []driver.NamedValue) (*StubResult, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+
+	s.mu.Lock()
+	s.statements = append(s.statements, StubStatement{SQL: query, Args: values})
+	var answer func(string, []driver.Value) (StubResult, error)
+	for i := len(s.handlers) - 1; i >= 0; i-- {
+		if strings.Contains(query, s.handlers[i].contains) {
+			answer = s.handlers[i].answer
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if answer == nil {
+		return nil, nil
+	}
+	result, err := answer(query, values)
+	return &result, err
+}
+
+var insertColumnsPattern = regexp.MustCompile(`^INSERT INTO "\w+" \(([^)]*)\)`)
+
+// InsertedValues maps the columns of an INSERT to its first row of arguments
+func InsertedValues(query string, args []driver.Value) map[string]driver.Value {
+	values := make(map[string]driver.Value)
+	match := insertColumnsPattern.FindStringSubmatch(query)
+	if match == nil {
+		return values
+	}
+	for i, column := range strings.Split(match[1], ",") {
+		if i < len(args) {
+			values[strings.Trim(column, `"`)] = args[i]
+		}
+	}
+	return values
+}
+
+// Connect and Driver make StubDB a driver.Connector
+func (s *StubDB) Connect(context.Context) (driver.Conn, error) { return conn{s}, nil }
+func (s *StubDB) Driver() driver.Driver                        { return nil }
+
+type conn struct{ db *StubDB }
+
+func (c conn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c conn) Close() error                        { return nil }
+func (c conn) Begin() (driver.Tx, error) {
+	if _, err := c.db.run("BEGIN", nil); err != nil {
+		return nil, err
+	}
+	return tx{c.db}, nil
+}
+
+// CheckNamedValue passes every argument through unconverted
+func (c conn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return &rows{}, nil
+	}
+	return &rows{columns: result.Columns, rows: result.Rows}, nil
+}
+
+func (c conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return driver.RowsAffected(1), nil
+	}
+	return driver.RowsAffected(result.Affected), nil
+}
+
+type tx struct{ db *StubDB }
+
+func (t tx) Commit() error {
+	_, err := t.db.run("COMMIT", nil)
+	return err
+}
+
+func (t tx) Rollback() error {
+	_, err := t.db.run("ROLLBACK", nil)
+	return err
+}
+
+type rows struct {
+	columns []string
+	rows    [][]driver.Value
+	next    int
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
@@ -1,13 +1,21 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/your-github/expense-tracker-backend/config"
 	"github.com/your-github/expense-tracker-backend/database"
 	"github.com/your-github/expense-tracker-backend/routes"
+	"github.com/your-github/expense-tracker-backend/services"
+	"github.com/your-github/expense-tracker-backend/utils"
 )
 
 func main() {
@@ -19,18 +27,59 @@ func main() {
 		log.Fatal("Failed to load configuration:", err)
 	}
 
+	if cfg.Categories.TaxonomyFile != "" {
+		if err := utils.LoadTaxonomyFile(cfg.Categories.TaxonomyFile); err != nil {
+			log.Fatal("Failed to load category taxonomy: ", err)
+		}
+	}
+
 	// Initialise DB & auto-migrate
-	database.Connect()
+	if err := database.Connect(cfg); err != nil {
+		log.Fatal("Failed to connect to database: ", err)
+	}
 	database.Migrate(database.DB)
+	if err := services.PromoteAdmins(database.DB, cfg.Admin.Emails); err != nil {
+		log.Println("Failed to promote configured admins:", err)
+	}
 
 	// Build router
-	r := routes.SetupRouter(database.DB, cfg)
+	r, closeServices := routes.SetupRouter(database.DB, cfg)
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
-	log.Printf("Expense Tracker API listening on :%s", port)
-	if err := r.Run(":" + port); err != nil {
-		log.Fatal(err)
+
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: r,
+	}
+
+	go func() {
+		log.Printf("Expense Tracker API listening on :%s", port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Shutting down server...")
+
+	// Give in-flight requests time to finish before stopping background work
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("Error during server shutdown: %v", err)
+	}
+	closeServices()
+
+	if sqlDB, err := database.DB.DB(); err == nil {
+		sqlDB.Close()
 	}
+
+	log.Println("Server stopped")
 }
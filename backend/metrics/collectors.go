@@ -0,0 +1,154 @@
+package metrics
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// HTTP request metrics recorded by GinMiddleware
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests handled, by method, route and status.",
+	}, []string{"method", "route", "status"})
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency in seconds, by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+	HTTPRequestSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_size_bytes",
+		Help:    "HTTP request body sizes in bytes, by method and route.",
+		Buckets: SizeBuckets,
+	}, []string{"method", "route"})
+	HTTPResponseSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_response_size_bytes",
+		Help:    "HTTP response body sizes in bytes, by method and route.",
+		Buckets: SizeBuckets,
+	}, []string{"method", "route"})
+)
+
+// Account Aggregator metrics
+var (
+	AAConsentsInitiated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "aa_consents_initiated_total",
+		Help: "AA consent requests created.",
+	})
+	AAConsentsRevoked = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "aa_consents_revoked_total",
+		Help: "AA consents revoked by users.",
+	})
+	AATransactionsStored = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "aa_transactions_stored_total",
+		Help: "Transactions stored from AA data fetches.",
+	})
+	AAFetches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "aa_fetches_total",
+		Help: "AA data fetches processed, by outcome.",
+	}, []string{"outcome"})
+)
+
+// GinMiddleware records request counts, latencies and payload sizes by
+// matched route. Request bodies sent without a Content-Length aren't sized.
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		HTTPRequests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		HTTPRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+		if size := c.Request.ContentLength; size >= 0 {
+			HTTPRequestSize.WithLabelValues(method, route).Observe(float64(size))
+		}
+		HTTPResponseSize.WithLabelValues(method, route).Observe(float64(max(c.Writer.Size(), 0)))
+	}
+}
+
+// CacheStats is implemented by caches that can report size and hit counts
+type CacheStats interface {
+	GetStats() map[string]interface{}
+}
+
+var (
+	cachesMu sync.Mutex
+	caches   = map[string]CacheStats{}
+)
+
+func init() {
+	// Export every fetch outcome at zero so rates work from the first scrape
+	for _, outcome := range []string{"success", "error", "skipped"} {
+		AAFetches.WithLabelValues(outcome)
+	}
+	prometheus.MustRegister(cacheCollector{})
+}
+
+// RegisterCache exposes a cache's stats under the given name
+func RegisterCache(name string, cache CacheStats) {
+	cachesMu.Lock()
+	defer cachesMu.Unlock()
+	caches[name] = cache
+}
+
+// cacheCollector reads the registered caches' stats at scrape time
+type cacheCollector struct{}
+
+var (
+	cacheEntriesDesc = prometheus.NewDesc("cache_entries",
+		"Entries currently held in each cache.", []string{"cache"}, nil)
+	cacheHitsDesc = prometheus.NewDesc("cache_hits_total",
+		"Cache lookups that found a live entry.", []string{"cache"}, nil)
+	cacheMissesDesc = prometheus.NewDesc("cache_misses_total",
+		"Cache lookups that found nothing or an expired entry.", []string{"cache"}, nil)
+)
+
+func (cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheEntriesDesc
+	ch <- cacheHitsDesc
+	ch <- cacheMissesDesc
+}
+
+func (cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	cachesMu.Lock()
+	defer cachesMu.Unlock()
+
+	for name, cache := range caches {
+		stats := cache.GetStats()
+		for _, stat := range []struct {
+			desc *prometheus.Desc
+			kind prometheus.ValueType
+			key  string
+		}{
+			{cacheEntriesDesc, prometheus.GaugeValue, "size"},
+			{cacheHitsDesc, prometheus.CounterValue, "hits"},
+			{cacheMissesDesc, prometheus.CounterValue, "misses"},
+		} {
+			if v, ok := toFloat(stats[stat.key]); ok {
+				ch <- prometheus.MustNewConstMetric(stat.desc, stat.kind, v, name)
+			}
+		}
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
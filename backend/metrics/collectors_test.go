@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+// scrape serves r's /metrics endpoint and returns the exposition
+func scrape(t *testing.T, r *gin.Engine) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("scrape = %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	return w.Body.String()
+}
+
+func TestScrapeExposesExpectedMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware())
+	r.GET("/metrics", gin.WrapH(Handler()))
+	r.GET("/expenses/:id", func(c *gin.Context) {
+		c.String(http.StatusNotFound, "missing")
+	})
+
+	cache := utils.NewLRUCache(10, time.Minute)
+	cache.Set("a", 1)
+	cache.Get("a")
+	cache.Get("b")
+	RegisterCache("scrape_test", cache)
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/expenses/3", nil))
+	out := scrape(t, r)
+
+	for _, want := range []string{
+		"# TYPE http_requests_total counter",
+		`http_requests_total{method="GET",route="/expenses/:id",status="404"} 1`,
+		"# TYPE http_request_duration_seconds histogram",
+		`http_request_duration_seconds_bucket{method="GET",route="/expenses/:id",le="+Inf"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/expenses/:id"} 1`,
+		"# TYPE cache_entries gauge",
+		`cache_entries{cache="scrape_test"} 1`,
+		`cache_hits_total{cache="scrape_test"} 1`,
+		`cache_misses_total{cache="scrape_test"} 1`,
+		// Unlabelled counters are exported before their first increment
+		"aa_consents_initiated_total 0",
+		"aa_consents_revoked_total 0",
+		"aa_transactions_stored_total 0",
+		"# TYPE aa_fetches_total counter",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("scrape is missing %q", want)
+		}
+	}
+}
+
+func TestUnmatchedRoutesShareOneSeries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware())
+	r.GET("/metrics", gin.WrapH(Handler()))
+
+	for _, path := range []string{"/nope/1", "/nope/2"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	out := scrape(t, r)
+	if !strings.Contains(out, `http_requests_total{method="GET",route="unmatched",status="404"} 2`) {
+		t.Errorf("unmatched requests weren't folded into one series:\n%s", out)
+	}
+	if strings.Contains(out, "/nope/") {
+		t.Error("raw paths leaked into the route label")
+	}
+}
+
+func TestGinMiddlewareRecordsPayloadSizes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware())
+	r.GET("/metrics", gin.WrapH(Handler()))
+	r.POST("/sized/:id", func(c *gin.Context) {
+		c.String(http.StatusOK, strings.Repeat("y", 300))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/sized/1", strings.NewReader(strings.Repeat("x", 2000)))
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	out := scrape(t, r)
+
+	for _, want := range []string{
+		`http_request_size_bytes_sum{method="POST",route="/sized/:id"} 2000`,
+		`http_response_size_bytes_sum{method="POST",route="/sized/:id"} 300`,
+		`http_request_size_bytes_count{method="POST",route="/sized/:id"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("scrape is missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+// Package metrics defines the service's Prometheus collectors and serves
+// them for scraping.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// SizeBuckets are payload size buckets in bytes, from 100B to 10MB
+var SizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+
+// Handler serves the default Prometheus registry
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
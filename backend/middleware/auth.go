@@ -5,10 +5,35 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/your-github/expense-tracker-backend/utils"
 )
 
-func Auth(jwtSecret string) gin.HandlerFunc {
+// Context keys populated by Auth. The legacy controllers read a numeric
+// "userID" while the AA handlers read a UUID string under "user_id"; a token
+// only populates the key matching the kind of subject it carries.
+const (
+	ContextUserID   = "userID"
+	ContextUserUUID = "user_id"
+	ContextEmail    = "email"
+	ContextRole     = "role"
+)
+
+// SubjectKind is the kind of user ID a router's handlers expect in tokens
+type SubjectKind int
+
+// The two stacks keep users in separate tables, so a numeric legacy ID has
+// no UUID counterpart and vice versa. Each router accepts only its own kind.
+const (
+	NumericSubject SubjectKind = iota // legacy router, read from ContextUserID
+	UUIDSubject                       // internal AA router, read from ContextUserUUID
+)
+
+// Auth validates the bearer token and stores the caller's identity in the
+// context. It is shared by the legacy router and the internal AA router;
+// tokens whose subject isn't of kind are rejected rather than passed on
+// without a user ID.
+func Auth(jwtSecret string, kind SubjectKind) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		h := c.GetHeader("Authorization")
 		if h == "" {
@@ -21,7 +46,88 @@ func Auth(jwtSecret string) gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			return
 		}
-		c.Set("userID", claims.UserID)
+		if subjectKind(claims) != kind {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token was issued by the other API; sign in again through this one"})
+			return
+		}
+		SetIdentity(c, claims)
+		c.Next()
+	}
+}
+
+// subjectKind reports which stack issued claims; subjects that are neither
+// numeric nor UUIDs match no kind
+func subjectKind(claims *utils.Claims) SubjectKind {
+	if _, ok := claims.NumericUserID(); ok {
+		return NumericSubject
+	}
+	if _, err := uuid.Parse(claims.UserID); err == nil {
+		return UUIDSubject
+	}
+	return -1
+}
+
+// SetIdentity stores the token subject under the context key each stack expects
+func SetIdentity(c *gin.Context, claims *utils.Claims) {
+	if uid, ok := claims.NumericUserID(); ok {
+		c.Set(ContextUserID, uid)
+	} else if _, err := uuid.Parse(claims.UserID); err == nil {
+		c.Set(ContextUserUUID, claims.UserID)
+	}
+	if claims.Email != "" {
+		c.Set(ContextEmail, claims.Email)
+	}
+	role := claims.Role
+	if role == "" {
+		role = utils.RoleUser
+	}
+	c.Set(ContextRole, role)
+}
+
+// RequireRole lets through only callers whose token carries one of roles.
+// It must run after Auth, which has already verified the token signature
+// and rejected unknown role values.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasRole(c.GetString(ContextRole), roles) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func hasRole(role string, roles []string) bool {
+	for _, allowed := range roles {
+		if role == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleLookup returns the role the authenticated caller holds now, or "" if
+// their account no longer exists
+type RoleLookup func(c *gin.Context) (string, error)
+
+// RequireCurrentRole is RequireRole for routes where a token's role may be
+// stale: it also re-reads the role with lookup, so an admin who is demoted
+// or deleted loses access before their token expires.
+func RequireCurrentRole(lookup RoleLookup, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasRole(c.GetString(ContextRole), roles) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+		role, err := lookup(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check role"})
+			return
+		}
+		if !hasRole(role, roles) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
 		c.Next()
 	}
 }
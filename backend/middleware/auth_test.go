@@ -0,0 +1,271 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+const testJWTSecret = "test-jwt-secret"
+
+// identity is what a route behind Auth saw in its context
+type identity struct {
+	UserID   uint   `json:"user_id"`
+	HasUint  bool   `json:"has_uint"`
+	UserUUID string `json:"user_uuid"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+}
+
+// newAuthRouter serves /whoami, which echoes the identity Auth stored, and
+// /admin, which also requires the admin role, to tokens of kind
+func newAuthRouter(kind SubjectKind) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Auth(testJWTSecret, kind))
+	whoami := func(c *gin.Context) {
+		_, hasUint := c.Get(ContextUserID)
+		c.JSON(http.StatusOK, identity{
+			UserID:   c.GetUint(ContextUserID),
+			HasUint:  hasUint,
+			UserUUID: c.GetString(ContextUserUUID),
+			Email:    c.GetString(ContextEmail),
+			Role:     c.GetString(ContextRole),
+		})
+	}
+	r.GET("/whoami", whoami)
+	r.GET("/admin", RequireRole(utils.RoleAdmin), whoami)
+	return r
+}
+
+func authRequest(t *testing.T, r *gin.Engine, path, token string) (*httptest.ResponseRecorder, identity) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var id identity
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &id); err != nil {
+			t.Fatalf("decode identity: %v", err)
+		}
+	}
+	return w, id
+}
+
+func signClaims(t *testing.T, claims jwt.Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testJWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestAuthLegacyToken(t *testing.T) {
+	token, err := utils.GenerateToken(42, "", testJWTSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, id := authRequest(t, newAuthRouter(NumericSubject), "/whoami", token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if !id.HasUint || id.UserID != 42 || id.UserUUID != "" || id.Role != utils.RoleUser {
+		t.Errorf("identity = %+v, want numeric user 42 with the user role", id)
+	}
+}
+
+func TestAuthInternalToken(t *testing.T) {
+	userID := uuid.New()
+	token, err := utils.NewToken(userID.String(), "asha@example.com", utils.RoleUser, time.Hour, testJWTSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, id := authRequest(t, newAuthRouter(UUIDSubject), "/whoami", token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if id.HasUint || id.UserUUID != userID.String() || id.Email != "asha@example.com" {
+		t.Errorf("identity = %+v, want UUID %s and no numeric ID", id, userID)
+	}
+}
+
+func TestAuthTokenSignedBeforeClaimsWereUnified(t *testing.T) {
+	// Old legacy tokens carried only a numeric "UserID" claim
+	token := signClaims(t, jwt.MapClaims{"UserID": 7, "exp": time.Now().Add(time.Hour).Unix()})
+
+	w, id := authRequest(t, newAuthRouter(NumericSubject), "/whoami", token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if !id.HasUint || id.UserID != 7 {
+		t.Errorf("identity = %+v, want numeric user 7", id)
+	}
+}
+
+func TestAuthRejectsBadTokens(t *testing.T) {
+	expired, err := utils.NewToken("1", "", utils.RoleUser, -time.Minute, testJWTSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSecret, err := utils.NewToken("1", "", utils.RoleUser, time.Hour, "another-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"missing":        "",
+		"malformed":      "not-a-jwt",
+		"expired":        expired,
+		"wrong secret":   otherSecret,
+		"no subject":     signClaims(t, jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()}),
+		"unknown role":   signClaims(t, jwt.MapClaims{"user_id": "1", "role": "root", "exp": time.Now().Add(time.Hour).Unix()}),
+		"none algorithm": unsignedToken(t),
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if w, _ := authRequest(t, newAuthRouter(NumericSubject), "/whoami", token); w.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want 401", w.Code)
+			}
+		})
+	}
+}
+
+func TestAuthRejectsTokensFromTheOtherStack(t *testing.T) {
+	legacy, err := utils.GenerateToken(42, utils.RoleUser, testJWTSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	internal, err := utils.NewToken(uuid.NewString(), "asha@example.com", utils.RoleUser, time.Hour, testJWTSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		kind  SubjectKind
+		token string
+	}{
+		{"internal token on the legacy router", NumericSubject, internal},
+		{"legacy token on the internal router", UUIDSubject, legacy},
+		{"subject of neither kind", NumericSubject, signClaims(t, jwt.MapClaims{"user_id": "asha", "exp": time.Now().Add(time.Hour).Unix()})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, _ := authRequest(t, newAuthRouter(tt.kind), "/whoami", tt.token)
+			if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "other API") {
+				t.Errorf("status = %d %s, want 401 naming the other API", w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func unsignedToken(t *testing.T) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"user_id": "1"}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestRequireRole(t *testing.T) {
+	r := newAuthRouter(NumericSubject)
+	user, err := utils.GenerateToken(1, utils.RoleUser, testJWTSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := utils.GenerateToken(2, utils.RoleAdmin, testJWTSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w, _ := authRequest(t, r, "/admin", user); w.Code != http.StatusForbidden {
+		t.Errorf("user status = %d, want 403", w.Code)
+	}
+	if w, id := authRequest(t, r, "/admin", admin); w.Code != http.StatusOK || id.Role != utils.RoleAdmin {
+		t.Errorf("admin status = %d, identity = %+v", w.Code, id)
+	}
+}
+
+func TestRequireRoleChecksTheClaim(t *testing.T) {
+	r := newAuthRouter(NumericSubject)
+	tests := map[string]struct {
+		token string
+		want  int
+	}{
+		// A token without a role claim is a plain user token
+		"missing claim": {signClaims(t, jwt.MapClaims{"user_id": "3", "exp": time.Now().Add(time.Hour).Unix()}), http.StatusForbidden},
+		"empty claim":   {signClaims(t, jwt.MapClaims{"user_id": "3", "role": "", "exp": time.Now().Add(time.Hour).Unix()}), http.StatusForbidden},
+		"unknown claim": {signClaims(t, jwt.MapClaims{"user_id": "3", "role": "Admin", "exp": time.Now().Add(time.Hour).Unix()}), http.StatusUnauthorized},
+		"admin claim":   {signClaims(t, jwt.MapClaims{"user_id": "3", "role": "admin", "exp": time.Now().Add(time.Hour).Unix()}), http.StatusOK},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if w, _ := authRequest(t, r, "/admin", tt.token); w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireCurrentRole(t *testing.T) {
+	admin, err := utils.GenerateToken(2, utils.RoleAdmin, testJWTSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := utils.GenerateToken(1, utils.RoleUser, testJWTSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		stored    string
+		lookupErr error
+		want      int
+		looked    bool
+	}{
+		{"still an admin", admin, utils.RoleAdmin, nil, http.StatusOK, true},
+		{"demoted since the token was issued", admin, utils.RoleUser, nil, http.StatusForbidden, true},
+		{"account deleted", admin, "", nil, http.StatusForbidden, true},
+		{"lookup failed", admin, "", errors.New("connection refused"), http.StatusInternalServerError, true},
+		{"user token", user, utils.RoleAdmin, nil, http.StatusForbidden, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lookedUp uint
+			looked := false
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.GET("/admin", Auth(testJWTSecret, NumericSubject), RequireCurrentRole(func(c *gin.Context) (string, error) {
+				looked, lookedUp = true, c.GetUint(ContextUserID)
+				return tt.stored, tt.lookupErr
+			}, utils.RoleAdmin), func(c *gin.Context) { c.JSON(http.StatusOK, identity{}) })
+
+			w, _ := authRequest(t, r, "/admin", tt.token)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if looked != tt.looked {
+				t.Errorf("looked up the role: %v, want %v", looked, tt.looked)
+			}
+			if looked && lookedUp != 2 {
+				t.Errorf("looked up user %d, want the token subject 2", lookedUp)
+			}
+		})
+	}
+}
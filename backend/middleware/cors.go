@@ -13,13 +13,14 @@ func CORS() gin.HandlerFunc {
 			"http://127.0.0.1:3000",
 			"http://0.0.0.0:3000",
 		},
-		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders: []string{
 			"Origin",
 			"Authorization",
 			"Content-Type",
 			"Accept",
 			"X-Requested-With",
+			"Idempotency-Key",
 		},
 		ExposeHeaders: []string{"Content-Length"},
 		AllowCredentials: true,
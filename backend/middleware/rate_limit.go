@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// Defaults for the RateLimiter eviction settings
+const (
+	DefaultLimiterIdleTTL  = 10 * time.Minute
+	DefaultLimiterMaxCount = 10000
+)
+
+// RateLimiter implements per-client-IP rate limiting using the token bucket
+// algorithm. Limiters of clients idle for IdleTTL are evicted; a limiter left
+// idle for at least burst/rate would have refilled anyway, so eviction does
+// not change behavior as long as IdleTTL covers that. At most MaxLimiters
+// are kept, dropping the least recently used first, so a flood of distinct
+// IPs cannot grow memory without bound.
+type RateLimiter struct {
+	limiters map[string]*list.Element // values are *limiterEntry
+	lru      *list.List               // front is the most recently used
+	mutex    sync.Mutex
+	rate     rate.Limit
+	burst    int
+
+	// IdleTTL is how long an unused limiter is kept; 0 uses DefaultLimiterIdleTTL
+	IdleTTL time.Duration
+	// MaxLimiters caps how many clients are tracked; 0 uses DefaultLimiterMaxCount
+	MaxLimiters int
+	// Allowlist holds client addresses that are never limited
+	Allowlist []netip.Prefix
+}
+
+type limiterEntry struct {
+	key      string
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter creates a new rate limiter
+func NewRateLimiter(r rate.Limit, burst int) *RateLimiter {
+	return &RateLimiter{
+		limiters: make(map[string]*list.Element),
+		lru:      list.New(),
+		rate:     r,
+		burst:    burst,
+	}
+}
+
+// getLimiter returns the rate limiter for the given key, first evicting
+// idle limiters and, when a new one would exceed MaxLimiters, the least
+// recently used
+func (rl *RateLimiter) getLimiter(key string, now time.Time) *rate.Limiter {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	ttl := rl.idleTTL()
+	for oldest := rl.lru.Back(); oldest != nil; oldest = rl.lru.Back() {
+		if now.Sub(oldest.Value.(*limiterEntry).lastSeen) < ttl {
+			break
+		}
+		rl.evict(oldest)
+	}
+
+	if element, exists := rl.limiters[key]; exists {
+		entry := element.Value.(*limiterEntry)
+		entry.lastSeen = now
+		rl.lru.MoveToFront(element)
+		return entry.limiter
+	}
+
+	for rl.lru.Len() >= rl.maxLimiters() {
+		rl.evict(rl.lru.Back())
+	}
+	entry := &limiterEntry{key: key, limiter: rate.NewLimiter(rl.rate, rl.burst), lastSeen: now}
+	rl.limiters[key] = rl.lru.PushFront(entry)
+
+	return entry.limiter
+}
+
+// evict drops a limiter; the caller holds the mutex
+func (rl *RateLimiter) evict(element *list.Element) {
+	rl.lru.Remove(element)
+	delete(rl.limiters, element.Value.(*limiterEntry).key)
+}
+
+// Len returns how many client limiters are currently tracked
+func (rl *RateLimiter) Len() int {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	return rl.lru.Len()
+}
+
+func (rl *RateLimiter) maxLimiters() int {
+	if rl.MaxLimiters <= 0 {
+		return DefaultLimiterMaxCount
+	}
+	return rl.MaxLimiters
+}
+
+func (rl *RateLimiter) idleTTL() time.Duration {
+	if rl.IdleTTL <= 0 {
+		return DefaultLimiterIdleTTL
+	}
+	return rl.IdleTTL
+}
+
+// allowlisted reports whether ip is in the allowlist
+func (rl *RateLimiter) allowlisted(ip string) bool {
+	if len(rl.Allowlist) == 0 {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range rl.Allowlist {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware limits requests per client IP. Every limited response carries
+// X-RateLimit-Limit (the burst), X-RateLimit-Remaining and X-RateLimit-Reset
+// (seconds until the bucket is full again); rejected ones add Retry-After.
+func (rl *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Use IP address as the key for rate limiting
+		key := c.ClientIP()
+		if rl.allowlisted(key) {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		limiter := rl.getLimiter(key, now)
+		allowed := limiter.AllowN(now, 1)
+		tokens := limiter.TokensAt(now)
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(rl.burst))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(int(math.Max(0, math.Floor(tokens)))))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(rl.secondsUntil(float64(rl.burst)-tokens)))
+
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(max(1, rl.secondsUntil(1-tokens))))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": "Rate limit exceeded. Please try again later.",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// secondsUntil returns how many whole seconds it takes to refill the given
+// number of tokens
+func (rl *RateLimiter) secondsUntil(tokens float64) int {
+	if tokens <= 0 || rl.rate <= 0 {
+		return 0
+	}
+	return int(math.Ceil(tokens / float64(rl.rate)))
+}
+
+// RateLimit middleware limits each client IP to r requests per second with
+// the given burst
+func RateLimit(r rate.Limit, burst int) gin.HandlerFunc {
+	return NewRateLimiter(r, burst).Middleware()
+}
+
+// ParseAllowlist parses IP addresses and CIDR ranges, e.g. "10.0.0.1" or
+// "10.0.0.0/8", into prefixes for RateLimiter.Allowlist
+func ParseAllowlist(entries []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allowlist range %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowlist address %q: %w", entry, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// serveFrom serves a GET of path to r from the client address ip
+func serveFrom(r *gin.Engine, path, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = net.JoinHostPort(ip, "40000")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func newRateLimitRouter(limiter *RateLimiter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(limiter.Middleware())
+	r.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	r := newRateLimitRouter(NewRateLimiter(rate.Limit(1), 2))
+
+	for i, remaining := range []string{"1", "0"} {
+		w := serveFrom(r, "/ok", "192.0.2.1")
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d = %d, want 200 within the burst", i+1, w.Code)
+		}
+		if w.Header().Get("X-RateLimit-Limit") != "2" || w.Header().Get("X-RateLimit-Remaining") != remaining {
+			t.Errorf("request %d: limit %q, remaining %q; want 2, %s", i+1,
+				w.Header().Get("X-RateLimit-Limit"), w.Header().Get("X-RateLimit-Remaining"), remaining)
+		}
+		if w.Header().Get("X-RateLimit-Reset") == "" || w.Header().Get("Retry-After") != "" {
+			t.Errorf("request %d: reset %q, retry-after %q", i+1, w.Header().Get("X-RateLimit-Reset"), w.Header().Get("Retry-After"))
+		}
+	}
+
+	w := serveFrom(r, "/ok", "192.0.2.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("over the burst = %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "1" || w.Header().Get("X-RateLimit-Remaining") != "0" || w.Header().Get("X-RateLimit-Reset") != "2" {
+		t.Errorf("rejected: retry-after %q, remaining %q, reset %q; want 1, 0, 2",
+			w.Header().Get("Retry-After"), w.Header().Get("X-RateLimit-Remaining"), w.Header().Get("X-RateLimit-Reset"))
+	}
+
+	// Other clients have their own bucket
+	if w := serveFrom(r, "/ok", "192.0.2.2"); w.Code != http.StatusOK {
+		t.Errorf("another client = %d, want 200", w.Code)
+	}
+}
+
+func TestRateLimitPerGroup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(NewRateLimiter(rate.Limit(100), 100).Middleware())
+	r.GET("/api/expenses", func(c *gin.Context) { c.Status(http.StatusOK) })
+	auth := r.Group("/api")
+	auth.Use(NewRateLimiter(rate.Every(time.Minute), 2).Middleware())
+	auth.GET("/login", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for i := 0; i < 2; i++ {
+		if w := serveFrom(r, "/api/login", "192.0.2.1"); w.Code != http.StatusOK {
+			t.Fatalf("login %d = %d, want 200", i+1, w.Code)
+		}
+	}
+	w := serveFrom(r, "/api/login", "192.0.2.1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("X-RateLimit-Limit") != "2" {
+		t.Errorf("third login = %d with limit %q, want 429 from the auth group's bucket", w.Code, w.Header().Get("X-RateLimit-Limit"))
+	}
+	if w := serveFrom(r, "/api/expenses", "192.0.2.1"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "100" {
+		t.Errorf("read after the auth limit = %d with limit %q, want 200 under the global bucket", w.Code, w.Header().Get("X-RateLimit-Limit"))
+	}
+}
+
+func TestRateLimitAllowlist(t *testing.T) {
+	allowlist, err := ParseAllowlist([]string{"10.0.0.0/8", " 127.0.0.1 ", ""})
+	if err != nil {
+		t.Fatalf("ParseAllowlist: %v", err)
+	}
+	limiter := NewRateLimiter(rate.Every(time.Hour), 1)
+	limiter.Allowlist = allowlist
+	r := newRateLimitRouter(limiter)
+
+	for _, ip := range []string{"10.1.2.3", "127.0.0.1", "::ffff:127.0.0.1"} {
+		for i := 0; i < 3; i++ {
+			if w := serveFrom(r, "/ok", ip); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "" {
+				t.Fatalf("%s request %d = %d with limit %q, want unlimited", ip, i+1, w.Code, w.Header().Get("X-RateLimit-Limit"))
+			}
+		}
+	}
+	serveFrom(r, "/ok", "11.0.0.1")
+	if w := serveFrom(r, "/ok", "11.0.0.1"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("address outside the allowlist = %d, want 429", w.Code)
+	}
+
+	for _, bad := range []string{"10.0.0.0/33", "not-an-ip"} {
+		if _, err := ParseAllowlist([]string{bad}); err == nil {
+			t.Errorf("ParseAllowlist(%q) succeeded", bad)
+		}
+	}
+	if got, _ := ParseAllowlist([]string{"10.1.2.3/8"}); len(got) != 1 || got[0] != netip.MustParsePrefix("10.0.0.0/8") {
+		t.Errorf("ParseAllowlist(10.1.2.3/8) = %v, want the masked range", got)
+	}
+}
+
+func TestRateLimiterEvictsIdleLimiters(t *testing.T) {
+	limiter := NewRateLimiter(rate.Limit(1), 1)
+	limiter.IdleTTL = time.Minute
+	start := time.Now()
+
+	first := limiter.getLimiter("192.0.2.1", start)
+	limiter.getLimiter("192.0.2.2", start)
+	if limiter.getLimiter("192.0.2.1", start.Add(30*time.Second)) != first {
+		t.Fatal("a limiter in use was replaced")
+	}
+	limiter.getLimiter("192.0.2.2", start.Add(59*time.Second))
+
+	// 192.0.2.1 was last seen at +30s and 192.0.2.2 at +59s
+	limiter.getLimiter("192.0.2.3", start.Add(91*time.Second))
+	if _, ok := limiter.limiters["192.0.2.1"]; ok {
+		t.Error("a limiter idle past the TTL was kept")
+	}
+	if _, ok := limiter.limiters["192.0.2.2"]; !ok {
+		t.Error("a limiter used within the TTL was evicted")
+	}
+	if len(limiter.limiters) != 2 {
+		t.Errorf("%d limiters kept, want 2", len(limiter.limiters))
+	}
+}
+
+func TestRateLimiterStaysBoundedUnderManyClients(t *testing.T) {
+	limiter := NewRateLimiter(rate.Every(time.Hour), 3)
+	limiter.MaxLimiters = 100
+	r := newRateLimitRouter(limiter)
+
+	const active = "198.51.100.7"
+	allowed := 0
+	for i := 0; i < 5000; i++ {
+		// A scanner from ever new addresses, each within its own burst
+		ip := netip.AddrFrom4([4]byte{10, byte(i >> 16), byte(i >> 8), byte(i)}).String()
+		if w := serveFrom(r, "/ok", ip); w.Code != http.StatusOK {
+			t.Fatalf("new client %s = %d, want 200", ip, w.Code)
+		}
+		// Interleaved, the active client keeps its bucket and is limited
+		if i%50 == 0 && serveFrom(r, "/ok", active).Code == http.StatusOK {
+			allowed++
+		}
+		if n := limiter.Len(); n > 100 {
+			t.Fatalf("tracking %d clients after %d requests, want at most 100", n, i+1)
+		}
+	}
+	if allowed != 3 {
+		t.Errorf("active client was allowed %d of 100 requests, want its burst of 3", allowed)
+	}
+}
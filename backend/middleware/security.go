@@ -1,65 +1,12 @@
 package middleware
 
 import (
+	"mime"
 	"net/http"
-	"sync"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/time/rate"
 )
 
-// RateLimiter implements a simple rate limiter using token bucket algorithm
-type RateLimiter struct {
-	limiters map[string]*rate.Limiter
-	mutex    sync.RWMutex
-	rate     rate.Limit
-	burst    int
-}
-
-// NewRateLimiter creates a new rate limiter
-func NewRateLimiter(r rate.Limit, burst int) *RateLimiter {
-	return &RateLimiter{
-		limiters: make(map[string]*rate.Limiter),
-		rate:     r,
-		burst:    burst,
-	}
-}
-
-// getLimiter returns the rate limiter for the given key
-func (rl *RateLimiter) getLimiter(key string) *rate.Limiter {
-	rl.mutex.Lock()
-	defer rl.mutex.Unlock()
-
-	limiter, exists := rl.limiters[key]
-	if !exists {
-		limiter = rate.NewLimiter(rl.rate, rl.burst)
-		rl.limiters[key] = limiter
-	}
-
-	return limiter
-}
-
-// RateLimit middleware implements rate limiting
-func RateLimit(r rate.Limit, burst int) gin.HandlerFunc {
-	limiter := NewRateLimiter(r, burst)
-	
-	return func(c *gin.Context) {
-		// Use IP address as the key for rate limiting
-		key := c.ClientIP()
-		
-		if !limiter.getLimiter(key).Allow() {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "Rate limit exceeded. Please try again later.",
-			})
-			c.Abort()
-			return
-		}
-		
-		c.Next()
-	}
-}
-
 // SecurityHeaders adds security headers to responses
 func SecurityHeaders() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -88,13 +35,23 @@ func SecurityHeaders() gin.HandlerFunc {
 	}
 }
 
-// InputValidation validates and sanitizes input
-func InputValidation() gin.HandlerFunc {
+// InputValidation rejects request bodies over maxBodySize bytes and
+// POST/PUT/PATCH bodies that aren't JSON. multipartRoutes lists the route patterns (as
+// registered, e.g. "/api/expenses/:id/receipts") that accept
+// multipart/form-data uploads instead; they still get the size limit.
+func InputValidation(maxBodySize int64, multipartRoutes ...string) gin.HandlerFunc {
+	multipart := make(map[string]bool, len(multipartRoutes))
+	for _, route := range multipartRoutes {
+		multipart[route] = true
+	}
+
 	return func(c *gin.Context) {
-		// Validate content type for POST/PUT requests
-		if c.Request.Method == "POST" || c.Request.Method == "PUT" {
-			contentType := c.GetHeader("Content-Type")
-			if contentType != "application/json" {
+		// Validate content type for POST/PUT/PATCH requests that carry a body
+		if (c.Request.Method == "POST" || c.Request.Method == "PUT" || c.Request.Method == "PATCH") && c.Request.ContentLength != 0 {
+			mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+			allowed := mediaType == "application/json" ||
+				(mediaType == "multipart/form-data" && multipart[c.FullPath()])
+			if !allowed {
 				c.JSON(http.StatusBadRequest, gin.H{
 					"error": "Content-Type must be application/json",
 				})
@@ -102,50 +59,21 @@ func InputValidation() gin.HandlerFunc {
 				return
 			}
 		}
-		
-		// Validate request size
-		if c.Request.ContentLength > 10*1024*1024 { // 10MB limit
+
+		// Validate request size. Bodies without a declared length are cut
+		// off at the limit while being read.
+		if c.Request.ContentLength > maxBodySize {
 			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
 				"error": "Request body too large",
 			})
 			c.Abort()
 			return
 		}
-		
-		c.Next()
-	}
-}
-
-// RequestTimeout adds timeout to requests
-func RequestTimeout(timeout time.Duration) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Set timeout for the request
-		c.Request = c.Request.WithContext(c.Request.Context())
-		
-		// Create a timer for the timeout
-		timer := time.NewTimer(timeout)
-		defer timer.Stop()
-		
-		// Create a channel to signal completion
-		done := make(chan bool, 1)
-		
-		go func() {
-			c.Next()
-			done <- true
-		}()
-		
-		select {
-		case <-done:
-			// Request completed successfully
-			return
-		case <-timer.C:
-			// Request timed out
-			c.JSON(http.StatusRequestTimeout, gin.H{
-				"error": "Request timeout",
-			})
-			c.Abort()
-			return
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBodySize)
 		}
+
+		c.Next()
 	}
 }
 
@@ -173,8 +101,9 @@ func CORSSecurity() gin.HandlerFunc {
 			c.Header("Access-Control-Allow-Origin", origin)
 		}
 		
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Request-ID, Idempotency-Key")
+		c.Header("Access-Control-Expose-Headers", "X-Request-ID, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Retry-After")
 		c.Header("Access-Control-Allow-Credentials", "true")
 		c.Header("Access-Control-Max-Age", "86400")
 		
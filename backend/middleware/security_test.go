@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testMaxBodySize = 1 << 10
+
+// newValidationRouter serves a JSON route behind InputValidation for every
+// method that carries a body, and a multipart upload route; both echo the
+// number of body bytes they read
+func newValidationRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(InputValidation(testMaxBodySize, "/uploads/:id"))
+	readBody := func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"read": len(body)})
+	}
+	r.POST("/items", readBody)
+	r.PUT("/items", readBody)
+	r.PATCH("/items", readBody)
+	r.POST("/uploads/:id", readBody)
+	return r
+}
+
+func multipartBody(t *testing.T, size int) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "receipt.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(bytes.Repeat([]byte("x"), size))
+	writer.Close()
+	return &body, writer.FormDataContentType()
+}
+
+func TestInputValidation(t *testing.T) {
+	router := newValidationRouter()
+	upload, uploadType := multipartBody(t, 100)
+	oversizedUpload, oversizedUploadType := multipartBody(t, testMaxBodySize)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        io.Reader
+		want        int
+	}{
+		{"json", http.MethodPost, "/items", "application/json", strings.NewReader(`{"a":1}`), http.StatusOK},
+		{"json with charset", http.MethodPost, "/items", "application/json; charset=utf-8", strings.NewReader(`{}`), http.StatusOK},
+		{"empty body without content type", http.MethodPost, "/items", "", nil, http.StatusOK},
+		{"form post", http.MethodPost, "/items", "application/x-www-form-urlencoded", strings.NewReader("a=1"), http.StatusBadRequest},
+		{"json put", http.MethodPut, "/items", "application/json", strings.NewReader(`{"a":1}`), http.StatusOK},
+		{"text put", http.MethodPut, "/items", "text/plain", strings.NewReader("a"), http.StatusBadRequest},
+		{"json patch", http.MethodPatch, "/items", "application/json", strings.NewReader(`{"amount":300}`), http.StatusOK},
+		{"text patch", http.MethodPatch, "/items", "text/plain", strings.NewReader("amount=300"), http.StatusBadRequest},
+		{"form patch", http.MethodPatch, "/items", "application/x-www-form-urlencoded", strings.NewReader("amount=300"), http.StatusBadRequest},
+		{"multipart on an upload route", http.MethodPost, "/uploads/1", uploadType, upload, http.StatusOK},
+		{"multipart elsewhere", http.MethodPost, "/items", uploadType, bytes.NewReader(upload.Bytes()), http.StatusBadRequest},
+		{"oversized json", http.MethodPost, "/items", "application/json", strings.NewReader(`"` + strings.Repeat("x", testMaxBodySize) + `"`), http.StatusRequestEntityTooLarge},
+		{"oversized upload", http.MethodPost, "/uploads/1", oversizedUploadType, oversizedUpload, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, tt.body)
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestInputValidationCutsOffUndeclaredLength(t *testing.T) {
+	router := newValidationRouter()
+
+	// Without a Content-Length the limit applies while the body is read
+	body := io.MultiReader(strings.NewReader(`"`), strings.NewReader(strings.Repeat("x", 2*testMaxBodySize)))
+	req := httptest.NewRequest(http.MethodPost, "/items", body)
+	req.ContentLength = -1
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestCORSSecurityPreflight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(CORSSecurity())
+	r.PATCH("/api/expenses/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/expenses/3", nil)
+	req.Header.Set("Origin", "http://localhost:5173")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPatch)
+	req.Header.Set("Access-Control-Request-Headers", "content-type, idempotency-key")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("preflight = %d, want 204", w.Code)
+	}
+	if origin := w.Header().Get("Access-Control-Allow-Origin"); origin != "http://localhost:5173" {
+		t.Errorf("Allow-Origin = %q", origin)
+	}
+	if methods := w.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(methods, "PATCH") {
+		t.Errorf("Allow-Methods = %q, want PATCH for partial updates", methods)
+	}
+	if headers := w.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(headers, "Idempotency-Key") {
+		t.Errorf("Allow-Headers = %q, want Idempotency-Key", headers)
+	}
+}
+
+func TestCORSAllowsPatchPreflight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(CORS())
+	r.PATCH("/api/expenses/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/expenses/3", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPatch)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent || !strings.Contains(w.Header().Get("Access-Control-Allow-Methods"), "PATCH") {
+		t.Errorf("PATCH preflight = %d, Allow-Methods %q", w.Code, w.Header().Get("Access-Control-Allow-Methods"))
+	}
+}
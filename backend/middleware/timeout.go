@@ -0,0 +1,238 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// timeoutBody is the response sent when a request exceeds its deadline
+const timeoutBody = `{"error":"Request timeout"}`
+
+// RequestTimeout bounds each request with a context deadline. Handlers write
+// into a buffer that is copied to the client only if they finish in time;
+// otherwise the client gets a 408 and anything the handler writes later is
+// discarded. A handler that flushes commits what it has written so far and
+// streams the rest directly; a streamed response that overruns the deadline
+// is cut off rather than replaced. The middleware always waits for the
+// handler to return so the gin.Context is never reused while it is still
+// running.
+//
+// exemptRoutes lists route patterns (as registered) that run without a
+// deadline, such as long downloads.
+func RequestTimeout(timeout time.Duration, exemptRoutes ...string) gin.HandlerFunc {
+	exempt := make(map[string]bool, len(exemptRoutes))
+	for _, route := range exemptRoutes {
+		exempt[route] = true
+	}
+
+	return func(c *gin.Context) {
+		if exempt[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		original := c.Writer
+		tw := newTimeoutWriter(original)
+		c.Writer = tw
+
+		done := make(chan struct{})
+		panicked := make(chan handlerPanic, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicked <- handlerPanic{value: p, stack: debug.Stack()}
+				}
+				close(done)
+			}()
+			c.Next()
+		}()
+
+		select {
+		case <-done:
+			c.Writer = original
+			select {
+			case p := <-panicked:
+				// Re-raise on the request goroutine so the recovery middleware sees it
+				panic(p.value)
+			default:
+			}
+			tw.copyTo(original)
+		case <-ctx.Done():
+			if streamed := tw.timeOut(); streamed {
+				<-done
+				c.Writer = original
+				logLatePanic(c, panicked)
+				return
+			}
+			original.Header().Set("Content-Type", "application/json; charset=utf-8")
+			original.Header().Set("Content-Length", strconv.Itoa(len(timeoutBody)))
+			original.WriteHeader(http.StatusRequestTimeout)
+			original.WriteString(timeoutBody)
+			original.Flush()
+
+			<-done
+			c.Writer = original
+			logLatePanic(c, panicked)
+		}
+	}
+}
+
+// handlerPanic is a panic recovered from the handler goroutine, with the
+// stack it was raised on
+type handlerPanic struct {
+	value interface{}
+	stack []byte
+}
+
+// logLatePanic logs a panic raised by a handler after its request timed
+// out. The client already has a response by then, so re-raising would only
+// make the recovery middleware write over it.
+func logLatePanic(c *gin.Context, panicked <-chan handlerPanic) {
+	select {
+	case p := <-panicked:
+		log.Printf("Panic after request timeout on %s %s: %v\n%s", c.Request.Method, c.Request.URL.Path, p.value, p.stack)
+	default:
+	}
+}
+
+// timeoutWriter buffers a handler's response so it can be dropped if the
+// request times out, until the handler flushes and it starts streaming.
+// Hijack, CloseNotify and Pusher pass through to the embedded writer.
+type timeoutWriter struct {
+	gin.ResponseWriter
+
+	header http.Header
+	body   bytes.Buffer
+
+	mu        sync.Mutex
+	status    int
+	written   bool
+	timedOut  bool
+	streaming bool // the response was committed by Flush; writes go straight through
+}
+
+func newTimeoutWriter(w gin.ResponseWriter) *timeoutWriter {
+	return &timeoutWriter{
+		ResponseWriter: w,
+		header:         w.Header().Clone(),
+		status:         http.StatusOK,
+	}
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if code > 0 && !w.written && !w.timedOut {
+		w.status = code
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.written = true
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.written = true
+	if w.streaming {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.body.Write(b)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.streaming {
+		return w.ResponseWriter.Size()
+	}
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.written
+}
+
+// Flush commits the status, headers and buffered body to the client and
+// switches to streaming, so later writes are sent as they happen
+func (w *timeoutWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	if !w.streaming {
+		w.commit(w.ResponseWriter)
+		w.body.Reset()
+		w.streaming = true
+	}
+	w.ResponseWriter.Flush()
+}
+
+// timeOut makes every later write fail and reports whether the response
+// was already being streamed, in which case nothing else can be sent
+func (w *timeoutWriter) timeOut() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timedOut = true
+	return w.streaming
+}
+
+// copyTo sends the buffered response to dst unless it was already streamed
+func (w *timeoutWriter) copyTo(dst gin.ResponseWriter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.streaming {
+		w.commit(dst)
+	}
+}
+
+// commit writes the buffered status, headers and body to dst; w.mu must be held
+func (w *timeoutWriter) commit(dst gin.ResponseWriter) {
+	for k, v := range w.header {
+		dst.Header()[k] = v
+	}
+	dst.WriteHeader(w.status)
+	if w.body.Len() > 0 {
+		dst.Write(w.body.Bytes())
+	} else if w.written {
+		dst.WriteHeaderNow()
+	}
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTimeoutRouter(timeout time.Duration, exemptRoutes ...string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.RecoveryWithWriter(io.Discard), RequestTimeout(timeout, exemptRoutes...))
+	return r
+}
+
+func serve(r *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestRequestTimeoutFastHandler(t *testing.T) {
+	r := newTimeoutRouter(time.Second)
+	r.GET("/fast", func(c *gin.Context) {
+		c.Header("X-Test", "1")
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+
+	w := serve(r, "/fast")
+	if w.Code != http.StatusCreated || w.Header().Get("X-Test") != "1" || w.Body.String() != `{"ok":true}` {
+		t.Errorf("got %d %q %q", w.Code, w.Header().Get("X-Test"), w.Body)
+	}
+}
+
+func TestRequestTimeoutSlowHandler(t *testing.T) {
+	r := newTimeoutRouter(20 * time.Millisecond)
+	lateWrite := make(chan error, 1)
+	r.GET("/slow", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+		_, err := c.Writer.WriteString("late")
+		lateWrite <- err
+	})
+
+	w := serve(r, "/slow")
+	if w.Code != http.StatusRequestTimeout || w.Body.String() != timeoutBody {
+		t.Errorf("got %d %q, want 408 %q", w.Code, w.Body, timeoutBody)
+	}
+	// The middleware waited for the handler, whose late write was refused
+	select {
+	case err := <-lateWrite:
+		if !errors.Is(err, http.ErrHandlerTimeout) {
+			t.Errorf("late write error = %v, want ErrHandlerTimeout", err)
+		}
+	default:
+		t.Error("RequestTimeout returned before the handler finished")
+	}
+}
+
+func TestRequestTimeoutStreamsAfterFlush(t *testing.T) {
+	r := newTimeoutRouter(time.Second)
+	r.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/csv")
+		c.Writer.WriteString("a,b\n")
+		c.Writer.Flush()
+		c.Writer.WriteString("1,2\n")
+		c.Writer.Flush()
+	})
+
+	w := serve(r, "/stream")
+	if !w.Flushed {
+		t.Error("the response was not flushed to the client")
+	}
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv" || w.Body.String() != "a,b\n1,2\n" {
+		t.Errorf("got %d %q %q", w.Code, w.Header().Get("Content-Type"), w.Body)
+	}
+}
+
+func TestRequestTimeoutCutsOffStreamAtDeadline(t *testing.T) {
+	r := newTimeoutRouter(20 * time.Millisecond)
+	r.GET("/stream", func(c *gin.Context) {
+		c.Writer.WriteString("first\n")
+		c.Writer.Flush()
+		<-c.Request.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+		c.Writer.WriteString("second\n")
+	})
+
+	// Headers were already sent, so the stream ends instead of turning into a 408
+	w := serve(r, "/stream")
+	if w.Code != http.StatusOK || w.Body.String() != "first\n" {
+		t.Errorf("got %d %q, want 200 %q", w.Code, w.Body, "first\n")
+	}
+}
+
+func TestRequestTimeoutExemptRoute(t *testing.T) {
+	r := newTimeoutRouter(10*time.Millisecond, "/export")
+	r.GET("/export", func(c *gin.Context) {
+		if _, ok := c.Request.Context().Deadline(); ok {
+			t.Error("an exempt route got a deadline")
+		}
+		time.Sleep(30 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	})
+
+	if w := serve(r, "/export"); w.Code != http.StatusOK || w.Body.String() != "done" {
+		t.Errorf("got %d %q", w.Code, w.Body)
+	}
+}
+
+func TestRequestTimeoutPanicReachesRecovery(t *testing.T) {
+	r := newTimeoutRouter(time.Second)
+	r.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	if w := serve(r, "/panic"); w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+}
+
+func TestRequestTimeoutLogsPanicAfterDeadline(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	r := newTimeoutRouter(20 * time.Millisecond)
+	r.GET("/late-panic", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		panic("late boom")
+	})
+
+	if w := serve(r, "/late-panic"); w.Code != http.StatusRequestTimeout || w.Body.String() != timeoutBody {
+		t.Errorf("got %d %q, want the 408 to stand", w.Code, w.Body)
+	}
+	if out := logged.String(); !strings.Contains(out, "late boom") || !strings.Contains(out, "/late-panic") {
+		t.Errorf("log = %q, want the late panic with its route", out)
+	}
+}
@@ -0,0 +1,10 @@
+-- Track AA data sessions that have already been ingested so duplicate
+-- DATA_READY webhooks are acknowledged without reprocessing
+CREATE TABLE processed_sessions (
+  session_id TEXT PRIMARY KEY,
+  bank_link_id UUID REFERENCES bank_links(id) ON DELETE SET NULL,
+  transaction_count INTEGER NOT NULL DEFAULT 0,
+  processed_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX idx_processed_sessions_bank_link_id ON processed_sessions(bank_link_id);
@@ -0,0 +1,5 @@
+-- Remember when a consent renewal reminder was sent so each link is only
+-- reminded once per expiry
+ALTER TABLE bank_links ADD COLUMN expiry_reminder_sent_at TIMESTAMPTZ;
+
+CREATE INDEX idx_bank_links_active_valid_till ON bank_links(valid_till) WHERE status = 'ACTIVE';
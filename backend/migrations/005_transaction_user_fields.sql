@@ -0,0 +1,4 @@
+-- User-editable annotations on transactions. user_category, when set, takes
+-- precedence over the normalizer's category and survives renormalization.
+ALTER TABLE transactions ADD COLUMN user_note TEXT NOT NULL DEFAULT '';
+ALTER TABLE transactions ADD COLUMN user_category TEXT NOT NULL DEFAULT '';
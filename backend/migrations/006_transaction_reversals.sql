@@ -0,0 +1,4 @@
+-- Links a reversal/refund to the transaction it reverses. Both legs point at
+-- each other so summaries can exclude the pair and net it to zero.
+ALTER TABLE transactions ADD COLUMN reversal_of UUID REFERENCES transactions(id) ON DELETE SET NULL;
+CREATE INDEX idx_transactions_reversal_of ON transactions(reversal_of) WHERE reversal_of IS NOT NULL;
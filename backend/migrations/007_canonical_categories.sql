@@ -0,0 +1,57 @@
+-- Maps the inconsistent category labels written by older categorizers
+-- ("Transportation", "Utilities", "Uncategorized", lower-case names, ...) onto
+-- the canonical taxonomy in utils/categories.go. Custom labels are untouched.
+
+UPDATE transactions SET category =
+  CASE LOWER(TRIM(category))
+    WHEN 'food', 'food delivery', 'dining', 'restaurant', 'groceries', 'food & dining' THEN 'Food & Dining'
+    WHEN 'transportation', 'fuel', 'transport' THEN 'Transport'
+    WHEN 'online shopping', 'shopping' THEN 'Shopping'
+    WHEN 'entertainment' THEN 'Entertainment'
+    WHEN 'bills', 'utilities', 'insurance', 'bills & utilities' THEN 'Bills & Utilities'
+    WHEN 'health', 'medical', 'healthcare' THEN 'Healthcare'
+    WHEN 'education' THEN 'Education'
+    WHEN 'travel' THEN 'Travel'
+    WHEN 'salary', 'interest', 'dividend', 'bonus', 'refund', 'reimbursement', 'investment return', 'income' THEN 'Income'
+    WHEN 'transfer', 'bank transfer', 'digital payments', 'transfers' THEN 'Transfers'
+    WHEN 'atm', 'atm withdrawal', 'cash', 'cash withdrawal' THEN 'Cash Withdrawal'
+    WHEN 'uncategorized', 'other' THEN 'Other'
+    ELSE category
+  END
+WHERE category IS NOT NULL;
+
+UPDATE transactions SET user_category =
+  CASE LOWER(TRIM(user_category))
+    WHEN 'food', 'food delivery', 'dining', 'restaurant', 'groceries', 'food & dining' THEN 'Food & Dining'
+    WHEN 'transportation', 'fuel', 'transport' THEN 'Transport'
+    WHEN 'online shopping', 'shopping' THEN 'Shopping'
+    WHEN 'entertainment' THEN 'Entertainment'
+    WHEN 'bills', 'utilities', 'insurance', 'bills & utilities' THEN 'Bills & Utilities'
+    WHEN 'health', 'medical', 'healthcare' THEN 'Healthcare'
+    WHEN 'education' THEN 'Education'
+    WHEN 'travel' THEN 'Travel'
+    WHEN 'salary', 'interest', 'dividend', 'bonus', 'refund', 'reimbursement', 'investment return', 'income' THEN 'Income'
+    WHEN 'transfer', 'bank transfer', 'digital payments', 'transfers' THEN 'Transfers'
+    WHEN 'atm', 'atm withdrawal', 'cash', 'cash withdrawal' THEN 'Cash Withdrawal'
+    WHEN 'uncategorized', 'other' THEN 'Other'
+    ELSE user_category
+  END
+WHERE user_category <> '';
+
+UPDATE category_overrides SET category =
+  CASE LOWER(TRIM(category))
+    WHEN 'food', 'food delivery', 'dining', 'restaurant', 'groceries', 'food & dining' THEN 'Food & Dining'
+    WHEN 'transportation', 'fuel', 'transport' THEN 'Transport'
+    WHEN 'online shopping', 'shopping' THEN 'Shopping'
+    WHEN 'entertainment' THEN 'Entertainment'
+    WHEN 'bills', 'utilities', 'insurance', 'bills & utilities' THEN 'Bills & Utilities'
+    WHEN 'health', 'medical', 'healthcare' THEN 'Healthcare'
+    WHEN 'education' THEN 'Education'
+    WHEN 'travel' THEN 'Travel'
+    WHEN 'salary', 'interest', 'dividend', 'bonus', 'refund', 'reimbursement', 'investment return', 'income' THEN 'Income'
+    WHEN 'transfer', 'bank transfer', 'digital payments', 'transfers' THEN 'Transfers'
+    WHEN 'atm', 'atm withdrawal', 'cash', 'cash withdrawal' THEN 'Cash Withdrawal'
+    WHEN 'uncategorized', 'other' THEN 'Other'
+    ELSE category
+  END
+WHERE TRUE;
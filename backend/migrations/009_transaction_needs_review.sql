@@ -0,0 +1,20 @@
+-- Categorization confidence and a review flag for transactions whose
+-- category is a guess or whose merchant could not be identified
+ALTER TABLE transactions
+  ADD COLUMN category_confidence REAL NOT NULL DEFAULT 0,
+  ADD COLUMN needs_review BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Existing rows were categorized before confidence was tracked: treat a
+-- fallback category as no confidence and anything else as a description match
+UPDATE transactions
+SET category_confidence = CASE
+  WHEN category IN ('', 'Other', 'Uncategorized') THEN 0
+  ELSE 0.6
+END;
+
+UPDATE transactions
+SET needs_review = TRUE
+WHERE user_category = ''
+  AND (category_confidence < 0.5 OR merchant_name IN ('', 'Unknown'));
+
+CREATE INDEX idx_transactions_needs_review ON transactions(user_id, posted_at DESC) WHERE needs_review;
@@ -0,0 +1,13 @@
+-- Users have a base currency, and every transaction keeps its original
+-- amount and currency alongside the amount converted into the user's
+-- currency at import, which summaries aggregate
+ALTER TABLE users ADD COLUMN currency TEXT NOT NULL DEFAULT 'INR';
+
+UPDATE transactions SET currency = COALESCE(NULLIF(UPPER(TRIM(currency)), ''), 'INR');
+
+ALTER TABLE transactions
+  ADD COLUMN base_amount NUMERIC(14,2) NOT NULL DEFAULT 0,
+  ADD COLUMN base_currency TEXT NOT NULL DEFAULT 'INR';
+
+-- Transactions imported so far were all in INR, the default user currency
+UPDATE transactions SET base_amount = amount, base_currency = currency;
@@ -0,0 +1,3 @@
+-- Transaction listings page by seeking past the last (posted_at, id) seen
+-- rather than with OFFSET, newest first
+CREATE INDEX idx_transactions_user_posted_id ON transactions(user_id, posted_at DESC, id DESC);
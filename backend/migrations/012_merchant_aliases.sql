@@ -0,0 +1,11 @@
+-- Merchant aliases canonicalize merchant names: descriptions matching
+-- pattern are attributed to merchant. Rows without a user apply to everyone.
+CREATE TABLE merchant_aliases (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  pattern TEXT NOT NULL,
+  merchant TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_merchant_aliases_user_id ON merchant_aliases(user_id);
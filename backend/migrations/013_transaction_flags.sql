@@ -0,0 +1,6 @@
+-- Flag for transactions whose amount is unusually large, either above an
+-- absolute threshold or far above the user's norm for the category
+ALTER TABLE transactions
+  ADD COLUMN flag_reason TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_transactions_flagged ON transactions(user_id, posted_at DESC) WHERE flag_reason <> '';
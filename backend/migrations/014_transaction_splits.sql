@@ -0,0 +1,14 @@
+-- Parts of a transaction divided across categories. The parts add up to the
+-- transaction's amount and replace its category in summaries.
+CREATE TABLE transaction_splits (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  transaction_id UUID NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+  amount NUMERIC(14,2) NOT NULL,
+  base_amount NUMERIC(14,2) NOT NULL,
+  category TEXT NOT NULL,
+  subcategory TEXT NOT NULL DEFAULT '',
+  note TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_transaction_splits_transaction_id ON transaction_splits(transaction_id);
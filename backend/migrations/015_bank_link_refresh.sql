@@ -0,0 +1,3 @@
+-- Remember when a bank link was last refreshed on demand so refreshes can
+-- be rate limited per link
+ALTER TABLE bank_links ADD COLUMN last_refreshed_at TIMESTAMPTZ;
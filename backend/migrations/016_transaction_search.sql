@@ -0,0 +1,6 @@
+-- Trigram indexes so case-insensitive substring search over descriptions
+-- and merchant names doesn't scan every transaction
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_transactions_description_trgm ON transactions USING GIN (description_raw gin_trgm_ops);
+CREATE INDEX idx_transactions_merchant_trgm ON transactions USING GIN (merchant_name gin_trgm_ops);
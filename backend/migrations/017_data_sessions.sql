@@ -0,0 +1,9 @@
+-- Who each AA data session was created for, so a DATA_READY webhook can be
+-- ingested into the right user's bank link
+CREATE TABLE data_sessions (
+  session_id TEXT PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  bank_link_id UUID NOT NULL REFERENCES bank_links(id) ON DELETE CASCADE,
+  dry_run BOOLEAN NOT NULL DEFAULT false,
+  created_at TIMESTAMPTZ DEFAULT now()
+);
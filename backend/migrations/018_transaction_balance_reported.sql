@@ -0,0 +1,5 @@
+-- Marks balances reported by the provider so later recomputes keep them as
+-- anchors instead of overwriting them with a computed running balance.
+-- Existing rows can't tell the two apart and stay unmarked.
+ALTER TABLE transactions
+  ADD COLUMN balance_reported BOOLEAN NOT NULL DEFAULT FALSE;
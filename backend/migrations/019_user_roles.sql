@@ -0,0 +1,4 @@
+-- Users hold a role; "admin" unlocks /api/admin. Existing users keep the
+-- plain user role.
+ALTER TABLE users
+  ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Bank is an entry in the public list of banks users can link accounts
+// from. The table is seeded from utils.Banks and managed by admins.
+type Bank struct {
+	ID         string    `json:"id" gorm:"primaryKey;size:64"`
+	Name       string    `json:"name" gorm:"not null"`
+	Logo       string    `json:"logo"`
+	Type       string    `json:"type" gorm:"size:20;not null;index"` // "public", "private", "foreign", "rrb" or "sfb"
+	IFSCPrefix string    `json:"ifsc_prefix,omitempty" gorm:"size:4"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -18,5 +18,7 @@ type BankAccount struct {
 	IFSCCode          string     `json:"ifsc_code"`
 	BranchName        string     `json:"branch_name"`
 	VerifiedAt        *time.Time `json:"verified_at"`
+	IdempotencyKey    *string    `json:"-" gorm:"size:255"` // client-supplied Idempotency-Key used to create the row
+	IdempotencyHash   *string    `json:"-" gorm:"size:64"`  // hash of the request body the key was first used with
 	User              User       `gorm:"foreignKey:UserID"`
 }
@@ -0,0 +1,16 @@
+package models
+
+import "gorm.io/gorm"
+
+// BudgetAlert records that a user crossed a budget threshold in a given
+// budget period, so each threshold fires at most once per period. Pending
+// alerts still await their email.
+type BudgetAlert struct {
+	gorm.Model
+	UserID    uint    `json:"user_id" gorm:"not null;uniqueIndex:idx_budget_alerts_user_month_threshold"`
+	Month     string  `json:"month" gorm:"not null;uniqueIndex:idx_budget_alerts_user_month_threshold"` // YYYY-MM, or the start date of a weekly or custom period
+	Threshold float64 `json:"threshold" gorm:"not null;uniqueIndex:idx_budget_alerts_user_month_threshold"`
+	Spent     float64 `json:"spent"`
+	Budget    float64 `json:"budget"`
+	Pending   bool    `json:"-" gorm:"not null;default:false"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "gorm.io/gorm"
+
+// CategoryRule assigns Category to the user's expenses whose title matches
+// Matcher (a case-insensitive substring, or a "/regex/"). Rules are applied
+// oldest first and outrank keyword auto-categorization.
+type CategoryRule struct {
+	gorm.Model
+	UserID      uint   `json:"-" gorm:"not null;index"`
+	Matcher     string `json:"matcher" gorm:"size:200;not null"`
+	Category    string `json:"category" gorm:"not null"`
+	Subcategory string `json:"subcategory"`
+}
@@ -7,9 +7,19 @@ type Expense struct {
 	Title         string  `json:"title" binding:"required"`
 	Amount        float64 `json:"amount" binding:"required,gt=0"`
 	Category      string  `json:"category"`
-	Date          string  `json:"date" binding:"required,datetime=2006-01-02"`
+	Subcategory   string  `json:"subcategory"`
+	Date          string  `json:"date" binding:"required,datetime=2006-01-02"`  // calendar date in the user's timezone
 	Type          string  `json:"type" binding:"required,oneof=income expense"` // Add type field
+	Currency      string  `json:"currency" gorm:"size:3;default:'INR'" binding:"omitempty,iso4217"`
 	PaymentMethod string  `json:"payment_method"`
 	Notes         string  `json:"notes"`
 	UserID        uint    `json:"-"`
+	Tags          []Tag   `json:"tags,omitempty" gorm:"many2many:expense_tags"`
+
+	// Optional geotag. Location is a human-readable place copied onto the
+	// mirrored transaction; City is what spend is grouped by.
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	Location  string   `json:"location" binding:"omitempty,max=200"`
+	City      string   `json:"city" binding:"omitempty,max=100"`
 }
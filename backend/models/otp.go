@@ -6,10 +6,20 @@ import (
 	"gorm.io/gorm"
 )
 
+// What an OTP verifies
+const (
+	OTPPurposeSignup      = "signup"       // the address a new account registered with
+	OTPPurposeEmailChange = "email_change" // the new address of a pending email change
+)
+
 type OTP struct {
 	gorm.Model
-	Email     string    `json:"email" gorm:"uniqueIndex"`
+	Email     string    `json:"email" gorm:"index"`
 	Code      string    `json:"code"`
+	Purpose   string    `json:"purpose" gorm:"size:20;not null;default:'signup'"`
 	ExpiresAt time.Time `json:"expires_at"`
 	Used      bool      `json:"used" gorm:"default:false"`
+	// Attempts counts wrong codes entered against this OTP; it is marked
+	// used once they reach the limit
+	Attempts int `json:"-" gorm:"not null;default:0"`
 }
@@ -0,0 +1,15 @@
+package models
+
+import "gorm.io/gorm"
+
+// Receipt is a file (image or PDF) attached to an expense. The bytes live in
+// the configured receipt store under StorageKey.
+type Receipt struct {
+	gorm.Model
+	ExpenseID   uint   `json:"expense_id" gorm:"not null;index"`
+	UserID      uint   `json:"-" gorm:"not null;index"`
+	Filename    string `json:"filename" gorm:"size:255"`
+	ContentType string `json:"content_type" gorm:"size:100"`
+	StorageKey  string `json:"-" gorm:"size:255;not null;uniqueIndex"`
+	Size        int64  `json:"size"`
+}
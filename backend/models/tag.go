@@ -0,0 +1,11 @@
+package models
+
+import "gorm.io/gorm"
+
+// Tag is a free-form, user-scoped label that can be attached to many
+// expenses and transactions. Names are unique per user.
+type Tag struct {
+	gorm.Model
+	UserID uint   `json:"-" gorm:"not null;uniqueIndex:idx_tags_user_name"`
+	Name   string `json:"name" gorm:"size:50;not null;uniqueIndex:idx_tags_user_name"`
+}
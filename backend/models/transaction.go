@@ -23,4 +23,5 @@ type Transaction struct {
 	Status          string      `json:"status" gorm:"default:'completed'"`
 	BankAccount     BankAccount `gorm:"foreignKey:BankAccountID"`
 	User            User        `gorm:"foreignKey:UserID"`
+	Tags            []Tag       `json:"tags,omitempty" gorm:"many2many:transaction_tags"`
 }
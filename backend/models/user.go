@@ -1,6 +1,10 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
 	gorm.Model
@@ -9,5 +13,25 @@ type User struct {
 	Password string  `json:"password,omitempty" binding:"required"`
 	GoogleID *string `json:"google_id,omitempty"`
 	Budget   float64
+	Currency string `json:"currency" gorm:"size:3;default:'INR'"`        // preferred display currency
+	Timezone string `json:"timezone" gorm:"size:64;default:'UTC'"`       // IANA zone the user's calendar dates are in
+	Role     string `json:"role" gorm:"size:20;not null;default:'user'"` // "user" or "admin"
+
+	// Budget period. Budget applies to each calendar month, each week, or
+	// each run of BudgetPeriodDays days; weekly and custom periods are
+	// counted from BudgetPeriodStart.
+	BudgetPeriod      string `json:"budget_period" gorm:"size:10;default:'monthly'"` // "monthly", "weekly" or "custom"
+	BudgetPeriodStart string `json:"budget_period_start,omitempty" gorm:"size:10"`   // YYYY-MM-DD
+	BudgetPeriodDays  int    `json:"budget_period_days,omitempty"`
+
+	// PendingEmail is the address the user asked to switch to. Email stays
+	// in use until the OTP sent to PendingEmail is verified.
+	PendingEmail string `json:"pending_email,omitempty" gorm:"size:255"`
+
+	// Emailed spending reports
+	ReportOptIn      bool       `json:"report_opt_in" gorm:"default:false"`
+	ReportCadence    string     `json:"report_cadence" gorm:"default:'monthly'"` // "weekly" or "monthly"
+	LastReportSentAt *time.Time `json:"-"`
+
 	Expenses []Expense `gorm:"constraint:OnDelete:CASCADE;"`
 }
@@ -0,0 +1,74 @@
+// Package requestid assigns each HTTP request a correlation ID and carries it
+// through the request context so downstream logs can be tied together.
+package requestid
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Header is the header an inbound ID is read from and the response echoes
+const Header = "X-Request-ID"
+
+// ContextKey is the gin context key the ID is stored under
+const ContextKey = "requestID"
+
+// maxLength bounds IDs accepted from clients
+const maxLength = 128
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying id
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID in ctx, or "" if there is none
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Logger returns logger annotated with the request ID from ctx, if any
+func Logger(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	if id := FromContext(ctx); id != "" {
+		return logger.With(zap.String("request_id", id))
+	}
+	return logger
+}
+
+// Middleware reuses a well-formed inbound X-Request-ID or generates a new
+// one, stores it on the gin and request contexts and echoes it back
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(Header)
+		if !valid(id) {
+			id = uuid.NewString()
+		}
+
+		c.Set(ContextKey, id)
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), id))
+		c.Header(Header, id)
+		c.Next()
+	}
+}
+
+// valid accepts non-empty printable ASCII IDs of reasonable length so
+// clients cannot inject control characters into logs
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newLoggingRouter serves /work, which logs through Logger, and returns the
+// captured logs
+func newLoggingRouter() (*gin.Engine, *observer.ObservedLogs) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(core)
+
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/work", func(c *gin.Context) {
+		Logger(c.Request.Context(), logger).Info("working")
+		c.String(http.StatusOK, c.GetString(ContextKey))
+	})
+	return r, logs
+}
+
+func get(r *gin.Engine, inbound string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/work", nil)
+	if inbound != "" {
+		req.Header.Set(Header, inbound)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// loggedID returns the request_id field of the only captured log entry
+func loggedID(t *testing.T, logs *observer.ObservedLogs) string {
+	t.Helper()
+	entries := logs.TakeAll()
+	if len(entries) != 1 {
+		t.Fatalf("captured %d log entries, want 1", len(entries))
+	}
+	id, _ := entries[0].ContextMap()["request_id"].(string)
+	return id
+}
+
+func TestMiddlewareGeneratesAnID(t *testing.T) {
+	r, logs := newLoggingRouter()
+
+	w := get(r, "")
+	id := w.Header().Get(Header)
+	if id == "" {
+		t.Fatal("no request ID on the response")
+	}
+	if w.Body.String() != id {
+		t.Errorf("gin context holds %q, response has %q", w.Body, id)
+	}
+	if logged := loggedID(t, logs); logged != id {
+		t.Errorf("logged request_id %q, response has %q", logged, id)
+	}
+
+	if other := get(r, "").Header().Get(Header); other == id {
+		t.Error("two requests got the same ID")
+	}
+}
+
+func TestMiddlewareHonoursInboundID(t *testing.T) {
+	r, logs := newLoggingRouter()
+
+	w := get(r, "upstream-abc-123")
+	if got := w.Header().Get(Header); got != "upstream-abc-123" {
+		t.Errorf("response ID = %q, want the inbound one", got)
+	}
+	if logged := loggedID(t, logs); logged != "upstream-abc-123" {
+		t.Errorf("logged request_id %q, want the inbound one", logged)
+	}
+}
+
+func TestMiddlewareReplacesMalformedIDs(t *testing.T) {
+	r, _ := newLoggingRouter()
+
+	for _, inbound := range []string{"has space", "tab\tid", strings.Repeat("x", maxLength+1)} {
+		if got := get(r, inbound).Header().Get(Header); got == inbound || got == "" {
+			t.Errorf("inbound %q answered with %q, want a generated ID", inbound, got)
+		}
+	}
+}
+
+func TestLoggerWithoutAnID(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	Logger(httptest.NewRequest(http.MethodGet, "/", nil).Context(), zap.New(core)).Info("no id")
+
+	if _, ok := logs.All()[0].ContextMap()["request_id"]; ok {
+		t.Error("tagged a log with an empty request ID")
+	}
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/gin-contrib/gzip"
@@ -11,11 +12,26 @@ import (
 
 	"github.com/your-github/expense-tracker-backend/config"
 	"github.com/your-github/expense-tracker-backend/controllers"
+	aaservices "github.com/your-github/expense-tracker-backend/internal/core/services"
+	"github.com/your-github/expense-tracker-backend/internal/repo"
+	"github.com/your-github/expense-tracker-backend/metrics"
 	"github.com/your-github/expense-tracker-backend/middleware"
+	"github.com/your-github/expense-tracker-backend/requestid"
 	"github.com/your-github/expense-tracker-backend/services"
+	"github.com/your-github/expense-tracker-backend/utils"
 )
 
-func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
+// multipartRoutes are the routes that take multipart/form-data uploads
+// rather than JSON bodies
+var multipartRoutes = []string{"/api/expenses/:id/receipts"}
+
+// streamingRoutes are long downloads that stream their response and run
+// without the request timeout
+var streamingRoutes = []string{"/api/profile/export"}
+
+// SetupRouter builds the API router. The returned cleanup function stops the
+// background work started by its services and should be called on shutdown.
+func SetupRouter(db *gorm.DB, cfg *config.Config) (*gin.Engine, func()) {
 	// Set Gin to release mode for better performance
 	gin.SetMode(gin.ReleaseMode)
 
@@ -23,49 +39,77 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 
 	// Use optimized middleware stack
 	r.Use(gin.Recovery())
+	r.Use(requestid.Middleware())
 	r.Use(gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
 		// Custom logging format for better performance
-		return fmt.Sprintf("%s | %d | %s | %s | %s | %d | %s\n",
+		return fmt.Sprintf("%s | %d | %s | %s | %s | %d | %s | %s\n",
 			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
 			param.StatusCode,
 			param.Method,
 			param.Path,
 			param.ClientIP,
 			param.Latency.Milliseconds(),
+			requestid.FromContext(param.Request.Context()),
 			param.ErrorMessage,
 		)
 	}))
 
+	if cfg.Metrics.Enabled {
+		r.Use(metrics.GinMiddleware())
+	}
+
 	// Enable Gzip compression for better performance
 	r.Use(gzip.Gzip(gzip.DefaultCompression))
 
 	// Apply security middleware with optimized settings
 	r.Use(middleware.SecurityHeaders())
-	r.Use(middleware.InputValidation())
-	r.Use(middleware.RequestTimeout(15 * time.Second)) // Reduced timeout for better responsiveness
-	r.Use(middleware.RateLimit(rate.Limit(200), 500))  // Increased rate limits for better scalability
+	r.Use(middleware.InputValidation(cfg.App.MaxBodySize, multipartRoutes...))
+	// Rejected requests shouldn't start a handler goroutine and deadline
+	r.Use(newRateLimiter(cfg.RateLimit, cfg.RateLimit.RPS, cfg.RateLimit.Burst).Middleware())
+	r.Use(middleware.RequestTimeout(15*time.Second, streamingRoutes...)) // Reduced timeout for better responsiveness
 	r.Use(middleware.CORSSecurity())
 
 	// Initialize optimized services with enhanced caching
 	authSvc := services.NewAuthService(db, cfg)
-	expSvc := services.NewExpenseService(db)
-	sumSvc := services.NewSummaryService(db)
+	expSvc := services.NewExpenseService(db, cfg.Cache.Shards)
+	rates := utils.NewRateProvider(cfg.Currency.RatesURL)
+	sumSvc := services.NewSummaryService(db, rates, cfg.Cache.Shards)
+	sumSvc.Rollover = cfg.Budget.Rollover
 
 	// Initialize controllers
 	authCtl := &controllers.AuthController{S: authSvc, Config: cfg}
 	expCtl := &controllers.ExpenseController{S: expSvc}
 	sumCtl := &controllers.SummaryController{S: sumSvc}
-	profCtl := &controllers.ProfileController{}
-	aiCtl := &controllers.AIController{}
+	profCtl := &controllers.ProfileController{Auth: authSvc, Summary: sumSvc, Rates: rates, Exports: services.NewExportService(db, repo.NewRepositories(db))}
+	currencyCtl := &controllers.CurrencyController{Rates: rates, Summary: sumSvc}
+	expSvc.Summary = sumSvc
+	expSvc.Alerts = services.NewBudgetAlertService(db, sumSvc, authSvc.EmailSvc, cfg.Budget.AlertThresholds)
+	reportSvc := services.NewReportService(db, sumSvc, authSvc.EmailSvc)
+	reportCron := reportSvc.StartScheduler()
+	reportCtl := &controllers.ReportController{}
+	tagCtl := &controllers.TagController{S: services.NewTagService(db, expSvc)}
+	ruleCtl := &controllers.CategoryRuleController{S: services.NewCategoryRuleService(db)}
+	normalizer := aaservices.NewNormalizer()
+	if cfg.Normalizer.RulesFile != "" {
+		if err := normalizer.LoadRulesFile(cfg.Normalizer.RulesFile); err != nil {
+			log.Println("Failed to load merchant rules, using built-in defaults:", err)
+		}
+	}
+	reconcileCtl := &controllers.ReconcileController{S: services.NewReconcileService(db, expSvc, normalizer)}
+	receiptStore, err := services.NewReceiptStoreFromConfig(cfg.Receipts)
+	if err != nil {
+		log.Fatal("Failed to configure receipt storage: ", err)
+	}
+	receiptCtl := &controllers.ReceiptController{S: services.NewReceiptService(db, receiptStore, cfg.Receipts.MaxSize)}
+	aiCtl := &controllers.AIController{Config: cfg, Rates: rates, Insights: services.NewInsightsService()}
+	bankCatalogCtl := &controllers.BankCatalogController{S: services.NewBankService(db)}
+	ifscCtl := &controllers.IFSCController{Lookup: utils.NewStaticIFSCLookup(utils.Banks)}
 	// Initialize bank verification service
-	bankVerificationSvc := services.NewBankVerificationService(
-		cfg.BankVerification.APIKey,
-		cfg.BankVerification.APIURL,
-		"mock", // Use "mock" for development, change to "karza", "signzy", or "razorpay" for production
-	)
+	bankVerificationSvc := services.NewBankVerificationServiceFromConfig(cfg.BankVerification)
 
 	// Initialize services
 	transactionSvc := services.NewTransactionService(db)
+	aiCtl.Transactions = transactionSvc
 
 	bankCtl := &controllers.BankController{
 		DB:                  db,
@@ -76,18 +120,43 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 		TransactionService: transactionSvc,
 	}
 
-	// Health check endpoint
-	r.GET("/api/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "healthy", "timestamp": time.Now().Unix()})
-	})
+	// Prometheus metrics
+	if cfg.Metrics.Enabled {
+		metrics.RegisterCache("expenses", expSvc.Cache)
+		metrics.RegisterCache("summary", sumSvc.Cache)
+		r.GET("/metrics", gin.WrapH(metrics.Handler()))
+	}
+
+	caches := map[string]*utils.LRUCache{"expenses": expSvc.Cache, "summary": sumSvc.Cache}
 
-	// Migration endpoint (no auth required for testing)
-	r.POST("/api/migrate-expenses", expCtl.MigrateAllExpensesToTransactions)
+	// Health check endpoints: liveness is static, readiness pings the database
+	healthCtl := &controllers.HealthController{
+		DB:     db,
+		Caches: caches,
+		Cron:   reportCron,
+	}
+	r.GET("/api/health", healthCtl.Live)
+	r.GET("/api/health/ready", healthCtl.Ready)
+
+	// Admin routes, restricted to users with the admin role
+	adminCtl := &controllers.AdminController{Expenses: expSvc, Caches: caches, DB: db, StartedAt: time.Now()}
+	admin := r.Group("/api/admin")
+	admin.Use(middleware.Auth(cfg.JWT.Secret, middleware.NumericSubject), middleware.RequireCurrentRole(func(c *gin.Context) (string, error) {
+		return authSvc.CurrentRole(c.GetUint(middleware.ContextUserID))
+	}, utils.RoleAdmin))
+	{
+		admin.POST("/migrate-expenses", adminCtl.MigrateExpenses)
+		admin.GET("/cache-stats", adminCtl.CacheStats)
+		admin.GET("/diagnostics", adminCtl.Diagnostics)
+		admin.POST("/cache-stats/reset", adminCtl.ResetCacheStats)
+		admin.POST("/banks", bankCatalogCtl.Create)
+		admin.PUT("/banks/:id", bankCatalogCtl.Update)
+		admin.DELETE("/banks/:id", bankCatalogCtl.Delete)
+	}
 
 	// Public routes (no authentication required)
 	r.GET("/api/categories", func(c *gin.Context) {
-		categories := []string{"Food", "Transport", "Shopping", "Entertainment", "Bills", "Income", "Other"}
-		c.JSON(200, categories)
+		c.JSON(200, utils.Categories())
 	})
 
 	r.GET("/api/analytics", func(c *gin.Context) {
@@ -95,121 +164,120 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 	})
 
 	// Public bank information
-	r.GET("/api/banks", func(c *gin.Context) {
-		banks := []map[string]interface{}{
-			// Public Sector Banks
-			{"id": "sbi", "name": "State Bank of India", "logo": "sbi.png", "type": "public"},
-			{"id": "pnb", "name": "Punjab National Bank", "logo": "pnb.png", "type": "public"},
-			{"id": "canara", "name": "Canara Bank", "logo": "canara.png", "type": "public"},
-			{"id": "bank-of-baroda", "name": "Bank of Baroda", "logo": "bob.png", "type": "public"},
-			{"id": "union-bank", "name": "Union Bank of India", "logo": "union.png", "type": "public"},
-			{"id": "bank-of-india", "name": "Bank of India", "logo": "boi.png", "type": "public"},
-			{"id": "central-bank", "name": "Central Bank of India", "logo": "cbi.png", "type": "public"},
-			{"id": "indian-bank", "name": "Indian Bank", "logo": "indian.png", "type": "public"},
-			{"id": "uco-bank", "name": "UCO Bank", "logo": "uco.png", "type": "public"},
-			{"id": "bank-of-maharashtra", "name": "Bank of Maharashtra", "logo": "bom.png", "type": "public"},
-			{"id": "punjab-sind-bank", "name": "Punjab & Sind Bank", "logo": "psb.png", "type": "public"},
-
-			// Private Sector Banks
-			{"id": "hdfc", "name": "HDFC Bank", "logo": "hdfc.png", "type": "private"},
-			{"id": "icici", "name": "ICICI Bank", "logo": "icici.png", "type": "private"},
-			{"id": "axis", "name": "Axis Bank", "logo": "axis.png", "type": "private"},
-			{"id": "kotak", "name": "Kotak Mahindra Bank", "logo": "kotak.png", "type": "private"},
-			{"id": "yes", "name": "Yes Bank", "logo": "yes.png", "type": "private"},
-			{"id": "indusind", "name": "IndusInd Bank", "logo": "indusind.png", "type": "private"},
-			{"id": "idfc", "name": "IDFC First Bank", "logo": "idfc.png", "type": "private"},
-			{"id": "bandhan", "name": "Bandhan Bank", "logo": "bandhan.png", "type": "private"},
-			{"id": "csb", "name": "CSB Bank", "logo": "csb.png", "type": "private"},
-			{"id": "dcb", "name": "DCB Bank", "logo": "dcb.png", "type": "private"},
-			{"id": "federal", "name": "Federal Bank", "logo": "federal.png", "type": "private"},
-			{"id": "karnataka", "name": "Karnataka Bank", "logo": "karnataka.png", "type": "private"},
-			{"id": "karur-vysya", "name": "Karur Vysya Bank", "logo": "kvb.png", "type": "private"},
-			{"id": "nainital", "name": "Nainital Bank", "logo": "nainital.png", "type": "private"},
-			{"id": "rbl", "name": "RBL Bank", "logo": "rbl.png", "type": "private"},
-			{"id": "south-indian", "name": "South Indian Bank", "logo": "sib.png", "type": "private"},
-			{"id": "tamilnad", "name": "Tamilnad Mercantile Bank", "logo": "tmb.png", "type": "private"},
-
-			// Foreign Banks
-			{"id": "citibank", "name": "Citibank", "logo": "citi.png", "type": "foreign"},
-			{"id": "hsbc", "name": "HSBC Bank", "logo": "hsbc.png", "type": "foreign"},
-			{"id": "standard-chartered", "name": "Standard Chartered Bank", "logo": "scb.png", "type": "foreign"},
-			{"id": "deutsche", "name": "Deutsche Bank", "logo": "deutsche.png", "type": "foreign"},
-			{"id": "barclays", "name": "Barclays Bank", "logo": "barclays.png", "type": "foreign"},
-			{"id": "dbs", "name": "DBS Bank", "logo": "dbs.png", "type": "foreign"},
-			{"id": "rbs", "name": "Royal Bank of Scotland", "logo": "rbs.png", "type": "foreign"},
-			{"id": "bnp-paribas", "name": "BNP Paribas", "logo": "bnp.png", "type": "foreign"},
-			{"id": "societe-generale", "name": "Societe Generale", "logo": "sg.png", "type": "foreign"},
-
-			// Regional Rural Banks
-			{"id": "andhra-pradesh-grameena", "name": "Andhra Pradesh Grameena Vikas Bank", "logo": "apgvb.png", "type": "rrb"},
-			{"id": "karnataka-gramin", "name": "Karnataka Gramin Bank", "logo": "kgb.png", "type": "rrb"},
-			{"id": "madhya-pradesh-gramin", "name": "Madhya Pradesh Gramin Bank", "logo": "mpgb.png", "type": "rrb"},
-			{"id": "rajasthan-marudhara", "name": "Rajasthan Marudhara Gramin Bank", "logo": "rmgb.png", "type": "rrb"},
-			{"id": "uttar-bihar-gramin", "name": "Uttar Bihar Gramin Bank", "logo": "ubgb.png", "type": "rrb"},
-
-			// Small Finance Banks
-			{"id": "au-small-finance", "name": "AU Small Finance Bank", "logo": "au.png", "type": "sfb"},
-			{"id": "equitas-small-finance", "name": "Equitas Small Finance Bank", "logo": "equitas.png", "type": "sfb"},
-			{"id": "fino-payments", "name": "Fino Payments Bank", "logo": "fino.png", "type": "sfb"},
-			{"id": "jammu-kashmir", "name": "Jammu & Kashmir Bank", "logo": "jkb.png", "type": "sfb"},
-			{"id": "karnataka-vikas", "name": "Karnataka Vikas Grameena Bank", "logo": "kvg.png", "type": "sfb"},
-			{"id": "maharashtra-gramin", "name": "Maharashtra Gramin Bank", "logo": "mgb.png", "type": "sfb"},
-			{"id": "odisha-gramya", "name": "Odisha Gramya Bank", "logo": "ogb.png", "type": "sfb"},
-			{"id": "puduvai-bharathiar", "name": "Puduvai Bharathiar Grama Bank", "logo": "pbg.png", "type": "sfb"},
-			{"id": "saurashtra-gramin", "name": "Saurashtra Gramin Bank", "logo": "sgb.png", "type": "sfb"},
-			{"id": "tamil-nadu-grama", "name": "Tamil Nadu Grama Bank", "logo": "tngb.png", "type": "sfb"},
-			{"id": "telangana-gramin", "name": "Telangana Grameena Bank", "logo": "tgb.png", "type": "sfb"},
-			{"id": "uttar-pradesh-gramin", "name": "Uttar Pradesh Gramin Bank", "logo": "upgb.png", "type": "sfb"},
-			{"id": "uttarakhand-gramin", "name": "Uttarakhand Gramin Bank", "logo": "ukgb.png", "type": "sfb"},
-			{"id": "west-bengal-gramin", "name": "West Bengal Gramin Bank", "logo": "wbgb.png", "type": "sfb"},
-		}
-		c.JSON(200, banks)
-	})
+	r.GET("/api/banks", bankCatalogCtl.List)
+	r.GET("/api/ifsc/:code", ifscCtl.Get)
 
-	// Auth routes
-	r.POST("/api/register", authCtl.Register)
-	r.POST("/api/signup", authCtl.Register) // Alias for register to match frontend
-	r.POST("/api/login", authCtl.Login)
-	r.POST("/api/verify-otp", authCtl.VerifyOTP)
-	r.POST("/api/resend-otp", authCtl.ResendOTP)
+	// Auth routes, with a stricter rate limit on top of the global one
+	auth := r.Group("/api")
+	auth.Use(newRateLimiter(cfg.RateLimit, cfg.RateLimit.AuthRPS, cfg.RateLimit.AuthBurst).Middleware())
+	{
+		auth.POST("/register", authCtl.Register)
+		auth.POST("/signup", authCtl.Register) // Alias for register to match frontend
+		auth.POST("/login", authCtl.Login)
+		auth.POST("/verify-otp", authCtl.VerifyOTP)
+		auth.POST("/resend-otp", authCtl.ResendOTP)
+	}
 
 	// Protected routes (authentication required)
 	protected := r.Group("/api")
-	protected.Use(middleware.Auth(cfg.JWT.Secret))
+	protected.Use(middleware.Auth(cfg.JWT.Secret, middleware.NumericSubject))
 	{
 		// Profile routes
 		protected.GET("/profile", profCtl.Get)
+		protected.PUT("/profile", profCtl.Update)
+		protected.GET("/profile/export", profCtl.Export)
+		protected.PUT("/profile/currency", currencyCtl.SetPreferred)
+		protected.PUT("/profile/timezone", profCtl.SetTimezone)
+		protected.POST("/profile/email", profCtl.RequestEmailChange)
+		protected.POST("/profile/email/verify", profCtl.VerifyEmailChange)
+		protected.GET("/profile/reports", reportCtl.GetSettings)
+		protected.PUT("/profile/reports", reportCtl.UpdateSettings)
 		protected.DELETE("/user", profCtl.Delete)
 
+		// Currency routes
+		protected.GET("/currency/rates", currencyCtl.GetRates)
+
 		// Expense routes with optimized endpoints
 		protected.POST("/expenses", expCtl.Create)
 		protected.GET("/expenses", expCtl.List)
 		protected.PUT("/expenses/:id", expCtl.Update)
+		protected.PATCH("/expenses/:id", expCtl.Patch)
 		protected.DELETE("/expenses/:id", expCtl.Delete)
+		protected.GET("/expenses/trash", expCtl.ListTrash)
+		protected.POST("/expenses/:id/restore", expCtl.Restore)
 		protected.POST("/expenses/recategorize", expCtl.Recategorize)
+		protected.POST("/expenses/recategorize/rule", expCtl.RecategorizeByRule)
+		protected.GET("/expenses/reconcile", reconcileCtl.List)
+		protected.POST("/expenses/reconcile", reconcileCtl.Resolve)
 		protected.GET("/expenses/range", expCtl.GetByDateRange)
 		protected.GET("/expenses/category/:category", expCtl.GetByCategory)
 		protected.POST("/expenses/migrate", expCtl.MigrateExpensesToTransactions)
+		protected.POST("/expenses/:id/tags", tagCtl.AttachToExpense)
+		protected.DELETE("/expenses/:id/tags/:tagId", tagCtl.DetachFromExpense)
+		protected.POST("/expenses/:id/receipts", receiptCtl.Upload)
+		protected.GET("/expenses/:id/receipts", receiptCtl.List)
+		protected.GET("/expenses/:id/receipts/:receiptId", receiptCtl.Download)
+
+		// Tag routes
+		protected.GET("/tags", tagCtl.List)
+		protected.POST("/tags", tagCtl.Create)
+		protected.DELETE("/tags/:id", tagCtl.Delete)
+
+		// Category rule routes
+		protected.GET("/category-rules", ruleCtl.List)
+		protected.POST("/category-rules", ruleCtl.Create)
+		protected.DELETE("/category-rules/:id", ruleCtl.Delete)
 
 		// Summary routes
 		protected.GET("/summary", sumCtl.Get)
 		protected.GET("/summary/lifetime", sumCtl.GetLifetime)
+		protected.GET("/summary/period", sumCtl.GetPeriod)
 		protected.GET("/summary/category-breakdown", sumCtl.GetCategoryBreakdown)
+		protected.GET("/summary/trends", sumCtl.GetTrends)
+		protected.GET("/summary/locations", sumCtl.GetLocations)
+		protected.GET("/summary/compare", sumCtl.Compare)
+		protected.GET("/summary/health", sumCtl.GetHealth)
+		protected.GET("/summary/merchants", txnCtl.GetMerchantBreakdown)
 
 		// AI insights route
 		protected.GET("/ai-insights", aiCtl.GetAIInsights)
+		protected.GET("/insights/local", aiCtl.GetLocalInsights)
 
 		// Bank account management routes
 		protected.GET("/bank-accounts", bankCtl.GetBankAccounts)
 		protected.POST("/bank-accounts", bankCtl.AddBankAccount)
 		protected.PUT("/bank-accounts/:id", bankCtl.UpdateBankAccount)
 		protected.DELETE("/bank-accounts/:id", bankCtl.DeleteBankAccount)
+		protected.POST("/bank-accounts/:id/restore", bankCtl.RestoreBankAccount)
 		protected.POST("/bank-accounts/:id/fetch", bankCtl.FetchTransactions)
 
 		// Transaction history routes
 		protected.GET("/transactions", txnCtl.GetTransactionHistory)
 		protected.GET("/transactions/bank-account/:id", txnCtl.GetTransactionsByBankAccount)
+		protected.GET("/transactions/recurring", txnCtl.GetRecurringTransactions)
+		protected.POST("/transactions/:id/tags", tagCtl.AttachToTransaction)
+		protected.DELETE("/transactions/:id/tags/:tagId", tagCtl.DetachFromTransaction)
+	}
+
+	cleanup := func() {
+		<-reportCron.Stop().Done()
+		expSvc.Close()
+		sumSvc.Close()
 	}
 
-	return r
+	return r, cleanup
+}
+
+// newRateLimiter builds a per-IP limiter of rps requests per second and the
+// given burst, sharing the configured allowlist and idle eviction
+func newRateLimiter(cfg config.RateLimitConfig, rps float64, burst int) *middleware.RateLimiter {
+	allowlist, err := middleware.ParseAllowlist(cfg.Allowlist)
+	if err != nil {
+		log.Fatal("Invalid RATE_LIMIT_ALLOWLIST: ", err)
+	}
+	limiter := middleware.NewRateLimiter(rate.Limit(rps), burst)
+	limiter.Allowlist = allowlist
+	limiter.IdleTTL = cfg.IdleTTL
+	limiter.MaxLimiters = cfg.MaxClients
+	return limiter
 }
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AccountNumberFormat describes what a bank's account numbers look like
+type AccountNumberFormat struct {
+	MinLength int
+	MaxLength int
+	Prefixes  []string // allowed leading digits; empty allows any
+}
+
+// defaultAccountNumberFormat applies to banks without a specific rule
+var defaultAccountNumberFormat = AccountNumberFormat{MinLength: 9, MaxLength: 18}
+
+// accountNumberFormats holds the known formats keyed by bank ID
+var accountNumberFormats = map[string]AccountNumberFormat{
+	"sbi":            {MinLength: 11, MaxLength: 11},
+	"hdfc":           {MinLength: 12, MaxLength: 14},
+	"icici":          {MinLength: 12, MaxLength: 12},
+	"axis":           {MinLength: 15, MaxLength: 15},
+	"airtel-payment": {MinLength: 10, MaxLength: 12},
+}
+
+// AccountNumberFormatFor returns the format rule for bankID, falling back to
+// the generic 9–18 digit rule for unknown banks
+func AccountNumberFormatFor(bankID string) AccountNumberFormat {
+	if format, ok := accountNumberFormats[strings.ToLower(strings.TrimSpace(bankID))]; ok {
+		return format
+	}
+	return defaultAccountNumberFormat
+}
+
+// Validate reports why accountNumber does not match the format, or nil if it does
+func (f AccountNumberFormat) Validate(accountNumber string) error {
+	for _, char := range accountNumber {
+		if char < '0' || char > '9' {
+			return fmt.Errorf("account number must contain only digits")
+		}
+	}
+
+	if len(accountNumber) < f.MinLength || len(accountNumber) > f.MaxLength {
+		if f.MinLength == f.MaxLength {
+			return fmt.Errorf("account number must be %d digits", f.MinLength)
+		}
+		return fmt.Errorf("account number must be %d-%d digits", f.MinLength, f.MaxLength)
+	}
+
+	if strings.Trim(accountNumber, "0") == "" {
+		return fmt.Errorf("account number cannot be all zeros")
+	}
+
+	if len(f.Prefixes) > 0 {
+		for _, prefix := range f.Prefixes {
+			if strings.HasPrefix(accountNumber, prefix) {
+				return nil
+			}
+		}
+		return fmt.Errorf("account number must start with one of %s", strings.Join(f.Prefixes, ", "))
+	}
+	return nil
+}
+
+// ValidateAccountNumberForBank checks accountNumber against bankID's format
+func ValidateAccountNumberForBank(bankID, accountNumber string) error {
+	return AccountNumberFormatFor(bankID).Validate(accountNumber)
+}
+
+// MaskAccountNumber hides all but the last four digits of an account number
+func MaskAccountNumber(accountNumber string) string {
+	if len(accountNumber) <= 4 {
+		return accountNumber
+	}
+	return "****" + accountNumber[len(accountNumber)-4:]
+}
+
+// MaskMobileNumber hides all but the last four digits of a mobile number
+func MaskMobileNumber(mobileNumber string) string {
+	if len(mobileNumber) <= 4 {
+		return mobileNumber
+	}
+	return "****" + mobileNumber[len(mobileNumber)-4:]
+}
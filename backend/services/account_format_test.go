@@ -0,0 +1,57 @@
+package services
+
+import "testing"
+
+func TestValidateAccountNumberForBank(t *testing.T) {
+	tests := []struct {
+		bank, number string
+		valid        bool
+	}{
+		{"sbi", "12345678901", true},
+		{"SBI", "12345678901", true},
+		{"sbi", "1234567890", false},
+		{"sbi", "123456789012", false},
+		{"hdfc", "123456789012", true},
+		{"hdfc", "12345678901234", true},
+		{"hdfc", "123456789012345", false},
+		{"icici", "123456789012", true},
+		{"icici", "1234567890123", false},
+		{"axis", "123456789012345", true},
+		{"axis", "12345678901234", false},
+		{"unknown-bank", "123456789", true},
+		{"unknown-bank", "123456789012345678", true},
+		{"unknown-bank", "12345678", false},
+		{"unknown-bank", "1234567890123456789", false},
+		{"hdfc", "12345678901a", false},
+		{"hdfc", "000000000000", false},
+		{"hdfc", "", false},
+	}
+	for _, tt := range tests {
+		err := ValidateAccountNumberForBank(tt.bank, tt.number)
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidateAccountNumberForBank(%q, %q) = %v, want valid %v", tt.bank, tt.number, err, tt.valid)
+		}
+	}
+}
+
+func TestAccountNumberFormatPrefixes(t *testing.T) {
+	format := AccountNumberFormat{MinLength: 4, MaxLength: 6, Prefixes: []string{"50", "91"}}
+	if err := format.Validate("501234"); err != nil {
+		t.Errorf("allowed prefix rejected: %v", err)
+	}
+	if err := format.Validate("401234"); err == nil {
+		t.Error("accepted a number outside the allowed prefixes")
+	}
+}
+
+func TestMockVerificationRejectsMalformedNumbers(t *testing.T) {
+	svc := NewBankVerificationService("", "", "mock")
+	req := verificationRequest
+	if !svc.validateMockVerification(req) {
+		t.Errorf("mock rejected a well-formed HDFC account")
+	}
+	req.AccountNumber = "12345678901"
+	if svc.validateMockVerification(req) {
+		t.Errorf("mock accepted an 11 digit HDFC account")
+	}
+}
@@ -2,6 +2,9 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -11,16 +14,72 @@ import (
 	"github.com/your-github/expense-tracker-backend/utils"
 )
 
+// OTP throttling limits applied per email address
+const (
+	otpMaxPerWindow = 3
+	otpWindow       = 15 * time.Minute
+	otpCooldown     = 60 * time.Second
+)
+
+// OTPRateLimitError is returned when an email has requested OTPs too often
+type OTPRateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *OTPRateLimitError) Error() string {
+	return fmt.Sprintf("too many OTP requests, please retry in %d seconds", e.RetryAfterSeconds())
+}
+
+// RetryAfterSeconds returns the wait time rounded up to whole seconds
+func (e *OTPRateLimitError) RetryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
 type AuthService struct {
-	DB       *gorm.DB
-	EmailSvc *EmailService
+	DB          *gorm.DB
+	EmailSvc    *EmailService
+	AdminEmails []string // accounts promoted to admin on login
 }
 
 func NewAuthService(db *gorm.DB, cfg *config.Config) *AuthService {
 	return &AuthService{
-		DB:       db,
-		EmailSvc: NewEmailService(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.User, cfg.SMTP.Pass),
+		DB:          db,
+		EmailSvc:    NewEmailService(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.User, cfg.SMTP.Pass),
+		AdminEmails: cfg.Admin.Emails,
+	}
+}
+
+// PromoteAdmins gives the admin role to the users with the given emails.
+// It is run at startup so configured admins exist without a manual update.
+func PromoteAdmins(db *gorm.DB, emails []string) error {
+	normalized := normalizeEmails(emails)
+	if len(normalized) == 0 {
+		return nil
 	}
+	return db.Model(&models.User{}).
+		Where("LOWER(email) IN ? AND role <> ?", normalized, utils.RoleAdmin).
+		Update("role", utils.RoleAdmin).Error
+}
+
+// isAdminEmail reports whether email is configured as an admin account
+func (s *AuthService) isAdminEmail(email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	for _, admin := range normalizeEmails(s.AdminEmails) {
+		if admin == email {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeEmails(emails []string) []string {
+	normalized := make([]string, 0, len(emails))
+	for _, email := range emails {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			normalized = append(normalized, email)
+		}
+	}
+	return normalized
 }
 
 func (s *AuthService) Register(user *models.User) error {
@@ -30,6 +89,11 @@ func (s *AuthService) Register(user *models.User) error {
 		return errors.New("user with this email already exists")
 	}
 
+	// Fail before creating the account; issueOTP enforces the limit atomically
+	if err := s.checkOTPThrottle(s.DB, user.Email); err != nil {
+		return err
+	}
+
 	hash, err := utils.HashPassword(user.Password)
 	if err != nil {
 		return err
@@ -43,25 +107,56 @@ func (s *AuthService) Register(user *models.User) error {
 	}
 
 	// Generate and send OTP
-	otp := s.EmailSvc.GenerateOTP()
+	return s.issueOTP(user.Email, models.OTPPurposeSignup)
+}
+
+// otpTTL is how long an emailed OTP stays valid
+const otpTTL = 10 * time.Minute
+
+// issueOTP stores a new OTP for email and purpose and emails it. The
+// throttle check and the insert run under a per-email advisory lock so
+// concurrent requests can't both pass the check.
+func (s *AuthService) issueOTP(email, purpose string) error {
+	otp, err := s.EmailSvc.GenerateOTP()
+	if err != nil {
+		return err
+	}
 	otpModel := models.OTP{
-		Email:     user.Email,
+		Email:     email,
 		Code:      otp,
-		ExpiresAt: time.Now().Add(10 * time.Minute), // OTP expires in 10 minutes
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(otpTTL),
 		Used:      false,
 	}
 
-	// Save OTP to database
-	if err := s.DB.Create(&otpModel).Error; err != nil {
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "otp:"+email).Error; err != nil {
+			return err
+		}
+		if err := s.checkOTPThrottle(tx, email); err != nil {
+			return err
+		}
+		return tx.Create(&otpModel).Error
+	})
+	if err != nil {
 		return err
 	}
 
 	// Send OTP email
-	if err := s.EmailSvc.SendOTP(user.Email, otp); err != nil {
-		return err
-	}
+	return s.EmailSvc.SendOTP(email, otp, purpose)
+}
 
-	return nil
+// CurrentRole returns the role the user holds now, or "" if the account is
+// gone. Admin routes check it so a demotion applies to tokens already issued.
+func (s *AuthService) CurrentRole(userID uint) (string, error) {
+	var roles []string
+	if err := s.DB.Model(&models.User{}).Where("id = ?", userID).Limit(1).Pluck("role", &roles).Error; err != nil {
+		return "", err
+	}
+	if len(roles) == 0 {
+		return "", nil
+	}
+	return roles[0], nil
 }
 
 func (s *AuthService) Login(email, pw string) (models.User, error) {
@@ -72,13 +167,19 @@ func (s *AuthService) Login(email, pw string) (models.User, error) {
 	if !utils.CheckPassword(u.Password, pw) {
 		return u, errors.New("invalid credentials")
 	}
+	// Configured admins who signed up after startup are promoted here
+	if u.Role != utils.RoleAdmin && s.isAdminEmail(u.Email) {
+		if err := s.DB.Model(&u).Update("role", utils.RoleAdmin).Error; err != nil {
+			return u, err
+		}
+	}
 	return u, nil
 }
 
 func (s *AuthService) VerifyOTP(email, otpCode string) error {
 	// Find the most recent OTP for this email
 	var otp models.OTP
-	if err := s.DB.Where("email = ? AND used = ?", email, false).
+	if err := s.DB.Where("email = ? AND purpose = ? AND used = ?", email, models.OTPPurposeSignup, false).
 		Order("created_at DESC").
 		First(&otp).Error; err != nil {
 		return errors.New("invalid or expired OTP")
@@ -116,26 +217,170 @@ func (s *AuthService) ResendOTP(email string) error {
 		return errors.New("user not found")
 	}
 
-	// Generate new OTP
-	otp := s.EmailSvc.GenerateOTP()
-	otpModel := models.OTP{
-		Email:     email,
-		Code:      otp,
-		ExpiresAt: time.Now().Add(10 * time.Minute), // OTP expires in 10 minutes
-		Used:      false,
+	// Generate and send a new OTP
+	return s.issueOTP(email, models.OTPPurposeSignup)
+}
+
+// checkOTPThrottle enforces a minimum gap between OTP sends and a cap on the
+// number of OTPs issued to one email within the throttling window
+func (s *AuthService) checkOTPThrottle(db *gorm.DB, email string) error {
+	now := time.Now()
+
+	var recent []models.OTP
+	if err := db.Unscoped().
+		Where("email = ? AND created_at > ?", email, now.Add(-otpWindow)).
+		Order("created_at DESC").
+		Find(&recent).Error; err != nil {
+		return err
+	}
+
+	if len(recent) == 0 {
+		return nil
+	}
+
+	var retryAfter time.Duration
+	if since := now.Sub(recent[0].CreatedAt); since < otpCooldown {
+		retryAfter = otpCooldown - since
+	}
+	if len(recent) >= otpMaxPerWindow {
+		// The window frees up once the oldest OTP that still counts ages out
+		oldest := recent[otpMaxPerWindow-1]
+		if wait := oldest.CreatedAt.Add(otpWindow).Sub(now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		return &OTPRateLimitError{RetryAfter: retryAfter}
 	}
+	return nil
+}
+
+var (
+	// ErrEmailInUse is returned when another account already has the email
+	ErrEmailInUse = errors.New("email is already in use")
+	// ErrSameEmail is returned when asked to change to the current email
+	ErrSameEmail = errors.New("new email is the same as the current email")
+	// ErrNoPendingEmail is returned when verifying without a pending email change
+	ErrNoPendingEmail = errors.New("no email change is pending")
+	// ErrInvalidOTPCode is returned when an OTP doesn't match the code sent
+	ErrInvalidOTPCode = errors.New("invalid OTP code")
+	// ErrOTPAttemptsExceeded is returned for the wrong code that uses up an
+	// OTP's attempts; a new code has to be requested
+	ErrOTPAttemptsExceeded = errors.New("too many incorrect codes; request a new one")
+)
+
+// maxOTPAttempts is how many wrong codes an email-change OTP accepts before
+// it is invalidated, so its six digits can't be guessed
+const maxOTPAttempts = 5
 
-	// Save new OTP to database
-	if err := s.DB.Create(&otpModel).Error; err != nil {
+// emailTaken reports whether an account other than uid uses email.
+// Soft-deleted accounts count since they keep their place in the unique index.
+func emailTaken(db *gorm.DB, email string, uid uint) (bool, error) {
+	var count int64
+	err := db.Unscoped().Model(&models.User{}).
+		Where("LOWER(email) = LOWER(?) AND id <> ?", email, uid).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// RequestEmailChange records newEmail as the user's pending email and sends
+// an OTP to it. The current email stays in use until ConfirmEmailChange.
+func (s *AuthService) RequestEmailChange(uid uint, newEmail string) error {
+	newEmail = strings.TrimSpace(newEmail)
+
+	var user models.User
+	if err := s.DB.First(&user, uid).Error; err != nil {
+		return err
+	}
+	if strings.EqualFold(user.Email, newEmail) {
+		return ErrSameEmail
+	}
+	taken, err := emailTaken(s.DB, newEmail, uid)
+	if err != nil {
 		return err
 	}
+	if taken {
+		return ErrEmailInUse
+	}
 
-	// Send OTP email
-	if err := s.EmailSvc.SendOTP(email, otp); err != nil {
+	// Fail before recording the pending email; issueOTP checks again atomically
+	if err := s.checkOTPThrottle(s.DB, newEmail); err != nil {
 		return err
 	}
+	if err := s.DB.Model(&user).Update("pending_email", newEmail).Error; err != nil {
+		return err
+	}
+	return s.issueOTP(newEmail, models.OTPPurposeEmailChange)
+}
 
-	return nil
+// ConfirmEmailChange switches the user to their pending email once otpCode
+// matches the latest OTP sent to it. Reports and alerts are addressed from
+// the user row, so they follow the new email from then on.
+func (s *AuthService) ConfirmEmailChange(uid uint, otpCode string) (models.User, error) {
+	var user models.User
+	var otp models.OTP
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, uid).Error; err != nil {
+			return err
+		}
+		if user.PendingEmail == "" {
+			return ErrNoPendingEmail
+		}
+
+		if err := tx.Where("email = ? AND purpose = ? AND used = ?", user.PendingEmail, models.OTPPurposeEmailChange, false).
+			Order("created_at DESC").
+			First(&otp).Error; err != nil {
+			return errors.New("invalid or expired OTP")
+		}
+		if time.Now().After(otp.ExpiresAt) {
+			return errors.New("OTP has expired")
+		}
+		if otp.Code != otpCode {
+			return ErrInvalidOTPCode
+		}
+
+		// The address may have been claimed since the change was requested
+		taken, err := emailTaken(tx, user.PendingEmail, uid)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrEmailInUse
+		}
+
+		// Retire every outstanding email-change OTP for the address, not
+		// just the one used
+		if err := tx.Model(&models.OTP{}).
+			Where("email = ? AND purpose = ? AND used = ?", user.PendingEmail, models.OTPPurposeEmailChange, false).
+			Update("used", true).Error; err != nil {
+			return err
+		}
+		user.Email, user.PendingEmail = user.PendingEmail, ""
+		return tx.Model(&user).Updates(map[string]interface{}{"email": user.Email, "pending_email": ""}).Error
+	})
+	if errors.Is(err, ErrInvalidOTPCode) {
+		// Counted outside the transaction, which the wrong code rolled back
+		return user, s.recordFailedOTPAttempt(otp)
+	}
+	return user, err
+}
+
+// recordFailedOTPAttempt counts a wrong code against otp, marking it used
+// once maxOTPAttempts is reached. The count is incremented in SQL so
+// concurrent guesses can't share an attempt. It returns the error to report
+// for the wrong code.
+func (s *AuthService) recordFailedOTPAttempt(otp models.OTP) error {
+	if err := s.DB.Model(&models.OTP{}).Where("id = ?", otp.ID).Updates(map[string]interface{}{
+		"attempts": gorm.Expr("attempts + 1"),
+		"used":     gorm.Expr("attempts + 1 >= ?", maxOTPAttempts),
+	}).Error; err != nil {
+		return err
+	}
+	if otp.Attempts+1 >= maxOTPAttempts {
+		return ErrOTPAttemptsExceeded
+	}
+	return ErrInvalidOTPCode
 }
 
 func GetDefaultBudget(b float64) float64 {
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gopkg.in/mail.v2"
+
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+	"github.com/your-github/expense-tracker-backend/models"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+// withRecentOTPs answers the throttle query with OTPs created the given
+// durations ago, newest first as the query orders them
+func withRecentOTPs(stub *testutil.StubDB, ages ...time.Duration) {
+	now := time.Now()
+	rows := make([][]driver.Value, 0, len(ages))
+	for i, age := range ages {
+		rows = append(rows, []driver.Value{int64(i + 1), "asha@example.com", now.Add(-age)})
+	}
+	stub.On(`FROM "otps"`, []string{"id", "email", "created_at"}, rows...)
+}
+
+func TestOTPThrottle(t *testing.T) {
+	tests := []struct {
+		name       string
+		ages       []time.Duration
+		retryAfter time.Duration // zero when the OTP may be sent
+	}{
+		{"first OTP", nil, 0},
+		{"inside cooldown", []time.Duration{20 * time.Second}, 40 * time.Second},
+		{"after cooldown", []time.Duration{2 * time.Minute}, 0},
+		{"two in window", []time.Duration{2 * time.Minute, 5 * time.Minute}, 0},
+		{"window cap reached", []time.Duration{5 * time.Minute, 10 * time.Minute, 14 * time.Minute}, time.Minute},
+		{"cap frees up with the third newest", []time.Duration{2 * time.Minute, 3 * time.Minute, 4 * time.Minute, 14 * time.Minute}, 11 * time.Minute},
+		{"cooldown longer than cap wait", []time.Duration{10 * time.Second, 5 * time.Minute, 14*time.Minute + 40*time.Second}, 50 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, stub := testutil.NewStubDB(t)
+			withRecentOTPs(stub, tt.ages...)
+			service := &AuthService{DB: db}
+
+			err := service.checkOTPThrottle(db, "asha@example.com")
+			if tt.retryAfter == 0 {
+				if err != nil {
+					t.Fatalf("err = %v, want the OTP allowed", err)
+				}
+				return
+			}
+			var rl *OTPRateLimitError
+			if !errors.As(err, &rl) {
+				t.Fatalf("err = %v, want an OTPRateLimitError", err)
+			}
+			if diff := rl.RetryAfter - tt.retryAfter; diff > time.Second || diff < -time.Second {
+				t.Errorf("RetryAfter = %v, want about %v", rl.RetryAfter, tt.retryAfter)
+			}
+		})
+	}
+}
+
+func TestOTPThrottleCountsOnlyTheWindow(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	withRecentOTPs(stub)
+	service := &AuthService{DB: db}
+
+	if err := service.checkOTPThrottle(db, "asha@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	queries := stub.Ran(`FROM "otps"`)
+	if len(queries) != 1 {
+		t.Fatalf("ran %d OTP queries, want 1", len(queries))
+	}
+	args := queries[0].Args
+	if args[0] != "asha@example.com" {
+		t.Errorf("throttled by %v, want the email", args[0])
+	}
+	since, ok := args[1].(time.Time)
+	if !ok || time.Since(since)-otpWindow > time.Second || time.Since(since) < otpWindow {
+		t.Errorf("counted OTPs since %v, want the last %v", args[1], otpWindow)
+	}
+}
+
+func TestResendOTPThrottledBeforeSending(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`FROM "users"`, []string{"id", "email"}, []driver.Value{int64(1), "asha@example.com"})
+	withRecentOTPs(stub, 30*time.Second)
+	// No SMTP host is configured, so reaching SendOTP would fail differently
+	service := &AuthService{DB: db, EmailSvc: &EmailService{}}
+
+	var rl *OTPRateLimitError
+	if err := service.ResendOTP("asha@example.com"); !errors.As(err, &rl) {
+		t.Fatalf("err = %v, want an OTPRateLimitError", err)
+	}
+	if rl.RetryAfterSeconds() < 29 || rl.RetryAfterSeconds() > 30 {
+		t.Errorf("retry after %ds, want about 30", rl.RetryAfterSeconds())
+	}
+	if len(stub.Ran(`INSERT INTO "otps"`)) != 0 {
+		t.Error("a throttled resend stored a new OTP")
+	}
+}
+
+// TestConcurrentOTPSendsStoreOne fires simultaneous sends at one email. The
+// stub serializes on the advisory lock the way Postgres does, holding it
+// until the transaction ends.
+func TestConcurrentOTPSendsStoreOne(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+
+	var lock, table sync.Mutex
+	var stored []time.Time
+	stub.Handle("pg_advisory_xact_lock", func(string, []driver.Value) (testutil.StubResult, error) {
+		lock.Lock()
+		return testutil.StubResult{}, nil
+	})
+	release := func(string, []driver.Value) (testutil.StubResult, error) {
+		lock.Unlock()
+		return testutil.StubResult{}, nil
+	}
+	stub.Handle("COMMIT", release)
+	stub.Handle("ROLLBACK", release)
+	stub.Handle(`FROM "otps"`, func(string, []driver.Value) (testutil.StubResult, error) {
+		table.Lock()
+		defer table.Unlock()
+		result := testutil.StubResult{Columns: []string{"id", "email", "created_at"}}
+		for i, created := range stored {
+			result.Rows = append([][]driver.Value{{int64(i + 1), "asha@example.com", created}}, result.Rows...)
+		}
+		return result, nil
+	})
+	stub.Handle(`INSERT INTO "otps"`, func(string, []driver.Value) (testutil.StubResult, error) {
+		table.Lock()
+		defer table.Unlock()
+		stored = append(stored, time.Now())
+		return testutil.StubResult{Columns: []string{"id"}, Rows: [][]driver.Value{{int64(len(stored))}}}, nil
+	})
+	// No SMTP host is configured, so the one OTP that's stored fails to send
+	service := &AuthService{DB: db, EmailSvc: &EmailService{}}
+
+	const senders = 8
+	var wg sync.WaitGroup
+	var throttled atomic.Int32
+	for range senders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var rl *OTPRateLimitError
+			if errors.As(service.issueOTP("asha@example.com", models.OTPPurposeSignup), &rl) {
+				throttled.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(stored) != 1 || throttled.Load() != senders-1 {
+		t.Errorf("stored %d OTPs and throttled %d sends, want 1 and %d", len(stored), throttled.Load(), senders-1)
+	}
+}
+
+func TestCurrentRole(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	service := &AuthService{DB: db}
+
+	if role, err := service.CurrentRole(4); err != nil || role != "" {
+		t.Errorf("missing user = %q, %v; want no role", role, err)
+	}
+	stub.On(`FROM "users"`, []string{"role"}, []driver.Value{"admin"})
+	if role, err := service.CurrentRole(4); err != nil || role != "admin" {
+		t.Errorf("admin = %q, %v", role, err)
+	}
+	if args := stub.Ran(`FROM "users"`)[0].Args; args[0] != uint(4) {
+		t.Errorf("looked up user %v, want 4", args[0])
+	}
+	stub.Fail(`FROM "users"`, errors.New("connection refused"))
+	if _, err := service.CurrentRole(4); err == nil {
+		t.Error("lookup error was swallowed")
+	}
+}
+
+func TestGenerateOTPIsSixDigits(t *testing.T) {
+	svc := NewEmailService("", 0, "", "")
+	for i := 0; i < 20; i++ {
+		otp, err := svc.GenerateOTP()
+		if err != nil {
+			t.Fatalf("GenerateOTP: %v", err)
+		}
+		if len(otp) != otpLength || strings.Trim(otp, utils.DigitCharset) != "" {
+			t.Fatalf("GenerateOTP() = %q, want %d digits", otp, otpLength)
+		}
+	}
+}
+
+func TestRenderOTPEmailByPurpose(t *testing.T) {
+	subject, body, err := renderOTPEmail("482913", models.OTPPurposeSignup)
+	if err != nil || subject != "BucksInfo - Email Verification Code" || !strings.Contains(body, "complete your registration") || !strings.Contains(body, "482913") {
+		t.Errorf("signup email = %q, %v:\n%s", subject, err, body)
+	}
+
+	subject, body, err = renderOTPEmail("482913", models.OTPPurposeEmailChange)
+	if err != nil || subject != "BucksInfo - Confirm your new email address" || !strings.Contains(body, "confirm the change") || !strings.Contains(body, "482913") {
+		t.Errorf("email change email = %q, %v:\n%s", subject, err, body)
+	}
+	if strings.Contains(body, "signing up") {
+		t.Error("the email change email talks about signing up")
+	}
+
+	if _, _, err := renderOTPEmail("482913", "password_reset"); err == nil {
+		t.Error("an unknown purpose was rendered")
+	}
+}
+
+// newEmailChangeFixture serves user 7, asha@example.com, with pending as
+// their pending email; emails are captured rather than sent
+func newEmailChangeFixture(t *testing.T, pending string) (*AuthService, *testutil.StubDB, *[]*mail.Message) {
+	t.Helper()
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`FROM "users"`, []string{"id", "email", "pending_email"}, []driver.Value{int64(7), "asha@example.com", pending})
+	stub.On(`SELECT count(*) FROM "users"`, []string{"count"}, []driver.Value{int64(0)})
+	var sent []*mail.Message
+	email := &EmailService{deliver: func(m *mail.Message) error {
+		sent = append(sent, m)
+		return nil
+	}}
+	return &AuthService{DB: db, EmailSvc: email}, stub, &sent
+}
+
+func TestRequestEmailChangeKeepsTheCurrentEmailUntilVerified(t *testing.T) {
+	service, stub, sent := newEmailChangeFixture(t, "")
+
+	if err := service.RequestEmailChange(7, " asha.new@example.com "); err != nil {
+		t.Fatalf("RequestEmailChange: %v", err)
+	}
+	updates := stub.Ran(`UPDATE "users"`)
+	if len(updates) != 1 || !strings.Contains(updates[0].SQL, `SET "pending_email"=$1`) || updates[0].Args[0] != "asha.new@example.com" {
+		t.Fatalf("updates = %+v, want only the pending email set", updates)
+	}
+	inserts := stub.Ran(`INSERT INTO "otps"`)
+	if len(inserts) != 1 {
+		t.Fatalf("stored %d OTPs, want 1", len(inserts))
+	}
+	if values := testutil.InsertedValues(inserts[0].SQL, inserts[0].Args); values["email"] != "asha.new@example.com" || values["purpose"] != models.OTPPurposeEmailChange {
+		t.Errorf("OTP = %v, want an email change OTP for the new address", values)
+	}
+	if len(*sent) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(*sent))
+	}
+	if to, subject := (*sent)[0].GetHeader("To"), (*sent)[0].GetHeader("Subject"); len(to) != 1 || to[0] != "asha.new@example.com" || subject[0] != "BucksInfo - Confirm your new email address" {
+		t.Errorf("sent %q to %v, want the email change code at the new address", subject, to)
+	}
+}
+
+func TestRequestEmailChangeRejectsAnEmailInUse(t *testing.T) {
+	service, stub, sent := newEmailChangeFixture(t, "")
+
+	if err := service.RequestEmailChange(7, "ASHA@example.com"); !errors.Is(err, ErrSameEmail) {
+		t.Errorf("current email: %v, want ErrSameEmail", err)
+	}
+	stub.On(`SELECT count(*) FROM "users"`, []string{"count"}, []driver.Value{int64(1)})
+	if err := service.RequestEmailChange(7, "ravi@example.com"); !errors.Is(err, ErrEmailInUse) {
+		t.Errorf("another account's email: %v, want ErrEmailInUse", err)
+	}
+	// Deleted accounts still hold their address
+	if counts := stub.Ran(`SELECT count(*) FROM "users"`); len(counts) != 1 || strings.Contains(counts[0].SQL, "deleted_at") {
+		t.Errorf("uniqueness checks = %+v, want one including deleted accounts", counts)
+	}
+	if len(stub.Ran(`UPDATE "users"`)) != 0 || len(stub.Ran(`INSERT INTO "otps"`)) != 0 || len(*sent) != 0 {
+		t.Error("a rejected email change was recorded")
+	}
+}
+
+// withEmailChangeOTP answers the OTP lookup with code, already tried attempts times
+func withEmailChangeOTP(stub *testutil.StubDB, code string, attempts int) {
+	stub.On(`FROM "otps"`, []string{"id", "email", "code", "purpose", "expires_at", "used", "attempts"},
+		[]driver.Value{int64(3), "asha.new@example.com", code, models.OTPPurposeEmailChange, time.Now().Add(5 * time.Minute), false, int64(attempts)})
+}
+
+func TestConfirmEmailChange(t *testing.T) {
+	service, stub, _ := newEmailChangeFixture(t, "asha.new@example.com")
+	withEmailChangeOTP(stub, "482913", 0)
+
+	if _, err := service.ConfirmEmailChange(7, "000000"); !errors.Is(err, ErrInvalidOTPCode) {
+		t.Fatalf("wrong code: %v, want ErrInvalidOTPCode", err)
+	}
+	if updates := stub.Ran(`UPDATE "users"`); len(updates) != 0 {
+		t.Fatalf("a wrong code changed the user: %+v", updates)
+	}
+	attempts := stub.Ran(`UPDATE "otps"`)
+	if len(attempts) != 1 || !strings.Contains(attempts[0].SQL, `"attempts"=attempts + 1`) {
+		t.Fatalf("OTP updates = %+v, want the failed attempt counted", attempts)
+	}
+
+	user, err := service.ConfirmEmailChange(7, "482913")
+	if err != nil {
+		t.Fatalf("ConfirmEmailChange: %v", err)
+	}
+	if user.Email != "asha.new@example.com" || user.PendingEmail != "" {
+		t.Errorf("user = %s pending %q, want the new email committed", user.Email, user.PendingEmail)
+	}
+	updates := stub.Ran(`UPDATE "users"`)
+	if len(updates) != 1 || !hasArg(updates[0].Args, "asha.new@example.com") {
+		t.Errorf("user updates = %+v, want the email switched", updates)
+	}
+}
+
+func TestConfirmEmailChangeInvalidatesTheCodeAfterTooManyAttempts(t *testing.T) {
+	service, stub, _ := newEmailChangeFixture(t, "asha.new@example.com")
+	withEmailChangeOTP(stub, "482913", maxOTPAttempts-1)
+
+	if _, err := service.ConfirmEmailChange(7, "000000"); !errors.Is(err, ErrOTPAttemptsExceeded) {
+		t.Fatalf("last wrong code: %v, want ErrOTPAttemptsExceeded", err)
+	}
+	attempts := stub.Ran(`UPDATE "otps"`)
+	if len(attempts) != 1 || !strings.Contains(attempts[0].SQL, `"used"=attempts + 1 >= $`) || !hasArg(attempts[0].Args, maxOTPAttempts) {
+		t.Errorf("OTP updates = %+v, want the OTP marked used at %d attempts", attempts, maxOTPAttempts)
+	}
+
+	// Once used, the lookup no longer finds it, even with the right code
+	stub.On(`FROM "otps"`, []string{"id"})
+	if _, err := service.ConfirmEmailChange(7, "482913"); err == nil {
+		t.Error("the invalidated code was accepted")
+	}
+}
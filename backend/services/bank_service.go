@@ -0,0 +1,153 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/your-github/expense-tracker-backend/models"
+)
+
+// Paging bounds for the bank list
+const (
+	DefaultBankListLimit = 100
+	MaxBankListLimit     = 500
+)
+
+// BankTypes are the accepted values of models.Bank.Type
+var BankTypes = []string{"public", "private", "foreign", "rrb", "sfb"}
+
+var (
+	ErrInvalidBank     = errors.New("bank needs an id of lowercase letters, digits and dashes, a name and, if set, a four-letter IFSC prefix")
+	ErrInvalidBankType = errors.New("type must be one of public, private, foreign, rrb, sfb")
+	ErrBankExists      = errors.New("bank already exists")
+	ErrBankNotFound    = errors.New("bank not found")
+)
+
+var bankIDPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// BankFilter narrows the bank list. Type matches exactly; Query matches
+// the name or ID case-insensitively.
+type BankFilter struct {
+	Type   string
+	Query  string
+	Limit  int
+	Offset int
+}
+
+// BankService serves and manages the public bank list
+type BankService struct {
+	DB *gorm.DB
+}
+
+// NewBankService creates a new bank service
+func NewBankService(db *gorm.DB) *BankService {
+	return &BankService{DB: db}
+}
+
+// ValidBankType reports whether t is one of BankTypes
+func ValidBankType(t string) bool {
+	for _, bankType := range BankTypes {
+		if t == bankType {
+			return true
+		}
+	}
+	return false
+}
+
+// List returns a page of banks ordered by name and the total number matching filter
+func (s *BankService) List(filter BankFilter) ([]models.Bank, int64, error) {
+	if filter.Type != "" && !ValidBankType(filter.Type) {
+		return nil, 0, ErrInvalidBankType
+	}
+
+	query := s.DB.Model(&models.Bank{})
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		pattern := "%" + escapeLike(q) + "%"
+		query = query.Where("name ILIKE ? OR id ILIKE ?", pattern, pattern)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultBankListLimit
+	}
+	banks := make([]models.Bank, 0)
+	err := query.Order("name").Limit(limit).Offset(filter.Offset).Find(&banks).Error
+	return banks, total, err
+}
+
+// Create adds a bank to the list
+func (s *BankService) Create(bank models.Bank) (models.Bank, error) {
+	if err := validateBank(&bank); err != nil {
+		return models.Bank{}, err
+	}
+
+	result := s.DB.Where("id = ?", bank.ID).FirstOrCreate(&bank)
+	if result.Error != nil {
+		return models.Bank{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return bank, ErrBankExists
+	}
+	return bank, nil
+}
+
+// Update replaces the details of the bank with the given ID
+func (s *BankService) Update(id string, bank models.Bank) (models.Bank, error) {
+	bank.ID = id
+	if err := validateBank(&bank); err != nil {
+		return models.Bank{}, err
+	}
+
+	result := s.DB.Model(&models.Bank{ID: id}).Select("name", "logo", "type", "ifsc_prefix").Updates(&bank)
+	if result.Error != nil {
+		return models.Bank{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.Bank{}, ErrBankNotFound
+	}
+
+	var updated models.Bank
+	err := s.DB.First(&updated, "id = ?", id).Error
+	return updated, err
+}
+
+// Delete removes a bank from the list. Accounts already linked to it are kept.
+func (s *BankService) Delete(id string) error {
+	result := s.DB.Delete(&models.Bank{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBankNotFound
+	}
+	return nil
+}
+
+// validateBank trims bank's fields and checks them
+func validateBank(bank *models.Bank) error {
+	bank.ID = strings.TrimSpace(bank.ID)
+	bank.Name = strings.TrimSpace(bank.Name)
+	bank.Logo = strings.TrimSpace(bank.Logo)
+	bank.IFSCPrefix = strings.ToUpper(strings.TrimSpace(bank.IFSCPrefix))
+	if !bankIDPattern.MatchString(bank.ID) || len(bank.ID) > 64 || bank.Name == "" {
+		return ErrInvalidBank
+	}
+	if !ValidBankType(bank.Type) {
+		return ErrInvalidBankType
+	}
+	if bank.IFSCPrefix != "" && len(bank.IFSCPrefix) != 4 {
+		return ErrInvalidBank
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+	"github.com/your-github/expense-tracker-backend/models"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+// bankTable answers bank list queries from the seeded utils.Banks,
+// honouring the type filter, the name/ID search and LIMIT/OFFSET
+func bankTable(stub *testutil.StubDB) {
+	arg := func(query, condition string, args []driver.Value) (string, bool) {
+		m := regexp.MustCompile(condition + ` \$(\d+)`).FindStringSubmatch(query)
+		if m == nil {
+			return "", false
+		}
+		n, _ := strconv.Atoi(m[1])
+		return args[n-1].(string), true
+	}
+	stub.Handle(`FROM "banks"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		var matched []utils.Bank
+		for _, bank := range utils.Banks {
+			if v, ok := arg(query, `type =`, args); ok && bank.Type != v {
+				continue
+			}
+			if v, ok := arg(query, `name ILIKE`, args); ok {
+				term := strings.ToLower(strings.Trim(v, "%"))
+				if !strings.Contains(strings.ToLower(bank.Name), term) && !strings.Contains(bank.ID, term) {
+					continue
+				}
+			}
+			matched = append(matched, bank)
+		}
+		if strings.Contains(query, "count(*)") {
+			return testutil.StubResult{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(len(matched))}}}, nil
+		}
+
+		sort.SliceStable(matched, func(i, j int) bool { return matched[i].Name < matched[j].Name })
+		if m := regexp.MustCompile(`OFFSET (\d+)`).FindStringSubmatch(query); m != nil {
+			offset, _ := strconv.Atoi(m[1])
+			matched = matched[min(offset, len(matched)):]
+		}
+		if m := regexp.MustCompile(`LIMIT (\d+)`).FindStringSubmatch(query); m != nil {
+			limit, _ := strconv.Atoi(m[1])
+			matched = matched[:min(limit, len(matched))]
+		}
+
+		result := testutil.StubResult{Columns: []string{"id", "name", "type"}}
+		for _, bank := range matched {
+			result.Rows = append(result.Rows, []driver.Value{bank.ID, bank.Name, bank.Type})
+		}
+		return result, nil
+	})
+}
+
+func TestBankListFiltersByTypeAndName(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	bankTable(stub)
+	service := NewBankService(db)
+
+	private, total, err := service.List(BankFilter{Type: "private"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(private) == 0 || total != int64(len(private)) {
+		t.Fatalf("private banks = %d of %d", len(private), total)
+	}
+	for _, bank := range private {
+		if bank.Type != "private" {
+			t.Errorf("%s is %s, want only private banks", bank.ID, bank.Type)
+		}
+	}
+
+	banks, total, err := service.List(BankFilter{Query: " baroda "})
+	if err != nil || total != 1 || len(banks) != 1 || banks[0].ID != "bank-of-baroda" {
+		t.Errorf("search for baroda = %+v (%d), %v; want Bank of Baroda", banks, total, err)
+	}
+	if banks, _, _ := service.List(BankFilter{Type: "public", Query: "hdfc"}); len(banks) != 0 {
+		t.Errorf("HDFC found among public banks: %+v", banks)
+	}
+	// Wildcards in the search are literal
+	if banks, _, _ := service.List(BankFilter{Query: "%"}); len(banks) != 0 {
+		t.Errorf("search for %% matched %d banks", len(banks))
+	}
+	search := stub.Ran(`name ILIKE`)
+	if last := search[len(search)-1]; last.Args[0] != `%\%%` {
+		t.Errorf("search args %v, want the %% escaped", last.Args)
+	}
+
+	if _, _, err := service.List(BankFilter{Type: "cooperative"}); !errors.Is(err, ErrInvalidBankType) {
+		t.Errorf("unknown type: %v, want ErrInvalidBankType", err)
+	}
+}
+
+func TestBankListPages(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	bankTable(stub)
+	service := NewBankService(db)
+
+	all, total, _ := service.List(BankFilter{Limit: MaxBankListLimit})
+	page, pageTotal, err := service.List(BankFilter{Limit: 5, Offset: 3})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(page) != 5 || pageTotal != total || total != int64(len(utils.Banks)) {
+		t.Fatalf("page of %d from %d, want 5 from %d", len(page), pageTotal, len(utils.Banks))
+	}
+	for i, bank := range page {
+		if bank.ID != all[i+3].ID {
+			t.Errorf("page[%d] = %s, want %s", i, bank.ID, all[i+3].ID)
+		}
+	}
+	if ran := stub.Ran(`SELECT * FROM "banks"`); !strings.Contains(ran[0].SQL, "ORDER BY name") {
+		t.Errorf("list isn't ordered by name: %s", ran[0].SQL)
+	}
+}
+
+func TestBankCreateValidatesAndRejectsDuplicates(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	service := NewBankService(db)
+
+	tests := []struct {
+		name string
+		bank models.Bank
+		want error
+	}{
+		{"uppercase id", models.Bank{ID: "HDFC", Name: "HDFC Bank", Type: "private"}, ErrInvalidBank},
+		{"no name", models.Bank{ID: "hdfc", Name: " ", Type: "private"}, ErrInvalidBank},
+		{"short IFSC prefix", models.Bank{ID: "hdfc", Name: "HDFC Bank", Type: "private", IFSCPrefix: "HDF"}, ErrInvalidBank},
+		{"unknown type", models.Bank{ID: "hdfc", Name: "HDFC Bank", Type: "cooperative"}, ErrInvalidBankType},
+	}
+	for _, tt := range tests {
+		if _, err := service.Create(tt.bank); !errors.Is(err, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	bank, err := service.Create(models.Bank{ID: " jana-sfb ", Name: "Jana Small Finance Bank", Type: "sfb", IFSCPrefix: "jsfb"})
+	if err != nil || bank.ID != "jana-sfb" || bank.IFSCPrefix != "JSFB" {
+		t.Fatalf("Create = %+v, %v; want a trimmed ID and upper-case prefix", bank, err)
+	}
+	if n := len(stub.Ran(`INSERT INTO "banks"`)); n != 1 {
+		t.Fatalf("ran %d inserts, want 1", n)
+	}
+
+	stub.On(`SELECT * FROM "banks"`, []string{"id", "name", "type"}, []driver.Value{"jana-sfb", "Jana Small Finance Bank", "sfb"})
+	if existing, err := service.Create(models.Bank{ID: "jana-sfb", Name: "Jana", Type: "sfb"}); !errors.Is(err, ErrBankExists) || existing.Name != "Jana Small Finance Bank" {
+		t.Errorf("duplicate = %+v, %v; want ErrBankExists with the stored bank", existing, err)
+	}
+}
+
+func TestBankUpdateAndDeleteUnknownBank(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.Handle(`"banks"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		return testutil.StubResult{Affected: 0}, nil
+	})
+	service := NewBankService(db)
+
+	if _, err := service.Update("missing", models.Bank{Name: "Missing Bank", Type: "public"}); !errors.Is(err, ErrBankNotFound) {
+		t.Errorf("Update: %v, want ErrBankNotFound", err)
+	}
+	if err := service.Delete("missing"); !errors.Is(err, ErrBankNotFound) {
+		t.Errorf("Delete: %v, want ErrBankNotFound", err)
+	}
+}
@@ -3,17 +3,38 @@ package services
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/your-github/expense-tracker-backend/config"
+)
+
+// Retry and circuit breaker defaults for provider calls
+const (
+	defaultVerificationMaxAttempts     = 3
+	defaultVerificationRetryBackoff    = 500 * time.Millisecond
+	defaultVerificationBreakerFailures = 5
+	defaultVerificationBreakerWindow   = time.Minute
+	defaultVerificationBreakerCooldown = 30 * time.Second
 )
 
+// ErrVerificationUnavailable is returned while the circuit breaker is open
+// after repeated provider failures
+var ErrVerificationUnavailable = errors.New("verification temporarily unavailable")
+
 type BankVerificationService struct {
-	APIKey   string
-	APIURL   string
-	Client   *http.Client
-	Provider string // "karza", "signzy", "razorpay", "mock"
+	APIKey       string
+	APIURL       string
+	Client       *http.Client
+	Provider     string // "karza", "signzy", "razorpay", "mock"
+	MaxAttempts  int
+	RetryBackoff time.Duration
+
+	breaker *circuitBreaker
 }
 
 type BankVerificationRequest struct {
@@ -93,11 +114,53 @@ func NewBankVerificationService(apiKey, apiURL, provider string) *BankVerificati
 		APIURL:   apiURL,
 		Provider: provider,
 		Client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: 30 * time.Second, // per attempt
 		},
+		MaxAttempts:  defaultVerificationMaxAttempts,
+		RetryBackoff: defaultVerificationRetryBackoff,
+		breaker: newCircuitBreaker(
+			defaultVerificationBreakerFailures,
+			defaultVerificationBreakerWindow,
+			defaultVerificationBreakerCooldown,
+		),
+	}
+}
+
+// BankVerificationProvider resolves the provider to use from config. Mock is
+// used when verification is disabled or the provider is unset or unknown.
+func BankVerificationProvider(cfg config.BankVerificationConfig) string {
+	if !cfg.Enabled {
+		return "mock"
+	}
+	switch provider := strings.ToLower(strings.TrimSpace(cfg.Provider)); provider {
+	case "karza", "signzy", "razorpay":
+		return provider
+	default:
+		return "mock"
 	}
 }
 
+// NewBankVerificationServiceFromConfig creates a verification service for the configured provider
+func NewBankVerificationServiceFromConfig(cfg config.BankVerificationConfig) *BankVerificationService {
+	svc := NewBankVerificationService(cfg.APIKey, cfg.APIURL, BankVerificationProvider(cfg))
+	if cfg.MaxAttempts > 0 {
+		svc.MaxAttempts = cfg.MaxAttempts
+	}
+	if cfg.RetryBackoff > 0 {
+		svc.RetryBackoff = cfg.RetryBackoff
+	}
+	if cfg.BreakerThreshold > 0 {
+		svc.breaker.threshold = cfg.BreakerThreshold
+	}
+	if cfg.BreakerWindow > 0 {
+		svc.breaker.window = cfg.BreakerWindow
+	}
+	if cfg.BreakerCooldown > 0 {
+		svc.breaker.cooldown = cfg.BreakerCooldown
+	}
+	return svc
+}
+
 // VerifyBankAccount verifies if the account number is linked to the provided mobile number
 func (s *BankVerificationService) VerifyBankAccount(req BankVerificationRequest) (*BankVerificationResponse, error) {
 	switch s.Provider {
@@ -127,23 +190,17 @@ func (s *BankVerificationService) verifyWithKarza(req BankVerificationRequest) (
 		return nil, fmt.Errorf("failed to marshal Karza request: %v", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", "https://api.karza.in/v3/kyc/bank-account-verification", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Karza request: %v", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("x-karza-key", s.APIKey)
-
-	resp, err := s.Client.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make Karza request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := s.send("Karza", func() (*http.Request, error) {
+		httpReq, err := http.NewRequest("POST", "https://api.karza.in/v3/kyc/bank-account-verification", bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("x-karza-key", s.APIKey)
+		return httpReq, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read Karza response: %v", err)
+		return nil, err
 	}
 
 	var karzaResp KarzaResponse
@@ -175,23 +232,17 @@ func (s *BankVerificationService) verifyWithSignzy(req BankVerificationRequest)
 		return nil, fmt.Errorf("failed to marshal Signzy request: %v", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", "https://api.signzy.com/v2/bank-account-verification", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Signzy request: %v", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+s.APIKey)
-
-	resp, err := s.Client.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make Signzy request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := s.send("Signzy", func() (*http.Request, error) {
+		httpReq, err := http.NewRequest("POST", "https://api.signzy.com/v2/bank-account-verification", bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Authorization", "Bearer "+s.APIKey)
+		return httpReq, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read Signzy response: %v", err)
+		return nil, err
 	}
 
 	var signzyResp SignzyResponse
@@ -223,23 +274,17 @@ func (s *BankVerificationService) verifyWithRazorpay(req BankVerificationRequest
 		return nil, fmt.Errorf("failed to marshal Razorpay request: %v", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", "https://api.razorpay.com/v1/bank-accounts/verify", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Razorpay request: %v", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Basic "+s.APIKey)
-
-	resp, err := s.Client.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make Razorpay request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := s.send("Razorpay", func() (*http.Request, error) {
+		httpReq, err := http.NewRequest("POST", "https://api.razorpay.com/v1/bank-accounts/verify", bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Authorization", "Basic "+s.APIKey)
+		return httpReq, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read Razorpay response: %v", err)
+		return nil, err
 	}
 
 	var razorpayResp map[string]interface{}
@@ -260,6 +305,56 @@ func (s *BankVerificationService) verifyWithRazorpay(req BankVerificationRequest
 	}, nil
 }
 
+// send issues a provider request, retrying network errors and 5xx responses
+// with exponential backoff. Repeated failures open the circuit breaker, after
+// which calls fail fast with ErrVerificationUnavailable.
+func (s *BankVerificationService) send(provider string, newRequest func() (*http.Request, error)) ([]byte, error) {
+	if !s.breaker.Allow() {
+		return nil, ErrVerificationUnavailable
+	}
+
+	attempts := s.MaxAttempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(s.RetryBackoff << (attempt - 2))
+		}
+
+		httpReq, err := newRequest()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s request: %v", provider, err)
+		}
+
+		resp, err := s.Client.Do(httpReq)
+		if err != nil {
+			lastErr = fmt.Errorf("failed to make %s request: %v", provider, err)
+			continue
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("failed to read %s response: %v", provider, err)
+			continue
+		}
+
+		if resp.StatusCode >= 500 {
+			lastErr = fmt.Errorf("%s returned status %d", provider, resp.StatusCode)
+			continue
+		}
+
+		s.breaker.Success()
+		return body, nil
+	}
+
+	s.breaker.Failure()
+	return nil, lastErr
+}
+
 // Enhanced Mock verification for testing and development
 func (s *BankVerificationService) MockVerifyBankAccount(req BankVerificationRequest) (*BankVerificationResponse, error) {
 	// Simulate API delay
@@ -298,32 +393,8 @@ func (s *BankVerificationService) MockVerifyBankAccount(req BankVerificationRequ
 
 // validateMockVerification provides realistic mock validation
 func (s *BankVerificationService) validateMockVerification(req BankVerificationRequest) bool {
-	// Basic format validation
-	if !ValidateAccountNumber(req.AccountNumber) || !ValidateMobileNumber(req.MobileNumber) {
-		return false
-	}
-
-	// Mock bank-specific validation
-	switch req.BankID {
-	case "sbi":
-		// SBI account numbers are typically 11 digits
-		return len(req.AccountNumber) == 11
-	case "hdfc":
-		// HDFC account numbers are typically 12-14 digits
-		return len(req.AccountNumber) >= 12 && len(req.AccountNumber) <= 14
-	case "icici":
-		// ICICI account numbers are typically 12 digits
-		return len(req.AccountNumber) == 12
-	case "axis":
-		// Axis account numbers are typically 15 digits
-		return len(req.AccountNumber) == 15
-	case "airtel-payment":
-		// Airtel Payment Bank account numbers are typically 10-12 digits
-		return len(req.AccountNumber) >= 10 && len(req.AccountNumber) <= 12
-	default:
-		// For other banks, accept 9-18 digits
-		return len(req.AccountNumber) >= 9 && len(req.AccountNumber) <= 18
-	}
+	return ValidateMobileNumber(req.MobileNumber) &&
+		ValidateAccountNumberForBank(req.BankID, req.AccountNumber) == nil
 }
 
 // generateMockIFSC generates realistic IFSC codes
@@ -362,18 +433,14 @@ func ValidateMobileNumber(mobile string) bool {
 	return false
 }
 
-// ValidateAccountNumber validates account number format
-func ValidateAccountNumber(accountNumber string) bool {
-	// Basic validation - account number should be between 9-18 digits
-	if len(accountNumber) < 9 || len(accountNumber) > 18 {
-		return false
-	}
+// NormalizeAccountNumber strips the spaces and hyphens users commonly type
+// into account numbers so equivalent inputs compare equal
+func NormalizeAccountNumber(accountNumber string) string {
+	return strings.NewReplacer(" ", "", "-", "", "\t", "").Replace(strings.TrimSpace(accountNumber))
+}
 
-	// Check if it contains only digits
-	for _, char := range accountNumber {
-		if char < '0' || char > '9' {
-			return false
-		}
-	}
-	return true
+// ValidateAccountNumber validates account number format against the generic
+// rule used for banks without a specific format
+func ValidateAccountNumber(accountNumber string) bool {
+	return defaultAccountNumberFormat.Validate(accountNumber) == nil
 }
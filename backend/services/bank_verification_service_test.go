@@ -0,0 +1,157 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-github/expense-tracker-backend/config"
+)
+
+// providerTransport answers provider calls with status and body after
+// failing the first failures calls with a 503, and records the hosts it was
+// sent to
+type providerTransport struct {
+	status   int
+	body     string
+	failures int
+	hosts    []string
+}
+
+func (p *providerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	p.hosts = append(p.hosts, r.URL.Host)
+	status := p.status
+	if len(p.hosts) <= p.failures {
+		status = http.StatusServiceUnavailable
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(p.body)),
+	}, nil
+}
+
+var verificationRequest = BankVerificationRequest{
+	BankID:        "hdfc",
+	AccountNumber: "123456789012",
+	MobileNumber:  "9876543210",
+	AccountHolder: "Asha",
+}
+
+func TestBankVerificationProvider(t *testing.T) {
+	tests := []struct {
+		cfg  config.BankVerificationConfig
+		want string
+	}{
+		{config.BankVerificationConfig{Enabled: true, Provider: "karza"}, "karza"},
+		{config.BankVerificationConfig{Enabled: true, Provider: " Signzy "}, "signzy"},
+		{config.BankVerificationConfig{Enabled: true, Provider: "razorpay"}, "razorpay"},
+		{config.BankVerificationConfig{Enabled: true}, "mock"},
+		{config.BankVerificationConfig{Enabled: true, Provider: "plaid"}, "mock"},
+		{config.BankVerificationConfig{Enabled: false, Provider: "karza"}, "mock"},
+	}
+	for _, tt := range tests {
+		if got := BankVerificationProvider(tt.cfg); got != tt.want {
+			t.Errorf("BankVerificationProvider(%+v) = %q, want %q", tt.cfg, got, tt.want)
+		}
+		if got := NewBankVerificationServiceFromConfig(tt.cfg).Provider; got != tt.want {
+			t.Errorf("service for %+v uses %q, want %q", tt.cfg, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyBankAccountDispatchesToTheConfiguredProvider(t *testing.T) {
+	tests := []struct {
+		provider, host, body string
+	}{
+		{"karza", "api.karza.in", `{"status":"success","data":{"verified":true,"accountType":"SAVINGS"}}`},
+		{"signzy", "api.signzy.com", `{"status":"success","result":{"verified":true}}`},
+		{"razorpay", "api.razorpay.com", `{"status":"verified"}`},
+	}
+	for _, tt := range tests {
+		transport := &providerTransport{status: http.StatusOK, body: tt.body}
+		svc := NewBankVerificationServiceFromConfig(config.BankVerificationConfig{Enabled: true, Provider: tt.provider})
+		svc.Client = &http.Client{Transport: transport}
+
+		resp, err := svc.VerifyBankAccount(verificationRequest)
+		if err != nil {
+			t.Errorf("%s: %v", tt.provider, err)
+			continue
+		}
+		if resp.Provider != tt.provider || !resp.Verified {
+			t.Errorf("%s: response = %+v", tt.provider, resp)
+		}
+		if len(transport.hosts) != 1 || transport.hosts[0] != tt.host {
+			t.Errorf("%s: called %v, want %s", tt.provider, transport.hosts, tt.host)
+		}
+	}
+}
+
+// karzaVerified is a Karza response for a verified account
+const karzaVerified = `{"status":"success","data":{"verified":true}}`
+
+func TestVerificationRetriesServerErrors(t *testing.T) {
+	transport := &providerTransport{status: http.StatusOK, body: karzaVerified, failures: 2}
+	svc := NewBankVerificationServiceFromConfig(config.BankVerificationConfig{
+		Enabled: true, Provider: "karza", MaxAttempts: 3, RetryBackoff: time.Millisecond,
+	})
+	svc.Client = &http.Client{Transport: transport}
+
+	resp, err := svc.VerifyBankAccount(verificationRequest)
+	if err != nil {
+		t.Fatalf("VerifyBankAccount: %v", err)
+	}
+	if !resp.Verified || len(transport.hosts) != 3 {
+		t.Errorf("verified = %v after %d calls, want true after 3", resp.Verified, len(transport.hosts))
+	}
+}
+
+func TestVerificationGivesUpAfterMaxAttempts(t *testing.T) {
+	transport := &providerTransport{status: http.StatusOK, body: karzaVerified, failures: 5}
+	svc := NewBankVerificationServiceFromConfig(config.BankVerificationConfig{
+		Enabled: true, Provider: "karza", MaxAttempts: 2, RetryBackoff: time.Millisecond,
+	})
+	svc.Client = &http.Client{Transport: transport}
+
+	if _, err := svc.VerifyBankAccount(verificationRequest); err == nil {
+		t.Error("a provider that kept failing verified the account")
+	}
+	if len(transport.hosts) != 2 {
+		t.Errorf("made %d calls, want 2", len(transport.hosts))
+	}
+}
+
+func TestVerificationCircuitBreaker(t *testing.T) {
+	transport := &providerTransport{status: http.StatusOK, body: karzaVerified, failures: 2}
+	svc := NewBankVerificationServiceFromConfig(config.BankVerificationConfig{
+		Enabled: true, Provider: "karza", MaxAttempts: 1,
+		BreakerThreshold: 2, BreakerWindow: time.Minute, BreakerCooldown: time.Minute,
+	})
+	svc.Client = &http.Client{Transport: transport}
+	now := time.Date(2025, time.March, 1, 9, 0, 0, 0, time.UTC)
+	svc.breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := svc.VerifyBankAccount(verificationRequest); err == nil || errors.Is(err, ErrVerificationUnavailable) {
+			t.Fatalf("call %d: err = %v, want the provider error", i+1, err)
+		}
+	}
+	if _, err := svc.VerifyBankAccount(verificationRequest); !errors.Is(err, ErrVerificationUnavailable) {
+		t.Fatalf("open breaker: err = %v, want ErrVerificationUnavailable", err)
+	}
+	if len(transport.hosts) != 2 {
+		t.Errorf("the open breaker let a call through: %d calls", len(transport.hosts))
+	}
+
+	// After the cooldown a trial call goes through and closes the breaker
+	now = now.Add(time.Minute + time.Second)
+	if resp, err := svc.VerifyBankAccount(verificationRequest); err != nil || !resp.Verified {
+		t.Fatalf("trial call: %+v, %v", resp, err)
+	}
+	if _, err := svc.VerifyBankAccount(verificationRequest); err != nil {
+		t.Errorf("closed breaker: %v", err)
+	}
+}
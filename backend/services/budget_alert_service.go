@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/your-github/expense-tracker-backend/models"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+// BudgetAlertSender delivers budget alert emails; EmailService implements it
+type BudgetAlertSender interface {
+	SendBudgetAlert(email, name string, period BudgetPeriod, threshold, spent, budget float64, currency string) error
+}
+
+// BudgetAlertService notifies users when spending so far in their budget
+// period crosses a configured percentage of their budget
+type BudgetAlertService struct {
+	DB         *gorm.DB
+	Summary    *SummaryService
+	Email      BudgetAlertSender
+	Thresholds []float64 // percentages of the budget, e.g. 80 and 100
+}
+
+// NewBudgetAlertService creates a budget alert service; thresholds are sorted ascending
+func NewBudgetAlertService(db *gorm.DB, summary *SummaryService, email BudgetAlertSender, thresholds []float64) *BudgetAlertService {
+	sorted := append([]float64(nil), thresholds...)
+	sort.Float64s(sorted)
+
+	return &BudgetAlertService{
+		DB:         db,
+		Summary:    summary,
+		Email:      email,
+		Thresholds: sorted,
+	}
+}
+
+// Evaluate recomputes the user's spend in the current budget period and
+// records every threshold crossed for the first time this period. One email
+// is sent for the highest crossed threshold whose alert hasn't been emailed
+// yet, so an alert whose email failed is retried on the next evaluation.
+// It returns the newly crossed thresholds.
+func (s *BudgetAlertService) Evaluate(uid uint, now time.Time) ([]float64, error) {
+	if len(s.Thresholds) == 0 {
+		return nil, nil
+	}
+
+	var user models.User
+	if err := s.DB.First(&user, uid).Error; err != nil {
+		return nil, err
+	}
+	if user.Budget <= 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Read totals directly rather than through the summary cache, which may
+	// not yet reflect the write that triggered this evaluation
+	display := s.Summary.displayCurrency(ctx, uid)
+	period := UserBudgetPeriod(user)
+	start, end := period.Bounds(now.In(utils.Location(user.Timezone)))
+	totals, err := s.Summary.aggregateInCurrency(ctx, display, "user_id = ? AND date >= ? AND date < ? AND type = 'expense'",
+		uid, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+
+	var spent float64
+	for _, t := range totals {
+		spent += t.Total
+	}
+	percent := spent / user.Budget * 100
+	key := period.Key(start)
+
+	var crossed []float64
+	for _, threshold := range s.Thresholds {
+		if percent < threshold {
+			break
+		}
+
+		alert := models.BudgetAlert{UserID: uid, Month: key, Threshold: threshold, Spent: spent, Budget: user.Budget, Pending: s.Email != nil}
+		result := s.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&alert)
+		if result.Error != nil {
+			return crossed, result.Error
+		}
+		if result.RowsAffected == 1 {
+			crossed = append(crossed, threshold)
+		}
+	}
+
+	if s.Email != nil {
+		if err := s.sendPending(ctx, user, period, key, percent, spent, display); err != nil {
+			return crossed, err
+		}
+	}
+
+	return crossed, nil
+}
+
+// sendPending emails the highest crossed threshold among the period's
+// alerts still waiting for their email. The alerts are claimed first so
+// concurrent evaluations send once, and released again if sending fails.
+func (s *BudgetAlertService) sendPending(ctx context.Context, user models.User, period BudgetPeriod, key string, percent, spent float64, display string) error {
+	var pending []models.BudgetAlert
+	err := s.DB.WithContext(ctx).
+		Where("user_id = ? AND month = ? AND pending AND threshold <= ?", user.ID, key, percent).
+		Order("threshold").
+		Find(&pending).Error
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+
+	ids := make([]uint, len(pending))
+	for i, alert := range pending {
+		ids[i] = alert.ID
+	}
+	claim := s.DB.WithContext(ctx).Model(&models.BudgetAlert{}).Where("id IN ? AND pending", ids).Update("pending", false)
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return claim.Error
+	}
+
+	highest := pending[len(pending)-1].Threshold
+	if err := s.Email.SendBudgetAlert(user.Email, user.Name, period, highest, spent, user.Budget, display); err != nil {
+		if release := s.DB.Model(&models.BudgetAlert{}).Where("id IN ?", ids).Update("pending", true).Error; release != nil {
+			log.Printf("Failed to release budget alerts of user %d for retry: %v", user.ID, release)
+		}
+		return fmt.Errorf("failed to send budget alert: %w", err)
+	}
+	return nil
+}
+
+// EvaluateAsync runs Evaluate in the background, logging any failure
+func (s *BudgetAlertService) EvaluateAsync(uid uint) {
+	go func() {
+		if _, err := s.Evaluate(uid, time.Now()); err != nil {
+			log.Printf("Budget alert evaluation failed for user %d: %v", uid, err)
+		}
+	}()
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"database/sql/driver"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+)
+
+// sentAlert is one budget alert email
+type sentAlert struct {
+	email     string
+	threshold float64
+	spent     float64
+}
+
+// fakeAlertSender records alerts and fails while err is set
+type fakeAlertSender struct {
+	mu   sync.Mutex
+	sent []sentAlert
+	err  error
+}
+
+func (f *fakeAlertSender) SendBudgetAlert(email, name string, period BudgetPeriod, threshold, spent, budget float64, currency string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, sentAlert{email: email, threshold: threshold, spent: spent})
+	return nil
+}
+
+// alertRow is a budget_alerts row of alertTable
+type alertRow struct {
+	id        int64
+	threshold float64
+	pending   bool
+}
+
+// alertTable keeps budget_alerts rows for one user and period in memory
+type alertTable struct {
+	mu   sync.Mutex
+	rows []*alertRow
+}
+
+func (a *alertTable) install(stub *testutil.StubDB) {
+	stub.Handle(`INSERT INTO "budget_alerts"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		values := testutil.InsertedValues(query, args)
+		threshold := values["threshold"].(float64)
+		for _, row := range a.rows {
+			if row.threshold == threshold {
+				return testutil.StubResult{Columns: []string{"id"}}, nil // ON CONFLICT DO NOTHING
+			}
+		}
+		row := &alertRow{id: int64(len(a.rows) + 1), threshold: threshold, pending: values["pending"].(bool)}
+		a.rows = append(a.rows, row)
+		return testutil.StubResult{Columns: []string{"id"}, Rows: [][]driver.Value{{row.id}}}, nil
+	})
+	stub.Handle(`FROM "budget_alerts"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		percent := args[2].(float64)
+		result := testutil.StubResult{Columns: []string{"id", "user_id", "month", "threshold", "pending"}}
+		for _, row := range a.rows {
+			if row.pending && row.threshold <= percent {
+				result.Rows = append(result.Rows, []driver.Value{row.id, args[0], args[1], row.threshold, row.pending})
+			}
+		}
+		sort.Slice(result.Rows, func(i, j int) bool { return result.Rows[i][3].(float64) < result.Rows[j][3].(float64) })
+		return result, nil
+	})
+	stub.Handle(`UPDATE "budget_alerts"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		pending := args[0].(bool)
+		claim := !pending // claiming only takes rows that are still pending
+		var affected int64
+		for _, arg := range args[2:] {
+			for _, row := range a.rows {
+				if int64(arg.(uint)) == row.id && (!claim || row.pending) {
+					row.pending = pending
+					affected++
+				}
+			}
+		}
+		return testutil.StubResult{Affected: affected}, nil
+	})
+}
+
+func (a *alertTable) pendingCount() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n := 0
+	for _, row := range a.rows {
+		if row.pending {
+			n++
+		}
+	}
+	return n
+}
+
+// newBudgetAlertFixture returns an alert service with thresholds of 80%
+// and 100% for a user with a monthly budget of 1000 whose month-to-date
+// spend is whatever *spent holds
+func newBudgetAlertFixture(t *testing.T) (*BudgetAlertService, *fakeAlertSender, *alertTable, *float64) {
+	t.Helper()
+	db, stub := testutil.NewStubDB(t)
+	spent := new(float64)
+
+	stub.On(`FROM "users"`, []string{"id", "name", "email", "budget", "currency", "timezone", "budget_period"},
+		[]driver.Value{int64(1), "Asha", "asha@example.com", 1000.0, "INR", "UTC", "monthly"})
+	stub.On(`SELECT "currency" FROM "users"`, []string{"currency"}, []driver.Value{"INR"})
+	stub.Handle("FROM expenses", func(_ string, args []driver.Value) (testutil.StubResult, error) {
+		return testutil.StubResult{
+			Columns: []string{"type", "category", "currency", "total"},
+			Rows:    [][]driver.Value{{"expense", "Food", "INR", *spent}},
+		}, nil
+	})
+	alerts := &alertTable{}
+	alerts.install(stub)
+
+	summary := NewSummaryService(db, nil, 1)
+	t.Cleanup(summary.Close)
+	sender := &fakeAlertSender{}
+	return NewBudgetAlertService(db, summary, sender, []float64{100, 80}), sender, alerts, spent
+}
+
+var alertNow = time.Date(2025, time.March, 15, 12, 0, 0, 0, time.UTC)
+
+func TestBudgetAlertsFireOncePerThreshold(t *testing.T) {
+	service, sender, _, spent := newBudgetAlertFixture(t)
+
+	steps := []struct {
+		spent   float64
+		crossed []float64
+		emailed []float64
+	}{
+		{500, nil, nil},
+		{850, []float64{80}, []float64{80}},
+		{900, nil, []float64{80}},
+		{1200, []float64{100}, []float64{80, 100}},
+		{1300, nil, []float64{80, 100}},
+	}
+	for _, step := range steps {
+		*spent = step.spent
+		crossed, err := service.Evaluate(1, alertNow)
+		if err != nil {
+			t.Fatalf("spent %.0f: %v", step.spent, err)
+		}
+		if !equalFloats(crossed, step.crossed) {
+			t.Errorf("spent %.0f: crossed %v, want %v", step.spent, crossed, step.crossed)
+		}
+		if got := emailedThresholds(sender); !equalFloats(got, step.emailed) {
+			t.Errorf("spent %.0f: emailed %v, want %v", step.spent, got, step.emailed)
+		}
+	}
+}
+
+func TestBudgetAlertCrossingEveryThresholdSendsOneEmail(t *testing.T) {
+	service, sender, alerts, spent := newBudgetAlertFixture(t)
+	*spent = 1500
+
+	crossed, err := service.Evaluate(1, alertNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalFloats(crossed, []float64{80, 100}) {
+		t.Errorf("crossed %v, want [80 100]", crossed)
+	}
+	if got := emailedThresholds(sender); !equalFloats(got, []float64{100}) {
+		t.Errorf("emailed %v, want only the highest threshold", got)
+	}
+	if alerts.pendingCount() != 0 {
+		t.Error("alerts are still pending after their email was sent")
+	}
+}
+
+func TestBudgetAlertRetriedAfterFailedEmail(t *testing.T) {
+	service, sender, alerts, spent := newBudgetAlertFixture(t)
+	*spent = 850
+	sender.err = errors.New("smtp unavailable")
+
+	crossed, err := service.Evaluate(1, alertNow)
+	if err == nil || !strings.Contains(err.Error(), "smtp unavailable") {
+		t.Fatalf("err = %v, want the send failure", err)
+	}
+	if !equalFloats(crossed, []float64{80}) {
+		t.Errorf("crossed %v, want [80]", crossed)
+	}
+	if alerts.pendingCount() != 1 {
+		t.Fatalf("%d pending alerts, want the failed one kept for retry", alerts.pendingCount())
+	}
+
+	// The next evaluation retries the email although nothing new was crossed
+	sender.err = nil
+	crossed, err = service.Evaluate(1, alertNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(crossed) != 0 {
+		t.Errorf("crossed %v again", crossed)
+	}
+	if got := emailedThresholds(sender); !equalFloats(got, []float64{80}) {
+		t.Errorf("emailed %v, want [80]", got)
+	}
+
+	if _, err := service.Evaluate(1, alertNow); err != nil {
+		t.Fatal(err)
+	}
+	if got := emailedThresholds(sender); len(got) != 1 {
+		t.Errorf("emailed %v, want the retried alert only once", got)
+	}
+}
+
+func TestBudgetAlertMonthIsTheUsersMonth(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`FROM "users"`, []string{"id", "name", "email", "budget", "currency", "timezone", "budget_period"},
+		[]driver.Value{int64(1), "Asha", "asha@example.com", 1000.0, "INR", "Asia/Kolkata", "monthly"})
+	stub.On(`SELECT "currency" FROM "users"`, []string{"currency"}, []driver.Value{"INR"})
+	stub.On("FROM expenses", totalsColumns, []driver.Value{"expense", "Food", "INR", 900.0})
+	(&alertTable{}).install(stub)
+	summary := NewSummaryService(db, nil, 1)
+	t.Cleanup(summary.Close)
+	service := NewBudgetAlertService(db, summary, &fakeAlertSender{}, []float64{80})
+
+	// 20:00 UTC on March 31 is already 01:30 on April 1 in Kolkata
+	if _, err := service.Evaluate(1, time.Date(2025, time.March, 31, 20, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	totals := stub.Ran("FROM expenses")
+	if len(totals) != 1 || totals[0].Args[1] != "2025-04-01" || totals[0].Args[2] != "2025-05-01" {
+		t.Fatalf("spend summed over %+v, want April", totals)
+	}
+	alerts := stub.Ran(`INSERT INTO "budget_alerts"`)
+	if len(alerts) != 1 {
+		t.Fatalf("recorded %d alerts, want 1", len(alerts))
+	}
+	if month := testutil.InsertedValues(alerts[0].SQL, alerts[0].Args)["month"]; month != "2025-04" {
+		t.Errorf("alert recorded for %v, want 2025-04", month)
+	}
+}
+
+func emailedThresholds(sender *fakeAlertSender) []float64 {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	var out []float64
+	for _, alert := range sender.sent {
+		out = append(out, alert.threshold)
+	}
+	return out
+}
+
+func equalFloats(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBudgetAlertsFireOncePerWeek(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`FROM "users"`, []string{"id", "name", "email", "budget", "currency", "timezone", "budget_period"},
+		[]driver.Value{int64(1), "Asha", "asha@example.com", 1000.0, "INR", "UTC", "weekly"})
+	stub.On(`SELECT "currency" FROM "users"`, []string{"currency"}, []driver.Value{"INR"})
+	stub.On("FROM expenses", totalsColumns, []driver.Value{"expense", "Food", "INR", 900.0})
+	(&alertTable{}).install(stub)
+	summary := NewSummaryService(db, nil, 1)
+	t.Cleanup(summary.Close)
+	sender := &fakeAlertSender{}
+	service := NewBudgetAlertService(db, summary, sender, []float64{80})
+
+	// alertNow is a Saturday; the week began on Monday March 10
+	if _, err := service.Evaluate(1, alertNow); err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	totals := stub.Ran("FROM expenses")
+	if len(totals) != 1 || totals[0].Args[1] != "2025-03-10" || totals[0].Args[2] != "2025-03-17" {
+		t.Fatalf("spend summed over %+v, want the week of March 10", totals)
+	}
+	alerts := stub.Ran(`INSERT INTO "budget_alerts"`)
+	if len(alerts) != 1 {
+		t.Fatalf("recorded %d alerts, want 1", len(alerts))
+	}
+	if week := testutil.InsertedValues(alerts[0].SQL, alerts[0].Args)["month"]; week != "2025-03-10" {
+		t.Errorf("alert recorded for %v, want 2025-03-10", week)
+	}
+	if got := emailedThresholds(sender); !equalFloats(got, []float64{80}) {
+		t.Errorf("emailed %v, want 80", got)
+	}
+
+	// The following Monday starts a new week
+	if _, err := service.Evaluate(1, alertNow.AddDate(0, 0, 2)); err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	alerts = stub.Ran(`INSERT INTO "budget_alerts"`)
+	if week := testutil.InsertedValues(alerts[len(alerts)-1].SQL, alerts[len(alerts)-1].Args)["month"]; week != "2025-03-17" {
+		t.Errorf("alert recorded for %v, want 2025-03-17", week)
+	}
+}
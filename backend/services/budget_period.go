@@ -0,0 +1,125 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/your-github/expense-tracker-backend/models"
+)
+
+// Budget period types
+const (
+	BudgetPeriodMonthly = "monthly"
+	BudgetPeriodWeekly  = "weekly"
+	BudgetPeriodCustom  = "custom"
+)
+
+// MaxBudgetPeriodDays bounds the length of a custom budget period
+const MaxBudgetPeriodDays = 366
+
+// ErrInvalidBudgetPeriod is returned for a budget period that can't be used
+var ErrInvalidBudgetPeriod = errors.New("invalid budget period")
+
+// weekAnchor is a Monday, so weekly periods without a start date run
+// Monday to Sunday like the weekly trends
+var weekAnchor = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// BudgetPeriod is the span a budget applies to, repeating back to back.
+// Start and Days are only used by weekly and custom periods.
+type BudgetPeriod struct {
+	Type  string
+	Start time.Time // a day the periods start on, in UTC
+	Days  int
+}
+
+// NewBudgetPeriod validates a period type with its start date (YYYY-MM-DD)
+// and length. Weekly periods take an optional start date; custom periods
+// need both a start date and 1-MaxBudgetPeriodDays days.
+func NewBudgetPeriod(periodType, start string, days int) (BudgetPeriod, error) {
+	switch periodType {
+	case "", BudgetPeriodMonthly:
+		return BudgetPeriod{Type: BudgetPeriodMonthly}, nil
+	case BudgetPeriodWeekly:
+		period := BudgetPeriod{Type: BudgetPeriodWeekly, Start: weekAnchor, Days: 7}
+		if start != "" {
+			anchor, err := time.Parse("2006-01-02", start)
+			if err != nil {
+				return BudgetPeriod{}, fmt.Errorf("%w: start must be YYYY-MM-DD", ErrInvalidBudgetPeriod)
+			}
+			period.Start = anchor
+		}
+		return period, nil
+	case BudgetPeriodCustom:
+		anchor, err := time.Parse("2006-01-02", start)
+		if err != nil {
+			return BudgetPeriod{}, fmt.Errorf("%w: a custom period needs a start date as YYYY-MM-DD", ErrInvalidBudgetPeriod)
+		}
+		if days < 1 || days > MaxBudgetPeriodDays {
+			return BudgetPeriod{}, fmt.Errorf("%w: a custom period must be 1-%d days", ErrInvalidBudgetPeriod, MaxBudgetPeriodDays)
+		}
+		return BudgetPeriod{Type: BudgetPeriodCustom, Start: anchor, Days: days}, nil
+	default:
+		return BudgetPeriod{}, fmt.Errorf("%w: type must be monthly, weekly or custom", ErrInvalidBudgetPeriod)
+	}
+}
+
+// UserBudgetPeriod returns the user's budget period, monthly when it is
+// unset or no longer valid
+func UserBudgetPeriod(user models.User) BudgetPeriod {
+	period, err := NewBudgetPeriod(user.BudgetPeriod, user.BudgetPeriodStart, user.BudgetPeriodDays)
+	if err != nil {
+		return BudgetPeriod{Type: BudgetPeriodMonthly}
+	}
+	return period
+}
+
+// Bounds returns the first day of the period containing day and the first
+// day of the next one. Only day's calendar date is used, so the result is
+// in UTC like expense dates.
+func (p BudgetPeriod) Bounds(day time.Time) (time.Time, time.Time) {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	if p.Type == BudgetPeriodMonthly || p.Days < 1 {
+		start := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	}
+
+	// Periods also repeat backwards from the start date, so days before it
+	// still fall in a period
+	offset := int(day.Sub(p.Start).Hours() / 24)
+	n := offset / p.Days
+	if offset%p.Days < 0 {
+		n--
+	}
+	start := p.Start.AddDate(0, 0, n*p.Days)
+	return start, start.AddDate(0, 0, p.Days)
+}
+
+// Key identifies the period starting at start, as recorded on budget
+// alerts: YYYY-MM for months, the start date otherwise
+func (p BudgetPeriod) Key(start time.Time) string {
+	if p.Type == BudgetPeriodMonthly {
+		return start.Format("2006-01")
+	}
+	return start.Format("2006-01-02")
+}
+
+// Label describes the period as an adjective, e.g. "monthly" or "10-day"
+func (p BudgetPeriod) Label() string {
+	if p.Type == BudgetPeriodCustom {
+		return fmt.Sprintf("%d-day", p.Days)
+	}
+	return p.Type
+}
+
+// Unit names one period, e.g. "month"
+func (p BudgetPeriod) Unit() string {
+	switch p.Type {
+	case BudgetPeriodWeekly:
+		return "week"
+	case BudgetPeriodCustom:
+		return "period"
+	default:
+		return "month"
+	}
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/your-github/expense-tracker-backend/models"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestNewBudgetPeriod(t *testing.T) {
+	tests := []struct {
+		name       string
+		periodType string
+		start      string
+		days       int
+		want       BudgetPeriod
+		wantErr    bool
+	}{
+		{"unset is monthly", "", "", 0, BudgetPeriod{Type: BudgetPeriodMonthly}, false},
+		{"monthly drops the start", "monthly", "2025-03-05", 10, BudgetPeriod{Type: BudgetPeriodMonthly}, false},
+		{"weekly from monday", "weekly", "", 0, BudgetPeriod{Type: BudgetPeriodWeekly, Start: weekAnchor, Days: 7}, false},
+		{"weekly from a start date", "weekly", "2025-03-06", 3, BudgetPeriod{Type: BudgetPeriodWeekly, Start: day("2025-03-06"), Days: 7}, false},
+		{"custom", "custom", "2025-03-05", 10, BudgetPeriod{Type: BudgetPeriodCustom, Start: day("2025-03-05"), Days: 10}, false},
+		{"custom without a start", "custom", "", 10, BudgetPeriod{}, true},
+		{"custom without days", "custom", "2025-03-05", 0, BudgetPeriod{}, true},
+		{"custom too long", "custom", "2025-03-05", MaxBudgetPeriodDays + 1, BudgetPeriod{}, true},
+		{"bad start date", "weekly", "05/03/2025", 0, BudgetPeriod{}, true},
+		{"unknown type", "daily", "", 0, BudgetPeriod{}, true},
+	}
+	for _, tt := range tests {
+		got, err := NewBudgetPeriod(tt.periodType, tt.start, tt.days)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidBudgetPeriod) {
+				t.Errorf("%s: err = %v, want ErrInvalidBudgetPeriod", tt.name, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s = %+v, %v; want %+v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestUserBudgetPeriodFallsBackToMonthly(t *testing.T) {
+	user := models.User{BudgetPeriod: BudgetPeriodCustom, BudgetPeriodDays: 10}
+	if period := UserBudgetPeriod(user); period.Type != BudgetPeriodMonthly {
+		t.Errorf("period = %+v, want monthly for a custom period without a start", period)
+	}
+}
+
+func TestBudgetPeriodBounds(t *testing.T) {
+	weekly, _ := NewBudgetPeriod(BudgetPeriodWeekly, "", 0)
+	custom, _ := NewBudgetPeriod(BudgetPeriodCustom, "2025-03-05", 10)
+	monthly, _ := NewBudgetPeriod(BudgetPeriodMonthly, "", 0)
+
+	tests := []struct {
+		name               string
+		period             BudgetPeriod
+		day                time.Time
+		wantStart, wantEnd string
+		wantKey            string
+	}{
+		{"monthly", monthly, time.Date(2025, time.February, 28, 23, 0, 0, 0, time.UTC), "2025-02-01", "2025-03-01", "2025-02"},
+		{"weekly sunday", weekly, day("2025-03-16"), "2025-03-10", "2025-03-17", "2025-03-10"},
+		{"weekly rolls over on monday", weekly, day("2025-03-17"), "2025-03-17", "2025-03-24", "2025-03-17"},
+		{"weekly across the year", weekly, day("2025-01-01"), "2024-12-30", "2025-01-06", "2024-12-30"},
+		{"custom first day", custom, day("2025-03-05"), "2025-03-05", "2025-03-15", "2025-03-05"},
+		{"custom last day", custom, day("2025-03-14"), "2025-03-05", "2025-03-15", "2025-03-05"},
+		{"custom rolls over after 10 days", custom, day("2025-03-15"), "2025-03-15", "2025-03-25", "2025-03-15"},
+		{"custom before the start", custom, day("2025-03-04"), "2025-02-23", "2025-03-05", "2025-02-23"},
+		{"custom a period before the start", custom, day("2025-02-23"), "2025-02-23", "2025-03-05", "2025-02-23"},
+		// Only the calendar date counts, whatever the zone
+		{"late in the evening", custom, time.Date(2025, time.March, 14, 23, 30, 0, 0, time.FixedZone("IST", 19800)), "2025-03-05", "2025-03-15", "2025-03-05"},
+	}
+	for _, tt := range tests {
+		start, end := tt.period.Bounds(tt.day)
+		if got := start.Format("2006-01-02"); got != tt.wantStart || end.Format("2006-01-02") != tt.wantEnd {
+			t.Errorf("%s: bounds = %s to %s, want %s to %s", tt.name, got, end.Format("2006-01-02"), tt.wantStart, tt.wantEnd)
+		}
+		if key := tt.period.Key(start); key != tt.wantKey {
+			t.Errorf("%s: key = %s, want %s", tt.name, key, tt.wantKey)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/your-github/expense-tracker-backend/models"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+// maxRuleMatcherLength matches the size of the category_rules.matcher column
+const maxRuleMatcherLength = 200
+
+var (
+	ErrInvalidCategoryRule  = errors.New("rule needs a matcher of at most 200 characters and a category")
+	ErrInvalidRuleRegex     = errors.New("rule matcher is not a valid regular expression")
+	ErrCategoryRuleNotFound = errors.New("category rule not found")
+)
+
+// CategoryRuleService manages the user's category rules for manual expenses
+type CategoryRuleService struct {
+	DB *gorm.DB
+}
+
+// NewCategoryRuleService creates a new category rule service
+func NewCategoryRuleService(db *gorm.DB) *CategoryRuleService {
+	return &CategoryRuleService{DB: db}
+}
+
+// List returns the user's rules in the order they are applied
+func (s *CategoryRuleService) List(uid uint) ([]models.CategoryRule, error) {
+	return loadCategoryRules(s.DB, uid)
+}
+
+// Create adds a rule for the user. The category is mapped onto the
+// canonical taxonomy and "/regex/" matchers must compile.
+func (s *CategoryRuleService) Create(uid uint, matcher, category, subcategory string) (models.CategoryRule, error) {
+	rule, err := newCategoryRule(uid, matcher, category, subcategory)
+	if err != nil {
+		return models.CategoryRule{}, err
+	}
+	if err := s.DB.Create(&rule).Error; err != nil {
+		return models.CategoryRule{}, err
+	}
+	return rule, nil
+}
+
+// newCategoryRule validates and normalizes an unsaved rule
+func newCategoryRule(uid uint, matcher, category, subcategory string) (models.CategoryRule, error) {
+	matcher = strings.TrimSpace(matcher)
+	category = strings.TrimSpace(category)
+	if matcher == "" || len(matcher) > maxRuleMatcherLength || category == "" {
+		return models.CategoryRule{}, ErrInvalidCategoryRule
+	}
+	if _, isRegex, err := utils.RuleRegex(matcher); isRegex && err != nil {
+		return models.CategoryRule{}, ErrInvalidRuleRegex
+	}
+
+	return models.CategoryRule{
+		UserID:      uid,
+		Matcher:     matcher,
+		Category:    utils.NormalizeCategory(category),
+		Subcategory: strings.TrimSpace(subcategory),
+	}, nil
+}
+
+// Delete removes one of the user's rules
+func (s *CategoryRuleService) Delete(uid, id uint) error {
+	result := s.DB.Where("id = ? AND user_id = ?", id, uid).Delete(&models.CategoryRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrCategoryRuleNotFound
+	}
+	return nil
+}
+
+// loadCategoryRules returns the user's rules oldest first
+func loadCategoryRules(db *gorm.DB, uid uint) ([]models.CategoryRule, error) {
+	var rules []models.CategoryRule
+	err := db.Where("user_id = ?", uid).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+// categorizerFor builds the categorizer applying the user's rules
+func categorizerFor(db *gorm.DB, uid uint) (utils.Categorizer, error) {
+	rules, err := loadCategoryRules(db, uid)
+	if err != nil {
+		return utils.Categorizer{}, err
+	}
+	categorizer := utils.Categorizer{Rules: make([]utils.CategoryRule, len(rules))}
+	for i, rule := range rules {
+		categorizer.Rules[i] = utils.CategoryRule{
+			Matcher:     rule.Matcher,
+			Category:    rule.Category,
+			Subcategory: rule.Subcategory,
+		}
+	}
+	return categorizer, nil
+}
+
+// categorizeExpense resolves e's category with the shared precedence:
+// explicit category, user rule, then for expenses the keyword guess.
+// Income that no rule matches keeps an empty category.
+func categorizeExpense(categorizer utils.Categorizer, e *models.Expense) {
+	var keyword utils.KeywordFunc
+	if e.Type == "expense" {
+		keyword = utils.KeywordCategory
+	}
+
+	decision := categorizer.Categorize(e.Category, e.Title, keyword)
+	if decision.Source == utils.CategorySourceFallback && e.Type != "expense" {
+		return
+	}
+	e.Category = decision.Category
+	if e.Subcategory == "" {
+		e.Subcategory = decision.Subcategory
+	}
+}
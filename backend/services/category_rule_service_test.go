@@ -0,0 +1,103 @@
+package services
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+	"github.com/your-github/expense-tracker-backend/models"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+var categoryRuleColumns = []string{"id", "user_id", "matcher", "category", "subcategory"}
+
+func TestCategoryRuleCreateValidates(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`INSERT INTO "category_rules"`, []string{"id"}, []driver.Value{int64(1)})
+	service := NewCategoryRuleService(db)
+
+	rule, err := service.Create(7, "  uber ", "travel", " Cabs ")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if rule.Matcher != "uber" || rule.Category != utils.CategoryTravel || rule.Subcategory != "Cabs" || rule.UserID != 7 {
+		t.Errorf("rule = %+v, want a trimmed matcher and the canonical category", rule)
+	}
+
+	tests := []struct {
+		name              string
+		matcher, category string
+		want              error
+	}{
+		{"blank matcher", "  ", "Travel", ErrInvalidCategoryRule},
+		{"long matcher", strings.Repeat("x", maxRuleMatcherLength+1), "Travel", ErrInvalidCategoryRule},
+		{"no category", "uber", "", ErrInvalidCategoryRule},
+		{"bad regex", "/(uber/", "Travel", ErrInvalidRuleRegex},
+	}
+	for _, tt := range tests {
+		if _, err := service.Create(7, tt.matcher, tt.category, ""); !errors.Is(err, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if n := len(stub.Ran(`INSERT INTO "category_rules"`)); n != 1 {
+		t.Errorf("stored %d rules, want only the valid one", n)
+	}
+}
+
+func TestCategoryRuleDeleteIsScopedToTheUser(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.Handle(`"category_rules"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		// Only rule 4 of user 7 exists
+		if toInt64(args[1]) == 4 && toInt64(args[2]) == 7 {
+			return testutil.StubResult{Affected: 1}, nil
+		}
+		return testutil.StubResult{Affected: 0}, nil
+	})
+	service := NewCategoryRuleService(db)
+
+	if err := service.Delete(8, 4); !errors.Is(err, ErrCategoryRuleNotFound) {
+		t.Errorf("deleting another user's rule: %v, want ErrCategoryRuleNotFound", err)
+	}
+	if err := service.Delete(7, 4); err != nil {
+		t.Errorf("Delete: %v", err)
+	}
+}
+
+func TestExpenseCategoryPrecedence(t *testing.T) {
+	service, _, stub := newExpenseFixture(t)
+	stub.On(`FROM "category_rules"`, categoryRuleColumns,
+		[]driver.Value{int64(1), int64(7), "uber", utils.CategoryEntertainment, "Nights out"},
+		[]driver.Value{int64(2), int64(7), "/^Salary/", utils.CategoryIncome, "Payroll"})
+
+	tests := []struct {
+		name            string
+		expense         models.Expense
+		wantCategory    string
+		wantSubcategory string
+	}{
+		{"explicit category", models.Expense{Title: "Uber home", Type: "expense", Category: "travel"}, utils.CategoryTravel, ""},
+		{"user rule over keyword", models.Expense{Title: "Uber home", Type: "expense"}, utils.CategoryEntertainment, "Nights out"},
+		{"keyword", models.Expense{Title: "Starbucks latte", Type: "expense"}, utils.CategoryFood, "Coffee"},
+		{"fallback", models.Expense{Title: "zzz", Type: "expense"}, utils.CategoryOther, ""},
+		{"user rule on income", models.Expense{Title: "Salary March", Type: "income"}, utils.CategoryIncome, "Payroll"},
+		{"income without a rule stays uncategorized", models.Expense{Title: "Starbucks refund", Type: "income"}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expense := tt.expense
+			expense.Amount, expense.Date, expense.Currency = 100, "2025-03-01", "INR"
+			if err := service.Create(&expense, 7); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if expense.Category != tt.wantCategory || expense.Subcategory != tt.wantSubcategory {
+				t.Errorf("categorized as %q / %q, want %q / %q", expense.Category, expense.Subcategory, tt.wantCategory, tt.wantSubcategory)
+			}
+		})
+	}
+
+	if rules := stub.Ran(`FROM "category_rules"`); len(rules) == 0 || !strings.Contains(rules[0].SQL, "ORDER BY id") {
+		t.Errorf("rules read as %+v, want oldest first", rules)
+	}
+}
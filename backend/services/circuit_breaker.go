@@ -0,0 +1,91 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// circuitBreaker opens after threshold failures within window and rejects
+// calls until cooldown has elapsed, after which a single trial call is let
+// through to decide whether to close again.
+type circuitBreaker struct {
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  []time.Time
+	openUntil time.Time
+}
+
+func newCircuitBreaker(threshold int, window, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		window:    window,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// Allow reports whether a call may proceed
+func (b *circuitBreaker) Allow() bool {
+	if b == nil || b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if now.Before(b.openUntil) {
+		return false
+	}
+	if !b.openUntil.IsZero() {
+		// Half-open: let this call through, but re-open straight away if it fails
+		b.openUntil = time.Time{}
+		b.failures = b.failures[:0]
+		for i := 1; i < b.threshold; i++ {
+			b.failures = append(b.failures, now)
+		}
+	}
+	return true
+}
+
+// Success resets the failure count
+func (b *circuitBreaker) Success() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = b.failures[:0]
+	b.openUntil = time.Time{}
+}
+
+// Failure records a failed call and opens the breaker once the threshold is reached
+func (b *circuitBreaker) Failure() {
+	if b == nil || b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	cutoff := now.Add(-b.window)
+	recent := b.failures[:0]
+	for _, at := range b.failures {
+		if at.After(cutoff) {
+			recent = append(recent, at)
+		}
+	}
+	b.failures = append(recent, now)
+
+	if len(b.failures) >= b.threshold {
+		b.openUntil = now.Add(b.cooldown)
+		b.failures = b.failures[:0]
+	}
+}
@@ -2,10 +2,14 @@ package services
 
 import (
 	"fmt"
-	"math/rand"
+	"html"
+	"strings"
 	"time"
 
 	"gopkg.in/mail.v2"
+
+	"github.com/your-github/expense-tracker-backend/models"
+	"github.com/your-github/expense-tracker-backend/utils"
 )
 
 type EmailService struct {
@@ -13,6 +17,10 @@ type EmailService struct {
 	SMTPPort int
 	SMTPUser string
 	SMTPPass string
+
+	// deliver, when set, is called with each message instead of sending it
+	// over SMTP
+	deliver func(m *mail.Message) error
 }
 
 func NewEmailService(smtpHost string, smtpPort int, smtpUser, smtpPass string) *EmailService {
@@ -24,35 +32,58 @@ func NewEmailService(smtpHost string, smtpPort int, smtpUser, smtpPass string) *
 	}
 }
 
-// GenerateOTP generates a 6-digit OTP
-func (s *EmailService) GenerateOTP() string {
-	rand.Seed(time.Now().UnixNano())
-	otp := rand.Intn(900000) + 100000 // Generates number between 100000 and 999999
-	return fmt.Sprintf("%06d", otp)
+// otpLength is the number of digits in an email OTP
+const otpLength = 6
+
+// GenerateOTP generates a 6-digit OTP from crypto/rand
+func (s *EmailService) GenerateOTP() (string, error) {
+	return utils.SecureRandomDigits(otpLength)
 }
 
-// SendOTP sends OTP to the specified email
-func (s *EmailService) SendOTP(email, otp string) error {
-	// Create new message
-	m := mail.NewMessage()
-	m.SetHeader("From", s.SMTPUser)
-	m.SetHeader("To", email)
-	m.SetHeader("Subject", "BucksInfo - Email Verification Code")
+// otpEmail is the wording of the OTP email for one purpose
+type otpEmail struct {
+	subject string
+	heading string // shown under the BucksInfo banner
+	intro   string // leads into the code
+	ignore  string // tells someone who didn't ask what to do
+}
+
+// otpEmails words the OTP email for each models.OTPPurpose*
+var otpEmails = map[string]otpEmail{
+	models.OTPPurposeSignup: {
+		subject: "BucksInfo - Email Verification Code",
+		heading: "Email Verification",
+		intro:   "Thank you for signing up with BucksInfo! Please use the following verification code to complete your registration:",
+		ignore:  "If you didn't request this code, please ignore this email.",
+	},
+	models.OTPPurposeEmailChange: {
+		subject: "BucksInfo - Confirm your new email address",
+		heading: "Email Change",
+		intro:   "You asked to change the email address of your BucksInfo account to this one. Please use the following verification code to confirm the change:",
+		ignore:  "If you didn't ask for this change, please ignore this email; the account keeps its current address.",
+	},
+}
+
+// renderOTPEmail returns the subject and HTML body of the OTP email for purpose
+func renderOTPEmail(otp, purpose string) (string, string, error) {
+	wording, ok := otpEmails[purpose]
+	if !ok {
+		return "", "", fmt.Errorf("no OTP email for purpose %q", purpose)
+	}
 
-	// Email body
 	body := fmt.Sprintf(`
 		<html>
 		<body>
 			<div style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto; padding: 20px;">
 				<div style="background: linear-gradient(135deg, #FFD700, #FFA500); padding: 20px; border-radius: 10px; text-align: center;">
 					<h1 style="color: #333; margin: 0; font-size: 28px;">BucksInfo</h1>
-					<p style="color: #333; margin: 10px 0 0 0; font-size: 16px;">Email Verification</p>
+					<p style="color: #333; margin: 10px 0 0 0; font-size: 16px;">%s</p>
 				</div>
 				
 				<div style="background: #f9f9f9; padding: 30px; border-radius: 10px; margin-top: 20px;">
 					<h2 style="color: #333; margin: 0 0 20px 0;">Your Verification Code</h2>
 					<p style="color: #666; margin: 0 0 20px 0; font-size: 16px;">
-						Thank you for signing up with BucksInfo! Please use the following verification code to complete your registration:
+						%s
 					</p>
 					
 					<div style="background: #333; color: #FFD700; padding: 20px; border-radius: 10px; text-align: center; margin: 20px 0;">
@@ -60,7 +91,7 @@ func (s *EmailService) SendOTP(email, otp string) error {
 					</div>
 					
 					<p style="color: #666; margin: 20px 0 0 0; font-size: 14px;">
-						This code will expire in 10 minutes. If you didn't request this code, please ignore this email.
+						This code will expire in 10 minutes. %s
 					</p>
 					
 					<div style="margin-top: 30px; padding-top: 20px; border-top: 1px solid #ddd;">
@@ -72,10 +103,113 @@ func (s *EmailService) SendOTP(email, otp string) error {
 			</div>
 		</body>
 		</html>
-	`, otp)
+	`, wording.heading, wording.intro, html.EscapeString(otp), wording.ignore)
+	return wording.subject, body, nil
+}
+
+// SendOTP emails otp to the specified email, worded for purpose, one of
+// the models.OTPPurpose* values
+func (s *EmailService) SendOTP(email, otp, purpose string) error {
+	subject, body, err := renderOTPEmail(otp, purpose)
+	if err != nil {
+		return err
+	}
+
+	m := mail.NewMessage()
+	m.SetHeader("From", s.SMTPUser)
+	m.SetHeader("To", email)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/html", body)
+
+	return s.send(m)
+}
+
+// SendSpendingReport emails a rendered spending report
+func (s *EmailService) SendSpendingReport(email string, report SpendingReport) error {
+	body, err := RenderSpendingReport(report)
+	if err != nil {
+		return err
+	}
+
+	m := mail.NewMessage()
+	m.SetHeader("From", s.SMTPUser)
+	m.SetHeader("To", email)
+	m.SetHeader("Subject", fmt.Sprintf("BucksInfo - Your %s spending report", report.Period))
+	m.SetBody("text/html", body)
+
+	return s.send(m)
+}
+
+// SendBudgetAlert notifies a user that their spending crossed a budget threshold
+func (s *EmailService) SendBudgetAlert(email, name string, period BudgetPeriod, threshold, spent, budget float64, currency string) error {
+	m := mail.NewMessage()
+	m.SetHeader("From", s.SMTPUser)
+	m.SetHeader("To", email)
+	m.SetHeader("Subject", fmt.Sprintf("BucksInfo - You've used %.0f%% of your %s budget", threshold, period.Label()))
+
+	body := fmt.Sprintf(`
+		<html>
+		<body>
+			<div style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto; padding: 20px;">
+				<div style="background: linear-gradient(135deg, #FFD700, #FFA500); padding: 20px; border-radius: 10px; text-align: center;">
+					<h1 style="color: #333; margin: 0; font-size: 28px;">BucksInfo</h1>
+					<p style="color: #333; margin: 10px 0 0 0; font-size: 16px;">Budget Alert</p>
+				</div>
+
+				<div style="background: #f9f9f9; padding: 30px; border-radius: 10px; margin-top: 20px;">
+					<h2 style="color: #333; margin: 0 0 20px 0;">Hi %s,</h2>
+					<p style="color: #666; margin: 0 0 20px 0; font-size: 16px;">
+						You've spent <strong>%s %.2f</strong> of your <strong>%s %.2f</strong> budget this %s, crossing %.0f%%.
+					</p>
+				</div>
+			</div>
+		</body>
+		</html>
+	`, html.EscapeString(name), currency, spent, currency, budget, period.Unit(), threshold)
 
 	m.SetBody("text/html", body)
 
+	return s.send(m)
+}
+
+// SendConsentExpiryReminder asks a user to renew a bank link consent before it lapses
+func (s *EmailService) SendConsentExpiryReminder(email, fiType string, validTill time.Time) error {
+	m := mail.NewMessage()
+	m.SetHeader("From", s.SMTPUser)
+	m.SetHeader("To", email)
+	m.SetHeader("Subject", "BucksInfo - Your bank link consent is about to expire")
+
+	body := fmt.Sprintf(`
+		<html>
+		<body>
+			<div style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto; padding: 20px;">
+				<div style="background: linear-gradient(135deg, #FFD700, #FFA500); padding: 20px; border-radius: 10px; text-align: center;">
+					<h1 style="color: #333; margin: 0; font-size: 28px;">BucksInfo</h1>
+					<p style="color: #333; margin: 10px 0 0 0; font-size: 16px;">Consent Renewal</p>
+				</div>
+
+				<div style="background: #f9f9f9; padding: 30px; border-radius: 10px; margin-top: 20px;">
+					<p style="color: #666; margin: 0 0 20px 0; font-size: 16px;">
+						Your consent to share <strong>%s</strong> account data expires on <strong>%s</strong>.
+						Renew it in the app to keep your transactions syncing.
+					</p>
+				</div>
+			</div>
+		</body>
+		</html>
+	`, html.EscapeString(strings.ToLower(fiType)), validTill.Format("2 Jan 2006"))
+
+	m.SetBody("text/html", body)
+
+	return s.send(m)
+}
+
+// send delivers a message through the configured SMTP server
+func (s *EmailService) send(m *mail.Message) error {
+	if s.deliver != nil {
+		return s.deliver(m)
+	}
+
 	// Create dialer
 	d := mail.NewDialer(s.SMTPHost, s.SMTPPort, s.SMTPUser, s.SMTPPass)
 	d.SSL = false
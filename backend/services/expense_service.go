@@ -2,25 +2,33 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/your-github/expense-tracker-backend/models"
 	"github.com/your-github/expense-tracker-backend/utils"
 )
 
 type ExpenseService struct {
-	DB    *gorm.DB
-	Cache *utils.LRUCache
+	DB      *gorm.DB
+	Cache   *utils.LRUCache
+	Alerts  *BudgetAlertService // optional; evaluated after expense writes
+	Summary *SummaryService     // optional; its cached totals are dropped after expense writes
 }
 
-// NewExpenseService creates a new expense service with enhanced caching
-func NewExpenseService(db *gorm.DB) *ExpenseService {
+// NewExpenseService creates a new expense service with enhanced caching,
+// its cache split into cacheShards locks
+func NewExpenseService(db *gorm.DB, cacheShards int) *ExpenseService {
 	// Increased cache size and optimized TTL for better performance
-	cache := utils.NewLRUCache(5000, 30*time.Minute) // Larger cache, longer TTL
-	cache.StartCleanup(10 * time.Minute)             // Less frequent cleanup
+	cache := utils.NewShardedLRUCache(5000, 30*time.Minute, cacheShards) // Larger cache, longer TTL
+	cache.StartCleanup(10 * time.Minute)                                 // Less frequent cleanup
 
 	return &ExpenseService{
 		DB:    db,
@@ -28,62 +36,54 @@ func NewExpenseService(db *gorm.DB) *ExpenseService {
 	}
 }
 
+// Close stops the cache cleanup goroutine
+func (s *ExpenseService) Close() {
+	s.Cache.StopCleanup()
+}
+
 func (s *ExpenseService) Create(e *models.Expense, uid uint) error {
-	// Only auto-categorize expenses, not income
-	if e.Type == "expense" && e.Category == "" {
-		e.Category = utils.AutoCategory(e.Title)
+	if _, err := TransactionTypeFor(e.Type); err != nil {
+		return err
+	}
+	if err := sanitizeExpenseText(e); err != nil {
+		return err
 	}
-	e.UserID = uid
 
 	// Use context with timeout for better performance
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
+	// Known labels are mapped onto the canonical taxonomy; without one the
+	// user's rules and then keyword matching decide
+	categorizer, err := categorizerFor(s.DB.WithContext(ctx), uid)
+	if err != nil {
+		return err
+	}
+	categorizeExpense(categorizer, e)
+	e.UserID = uid
+
+	// Default to the user's preferred currency
+	if e.Currency == "" {
+		var currency string
+		s.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", uid).Select("currency").Scan(&currency)
+		e.Currency = utils.NormalizeCurrency(currency)
+	}
+
 	// Start a transaction to ensure both expense and transaction are created
 	tx := s.DB.WithContext(ctx).Begin()
 	if tx.Error != nil {
 		return tx.Error
 	}
 
-	// Create the expense
-	err := tx.Create(e).Error
+	// Create the expense; tags are attached separately through TagService
+	err = tx.Omit(clause.Associations).Create(e).Error
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	// Parse the date string to time.Time
-	date, err := time.Parse("2006-01-02", e.Date)
-	if err != nil {
-		// If date parsing fails, use current time
-		date = time.Now()
-	}
-
-	// Convert expense type to transaction type
-	transactionType := "debit"
-	if e.Type == "income" {
-		transactionType = "credit"
-	}
-
-	// Create a corresponding transaction record
-	transaction := models.Transaction{
-		UserID:          uid,
-		BankAccountID:   0, // No bank account for manual expenses
-		TransactionID:   fmt.Sprintf("MANUAL_%d", e.ID),
-		TransactionDate: date,
-		Description:     e.Title,
-		Amount:          e.Amount,
-		Type:            transactionType,
-		Category:        e.Category,
-		Balance:         0, // No balance for manual expenses
-		ReferenceNumber: "",
-		MerchantName:    e.PaymentMethod,
-		Location:        "Manual Entry",
-		Status:          "completed",
-	}
-
-	err = tx.Create(&transaction).Error
-	if err != nil {
+	// Create the mirrored transaction record
+	if err := upsertManualTransaction(tx, *e); err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -91,11 +91,11 @@ func (s *ExpenseService) Create(e *models.Expense, uid uint) error {
 	// Commit the transaction
 	err = tx.Commit().Error
 	if err == nil {
-		// Invalidate cache for this user with delay to prevent race conditions
-		go func() {
-			time.Sleep(100 * time.Millisecond)
-			s.invalidateUserCache(uid)
-		}()
+		// Invalidate synchronously so the next read sees the committed write
+		s.invalidateUserCache(uid)
+		if e.Type == "expense" {
+			s.evaluateBudgetAlerts(uid)
+		}
 	}
 	return err
 }
@@ -107,14 +107,21 @@ func (s *ExpenseService) Update(id uint, uid uint, in *models.Expense) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
+	if _, err := TransactionTypeFor(in.Type); err != nil {
+		return err
+	}
+	if err := sanitizeExpenseText(in); err != nil {
+		return err
+	}
 	if err := s.DB.WithContext(ctx).First(&exp, "id=? AND user_id=?", id, uid).Error; err != nil {
 		return err
 	}
 
-	// Only auto-categorize expenses, not income
-	if in.Type == "expense" && in.Category == "" {
-		in.Category = utils.AutoCategory(in.Title)
+	categorizer, err := categorizerFor(s.DB.WithContext(ctx), uid)
+	if err != nil {
+		return err
 	}
+	categorizeExpense(categorizer, in)
 
 	// Start a transaction to ensure both expense and transaction are updated
 	tx := s.DB.WithContext(ctx).Begin()
@@ -122,60 +129,15 @@ func (s *ExpenseService) Update(id uint, uid uint, in *models.Expense) error {
 		return tx.Error
 	}
 
-	// Update the expense
-	err := tx.Model(&exp).Updates(in).Error
+	// Update the expense; tags are managed separately through TagService
+	err = tx.Model(&exp).Omit(clause.Associations).Updates(in).Error
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	// Parse the date string to time.Time
-	date, err := time.Parse("2006-01-02", in.Date)
-	if err != nil {
-		// If date parsing fails, use current time
-		date = time.Now()
-	}
-
-	// Convert expense type to transaction type
-	transactionType := "debit"
-	if in.Type == "income" {
-		transactionType = "credit"
-	}
-
-	// Update the corresponding transaction record
-	transactionID := fmt.Sprintf("MANUAL_%d", id)
-	var transaction models.Transaction
-	if err := tx.Where("transaction_id = ? AND user_id = ?", transactionID, uid).First(&transaction).Error; err != nil {
-		// If transaction doesn't exist, create it
-		transaction = models.Transaction{
-			UserID:          uid,
-			BankAccountID:   0,
-			TransactionID:   transactionID,
-			TransactionDate: date,
-			Description:     in.Title,
-			Amount:          in.Amount,
-			Type:            transactionType,
-			Category:        in.Category,
-			Balance:         0,
-			ReferenceNumber: "",
-			MerchantName:    in.PaymentMethod,
-			Location:        "Manual Entry",
-			Status:          "completed",
-		}
-		err = tx.Create(&transaction).Error
-	} else {
-		// Update existing transaction
-		err = tx.Model(&transaction).Updates(map[string]interface{}{
-			"transaction_date": date,
-			"description":      in.Title,
-			"amount":           in.Amount,
-			"type":             transactionType,
-			"category":         in.Category,
-			"merchant_name":    in.PaymentMethod,
-		}).Error
-	}
-
-	if err != nil {
+	// Keep the mirrored transaction record in step with the expense
+	if err := syncManualTransaction(tx, id, uid, in); err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -183,15 +145,226 @@ func (s *ExpenseService) Update(id uint, uid uint, in *models.Expense) error {
 	// Commit the transaction
 	err = tx.Commit().Error
 	if err == nil {
-		// Invalidate cache for this user with delay
-		go func() {
-			time.Sleep(100 * time.Millisecond)
-			s.invalidateUserCache(uid)
-		}()
+		// Invalidate synchronously so the next read sees the committed write
+		s.invalidateUserCache(uid)
+		if in.Type == "expense" {
+			s.evaluateBudgetAlerts(uid)
+		}
 	}
 	return err
 }
 
+// ExpensePatch holds the fields of a partial expense update. Nil fields are
+// left unchanged; a non-nil empty string clears optional text fields.
+type ExpensePatch struct {
+	Title         *string  `json:"title" binding:"omitempty,min=1"`
+	Amount        *float64 `json:"amount" binding:"omitempty,gt=0"`
+	Category      *string  `json:"category"`
+	Subcategory   *string  `json:"subcategory"`
+	Date          *string  `json:"date" binding:"omitempty,datetime=2006-01-02"`
+	Type          *string  `json:"type" binding:"omitempty,oneof=income expense"`
+	Currency      *string  `json:"currency" binding:"omitempty,iso4217"`
+	PaymentMethod *string  `json:"payment_method"`
+	Notes         *string  `json:"notes"`
+	Latitude      *float64 `json:"latitude"`
+	Longitude     *float64 `json:"longitude"`
+	Location      *string  `json:"location" binding:"omitempty,max=200"`
+	City          *string  `json:"city" binding:"omitempty,max=100"`
+}
+
+// sanitizeText cleans the title and notes set on p as sanitizeExpenseText does
+func (p *ExpensePatch) sanitizeText() error {
+	if p.Title != nil {
+		title := utils.SanitizeText(*p.Title, false)
+		if title == "" {
+			return ErrEmptyExpenseTitle
+		}
+		p.Title = &title
+	}
+	if p.Notes != nil {
+		notes := utils.SanitizeText(*p.Notes, true)
+		p.Notes = &notes
+	}
+	if p.Location != nil {
+		location := strings.TrimSpace(utils.SanitizeText(*p.Location, false))
+		p.Location = &location
+	}
+	if p.City != nil {
+		city := strings.TrimSpace(utils.SanitizeText(*p.City, false))
+		p.City = &city
+	}
+	if (p.Latitude == nil) != (p.Longitude == nil) {
+		return ErrInvalidGeotag
+	}
+	if p.Latitude != nil {
+		return validateCoordinates(*p.Latitude, *p.Longitude)
+	}
+	return nil
+}
+
+// columns returns the column updates for the fields set on p
+func (p ExpensePatch) columns() map[string]interface{} {
+	columns := make(map[string]interface{})
+	if p.Title != nil {
+		columns["title"] = *p.Title
+	}
+	if p.Amount != nil {
+		columns["amount"] = *p.Amount
+	}
+	if p.Category != nil {
+		category := *p.Category
+		if category != "" {
+			category = utils.NormalizeCategory(category)
+		}
+		columns["category"] = category
+	}
+	if p.Subcategory != nil {
+		columns["subcategory"] = *p.Subcategory
+	}
+	if p.Date != nil {
+		columns["date"] = *p.Date
+	}
+	if p.Type != nil {
+		columns["type"] = *p.Type
+	}
+	if p.Currency != nil {
+		columns["currency"] = *p.Currency
+	}
+	if p.PaymentMethod != nil {
+		columns["payment_method"] = *p.PaymentMethod
+	}
+	if p.Notes != nil {
+		columns["notes"] = *p.Notes
+	}
+	if p.Latitude != nil {
+		columns["latitude"] = *p.Latitude
+		columns["longitude"] = *p.Longitude
+	}
+	if p.Location != nil {
+		columns["location"] = *p.Location
+	}
+	if p.City != nil {
+		columns["city"] = *p.City
+	}
+	return columns
+}
+
+// ErrEmptyExpenseTitle is returned when a title is empty once sanitized
+var ErrEmptyExpenseTitle = errors.New("title must contain visible text")
+
+// sanitizeExpenseText cleans the user-typed title and notes of e before they
+// are stored; see utils.SanitizeText. Titles are kept to one line.
+func sanitizeExpenseText(e *models.Expense) error {
+	e.Title = utils.SanitizeText(e.Title, false)
+	if e.Title == "" {
+		return ErrEmptyExpenseTitle
+	}
+	e.Notes = utils.SanitizeText(e.Notes, true)
+	e.Location = strings.TrimSpace(utils.SanitizeText(e.Location, false))
+	e.City = strings.TrimSpace(utils.SanitizeText(e.City, false))
+	if (e.Latitude == nil) != (e.Longitude == nil) {
+		return ErrInvalidGeotag
+	}
+	if e.Latitude != nil {
+		return validateCoordinates(*e.Latitude, *e.Longitude)
+	}
+	return nil
+}
+
+// ErrInvalidGeotag is returned for coordinates that are half set or out of range
+var ErrInvalidGeotag = errors.New("latitude and longitude must be set together, within -90..90 and -180..180")
+
+// validateCoordinates checks a latitude/longitude pair is on the globe
+func validateCoordinates(latitude, longitude float64) error {
+	if math.IsNaN(latitude) || math.IsNaN(longitude) ||
+		latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return ErrInvalidGeotag
+	}
+	return nil
+}
+
+// ErrEmptyPatch is returned when a partial update sets no fields
+var ErrEmptyPatch = errors.New("no fields to update")
+
+// Patch updates only the fields set on patch, so zero values such as an
+// empty note are written rather than ignored, and returns the updated expense
+func (s *ExpenseService) Patch(id, uid uint, patch ExpensePatch) (models.Expense, error) {
+	var exp models.Expense
+
+	if err := patch.sanitizeText(); err != nil {
+		return exp, err
+	}
+	columns := patch.columns()
+	if len(columns) == 0 {
+		return exp, ErrEmptyPatch
+	}
+	if patch.Type != nil {
+		if _, err := TransactionTypeFor(*patch.Type); err != nil {
+			return exp, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&exp, "id=? AND user_id=?", id, uid).Error; err != nil {
+			return err
+		}
+
+		// Mirror Update: expenses left without a category are categorized
+		// by the user's rules and keywords
+		patched := models.Expense{Title: exp.Title, Type: exp.Type, Category: exp.Category}
+		if patch.Title != nil {
+			patched.Title = *patch.Title
+		}
+		if patch.Type != nil {
+			patched.Type = *patch.Type
+		}
+		if patch.Category != nil {
+			patched.Category = *patch.Category
+		}
+		if patched.Category == "" {
+			categorizer, err := categorizerFor(tx, uid)
+			if err != nil {
+				return err
+			}
+			categorizeExpense(categorizer, &patched)
+			if patched.Category != "" {
+				columns["category"] = patched.Category
+				if patch.Subcategory == nil {
+					columns["subcategory"] = patched.Subcategory
+				}
+			}
+		}
+
+		if err := tx.Model(&exp).Omit(clause.Associations).Updates(columns).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&exp, "id=? AND user_id=?", id, uid).Error; err != nil {
+			return err
+		}
+		return syncManualTransaction(tx, id, uid, &exp)
+	})
+	if err != nil {
+		return exp, err
+	}
+
+	s.invalidateUserCache(uid)
+	if exp.Type == "expense" {
+		s.evaluateBudgetAlerts(uid)
+	}
+	return exp, nil
+}
+
+// syncManualTransaction writes the transactions row that mirrors expense id
+// from the values in e, which need not carry the expense's ID or owner
+func syncManualTransaction(tx *gorm.DB, id, uid uint, e *models.Expense) error {
+	mirror := *e
+	mirror.ID, mirror.UserID = id, uid
+	return upsertManualTransaction(tx, mirror)
+}
+
 func (s *ExpenseService) Delete(id, uid uint) error {
 	// Use context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -211,7 +384,7 @@ func (s *ExpenseService) Delete(id, uid uint) error {
 	}
 
 	// Delete the corresponding transaction record
-	transactionID := fmt.Sprintf("MANUAL_%d", id)
+	transactionID := manualTransactionID(uid, id)
 	err = tx.Delete(&models.Transaction{}, "transaction_id=? AND user_id=?", transactionID, uid).Error
 	if err != nil {
 		tx.Rollback()
@@ -221,15 +394,143 @@ func (s *ExpenseService) Delete(id, uid uint) error {
 	// Commit the transaction
 	err = tx.Commit().Error
 	if err == nil {
-		// Invalidate cache for this user with delay
-		go func() {
-			time.Sleep(100 * time.Millisecond)
-			s.invalidateUserCache(uid)
-		}()
+		// Invalidate synchronously so the next read sees the committed write
+		s.invalidateUserCache(uid)
 	}
 	return err
 }
 
+// ListTrash returns the user's soft-deleted expenses, most recently deleted first
+func (s *ExpenseService) ListTrash(uid uint) ([]models.Expense, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var expenses []models.Expense
+	err := s.DB.WithContext(ctx).Unscoped().
+		Where("user_id = ? AND deleted_at IS NOT NULL", uid).
+		Order("deleted_at DESC").
+		Find(&expenses).Error
+	return expenses, err
+}
+
+// Restore undeletes a soft-deleted expense and its mirrored MANUAL_ transaction.
+// The mirror keeps its unique transaction_id while soft-deleted, so the
+// upsert revives it in place, or recreates it if it no longer exists at all.
+func (s *ExpenseService) Restore(id, uid uint) (models.Expense, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var exp models.Expense
+	if err := s.DB.WithContext(ctx).Unscoped().
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, uid).
+		First(&exp).Error; err != nil {
+		return exp, err
+	}
+
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&exp).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return upsertManualTransaction(tx, exp)
+	})
+	if err != nil {
+		return exp, err
+	}
+
+	exp.DeletedAt = gorm.DeletedAt{}
+	s.invalidateUserCache(uid)
+	return exp, nil
+}
+
+// manualTransactionID is the transaction_id of the row mirroring expense id.
+// transaction_id is globally unique, so the owner is part of the key.
+func manualTransactionID(uid, id uint) string {
+	return fmt.Sprintf("MANUAL_%d_%d", uid, id)
+}
+
+// upsertManualTransaction inserts the mirror of e under the user's manual
+// account or, when a row with its transaction_id already exists (including
+// one soft-deleted by an earlier delete), overwrites it and clears deleted_at
+func upsertManualTransaction(tx *gorm.DB, e models.Expense) error {
+	accountID, err := EnsureManualBankAccount(tx, e.UserID)
+	if err != nil {
+		return err
+	}
+
+	transaction, err := manualTransactionFor(e, accountID)
+	if err != nil {
+		return err
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "transaction_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"bank_account_id", "transaction_date", "description", "amount", "type", "category",
+			"merchant_name", "location", "status", "updated_at", "deleted_at",
+		}),
+	}).Create(&transaction).Error
+}
+
+// ErrInvalidExpenseType is returned for an expense type other than "income"
+// or "expense"
+var ErrInvalidExpenseType = errors.New(`expense type must be "income" or "expense"`)
+
+// TransactionTypeFor maps an expense type onto the type of the transaction
+// mirroring it: income is a credit and an expense a debit. Any other type is
+// rejected rather than guessed, so income is never stored as a debit.
+func TransactionTypeFor(expenseType string) (string, error) {
+	switch expenseType {
+	case "income":
+		return "credit", nil
+	case "expense":
+		return "debit", nil
+	default:
+		return "", fmt.Errorf("%w, got %q", ErrInvalidExpenseType, expenseType)
+	}
+}
+
+// manualTransactionFor builds the MANUAL_ transaction mirroring an expense
+// in the manual account bankAccountID
+func manualTransactionFor(e models.Expense, bankAccountID uint) (models.Transaction, error) {
+	transactionType, err := TransactionTypeFor(e.Type)
+	if err != nil {
+		return models.Transaction{}, err
+	}
+
+	date, err := time.Parse("2006-01-02", e.Date)
+	if err != nil {
+		date = time.Now()
+	}
+
+	return models.Transaction{
+		UserID:          e.UserID,
+		BankAccountID:   bankAccountID,
+		TransactionID:   manualTransactionID(e.UserID, e.ID),
+		TransactionDate: date,
+		Description:     e.Title,
+		Amount:          e.Amount,
+		Type:            transactionType,
+		Category:        e.Category,
+		Balance:         0,
+		ReferenceNumber: "",
+		MerchantName:    e.PaymentMethod,
+		Location:        manualLocation(e),
+		Status:          "completed",
+	}, nil
+}
+
+// manualLocation is the location shown on an expense's mirrored
+// transaction: the expense's place, else its city, else "Manual Entry"
+func manualLocation(e models.Expense) string {
+	switch {
+	case e.Location != "":
+		return e.Location
+	case e.City != "":
+		return e.City
+	default:
+		return "Manual Entry"
+	}
+}
+
 func (s *ExpenseService) List(uid uint, limit ...int) ([]models.Expense, error) {
 	// Enhanced cache key with limit
 	limitVal := 0
@@ -246,7 +547,7 @@ func (s *ExpenseService) List(uid uint, limit ...int) ([]models.Expense, error)
 	}
 
 	var ex []models.Expense
-	query := s.DB.Where("user_id=?", uid).Order("date desc, created_at desc")
+	query := s.DB.Preload("Tags").Where("user_id=?", uid).Order("date desc, created_at desc")
 
 	if limitVal > 0 {
 		query = query.Limit(limitVal)
@@ -264,6 +565,46 @@ func (s *ExpenseService) List(uid uint, limit ...int) ([]models.Expense, error)
 	return ex, err
 }
 
+// ListByTags returns expenses carrying the named tags: any of them, or all
+// of them when matchAll is set. Results are not cached.
+func (s *ExpenseService) ListByTags(uid uint, tags []string, matchAll bool, limit int) ([]models.Expense, error) {
+	names := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		name := NormalizeTagName(tag)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return s.List(uid, limit)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tagged := s.DB.Table("expense_tags").
+		Select("expense_tags.expense_id").
+		Joins("JOIN tags ON tags.id = expense_tags.tag_id").
+		Where("tags.user_id = ? AND tags.name IN ? AND tags.deleted_at IS NULL", uid, names).
+		Group("expense_tags.expense_id")
+	if matchAll {
+		tagged = tagged.Having("COUNT(DISTINCT tags.id) = ?", len(names))
+	}
+
+	query := s.DB.WithContext(ctx).Preload("Tags").
+		Where("user_id = ? AND id IN (?)", uid, tagged).
+		Order("date desc, created_at desc")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var ex []models.Expense
+	err := query.Find(&ex).Error
+	return ex, err
+}
+
 func (s *ExpenseService) Get(id, uid uint) (models.Expense, error) {
 	// Try to get from cache first
 	cacheKey := fmt.Sprintf("expense:%d:%d", uid, id)
@@ -287,50 +628,209 @@ func (s *ExpenseService) Get(id, uid uint) (models.Expense, error) {
 	return e, err
 }
 
-func (s *ExpenseService) RecategorizeAll(uid uint) error {
+// uncategorizedCondition selects expenses RecategorizeAll may change
+const uncategorizedCondition = "(category = '' OR category = 'Other')"
+
+// RecategorizeAll re-runs the user's rules and keyword matching over
+// uncategorized expenses and updates them together with their mirrored
+// manual transactions. Expenses moving to the same category are updated in
+// batches, each in its own DB transaction, so a failing batch doesn't undo
+// the others: the result counts what was updated and what failed, and the
+// first error is returned alongside it. Mirrors left out of step with their
+// expense, for example by an earlier run, are brought back in line, so
+// running it again only ever repairs.
+func (s *ExpenseService) RecategorizeAll(uid uint) (RecategorizeResult, error) {
 	var expenses []models.Expense
 
 	// Use context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := s.DB.WithContext(ctx).Where("user_id = ? AND (category = '' OR category = 'Other')", uid).Find(&expenses).Error; err != nil {
-		return err
+	if err := s.DB.WithContext(ctx).Where("user_id = ? AND "+uncategorizedCondition, uid).Find(&expenses).Error; err != nil {
+		return RecategorizeResult{}, err
+	}
+
+	categorizer, err := categorizerFor(s.DB.WithContext(ctx), uid)
+	if err != nil {
+		return RecategorizeResult{}, err
 	}
 
-	// Use batch update for better performance
-	updates := make([]map[string]interface{}, 0, len(expenses))
+	// Group the expenses by their new category so each batch is one UPDATE
+	type target struct{ category, subcategory string }
+	groups := make(map[target][]uint)
+	var targets []target
+	result := RecategorizeResult{Matched: len(expenses)}
 	for _, expense := range expenses {
+		// Uncategorized rows are re-run through the user's rules and, for
+		// expenses, keyword matching
+		var keyword utils.KeywordFunc
 		if expense.Type == "expense" {
-			newCategory := utils.AutoCategory(expense.Title)
-			if newCategory != "Other" {
-				updates = append(updates, map[string]interface{}{
-					"id":       expense.ID,
-					"category": newCategory,
-				})
+			keyword = utils.KeywordCategory
+		}
+		decision := categorizer.Categorize("", expense.Title, keyword)
+		if decision.Source == utils.CategorySourceFallback {
+			continue
+		}
+		if decision.Category == expense.Category && decision.Subcategory == expense.Subcategory {
+			continue
+		}
+		key := target{decision.Category, decision.Subcategory}
+		if _, ok := groups[key]; !ok {
+			targets = append(targets, key)
+		}
+		groups[key] = append(groups[key], expense.ID)
+	}
+
+	var firstErr error
+	for _, key := range targets {
+		ids := groups[key]
+		for start := 0; start < len(ids); start += recategorizeBatchSize {
+			batch := ids[start:min(start+recategorizeBatchSize, len(ids))]
+			var updated int64
+			err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				// The condition is repeated so an expense categorized since
+				// it was read is left alone
+				res := tx.Model(&models.Expense{}).Where("user_id = ? AND id IN ? AND "+uncategorizedCondition, uid, batch).
+					Updates(map[string]interface{}{"category": key.category, "subcategory": key.subcategory})
+				if res.Error != nil {
+					return res.Error
+				}
+				updated = res.RowsAffected
+				return syncManualCategories(tx, uid, batch)
+			})
+			if err != nil {
+				result.Failed += len(batch)
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
 			}
+			result.Updated += int(updated)
 		}
 	}
 
-	// Batch update for better performance
-	if len(updates) > 0 {
-		err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-			for _, update := range updates {
-				if err := tx.Model(&models.Expense{}).Where("id = ?", update["id"]).Update("category", update["category"]).Error; err != nil {
-					return err
-				}
+	// Repair mirrors of any other expense whose category has drifted
+	synced, err := syncManualCategoriesForUser(s.DB.WithContext(ctx), uid)
+	if err != nil && firstErr == nil {
+		firstErr = err
+	}
+	result.Synced = int(synced)
+
+	if result.Updated > 0 || result.Synced > 0 {
+		s.invalidateUserCache(uid)
+	}
+	return result, firstErr
+}
+
+// syncManualCategories copies the category of the given expenses onto
+// their mirrored manual transactions
+func syncManualCategories(tx *gorm.DB, uid uint, expenseIDs []uint) error {
+	return tx.Exec(`
+		UPDATE transactions SET category = expenses.category, updated_at = NOW()
+		FROM expenses
+		WHERE expenses.user_id = ? AND expenses.id IN ?
+			AND transactions.user_id = expenses.user_id
+			AND transactions.transaction_id = 'MANUAL_' || expenses.user_id || '_' || expenses.id
+			AND transactions.category IS DISTINCT FROM expenses.category`,
+		uid, expenseIDs).Error
+}
+
+// syncManualCategoriesForUser copies the category of every expense of the
+// user onto its mirrored manual transaction where the two differ, returning
+// how many transactions changed
+func syncManualCategoriesForUser(db *gorm.DB, uid uint) (int64, error) {
+	res := db.Exec(`
+		UPDATE transactions SET category = expenses.category, updated_at = NOW()
+		FROM expenses
+		WHERE expenses.user_id = ? AND expenses.deleted_at IS NULL
+			AND transactions.user_id = expenses.user_id
+			AND transactions.deleted_at IS NULL
+			AND transactions.transaction_id = 'MANUAL_' || expenses.user_id || '_' || expenses.id
+			AND transactions.category IS DISTINCT FROM expenses.category`,
+		uid)
+	return res.RowsAffected, res.Error
+}
+
+// recategorizeBatchSize is how many expenses one UPDATE recategorizes
+const recategorizeBatchSize = 500
+
+// RecategorizeResult reports a bulk recategorization
+type RecategorizeResult struct {
+	Matched int                  `json:"matched"`
+	Updated int                  `json:"updated"`
+	Failed  int                  `json:"failed,omitempty"` // expenses in batches that could not be saved
+	Synced  int                  `json:"synced,omitempty"` // mirrored transactions brought back in line with their expense
+	Rule    *models.CategoryRule `json:"rule,omitempty"`
+}
+
+// RecategorizeByRule moves every expense whose title matches matcher into
+// category/subcategory, whatever its current category, and updates the
+// mirrored manual transactions in the same DB transaction. With save the
+// rule is also stored so later expenses are categorized the same way.
+func (s *ExpenseService) RecategorizeByRule(uid uint, matcher, category, subcategory string, save bool) (RecategorizeResult, error) {
+	rule, err := newCategoryRule(uid, matcher, category, subcategory)
+	if err != nil {
+		return RecategorizeResult{}, err
+	}
+	matchRule := utils.CategoryRule{Matcher: rule.Matcher, Category: rule.Category, Subcategory: rule.Subcategory}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var expenses []models.Expense
+	if err := s.DB.WithContext(ctx).Select("id", "title", "category", "subcategory").
+		Where("user_id = ?", uid).Find(&expenses).Error; err != nil {
+		return RecategorizeResult{}, err
+	}
+
+	var result RecategorizeResult
+	var ids []uint
+	for _, expense := range expenses {
+		if !matchRule.Matches(expense.Title) {
+			continue
+		}
+		result.Matched++
+		if expense.Category != rule.Category || expense.Subcategory != rule.Subcategory {
+			ids = append(ids, expense.ID)
+		}
+	}
+
+	err = s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(ids); start += recategorizeBatchSize {
+			batch := ids[start:min(start+recategorizeBatchSize, len(ids))]
+			if err := tx.Model(&models.Expense{}).Where("user_id = ? AND id IN ?", uid, batch).Updates(map[string]interface{}{
+				"category":    rule.Category,
+				"subcategory": rule.Subcategory,
+			}).Error; err != nil {
+				return err
 			}
-			return nil
-		})
 
-		if err == nil {
-			// Invalidate cache for this user
-			s.invalidateUserCache(uid)
+			transactionIDs := make([]string, len(batch))
+			for i, id := range batch {
+				transactionIDs[i] = manualTransactionID(uid, id)
+			}
+			if err := tx.Model(&models.Transaction{}).Where("user_id = ? AND transaction_id IN ?", uid, transactionIDs).
+				Update("category", rule.Category).Error; err != nil {
+				return err
+			}
 		}
-		return err
+		if save {
+			if err := tx.Create(&rule).Error; err != nil {
+				return err
+			}
+			result.Rule = &rule
+		}
+		return nil
+	})
+	if err != nil {
+		return RecategorizeResult{}, err
 	}
 
-	return nil
+	result.Updated = len(ids)
+	if len(ids) > 0 {
+		s.invalidateUserCache(uid)
+	}
+	return result, nil
 }
 
 // GetExpensesByDateRange efficiently retrieves expenses within a date range
@@ -362,6 +862,7 @@ func (s *ExpenseService) GetExpensesByDateRange(uid uint, startDate, endDate str
 
 // GetExpensesByCategory efficiently retrieves expenses by category
 func (s *ExpenseService) GetExpensesByCategory(uid uint, category string) ([]models.Expense, error) {
+	category = utils.NormalizeCategory(category)
 	cacheKey := fmt.Sprintf("expenses_category:%d:%s", uid, category)
 	if cached, found := s.Cache.Get(cacheKey); found {
 		if expenses, ok := cached.([]models.Expense); ok {
@@ -387,73 +888,130 @@ func (s *ExpenseService) GetExpensesByCategory(uid uint, category string) ([]mod
 	return expenses, err
 }
 
-// invalidateUserCache removes all cache entries for a specific user
+// evaluateBudgetAlerts checks budget thresholds in the background when alerts are configured
+func (s *ExpenseService) evaluateBudgetAlerts(uid uint) {
+	if s.Alerts != nil {
+		s.Alerts.EvaluateAsync(uid)
+	}
+}
+
+// invalidateUserCache drops the user's cached expense lists and, when a
+// summary service is attached, their cached totals
 func (s *ExpenseService) invalidateUserCache(uid uint) {
-	// Get all cache keys and remove those belonging to this user
-	// This is a simplified approach - in production, you might want a more sophisticated cache invalidation strategy
+	s.Cache.DeleteFunc(func(key string) bool { return isUserCacheKey(key, uid) })
+	if s.Summary != nil {
+		s.Summary.InvalidateUserCache(uid)
+	}
+}
 
-	// Clear cache entries that contain this user ID
-	// Note: This is a simplified approach. In a real implementation, you might want to maintain a separate index
-	// TODO: Implement selective cache invalidation based on user ID
-	// For now, clear all cache when user data changes for simplicity
-	_ = uid // Use uid parameter (will be used in future implementation)
-	s.Cache.Clear()
+// isUserCacheKey reports whether a cache key belongs to uid. Expense and
+// summary keys all have the form "<kind>:<uid>:...".
+func isUserCacheKey(key string, uid uint) bool {
+	_, rest, found := strings.Cut(key, ":")
+	if !found {
+		return false
+	}
+	owner, _, _ := strings.Cut(rest, ":")
+	return owner == strconv.FormatUint(uint64(uid), 10)
 }
 
-// MigrateExistingExpensesToTransactions creates transaction records for existing expenses
-// This should be called once to migrate existing data
-func (s *ExpenseService) MigrateExistingExpensesToTransactions() error {
-	var expenses []models.Expense
+// expenseMigrationBatchSize is how many expenses are mirrored per query and DB transaction
+const expenseMigrationBatchSize = 500
 
-	// Get all expenses that don't have corresponding transaction records
-	err := s.DB.Find(&expenses).Error
-	if err != nil {
-		return err
-	}
+// ExpenseMigrationResult reports what a mirror migration did
+type ExpenseMigrationResult struct {
+	Users   int `json:"users"`
+	Scanned int `json:"scanned"`
+	Created int `json:"created"`
+}
 
-	for _, expense := range expenses {
-		// Check if transaction already exists
-		transactionID := fmt.Sprintf("MANUAL_%d", expense.ID)
-		var existingTransaction models.Transaction
-		if err := s.DB.Where("transaction_id = ?", transactionID).First(&existingTransaction).Error; err == nil {
-			// Transaction already exists, skip
-			continue
+// MigrateUserExpensesToTransactions creates the missing MANUAL_ transaction
+// mirrors for one user's expenses. Expenses are walked in ID order in
+// batches and ones already mirrored are skipped, so the migration can be
+// re-run or resumed after a failure without creating duplicates.
+func (s *ExpenseService) MigrateUserExpensesToTransactions(ctx context.Context, uid uint) (ExpenseMigrationResult, error) {
+	result := ExpenseMigrationResult{Users: 1}
+
+	var lastID uint
+	for {
+		var expenses []models.Expense
+		err := s.DB.WithContext(ctx).
+			Where("user_id = ? AND id > ?", uid, lastID).
+			Order("id ASC").
+			Limit(expenseMigrationBatchSize).
+			Find(&expenses).Error
+		if err != nil {
+			return result, err
+		}
+		if len(expenses) == 0 {
+			break
 		}
+		lastID = expenses[len(expenses)-1].ID
+		result.Scanned += len(expenses)
 
-		// Parse the date string to time.Time
-		date, err := time.Parse("2006-01-02", expense.Date)
+		keys := make([]string, len(expenses))
+		for i, expense := range expenses {
+			keys[i] = manualTransactionID(uid, expense.ID)
+		}
+		var mirrored []string
+		err = s.DB.WithContext(ctx).Model(&models.Transaction{}).
+			Where("transaction_id IN ?", keys).
+			Pluck("transaction_id", &mirrored).Error
 		if err != nil {
-			// If date parsing fails, use current time
-			date = time.Now()
+			return result, err
 		}
-
-		// Convert expense type to transaction type
-		transactionType := "debit"
-		if expense.Type == "income" {
-			transactionType = "credit"
+		exists := make(map[string]bool, len(mirrored))
+		for _, key := range mirrored {
+			exists[key] = true
 		}
 
-		// Create a corresponding transaction record
-		transaction := models.Transaction{
-			UserID:          expense.UserID,
-			BankAccountID:   0, // No bank account for manual expenses
-			TransactionID:   transactionID,
-			TransactionDate: date,
-			Description:     expense.Title,
-			Amount:          expense.Amount,
-			Type:            transactionType,
-			Category:        expense.Category,
-			Balance:         0, // No balance for manual expenses
-			ReferenceNumber: "",
-			MerchantName:    expense.PaymentMethod,
-			Location:        "Manual Entry",
-			Status:          "completed",
+		created := 0
+		err = s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			for _, expense := range expenses {
+				if exists[manualTransactionID(uid, expense.ID)] {
+					continue
+				}
+				if err := upsertManualTransaction(tx, expense); err != nil {
+					return fmt.Errorf("failed to create transaction for expense %d: %w", expense.ID, err)
+				}
+				created++
+			}
+			return nil
+		})
+		if err != nil {
+			return result, err
 		}
+		result.Created += created
 
-		if err := s.DB.Create(&transaction).Error; err != nil {
-			return fmt.Errorf("failed to create transaction for expense %d: %v", expense.ID, err)
+		if len(expenses) < expenseMigrationBatchSize {
+			break
 		}
 	}
 
-	return nil
+	if result.Created > 0 {
+		s.invalidateUserCache(uid)
+	}
+	return result, nil
+}
+
+// MigrateAllExpensesToTransactions runs MigrateUserExpensesToTransactions for
+// every user with expenses, one user at a time
+func (s *ExpenseService) MigrateAllExpensesToTransactions(ctx context.Context) (ExpenseMigrationResult, error) {
+	var result ExpenseMigrationResult
+
+	var userIDs []uint
+	if err := s.DB.WithContext(ctx).Model(&models.Expense{}).Distinct().Order("user_id").Pluck("user_id", &userIDs).Error; err != nil {
+		return result, err
+	}
+
+	for _, uid := range userIDs {
+		userResult, err := s.MigrateUserExpensesToTransactions(ctx, uid)
+		result.Users++
+		result.Scanned += userResult.Scanned
+		result.Created += userResult.Created
+		if err != nil {
+			return result, fmt.Errorf("user %d: %w", uid, err)
+		}
+	}
+	return result, nil
 }
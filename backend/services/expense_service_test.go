@@ -0,0 +1,1069 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/goleak"
+
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+	"github.com/your-github/expense-tracker-backend/models"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+// expenseRow is an expenses row of expenseTable
+type expenseRow struct {
+	id      int64
+	userID  uint
+	title   string
+	amount  float64
+	date    string
+	kind    string
+	deleted bool
+}
+
+// expenseTable keeps expenses rows and the transaction_id of their manual
+// mirrors in memory, with soft deletes
+type expenseTable struct {
+	mu       sync.Mutex
+	expenses map[int64]*expenseRow
+	// mirrors maps a mirror's transaction_id to whether it is soft-deleted
+	mirrors map[string]bool
+}
+
+// expenseColumns are the expenses columns the table answers with
+var expenseColumns = []string{"id", "title", "amount", "date", "type", "user_id", "deleted_at"}
+
+func newExpenseTable(stub *testutil.StubDB) *expenseTable {
+	table := &expenseTable{expenses: make(map[int64]*expenseRow), mirrors: make(map[string]bool)}
+	stub.On(`FROM "bank_accounts"`, []string{"id", "user_id", "bank_id"}, []driver.Value{int64(50), int64(7), ManualBankID})
+	stub.Handle(`UPDATE "expenses" SET "deleted_at"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		deleting := args[0] != nil
+		var affected int64
+		for _, row := range table.expenses {
+			if deleting && row.id == toInt64(args[1]) && row.userID == uint(toInt64(args[2])) && !row.deleted {
+				row.deleted, affected = true, 1
+			}
+			if !deleting && row.id == toInt64(args[len(args)-1]) {
+				row.deleted, affected = false, 1
+			}
+		}
+		return testutil.StubResult{Affected: affected}, nil
+	})
+	stub.Handle(`UPDATE "transactions" SET "deleted_at"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		key := args[1].(string)
+		if deleted, ok := table.mirrors[key]; ok && !deleted {
+			table.mirrors[key] = true
+			return testutil.StubResult{Affected: 1}, nil
+		}
+		return testutil.StubResult{}, nil
+	})
+	stub.Handle(`INSERT INTO "transactions"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		values := testutil.InsertedValues(query, args)
+		table.mirrors[values["transaction_id"].(string)] = false
+		return testutil.StubResult{Columns: []string{"id"}, Rows: [][]driver.Value{{int64(len(table.mirrors))}}}, nil
+	})
+	stub.Handle(`INSERT INTO "expenses"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		values := testutil.InsertedValues(query, args)
+		row := &expenseRow{
+			id:     int64(len(table.expenses) + 1),
+			userID: uint(toInt64(values["user_id"])),
+			title:  values["title"].(string),
+			amount: values["amount"].(float64),
+			date:   values["date"].(string),
+			kind:   values["type"].(string),
+		}
+		table.expenses[row.id] = row
+		return testutil.StubResult{Columns: []string{"id"}, Rows: [][]driver.Value{{row.id}}}, nil
+	})
+	stub.Handle(`FROM "expenses"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		trash := strings.Contains(query, "deleted_at IS NOT NULL")
+		byID := strings.Contains(query, "WHERE id = $1")
+		var rows []*expenseRow
+		for _, row := range table.expenses {
+			if row.deleted != trash {
+				continue
+			}
+			if byID && (row.id != toInt64(args[0]) || row.userID != uint(toInt64(args[1]))) {
+				continue
+			}
+			if !byID && row.userID != uint(toInt64(args[0])) {
+				continue
+			}
+			rows = append(rows, row)
+		}
+		sort.Slice(rows, func(i, j int) bool { return rows[i].id < rows[j].id })
+
+		result := testutil.StubResult{Columns: expenseColumns}
+		for _, row := range rows {
+			var deletedAt driver.Value
+			if row.deleted {
+				deletedAt = time.Date(2025, time.March, 2, 0, 0, 0, 0, time.UTC)
+			}
+			result.Rows = append(result.Rows, []driver.Value{row.id, row.title, row.amount, row.date, row.kind, int64(row.userID), deletedAt})
+		}
+		return result, nil
+	})
+	return table
+}
+
+// add stores a live expense and its live mirror
+func (e *expenseTable) add(row expenseRow) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expenses[row.id] = &row
+	e.mirrors[manualTransactionID(row.userID, uint(row.id))] = false
+}
+
+// mirrorDeleted reports whether the expense's mirror exists and is soft-deleted
+func (e *expenseTable) mirrorDeleted(uid uint, id int64) (deleted, exists bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	deleted, exists = e.mirrors[manualTransactionID(uid, uint(id))]
+	return deleted, exists
+}
+
+func toInt64(value driver.Value) int64 {
+	switch v := value.(type) {
+	case int64:
+		return v
+	case uint:
+		return int64(v)
+	case int:
+		return int64(v)
+	}
+	return -1
+}
+
+func newExpenseFixture(t *testing.T) (*ExpenseService, *expenseTable, *testutil.StubDB) {
+	t.Helper()
+	db, stub := testutil.NewStubDB(t)
+	service := NewExpenseService(db, 1)
+	t.Cleanup(service.Close)
+	return service, newExpenseTable(stub), stub
+}
+
+func TestExpenseDeleteTrashRestoreRoundTrip(t *testing.T) {
+	service, table, _ := newExpenseFixture(t)
+	table.add(expenseRow{id: 3, userID: 7, title: "Groceries", amount: 640, date: "2025-03-01", kind: "expense"})
+	table.add(expenseRow{id: 4, userID: 7, title: "Fuel", amount: 1200, date: "2025-03-01", kind: "expense"})
+
+	if err := service.Delete(3, 7); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if deleted, _ := table.mirrorDeleted(7, 3); !deleted {
+		t.Error("deleting the expense left its mirror live")
+	}
+
+	trash, err := service.ListTrash(7)
+	if err != nil {
+		t.Fatalf("ListTrash: %v", err)
+	}
+	if len(trash) != 1 || trash[0].ID != 3 || trash[0].Title != "Groceries" {
+		t.Fatalf("trash = %+v, want only expense 3", trash)
+	}
+
+	restored, err := service.Restore(3, 7)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if restored.ID != 3 || restored.DeletedAt.Valid {
+		t.Errorf("restored = %+v, want expense 3 without deleted_at", restored)
+	}
+	if deleted, exists := table.mirrorDeleted(7, 3); !exists || deleted {
+		t.Error("restoring the expense didn't revive its mirror")
+	}
+
+	trash, err = service.ListTrash(7)
+	if err != nil {
+		t.Fatalf("ListTrash: %v", err)
+	}
+	if len(trash) != 0 {
+		t.Errorf("trash after restore = %+v, want empty", trash)
+	}
+}
+
+func TestExpenseRestoreRecreatesMissingMirror(t *testing.T) {
+	service, table, _ := newExpenseFixture(t)
+	table.add(expenseRow{id: 3, userID: 7, title: "Groceries", amount: 640, date: "2025-03-01", kind: "expense", deleted: true})
+	table.mu.Lock()
+	delete(table.mirrors, manualTransactionID(7, 3))
+	table.mu.Unlock()
+
+	if _, err := service.Restore(3, 7); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if deleted, exists := table.mirrorDeleted(7, 3); !exists || deleted {
+		t.Error("restore didn't recreate the mirror")
+	}
+}
+
+func TestExpenseRestoreChecksOwnership(t *testing.T) {
+	service, table, stub := newExpenseFixture(t)
+	table.add(expenseRow{id: 3, userID: 7, title: "Groceries", amount: 640, date: "2025-03-01", kind: "expense", deleted: true})
+
+	if _, err := service.Restore(3, 8); err == nil {
+		t.Fatal("another user restored the expense")
+	}
+	if trash, _ := service.ListTrash(8); len(trash) != 0 {
+		t.Errorf("another user's trash lists %+v", trash)
+	}
+	if len(stub.Ran(`INSERT INTO "transactions"`)) != 0 {
+		t.Error("a rejected restore touched the mirror")
+	}
+
+	// A live expense isn't in the trash, so it can't be restored either
+	table.add(expenseRow{id: 4, userID: 7, title: "Fuel", amount: 1200, date: "2025-03-01", kind: "expense"})
+	if _, err := service.Restore(4, 7); err == nil {
+		t.Error("restored an expense that wasn't deleted")
+	}
+}
+
+func TestExpenseReadsAfterWritesAreFresh(t *testing.T) {
+	service, table, _ := newExpenseFixture(t)
+	table.add(expenseRow{id: 1, userID: 7, title: "Groceries", amount: 640, date: "2025-03-01", kind: "expense"})
+
+	titles := func() []string {
+		expenses, err := service.List(7)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		var out []string
+		for _, e := range expenses {
+			out = append(out, e.Title)
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	// Warm the cache, then read straight after each write
+	if got := titles(); len(got) != 1 {
+		t.Fatalf("List = %v, want the seeded expense", got)
+	}
+	created := &models.Expense{Title: "Fuel", Amount: 1200, Date: "2025-03-02", Type: "expense", Currency: "INR"}
+	if err := service.Create(created, 7); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if got := titles(); len(got) != 2 || got[0] != "Fuel" {
+		t.Errorf("List after Create = %v, want Fuel included", got)
+	}
+	if err := service.Delete(1, 7); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if got := titles(); len(got) != 1 || got[0] != "Fuel" {
+		t.Errorf("List after Delete = %v, want only Fuel", got)
+	}
+}
+
+func TestCloseStopsCacheCleanup(t *testing.T) {
+	// Goroutines of other tests aren't this test's concern
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	expenses := NewExpenseService(nil, 1)
+	summary := NewSummaryService(nil, testRates, 1)
+	expenses.Close()
+	summary.Close()
+
+	// Closing twice is harmless
+	expenses.Close()
+	summary.Close()
+}
+
+// patchFixture returns an expense service over a stub holding expense 3 of
+// user 7, a taxi ride with a note
+func patchFixture(t *testing.T) (*ExpenseService, *testutil.StubDB) {
+	t.Helper()
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`FROM "bank_accounts"`, []string{"id", "user_id", "bank_id"}, []driver.Value{int64(50), int64(7), ManualBankID})
+	stub.On(`FROM "expenses"`, []string{"id", "user_id", "title", "amount", "date", "type", "category", "notes"},
+		[]driver.Value{int64(3), int64(7), "Taxi", 250.0, "2025-03-01", "expense", "Transport", "airport"})
+	service := NewExpenseService(db, 1)
+	t.Cleanup(service.Close)
+	return service, stub
+}
+
+func TestPatchUpdatesOnlyTheGivenField(t *testing.T) {
+	service, stub := patchFixture(t)
+	amount := 300.0
+
+	if _, err := service.Patch(3, 7, ExpensePatch{Amount: &amount}); err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	updates := stub.Ran(`UPDATE "expenses"`)
+	if len(updates) != 1 || !strings.Contains(updates[0].SQL, `SET "amount"=$1,"updated_at"=$2 WHERE`) {
+		t.Fatalf("updates = %+v, want only the amount written", updates)
+	}
+	if updates[0].Args[0] != 300.0 {
+		t.Errorf("amount written as %v", updates[0].Args[0])
+	}
+	if len(stub.Ran(`INSERT INTO "transactions"`)) != 1 {
+		t.Error("the manual transaction mirror wasn't synced")
+	}
+}
+
+func TestPatchClearsAFieldToEmpty(t *testing.T) {
+	service, stub := patchFixture(t)
+	empty := ""
+
+	if _, err := service.Patch(3, 7, ExpensePatch{Notes: &empty}); err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	updates := stub.Ran(`UPDATE "expenses"`)
+	if len(updates) != 1 || !strings.Contains(updates[0].SQL, `SET "notes"=$1,"updated_at"=$2 WHERE`) || updates[0].Args[0] != "" {
+		t.Errorf("updates = %+v, want notes cleared rather than skipped", updates)
+	}
+}
+
+func TestPatchMapsCategoryOntoTaxonomy(t *testing.T) {
+	service, stub := patchFixture(t)
+	category := "food delivery"
+
+	if _, err := service.Patch(3, 7, ExpensePatch{Category: &category}); err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	updates := stub.Ran(`UPDATE "expenses"`)
+	if len(updates) != 1 || updates[0].Args[0] != utils.CategoryFood {
+		t.Errorf("updates = %+v, want the category written as %q", updates, utils.CategoryFood)
+	}
+}
+
+func TestPatchClearingCategoryRecategorizesWithSubcategory(t *testing.T) {
+	service, stub := patchFixture(t)
+	empty := ""
+
+	if _, err := service.Patch(3, 7, ExpensePatch{Category: &empty}); err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	updates := stub.Ran(`UPDATE "expenses"`)
+	if len(updates) != 1 || !strings.Contains(updates[0].SQL, `SET "category"=$1,"subcategory"=$2`) {
+		t.Fatalf("updates = %+v, want category and subcategory written", updates)
+	}
+	if args := updates[0].Args; args[0] != utils.CategoryTransport || args[1] != "Ride Hailing" {
+		t.Errorf("recategorized %q as %v / %v, want Transport / Ride Hailing", "Taxi", args[0], args[1])
+	}
+}
+
+func TestPatchRejectsEmptyAndInvalidPatches(t *testing.T) {
+	service, stub := patchFixture(t)
+	blank, latitude := "   ", 12.9
+
+	if _, err := service.Patch(3, 7, ExpensePatch{}); !errors.Is(err, ErrEmptyPatch) {
+		t.Errorf("empty patch: %v, want ErrEmptyPatch", err)
+	}
+	if _, err := service.Patch(3, 7, ExpensePatch{Title: &blank}); !errors.Is(err, ErrEmptyExpenseTitle) {
+		t.Errorf("blank title: %v, want ErrEmptyExpenseTitle", err)
+	}
+	if _, err := service.Patch(3, 7, ExpensePatch{Latitude: &latitude}); !errors.Is(err, ErrInvalidGeotag) {
+		t.Errorf("latitude alone: %v, want ErrInvalidGeotag", err)
+	}
+	if len(stub.Ran(`UPDATE "expenses"`)) != 0 {
+		t.Error("a rejected patch was written")
+	}
+}
+
+func TestTransactionTypeFor(t *testing.T) {
+	tests := []struct {
+		expenseType string
+		want        string
+	}{
+		{"income", "credit"},
+		{"expense", "debit"},
+		{"Income", ""},
+		{"refund", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, err := TransactionTypeFor(tt.expenseType)
+		if got != tt.want || (tt.want == "") != errors.Is(err, ErrInvalidExpenseType) {
+			t.Errorf("TransactionTypeFor(%q) = %q, %v; want %q", tt.expenseType, got, err, tt.want)
+		}
+	}
+}
+
+func TestExpenseServiceRejectsUnknownTypes(t *testing.T) {
+	service, stub := patchFixture(t)
+	refund := "refund"
+
+	err := service.Create(&models.Expense{Title: "Refund", Amount: 100, Date: "2025-03-01", Type: refund, Currency: "INR"}, 7)
+	if !errors.Is(err, ErrInvalidExpenseType) || !strings.Contains(err.Error(), `"refund"`) {
+		t.Errorf("Create: %v, want ErrInvalidExpenseType naming the type", err)
+	}
+	if err := service.Update(3, 7, &models.Expense{Title: "Taxi", Amount: 250, Date: "2025-03-01", Type: refund}); !errors.Is(err, ErrInvalidExpenseType) {
+		t.Errorf("Update: %v, want ErrInvalidExpenseType", err)
+	}
+	if _, err := service.Patch(3, 7, ExpensePatch{Type: &refund}); !errors.Is(err, ErrInvalidExpenseType) {
+		t.Errorf("Patch: %v, want ErrInvalidExpenseType", err)
+	}
+	// Internal callers that skip the HTTP binding are caught too
+	if err := upsertManualTransaction(service.DB, models.Expense{UserID: 7, Type: refund}); !errors.Is(err, ErrInvalidExpenseType) {
+		t.Errorf("mirroring: %v, want ErrInvalidExpenseType", err)
+	}
+
+	for _, table := range []string{`INSERT INTO "expenses"`, `UPDATE "expenses"`, `INSERT INTO "transactions"`, `UPDATE "transactions"`} {
+		if ran := stub.Ran(table); len(ran) != 0 {
+			t.Errorf("an invalid type was written: %s", ran[0].SQL)
+		}
+	}
+}
+
+func TestIncomeMirrorIsACredit(t *testing.T) {
+	service, _, stub := newExpenseFixture(t)
+
+	for _, expense := range []*models.Expense{
+		{Title: "Salary", Amount: 50000, Date: "2025-03-01", Type: "income", Currency: "INR"},
+		{Title: "Rent", Amount: 20000, Date: "2025-03-01", Type: "expense", Currency: "INR"},
+	} {
+		if err := service.Create(expense, 7); err != nil {
+			t.Fatalf("Create %s: %v", expense.Title, err)
+		}
+	}
+	mirrors := stub.Ran(`INSERT INTO "transactions"`)
+	if len(mirrors) != 2 {
+		t.Fatalf("wrote %d mirrors, want 2", len(mirrors))
+	}
+	for i, want := range []string{"credit", "debit"} {
+		if got := testutil.InsertedValues(mirrors[i].SQL, mirrors[i].Args)["type"]; got != want {
+			t.Errorf("mirror %d type = %v, want %s", i+1, got, want)
+		}
+	}
+}
+
+// enforceUniqueMirrors makes mirror inserts on table behave like the unique
+// transaction_id index: a plain insert of an existing key fails, and only an
+// upsert that assigns deleted_at revives a soft-deleted mirror
+func enforceUniqueMirrors(stub *testutil.StubDB, table *expenseTable) {
+	stub.Handle(`INSERT INTO "transactions"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		key := testutil.InsertedValues(query, args)["transaction_id"].(string)
+		if _, exists := table.mirrors[key]; exists {
+			if !strings.Contains(query, `ON CONFLICT ("transaction_id") DO UPDATE`) {
+				return testutil.StubResult{}, errors.New(`duplicate key value violates unique constraint "idx_transactions_transaction_id"`)
+			}
+			if !strings.Contains(query, `"deleted_at"="excluded"."deleted_at"`) {
+				return testutil.StubResult{Columns: []string{"id"}, Rows: [][]driver.Value{{int64(1)}}}, nil
+			}
+		}
+		table.mirrors[key] = false
+		return testutil.StubResult{Columns: []string{"id"}, Rows: [][]driver.Value{{int64(len(table.mirrors))}}}, nil
+	})
+}
+
+func TestExpenseRecreatedOverSoftDeletedMirror(t *testing.T) {
+	service, table, stub := newExpenseFixture(t)
+	enforceUniqueMirrors(stub, table)
+	// An earlier expense 1 was deleted, leaving its mirror soft-deleted,
+	// and the new expense is assigned the same ID
+	table.mu.Lock()
+	table.mirrors[manualTransactionID(7, 1)] = true
+	table.mu.Unlock()
+
+	created := &models.Expense{Title: "Fuel", Amount: 1200, Date: "2025-03-02", Type: "expense", Currency: "INR"}
+	if err := service.Create(created, 7); err != nil {
+		t.Fatalf("Create over a soft-deleted mirror: %v", err)
+	}
+	if deleted, exists := table.mirrorDeleted(7, 1); !exists || deleted {
+		t.Error("the new expense's mirror is still soft-deleted")
+	}
+}
+
+func TestExpenseCreateStoresSubcategory(t *testing.T) {
+	service, _, stub := newExpenseFixture(t)
+
+	guessed := &models.Expense{Title: "Starbucks latte", Amount: 320, Date: "2025-03-02", Type: "expense", Currency: "INR"}
+	if err := service.Create(guessed, 7); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if guessed.Category != utils.CategoryFood || guessed.Subcategory != "Coffee" {
+		t.Errorf("auto-categorized as %s / %s, want Food & Dining / Coffee", guessed.Category, guessed.Subcategory)
+	}
+	inserts := stub.Ran(`INSERT INTO "expenses"`)
+	if values := testutil.InsertedValues(inserts[0].SQL, inserts[0].Args); values["subcategory"] != "Coffee" {
+		t.Errorf("stored subcategory %v, want Coffee", values["subcategory"])
+	}
+
+	// A subcategory the user chose is kept
+	chosen := &models.Expense{Title: "Starbucks latte", Subcategory: "Treats", Amount: 320, Date: "2025-03-02", Type: "expense", Currency: "INR"}
+	if err := service.Create(chosen, 7); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if chosen.Subcategory != "Treats" {
+		t.Errorf("subcategory = %q, want the user's Treats", chosen.Subcategory)
+	}
+}
+
+func TestExpenseCreateSanitizesText(t *testing.T) {
+	service, _, stub := newExpenseFixture(t)
+
+	expense := &models.Expense{
+		Title: "  Taxi\u202Egnp.exe\x00\n", Notes: "airport\r\nrun\x07", Category: "Transport",
+		Amount: 250, Date: "2025-03-02", Type: "expense", Currency: "INR",
+	}
+	if err := service.Create(expense, 7); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	inserts := stub.Ran(`INSERT INTO "expenses"`)
+	values := testutil.InsertedValues(inserts[0].SQL, inserts[0].Args)
+	if values["title"] != "Taxignp.exe" || values["notes"] != "airport\nrun" {
+		t.Errorf("stored title %q and notes %q, want control and bidi characters removed", values["title"], values["notes"])
+	}
+
+	invisible := &models.Expense{Title: "\u202E\x00\t", Amount: 250, Date: "2025-03-02", Type: "expense", Currency: "INR"}
+	if err := service.Create(invisible, 7); !errors.Is(err, ErrEmptyExpenseTitle) {
+		t.Errorf("invisible title: %v, want ErrEmptyExpenseTitle", err)
+	}
+	if len(stub.Ran(`INSERT INTO "expenses"`)) != 1 {
+		t.Error("an expense without a visible title was stored")
+	}
+}
+
+func TestPatchSanitizesText(t *testing.T) {
+	service, stub := patchFixture(t)
+	title, notes := "Cab\u2066 home\x1b", "left\tbag\x00"
+
+	if _, err := service.Patch(3, 7, ExpensePatch{Title: &title, Notes: &notes}); err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	updates := stub.Ran(`UPDATE "expenses"`)
+	if len(updates) != 1 || !hasArg(updates[0].Args, "Cab home") || !hasArg(updates[0].Args, "left\tbag") {
+		t.Errorf("updates = %+v, want the sanitized title and notes written", updates)
+	}
+
+	invisible := "\u202A\u202C"
+	if _, err := service.Patch(3, 7, ExpensePatch{Title: &invisible}); !errors.Is(err, ErrEmptyExpenseTitle) {
+		t.Errorf("invisible title: %v, want ErrEmptyExpenseTitle", err)
+	}
+}
+
+func TestExpenseDeleteThenRestoreKeepsOneMirror(t *testing.T) {
+	service, table, stub := newExpenseFixture(t)
+	enforceUniqueMirrors(stub, table)
+	table.add(expenseRow{id: 3, userID: 7, title: "Groceries", amount: 640, date: "2025-03-01", kind: "expense"})
+
+	if err := service.Delete(3, 7); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := service.Restore(3, 7); err != nil {
+		t.Fatalf("Restore over the soft-deleted mirror: %v", err)
+	}
+	if deleted, exists := table.mirrorDeleted(7, 3); !exists || deleted {
+		t.Error("restoring didn't revive the mirror")
+	}
+	if len(table.mirrors) != 1 {
+		t.Errorf("mirrors = %v, want the one revived in place", table.mirrors)
+	}
+}
+
+func TestManualMirrorKeysAreUserScoped(t *testing.T) {
+	service, table, stub := newExpenseFixture(t)
+	enforceUniqueMirrors(stub, table)
+	// User 8 already has a mirrored expense 1
+	table.mu.Lock()
+	table.mirrors[manualTransactionID(8, 1)] = false
+	table.mu.Unlock()
+
+	created := &models.Expense{Title: "Fuel", Amount: 1200, Date: "2025-03-02", Type: "expense", Currency: "INR"}
+	if err := service.Create(created, 7); err != nil {
+		t.Fatalf("Create of user 7's expense 1: %v", err)
+	}
+	for _, uid := range []uint{7, 8} {
+		if deleted, exists := table.mirrorDeleted(uid, 1); !exists || deleted {
+			t.Errorf("user %d's mirror of expense 1 is missing", uid)
+		}
+	}
+}
+
+// migrationTable answers the mirror migration's queries over users' expenses
+// and the transaction_id of existing mirrors
+type migrationTable struct {
+	mu       sync.Mutex
+	expenses map[uint][]int64 // user ID -> expense IDs in ascending order
+	mirrors  map[string]int   // transaction_id -> times inserted
+	inserts  int
+}
+
+func newMigrationTable(stub *testutil.StubDB, expenses map[uint][]int64) *migrationTable {
+	table := &migrationTable{expenses: expenses, mirrors: make(map[string]int)}
+	stub.On(`FROM "bank_accounts"`, []string{"id", "user_id", "bank_id"}, []driver.Value{int64(50), int64(7), ManualBankID})
+	stub.Handle(`SELECT DISTINCT "user_id" FROM "expenses"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		var users []uint
+		for uid := range table.expenses {
+			users = append(users, uid)
+		}
+		sort.Slice(users, func(i, j int) bool { return users[i] < users[j] })
+		result := testutil.StubResult{Columns: []string{"user_id"}}
+		for _, uid := range users {
+			result.Rows = append(result.Rows, []driver.Value{int64(uid)})
+		}
+		return result, nil
+	})
+	stub.Handle(`user_id = $1 AND id > $2`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		uid, after := uint(toInt64(args[0])), toInt64(args[1])
+		result := testutil.StubResult{Columns: []string{"id", "user_id", "title", "amount", "date", "type"}}
+		for _, id := range table.expenses[uid] {
+			if id > after && len(result.Rows) < expenseMigrationBatchSize {
+				result.Rows = append(result.Rows, []driver.Value{id, int64(uid), "Expense", 100.0, "2025-03-01", "expense"})
+			}
+		}
+		return result, nil
+	})
+	stub.Handle(`SELECT "transaction_id" FROM "transactions"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		result := testutil.StubResult{Columns: []string{"transaction_id"}}
+		for _, arg := range args {
+			if key, ok := arg.(string); ok && table.mirrors[key] > 0 {
+				result.Rows = append(result.Rows, []driver.Value{key})
+			}
+		}
+		return result, nil
+	})
+	stub.Handle(`INSERT INTO "transactions"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		table.mirrors[testutil.InsertedValues(query, args)["transaction_id"].(string)]++
+		table.inserts++
+		return testutil.StubResult{Columns: []string{"id"}, Rows: [][]driver.Value{{int64(table.inserts)}}}, nil
+	})
+	return table
+}
+
+// expenseIDs returns the IDs from first up to n expenses later
+func expenseIDs(first int64, n int) []int64 {
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = first + int64(i)
+	}
+	return ids
+}
+
+func TestMigrateExpensesInBatchesWithoutDuplicates(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	service := NewExpenseService(db, 1)
+	t.Cleanup(service.Close)
+	// User 7 spans three batches; user 8 has a couple of expenses
+	table := newMigrationTable(stub, map[uint][]int64{
+		7: expenseIDs(1, 2*expenseMigrationBatchSize+3),
+		8: expenseIDs(5000, 2),
+	})
+	// One of user 7's expenses was already mirrored
+	table.mirrors[manualTransactionID(7, 10)] = 1
+
+	result, err := service.MigrateAllExpensesToTransactions(context.Background())
+	if err != nil {
+		t.Fatalf("MigrateAllExpensesToTransactions: %v", err)
+	}
+	scanned := 2*expenseMigrationBatchSize + 3 + 2
+	if result.Users != 2 || result.Scanned != scanned || result.Created != scanned-1 {
+		t.Errorf("result = %+v, want 2 users, %d scanned and %d created", result, scanned, scanned-1)
+	}
+	if pages := len(stub.Ran(`user_id = $1 AND id > $2`)); pages != 4 {
+		t.Errorf("read %d pages, want 3 for user 7 and 1 for user 8", pages)
+	}
+	for key, inserted := range table.mirrors {
+		if inserted != 1 {
+			t.Fatalf("%s inserted %d times", key, inserted)
+		}
+	}
+
+	// A re-run finds everything mirrored and inserts nothing
+	inserts := table.inserts
+	again, err := service.MigrateAllExpensesToTransactions(context.Background())
+	if err != nil {
+		t.Fatalf("re-run: %v", err)
+	}
+	if again.Created != 0 || table.inserts != inserts {
+		t.Errorf("re-run created %d mirrors (%d inserts), want none", again.Created, table.inserts-inserts)
+	}
+}
+
+func TestMigrateUserExpensesOnlyTouchesThatUser(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	service := NewExpenseService(db, 1)
+	t.Cleanup(service.Close)
+	table := newMigrationTable(stub, map[uint][]int64{7: expenseIDs(1, 3), 8: expenseIDs(10, 3)})
+
+	result, err := service.MigrateUserExpensesToTransactions(context.Background(), 8)
+	if err != nil {
+		t.Fatalf("MigrateUserExpensesToTransactions: %v", err)
+	}
+	if result.Created != 3 || len(table.mirrors) != 3 {
+		t.Errorf("result = %+v with mirrors %v, want user 8's 3 expenses", result, table.mirrors)
+	}
+	for key := range table.mirrors {
+		if !strings.HasPrefix(key, "MANUAL_8_") {
+			t.Errorf("mirrored %s for another user", key)
+		}
+	}
+}
+
+func TestExpenseWritesInvalidateOnlyThatUsersCaches(t *testing.T) {
+	service, table, _ := newExpenseFixture(t)
+	summary := NewSummaryService(service.DB, testRates, 1)
+	t.Cleanup(summary.Close)
+	service.Summary = summary
+	table.add(expenseRow{id: 1, userID: 7, title: "Groceries", amount: 640, date: "2025-03-01", kind: "expense"})
+
+	seed := func() {
+		service.Cache.Set("expenses:7:0", []models.Expense{})
+		service.Cache.Set("expenses:8:0", []models.Expense{})
+		summary.Cache.Set("summary_lifetime:7:INR", Summary{})
+		summary.Cache.Set("summary_lifetime:8:INR", Summary{})
+	}
+	check := func(write string) {
+		t.Helper()
+		if _, ok := service.Cache.Get("expenses:7:0"); ok {
+			t.Errorf("%s left user 7's expense list cached", write)
+		}
+		if _, ok := summary.Cache.Get("summary_lifetime:7:INR"); ok {
+			t.Errorf("%s left user 7's summary cached", write)
+		}
+		if _, ok := service.Cache.Get("expenses:8:0"); !ok {
+			t.Errorf("%s dropped user 8's expense list", write)
+		}
+		if _, ok := summary.Cache.Get("summary_lifetime:8:INR"); !ok {
+			t.Errorf("%s dropped user 8's summary", write)
+		}
+	}
+
+	seed()
+	if err := service.Create(&models.Expense{Title: "Fuel", Amount: 1200, Date: "2025-03-02", Type: "expense", Currency: "INR"}, 7); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	check("Create")
+
+	seed()
+	if err := service.Delete(1, 7); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	check("Delete")
+}
+
+func TestIsUserCacheKey(t *testing.T) {
+	tests := map[string]bool{
+		"expenses:7:50":                    true,
+		"expense:7:3":                      true,
+		"summary_monthly:7:2025:3:0.0:INR": true,
+		"summary_lifetime:7":               true,
+		"expenses:70:50":                   false,
+		"expenses:17:50":                   false,
+		"expenses":                         false,
+		"7:expenses":                       false,
+	}
+	for key, want := range tests {
+		if got := isUserCacheKey(key, 7); got != want {
+			t.Errorf("isUserCacheKey(%q, 7) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+// hasArg reports whether args contains want
+func hasArg(args []driver.Value, want driver.Value) bool {
+	for _, arg := range args {
+		if arg == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRecategorizeByRule(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`FROM "expenses"`, []string{"id", "title", "category", "subcategory"},
+		[]driver.Value{int64(1), "Uber to airport", utils.CategoryFood, ""},
+		[]driver.Value{int64(2), "UBER trip home", utils.CategoryTravel, "Cabs"},
+		[]driver.Value{int64(3), "Coffee", utils.CategoryFood, ""},
+	)
+	stub.On(`INSERT INTO "category_rules"`, []string{"id"}, []driver.Value{int64(9)})
+	service := NewExpenseService(db, 1)
+	t.Cleanup(service.Close)
+
+	result, err := service.RecategorizeByRule(7, "uber", "travel", "Cabs", false)
+	if err != nil {
+		t.Fatalf("RecategorizeByRule: %v", err)
+	}
+	// Expense 2 already sits in Travel/Cabs and isn't rewritten
+	if result.Matched != 2 || result.Updated != 1 || result.Rule != nil {
+		t.Errorf("result = %+v, want 2 matched, 1 updated and no rule", result)
+	}
+	expenseUpdates := stub.Ran(`UPDATE "expenses"`)
+	if len(expenseUpdates) != 1 || !hasArg(expenseUpdates[0].Args, uint(1)) || hasArg(expenseUpdates[0].Args, uint(2)) ||
+		!hasArg(expenseUpdates[0].Args, utils.CategoryTravel) || !hasArg(expenseUpdates[0].Args, "Cabs") {
+		t.Errorf("expense updates = %+v, want expense 1 moved to Travel/Cabs", expenseUpdates)
+	}
+	mirrorUpdates := stub.Ran(`UPDATE "transactions"`)
+	if len(mirrorUpdates) != 1 || !hasArg(mirrorUpdates[0].Args, manualTransactionID(7, 1)) || !hasArg(mirrorUpdates[0].Args, uint(7)) {
+		t.Errorf("mirror updates = %+v, want user 7's mirror of expense 1", mirrorUpdates)
+	}
+	if n := len(stub.Ran(`INSERT INTO "category_rules"`)); n != 0 {
+		t.Errorf("stored %d rules without save_rule", n)
+	}
+
+	result, err = service.RecategorizeByRule(7, "/^Coffee$/", "Food", "Cafe", true)
+	if err != nil {
+		t.Fatalf("RecategorizeByRule with save: %v", err)
+	}
+	if result.Matched != 1 || result.Updated != 1 || result.Rule == nil || result.Rule.Matcher != "/^Coffee$/" || result.Rule.UserID != 7 {
+		t.Errorf("result = %+v, want expense 3 updated and the rule saved for user 7", result)
+	}
+	inserts := stub.Ran(`INSERT INTO "category_rules"`)
+	if len(inserts) != 1 {
+		t.Fatalf("stored %d rules, want 1", len(inserts))
+	}
+	if row := testutil.InsertedValues(inserts[0].SQL, inserts[0].Args); row["category"] != utils.CategoryFood || row["subcategory"] != "Cafe" {
+		t.Errorf("saved rule = %v", row)
+	}
+}
+
+func TestRecategorizeByRuleRejectsInvalidRules(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	service := NewExpenseService(db, 1)
+	t.Cleanup(service.Close)
+
+	for _, matcher := range []string{"", "/(uber/"} {
+		if _, err := service.RecategorizeByRule(7, matcher, "Travel", "", true); !errors.Is(err, ErrInvalidCategoryRule) && !errors.Is(err, ErrInvalidRuleRegex) {
+			t.Errorf("matcher %q: %v, want a validation error", matcher, err)
+		}
+	}
+	if ran := stub.Ran(`"expenses"`); len(ran) != 0 {
+		t.Errorf("invalid rules touched expenses: %+v", ran)
+	}
+}
+
+// categoryTable keeps the categories of user 7's expenses and of their
+// manual mirrors, answering RecategorizeAll's queries
+type categoryTable struct {
+	expenses map[uint]*categoryRow
+	mirrors  map[uint]string // expense ID to the mirror's category
+	failFor  string          // category whose expense UPDATE fails
+}
+
+// categoryRow is an expense of user 7 with its category
+type categoryRow struct {
+	id                    uint
+	title                 string
+	category, subcategory string
+}
+
+func newCategoryTable(stub *testutil.StubDB, expenses ...categoryRow) *categoryTable {
+	table := &categoryTable{expenses: make(map[uint]*categoryRow), mirrors: make(map[uint]string)}
+	for i := range expenses {
+		table.expenses[expenses[i].id] = &expenses[i]
+		table.mirrors[expenses[i].id] = expenses[i].category
+	}
+	uncategorized := func(e *categoryRow) bool { return e.category == "" || e.category == utils.CategoryOther }
+
+	stub.Handle(`FROM "expenses"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		result := testutil.StubResult{Columns: []string{"id", "title", "type", "category", "subcategory", "user_id"}}
+		for id := uint(1); id <= uint(len(table.expenses)); id++ {
+			if e := table.expenses[id]; uncategorized(e) {
+				result.Rows = append(result.Rows, []driver.Value{int64(e.id), e.title, "expense", e.category, e.subcategory, int64(7)})
+			}
+		}
+		return result, nil
+	})
+	stub.Handle(`UPDATE "expenses"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		category, subcategory := args[0].(string), args[1].(string)
+		if category == table.failFor {
+			return testutil.StubResult{}, errors.New("connection reset")
+		}
+		var affected int64
+		for _, arg := range args[3:] { // after the user ID come the batch's IDs
+			if id, ok := arg.(uint); ok {
+				if e := table.expenses[id]; e != nil && uncategorized(e) {
+					e.category, e.subcategory = category, subcategory
+					affected++
+				}
+			}
+		}
+		return testutil.StubResult{Affected: affected}, nil
+	})
+	stub.Handle(`UPDATE transactions SET category = expenses.category`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		batch := make(map[uint]bool)
+		for _, arg := range args[1:] {
+			batch[arg.(uint)] = true
+		}
+		var affected int64
+		for id, e := range table.expenses {
+			if (len(batch) == 0 || batch[id]) && table.mirrors[id] != e.category {
+				table.mirrors[id] = e.category
+				affected++
+			}
+		}
+		return testutil.StubResult{Affected: affected}, nil
+	})
+	return table
+}
+
+func TestRecategorizeAllKeepsMirrorsInSync(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	table := newCategoryTable(stub,
+		categoryRow{id: 1, title: "Swiggy dinner", category: utils.CategoryOther},
+		categoryRow{id: 2, title: "Uber to airport"},
+		categoryRow{id: 3, title: "Zomato lunch", category: utils.CategoryOther},
+		categoryRow{id: 4, title: "Misc", category: utils.CategoryOther},
+		categoryRow{id: 5, title: "Headphones", category: utils.CategoryShopping},
+	)
+	// Expense 5's mirror drifted before this change
+	table.mirrors[5] = utils.CategoryOther
+	service := NewExpenseService(db, 1)
+	t.Cleanup(service.Close)
+
+	result, err := service.RecategorizeAll(7)
+	if err != nil {
+		t.Fatalf("RecategorizeAll: %v", err)
+	}
+	if result.Matched != 4 || result.Updated != 3 || result.Failed != 0 || result.Synced != 1 {
+		t.Errorf("result = %+v, want 4 matched, 3 updated and the drifted mirror synced", result)
+	}
+	for id, e := range table.expenses {
+		if table.mirrors[id] != e.category {
+			t.Errorf("expense %d in %q, its mirror in %q", id, e.category, table.mirrors[id])
+		}
+	}
+	if table.expenses[1].category != utils.CategoryFood || table.expenses[4].category != utils.CategoryOther {
+		t.Errorf("expense 1 in %q, expense 4 in %q", table.expenses[1].category, table.expenses[4].category)
+	}
+
+	// Running it again changes nothing
+	again, err := service.RecategorizeAll(7)
+	if err != nil || again.Updated != 0 || again.Synced != 0 {
+		t.Errorf("second run = %+v, %v; want nothing updated", again, err)
+	}
+}
+
+func TestRecategorizeAllReportsPartialSuccess(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	table := newCategoryTable(stub,
+		categoryRow{id: 1, title: "Swiggy dinner", category: utils.CategoryOther},
+		categoryRow{id: 2, title: "Uber to airport"},
+	)
+	travel := utils.AutoCategorize("Uber to airport").Category
+	table.failFor = travel
+	service := NewExpenseService(db, 1)
+	t.Cleanup(service.Close)
+
+	result, err := service.RecategorizeAll(7)
+	if err == nil {
+		t.Fatal("RecategorizeAll hid the failed batch")
+	}
+	if result.Updated != 1 || result.Failed != 1 {
+		t.Errorf("result = %+v, want 1 updated and 1 failed", result)
+	}
+	if table.expenses[1].category != utils.CategoryFood || table.mirrors[1] != utils.CategoryFood {
+		t.Errorf("the saved batch left expense %q, mirror %q", table.expenses[1].category, table.mirrors[1])
+	}
+	if table.expenses[2].category != "" || table.mirrors[2] != "" {
+		t.Errorf("the failed batch left expense %q, mirror %q", table.expenses[2].category, table.mirrors[2])
+	}
+
+	// A retry picks up only what failed
+	table.failFor = ""
+	retry, err := service.RecategorizeAll(7)
+	if err != nil || retry.Matched != 1 || retry.Updated != 1 || table.mirrors[2] != travel {
+		t.Errorf("retry = %+v, %v; mirror in %q, want expense 2 moved to %q", retry, err, table.mirrors[2], travel)
+	}
+}
+
+func TestExpenseCreateCarriesTheGeotagIntoTheMirror(t *testing.T) {
+	service, _, stub := newExpenseFixture(t)
+	latitude, longitude := 19.0596, 72.8295
+
+	expense := &models.Expense{
+		Title: "Dinner", Amount: 1800, Date: "2025-03-02", Type: "expense", Currency: "INR",
+		Latitude: &latitude, Longitude: &longitude, Location: " Bandra West ", City: "Mumbai",
+	}
+	if err := service.Create(expense, 7); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	inserts := stub.Ran(`INSERT INTO "expenses"`)
+	values := testutil.InsertedValues(inserts[0].SQL, inserts[0].Args)
+	lat, _ := values["latitude"].(*float64)
+	lng, _ := values["longitude"].(*float64)
+	if lat == nil || *lat != latitude || lng == nil || *lng != longitude || values["location"] != "Bandra West" || values["city"] != "Mumbai" {
+		t.Errorf("stored geotag = %v %v %q %q", lat, lng, values["location"], values["city"])
+	}
+	mirrors := stub.Ran(`INSERT INTO "transactions"`)
+	if len(mirrors) != 1 {
+		t.Fatalf("wrote %d mirrors, want 1", len(mirrors))
+	}
+	if got := testutil.InsertedValues(mirrors[0].SQL, mirrors[0].Args)["location"]; got != "Bandra West" {
+		t.Errorf("mirror location = %q, want the expense's place", got)
+	}
+}
+
+func TestManualLocation(t *testing.T) {
+	tests := []struct {
+		expense models.Expense
+		want    string
+	}{
+		{models.Expense{Location: "Bandra West", City: "Mumbai"}, "Bandra West"},
+		{models.Expense{City: "Mumbai"}, "Mumbai"},
+		{models.Expense{}, "Manual Entry"},
+	}
+	for _, tt := range tests {
+		if got := manualLocation(tt.expense); got != tt.want {
+			t.Errorf("manualLocation(%q, %q) = %q, want %q", tt.expense.Location, tt.expense.City, got, tt.want)
+		}
+	}
+}
+
+func TestExpenseGeotagValidation(t *testing.T) {
+	service, _, stub := newExpenseFixture(t)
+	coordinate := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name                string
+		latitude, longitude *float64
+	}{
+		{"latitude only", coordinate(19.06), nil},
+		{"longitude only", nil, coordinate(72.83)},
+		{"latitude off the globe", coordinate(91), coordinate(72.83)},
+		{"longitude off the globe", coordinate(19.06), coordinate(-180.5)},
+		{"not a number", coordinate(math.NaN()), coordinate(72.83)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expense := &models.Expense{Title: "Dinner", Amount: 1800, Date: "2025-03-02", Type: "expense", Currency: "INR", Latitude: tt.latitude, Longitude: tt.longitude}
+			if err := service.Create(expense, 7); !errors.Is(err, ErrInvalidGeotag) {
+				t.Errorf("Create: %v, want ErrInvalidGeotag", err)
+			}
+			if _, err := service.Patch(3, 7, ExpensePatch{Latitude: tt.latitude, Longitude: tt.longitude}); !errors.Is(err, ErrInvalidGeotag) {
+				t.Errorf("Patch: %v, want ErrInvalidGeotag", err)
+			}
+		})
+	}
+	if len(stub.Ran(`INSERT INTO "expenses"`)) != 0 || len(stub.Ran(`UPDATE "expenses"`)) != 0 {
+		t.Error("an invalid geotag was written")
+	}
+}
+
+func TestPatchSetsTheGeotag(t *testing.T) {
+	service, stub := patchFixture(t)
+	latitude, longitude, city := 18.5204, 73.8567, " Pune "
+
+	if _, err := service.Patch(3, 7, ExpensePatch{Latitude: &latitude, Longitude: &longitude, City: &city}); err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	updates := stub.Ran(`UPDATE "expenses"`)
+	if len(updates) != 1 || !hasArg(updates[0].Args, latitude) || !hasArg(updates[0].Args, longitude) || !hasArg(updates[0].Args, "Pune") {
+		t.Errorf("updates = %+v, want the coordinates and trimmed city written", updates)
+	}
+}
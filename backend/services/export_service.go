@@ -0,0 +1,357 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/internal/repo"
+	"github.com/your-github/expense-tracker-backend/models"
+)
+
+// exportBatchSize is how many rows are loaded per query while exporting
+const exportBatchSize = 500
+
+// ExportService writes everything stored about a user as one JSON document
+type ExportService struct {
+	DB *gorm.DB
+	AA *repo.Repositories // Account Aggregator data; nil leaves bank links and overrides empty
+}
+
+// NewExportService creates a new account data export service
+func NewExportService(db *gorm.DB, aa *repo.Repositories) *ExportService {
+	return &ExportService{DB: db, AA: aa}
+}
+
+// ExportProfile is the user row without credentials
+type ExportProfile struct {
+	ID            uint      `json:"id"`
+	Name          string    `json:"name"`
+	Email         string    `json:"email"`
+	PendingEmail  string    `json:"pending_email,omitempty"`
+	Budget        float64   `json:"budget"`
+	Currency      string    `json:"currency"`
+	Timezone      string    `json:"timezone"`
+	Role          string    `json:"role"`
+	ReportOptIn   bool      `json:"report_opt_in"`
+	ReportCadence string    `json:"report_cadence"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ExportBankAccount is a linked bank account with its numbers masked
+type ExportBankAccount struct {
+	ID                uint       `json:"id"`
+	BankID            string     `json:"bank_id"`
+	AccountNumber     string     `json:"account_number"`
+	AccountHolderName string     `json:"account_holder_name"`
+	MobileNumber      string     `json:"mobile_number"`
+	Status            string     `json:"status"`
+	AccountType       string     `json:"account_type"`
+	IFSCCode          string     `json:"ifsc_code"`
+	BranchName        string     `json:"branch_name"`
+	VerifiedAt        *time.Time `json:"verified_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// ExportTransaction is a bank or manual transaction without its preloaded
+// account and user
+type ExportTransaction struct {
+	ID              uint         `json:"id"`
+	BankAccountID   uint         `json:"bank_account_id"`
+	TransactionID   string       `json:"transaction_id"`
+	TransactionDate time.Time    `json:"transaction_date"`
+	Description     string       `json:"description"`
+	Amount          float64      `json:"amount"`
+	Type            string       `json:"type"`
+	Category        string       `json:"category"`
+	Balance         float64      `json:"balance"`
+	ReferenceNumber string       `json:"reference_number"`
+	MerchantName    string       `json:"merchant_name"`
+	Location        string       `json:"location"`
+	Status          string       `json:"status"`
+	Tags            []models.Tag `json:"tags,omitempty"`
+}
+
+// ExportBankLink is an Account Aggregator bank link without its user and transactions
+type ExportBankLink struct {
+	ID              uuid.UUID  `json:"id"`
+	AAConsentID     string     `json:"aa_consent_id"`
+	FIType          string     `json:"fi_type"`
+	Status          string     `json:"status"`
+	ValidTill       *time.Time `json:"valid_till"`
+	LastRefreshedAt *time.Time `json:"last_refreshed_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// ExportCategoryOverride is an Account Aggregator category override without its user
+type ExportCategoryOverride struct {
+	ID          uuid.UUID `json:"id"`
+	Matcher     string    `json:"matcher"`
+	Category    string    `json:"category"`
+	Subcategory string    `json:"subcategory"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Export writes the user's profile, expenses (including trashed ones),
+// transactions, masked bank accounts, category rules, tags, receipt
+// metadata, budget alerts, bank links and category overrides to w as a
+// single JSON object. Rows are read and written in batches, w is flushed
+// after each batch when it supports it, and ctx is checked between batches
+// so a cancelled request stops early. Nothing is written when the user
+// doesn't exist, in which case gorm.ErrRecordNotFound is returned.
+//
+// Bank links and category overrides belong to the UUID-keyed Account
+// Aggregator account, which is matched to the user by email.
+func (s *ExportService) Export(ctx context.Context, uid uint, w io.Writer) error {
+	db := s.DB.WithContext(ctx)
+
+	var user models.User
+	if err := db.First(&user, uid).Error; err != nil {
+		return err
+	}
+
+	out := newJSONObjectWriter(w)
+	out.field("exported_at", time.Now().UTC())
+	out.field("profile", ExportProfile{
+		ID:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		PendingEmail:  user.PendingEmail,
+		Budget:        user.Budget,
+		Currency:      user.Currency,
+		Timezone:      user.Timezone,
+		Role:          user.Role,
+		ReportOptIn:   user.ReportOptIn,
+		ReportCadence: user.ReportCadence,
+		CreatedAt:     user.CreatedAt,
+	})
+	if err := out.err; err != nil {
+		return err
+	}
+
+	owned := func() *gorm.DB { return db.Where("user_id = ?", uid) }
+	if err := exportRows(ctx, out, "expenses", owned().Unscoped().Preload("Tags"), func(e models.Expense) interface{} {
+		return e
+	}); err != nil {
+		return err
+	}
+	if err := exportRows(ctx, out, "transactions", owned().Preload("Tags"), func(t models.Transaction) interface{} {
+		return ExportTransaction{
+			ID:              t.ID,
+			BankAccountID:   t.BankAccountID,
+			TransactionID:   t.TransactionID,
+			TransactionDate: t.TransactionDate,
+			Description:     t.Description,
+			Amount:          t.Amount,
+			Type:            t.Type,
+			Category:        t.Category,
+			Balance:         t.Balance,
+			ReferenceNumber: t.ReferenceNumber,
+			MerchantName:    t.MerchantName,
+			Location:        t.Location,
+			Status:          t.Status,
+			Tags:            t.Tags,
+		}
+	}); err != nil {
+		return err
+	}
+	if err := exportRows(ctx, out, "bank_accounts", owned(), func(a models.BankAccount) interface{} {
+		return ExportBankAccount{
+			ID:                a.ID,
+			BankID:            a.BankID,
+			AccountNumber:     MaskAccountNumber(a.AccountNumber),
+			AccountHolderName: a.AccountHolderName,
+			MobileNumber:      MaskMobileNumber(a.MobileNumber),
+			Status:            a.Status,
+			AccountType:       a.AccountType,
+			IFSCCode:          a.IFSCCode,
+			BranchName:        a.BranchName,
+			VerifiedAt:        a.VerifiedAt,
+			CreatedAt:         a.CreatedAt,
+		}
+	}); err != nil {
+		return err
+	}
+	if err := exportRows(ctx, out, "category_rules", owned(), func(r models.CategoryRule) interface{} { return r }); err != nil {
+		return err
+	}
+	if err := exportRows(ctx, out, "tags", owned(), func(t models.Tag) interface{} { return t }); err != nil {
+		return err
+	}
+	if err := exportRows(ctx, out, "receipts", owned(), func(r models.Receipt) interface{} { return r }); err != nil {
+		return err
+	}
+	if err := exportRows(ctx, out, "budget_alerts", owned(), func(a models.BudgetAlert) interface{} { return a }); err != nil {
+		return err
+	}
+	if err := s.exportAAData(ctx, out, user.Email); err != nil {
+		return err
+	}
+	return out.close()
+}
+
+// exportAAData writes the bank links and category overrides of the AA
+// account registered with email; both are empty when there is none
+func (s *ExportService) exportAAData(ctx context.Context, out *jsonObjectWriter, email string) error {
+	var links []*domain.BankLink
+	var overrides []*domain.CategoryOverride
+	if s.AA != nil {
+		account, err := s.AA.User.GetByEmail(ctx, email)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+		case err != nil:
+			return err
+		default:
+			if links, err = s.AA.BankLink.GetByUserID(ctx, account.ID); err != nil {
+				return err
+			}
+			if overrides, err = s.AA.CategoryOverride.GetByUserID(ctx, account.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	out.beginArray("bank_links")
+	for _, l := range links {
+		out.item(ExportBankLink{
+			ID:              l.ID,
+			AAConsentID:     l.AAConsentID,
+			FIType:          l.FIType,
+			Status:          l.Status,
+			ValidTill:       l.ValidTill,
+			LastRefreshedAt: l.LastRefreshedAt,
+			CreatedAt:       l.CreatedAt,
+		})
+	}
+	out.endArray()
+
+	out.beginArray("category_overrides")
+	for _, o := range overrides {
+		out.item(ExportCategoryOverride{
+			ID:          o.ID,
+			Matcher:     o.Matcher,
+			Category:    o.Category,
+			Subcategory: o.Subcategory,
+			CreatedAt:   o.CreatedAt,
+		})
+	}
+	out.endArray()
+	out.flush()
+	return out.err
+}
+
+// exportRows writes the rows of query as the array field name, converting
+// each with convert. Rows are loaded exportBatchSize at a time in primary
+// key order.
+func exportRows[T any](ctx context.Context, out *jsonObjectWriter, name string, query *gorm.DB, convert func(T) interface{}) error {
+	out.beginArray(name)
+	var batch []T
+	err := query.FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for _, row := range batch {
+			out.item(convert(row))
+		}
+		out.flush()
+		return out.err
+	}).Error
+	if err != nil {
+		return err
+	}
+	out.endArray()
+	return out.err
+}
+
+// jsonObjectWriter writes a JSON object field by field, so large arrays
+// never have to be held in memory. The first write error sticks in err and
+// turns later writes into no-ops.
+type jsonObjectWriter struct {
+	w       io.Writer
+	err     error
+	fields  int
+	items   int
+	started bool
+}
+
+func newJSONObjectWriter(w io.Writer) *jsonObjectWriter {
+	return &jsonObjectWriter{w: w}
+}
+
+func (o *jsonObjectWriter) write(s string) {
+	if o.err == nil {
+		_, o.err = io.WriteString(o.w, s)
+	}
+}
+
+func (o *jsonObjectWriter) value(v interface{}) {
+	if o.err != nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		o.err = err
+		return
+	}
+	_, o.err = o.w.Write(data)
+}
+
+// key opens the object on first use and writes the field name
+func (o *jsonObjectWriter) key(name string) {
+	switch {
+	case !o.started:
+		o.write("{")
+		o.started = true
+	case o.fields > 0:
+		o.write(",")
+	}
+	o.fields++
+	o.value(name)
+	o.write(":")
+}
+
+func (o *jsonObjectWriter) field(name string, v interface{}) {
+	o.key(name)
+	o.value(v)
+}
+
+func (o *jsonObjectWriter) beginArray(name string) {
+	o.key(name)
+	o.write("[")
+	o.items = 0
+}
+
+func (o *jsonObjectWriter) item(v interface{}) {
+	if o.items > 0 {
+		o.write(",")
+	}
+	o.items++
+	o.value(v)
+}
+
+func (o *jsonObjectWriter) endArray() {
+	o.write("]")
+}
+
+// flush sends what has been written so far when the writer buffers, such
+// as an HTTP response
+func (o *jsonObjectWriter) flush() {
+	if f, ok := o.w.(interface{ Flush() }); ok && o.err == nil {
+		f.Flush()
+	}
+}
+
+// close ends the object and returns the first write error
+func (o *jsonObjectWriter) close() error {
+	if !o.started {
+		o.write("{")
+	}
+	o.write("}")
+	return o.err
+}
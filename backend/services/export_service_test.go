@@ -0,0 +1,200 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/your-github/expense-tracker-backend/internal/core/domain"
+	"github.com/your-github/expense-tracker-backend/internal/repo"
+
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+)
+
+type exportAAUsers struct {
+	repo.UserRepository
+	users map[string]*domain.User
+}
+
+func (r exportAAUsers) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	if user, ok := r.users[email]; ok {
+		return user, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type exportAABankLinks struct {
+	repo.BankLinkRepository
+	links []*domain.BankLink
+}
+
+func (r exportAABankLinks) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.BankLink, error) {
+	var out []*domain.BankLink
+	for _, link := range r.links {
+		if link.UserID == userID {
+			out = append(out, link)
+		}
+	}
+	return out, nil
+}
+
+type exportAAOverrides struct {
+	repo.CategoryOverrideRepository
+	overrides []*domain.CategoryOverride
+}
+
+func (r exportAAOverrides) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.CategoryOverride, error) {
+	var out []*domain.CategoryOverride
+	for _, override := range r.overrides {
+		if override.UserID == userID {
+			out = append(out, override)
+		}
+	}
+	return out, nil
+}
+
+// flushCounter is an export destination that counts flushes
+type flushCounter struct {
+	bytes.Buffer
+	flushes int
+}
+
+func (w *flushCounter) Flush() { w.flushes++ }
+
+// newExportFixture returns an export service over a legacy user with one
+// row of every kind and an AA account under the same email
+func newExportFixture(t *testing.T) (*ExportService, *testutil.StubDB) {
+	t.Helper()
+	db, stub := testutil.NewStubDB(t)
+	now := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	stub.On(`FROM "users"`, []string{"id", "name", "email", "password", "google_id", "currency", "created_at"},
+		[]driver.Value{int64(7), "Asha", "asha@example.com", "$2a$10$secrethashvalue", "google-sub-123", "INR", now})
+	stub.On(`FROM "expenses"`, []string{"id", "title", "amount", "date", "type", "user_id"},
+		[]driver.Value{int64(1), "Groceries", 420.5, "2025-02-28", "expense", int64(7)})
+	stub.On(`FROM "transactions"`, []string{"id", "user_id", "bank_account_id", "transaction_id", "description", "amount", "type", "transaction_date"},
+		[]driver.Value{int64(2), int64(7), int64(3), "TXN-1", "UPI payment", 99.0, "debit", now})
+	stub.On(`FROM "bank_accounts"`, []string{"id", "user_id", "bank_id", "account_number", "account_holder_name", "mobile_number", "idempotency_key"},
+		[]driver.Value{int64(3), int64(7), "HDFC", "123456789012", "Asha", "9876543210", "idem-key-secret"})
+	stub.On(`FROM "category_rules"`, []string{"id", "user_id", "matcher", "category"},
+		[]driver.Value{int64(4), int64(7), "swiggy", "Food"})
+	stub.On(`FROM "tags"`, []string{"id", "user_id", "name"},
+		[]driver.Value{int64(5), int64(7), "work"})
+	stub.On(`FROM "receipts"`, []string{"id", "expense_id", "user_id", "filename", "storage_key"},
+		[]driver.Value{int64(6), int64(1), int64(7), "bill.png", "receipts/7/storage-key-secret"})
+	stub.On(`FROM "budget_alerts"`, []string{"id", "user_id", "month", "threshold"},
+		[]driver.Value{int64(8), int64(7), "2025-02", 0.8})
+
+	account := &domain.User{ID: uuid.New(), Email: "asha@example.com", PasswordHash: "aa-password-hash"}
+	other := uuid.New()
+	aa := &repo.Repositories{
+		User: exportAAUsers{users: map[string]*domain.User{account.Email: account}},
+		BankLink: exportAABankLinks{links: []*domain.BankLink{
+			{ID: uuid.New(), UserID: account.ID, AAConsentID: "consent-1", FIType: "SAVINGS", Status: "ACTIVE"},
+			{ID: uuid.New(), UserID: other, AAConsentID: "consent-other", FIType: "SAVINGS", Status: "ACTIVE"},
+		}},
+		CategoryOverride: exportAAOverrides{overrides: []*domain.CategoryOverride{
+			{ID: uuid.New(), UserID: account.ID, Matcher: "zomato", Category: "Food"},
+		}},
+	}
+	return NewExportService(db, aa), stub
+}
+
+func TestExportContainsEveryDataType(t *testing.T) {
+	service, _ := newExportFixture(t)
+	var out flushCounter
+
+	if err := service.Export(context.Background(), 7, &out); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	var export map[string]json.RawMessage
+	if err := json.Unmarshal(out.Bytes(), &export); err != nil {
+		t.Fatalf("export is not a JSON object: %v\n%s", err, out.String())
+	}
+	for _, section := range []string{"expenses", "transactions", "bank_accounts", "category_rules", "tags", "receipts", "budget_alerts", "bank_links", "category_overrides"} {
+		var rows []json.RawMessage
+		if err := json.Unmarshal(export[section], &rows); err != nil {
+			t.Errorf("%s is not an array: %v", section, err)
+			continue
+		}
+		if len(rows) != 1 {
+			t.Errorf("%s has %d rows, want 1", section, len(rows))
+		}
+	}
+	if !strings.Contains(string(export["profile"]), `"asha@example.com"`) {
+		t.Errorf("profile = %s", export["profile"])
+	}
+	if !strings.Contains(string(export["bank_links"]), "consent-1") {
+		t.Errorf("bank_links = %s", export["bank_links"])
+	}
+	if out.flushes == 0 {
+		t.Error("the export was never flushed while streaming")
+	}
+}
+
+func TestExportLeavesOutSecrets(t *testing.T) {
+	service, _ := newExportFixture(t)
+	var out flushCounter
+
+	if err := service.Export(context.Background(), 7, &out); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	body := out.String()
+	for _, secret := range []string{"secrethashvalue", "aa-password-hash", "google-sub-123", "idem-key-secret", "storage-key-secret", "123456789012", "9876543210", `"password"`} {
+		if strings.Contains(body, secret) {
+			t.Errorf("export contains %q", secret)
+		}
+	}
+}
+
+func TestExportWithoutAAAccount(t *testing.T) {
+	service, _ := newExportFixture(t)
+	service.AA.User = exportAAUsers{}
+	var out bytes.Buffer
+
+	if err := service.Export(context.Background(), 7, &out); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	var export struct {
+		BankLinks         []json.RawMessage `json:"bank_links"`
+		CategoryOverrides []json.RawMessage `json:"category_overrides"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &export); err != nil {
+		t.Fatal(err)
+	}
+	if export.BankLinks == nil || export.CategoryOverrides == nil || len(export.BankLinks)+len(export.CategoryOverrides) != 0 {
+		t.Errorf("want empty AA sections, got %s", out.String())
+	}
+}
+
+func TestExportUnknownUser(t *testing.T) {
+	db, _ := testutil.NewStubDB(t)
+	var out bytes.Buffer
+
+	err := NewExportService(db, nil).Export(context.Background(), 99, &out)
+	if err != gorm.ErrRecordNotFound {
+		t.Fatalf("err = %v, want gorm.ErrRecordNotFound", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("wrote %q for an unknown user", out.String())
+	}
+}
+
+func TestExportStopsWhenCancelled(t *testing.T) {
+	service, _ := newExportFixture(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := service.Export(ctx, 7, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected an error for a cancelled export")
+	}
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+// Thresholds of the local insight rules
+const (
+	insightGoodSavingsRate      = 20.0 // % of income saved
+	insightConcentrationPercent = 40.0 // % of spending in the top category
+	insightSavingsTrendPoints   = 10.0 // savings rate change between the last two months
+	insightHealthyExpenseRatio  = 80.0 // % of income spent
+	insightRecurringSharePct    = 20.0 // % of monthly spending that is recurring
+	insightWeekendSpikeRatio    = 1.5  // weekend vs weekday average daily spend
+)
+
+// InsightsService derives insights from a user's financial data with fixed
+// rules, so insights are available without an AI provider. The same rules
+// back the AI endpoint when the provider fails.
+type InsightsService struct{}
+
+// NewInsightsService creates a new local insights service
+func NewInsightsService() *InsightsService {
+	return &InsightsService{}
+}
+
+// Generate returns the insights whose rules fire for data, in a fixed order
+func (s *InsightsService) Generate(data utils.FinancialData) []utils.Insight {
+	insights := []utils.Insight{savingsRateInsight(data)}
+	for _, rule := range []func(utils.FinancialData) (utils.Insight, bool){
+		savingsTrendInsight,
+		concentrationInsight,
+		recurringInsight,
+		weekendSpendingInsight,
+	} {
+		if insight, ok := rule(data); ok {
+			insights = append(insights, insight)
+		}
+	}
+
+	// Spending anomalies are computed deterministically and always reported
+	for _, anomaly := range data.Anomalies {
+		insights = append(insights, utils.Insight{
+			Type:  "warning",
+			Title: "Unusual Spending in " + anomaly.Category,
+			Description: fmt.Sprintf("You spent %.2f on %s this month, %.1f%% above your 3-month average of %.2f.",
+				anomaly.CurrentAmount, anomaly.Category, anomaly.PercentIncrease, anomaly.TrailingAverage),
+			Suggestion: "Review recent " + anomaly.Category + " transactions to see what drove the increase.",
+		})
+	}
+
+	if insight, ok := financialHealthInsight(data); ok {
+		insights = append(insights, insight)
+	}
+	return insights
+}
+
+// savingsRateInsight praises or flags the overall savings rate
+func savingsRateInsight(data utils.FinancialData) utils.Insight {
+	description := fmt.Sprintf("Your savings rate is %.1f%%.", data.SavingsRate)
+	if data.SavingsRate >= insightGoodSavingsRate {
+		return utils.Insight{
+			Type:        "success",
+			Title:       "Excellent Savings Rate",
+			Description: description,
+			Suggestion:  "Keep up the great work! Consider investing your savings for long-term growth.",
+		}
+	}
+	return utils.Insight{
+		Type:        "warning",
+		Title:       "Savings Rate Alert",
+		Description: description,
+		Suggestion:  "Consider reducing expenses or increasing income to improve your savings rate.",
+	}
+}
+
+// savingsTrendInsight compares the savings rate of the two most recent
+// months with income. MonthlyTrends is newest first.
+func savingsTrendInsight(data utils.FinancialData) (utils.Insight, bool) {
+	if len(data.MonthlyTrends) < 2 {
+		return utils.Insight{}, false
+	}
+	latest, previous := data.MonthlyTrends[0], data.MonthlyTrends[1]
+	if latest.Income <= 0 || previous.Income <= 0 {
+		return utils.Insight{}, false
+	}
+
+	latestRate := utils.SavingsRate(latest.Income, latest.Expenses)
+	previousRate := utils.SavingsRate(previous.Income, previous.Expenses)
+	description := fmt.Sprintf("You saved %.1f%% of your income in %s, compared with %.1f%% in %s.",
+		latestRate, latest.Month, previousRate, previous.Month)
+	switch change := latestRate - previousRate; {
+	case change <= -insightSavingsTrendPoints:
+		return utils.Insight{
+			Type:        "warning",
+			Title:       "Savings Rate Falling",
+			Description: description,
+			Suggestion:  "Check which categories grew this month and trim the ones that aren't essential.",
+		}, true
+	case change >= insightSavingsTrendPoints:
+		return utils.Insight{
+			Type:        "success",
+			Title:       "Savings Rate Improving",
+			Description: description,
+			Suggestion:  "Lock in the gain by moving the extra savings into an investment or emergency fund.",
+		}, true
+	}
+	return utils.Insight{}, false
+}
+
+// concentrationInsight flags spending dominated by a single category
+func concentrationInsight(data utils.FinancialData) (utils.Insight, bool) {
+	if len(data.CategorySpending) == 0 || data.TotalExpenses <= 0 {
+		return utils.Insight{}, false
+	}
+
+	categories := make([]string, 0, len(data.CategorySpending))
+	for category := range data.CategorySpending {
+		categories = append(categories, category)
+	}
+	// Ties go to the alphabetically first category so output is stable
+	sort.Strings(categories)
+	var topCategory string
+	var topAmount float64
+	for _, category := range categories {
+		if amount := data.CategorySpending[category]; amount > topAmount {
+			topCategory, topAmount = category, amount
+		}
+	}
+
+	percentage := topAmount / data.TotalExpenses * 100
+	if percentage <= insightConcentrationPercent {
+		return utils.Insight{}, false
+	}
+	return utils.Insight{
+		Type:        "warning",
+		Title:       "High Category Concentration",
+		Description: fmt.Sprintf("%s accounts for %.1f%% of your spending.", topCategory, percentage),
+		Suggestion:  "Consider diversifying your spending across different categories.",
+	}, true
+}
+
+// recurringInsight totals detected monthly subscriptions and standing
+// instructions, warning when they take a large share of monthly spending
+func recurringInsight(data utils.FinancialData) (utils.Insight, bool) {
+	if data.RecurringCount == 0 || data.RecurringTotal <= 0 {
+		return utils.Insight{}, false
+	}
+
+	description := fmt.Sprintf("%d recurring charges add up to %.2f a month.", data.RecurringCount, data.RecurringTotal)
+	var monthlyExpenses float64
+	if len(data.MonthlyTrends) > 0 {
+		for _, month := range data.MonthlyTrends {
+			monthlyExpenses += month.Expenses
+		}
+		monthlyExpenses /= float64(len(data.MonthlyTrends))
+	}
+	if monthlyExpenses > 0 {
+		share := data.RecurringTotal / monthlyExpenses * 100
+		description = fmt.Sprintf("%d recurring charges add up to %.2f a month, %.1f%% of your average monthly spending.",
+			data.RecurringCount, data.RecurringTotal, share)
+		if share >= insightRecurringSharePct {
+			return utils.Insight{
+				Type:        "warning",
+				Title:       "Recurring Charges Add Up",
+				Description: description,
+				Suggestion:  "Cancel subscriptions you no longer use; they renew whether you notice them or not.",
+			}, true
+		}
+	}
+	return utils.Insight{
+		Type:        "info",
+		Title:       "Recurring Charges",
+		Description: description,
+		Suggestion:  "Review your subscriptions now and then to make sure each one is still worth it.",
+	}, true
+}
+
+// weekendSpendingInsight flags average weekend days costing much more than
+// average weekdays
+func weekendSpendingInsight(data utils.FinancialData) (utils.Insight, bool) {
+	if data.WeekdayAverage <= 0 || data.WeekendAverage < data.WeekdayAverage*insightWeekendSpikeRatio {
+		return utils.Insight{}, false
+	}
+	return utils.Insight{
+		Type:  "tip",
+		Title: "Weekend Spending Spike",
+		Description: fmt.Sprintf("You spend %.2f per weekend day on average, %.1fx the %.2f of a weekday.",
+			data.WeekendAverage, data.WeekendAverage/data.WeekdayAverage, data.WeekdayAverage),
+		Suggestion: "Plan weekend outings and set a weekend budget to keep leisure spending in check.",
+	}, true
+}
+
+// financialHealthInsight praises expenses well below income
+func financialHealthInsight(data utils.FinancialData) (utils.Insight, bool) {
+	if data.TotalIncome <= 0 {
+		return utils.Insight{}, false
+	}
+	expenseRatio := utils.ExpenseRatio(data.TotalIncome, data.TotalExpenses)
+	if expenseRatio >= insightHealthyExpenseRatio {
+		return utils.Insight{}, false
+	}
+	return utils.Insight{
+		Type:        "success",
+		Title:       "Good Financial Health",
+		Description: fmt.Sprintf("Your expenses are %.1f%% of your income.", expenseRatio),
+		Suggestion:  "You have a healthy balance between income and expenses.",
+	}, true
+}
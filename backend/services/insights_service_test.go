@@ -0,0 +1,136 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+func TestFallbackInsightsReportAnomalies(t *testing.T) {
+	monthly := map[string]map[string]float64{
+		"2025-01": {"Food": 1000, "Rent": 15000},
+		"2025-02": {"Food": 1000, "Rent": 15000},
+		"2025-03": {"Food": 1000, "Rent": 15000},
+		"2025-04": {"Food": 2500, "Rent": 15000},
+	}
+	data := utils.FinancialData{
+		TotalIncome:   50000,
+		TotalExpenses: 17500,
+		SavingsRate:   65,
+		Anomalies:     utils.DetectSpendingAnomalies(monthly, time.Date(2025, time.April, 10, 0, 0, 0, 0, time.UTC), 50),
+	}
+
+	var anomalies []utils.Insight
+	for _, insight := range NewInsightsService().Generate(data) {
+		if strings.HasPrefix(insight.Title, "Unusual Spending") {
+			anomalies = append(anomalies, insight)
+		}
+	}
+	if len(anomalies) != 1 {
+		t.Fatalf("got %d anomaly insights, want 1 for Food", len(anomalies))
+	}
+	insight := anomalies[0]
+	if insight.Type != "warning" {
+		t.Errorf("type = %q, want warning", insight.Type)
+	}
+	for _, want := range []string{"Food", "2500.00", "150.0%"} {
+		if !strings.Contains(insight.Title+" "+insight.Description, want) {
+			t.Errorf("insight %+v does not mention %q", insight, want)
+		}
+	}
+}
+
+func TestFallbackInsightsWithoutAnomalies(t *testing.T) {
+	data := utils.FinancialData{TotalIncome: 50000, TotalExpenses: 20000, SavingsRate: 60}
+	for _, insight := range NewInsightsService().Generate(data) {
+		if strings.HasPrefix(insight.Title, "Unusual Spending") {
+			t.Errorf("unexpected anomaly insight %+v", insight)
+		}
+	}
+}
+
+// insightTitles returns the titles of the insights generated for data
+func insightTitles(data utils.FinancialData) map[string]utils.Insight {
+	titles := make(map[string]utils.Insight)
+	for _, insight := range NewInsightsService().Generate(data) {
+		titles[insight.Title] = insight
+	}
+	return titles
+}
+
+func TestInsightRules(t *testing.T) {
+	months := func(rates ...[2]float64) []utils.MonthlyData {
+		var trends []utils.MonthlyData
+		for i, r := range rates {
+			trends = append(trends, utils.MonthlyData{Month: fmt.Sprintf("2025-0%d", len(rates)-i), Income: r[0], Expenses: r[1]})
+		}
+		return trends
+	}
+
+	tests := []struct {
+		name     string
+		data     utils.FinancialData
+		title    string
+		fires    bool
+		wantType string
+	}{
+		{"good savings rate", utils.FinancialData{SavingsRate: 25}, "Excellent Savings Rate", true, "success"},
+		{"poor savings rate", utils.FinancialData{SavingsRate: 5}, "Savings Rate Alert", true, "warning"},
+
+		{"savings falling", utils.FinancialData{MonthlyTrends: months([2]float64{1000, 900}, [2]float64{1000, 500})}, "Savings Rate Falling", true, "warning"},
+		{"savings improving", utils.FinancialData{MonthlyTrends: months([2]float64{1000, 500}, [2]float64{1000, 900})}, "Savings Rate Improving", true, "success"},
+		{"savings steady", utils.FinancialData{MonthlyTrends: months([2]float64{1000, 550}, [2]float64{1000, 500})}, "Savings Rate Falling", false, ""},
+		{"no income last month", utils.FinancialData{MonthlyTrends: months([2]float64{1000, 900}, [2]float64{0, 500})}, "Savings Rate Falling", false, ""},
+		{"a single month", utils.FinancialData{MonthlyTrends: months([2]float64{1000, 900})}, "Savings Rate Falling", false, ""},
+
+		{"one category dominates", utils.FinancialData{TotalExpenses: 1000, CategorySpending: map[string]float64{"Rent": 600, "Food": 400}}, "High Category Concentration", true, "warning"},
+		{"spread spending", utils.FinancialData{TotalExpenses: 1000, CategorySpending: map[string]float64{"Rent": 400, "Food": 300, "Travel": 300}}, "High Category Concentration", false, ""},
+
+		{"recurring is a large share", utils.FinancialData{RecurringCount: 2, RecurringTotal: 300, MonthlyTrends: months([2]float64{0, 1000})}, "Recurring Charges Add Up", true, "warning"},
+		{"recurring is a small share", utils.FinancialData{RecurringCount: 2, RecurringTotal: 100, MonthlyTrends: months([2]float64{0, 1000})}, "Recurring Charges", true, "info"},
+		{"recurring without trends", utils.FinancialData{RecurringCount: 1, RecurringTotal: 499}, "Recurring Charges", true, "info"},
+		{"no recurring charges", utils.FinancialData{}, "Recurring Charges", false, ""},
+
+		{"weekend spike", utils.FinancialData{WeekdayAverage: 100, WeekendAverage: 200}, "Weekend Spending Spike", true, "tip"},
+		{"weekend close to weekdays", utils.FinancialData{WeekdayAverage: 100, WeekendAverage: 120}, "Weekend Spending Spike", false, ""},
+		{"no weekday spending", utils.FinancialData{WeekendAverage: 200}, "Weekend Spending Spike", false, ""},
+
+		{"healthy expense ratio", utils.FinancialData{TotalIncome: 1000, TotalExpenses: 500}, "Good Financial Health", true, "success"},
+		{"high expense ratio", utils.FinancialData{TotalIncome: 1000, TotalExpenses: 900}, "Good Financial Health", false, ""},
+		{"no income", utils.FinancialData{TotalExpenses: 100}, "Good Financial Health", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			insight, fired := insightTitles(tt.data)[tt.title]
+			if fired != tt.fires {
+				t.Fatalf("%q fired = %v, want %v", tt.title, fired, tt.fires)
+			}
+			if fired && insight.Type != tt.wantType {
+				t.Errorf("%q type = %q, want %q", tt.title, insight.Type, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestInsightDescriptionsCarryTheFigures(t *testing.T) {
+	titles := insightTitles(utils.FinancialData{
+		TotalExpenses:    1000,
+		CategorySpending: map[string]float64{"Rent": 500, "Travel": 500}, // a tie goes to the first name
+		RecurringCount:   3,
+		RecurringTotal:   250,
+		WeekdayAverage:   100,
+		WeekendAverage:   250,
+	})
+	for title, want := range map[string]string{
+		"High Category Concentration": "Rent accounts for 50.0%",
+		"Recurring Charges":           "3 recurring charges add up to 250.00",
+		"Weekend Spending Spike":      "250.00 per weekend day on average, 2.5x the 100.00",
+	} {
+		if insight, ok := titles[title]; !ok || !strings.Contains(insight.Description, want) {
+			t.Errorf("%s = %q, want it to mention %q", title, insight.Description, want)
+		}
+	}
+}
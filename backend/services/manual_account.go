@@ -0,0 +1,66 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/your-github/expense-tracker-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ManualBankID is the bank_id of the synthetic account that manual expense
+// mirrors belong to. Each user has at most one such account.
+const ManualBankID = "MANUAL"
+
+// manualBankAccountFor builds the synthetic manual account of a user
+func manualBankAccountFor(uid uint) models.BankAccount {
+	return models.BankAccount{
+		UserID:            uid,
+		BankID:            ManualBankID,
+		AccountNumber:     ManualBankID,
+		AccountHolderName: "Manual Entry",
+		Status:            "ACTIVE",
+		AccountType:       "MANUAL",
+	}
+}
+
+// EnsureManualBankAccount returns the ID of the user's manual account,
+// creating it on first use and reviving it if it was soft-deleted. A unique
+// index on (user_id) for MANUAL rows makes concurrent first calls converge on
+// one account.
+func EnsureManualBankAccount(tx *gorm.DB, uid uint) (uint, error) {
+	var account models.BankAccount
+	err := tx.Unscoped().Where("user_id = ? AND bank_id = ?", uid, ManualBankID).Limit(1).Find(&account).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up manual account: %w", err)
+	}
+	if account.ID != 0 {
+		if account.DeletedAt.Valid {
+			if err := tx.Unscoped().Model(&account).Update("deleted_at", nil).Error; err != nil {
+				return 0, fmt.Errorf("failed to restore manual account: %w", err)
+			}
+		}
+		return account.ID, nil
+	}
+
+	// The conflict target repeats the partial index predicate as a literal:
+	// Postgres cannot match the index against a bound parameter
+	account = manualBankAccountFor(uid)
+	err = tx.Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "user_id"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "bank_id = '" + ManualBankID + "'"}}},
+		DoNothing:   true,
+	}).Create(&account).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to create manual account: %w", err)
+	}
+	if account.ID != 0 {
+		return account.ID, nil
+	}
+
+	// Lost the race to a concurrent insert; use the winner's row
+	if err := tx.Where("user_id = ? AND bank_id = ?", uid, ManualBankID).First(&account).Error; err != nil {
+		return 0, fmt.Errorf("failed to look up manual account: %w", err)
+	}
+	return account.ID, nil
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+	"github.com/your-github/expense-tracker-backend/models"
+)
+
+var manualAccountColumns = []string{"id", "user_id", "bank_id", "deleted_at"}
+
+func TestEnsureManualBankAccountCreatesOnce(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	var created map[string]driver.Value
+	stub.Handle(`INSERT INTO "bank_accounts"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		created = testutil.InsertedValues(query, args)
+		return testutil.StubResult{Columns: []string{"id"}, Rows: [][]driver.Value{{int64(50)}}}, nil
+	})
+
+	id, err := EnsureManualBankAccount(db, 7)
+	if err != nil || id != 50 {
+		t.Fatalf("first call = %d, %v; want the new account 50", id, err)
+	}
+	if created["bank_id"] != ManualBankID || created["account_holder_name"] != "Manual Entry" || toInt64(created["user_id"]) != 7 {
+		t.Errorf("created %v, want user 7's Manual Entry account", created)
+	}
+	insert := stub.Ran(`INSERT INTO "bank_accounts"`)[0].SQL
+	if !strings.Contains(insert, `ON CONFLICT ("user_id")`) || !strings.Contains(insert, `WHERE bank_id = 'MANUAL' DO NOTHING`) {
+		t.Errorf("insert %s doesn't target the partial unique index", insert)
+	}
+
+	// Once it exists it is reused
+	stub.On(`FROM "bank_accounts"`, manualAccountColumns, []driver.Value{int64(50), int64(7), ManualBankID, nil})
+	if id, err := EnsureManualBankAccount(db, 7); err != nil || id != 50 {
+		t.Errorf("second call = %d, %v; want 50", id, err)
+	}
+	if n := len(stub.Ran(`INSERT INTO "bank_accounts"`)); n != 1 {
+		t.Errorf("inserted %d manual accounts, want 1", n)
+	}
+}
+
+func TestEnsureManualBankAccountRevivesDeletedAccount(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`FROM "bank_accounts"`, manualAccountColumns, []driver.Value{int64(50), int64(7), ManualBankID, time.Now()})
+
+	if id, err := EnsureManualBankAccount(db, 7); err != nil || id != 50 {
+		t.Fatalf("EnsureManualBankAccount = %d, %v; want 50", id, err)
+	}
+	restore := stub.Ran(`UPDATE "bank_accounts" SET "deleted_at"`)
+	if len(restore) != 1 || restore[0].Args[0] != nil {
+		t.Errorf("ran %+v, want deleted_at cleared", restore)
+	}
+	if len(stub.Ran(`INSERT INTO "bank_accounts"`)) != 0 {
+		t.Error("created a second manual account instead of reviving the first")
+	}
+}
+
+func TestEnsureManualBankAccountLosingTheRaceUsesTheWinner(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	lookups := 0
+	stub.Handle(`FROM "bank_accounts"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		lookups++
+		result := testutil.StubResult{Columns: manualAccountColumns}
+		// A concurrent request creates the account between the lookup and
+		// the insert, which then does nothing
+		if lookups > 1 {
+			result.Rows = [][]driver.Value{{int64(51), int64(7), ManualBankID, nil}}
+		}
+		return result, nil
+	})
+	stub.On(`INSERT INTO "bank_accounts"`, []string{"id"})
+
+	if id, err := EnsureManualBankAccount(db, 7); err != nil || id != 51 {
+		t.Errorf("EnsureManualBankAccount = %d, %v; want the concurrent request's account 51", id, err)
+	}
+}
+
+func TestManualMirrorBelongsToManualAccount(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`FROM "bank_accounts"`, manualAccountColumns, []driver.Value{int64(50), int64(7), ManualBankID, nil})
+	var mirror map[string]driver.Value
+	stub.Handle(`INSERT INTO "transactions"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		mirror = testutil.InsertedValues(query, args)
+		return testutil.StubResult{Columns: []string{"id"}, Rows: [][]driver.Value{{int64(1)}}}, nil
+	})
+
+	expense := models.Expense{UserID: 7, Title: "Chai", Amount: 20, Date: "2025-03-01", Type: "expense"}
+	expense.ID = 3
+	if err := upsertManualTransaction(db, expense); err != nil {
+		t.Fatalf("upsertManualTransaction: %v", err)
+	}
+	if toInt64(mirror["bank_account_id"]) != 50 || mirror["transaction_id"] != manualTransactionID(7, 3) {
+		t.Errorf("mirror = %v, want %s in account 50", mirror, manualTransactionID(7, 3))
+	}
+	// Mirrors written before the account existed move onto it on update
+	if upsert := stub.Ran(`INSERT INTO "transactions"`)[0].SQL; !strings.Contains(upsert, `"bank_account_id"="excluded"."bank_account_id"`) {
+		t.Errorf("upsert %s doesn't update the account", upsert)
+	}
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/your-github/expense-tracker-backend/models"
+)
+
+// DefaultReceiptMaxSize is used when no size limit is configured
+const DefaultReceiptMaxSize = 5 * 1024 * 1024
+
+var (
+	ErrReceiptTooLarge        = errors.New("receipt file is too large")
+	ErrReceiptEmpty           = errors.New("receipt file is empty")
+	ErrReceiptType            = errors.New("receipt must be a JPEG, PNG or WebP image or a PDF")
+	ErrReceiptNotFound        = errors.New("receipt not found")
+	ErrReceiptExpenseNotFound = errors.New("expense not found")
+)
+
+// receiptExtensions maps the accepted (sniffed) content types to the file
+// extension used in storage keys
+var receiptExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+type ReceiptService struct {
+	DB      *gorm.DB
+	Store   ReceiptStore
+	MaxSize int64 // bytes
+}
+
+// NewReceiptService creates a receipt service; maxSize <= 0 uses DefaultReceiptMaxSize
+func NewReceiptService(db *gorm.DB, store ReceiptStore, maxSize int64) *ReceiptService {
+	if maxSize <= 0 {
+		maxSize = DefaultReceiptMaxSize
+	}
+	return &ReceiptService{DB: db, Store: store, MaxSize: maxSize}
+}
+
+// ownedExpense checks the expense exists and belongs to uid
+func (s *ReceiptService) ownedExpense(uid, expenseID uint) error {
+	var count int64
+	if err := s.DB.Model(&models.Expense{}).Where("id = ? AND user_id = ?", expenseID, uid).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrReceiptExpenseNotFound
+	}
+	return nil
+}
+
+// Upload stores a receipt for one of the user's expenses. At most MaxSize+1
+// bytes are read from r; the content type is sniffed from the bytes rather
+// than trusted from the client.
+func (s *ReceiptService) Upload(ctx context.Context, uid, expenseID uint, filename string, r io.Reader) (models.Receipt, error) {
+	if err := s.ownedExpense(uid, expenseID); err != nil {
+		return models.Receipt{}, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, s.MaxSize+1))
+	if err != nil {
+		return models.Receipt{}, err
+	}
+	if int64(len(data)) > s.MaxSize {
+		return models.Receipt{}, ErrReceiptTooLarge
+	}
+	if len(data) == 0 {
+		return models.Receipt{}, ErrReceiptEmpty
+	}
+
+	contentType := http.DetectContentType(data)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	ext, ok := receiptExtensions[contentType]
+	if !ok {
+		return models.Receipt{}, ErrReceiptType
+	}
+
+	receipt := models.Receipt{
+		ExpenseID:   expenseID,
+		UserID:      uid,
+		Filename:    sanitizeReceiptFilename(filename, ext),
+		ContentType: contentType,
+		StorageKey:  fmt.Sprintf("receipts/%d/%d/%s%s", uid, expenseID, uuid.NewString(), ext),
+		Size:        int64(len(data)),
+	}
+
+	if err := s.Store.Put(ctx, receipt.StorageKey, data, contentType); err != nil {
+		return models.Receipt{}, fmt.Errorf("failed to store receipt: %w", err)
+	}
+	if err := s.DB.WithContext(ctx).Create(&receipt).Error; err != nil {
+		// Don't leave an orphaned file behind
+		_ = s.Store.Delete(ctx, receipt.StorageKey)
+		return models.Receipt{}, err
+	}
+	return receipt, nil
+}
+
+// List returns the receipts attached to one of the user's expenses
+func (s *ReceiptService) List(uid, expenseID uint) ([]models.Receipt, error) {
+	if err := s.ownedExpense(uid, expenseID); err != nil {
+		return nil, err
+	}
+	receipts := []models.Receipt{}
+	err := s.DB.Where("expense_id = ? AND user_id = ?", expenseID, uid).Order("created_at").Find(&receipts).Error
+	return receipts, err
+}
+
+// Open returns a receipt's metadata and contents. The receipt must belong to
+// the given expense and user; the caller closes the reader.
+func (s *ReceiptService) Open(ctx context.Context, uid, expenseID, receiptID uint) (models.Receipt, io.ReadCloser, error) {
+	var receipt models.Receipt
+	err := s.DB.Where("id = ? AND expense_id = ? AND user_id = ?", receiptID, expenseID, uid).First(&receipt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return receipt, nil, ErrReceiptNotFound
+	}
+	if err != nil {
+		return receipt, nil, err
+	}
+
+	body, err := s.Store.Get(ctx, receipt.StorageKey)
+	if errors.Is(err, ErrReceiptObjectNotFound) {
+		return receipt, nil, ErrReceiptNotFound
+	}
+	if err != nil {
+		return receipt, nil, fmt.Errorf("failed to read receipt: %w", err)
+	}
+	return receipt, body, nil
+}
+
+// sanitizeReceiptFilename keeps the base name of the client's filename for
+// display, falling back to "receipt<ext>"
+func sanitizeReceiptFilename(name, ext string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" {
+		return "receipt" + ext
+	}
+	if len(name) > 255 {
+		name = name[len(name)-255:]
+	}
+	return name
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/your-github/expense-tracker-backend/config"
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+)
+
+// pngReceipt is a payload the content sniffer reports as image/png
+var pngReceipt = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 64)...)
+
+var receiptColumns = []string{"id", "expense_id", "user_id", "filename", "content_type", "storage_key", "size"}
+
+// receiptTable keeps the receipts rows the service writes; expense 3 belongs
+// to user 7
+type receiptTable struct {
+	mu   sync.Mutex
+	rows []map[string]driver.Value
+}
+
+func newReceiptFixture(t *testing.T, maxSize int64) (*ReceiptService, *receiptTable, *testutil.StubDB) {
+	t.Helper()
+	db, stub := testutil.NewStubDB(t)
+	table := &receiptTable{}
+
+	stub.Handle(`FROM "expenses"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		count := int64(0)
+		if args[0] == uint(3) && args[1] == uint(7) {
+			count = 1
+		}
+		return testutil.StubResult{Columns: []string{"count"}, Rows: [][]driver.Value{{count}}}, nil
+	})
+	stub.Handle(`INSERT INTO "receipts"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		row := testutil.InsertedValues(query, args)
+		row["id"] = int64(len(table.rows) + 1)
+		table.rows = append(table.rows, row)
+		return testutil.StubResult{Columns: []string{"id"}, Rows: [][]driver.Value{{row["id"]}}}, nil
+	})
+	stub.Handle(`FROM "receipts"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		result := testutil.StubResult{Columns: receiptColumns}
+		for _, row := range table.rows {
+			if row["id"] == int64(args[0].(uint)) && row["expense_id"] == args[1] && row["user_id"] == args[2] {
+				result.Rows = append(result.Rows, []driver.Value{
+					row["id"], row["expense_id"], row["user_id"], row["filename"], row["content_type"], row["storage_key"], row["size"],
+				})
+			}
+		}
+		return result, nil
+	})
+
+	store := &LocalReceiptStore{Dir: t.TempDir()}
+	return NewReceiptService(db, store, maxSize), table, stub
+}
+
+func TestReceiptUploadAndOpenRoundTrip(t *testing.T) {
+	service, table, _ := newReceiptFixture(t, 1024)
+
+	receipt, err := service.Upload(context.Background(), 7, 3, `C:\Users\asha\taxi "May".png`, bytes.NewReader(pngReceipt))
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if receipt.ContentType != "image/png" || receipt.Size != int64(len(pngReceipt)) || receipt.Filename != "taxi May.png" {
+		t.Errorf("receipt = %+v, want a sniffed PNG with the sanitized base name", receipt)
+	}
+	if !strings.HasPrefix(receipt.StorageKey, "receipts/7/3/") || !strings.HasSuffix(receipt.StorageKey, ".png") {
+		t.Errorf("storage key %q isn't scoped to the user and expense", receipt.StorageKey)
+	}
+	if len(table.rows) != 1 {
+		t.Fatalf("stored %d receipt rows, want 1", len(table.rows))
+	}
+
+	opened, body, err := service.Open(context.Background(), 7, 3, receipt.ID)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer body.Close()
+	data, _ := io.ReadAll(body)
+	if !bytes.Equal(data, pngReceipt) || opened.StorageKey != receipt.StorageKey {
+		t.Errorf("opened %d bytes from %q, want the uploaded file", len(data), opened.StorageKey)
+	}
+
+	// Another user can't open it, even knowing the IDs
+	if _, _, err := service.Open(context.Background(), 8, 3, receipt.ID); !errors.Is(err, ErrReceiptNotFound) {
+		t.Errorf("Open as another user: %v, want ErrReceiptNotFound", err)
+	}
+}
+
+func TestReceiptUploadRejections(t *testing.T) {
+	service, table, _ := newReceiptFixture(t, 1024)
+
+	tests := []struct {
+		name string
+		uid  uint
+		data []byte
+		want error
+	}{
+		{"oversized", 7, append(pngReceipt, make([]byte, 1024)...), ErrReceiptTooLarge},
+		{"empty", 7, nil, ErrReceiptEmpty},
+		{"not an image", 7, []byte("#!/bin/sh\necho hi\n"), ErrReceiptType},
+		{"html", 7, []byte("<html><script>alert(1)</script></html>"), ErrReceiptType},
+		{"another user's expense", 8, pngReceipt, ErrReceiptExpenseNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.Upload(context.Background(), tt.uid, 3, "receipt.png", bytes.NewReader(tt.data)); !errors.Is(err, tt.want) {
+				t.Errorf("Upload: %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if len(table.rows) != 0 {
+		t.Errorf("rejected uploads stored %d rows", len(table.rows))
+	}
+	files, _ := filepath.Glob(filepath.Join(service.Store.(*LocalReceiptStore).Dir, "receipts", "*", "*", "*"))
+	if len(files) != 0 {
+		t.Errorf("rejected uploads left files behind: %v", files)
+	}
+}
+
+func TestReceiptUploadRemovesFileWhenInsertFails(t *testing.T) {
+	service, _, stub := newReceiptFixture(t, 1024)
+	stub.Fail(`INSERT INTO "receipts"`, errors.New("connection reset"))
+
+	if _, err := service.Upload(context.Background(), 7, 3, "receipt.png", bytes.NewReader(pngReceipt)); err == nil {
+		t.Fatal("Upload succeeded although the insert failed")
+	}
+	files, _ := filepath.Glob(filepath.Join(service.Store.(*LocalReceiptStore).Dir, "receipts", "7", "3", "*"))
+	if len(files) != 0 {
+		t.Errorf("orphaned files left in the store: %v", files)
+	}
+}
+
+func TestLocalReceiptStoreRejectsEscapingKeys(t *testing.T) {
+	dir := t.TempDir()
+	store := &LocalReceiptStore{Dir: filepath.Join(dir, "store")}
+	for _, key := range []string{"../outside.png", "/etc/passwd", "receipts/../../outside.png", `receipts\x.png`, ""} {
+		if err := store.Put(context.Background(), key, pngReceipt, "image/png"); err == nil {
+			t.Errorf("Put(%q) succeeded", key)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "outside.png")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("a key escaped the store's directory")
+	}
+	if _, err := store.Get(context.Background(), "receipts/missing.png"); !errors.Is(err, ErrReceiptObjectNotFound) {
+		t.Errorf("Get of a missing key: %v, want ErrReceiptObjectNotFound", err)
+	}
+}
+
+// fakeS3 is a path-style S3 endpoint keeping objects in memory; it requires
+// a SigV4 Authorization header for the configured access key
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test-access/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = data
+		f.types[r.URL.Path] = r.Header.Get("Content-Type")
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodGet, http.MethodHead:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `<Error><Code>NoSuchKey</Code><Message>missing</Message></Error>`)
+			return
+		}
+		w.Header().Set("Content-Type", f.types[r.URL.Path])
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestS3ReceiptStoreRoundTrip(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}, types: map[string]string{}}
+	server := httptest.NewTLSServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := newS3ReceiptStore(config.ReceiptsConfig{
+		S3Endpoint:  server.URL,
+		S3Region:    "us-east-1",
+		S3Bucket:    "receipts",
+		S3AccessKey: "test-access",
+		S3SecretKey: "test-secret",
+	}, server.Client().Transport)
+	if err != nil {
+		t.Fatalf("newS3ReceiptStore: %v", err)
+	}
+	ctx := context.Background()
+	key := "receipts/7/3/taxi may.png"
+
+	if err := store.Put(ctx, key, pngReceipt, "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, ok := fake.objects["/receipts/"+key]; !ok {
+		t.Errorf("objects = %v, want a path-style key under the bucket", fake.objects)
+	}
+
+	body, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, err := io.ReadAll(body)
+	body.Close()
+	if err != nil || !bytes.Equal(data, pngReceipt) {
+		t.Errorf("Get returned %d bytes (%v), want the uploaded receipt", len(data), err)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrReceiptObjectNotFound) {
+		t.Errorf("Get after Delete: %v, want ErrReceiptObjectNotFound", err)
+	}
+	if err := store.Put(ctx, "../outside.png", pngReceipt, "image/png"); err == nil {
+		t.Error("Put accepted a key escaping the bucket prefix")
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/your-github/expense-tracker-backend/config"
+)
+
+// ErrReceiptObjectNotFound is returned by a ReceiptStore for unknown keys
+var ErrReceiptObjectNotFound = errors.New("receipt file not found")
+
+// ReceiptStore persists receipt files by key
+type ReceiptStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewReceiptStoreFromConfig builds the store selected by cfg.Storage
+func NewReceiptStoreFromConfig(cfg config.ReceiptsConfig) (ReceiptStore, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Storage)) {
+	case "", "local":
+		return &LocalReceiptStore{Dir: cfg.Dir}, nil
+	case "s3":
+		if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+			return nil, errors.New("s3 receipt storage requires RECEIPTS_S3_ENDPOINT and RECEIPTS_S3_BUCKET")
+		}
+		store, err := newS3ReceiptStore(cfg, nil)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown receipt storage %q", cfg.Storage)
+	}
+}
+
+// validReceiptKey rejects keys that could escape the store's root
+func validReceiptKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// LocalReceiptStore keeps receipts as files under Dir
+type LocalReceiptStore struct {
+	Dir string
+}
+
+func (s *LocalReceiptStore) path(key string) (string, error) {
+	if !validReceiptKey(key) {
+		return "", fmt.Errorf("invalid receipt key %q", key)
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes the file via a temp file so readers never see a partial upload
+func (s *LocalReceiptStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalReceiptStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrReceiptObjectNotFound
+	}
+	return f, err
+}
+
+func (s *LocalReceiptStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// S3ReceiptStore keeps receipts in an S3-compatible bucket using
+// path-style requests
+type S3ReceiptStore struct {
+	Bucket string
+	Client *minio.Client
+}
+
+// newS3ReceiptStore connects to cfg.S3Endpoint; a nil transport uses the
+// client's default
+func newS3ReceiptStore(cfg config.ReceiptsConfig, transport http.RoundTripper) (*S3ReceiptStore, error) {
+	endpoint, err := url.Parse(strings.TrimRight(cfg.S3Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid RECEIPTS_S3_ENDPOINT %q", cfg.S3Endpoint)
+	}
+	client, err := minio.New(endpoint.Host, &minio.Options{
+		Creds:        credentials.NewStaticV4(cfg.S3AccessKey, cfg.S3SecretKey, ""),
+		Secure:       endpoint.Scheme == "https",
+		Region:       cfg.S3Region,
+		BucketLookup: minio.BucketLookupPath,
+		Transport:    transport,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &S3ReceiptStore{Bucket: cfg.S3Bucket, Client: client}, nil
+}
+
+func (s *S3ReceiptStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if !validReceiptKey(key) {
+		return fmt.Errorf("invalid receipt key %q", key)
+	}
+	_, err := s.Client.PutObject(ctx, s.Bucket, key, bytes.NewReader(data), int64(len(data)),
+		minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *S3ReceiptStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if !validReceiptKey(key) {
+		return nil, fmt.Errorf("invalid receipt key %q", key)
+	}
+	obj, err := s.Client.GetObject(ctx, s.Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// GetObject is lazy; Stat surfaces a missing key before the caller reads
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+			return nil, ErrReceiptObjectNotFound
+		}
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *S3ReceiptStore) Delete(ctx context.Context, key string) error {
+	if !validReceiptKey(key) {
+		return fmt.Errorf("invalid receipt key %q", key)
+	}
+	err := s.Client.RemoveObject(ctx, s.Bucket, key, minio.RemoveObjectOptions{})
+	if err != nil && minio.ToErrorResponse(err).StatusCode != http.StatusNotFound {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	aaservices "github.com/your-github/expense-tracker-backend/internal/core/services"
+	"github.com/your-github/expense-tracker-backend/models"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+// Reconciliation tuning
+const (
+	DefaultReconcileAmountTolerance = 1.0 // amounts this far apart still match
+	DefaultReconcileDayTolerance    = 2   // calendar days a bank posting may lag the expense
+	MaxReconcileDayTolerance        = 7
+	reconcileLookbackDays           = 90
+)
+
+// Ways of resolving a duplicate pair
+const (
+	// ReconcileMerge keeps the expense but takes the bank transaction's
+	// amount and date, then removes the bank transaction
+	ReconcileMerge = "merge"
+	// ReconcileKeepExpense removes the bank transaction, leaving the expense as typed
+	ReconcileKeepExpense = "keep_expense"
+	// ReconcileKeepTransaction moves the expense to the trash, leaving the
+	// bank transaction
+	ReconcileKeepTransaction = "keep_transaction"
+)
+
+var (
+	// ErrInvalidReconcileAction is returned for an unknown resolution
+	ErrInvalidReconcileAction = errors.New(`action must be "merge", "keep_expense" or "keep_transaction"`)
+	// ErrNotBankTransaction is returned when the transaction to reconcile is
+	// itself the mirror of a manual expense
+	ErrNotBankTransaction = errors.New("transaction is not a bank transaction")
+)
+
+// DuplicateCandidate is a manual expense and a bank transaction that likely
+// record the same payment
+type DuplicateCandidate struct {
+	Expense          models.Expense     `json:"expense"`
+	Transaction      models.Transaction `json:"transaction"`
+	AmountDifference float64            `json:"amount_difference"`
+	DaysApart        int                `json:"days_apart"`
+}
+
+// ReconcileService finds manual expenses that were also imported from a
+// linked bank account, which would otherwise be counted twice in the
+// transaction views, and resolves them
+type ReconcileService struct {
+	DB         *gorm.DB
+	Expenses   *ExpenseService
+	Normalizer *aaservices.Normalizer
+}
+
+// NewReconcileService creates a reconciliation service comparing merchants
+// with normalizer
+func NewReconcileService(db *gorm.DB, expenses *ExpenseService, normalizer *aaservices.Normalizer) *ReconcileService {
+	return &ReconcileService{DB: db, Expenses: expenses, Normalizer: normalizer}
+}
+
+// manualTransactionPattern matches the transaction_id of manual expense mirrors
+const manualTransactionPattern = `MANUAL\_%`
+
+// FindDuplicates pairs the user's recent manual expenses with bank
+// transactions of the same type whose amount is within amountTolerance,
+// posted within dayTolerance calendar days and naming the same merchant.
+// Each expense and transaction appears in at most one pair, the closest.
+func (s *ReconcileService) FindDuplicates(ctx context.Context, uid uint, amountTolerance float64, dayTolerance int) ([]DuplicateCandidate, error) {
+	loc := UserLocation(s.DB.WithContext(ctx), uid)
+	since := time.Now().In(loc).AddDate(0, 0, -reconcileLookbackDays-dayTolerance)
+
+	// Bank transactions carry no currency, so only expenses in the default
+	// currency can be compared with them
+	var expenses []models.Expense
+	if err := s.DB.WithContext(ctx).
+		Where("user_id = ? AND date >= ?", uid, since.Format("2006-01-02")).
+		Where("COALESCE(NULLIF(currency, ''), ?) = ?", utils.DefaultCurrency, utils.DefaultCurrency).
+		Find(&expenses).Error; err != nil {
+		return nil, err
+	}
+	var transactions []models.Transaction
+	if err := activeAccountTransactions(s.DB.WithContext(ctx)).
+		Where("user_id = ? AND transaction_date >= ? AND transaction_id NOT LIKE ?", uid, since, manualTransactionPattern).
+		Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+
+	var candidates []DuplicateCandidate
+	for _, expense := range expenses {
+		expenseDate, err := time.ParseInLocation("2006-01-02", expense.Date, loc)
+		if err != nil {
+			continue
+		}
+		transactionType, err := TransactionTypeFor(expense.Type)
+		if err != nil {
+			continue
+		}
+		for _, txn := range transactions {
+			if txn.Type != transactionType {
+				continue
+			}
+			amountDifference := math.Abs(expense.Amount - txn.Amount)
+			if amountDifference > amountTolerance {
+				continue
+			}
+			daysApart := calendarDaysApart(expenseDate, txn.TransactionDate.In(loc))
+			if daysApart > dayTolerance {
+				continue
+			}
+			if !s.Normalizer.SameMerchant(expense.Title, txn.MerchantName) && !s.Normalizer.SameMerchant(expense.Title, txn.Description) {
+				continue
+			}
+			candidates = append(candidates, DuplicateCandidate{
+				Expense:          expense,
+				Transaction:      txn,
+				AmountDifference: math.Round(amountDifference*100) / 100,
+				DaysApart:        daysApart,
+			})
+		}
+	}
+
+	// Closest pairs claim their expense and transaction first
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].DaysApart != candidates[j].DaysApart {
+			return candidates[i].DaysApart < candidates[j].DaysApart
+		}
+		return candidates[i].AmountDifference < candidates[j].AmountDifference
+	})
+	usedExpenses := make(map[uint]bool)
+	usedTransactions := make(map[uint]bool)
+	duplicates := make([]DuplicateCandidate, 0)
+	for _, candidate := range candidates {
+		if usedExpenses[candidate.Expense.ID] || usedTransactions[candidate.Transaction.ID] {
+			continue
+		}
+		usedExpenses[candidate.Expense.ID] = true
+		usedTransactions[candidate.Transaction.ID] = true
+		duplicates = append(duplicates, candidate)
+	}
+	return duplicates, nil
+}
+
+// calendarDaysApart returns how many calendar days separate a and b, which
+// must be in the same location
+func calendarDaysApart(a, b time.Time) int {
+	dayA := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	dayB := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(math.Abs(dayA.Sub(dayB).Hours() / 24))
+}
+
+// Resolve settles a duplicate pair with action, one of ReconcileMerge,
+// ReconcileKeepExpense or ReconcileKeepTransaction. Summaries are computed
+// from expenses, so they change only when the expense is merged or trashed;
+// their caches are invalidated either way. Returns gorm.ErrRecordNotFound
+// when the user has no such expense or transaction.
+func (s *ReconcileService) Resolve(ctx context.Context, uid, expenseID, transactionID uint, action string) error {
+	switch action {
+	case ReconcileMerge, ReconcileKeepExpense, ReconcileKeepTransaction:
+	default:
+		return ErrInvalidReconcileAction
+	}
+
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var expense models.Expense
+		if err := tx.Where("id = ? AND user_id = ?", expenseID, uid).First(&expense).Error; err != nil {
+			return err
+		}
+		var txn models.Transaction
+		if err := tx.Where("id = ? AND user_id = ?", transactionID, uid).First(&txn).Error; err != nil {
+			return err
+		}
+		if isManualTransaction(txn) {
+			return ErrNotBankTransaction
+		}
+
+		switch action {
+		case ReconcileKeepTransaction:
+			if err := tx.Delete(&models.Expense{}, "id = ? AND user_id = ?", expense.ID, uid).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&models.Transaction{}, "transaction_id = ? AND user_id = ?", manualTransactionID(uid, expense.ID), uid).Error
+		case ReconcileMerge:
+			expense.Amount = txn.Amount
+			expense.Date = txn.TransactionDate.In(UserLocation(tx, uid)).Format("2006-01-02")
+			if err := tx.Model(&models.Expense{}).Where("id = ?", expense.ID).
+				Updates(map[string]interface{}{"amount": expense.Amount, "date": expense.Date}).Error; err != nil {
+				return err
+			}
+			if err := upsertManualTransaction(tx, expense); err != nil {
+				return fmt.Errorf("failed to sync merged expense: %w", err)
+			}
+		}
+		return tx.Delete(&models.Transaction{}, "id = ?", txn.ID).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.Expenses.invalidateUserCache(uid)
+	return nil
+}
+
+// isManualTransaction reports whether txn mirrors a manual expense
+func isManualTransaction(txn models.Transaction) bool {
+	return strings.HasPrefix(txn.TransactionID, "MANUAL_")
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	aaservices "github.com/your-github/expense-tracker-backend/internal/core/services"
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+)
+
+var (
+	reconcileExpenseColumns     = []string{"id", "user_id", "title", "amount", "date", "type", "currency"}
+	reconcileTransactionColumns = []string{"id", "user_id", "transaction_id", "transaction_date", "description", "amount", "type", "merchant_name"}
+)
+
+func newReconcileFixture(t *testing.T) (*ReconcileService, *testutil.StubDB) {
+	t.Helper()
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`FROM "bank_accounts"`, []string{"id", "user_id", "bank_id"}, []driver.Value{int64(50), int64(7), ManualBankID})
+	expenses := NewExpenseService(db, 1)
+	t.Cleanup(expenses.Close)
+	return NewReconcileService(db, expenses, aaservices.NewNormalizer()), stub
+}
+
+// daysAgo is the UTC calendar day n days before today, as an expense date
+// and as the midday posting time of a bank transaction
+func daysAgo(n int) (string, time.Time) {
+	day := time.Now().UTC().AddDate(0, 0, -n)
+	return day.Format("2006-01-02"), time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, time.UTC)
+}
+
+func TestFindDuplicatesFlagsManualExpensesImportedFromTheBank(t *testing.T) {
+	service, stub := newReconcileFixture(t)
+	today, todayPosted := daysAgo(0)
+	yesterday, yesterdayPosted := daysAgo(1)
+	_, weekAgoPosted := daysAgo(6)
+	stub.On(`FROM "expenses"`, reconcileExpenseColumns,
+		[]driver.Value{int64(1), int64(7), "Swiggy dinner", 450.0, yesterday, "expense", "INR"},
+		[]driver.Value{int64(2), int64(7), "Uber ride", 300.0, yesterday, "expense", "INR"},
+		[]driver.Value{int64(3), int64(7), "Bookstore", 800.0, today, "expense", "INR"},
+		[]driver.Value{int64(4), int64(7), "Zomato", 200.0, today, "expense", "INR"},
+		[]driver.Value{int64(5), int64(7), "Swiggy refund", 120.0, today, "income", "INR"},
+	)
+	stub.On(`FROM "transactions"`, reconcileTransactionColumns,
+		// Posted the day after the expense, for the same amount
+		[]driver.Value{int64(11), int64(7), "TXN11", todayPosted, "UPI/SWIGGY/ORDER 99812", 450.0, "debit", ""},
+		// Same merchant and amount, but too long before the expense
+		[]driver.Value{int64(12), int64(7), "TXN12", weekAgoPosted, "UBER TRIP", 300.0, "debit", "Uber"},
+		// Same amount and day, another merchant
+		[]driver.Value{int64(13), int64(7), "TXN13", todayPosted, "AMAZON PAY", 800.0, "debit", "Amazon"},
+		// Two candidates for one expense; the same-day one is closest
+		[]driver.Value{int64(14), int64(7), "TXN14", yesterdayPosted, "ZOMATO ORDER", 200.0, "debit", "Zomato"},
+		[]driver.Value{int64(15), int64(7), "TXN15", todayPosted, "ZOMATO ORDER", 200.5, "debit", "Zomato"},
+		// A debit can't duplicate income
+		[]driver.Value{int64(16), int64(7), "TXN16", todayPosted, "UPI/SWIGGY/REFUND", 120.0, "debit", ""},
+	)
+
+	duplicates, err := service.FindDuplicates(context.Background(), 7, DefaultReconcileAmountTolerance, DefaultReconcileDayTolerance)
+	if err != nil {
+		t.Fatalf("FindDuplicates: %v", err)
+	}
+	pairs := make(map[uint]uint)
+	for _, d := range duplicates {
+		pairs[d.Expense.ID] = d.Transaction.ID
+	}
+	if len(pairs) != 2 || pairs[1] != 11 || pairs[4] != 15 {
+		t.Fatalf("flagged expense → transaction %v, want 1 → 11 and 4 → 15", pairs)
+	}
+	for _, d := range duplicates {
+		if d.Expense.ID == 1 && (d.DaysApart != 1 || d.AmountDifference != 0) {
+			t.Errorf("Swiggy pair = %d days, %.2f apart; want 1 day, 0", d.DaysApart, d.AmountDifference)
+		}
+		if d.Expense.ID == 4 && (d.DaysApart != 0 || d.AmountDifference != 0.5) {
+			t.Errorf("Zomato pair = %d days, %.2f apart; want 0 days, 0.50", d.DaysApart, d.AmountDifference)
+		}
+	}
+
+	// Manual mirrors are never offered as the bank side
+	lookups := stub.Ran(`FROM "transactions"`)
+	if len(lookups) != 1 || !strings.Contains(lookups[0].SQL, "transaction_id NOT LIKE") || !hasArg(lookups[0].Args, manualTransactionPattern) {
+		t.Errorf("transactions lookup = %+v, want manual mirrors excluded", lookups)
+	}
+
+	// A wider window reaches the Uber transaction
+	duplicates, _ = service.FindDuplicates(context.Background(), 7, DefaultReconcileAmountTolerance, MaxReconcileDayTolerance)
+	if len(duplicates) != 3 {
+		t.Errorf("found %d duplicates within %d days, want 3", len(duplicates), MaxReconcileDayTolerance)
+	}
+}
+
+func TestResolveDuplicate(t *testing.T) {
+	_, posted := daysAgo(0)
+	tests := []struct {
+		action       string
+		expenseMoved bool
+		expenseGone  bool
+		bankGone     bool
+	}{
+		{ReconcileMerge, true, false, true},
+		{ReconcileKeepExpense, false, false, true},
+		{ReconcileKeepTransaction, false, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			service, stub := newReconcileFixture(t)
+			stub.On(`FROM "expenses"`, reconcileExpenseColumns, []driver.Value{int64(1), int64(7), "Swiggy dinner", 450.0, "2025-03-01", "expense", "INR"})
+			stub.On(`FROM "transactions"`, reconcileTransactionColumns, []driver.Value{int64(11), int64(7), "TXN11", posted, "UPI/SWIGGY", 452.0, "debit", ""})
+
+			if err := service.Resolve(context.Background(), 7, 1, 11, tt.action); err != nil {
+				t.Fatalf("Resolve: %v", err)
+			}
+			var bankDeleted, mirrorDeleted bool
+			for _, ran := range stub.Ran(`UPDATE "transactions" SET "deleted_at"`) {
+				bankDeleted = bankDeleted || (strings.Contains(ran.SQL, "id = $2") && hasArg(ran.Args, uint(11)))
+				mirrorDeleted = mirrorDeleted || hasArg(ran.Args, manualTransactionID(7, 1))
+			}
+			if bankDeleted != tt.bankGone {
+				t.Errorf("bank transaction removed %v, want %v", bankDeleted, tt.bankGone)
+			}
+
+			moved := false
+			for _, ran := range stub.Ran(`UPDATE "expenses"`) {
+				moved = moved || (hasArg(ran.Args, 452.0) && hasArg(ran.Args, posted.Format("2006-01-02")))
+			}
+			if moved != tt.expenseMoved {
+				t.Errorf("expense took the bank amount and date: %v, want %v", moved, tt.expenseMoved)
+			}
+			trashed := len(stub.Ran(`UPDATE "expenses" SET "deleted_at"`)) == 1
+			if trashed != tt.expenseGone || mirrorDeleted != tt.expenseGone {
+				t.Errorf("expense trashed %v with mirror %v, want %v", trashed, mirrorDeleted, tt.expenseGone)
+			}
+		})
+	}
+}
+
+func TestResolveDuplicateRejections(t *testing.T) {
+	service, stub := newReconcileFixture(t)
+	if err := service.Resolve(context.Background(), 7, 1, 11, "both"); !errors.Is(err, ErrInvalidReconcileAction) {
+		t.Errorf("unknown action: %v, want ErrInvalidReconcileAction", err)
+	}
+	if err := service.Resolve(context.Background(), 7, 1, 11, ReconcileMerge); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("missing expense: %v, want gorm.ErrRecordNotFound", err)
+	}
+
+	_, posted := daysAgo(0)
+	stub.On(`FROM "expenses"`, reconcileExpenseColumns, []driver.Value{int64(1), int64(7), "Swiggy dinner", 450.0, "2025-03-01", "expense", "INR"})
+	stub.On(`FROM "transactions"`, reconcileTransactionColumns, []driver.Value{int64(12), int64(7), manualTransactionID(7, 2), posted, "Swiggy", 450.0, "debit", ""})
+	if err := service.Resolve(context.Background(), 7, 1, 12, ReconcileKeepExpense); !errors.Is(err, ErrNotBankTransaction) {
+		t.Errorf("manual mirror: %v, want ErrNotBankTransaction", err)
+	}
+	if len(stub.Ran(`UPDATE "transactions"`)) != 0 {
+		t.Error("a rejected resolution deleted a transaction")
+	}
+}
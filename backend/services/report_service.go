@@ -0,0 +1,201 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+
+	"github.com/your-github/expense-tracker-backend/models"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+// Report cadences a user can opt in to
+const (
+	ReportCadenceWeekly  = "weekly"
+	ReportCadenceMonthly = "monthly"
+)
+
+// SpendingReport is the data rendered into the report email
+type SpendingReport struct {
+	Name       string
+	Period     string // "weekly" or "monthly"
+	PeriodName string // e.g. "March 2025" or "March 2025 (month to date)"
+	Summary    Summary
+	Categories []ReportCategory
+}
+
+// ReportCategory is one row of the top-categories table
+type ReportCategory struct {
+	Name   string
+	Amount float64
+}
+
+var spendingReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"money": func(currency string, v float64) string { return fmt.Sprintf("%s %.2f", currency, v) },
+}).Parse(`
+		<html>
+		<body>
+			<div style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto; padding: 20px;">
+				<div style="background: linear-gradient(135deg, #FFD700, #FFA500); padding: 20px; border-radius: 10px; text-align: center;">
+					<h1 style="color: #333; margin: 0; font-size: 28px;">BucksInfo</h1>
+					<p style="color: #333; margin: 10px 0 0 0; font-size: 16px;">Spending Report &middot; {{.PeriodName}}</p>
+				</div>
+
+				<div style="background: #f9f9f9; padding: 30px; border-radius: 10px; margin-top: 20px;">
+					<h2 style="color: #333; margin: 0 0 20px 0;">Hi {{.Name}},</h2>
+					<p style="color: #666; margin: 0 0 20px 0; font-size: 16px;">Here's how your money moved in {{.PeriodName}}.</p>
+
+					<table style="width: 100%; border-collapse: collapse; font-size: 15px;">
+						<tr><td style="padding: 8px 0; color: #666;">Income</td><td style="padding: 8px 0; text-align: right; color: #2e7d32;">{{money .Summary.Currency .Summary.TotalIncome}}</td></tr>
+						<tr><td style="padding: 8px 0; color: #666;">Expenses</td><td style="padding: 8px 0; text-align: right; color: #c62828;">{{money .Summary.Currency .Summary.TotalExpenses}}</td></tr>
+						<tr><td style="padding: 8px 0; color: #666;">Net balance</td><td style="padding: 8px 0; text-align: right;">{{money .Summary.Currency .Summary.NetBalance}}</td></tr>
+						<tr><td style="padding: 8px 0; color: #666;">Average daily spend</td><td style="padding: 8px 0; text-align: right;">{{money .Summary.Currency .Summary.AverageDaily}}</td></tr>
+						<tr><td style="padding: 8px 0; color: #666;">Remaining budget</td><td style="padding: 8px 0; text-align: right;">{{money .Summary.Currency .Summary.RemainingBudget}}</td></tr>
+					</table>
+					{{if .Categories}}
+					<h3 style="color: #333; margin: 30px 0 10px 0;">Top categories</h3>
+					<table style="width: 100%; border-collapse: collapse; font-size: 15px;">
+						{{range .Categories}}<tr><td style="padding: 6px 0; color: #666;">{{.Name}}</td><td style="padding: 6px 0; text-align: right;">{{money $.Summary.Currency .Amount}}</td></tr>
+						{{end}}
+					</table>
+					{{end}}
+					<div style="margin-top: 30px; padding-top: 20px; border-top: 1px solid #ddd;">
+						<p style="color: #999; margin: 0; font-size: 12px;">
+							You're receiving this {{.Period}} report because you opted in. You can turn it off from your profile settings.
+						</p>
+					</div>
+				</div>
+			</div>
+		</body>
+		</html>
+`))
+
+// RenderSpendingReport renders the HTML body of a spending report email
+func RenderSpendingReport(report SpendingReport) (string, error) {
+	var buf bytes.Buffer
+	if err := spendingReportTemplate.Execute(&buf, report); err != nil {
+		return "", fmt.Errorf("failed to render spending report: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// ReportService emails periodic spending summaries to opted-in users
+type ReportService struct {
+	DB      *gorm.DB
+	Summary *SummaryService
+	Email   *EmailService
+}
+
+// NewReportService creates a new report service
+func NewReportService(db *gorm.DB, summary *SummaryService, email *EmailService) *ReportService {
+	return &ReportService{
+		DB:      db,
+		Summary: summary,
+		Email:   email,
+	}
+}
+
+// StartScheduler runs SendDueReports every morning at 08:00 server time
+func (s *ReportService) StartScheduler() *cron.Cron {
+	c := cron.New()
+	if _, err := c.AddFunc("0 8 * * *", func() {
+		sent, err := s.SendDueReports(time.Now())
+		if err != nil {
+			log.Printf("Spending report job failed: %v", err)
+			return
+		}
+		log.Printf("Spending report job sent %d reports", sent)
+	}); err != nil {
+		log.Printf("Failed to schedule spending report job: %v", err)
+	}
+	c.Start()
+	return c
+}
+
+// SendDueReports emails every opted-in user whose cadence is due on now:
+// weekly reports go out on Mondays with month-to-date figures, monthly
+// reports on the 1st covering the previous month. It returns how many were sent.
+func (s *ReportService) SendDueReports(now time.Time) (int, error) {
+	var cadences []string
+	if now.Weekday() == time.Monday {
+		cadences = append(cadences, ReportCadenceWeekly)
+	}
+	if now.Day() == 1 {
+		cadences = append(cadences, ReportCadenceMonthly)
+	}
+	if len(cadences) == 0 {
+		return 0, nil
+	}
+
+	var users []models.User
+	if err := s.DB.Where("report_opt_in = ? AND report_cadence IN ?", true, cadences).Find(&users).Error; err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, user := range users {
+		// Guard against double sends if the job is re-run the same day
+		if user.LastReportSentAt != nil && sameDay(*user.LastReportSentAt, now) {
+			continue
+		}
+
+		report, err := s.BuildReport(user, now)
+		if err != nil {
+			log.Printf("Failed to build spending report for user %d: %v", user.ID, err)
+			continue
+		}
+		if err := s.Email.SendSpendingReport(user.Email, report); err != nil {
+			log.Printf("Failed to send spending report to user %d: %v", user.ID, err)
+			continue
+		}
+
+		s.DB.Model(&models.User{}).Where("id = ?", user.ID).Update("last_report_sent_at", now)
+		sent++
+	}
+	return sent, nil
+}
+
+// BuildReport computes the report for a user's cadence as of now
+func (s *ReportService) BuildReport(user models.User, now time.Time) (SpendingReport, error) {
+	now = now.In(utils.Location(user.Timezone))
+	period := now
+	periodName := now.Format("January 2006") + " (month to date)"
+	cadence := user.ReportCadence
+	if cadence == ReportCadenceMonthly {
+		period = now.AddDate(0, 0, -now.Day()) // last day of previous month
+		periodName = period.Format("January 2006")
+	} else {
+		cadence = ReportCadenceWeekly
+	}
+
+	summary, err := s.Summary.Monthly(user.ID, user.Budget, period.Year(), period.Month(), false)
+	if err != nil {
+		return SpendingReport{}, err
+	}
+
+	categories := make([]ReportCategory, 0, len(summary.TopCategories))
+	for name, amount := range summary.TopCategories {
+		categories = append(categories, ReportCategory{Name: name, Amount: amount})
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].Amount > categories[j].Amount })
+
+	return SpendingReport{
+		Name:       user.Name,
+		Period:     cadence,
+		PeriodName: periodName,
+		Summary:    summary,
+		Categories: categories,
+	}, nil
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
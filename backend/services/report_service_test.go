@@ -0,0 +1,175 @@
+package services
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-github/expense-tracker-backend/models"
+)
+
+func TestRenderSpendingReport(t *testing.T) {
+	body, err := RenderSpendingReport(SpendingReport{
+		Name:       "Asha <script>",
+		Period:     ReportCadenceMonthly,
+		PeriodName: "March 2025",
+		Summary: Summary{
+			Currency:        "INR",
+			TotalIncome:     85000,
+			TotalExpenses:   42350.5,
+			NetBalance:      42649.5,
+			AverageDaily:    1366.15,
+			RemainingBudget: 7649.5,
+		},
+		Categories: []ReportCategory{{Name: "Food & Dining", Amount: 12000}, {Name: "Travel", Amount: 8000.25}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"March 2025",
+		"INR 85000.00",
+		"INR 42350.50",
+		"INR 42649.50",
+		"INR 1366.15",
+		"INR 7649.50",
+		"Food &amp; Dining", "INR 12000.00",
+		"Travel", "INR 8000.25",
+		"monthly report",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("report is missing %q", want)
+		}
+	}
+	if strings.Contains(body, "<script>") {
+		t.Error("the user's name was not escaped")
+	}
+}
+
+func TestRenderSpendingReportEscapesUserText(t *testing.T) {
+	body, err := RenderSpendingReport(SpendingReport{
+		Name:       `Asha" onmouseover="alert(1)`,
+		Period:     ReportCadenceMonthly,
+		PeriodName: "March 2025",
+		Summary:    Summary{Currency: "INR", TotalExpenses: 500},
+		Categories: []ReportCategory{{Name: "<script>alert(1)</script>", Amount: 500}, {Name: `<img src=x onerror="steal()">`, Amount: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"&lt;script&gt;alert(1)&lt;/script&gt;", "&lt;img src=x onerror=&#34;steal()&#34;&gt;", "Asha&#34; onmouseover=&#34;alert(1)"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("report is missing the escaped %q", want)
+		}
+	}
+	for _, unwanted := range []string{"<script>", "<img", `" onmouseover="`} {
+		if strings.Contains(body, unwanted) {
+			t.Errorf("report contains the raw %q", unwanted)
+		}
+	}
+}
+
+func TestRenderSpendingReportWithoutCategories(t *testing.T) {
+	body, err := RenderSpendingReport(SpendingReport{Name: "Asha", Period: ReportCadenceWeekly, Summary: Summary{Currency: "USD"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(body, "Top categories") {
+		t.Error("rendered an empty categories table")
+	}
+	if !strings.Contains(body, "USD 0.00") {
+		t.Error("zero totals are missing")
+	}
+}
+
+func TestBuildReportPeriods(t *testing.T) {
+	summary, stub := newSummaryFixture(t, "INR")
+	stub.On(`FROM expenses`, totalsColumns,
+		[]driver.Value{"expense", "Travel", "INR", 800.0},
+		[]driver.Value{"expense", "Food & Dining", "INR", 1200.0},
+	)
+	service := NewReportService(summary.DB, summary, nil)
+	now := time.Date(2025, time.April, 1, 8, 0, 0, 0, time.UTC)
+
+	monthly, err := service.BuildReport(models.User{Name: "Asha", ReportCadence: ReportCadenceMonthly, Timezone: "UTC"}, now)
+	if err != nil {
+		t.Fatalf("BuildReport: %v", err)
+	}
+	if monthly.PeriodName != "March 2025" || monthly.Period != ReportCadenceMonthly {
+		t.Errorf("monthly report covers %q (%s), want March 2025", monthly.PeriodName, monthly.Period)
+	}
+	if len(monthly.Categories) != 2 || monthly.Categories[0].Name != "Food & Dining" {
+		t.Errorf("categories = %+v, want largest first", monthly.Categories)
+	}
+	ran := stub.Ran(`FROM expenses`)
+	if args := ran[len(ran)-1].Args; args[1] != "2025-03-01" || args[2] != "2025-04-01" {
+		t.Errorf("monthly report summed %v to %v, want March", args[1], args[2])
+	}
+
+	weekly, err := service.BuildReport(models.User{Name: "Asha", ReportCadence: ReportCadenceWeekly, Timezone: "UTC"}, now)
+	if err != nil {
+		t.Fatalf("BuildReport: %v", err)
+	}
+	if weekly.PeriodName != "April 2025 (month to date)" || weekly.Period != ReportCadenceWeekly {
+		t.Errorf("weekly report covers %q (%s), want April to date", weekly.PeriodName, weekly.Period)
+	}
+}
+
+func TestBuildReportUsesTheUsersTimezone(t *testing.T) {
+	summary, stub := newSummaryFixture(t, "INR")
+	stub.On(`FROM expenses`, totalsColumns, []driver.Value{"expense", "Travel", "INR", 800.0})
+	service := NewReportService(summary.DB, summary, nil)
+	// Already April in UTC, still March 31 in Los Angeles
+	now := time.Date(2025, time.April, 1, 3, 0, 0, 0, time.UTC)
+
+	monthly, err := service.BuildReport(models.User{Name: "Asha", ReportCadence: ReportCadenceMonthly, Timezone: "America/Los_Angeles"}, now)
+	if err != nil {
+		t.Fatalf("BuildReport: %v", err)
+	}
+	if monthly.PeriodName != "February 2025" {
+		t.Errorf("monthly report covers %q, want February 2025", monthly.PeriodName)
+	}
+	weekly, err := service.BuildReport(models.User{Name: "Asha", ReportCadence: ReportCadenceWeekly, Timezone: "America/Los_Angeles"}, now)
+	if err != nil {
+		t.Fatalf("BuildReport: %v", err)
+	}
+	if weekly.PeriodName != "March 2025 (month to date)" {
+		t.Errorf("weekly report covers %q, want March to date", weekly.PeriodName)
+	}
+	ran := stub.Ran(`FROM expenses`)
+	if args := ran[len(ran)-1].Args; args[1] != "2025-03-01" || args[2] != "2025-04-01" {
+		t.Errorf("weekly report summed %v to %v, want March", args[1], args[2])
+	}
+}
+
+func TestSendDueReportsOnlyOnDueDays(t *testing.T) {
+	summary, stub := newSummaryFixture(t, "INR")
+	service := NewReportService(summary.DB, summary, &EmailService{})
+
+	// A Wednesday that isn't the 1st
+	sent, err := service.SendDueReports(time.Date(2025, time.April, 9, 8, 0, 0, 0, time.UTC))
+	if err != nil || sent != 0 {
+		t.Fatalf("sent %d, err %v", sent, err)
+	}
+	if len(stub.Ran(`FROM "users"`)) != 0 {
+		t.Error("looked up users on a day nothing is due")
+	}
+
+	// Due on a Monday, but already sent today
+	monday := time.Date(2025, time.April, 7, 8, 0, 0, 0, time.UTC)
+	stub.On(`report_opt_in = `, []string{"id", "email", "report_opt_in", "report_cadence", "last_report_sent_at"},
+		[]driver.Value{int64(7), "asha@example.com", true, ReportCadenceWeekly, monday.Add(-time.Hour)})
+	sent, err = service.SendDueReports(monday)
+	if err != nil || sent != 0 {
+		t.Fatalf("sent %d, err %v", sent, err)
+	}
+	queries := stub.Ran(`report_opt_in = `)
+	if len(queries) != 1 || queries[0].Args[1] != ReportCadenceWeekly {
+		t.Errorf("queried %+v, want the weekly cadence only", queries)
+	}
+	if len(stub.Ran(`UPDATE "users"`)) != 0 {
+		t.Error("recorded a send that was skipped")
+	}
+}
@@ -2,11 +2,15 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"time"
 
 	"gorm.io/gorm"
 
+	"github.com/your-github/expense-tracker-backend/models"
 	"github.com/your-github/expense-tracker-backend/utils"
 )
 
@@ -16,124 +20,323 @@ type Summary struct {
 	NetBalance      float64            `json:"net_balance"`
 	TopCategories   map[string]float64 `json:"top_categories"`
 	AverageDaily    float64            `json:"average_daily"`
-	RemainingBudget float64            `json:"remaining_budget"`
+	RemainingBudget float64            `json:"remaining_budget"` // budget - TotalExpenses; income is not counted
+	Currency        string             `json:"currency"`
+
+	// Rollover is the unused budget carried in from the previous month and
+	// RemainingWithRollover adds it to RemainingBudget. Both are zero/equal
+	// to RemainingBudget when rollover is disabled.
+	Rollover              float64 `json:"rollover"`
+	RemainingWithRollover float64 `json:"remaining_with_rollover"`
 }
 
 type SummaryService struct {
-	DB    *gorm.DB
-	Cache *utils.LRUCache
+	DB       *gorm.DB
+	Cache    *utils.LRUCache
+	Rates    utils.RateProvider
+	Rollover bool // carry unused budget into the following month
 }
 
-// NewSummaryService creates a new summary service with caching
-func NewSummaryService(db *gorm.DB) *SummaryService {
-	cache := utils.NewLRUCache(500, 10*time.Minute) // Cache for 10 minutes
-	cache.StartCleanup(5 * time.Minute)             // Cleanup every 5 minutes
+// NewSummaryService creates a new summary service with caching. Totals are
+// converted into each user's display currency using rates.
+func NewSummaryService(db *gorm.DB, rates utils.RateProvider, cacheShards int) *SummaryService {
+	cache := utils.NewShardedLRUCache(500, 10*time.Minute, cacheShards) // Cache for 10 minutes
+	cache.StartCleanup(5 * time.Minute)                                 // Cleanup every 5 minutes
 
 	return &SummaryService{
 		DB:    db,
 		Cache: cache,
+		Rates: rates,
 	}
 }
 
-func (s *SummaryService) Monthly(uid uint, budget float64, year int, month time.Month) (Summary, error) {
+// Close stops the cache cleanup goroutine
+func (s *SummaryService) Close() {
+	s.Cache.StopCleanup()
+}
+
+// currencyTotal is an aggregate of one expense type/category in one currency
+type currencyTotal struct {
+	Type     string
+	Category string
+	Currency string
+	Total    float64
+}
+
+// displayCurrency returns the user's preferred currency for summaries
+func (s *SummaryService) displayCurrency(ctx context.Context, uid uint) string {
+	var currency string
+	s.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", uid).Select("currency").Scan(&currency)
+	return utils.NormalizeCurrency(currency)
+}
+
+// UserLocation returns the user's timezone. Expense dates are calendar
+// dates in that zone, so "today" and month boundaries are taken from it
+// rather than from the server's zone.
+func UserLocation(db *gorm.DB, uid uint) *time.Location {
+	var timezone string
+	db.Model(&models.User{}).Where("id = ?", uid).Select("timezone").Scan(&timezone)
+	return utils.Location(timezone)
+}
+
+// aggregateInCurrency totals expenses matching the condition per type and
+// category, converting every currency into display before summing.
+func (s *SummaryService) aggregateInCurrency(ctx context.Context, display, condition string, args ...interface{}) ([]currencyTotal, error) {
+	var rows []currencyTotal
+	err := s.DB.WithContext(ctx).Raw(`
+		SELECT
+			type,
+			category,
+			COALESCE(NULLIF(currency, ''), '`+utils.DefaultCurrency+`') as currency,
+			SUM(amount) as total
+		FROM expenses
+		WHERE deleted_at IS NULL AND `+condition+`
+		GROUP BY 1, 2, 3
+	`, args...).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Merge rows that differ only by currency once converted
+	merged := make(map[[2]string]float64)
+	var order [][2]string
+	for _, row := range rows {
+		converted, err := utils.ConvertAmount(s.Rates, row.Total, row.Currency, display)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert %s totals: %w", row.Currency, err)
+		}
+		key := [2]string{row.Type, row.Category}
+		if _, ok := merged[key]; !ok {
+			order = append(order, key)
+		}
+		merged[key] += converted
+	}
+
+	totals := make([]currencyTotal, 0, len(order))
+	for _, key := range order {
+		totals = append(totals, currencyTotal{Type: key[0], Category: key[1], Currency: display, Total: merged[key]})
+	}
+	return totals, nil
+}
+
+// applyTotals fills the income/expense totals and the top n expense categories
+func applyTotals(sum *Summary, totals []currencyTotal, n int) {
+	categories := make(map[string]float64)
+	for _, t := range totals {
+		switch t.Type {
+		case "expense":
+			sum.TotalExpenses += t.Total
+			if t.Category != "" {
+				categories[t.Category] += t.Total
+			}
+		case "income":
+			sum.TotalIncome += t.Total
+		}
+	}
+	sum.NetBalance = sum.TotalIncome - sum.TotalExpenses
+
+	names := make([]string, 0, len(categories))
+	for name := range categories {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return categories[names[i]] > categories[names[j]] })
+	if len(names) > n {
+		names = names[:n]
+	}
+
+	sum.TopCategories = make(map[string]float64, len(names))
+	for _, name := range names {
+		sum.TopCategories[name] = categories[name]
+	}
+}
+
+// cached looks up key unless refresh asks for a recomputation, in which
+// case the caller's Set overwrites the stale entry
+func (s *SummaryService) cached(key string, refresh bool) (interface{}, bool) {
+	if refresh {
+		return nil, false
+	}
+	return s.Cache.Get(key)
+}
+
+// Monthly returns the month's totals against budget, with the days elapsed
+// counted in the user's timezone. With refresh the cached value is ignored
+// and replaced by a freshly computed one.
+func (s *SummaryService) Monthly(uid uint, budget float64, year int, month time.Month, refresh bool) (Summary, error) {
+	// Use context with timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	display := s.displayCurrency(ctx, uid)
+	loc := UserLocation(s.DB.WithContext(ctx), uid)
+
 	// Try to get from cache first
-	cacheKey := fmt.Sprintf("summary_monthly:%d:%d:%d:%f", uid, year, month, budget)
-	if cached, found := s.Cache.Get(cacheKey); found {
+	cacheKey := fmt.Sprintf("summary_monthly:%d:%d:%d:%f:%s:%t:%s", uid, year, month, budget, display, s.Rollover, loc)
+	if cached, found := s.cached(cacheKey, refresh); found {
 		if summary, ok := cached.(Summary); ok {
 			return summary, nil
 		}
 	}
 
-	var sum Summary
-	start := time.Date(year, month, 1, 0, 0, 0, 0, time.Local)
+	sum := Summary{Currency: display}
+	start := time.Date(year, month, 1, 0, 0, 0, 0, loc)
 	end := start.AddDate(0, 1, 0)
 
 	// Convert to string format to match database date field
 	startStr := start.Format("2006-01-02")
 	endStr := end.Format("2006-01-02")
 
-	// Use context with timeout
+	totals, err := s.aggregateInCurrency(ctx, display, "user_id = ? AND date >= ? AND date < ?", uid, startStr, endStr)
+	if err != nil {
+		return sum, err
+	}
+	applyTotals(&sum, totals, 3)
+
+	days := daysElapsed(start, end, time.Now().In(loc))
+	if days > 0 {
+		sum.AverageDaily = sum.TotalExpenses / float64(days)
+	}
+
+	// Remaining budget only counts spending; income doesn't top up the budget
+	sum.RemainingBudget = budget - sum.TotalExpenses
+	sum.RemainingWithRollover = sum.RemainingBudget
+
+	if s.Rollover && budget > 0 {
+		prevStr := start.AddDate(0, -1, 0).Format("2006-01-02")
+		prevTotals, err := s.aggregateInCurrency(ctx, display, "user_id = ? AND date >= ? AND date < ? AND type = 'expense'", uid, prevStr, startStr)
+		if err != nil {
+			return sum, err
+		}
+		var prevExpenses float64
+		for _, t := range prevTotals {
+			prevExpenses += t.Total
+		}
+		if unused := budget - prevExpenses; unused > 0 {
+			sum.Rollover = unused
+		}
+		sum.RemainingWithRollover = sum.RemainingBudget + sum.Rollover
+	}
+
+	// Cache the result
+	s.Cache.Set(cacheKey, sum)
+
+	return sum, nil
+}
+
+// daysElapsed returns how many days of the month [start, end) have begun by
+// now: all of them for a past month, none for a future one
+func daysElapsed(start, end, now time.Time) int {
+	if now.Before(start) {
+		return 0
+	}
+	if !now.Before(end) {
+		return end.AddDate(0, 0, -1).Day()
+	}
+	return now.Day()
+}
+
+// PeriodSummary is a Summary of the budget period containing today.
+// EndDate is the period's last day.
+type PeriodSummary struct {
+	Summary
+	Period      string `json:"period"`
+	StartDate   string `json:"start_date"`
+	EndDate     string `json:"end_date"`
+	Days        int    `json:"days"`
+	DaysElapsed int    `json:"days_elapsed"`
+	DaysLeft    int    `json:"days_left"`
+}
+
+// ActivePeriod returns the totals of the budget period containing today in
+// the user's timezone, against budget. With rollover enabled the budget
+// left unused in the previous period is carried in. refresh bypasses the
+// cache as in Monthly.
+func (s *SummaryService) ActivePeriod(uid uint, budget float64, period BudgetPeriod, refresh bool) (PeriodSummary, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	// Use efficient single query with aggregation for better performance
-	type AggResult struct {
-		Type     string  `json:"type"`
-		Total    float64 `json:"total"`
-		Category string  `json:"category"`
-		Amount   float64 `json:"amount"`
-	}
+	display := s.displayCurrency(ctx, uid)
+	today := time.Now().In(UserLocation(s.DB.WithContext(ctx), uid))
+	start, end := period.Bounds(today)
+	days := int(end.Sub(start).Hours() / 24)
+	elapsed := int(time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC).Sub(start).Hours()/24) + 1
 
-	var results []AggResult
+	startStr := start.Format("2006-01-02")
+	endStr := end.Format("2006-01-02")
 
-	// Single query to get all aggregated data
-	err := s.DB.WithContext(ctx).Raw(`
-		SELECT 
-			type,
-			SUM(amount) as total,
-			category,
-			amount
-		FROM expenses 
-		WHERE user_id = ? AND date >= ? AND date < ?
-		GROUP BY type, category, amount
-		ORDER BY type, total DESC
-	`, uid, startStr, endStr).Scan(&results).Error
+	// Keyed by the day too: AverageDaily and the days left change daily
+	cacheKey := fmt.Sprintf("summary_period:%d:%s:%s:%d:%f:%s:%t", uid, startStr, endStr, elapsed, budget, display, s.Rollover)
+	if cached, found := s.cached(cacheKey, refresh); found {
+		if summary, ok := cached.(PeriodSummary); ok {
+			return summary, nil
+		}
+	}
+
+	sum := PeriodSummary{
+		Summary:     Summary{Currency: display},
+		Period:      period.Type,
+		StartDate:   startStr,
+		EndDate:     end.AddDate(0, 0, -1).Format("2006-01-02"),
+		Days:        days,
+		DaysElapsed: elapsed,
+		DaysLeft:    days - elapsed,
+	}
 
+	totals, err := s.aggregateInCurrency(ctx, display, "user_id = ? AND date >= ? AND date < ?", uid, startStr, endStr)
 	if err != nil {
 		return sum, err
 	}
-
-	// Process results efficiently
-	sum.TopCategories = make(map[string]float64)
-	for _, result := range results {
-		switch result.Type {
-		case "expense":
-			sum.TotalExpenses += result.Total
-			// Track top categories for expenses only
-			if result.Category != "" {
-				sum.TopCategories[result.Category] += result.Amount
-			}
-		case "income":
-			sum.TotalIncome += result.Total
+	applyTotals(&sum.Summary, totals, 3)
+	sum.AverageDaily = sum.TotalExpenses / float64(elapsed)
+
+	sum.RemainingBudget = budget - sum.TotalExpenses
+	sum.RemainingWithRollover = sum.RemainingBudget
+
+	if s.Rollover && budget > 0 {
+		prevStart, _ := period.Bounds(start.AddDate(0, 0, -1))
+		prevTotals, err := s.aggregateInCurrency(ctx, display, "user_id = ? AND date >= ? AND date < ? AND type = 'expense'",
+			uid, prevStart.Format("2006-01-02"), startStr)
+		if err != nil {
+			return sum, err
+		}
+		var prevExpenses float64
+		for _, t := range prevTotals {
+			prevExpenses += t.Total
 		}
+		if unused := budget - prevExpenses; unused > 0 {
+			sum.Rollover = unused
+		}
+		sum.RemainingWithRollover = sum.RemainingBudget + sum.Rollover
 	}
 
-	// Calculate net balance
-	sum.NetBalance = sum.TotalIncome - sum.TotalExpenses
+	s.Cache.Set(cacheKey, sum)
 
-	// Get top 3 categories efficiently
-	topCategories := make([]struct {
-		Category string  `json:"category"`
-		Total    float64 `json:"total"`
-	}, 0, 3)
-
-	err = s.DB.WithContext(ctx).Raw(`
-		SELECT category, SUM(amount) as total
-		FROM expenses 
-		WHERE user_id = ? AND date >= ? AND date < ? AND type = 'expense'
-		GROUP BY category 
-		ORDER BY total DESC 
-		LIMIT 3
-	`, uid, startStr, endStr).Scan(&topCategories).Error
-
-	if err == nil {
-		sum.TopCategories = make(map[string]float64)
-		for _, cat := range topCategories {
-			if cat.Category != "" {
-				sum.TopCategories[cat.Category] = cat.Total
-			}
+	return sum, nil
+}
+
+// Lifetime totals for profile page; refresh bypasses the cache as in Monthly
+func (s *SummaryService) Lifetime(uid uint, refresh bool) (Summary, error) {
+	// Use context with timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	display := s.displayCurrency(ctx, uid)
+
+	// Try to get from cache first
+	cacheKey := fmt.Sprintf("summary_lifetime:%d:%s", uid, display)
+	if cached, found := s.cached(cacheKey, refresh); found {
+		if summary, ok := cached.(Summary); ok {
+			return summary, nil
 		}
-	} else {
-		sum.TopCategories = make(map[string]float64)
 	}
 
-	days := time.Now().Day()
-	if days > 0 {
-		sum.AverageDaily = sum.TotalExpenses / float64(days)
-	}
+	sum := Summary{Currency: display}
 
-	// Calculate remaining budget (budget - expenses + income)
-	sum.RemainingBudget = budget - sum.TotalExpenses + sum.TotalIncome
+	totals, err := s.aggregateInCurrency(ctx, display, "user_id = ?", uid)
+	if err != nil {
+		return sum, err
+	}
+	applyTotals(&sum, totals, 5)
 
 	// Cache the result
 	s.Cache.Set(cacheKey, sum)
@@ -141,147 +344,481 @@ func (s *SummaryService) Monthly(uid uint, budget float64, year int, month time.
 	return sum, nil
 }
 
-// Lifetime totals for profile page
-func (s *SummaryService) Lifetime(uid uint) (Summary, error) {
-	// Try to get from cache first
-	cacheKey := fmt.Sprintf("summary_lifetime:%d", uid)
-	if cached, found := s.Cache.Get(cacheKey); found {
-		if summary, ok := cached.(Summary); ok {
-			return summary, nil
+// CategoryRollup folds small categories of a breakdown into Other. TopN
+// keeps only the N largest categories besides Other; MinAmount folds every
+// category below it. Zero values disable either rule.
+type CategoryRollup struct {
+	TopN      int
+	MinAmount float64
+}
+
+// apply returns breakdown with the rolled up categories added to Other.
+// The total over all categories is unchanged; ties are broken by name so
+// the same categories are kept every time.
+func (r CategoryRollup) apply(breakdown map[string]float64) map[string]float64 {
+	if r.TopN <= 0 && r.MinAmount <= 0 {
+		return breakdown
+	}
+
+	other, hasOther := breakdown[utils.CategoryOther]
+	names := make([]string, 0, len(breakdown))
+	for name, amount := range breakdown {
+		if name == utils.CategoryOther {
+			continue
 		}
+		if amount < r.MinAmount {
+			other += amount
+			hasOther = true
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if breakdown[names[i]] != breakdown[names[j]] {
+			return breakdown[names[i]] > breakdown[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if r.TopN > 0 && len(names) > r.TopN {
+		for _, name := range names[r.TopN:] {
+			other += breakdown[name]
+		}
+		hasOther = true
+		names = names[:r.TopN]
 	}
 
-	var sum Summary
+	rolled := make(map[string]float64, len(names)+1)
+	for _, name := range names {
+		rolled[name] = breakdown[name]
+	}
+	if hasOther {
+		rolled[utils.CategoryOther] = other
+	}
+	return rolled
+}
 
+// GetCategoryBreakdown efficiently gets expense breakdown by category with
+// small categories rolled up as rollup asks; refresh bypasses the cache as
+// in Monthly. The cache holds the full breakdown, so any rollup is served
+// from the same entry.
+func (s *SummaryService) GetCategoryBreakdown(uid uint, startDate, endDate string, rollup CategoryRollup, refresh bool) (map[string]float64, error) {
 	// Use context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Use efficient single query with aggregation
-	type AggResult struct {
-		Type     string  `json:"type"`
-		Total    float64 `json:"total"`
-		Category string  `json:"category"`
-		Amount   float64 `json:"amount"`
+	display := s.displayCurrency(ctx, uid)
+
+	cacheKey := fmt.Sprintf("category_breakdown:%d:%s:%s:%s", uid, startDate, endDate, display)
+	if cached, found := s.cached(cacheKey, refresh); found {
+		if breakdown, ok := cached.(map[string]float64); ok {
+			return rollup.apply(breakdown), nil
+		}
 	}
 
-	var results []AggResult
+	totals, err := s.aggregateInCurrency(ctx, display, "user_id = ? AND date >= ? AND date <= ? AND type = 'expense'", uid, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
 
-	// Single query to get all aggregated data
-	err := s.DB.WithContext(ctx).Raw(`
-		SELECT 
-			type,
-			SUM(amount) as total,
-			category,
-			amount
-		FROM expenses 
-		WHERE user_id = ?
-		GROUP BY type, category, amount
-		ORDER BY type, total DESC
-	`, uid).Scan(&results).Error
+	breakdown := make(map[string]float64)
+	for _, t := range totals {
+		if t.Category != "" {
+			breakdown[t.Category] += t.Total
+		}
+	}
 
+	// Cache the result
+	s.Cache.Set(cacheKey, breakdown)
+
+	return rollup.apply(breakdown), nil
+}
+
+// FinancialHealth scores the user's lifetime income and spend (see
+// utils.ComputeFinancialHealth); refresh bypasses the cache as in Monthly
+func (s *SummaryService) FinancialHealth(uid uint, refresh bool) (utils.FinancialHealth, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	display := s.displayCurrency(ctx, uid)
+
+	cacheKey := fmt.Sprintf("financial_health:%d:%s", uid, display)
+	if cached, found := s.cached(cacheKey, refresh); found {
+		if health, ok := cached.(utils.FinancialHealth); ok {
+			return health, nil
+		}
+	}
+
+	totals, err := s.aggregateInCurrency(ctx, display, "user_id = ?", uid)
 	if err != nil {
-		return sum, err
+		return utils.FinancialHealth{}, err
 	}
 
-	// Process results efficiently
-	sum.TopCategories = make(map[string]float64)
-	for _, result := range results {
-		switch result.Type {
+	var income, expenses float64
+	categories := make(map[string]float64)
+	for _, t := range totals {
+		switch t.Type {
 		case "expense":
-			sum.TotalExpenses += result.Total
-			// Track top categories for expenses only
-			if result.Category != "" {
-				sum.TopCategories[result.Category] += result.Amount
-			}
+			expenses += t.Total
+			categories[utils.NormalizeCategory(t.Category)] += t.Total
 		case "income":
-			sum.TotalIncome += result.Total
+			income += t.Total
 		}
 	}
 
-	// Calculate net balance
-	sum.NetBalance = sum.TotalIncome - sum.TotalExpenses
+	health := utils.ComputeFinancialHealth(income, expenses, categories)
+	health.Currency = display
 
-	// Get top 5 categories efficiently
-	topCategories := make([]struct {
-		Category string  `json:"category"`
-		Total    float64 `json:"total"`
-	}, 0, 5)
-
-	err = s.DB.WithContext(ctx).Raw(`
-		SELECT category, SUM(amount) as total
-		FROM expenses 
-		WHERE user_id = ? AND type = 'expense'
-		GROUP BY category 
-		ORDER BY total DESC 
-		LIMIT 5
-	`, uid).Scan(&topCategories).Error
-
-	if err == nil {
-		sum.TopCategories = make(map[string]float64)
-		for _, cat := range topCategories {
-			if cat.Category != "" {
-				sum.TopCategories[cat.Category] = cat.Total
-			}
+	s.Cache.Set(cacheKey, health)
+
+	return health, nil
+}
+
+// ErrInvalidPeriod is returned for a comparison period not written as YYYY-MM
+var ErrInvalidPeriod = errors.New("period must be a month written as YYYY-MM")
+
+// CategoryDelta compares one expense category across two periods.
+// DeltaPercent is relative to AmountA and nil when AmountA is zero.
+type CategoryDelta struct {
+	Category     string   `json:"category"`
+	AmountA      float64  `json:"amount_a"`
+	AmountB      float64  `json:"amount_b"`
+	Delta        float64  `json:"delta"`
+	DeltaPercent *float64 `json:"delta_percent"`
+}
+
+// PeriodComparison holds the change in spending from PeriodA to PeriodB.
+// Categories with spending in either period are listed, largest absolute
+// change first.
+type PeriodComparison struct {
+	PeriodA      string          `json:"period_a"`
+	PeriodB      string          `json:"period_b"`
+	Currency     string          `json:"currency"`
+	TotalA       float64         `json:"total_a"`
+	TotalB       float64         `json:"total_b"`
+	Delta        float64         `json:"delta"`
+	DeltaPercent *float64        `json:"delta_percent"`
+	Categories   []CategoryDelta `json:"categories"`
+}
+
+// deltaPercent returns the change from a to b in percent of a, or nil when
+// a is zero and no meaningful percentage exists
+func deltaPercent(a, b float64) *float64 {
+	if a == 0 {
+		return nil
+	}
+	percent := (b - a) / math.Abs(a) * 100
+	return &percent
+}
+
+// parsePeriod parses a YYYY-MM month into its first day
+func parsePeriod(period string) (time.Time, error) {
+	start, err := time.Parse("2006-01", period)
+	if err != nil {
+		return time.Time{}, ErrInvalidPeriod
+	}
+	return start, nil
+}
+
+// comparePeriods builds the comparison of two periods' expense totals
+func comparePeriods(a, b []currencyTotal) PeriodComparison {
+	amounts := make(map[string]*CategoryDelta)
+	var names []string
+	entry := func(category string) *CategoryDelta {
+		if category == "" {
+			category = utils.CategoryOther
 		}
-	} else {
-		sum.TopCategories = make(map[string]float64)
+		if _, ok := amounts[category]; !ok {
+			amounts[category] = &CategoryDelta{Category: category}
+			names = append(names, category)
+		}
+		return amounts[category]
 	}
 
-	// Cache the result
-	s.Cache.Set(cacheKey, sum)
+	var cmp PeriodComparison
+	for _, t := range a {
+		cmp.TotalA += t.Total
+		entry(t.Category).AmountA += t.Total
+	}
+	for _, t := range b {
+		cmp.TotalB += t.Total
+		entry(t.Category).AmountB += t.Total
+	}
+	cmp.Delta = cmp.TotalB - cmp.TotalA
+	cmp.DeltaPercent = deltaPercent(cmp.TotalA, cmp.TotalB)
+
+	cmp.Categories = make([]CategoryDelta, 0, len(names))
+	for _, name := range names {
+		d := amounts[name]
+		d.Delta = d.AmountB - d.AmountA
+		d.DeltaPercent = deltaPercent(d.AmountA, d.AmountB)
+		cmp.Categories = append(cmp.Categories, *d)
+	}
+	sort.SliceStable(cmp.Categories, func(i, j int) bool {
+		di, dj := math.Abs(cmp.Categories[i].Delta), math.Abs(cmp.Categories[j].Delta)
+		if di != dj {
+			return di > dj
+		}
+		return cmp.Categories[i].Category < cmp.Categories[j].Category
+	})
+	return cmp
+}
 
-	return sum, nil
+// Compare returns the change in spending between two months written as
+// YYYY-MM. An empty periodA defaults to last month and an empty periodB to
+// this month, both in the user's timezone.
+func (s *SummaryService) Compare(uid uint, periodA, periodB string) (PeriodComparison, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	now := time.Now().In(UserLocation(s.DB.WithContext(ctx), uid))
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if periodA == "" {
+		periodA = thisMonth.AddDate(0, -1, 0).Format("2006-01")
+	}
+	if periodB == "" {
+		periodB = thisMonth.Format("2006-01")
+	}
+	startA, err := parsePeriod(periodA)
+	if err != nil {
+		return PeriodComparison{}, err
+	}
+	startB, err := parsePeriod(periodB)
+	if err != nil {
+		return PeriodComparison{}, err
+	}
+
+	display := s.displayCurrency(ctx, uid)
+	totals := make([][]currencyTotal, 2)
+	for i, start := range []time.Time{startA, startB} {
+		totals[i], err = s.aggregateInCurrency(ctx, display, "user_id = ? AND date >= ? AND date < ? AND type = 'expense'",
+			uid, start.Format("2006-01-02"), start.AddDate(0, 1, 0).Format("2006-01-02"))
+		if err != nil {
+			return PeriodComparison{}, err
+		}
+	}
+
+	cmp := comparePeriods(totals[0], totals[1])
+	cmp.PeriodA = startA.Format("2006-01")
+	cmp.PeriodB = startB.Format("2006-01")
+	cmp.Currency = display
+	return cmp, nil
 }
 
-// GetCategoryBreakdown efficiently gets expense breakdown by category
-func (s *SummaryService) GetCategoryBreakdown(uid uint, startDate, endDate string) (map[string]float64, error) {
-	cacheKey := fmt.Sprintf("category_breakdown:%d:%s:%s", uid, startDate, endDate)
-	if cached, found := s.Cache.Get(cacheKey); found {
-		if breakdown, ok := cached.(map[string]float64); ok {
-			return breakdown, nil
+// Trend granularities accepted by Trends
+const (
+	TrendGranularityMonth = "month"
+	TrendGranularityWeek  = "week"
+)
+
+// Trends holds income, expense and net savings totals per period. The
+// arrays are aligned with Periods, oldest first, and empty periods are zero.
+type Trends struct {
+	Granularity string    `json:"granularity"`
+	Currency    string    `json:"currency"`
+	Periods     []string  `json:"periods"` // start date of each bucket, YYYY-MM-DD
+	Income      []float64 `json:"income"`
+	Expenses    []float64 `json:"expenses"`
+	NetSavings  []float64 `json:"net_savings"`
+}
+
+// trendBuckets returns the start date of every bucket covering the last
+// months calendar months up to today. Expense dates are plain calendar
+// dates, so bucket boundaries are computed in UTC to avoid any offset
+// shifting a date into the neighbouring bucket.
+func trendBuckets(granularity string, months int, today time.Time) []time.Time {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	first := time.Date(today.Year(), today.Month()-time.Month(months-1), 1, 0, 0, 0, 0, time.UTC)
+
+	var buckets []time.Time
+	switch granularity {
+	case TrendGranularityWeek:
+		// Weeks start on Monday, matching Postgres date_trunc('week')
+		start := first.AddDate(0, 0, -((int(first.Weekday()) + 6) % 7))
+		for d := start; !d.After(today); d = d.AddDate(0, 0, 7) {
+			buckets = append(buckets, d)
+		}
+	default:
+		for d := first; !d.After(today); d = d.AddDate(0, 1, 0) {
+			buckets = append(buckets, d)
 		}
 	}
+	return buckets
+}
 
-	// Use context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Trends returns per-month or per-week income, expenses and net savings for
+// the last months calendar months up to today in the user's timezone,
+// converted into the user's display currency
+func (s *SummaryService) Trends(uid uint, granularity string, months int) (Trends, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	var results []struct {
-		Category string  `json:"category"`
-		Total    float64 `json:"total"`
+	if granularity != TrendGranularityWeek {
+		granularity = TrendGranularityMonth
+	}
+	if months < 1 {
+		months = 1
 	}
 
-	err := s.DB.WithContext(ctx).Raw(`
-		SELECT category, SUM(amount) as total
-		FROM expenses 
-		WHERE user_id = ? AND date >= ? AND date <= ? AND type = 'expense'
-		GROUP BY category 
-		ORDER BY total DESC
-	`, uid, startDate, endDate).Scan(&results).Error
+	display := s.displayCurrency(ctx, uid)
+	today := time.Now().In(UserLocation(s.DB.WithContext(ctx), uid))
+
+	cacheKey := fmt.Sprintf("summary_trends:%d:%s:%d:%s:%s", uid, granularity, months, display, today.Format("2006-01-02"))
+	if cached, found := s.Cache.Get(cacheKey); found {
+		if trends, ok := cached.(Trends); ok {
+			return trends, nil
+		}
+	}
 
+	buckets := trendBuckets(granularity, months, today)
+	trends := Trends{
+		Granularity: granularity,
+		Currency:    display,
+		Periods:     make([]string, len(buckets)),
+		Income:      make([]float64, len(buckets)),
+		Expenses:    make([]float64, len(buckets)),
+		NetSavings:  make([]float64, len(buckets)),
+	}
+	index := make(map[string]int, len(buckets))
+	for i, bucket := range buckets {
+		trends.Periods[i] = bucket.Format("2006-01-02")
+		index[trends.Periods[i]] = i
+	}
+
+	// date is stored as text; casting through timestamp (not timestamptz)
+	// keeps the session time zone out of the bucketing
+	var rows []struct {
+		Bucket   string
+		Type     string
+		Currency string
+		Total    float64
+	}
+	err := s.DB.WithContext(ctx).Raw(`
+		SELECT
+			to_char(date_trunc(?, date::timestamp), 'YYYY-MM-DD') as bucket,
+			type,
+			COALESCE(NULLIF(currency, ''), '`+utils.DefaultCurrency+`') as currency,
+			SUM(amount) as total
+		FROM expenses
+		WHERE deleted_at IS NULL AND user_id = ? AND date >= ? AND date <= ?
+		GROUP BY 1, 2, 3
+	`, granularity, uid, buckets[0].Format("2006-01-02"), today.Format("2006-01-02")).Scan(&rows).Error
 	if err != nil {
-		return nil, err
+		return trends, err
 	}
 
-	breakdown := make(map[string]float64)
-	for _, result := range results {
-		if result.Category != "" {
-			breakdown[result.Category] = result.Total
+	for _, row := range rows {
+		i, ok := index[row.Bucket]
+		if !ok {
+			continue
+		}
+		converted, err := utils.ConvertAmount(s.Rates, row.Total, row.Currency, display)
+		if err != nil {
+			return trends, fmt.Errorf("failed to convert %s totals: %w", row.Currency, err)
 		}
+		switch row.Type {
+		case "income":
+			trends.Income[i] += converted
+		case "expense":
+			trends.Expenses[i] += converted
+		}
+	}
+	for i := range buckets {
+		trends.NetSavings[i] = trends.Income[i] - trends.Expenses[i]
 	}
 
-	// Cache the result
-	s.Cache.Set(cacheKey, breakdown)
+	s.Cache.Set(cacheKey, trends)
+
+	return trends, nil
+}
+
+// UnknownCity labels spend on expenses without a city
+const UnknownCity = "Unknown"
+
+// CitySpend is the spending recorded in one city
+type CitySpend struct {
+	City  string  `json:"city"`
+	Total float64 `json:"total"`
+	Count int64   `json:"count"`
+}
+
+// LocationSpend is the user's spending grouped by the city of each expense,
+// largest first, in the user's display currency
+type LocationSpend struct {
+	Currency string      `json:"currency"`
+	Cities   []CitySpend `json:"cities"`
+}
+
+// SpendByCity totals the user's expenses between startDate and endDate
+// (inclusive, YYYY-MM-DD; either may be empty for no bound) per city.
+// Cities are matched case-insensitively and expenses without one are
+// reported as UnknownCity.
+func (s *SummaryService) SpendByCity(uid uint, startDate, endDate string) (LocationSpend, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	display := s.displayCurrency(ctx, uid)
+	spend := LocationSpend{Currency: display, Cities: []CitySpend{}}
+
+	query := s.DB.WithContext(ctx).Model(&models.Expense{}).
+		Select(`
+			COALESCE(LOWER(TRIM(city)), '') as city_key,
+			COALESCE(MIN(TRIM(city)), '') as city,
+			COALESCE(NULLIF(currency, ''), ?) as currency,
+			SUM(amount) as total,
+			COUNT(*) as count`, utils.DefaultCurrency).
+		Where("user_id = ? AND type = 'expense'", uid).
+		Group("1, 3")
+	if startDate != "" {
+		query = query.Where("date >= ?", startDate)
+	}
+	if endDate != "" {
+		query = query.Where("date <= ?", endDate)
+	}
+	var rows []struct {
+		CityKey  string
+		City     string
+		Currency string
+		Total    float64
+		Count    int64
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return spend, err
+	}
 
-	return breakdown, nil
+	// Merge rows that differ only by currency once converted
+	byCity := make(map[string]*CitySpend)
+	for _, row := range rows {
+		converted, err := utils.ConvertAmount(s.Rates, row.Total, row.Currency, display)
+		if err != nil {
+			return spend, fmt.Errorf("failed to convert %s totals: %w", row.Currency, err)
+		}
+		city, ok := byCity[row.CityKey]
+		if !ok {
+			name := row.City
+			if name == "" {
+				name = UnknownCity
+			}
+			city = &CitySpend{City: name}
+			byCity[row.CityKey] = city
+		}
+		city.Total += converted
+		city.Count += row.Count
+	}
+
+	for _, city := range byCity {
+		spend.Cities = append(spend.Cities, *city)
+	}
+	sort.Slice(spend.Cities, func(i, j int) bool {
+		if spend.Cities[i].Total != spend.Cities[j].Total {
+			return spend.Cities[i].Total > spend.Cities[j].Total
+		}
+		return spend.Cities[i].City < spend.Cities[j].City
+	})
+	return spend, nil
 }
 
-// InvalidateUserCache removes cache entries for a specific user
+// InvalidateUserCache removes the user's cached summaries, leaving other
+// users' entries in place
 func (s *SummaryService) InvalidateUserCache(uid uint) {
-	// Clear all cache entries for this user
-	// TODO: Implement selective cache invalidation based on user ID
-	// For now, clear all cache when user data changes for simplicity
-	_ = uid // Use uid parameter (will be used in future implementation)
-	s.Cache.Clear()
+	s.Cache.DeleteFunc(func(key string) bool { return isUserCacheKey(key, uid) })
 }
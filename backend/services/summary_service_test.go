@@ -0,0 +1,640 @@
+package services
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+	"github.com/your-github/expense-tracker-backend/utils"
+)
+
+// fixedRates quotes every currency against INR: one unit of a currency is
+// worth inINR rupees
+type fixedRates map[string]float64
+
+func (r fixedRates) Rates(base string) (map[string]float64, error) {
+	baseInINR, ok := r[base]
+	if !ok {
+		return nil, errors.New("no rates for " + base)
+	}
+	rates := make(map[string]float64, len(r))
+	for code, inINR := range r {
+		rates[code] = baseInINR / inINR
+	}
+	return rates, nil
+}
+
+var testRates = fixedRates{"INR": 1, "USD": 83, "EUR": 90}
+
+// totalsColumns are the columns of the per-currency aggregate query
+var totalsColumns = []string{"type", "category", "currency", "total"}
+
+// newSummaryFixture returns a summary service for a user whose display
+// currency is display, in UTC
+func newSummaryFixture(t *testing.T, display string) (*SummaryService, *testutil.StubDB) {
+	t.Helper()
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`SELECT "currency" FROM "users"`, []string{"currency"}, []driver.Value{display})
+	stub.On(`SELECT "timezone" FROM "users"`, []string{"timezone"}, []driver.Value{"UTC"})
+	service := NewSummaryService(db, testRates, 1)
+	t.Cleanup(service.Close)
+	return service, stub
+}
+
+func TestMonthlyConvertsMixedCurrencies(t *testing.T) {
+	service, stub := newSummaryFixture(t, "INR")
+	stub.On(`FROM expenses`, totalsColumns,
+		[]driver.Value{"expense", "Food & Dining", "INR", 1000.0},
+		[]driver.Value{"expense", "Food & Dining", "USD", 10.0},
+		[]driver.Value{"expense", "Travel", "EUR", 20.0},
+		[]driver.Value{"income", "Salary", "INR", 50000.0},
+		[]driver.Value{"income", "Salary", "EUR", 100.0},
+	)
+
+	sum, err := service.Monthly(7, 10000, 2025, time.March, false)
+	if err != nil {
+		t.Fatalf("Monthly: %v", err)
+	}
+	if sum.Currency != "INR" {
+		t.Errorf("currency = %s, want INR", sum.Currency)
+	}
+	if sum.TotalExpenses != 3630 {
+		t.Errorf("total expenses = %v, want 1000 + 10 USD + 20 EUR = 3630", sum.TotalExpenses)
+	}
+	if sum.TotalIncome != 59000 {
+		t.Errorf("total income = %v, want 50000 + 100 EUR = 59000", sum.TotalIncome)
+	}
+	if got := sum.TopCategories["Food & Dining"]; got != 1830 {
+		t.Errorf("Food & Dining = %v, want the INR and USD rows merged into 1830", got)
+	}
+	if sum.RemainingBudget != 10000-3630 {
+		t.Errorf("remaining budget = %v, want %v", sum.RemainingBudget, 10000-3630)
+	}
+}
+
+func TestMonthlyConvertsIntoTheDisplayCurrency(t *testing.T) {
+	service, stub := newSummaryFixture(t, "USD")
+	stub.On(`FROM expenses`, totalsColumns,
+		[]driver.Value{"expense", "Food & Dining", "INR", 830.0},
+		[]driver.Value{"expense", "Food & Dining", "USD", 10.0},
+	)
+
+	sum, err := service.Monthly(7, 0, 2025, time.March, false)
+	if err != nil {
+		t.Fatalf("Monthly: %v", err)
+	}
+	if sum.Currency != "USD" || sum.TotalExpenses != 20 {
+		t.Errorf("total = %v %s, want 20 USD", sum.TotalExpenses, sum.Currency)
+	}
+}
+
+func TestMonthlyFailsWithoutARate(t *testing.T) {
+	service, stub := newSummaryFixture(t, "INR")
+	stub.On(`FROM expenses`, totalsColumns, []driver.Value{"expense", "Food & Dining", "GBP", 10.0})
+
+	if _, err := service.Monthly(7, 0, 2025, time.March, false); err == nil {
+		t.Error("summed an amount that couldn't be converted")
+	}
+}
+
+func TestTrendBuckets(t *testing.T) {
+	today := time.Date(2025, time.March, 12, 23, 30, 0, 0, time.FixedZone("IST", 5*3600+1800))
+
+	months := trendBuckets(TrendGranularityMonth, 3, today)
+	want := []string{"2025-01-01", "2025-02-01", "2025-03-01"}
+	if len(months) != len(want) {
+		t.Fatalf("month buckets = %v, want %v", months, want)
+	}
+	for i, bucket := range months {
+		if bucket.Format("2006-01-02") != want[i] {
+			t.Errorf("month bucket %d = %s, want %s", i, bucket.Format("2006-01-02"), want[i])
+		}
+	}
+
+	// 2025-02-01 is a Saturday, so the first week starts on Monday 27 January
+	weeks := trendBuckets(TrendGranularityWeek, 2, today)
+	if first := weeks[0].Format("2006-01-02"); first != "2025-01-27" {
+		t.Errorf("first week = %s, want 2025-01-27", first)
+	}
+	if last := weeks[len(weeks)-1].Format("2006-01-02"); last != "2025-03-10" {
+		t.Errorf("last week = %s, want the week of today, 2025-03-10", last)
+	}
+	for i, bucket := range weeks {
+		if bucket.Weekday() != time.Monday {
+			t.Errorf("week %d starts on %s", i, bucket.Weekday())
+		}
+	}
+}
+
+func TestTrendsFillEmptyBuckets(t *testing.T) {
+	for _, granularity := range []string{TrendGranularityMonth, TrendGranularityWeek} {
+		t.Run(granularity, func(t *testing.T) {
+			service, stub := newSummaryFixture(t, "INR")
+			buckets := trendBuckets(granularity, 3, time.Now().UTC())
+			first, last := buckets[0].Format("2006-01-02"), buckets[len(buckets)-1].Format("2006-01-02")
+			stub.On(`date_trunc`, []string{"bucket", "type", "currency", "total"},
+				[]driver.Value{first, "income", "INR", 50000.0},
+				[]driver.Value{first, "expense", "INR", 20000.0},
+				[]driver.Value{first, "expense", "USD", 100.0},
+				[]driver.Value{last, "expense", "INR", 1500.0},
+			)
+
+			trends, err := service.Trends(7, granularity, 3)
+			if err != nil {
+				t.Fatalf("Trends: %v", err)
+			}
+			if len(trends.Periods) != len(buckets) || len(trends.Income) != len(buckets) || len(trends.NetSavings) != len(buckets) {
+				t.Fatalf("trends = %+v, want %d aligned buckets", trends, len(buckets))
+			}
+			if trends.Income[0] != 50000 || trends.Expenses[0] != 28300 || trends.NetSavings[0] != 21700 {
+				t.Errorf("first bucket = %v/%v/%v, want 50000/28300/21700",
+					trends.Income[0], trends.Expenses[0], trends.NetSavings[0])
+			}
+			n := len(buckets) - 1
+			if trends.Expenses[n] != 1500 || trends.NetSavings[n] != -1500 {
+				t.Errorf("last bucket = %v/%v, want 1500 spent and -1500 saved", trends.Expenses[n], trends.NetSavings[n])
+			}
+			for i := 1; i < n; i++ {
+				if trends.Income[i] != 0 || trends.Expenses[i] != 0 || trends.NetSavings[i] != 0 {
+					t.Errorf("bucket %s = %v/%v/%v, want an empty bucket", trends.Periods[i],
+						trends.Income[i], trends.Expenses[i], trends.NetSavings[i])
+				}
+			}
+
+			if _, err := service.Trends(7, granularity, 3); err != nil {
+				t.Fatal(err)
+			}
+			if ran := len(stub.Ran(`date_trunc`)); ran != 1 {
+				t.Errorf("ran the trends query %d times, want the second call cached", ran)
+			}
+		})
+	}
+}
+
+func TestDaysElapsed(t *testing.T) {
+	start := time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	tests := []struct {
+		name string
+		now  time.Time
+		want int
+	}{
+		{"current month", time.Date(2025, time.February, 10, 15, 0, 0, 0, time.UTC), 10},
+		{"first day", start, 1},
+		{"past month", time.Date(2025, time.April, 5, 0, 0, 0, 0, time.UTC), 28},
+		{"future month", time.Date(2025, time.January, 20, 0, 0, 0, 0, time.UTC), 0},
+	}
+	for _, tt := range tests {
+		if got := daysElapsed(start, end, tt.now); got != tt.want {
+			t.Errorf("%s: daysElapsed = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMonthlyAverageDaily(t *testing.T) {
+	now := time.Now().UTC()
+	tests := []struct {
+		name        string
+		year        int
+		month       time.Month
+		wantAverage float64
+	}{
+		{"past month", 2024, time.February, 2900.0 / 29},
+		{"current month", now.Year(), now.Month(), 2900.0 / float64(now.Day())},
+		{"future month", now.Year() + 1, now.Month(), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, stub := newSummaryFixture(t, "INR")
+			stub.On(`FROM expenses`, totalsColumns, []driver.Value{"expense", "Food & Dining", "INR", 2900.0})
+
+			sum, err := service.Monthly(7, 0, tt.year, tt.month, false)
+			if err != nil {
+				t.Fatalf("Monthly: %v", err)
+			}
+			if sum.AverageDaily != tt.wantAverage {
+				t.Errorf("average daily = %v, want %v", sum.AverageDaily, tt.wantAverage)
+			}
+		})
+	}
+}
+
+// monthTotals answers the aggregate query with rows keyed by the month
+// start date the query is bound to
+func monthTotals(stub *testutil.StubDB, byMonth map[string][][]driver.Value) {
+	stub.Handle(`FROM expenses`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		for _, arg := range args {
+			if rows, ok := byMonth[fmt.Sprint(arg)]; ok {
+				return testutil.StubResult{Columns: totalsColumns, Rows: rows}, nil
+			}
+		}
+		return testutil.StubResult{Columns: totalsColumns}, nil
+	})
+}
+
+func TestMonthlyRemainingBudgetIgnoresIncome(t *testing.T) {
+	service, stub := newSummaryFixture(t, "INR")
+	monthTotals(stub, map[string][][]driver.Value{
+		"2025-03-01": {
+			{"expense", "Food & Dining", "INR", 4000.0},
+			{"income", "Salary", "INR", 50000.0},
+		},
+	})
+
+	sum, err := service.Monthly(7, 10000, 2025, time.March, false)
+	if err != nil {
+		t.Fatalf("Monthly: %v", err)
+	}
+	if sum.RemainingBudget != 6000 || sum.RemainingWithRollover != 6000 || sum.Rollover != 0 {
+		t.Errorf("remaining = %v, with rollover %v (carried %v); want 6000 with nothing carried",
+			sum.RemainingBudget, sum.RemainingWithRollover, sum.Rollover)
+	}
+	if len(stub.Ran(`type = 'expense'`)) != 0 {
+		t.Error("queried the previous month with rollover disabled")
+	}
+}
+
+func TestMonthlyRollover(t *testing.T) {
+	tests := []struct {
+		name         string
+		previous     [][]driver.Value
+		wantRollover float64
+	}{
+		{"unused budget carries over", [][]driver.Value{{"expense", "Food & Dining", "INR", 7000.0}}, 3000},
+		{"overspending carries nothing", [][]driver.Value{{"expense", "Travel", "INR", 12000.0}}, 0},
+		{"empty previous month", nil, 10000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, stub := newSummaryFixture(t, "INR")
+			service.Rollover = true
+			monthTotals(stub, map[string][][]driver.Value{
+				"2025-02-01": tt.previous,
+				"2025-03-01": {
+					{"expense", "Food & Dining", "INR", 4000.0},
+					{"income", "Salary", "INR", 50000.0},
+				},
+			})
+
+			sum, err := service.Monthly(7, 10000, 2025, time.March, false)
+			if err != nil {
+				t.Fatalf("Monthly: %v", err)
+			}
+			if sum.RemainingBudget != 6000 {
+				t.Errorf("raw remaining = %v, want 6000", sum.RemainingBudget)
+			}
+			if sum.Rollover != tt.wantRollover || sum.RemainingWithRollover != 6000+tt.wantRollover {
+				t.Errorf("rollover = %v, remaining with rollover %v; want %v and %v",
+					sum.Rollover, sum.RemainingWithRollover, tt.wantRollover, 6000+tt.wantRollover)
+			}
+		})
+	}
+}
+
+func TestRefreshBypassesAndRepopulatesTheCache(t *testing.T) {
+	service, stub := newSummaryFixture(t, "INR")
+	spent := 500.0
+	stub.Handle(`FROM expenses`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		return testutil.StubResult{Columns: totalsColumns, Rows: [][]driver.Value{{"expense", "Food & Dining", "INR", spent}}}, nil
+	})
+	lifetime := func(refresh bool) float64 {
+		sum, err := service.Lifetime(7, refresh)
+		if err != nil {
+			t.Fatalf("Lifetime: %v", err)
+		}
+		return sum.TotalExpenses
+	}
+
+	if got := lifetime(false); got != 500 {
+		t.Fatalf("first read = %v, want 500", got)
+	}
+	spent = 800 // an import lands after the summary was cached
+	if got := lifetime(false); got != 500 {
+		t.Errorf("cached read = %v, want the cached 500", got)
+	}
+	if got := lifetime(true); got != 800 {
+		t.Errorf("refresh = %v, want the fresh 800", got)
+	}
+	spent = 900
+	if got := lifetime(false); got != 800 {
+		t.Errorf("read after refresh = %v, want the refreshed 800 from the cache", got)
+	}
+}
+
+func TestInvalidateUserCacheKeepsOtherUsers(t *testing.T) {
+	service := NewSummaryService(nil, testRates, 4)
+	t.Cleanup(service.Close)
+	for _, key := range []string{"summary_lifetime:7:INR", "category_breakdown:7:2025-01-01:2025-01-31:INR", "summary_lifetime:8:INR", "summary_lifetime:77:INR"} {
+		service.Cache.Set(key, Summary{})
+	}
+
+	service.InvalidateUserCache(7)
+	for key, want := range map[string]bool{
+		"summary_lifetime:7:INR": false, "category_breakdown:7:2025-01-01:2025-01-31:INR": false,
+		"summary_lifetime:8:INR": true, "summary_lifetime:77:INR": true,
+	} {
+		if _, ok := service.Cache.Get(key); ok != want {
+			t.Errorf("%s cached = %v, want %v", key, ok, want)
+		}
+	}
+}
+
+func TestFinancialHealthMergesCategoryAliases(t *testing.T) {
+	service, stub := newSummaryFixture(t, "INR")
+	stub.On(`FROM expenses`, totalsColumns,
+		[]driver.Value{"expense", "Food", "INR", 30000.0},
+		[]driver.Value{"expense", "Food Delivery", "INR", 20000.0},
+		[]driver.Value{"expense", "Transportation", "INR", 10000.0},
+		[]driver.Value{"income", "Salary", "INR", 100000.0},
+	)
+
+	health, err := service.FinancialHealth(7, false)
+	if err != nil {
+		t.Fatalf("FinancialHealth: %v", err)
+	}
+	if health.SavingsRate != 40 || health.Currency != "INR" {
+		t.Errorf("health = %+v, want a 40%% savings rate in INR", health)
+	}
+	// Food and Food Delivery are one category: shares of 5/6 and 1/6
+	if health.TopCategory != "Food & Dining" || health.Concentration < 0.72 || health.Concentration > 0.73 {
+		t.Errorf("concentration %v led by %q, want the aliases merged", health.Concentration, health.TopCategory)
+	}
+}
+
+func TestCompareReportsNewAndDroppedCategories(t *testing.T) {
+	service, stub := newSummaryFixture(t, "INR")
+	monthTotals(stub, map[string][][]driver.Value{
+		"2025-02-01": {
+			{"expense", "Food", "INR", 1000.0},
+			{"expense", "Travel", "INR", 500.0},
+		},
+		"2025-03-01": {
+			{"expense", "Food", "INR", 1500.0},
+			{"expense", "Shopping", "USD", 3.0},
+		},
+	})
+
+	cmp, err := service.Compare(1, "2025-02", "2025-03")
+	if err != nil {
+		t.Fatalf("Compare: %v", err)
+	}
+	if cmp.PeriodA != "2025-02" || cmp.PeriodB != "2025-03" || cmp.Currency != "INR" {
+		t.Errorf("comparison covers %s to %s in %s", cmp.PeriodA, cmp.PeriodB, cmp.Currency)
+	}
+	// The USD purchase is converted before it is compared
+	if cmp.TotalA != 1500 || cmp.TotalB != 1749 || cmp.Delta != 249 || cmp.DeltaPercent == nil || *cmp.DeltaPercent != 16.6 {
+		t.Errorf("totals = %v to %v, delta %v (%v%%)", cmp.TotalA, cmp.TotalB, cmp.Delta, cmp.DeltaPercent)
+	}
+
+	want := []struct {
+		category         string
+		amountA, amountB float64
+		percent          *float64
+	}{
+		{"Food", 1000, 1500, floatPtr(50.0)},
+		{"Travel", 500, 0, floatPtr(-100.0)}, // dropped
+		{"Shopping", 0, 249, nil},            // new: no percentage against zero
+	}
+	if len(cmp.Categories) != len(want) {
+		t.Fatalf("categories = %+v", cmp.Categories)
+	}
+	for i, w := range want {
+		got := cmp.Categories[i]
+		if got.Category != w.category || got.AmountA != w.amountA || got.AmountB != w.amountB || got.Delta != w.amountB-w.amountA {
+			t.Errorf("category %d = %+v, want %s from %v to %v", i, got, w.category, w.amountA, w.amountB)
+		}
+		if (got.DeltaPercent == nil) != (w.percent == nil) || (w.percent != nil && *got.DeltaPercent != *w.percent) {
+			t.Errorf("%s delta percent = %v, want %v", got.Category, got.DeltaPercent, w.percent)
+		}
+	}
+}
+
+func floatPtr(v float64) *float64 { return &v }
+
+func TestCompareWithoutSpendingGuardsTheDivision(t *testing.T) {
+	service, _ := newSummaryFixture(t, "INR")
+
+	cmp, err := service.Compare(1, "2025-02", "2025-03")
+	if err != nil {
+		t.Fatalf("Compare: %v", err)
+	}
+	if cmp.TotalA != 0 || cmp.Delta != 0 || cmp.DeltaPercent != nil || cmp.Categories == nil || len(cmp.Categories) != 0 {
+		t.Errorf("comparison = %+v, want zero totals, no percentage and an empty category list", cmp)
+	}
+}
+
+func TestComparePeriods(t *testing.T) {
+	service, stub := newSummaryFixture(t, "INR")
+
+	for _, bad := range [][2]string{{"2025-13", "2025-03"}, {"2025-02", "March"}, {"2025-02-01", ""}} {
+		if _, err := service.Compare(1, bad[0], bad[1]); !errors.Is(err, ErrInvalidPeriod) {
+			t.Errorf("Compare(%q, %q): %v, want ErrInvalidPeriod", bad[0], bad[1], err)
+		}
+	}
+	if n := len(stub.Ran(`FROM expenses`)); n != 0 {
+		t.Errorf("invalid periods ran %d aggregates", n)
+	}
+
+	cmp, err := service.Compare(1, "", "")
+	if err != nil {
+		t.Fatalf("Compare: %v", err)
+	}
+	now := time.Now().UTC()
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if cmp.PeriodA != thisMonth.AddDate(0, -1, 0).Format("2006-01") || cmp.PeriodB != thisMonth.Format("2006-01") {
+		t.Errorf("default periods = %s and %s, want last month and this month", cmp.PeriodA, cmp.PeriodB)
+	}
+}
+
+func TestCategoryRollup(t *testing.T) {
+	breakdown := map[string]float64{
+		"Food & Dining": 12000, "Shopping": 8000, "Travel": 8000, "Bills & Utilities": 5000,
+		"Education": 300, "Healthcare": 200, utils.CategoryOther: 500,
+	}
+	total := func(m map[string]float64) (sum float64) {
+		for _, amount := range m {
+			sum += amount
+		}
+		return sum
+	}
+
+	tests := []struct {
+		name   string
+		rollup CategoryRollup
+		want   map[string]float64
+	}{
+		{"no rollup", CategoryRollup{}, breakdown},
+		{"top 2, ties by name", CategoryRollup{TopN: 2}, map[string]float64{
+			"Food & Dining": 12000, "Shopping": 8000, utils.CategoryOther: 14000,
+		}},
+		{"min amount", CategoryRollup{MinAmount: 1000}, map[string]float64{
+			"Food & Dining": 12000, "Shopping": 8000, "Travel": 8000, "Bills & Utilities": 5000, utils.CategoryOther: 1000,
+		}},
+		{"both", CategoryRollup{TopN: 3, MinAmount: 8500}, map[string]float64{
+			"Food & Dining": 12000, utils.CategoryOther: 22000,
+		}},
+		{"top n above the category count", CategoryRollup{TopN: 10}, breakdown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.rollup.apply(breakdown)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for name, amount := range tt.want {
+				if got[name] != amount {
+					t.Errorf("%s = %v, want %v", name, got[name], amount)
+				}
+			}
+			if total(got) != total(breakdown) {
+				t.Errorf("total %v, want the unrolled %v", total(got), total(breakdown))
+			}
+		})
+	}
+
+	// Other only appears once something is rolled into it
+	noOther := map[string]float64{"Food & Dining": 100, "Travel": 50}
+	if got := (CategoryRollup{TopN: 2}).apply(noOther); len(got) != 2 {
+		t.Errorf("nothing rolled up, got %v", got)
+	}
+	if len(breakdown) != 7 || breakdown["Education"] != 300 {
+		t.Error("apply changed the breakdown it was given")
+	}
+}
+
+func TestGetCategoryBreakdownRollsUpTheCachedBreakdown(t *testing.T) {
+	service, stub := newSummaryFixture(t, "INR")
+	stub.On(`FROM expenses`, totalsColumns,
+		[]driver.Value{"expense", "Food & Dining", "INR", 4000.0},
+		[]driver.Value{"expense", "Travel", "USD", 10.0},
+		[]driver.Value{"expense", "Education", "INR", 150.0},
+		[]driver.Value{"expense", "Healthcare", "INR", 90.0},
+	)
+
+	full, err := service.GetCategoryBreakdown(7, "2025-03-01", "2025-03-31", CategoryRollup{}, false)
+	if err != nil {
+		t.Fatalf("GetCategoryBreakdown: %v", err)
+	}
+	rolled, err := service.GetCategoryBreakdown(7, "2025-03-01", "2025-03-31", CategoryRollup{TopN: 2}, false)
+	if err != nil {
+		t.Fatalf("GetCategoryBreakdown: %v", err)
+	}
+	if len(full) != 4 || full["Travel"] != 830 {
+		t.Errorf("full breakdown = %v", full)
+	}
+	if len(rolled) != 3 || rolled["Food & Dining"] != 4000 || rolled["Travel"] != 830 || rolled[utils.CategoryOther] != 240 {
+		t.Errorf("top 2 = %v, want Education and Healthcare in Other", rolled)
+	}
+	if n := len(stub.Ran(`FROM expenses`)); n != 1 {
+		t.Errorf("ran %d breakdown queries, want the rollup served from the cached one", n)
+	}
+}
+
+func TestSpendByCity(t *testing.T) {
+	service, stub := newSummaryFixture(t, "INR")
+	stub.On(`FROM "expenses"`, []string{"city_key", "city", "currency", "total", "count"},
+		[]driver.Value{"mumbai", "Mumbai", "INR", 1000.0, int64(2)},
+		[]driver.Value{"mumbai", "mumbai", "USD", 10.0, int64(1)},
+		[]driver.Value{"pune", "Pune", "INR", 500.0, int64(1)},
+		[]driver.Value{"", "", "INR", 200.0, int64(1)},
+	)
+
+	spend, err := service.SpendByCity(7, "2025-03-01", "2025-03-31")
+	if err != nil {
+		t.Fatalf("SpendByCity: %v", err)
+	}
+	want := []CitySpend{{City: "Mumbai", Total: 1830, Count: 3}, {City: "Pune", Total: 500, Count: 1}, {City: UnknownCity, Total: 200, Count: 1}}
+	if spend.Currency != "INR" || len(spend.Cities) != len(want) {
+		t.Fatalf("spend = %+v, want %+v", spend, want)
+	}
+	for i := range want {
+		if spend.Cities[i] != want[i] {
+			t.Errorf("city %d = %+v, want %+v", i, spend.Cities[i], want[i])
+		}
+	}
+
+	ran := stub.Ran(`FROM "expenses"`)
+	if len(ran) != 1 || !hasArg(ran[0].Args, uint(7)) || !hasArg(ran[0].Args, "2025-03-01") || !hasArg(ran[0].Args, "2025-03-31") {
+		t.Fatalf("query not limited to the user and dates: %+v", ran)
+	}
+	for _, part := range []string{"LOWER(TRIM(city))", "type = 'expense'", `"expenses"."deleted_at" IS NULL`} {
+		if !strings.Contains(ran[0].SQL, part) {
+			t.Errorf("query %s\nmissing %q", ran[0].SQL, part)
+		}
+	}
+}
+
+func TestSpendByCityWithoutExpenses(t *testing.T) {
+	service, _ := newSummaryFixture(t, "EUR")
+	spend, err := service.SpendByCity(7, "", "")
+	if err != nil || spend.Currency != "EUR" || spend.Cities == nil || len(spend.Cities) != 0 {
+		t.Errorf("spend = %+v, %v; want an empty list in EUR", spend, err)
+	}
+}
+
+func TestActivePeriodCustomTenDays(t *testing.T) {
+	service, stub := newSummaryFixture(t, "INR")
+	service.Rollover = true
+	today := time.Now().UTC()
+	// Today is the fourth day of a 10-day period
+	start := time.Date(today.Year(), today.Month(), today.Day()-3, 0, 0, 0, 0, time.UTC)
+	period, err := NewBudgetPeriod(BudgetPeriodCustom, start.AddDate(0, 0, -20).Format("2006-01-02"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	monthTotals(stub, map[string][][]driver.Value{
+		start.AddDate(0, 0, -10).Format("2006-01-02"): {{"expense", "Food & Dining", "INR", 3500.0}},
+		start.Format("2006-01-02"): {
+			{"expense", "Food & Dining", "INR", 2000.0},
+			{"income", "Salary", "INR", 50000.0},
+		},
+	})
+
+	sum, err := service.ActivePeriod(7, 5000, period, false)
+	if err != nil {
+		t.Fatalf("ActivePeriod: %v", err)
+	}
+	if sum.Period != BudgetPeriodCustom || sum.StartDate != start.Format("2006-01-02") || sum.EndDate != start.AddDate(0, 0, 9).Format("2006-01-02") {
+		t.Errorf("period = %s from %s to %s, want custom from %s", sum.Period, sum.StartDate, sum.EndDate, start.Format("2006-01-02"))
+	}
+	if sum.Days != 10 || sum.DaysElapsed != 4 || sum.DaysLeft != 6 {
+		t.Errorf("days = %d, elapsed %d, left %d; want 10, 4 and 6", sum.Days, sum.DaysElapsed, sum.DaysLeft)
+	}
+	if sum.TotalExpenses != 2000 || sum.AverageDaily != 500 || sum.RemainingBudget != 3000 {
+		t.Errorf("spent %v (%v a day), remaining %v; want 2000, 500 and 3000", sum.TotalExpenses, sum.AverageDaily, sum.RemainingBudget)
+	}
+	if sum.Rollover != 1500 || sum.RemainingWithRollover != 4500 {
+		t.Errorf("rollover = %v, remaining with rollover %v; want 1500 from the previous period and 4500", sum.Rollover, sum.RemainingWithRollover)
+	}
+}
+
+func TestActivePeriodWeekly(t *testing.T) {
+	service, stub := newSummaryFixture(t, "INR")
+	period, _ := NewBudgetPeriod(BudgetPeriodWeekly, "", 0)
+	start, end := period.Bounds(time.Now().UTC())
+	monthTotals(stub, map[string][][]driver.Value{
+		start.Format("2006-01-02"): {{"expense", "Travel", "INR", 700.0}},
+	})
+
+	sum, err := service.ActivePeriod(7, 1000, period, false)
+	if err != nil {
+		t.Fatalf("ActivePeriod: %v", err)
+	}
+	if sum.Days != 7 || sum.DaysElapsed+sum.DaysLeft != 7 || sum.EndDate != end.AddDate(0, 0, -1).Format("2006-01-02") {
+		t.Errorf("period = %+v, want the 7 days from %s", sum, start.Format("2006-01-02"))
+	}
+	if start.Weekday() != time.Monday {
+		t.Errorf("week starts on %s, want Monday", start.Weekday())
+	}
+	if sum.TotalExpenses != 700 || sum.RemainingBudget != 300 {
+		t.Errorf("spent %v, remaining %v; want 700 and 300", sum.TotalExpenses, sum.RemainingBudget)
+	}
+	totals := stub.Ran(`FROM expenses`)
+	if len(totals) != 1 || !hasArg(totals[0].Args, end.Format("2006-01-02")) {
+		t.Errorf("totals queried as %+v, want the week ending %s", totals, end.Format("2006-01-02"))
+	}
+}
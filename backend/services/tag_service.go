@@ -0,0 +1,168 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/your-github/expense-tracker-backend/models"
+)
+
+// maxTagNameLength matches the size of the tags.name column
+const maxTagNameLength = 50
+
+var (
+	ErrInvalidTagName = errors.New("tag name must be 1-50 characters and cannot contain commas")
+	ErrTagExists      = errors.New("tag already exists")
+	ErrTagNotFound    = errors.New("tag not found")
+)
+
+type TagService struct {
+	DB       *gorm.DB
+	Expenses *ExpenseService // optional; its caches are cleared when expense tags change
+}
+
+// NewTagService creates a new tag service
+func NewTagService(db *gorm.DB, expenses *ExpenseService) *TagService {
+	return &TagService{DB: db, Expenses: expenses}
+}
+
+// NormalizeTagName lower-cases and trims a tag so "Work " and "work" are the same tag
+func NormalizeTagName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// List returns the user's tags ordered by name
+func (s *TagService) List(uid uint) ([]models.Tag, error) {
+	var tags []models.Tag
+	err := s.DB.Where("user_id = ?", uid).Order("name").Find(&tags).Error
+	return tags, err
+}
+
+// Create adds a tag for the user
+func (s *TagService) Create(uid uint, name string) (models.Tag, error) {
+	name = NormalizeTagName(name)
+	if name == "" || len(name) > maxTagNameLength || strings.Contains(name, ",") {
+		return models.Tag{}, ErrInvalidTagName
+	}
+
+	var existing models.Tag
+	if err := s.DB.Where("user_id = ? AND name = ?", uid, name).First(&existing).Error; err == nil {
+		return existing, ErrTagExists
+	}
+
+	tag := models.Tag{UserID: uid, Name: name}
+	if err := s.DB.Create(&tag).Error; err != nil {
+		return models.Tag{}, err
+	}
+	return tag, nil
+}
+
+// Delete removes a tag and detaches it from everything it was attached to
+func (s *TagService) Delete(uid, tagID uint) error {
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var tag models.Tag
+		if err := tx.Where("id = ? AND user_id = ?", tagID, uid).First(&tag).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTagNotFound
+			}
+			return err
+		}
+		if err := tx.Exec("DELETE FROM expense_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM transaction_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		// Hard delete so the name can be reused under the unique index
+		return tx.Unscoped().Delete(&tag).Error
+	})
+	if err == nil {
+		s.invalidateExpenses(uid)
+	}
+	return err
+}
+
+// AttachToExpense adds the given tags to one of the user's expenses
+func (s *TagService) AttachToExpense(uid, expenseID uint, tagIDs []uint) error {
+	var expense models.Expense
+	if err := s.DB.Where("id = ? AND user_id = ?", expenseID, uid).First(&expense).Error; err != nil {
+		return err
+	}
+	tags, err := s.userTags(uid, tagIDs)
+	if err != nil {
+		return err
+	}
+	if err := s.DB.Model(&expense).Association("Tags").Append(tags); err != nil {
+		return err
+	}
+	s.invalidateExpenses(uid)
+	return nil
+}
+
+// DetachFromExpense removes a tag from one of the user's expenses
+func (s *TagService) DetachFromExpense(uid, expenseID, tagID uint) error {
+	var expense models.Expense
+	if err := s.DB.Where("id = ? AND user_id = ?", expenseID, uid).First(&expense).Error; err != nil {
+		return err
+	}
+	tags, err := s.userTags(uid, []uint{tagID})
+	if err != nil {
+		return err
+	}
+	if err := s.DB.Model(&expense).Association("Tags").Delete(tags); err != nil {
+		return err
+	}
+	s.invalidateExpenses(uid)
+	return nil
+}
+
+// AttachToTransaction adds the given tags to one of the user's transactions
+func (s *TagService) AttachToTransaction(uid, transactionID uint, tagIDs []uint) error {
+	var txn models.Transaction
+	if err := s.DB.Where("id = ? AND user_id = ?", transactionID, uid).First(&txn).Error; err != nil {
+		return err
+	}
+	tags, err := s.userTags(uid, tagIDs)
+	if err != nil {
+		return err
+	}
+	return s.DB.Model(&txn).Association("Tags").Append(tags)
+}
+
+// DetachFromTransaction removes a tag from one of the user's transactions
+func (s *TagService) DetachFromTransaction(uid, transactionID, tagID uint) error {
+	var txn models.Transaction
+	if err := s.DB.Where("id = ? AND user_id = ?", transactionID, uid).First(&txn).Error; err != nil {
+		return err
+	}
+	tags, err := s.userTags(uid, []uint{tagID})
+	if err != nil {
+		return err
+	}
+	return s.DB.Model(&txn).Association("Tags").Delete(tags)
+}
+
+// userTags loads the requested tags, failing if any of them is not the user's
+func (s *TagService) userTags(uid uint, tagIDs []uint) ([]models.Tag, error) {
+	ids := make(map[uint]bool, len(tagIDs))
+	for _, id := range tagIDs {
+		ids[id] = true
+	}
+
+	var tags []models.Tag
+	if err := s.DB.Where("user_id = ? AND id IN ?", uid, tagIDs).Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	if len(tags) != len(ids) {
+		return nil, ErrTagNotFound
+	}
+	return tags, nil
+}
+
+func (s *TagService) invalidateExpenses(uid uint) {
+	if s.Expenses != nil {
+		s.Expenses.invalidateUserCache(uid)
+	}
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+)
+
+// tagColumns are the tags columns the stubs answer with
+var tagColumns = []string{"id", "user_id", "name"}
+
+func TestCreateTagNormalizesAndRejectsDuplicates(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	service := NewTagService(db, nil)
+
+	tag, err := service.Create(7, "  Work-Trip ")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if tag.Name != "work-trip" || tag.UserID != 7 {
+		t.Errorf("tag = %+v, want work-trip for user 7", tag)
+	}
+
+	stub.On(`FROM "tags"`, tagColumns, []driver.Value{int64(1), int64(7), "work-trip"})
+	if _, err := service.Create(7, "WORK-TRIP"); !errors.Is(err, ErrTagExists) {
+		t.Errorf("duplicate: %v, want ErrTagExists", err)
+	}
+	lookups := stub.Ran(`FROM "tags"`)
+	if args := lookups[len(lookups)-1].Args; args[0] != uint(7) {
+		t.Errorf("duplicate check ran for user %v, want it scoped to 7", args[0])
+	}
+
+	for _, name := range []string{"", "   ", "a,b", strings.Repeat("x", maxTagNameLength+1)} {
+		if _, err := service.Create(7, name); !errors.Is(err, ErrInvalidTagName) {
+			t.Errorf("Create(%q): %v, want ErrInvalidTagName", name, err)
+		}
+	}
+}
+
+func TestAttachMultipleTagsToExpense(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	service := NewTagService(db, nil)
+	stub.On(`FROM "expenses"`, []string{"id", "user_id", "title"}, []driver.Value{int64(3), int64(7), "Taxi"})
+	stub.On(`FROM "tags"`, tagColumns,
+		[]driver.Value{int64(1), int64(7), "work"},
+		[]driver.Value{int64(2), int64(7), "reimbursable"},
+	)
+
+	if err := service.AttachToExpense(7, 3, []uint{1, 2}); err != nil {
+		t.Fatalf("AttachToExpense: %v", err)
+	}
+	joins := stub.Ran(`INSERT INTO "expense_tags"`)
+	if len(joins) != 1 || len(joins[0].Args) != 4 {
+		t.Fatalf("join inserts = %+v, want one insert of two rows", joins)
+	}
+	if args := joins[0].Args; args[0] != uint(3) || args[1] != uint(1) || args[2] != uint(3) || args[3] != uint(2) {
+		t.Errorf("joined %v, want expense 3 with tags 1 and 2", args)
+	}
+}
+
+func TestAttachRejectsAnotherUsersTag(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	service := NewTagService(db, nil)
+	stub.On(`FROM "expenses"`, []string{"id", "user_id", "title"}, []driver.Value{int64(3), int64(7), "Taxi"})
+	// Tag 9 belongs to someone else, so only tag 1 comes back for user 7
+	stub.On(`FROM "tags"`, tagColumns, []driver.Value{int64(1), int64(7), "work"})
+
+	if err := service.AttachToExpense(7, 3, []uint{1, 9}); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("AttachToExpense: %v, want ErrTagNotFound", err)
+	}
+	if len(stub.Ran(`INSERT INTO "expense_tags"`)) != 0 {
+		t.Error("attached tags despite the foreign tag")
+	}
+}
+
+func TestListByTagsIntersectionAndUnion(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	service := NewExpenseService(db, 1)
+	t.Cleanup(service.Close)
+
+	tagged := map[int64][]string{
+		1: {"work", "reimbursable"},
+		2: {"work"},
+		3: {"reimbursable"},
+		4: {"personal"},
+	}
+	stub.Handle(`FROM "expenses"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		var names []string
+		for _, arg := range args {
+			if name, ok := arg.(string); ok {
+				names = append(names, name)
+			}
+		}
+		matchAll := strings.Contains(query, "HAVING")
+
+		result := testutil.StubResult{Columns: []string{"id", "user_id", "title"}}
+		for id := int64(1); id <= 4; id++ {
+			matched := 0
+			for _, tag := range tagged[id] {
+				for _, name := range names {
+					if tag == name {
+						matched++
+					}
+				}
+			}
+			if (matchAll && matched == len(names)) || (!matchAll && matched > 0) {
+				result.Rows = append(result.Rows, []driver.Value{id, int64(7), "expense"})
+			}
+		}
+		return result, nil
+	})
+
+	ids := func(matchAll bool) []uint {
+		expenses, err := service.ListByTags(7, []string{"Work", " reimbursable", "work"}, matchAll, 0)
+		if err != nil {
+			t.Fatalf("ListByTags: %v", err)
+		}
+		var out []uint
+		for _, e := range expenses {
+			out = append(out, e.ID)
+		}
+		return out
+	}
+
+	if got := ids(true); len(got) != 1 || got[0] != 1 {
+		t.Errorf("all of work and reimbursable = %v, want [1]", got)
+	}
+	if got := ids(false); len(got) != 3 {
+		t.Errorf("any of work and reimbursable = %v, want [1 2 3]", got)
+	}
+
+	queries := stub.Ran(`FROM "expenses"`)
+	if args := queries[0].Args; fmt.Sprint(args[len(args)-1]) != "2" {
+		t.Errorf("intersection counted %v tags, want the 2 distinct names", args[len(args)-1])
+	}
+}
@@ -1,16 +1,28 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"math/rand"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/your-github/expense-tracker-backend/models"
+	"github.com/your-github/expense-tracker-backend/utils"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TransactionService struct {
 	DB *gorm.DB
+
+	// Rand drives GenerateMockTransactions; set a seeded source to get
+	// reproducible mock data. Access is serialized by randMu.
+	Rand   *rand.Rand
+	randMu sync.Mutex
 }
 
 type MockTransaction struct {
@@ -26,12 +38,57 @@ type MockTransaction struct {
 	Location        string    `json:"location"`
 }
 
+// RecurringCharge is a merchant charge detected as repeating on a regular cadence
+type RecurringCharge struct {
+	Merchant      string    `json:"merchant"`
+	Category      string    `json:"category"`
+	AverageAmount float64   `json:"average_amount"`
+	Cadence       string    `json:"cadence"`
+	IntervalDays  float64   `json:"interval_days"`
+	Occurrences   int       `json:"occurrences"`
+	LastSeen      time.Time `json:"last_seen"`
+	NextExpected  time.Time `json:"next_expected"`
+}
+
+// MerchantSpend is a user's debit spending at one merchant
+type MerchantSpend struct {
+	Merchant string  `json:"merchant"`
+	Total    float64 `json:"total"`
+	Count    int64   `json:"count"`
+	Percent  float64 `json:"percent"` // share of all spending in the range
+}
+
+// unknownMerchant groups transactions that have no merchant name
+const unknownMerchant = "Unknown"
+
+// storeTransactionsBatchSize is the number of rows per INSERT when storing fetched transactions
+const storeTransactionsBatchSize = 100
+
+// transactionQueryTimeout bounds read queries on top of the caller's context
+const transactionQueryTimeout = 5 * time.Second
+
+// Recurring detection tuning
+const (
+	recurringMinOccurrences  = 3
+	recurringMinGapDays      = 28
+	recurringMaxGapDays      = 32
+	recurringAmountTolerance = 0.10 // charges within 10% of each other are treated as the same
+	recurringLookbackMonths  = 12
+)
+
 func NewTransactionService(db *gorm.DB) *TransactionService {
-	return &TransactionService{DB: db}
+	return &TransactionService{DB: db, Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
 }
 
 // GenerateMockTransactions generates realistic mock transactions for a bank account
 func (s *TransactionService) GenerateMockTransactions(bankAccountID uint, userID uint, count int) ([]MockTransaction, error) {
+	s.randMu.Lock()
+	defer s.randMu.Unlock()
+	if s.Rand == nil {
+		s.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	rng := s.Rand
+
 	var transactions []MockTransaction
 
 	// Categories for different transaction types
@@ -71,21 +128,21 @@ func (s *TransactionService) GenerateMockTransactions(bankAccountID uint, userID
 
 	for i := 0; i < count; i++ {
 		// Random date within the last 30 days
-		daysAgo := rand.Intn(30)
+		daysAgo := rng.Intn(30)
 		transactionDate := endDate.AddDate(0, 0, -daysAgo)
 
 		// Random transaction type (70% debit, 30% credit)
 		transactionType := "debit"
-		if rand.Float64() < 0.3 {
+		if rng.Float64() < 0.3 {
 			transactionType = "credit"
 		}
 
 		// Select category
 		categoryList := categories[transactionType]
-		category := categoryList[rand.Intn(len(categoryList))]
+		category := categoryList[rng.Intn(len(categoryList))]
 
 		// Generate amount based on category and type
-		amount := s.generateAmount(category, transactionType)
+		amount := s.generateAmount(rng, category, transactionType)
 
 		// Update balance
 		if transactionType == "debit" {
@@ -98,7 +155,7 @@ func (s *TransactionService) GenerateMockTransactions(bankAccountID uint, userID
 		merchantList := merchants[category]
 		merchantName := "Unknown"
 		if len(merchantList) > 0 {
-			merchantName = merchantList[rand.Intn(len(merchantList))]
+			merchantName = merchantList[rng.Intn(len(merchantList))]
 		}
 
 		// Generate description
@@ -110,11 +167,11 @@ func (s *TransactionService) GenerateMockTransactions(bankAccountID uint, userID
 			Description:     description,
 			Amount:          amount,
 			Type:            transactionType,
-			Category:        category,
+			Category:        utils.NormalizeCategory(category),
 			Balance:         currentBalance,
-			ReferenceNumber: fmt.Sprintf("REF%d", rand.Intn(999999)),
+			ReferenceNumber: fmt.Sprintf("REF%d", rng.Intn(999999)),
 			MerchantName:    merchantName,
-			Location:        s.generateLocation(),
+			Location:        s.generateLocation(rng),
 		}
 
 		transactions = append(transactions, transaction)
@@ -132,10 +189,40 @@ func (s *TransactionService) GenerateMockTransactions(bankAccountID uint, userID
 	return transactions, nil
 }
 
-// StoreTransactions stores transactions in the database
-func (s *TransactionService) StoreTransactions(transactions []MockTransaction, bankAccountID uint, userID uint) error {
+// StoreTransactions stores transactions in the database in a single DB
+// transaction, skipping any whose transaction_id is already stored
+func (s *TransactionService) StoreTransactions(ctx context.Context, transactions []MockTransaction, bankAccountID uint, userID uint) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+
+	// Load the IDs that already exist in one query rather than one per row.
+	// Soft-deleted rows count, so a transaction removed while reconciling
+	// isn't imported again.
+	ids := make([]string, 0, len(transactions))
 	for _, txn := range transactions {
-		transaction := models.Transaction{
+		ids = append(ids, txn.TransactionID)
+	}
+	var existingIDs []string
+	if err := s.DB.WithContext(ctx).Unscoped().Model(&models.Transaction{}).
+		Where("transaction_id IN ?", ids).
+		Pluck("transaction_id", &existingIDs).Error; err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(existingIDs)+len(transactions))
+	for _, id := range existingIDs {
+		seen[id] = true
+	}
+
+	// Skip transactions already stored, and repeats within this batch
+	var pending []models.Transaction
+	for _, txn := range transactions {
+		if seen[txn.TransactionID] {
+			continue
+		}
+		seen[txn.TransactionID] = true
+
+		pending = append(pending, models.Transaction{
 			UserID:          userID,
 			BankAccountID:   bankAccountID,
 			TransactionID:   txn.TransactionID,
@@ -149,49 +236,125 @@ func (s *TransactionService) StoreTransactions(transactions []MockTransaction, b
 			MerchantName:    txn.MerchantName,
 			Location:        txn.Location,
 			Status:          "completed",
-		}
+		})
+	}
+	if len(pending) == 0 {
+		return nil
+	}
 
-		// Check if transaction already exists
-		var existing models.Transaction
-		if err := s.DB.Where("transaction_id = ?", txn.TransactionID).First(&existing).Error; err == nil {
-			// Transaction already exists, skip
-			continue
-		}
+	// Insert everything or nothing
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Omit(clause.Associations).CreateInBatches(pending, storeTransactionsBatchSize).Error
+	})
+}
 
-		if err := s.DB.Create(&transaction).Error; err != nil {
-			return err
-		}
-	}
+// TransactionFilter narrows and orders a transaction history query
+type TransactionFilter struct {
+	Limit    int
+	Offset   int
+	SortBy   string // "date" (default) or "amount"
+	Asc      bool   // ascending order; newest/largest first by default
+	Type     string // "credit" or "debit"; empty matches both
+	Category string // exact, case-insensitive match; empty matches all
+
+	// From and To bound transaction_date to [From, To); zero values leave
+	// that side open
+	From time.Time
+	To   time.Time
+
+	MinAmount *float64 // inclusive
+	MaxAmount *float64 // inclusive
+	Merchant  string   // case-insensitive substring of merchant_name
+}
+
+// transactionSortColumns maps TransactionFilter.SortBy to columns
+var transactionSortColumns = map[string]string{
+	"date":   "transaction_date",
+	"amount": "amount",
+}
 
-	return nil
+// activeAccountTransactions excludes transactions of soft-deleted bank
+// accounts. Manual transactions have no bank account row and are kept.
+func activeAccountTransactions(db *gorm.DB) *gorm.DB {
+	return db.Where("NOT EXISTS (SELECT 1 FROM bank_accounts ba WHERE ba.id = transactions.bank_account_id AND ba.deleted_at IS NOT NULL)")
 }
 
-// GetTransactions retrieves transactions for a user, sorted by date
-func (s *TransactionService) GetTransactions(userID uint, limit int, offset int) ([]models.Transaction, error) {
+// likeEscaper escapes LIKE wildcards so user input matches literally
+// (backslash is Postgres' default LIKE escape character)
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// GetTransactions retrieves a page of a user's transactions matching filter,
+// along with the total number of matching transactions
+func (s *TransactionService) GetTransactions(ctx context.Context, userID uint, filter TransactionFilter) ([]models.Transaction, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, transactionQueryTimeout)
+	defer cancel()
+
 	var transactions []models.Transaction
+	var total int64
 
 	// Get all transactions (both bank and manual) from the transactions table
-	query := s.DB.Where("user_id = ?", userID).
-		Preload("BankAccount").
-		Order("transaction_date DESC")
+	query := s.DB.WithContext(ctx).Model(&models.Transaction{}).Scopes(activeAccountTransactions).Where("user_id = ?", userID)
+	if filter.Type != "" {
+		query = query.Where("LOWER(type) = ?", strings.ToLower(filter.Type))
+	}
+	if filter.Category != "" {
+		query = query.Where("LOWER(category) = ?", strings.ToLower(filter.Category))
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("transaction_date >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("transaction_date < ?", filter.To)
+	}
+	if filter.MinAmount != nil {
+		query = query.Where("amount >= ?", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		query = query.Where("amount <= ?", *filter.MaxAmount)
+	}
+	if merchant := strings.TrimSpace(filter.Merchant); merchant != "" {
+		query = query.Where("LOWER(merchant_name) LIKE ?", "%"+escapeLike(strings.ToLower(merchant))+"%")
+	}
 
-	if limit > 0 {
-		query = query.Limit(limit)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
 	}
 
-	if offset > 0 {
-		query = query.Offset(offset)
+	column, ok := transactionSortColumns[filter.SortBy]
+	if !ok {
+		column = transactionSortColumns["date"]
+	}
+	direction := "DESC"
+	if filter.Asc {
+		direction = "ASC"
+	}
+	// id breaks ties so pages don't overlap when sort values repeat
+	query = query.Preload("BankAccount").Order(column + " " + direction).Order("id " + direction)
+
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
 	}
 
 	err := query.Find(&transactions).Error
-	return transactions, err
+	return transactions, total, err
 }
 
 // GetTransactionsByBankAccount retrieves transactions for a specific bank account
-func (s *TransactionService) GetTransactionsByBankAccount(userID uint, bankAccountID uint, limit int, offset int) ([]models.Transaction, error) {
+func (s *TransactionService) GetTransactionsByBankAccount(ctx context.Context, userID uint, bankAccountID uint, limit int, offset int) ([]models.Transaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, transactionQueryTimeout)
+	defer cancel()
+
 	var transactions []models.Transaction
 
-	query := s.DB.Where("user_id = ? AND bank_account_id = ?", userID, bankAccountID).
+	query := s.DB.WithContext(ctx).Scopes(activeAccountTransactions).Where("user_id = ? AND bank_account_id = ?", userID, bankAccountID).
 		Preload("BankAccount").
 		Order("transaction_date DESC")
 
@@ -207,33 +370,226 @@ func (s *TransactionService) GetTransactionsByBankAccount(userID uint, bankAccou
 	return transactions, err
 }
 
+// MerchantBreakdown groups the user's debits in [start, end) by merchant,
+// largest spend first. Merchant names are compared case-insensitively and
+// blank names are reported as "Unknown". A limit of 0 returns every merchant;
+// percentages are always relative to all spending in the range.
+func (s *TransactionService) MerchantBreakdown(ctx context.Context, userID uint, start, end time.Time, limit int) ([]MerchantSpend, error) {
+	ctx, cancel := context.WithTimeout(ctx, transactionQueryTimeout)
+	defer cancel()
+
+	base := func() *gorm.DB {
+		return s.DB.WithContext(ctx).Model(&models.Transaction{}).Scopes(activeAccountTransactions).
+			Where("user_id = ? AND LOWER(type) = ? AND transaction_date >= ? AND transaction_date < ?", userID, "debit", start, end)
+	}
+
+	var totalSpend float64
+	if err := base().Select("COALESCE(SUM(amount), 0)").Scan(&totalSpend).Error; err != nil {
+		return nil, err
+	}
+
+	merchantKey := "COALESCE(NULLIF(LOWER(TRIM(merchant_name)), ''), '" + strings.ToLower(unknownMerchant) + "')"
+	query := base().
+		Select("MAX(COALESCE(NULLIF(TRIM(merchant_name), ''), '" + unknownMerchant + "')) as merchant, SUM(amount) as total, COUNT(*) as count").
+		Group(merchantKey).
+		Order("total DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	spends := []MerchantSpend{}
+	if err := query.Scan(&spends).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range spends {
+		if totalSpend > 0 {
+			spends[i].Percent = math.Round(spends[i].Total/totalSpend*10000) / 100
+		}
+	}
+	return spends, nil
+}
+
+// DetectRecurring finds debits that repeat roughly monthly for the same merchant
+// and a similar amount, such as subscriptions and standing instructions
+func (s *TransactionService) DetectRecurring(ctx context.Context, userID uint) ([]RecurringCharge, error) {
+	ctx, cancel := context.WithTimeout(ctx, transactionQueryTimeout)
+	defer cancel()
+
+	var transactions []models.Transaction
+	since := time.Now().AddDate(0, -recurringLookbackMonths, 0)
+	if err := s.DB.WithContext(ctx).Scopes(activeAccountTransactions).Where("user_id = ? AND LOWER(type) = ? AND transaction_date >= ?", userID, "debit", since).
+		Order("transaction_date ASC").
+		Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+
+	// Group by normalized merchant, then split each group into amount clusters
+	byMerchant := make(map[string][]models.Transaction)
+	var merchantOrder []string
+	for _, txn := range transactions {
+		key := recurringMerchantKey(txn)
+		if key == "" {
+			continue
+		}
+		if _, exists := byMerchant[key]; !exists {
+			merchantOrder = append(merchantOrder, key)
+		}
+		byMerchant[key] = append(byMerchant[key], txn)
+	}
+
+	recurring := make([]RecurringCharge, 0)
+	for _, key := range merchantOrder {
+		for _, cluster := range clusterByAmount(byMerchant[key]) {
+			if charge, ok := detectMonthlyCadence(cluster); ok {
+				recurring = append(recurring, charge)
+			}
+		}
+	}
+
+	sort.Slice(recurring, func(i, j int) bool {
+		return recurring[i].NextExpected.Before(recurring[j].NextExpected)
+	})
+	return recurring, nil
+}
+
+// recurringMerchantKey picks the merchant name, falling back to the description
+func recurringMerchantKey(txn models.Transaction) string {
+	name := txn.MerchantName
+	if name == "" || strings.EqualFold(name, "unknown") {
+		name = txn.Description
+	}
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// clusterByAmount groups chronologically ordered transactions whose amounts
+// are within recurringAmountTolerance of the cluster's running average
+func clusterByAmount(transactions []models.Transaction) [][]models.Transaction {
+	type cluster struct {
+		txns  []models.Transaction
+		total float64
+	}
+	var clusters []*cluster
+	for _, txn := range transactions {
+		var match *cluster
+		for _, c := range clusters {
+			avg := c.total / float64(len(c.txns))
+			if avg > 0 && math.Abs(txn.Amount-avg)/avg <= recurringAmountTolerance {
+				match = c
+				break
+			}
+		}
+		if match == nil {
+			match = &cluster{}
+			clusters = append(clusters, match)
+		}
+		match.txns = append(match.txns, txn)
+		match.total += txn.Amount
+	}
+
+	result := make([][]models.Transaction, 0, len(clusters))
+	for _, c := range clusters {
+		result = append(result, c.txns)
+	}
+	return result
+}
+
+// detectMonthlyCadence reports a recurring charge when the median gap between
+// consecutive occurrences falls inside the monthly window and most gaps do,
+// so a charge that once slipped by a few days still counts. One gap of about
+// two months is read as a single skipped month and counts as two periods.
+func detectMonthlyCadence(transactions []models.Transaction) (RecurringCharge, bool) {
+	if len(transactions) < recurringMinOccurrences {
+		return RecurringCharge{}, false
+	}
+
+	var totalAmount float64
+	gaps := make([]float64, 0, len(transactions))
+	inWindow := 0
+	skipped := false
+	for i, txn := range transactions {
+		totalAmount += txn.Amount
+		if i == 0 {
+			continue
+		}
+		gap := txn.TransactionDate.Sub(transactions[i-1].TransactionDate).Hours() / 24
+		switch {
+		case gap >= recurringMinGapDays && gap <= recurringMaxGapDays:
+			inWindow++
+			gaps = append(gaps, gap)
+		case !skipped && gap >= 2*recurringMinGapDays && gap <= 2*recurringMaxGapDays:
+			skipped = true
+			inWindow += 2
+			gaps = append(gaps, gap/2, gap/2)
+		default:
+			gaps = append(gaps, gap)
+		}
+	}
+	if inWindow*2 <= len(gaps) {
+		return RecurringCharge{}, false
+	}
+	medianGap := median(gaps)
+	if medianGap < recurringMinGapDays || medianGap > recurringMaxGapDays {
+		return RecurringCharge{}, false
+	}
+
+	last := transactions[len(transactions)-1]
+	merchant := last.MerchantName
+	if merchant == "" || strings.EqualFold(merchant, "unknown") {
+		merchant = last.Description
+	}
+
+	return RecurringCharge{
+		Merchant:      merchant,
+		Category:      last.Category,
+		AverageAmount: math.Round(totalAmount/float64(len(transactions))*100) / 100,
+		Cadence:       "monthly",
+		IntervalDays:  math.Round(medianGap*10) / 10,
+		Occurrences:   len(transactions),
+		LastSeen:      last.TransactionDate,
+		NextExpected:  last.TransactionDate.Add(time.Duration(medianGap * float64(24*time.Hour))),
+	}, true
+}
+
+// median returns the middle value of values, averaging the two middle values
+// of an even-length slice
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
 // Helper functions
-func (s *TransactionService) generateAmount(category, transactionType string) float64 {
+func (s *TransactionService) generateAmount(rng *rand.Rand, category, transactionType string) float64 {
 	switch category {
 	case "Salary":
-		return float64(rand.Intn(50000) + 30000) // 30k-80k
+		return float64(rng.Intn(50000) + 30000) // 30k-80k
 	case "Transfer":
-		return float64(rand.Intn(10000) + 1000) // 1k-11k
+		return float64(rng.Intn(10000) + 1000) // 1k-11k
 	case "Food & Dining":
-		return float64(rand.Intn(500) + 100) // 100-600
+		return float64(rng.Intn(500) + 100) // 100-600
 	case "Shopping":
-		return float64(rand.Intn(2000) + 200) // 200-2200
+		return float64(rng.Intn(2000) + 200) // 200-2200
 	case "Transportation":
-		return float64(rand.Intn(300) + 50) // 50-350
+		return float64(rng.Intn(300) + 50) // 50-350
 	case "Entertainment":
-		return float64(rand.Intn(1000) + 100) // 100-1100
+		return float64(rng.Intn(1000) + 100) // 100-1100
 	case "Utilities":
-		return float64(rand.Intn(2000) + 500) // 500-2500
+		return float64(rng.Intn(2000) + 500) // 500-2500
 	case "Healthcare":
-		return float64(rand.Intn(5000) + 500) // 500-5500
+		return float64(rng.Intn(5000) + 500) // 500-5500
 	case "Education":
-		return float64(rand.Intn(10000) + 1000) // 1k-11k
+		return float64(rng.Intn(10000) + 1000) // 1k-11k
 	case "Travel":
-		return float64(rand.Intn(15000) + 2000) // 2k-17k
+		return float64(rng.Intn(15000) + 2000) // 2k-17k
 	case "Insurance":
-		return float64(rand.Intn(5000) + 1000) // 1k-6k
+		return float64(rng.Intn(5000) + 1000) // 1k-6k
 	default:
-		return float64(rand.Intn(1000) + 100) // 100-1100
+		return float64(rng.Intn(1000) + 100) // 100-1100
 	}
 }
 
@@ -266,12 +622,12 @@ func (s *TransactionService) generateDescription(category, merchant, transaction
 	}
 }
 
-func (s *TransactionService) generateLocation() string {
+func (s *TransactionService) generateLocation(rng *rand.Rand) string {
 	locations := []string{
 		"Mumbai, Maharashtra", "Delhi, NCR", "Bangalore, Karnataka",
 		"Chennai, Tamil Nadu", "Kolkata, West Bengal", "Hyderabad, Telangana",
 		"Pune, Maharashtra", "Ahmedabad, Gujarat", "Jaipur, Rajasthan",
 		"Lucknow, Uttar Pradesh", "Online", "N/A",
 	}
-	return locations[rand.Intn(len(locations))]
+	return locations[rng.Intn(len(locations))]
 }
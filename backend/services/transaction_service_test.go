@@ -0,0 +1,605 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-github/expense-tracker-backend/models"
+
+	"github.com/your-github/expense-tracker-backend/internal/testutil"
+)
+
+// chargeSeries returns debits at merchant starting at start, each gap days
+// after the previous one
+func chargeSeries(merchant string, amount float64, start time.Time, gaps ...int) []models.Transaction {
+	txns := []models.Transaction{{MerchantName: merchant, Amount: amount, Type: "debit", TransactionDate: start}}
+	date := start
+	for _, gap := range gaps {
+		date = date.AddDate(0, 0, gap)
+		txns = append(txns, models.Transaction{MerchantName: merchant, Amount: amount, Type: "debit", TransactionDate: date})
+	}
+	return txns
+}
+
+func TestDetectMonthlyCadence(t *testing.T) {
+	start := time.Date(2025, time.January, 5, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		gaps     []int
+		want     bool
+		interval float64
+	}{
+		{"exact monthly", []int{30, 30, 30}, true, 30},
+		{"calendar months", []int{31, 28, 31, 30}, true, 30.5},
+		{"one charge slipped", []int{30, 35, 29, 31}, true, 30.5},
+		{"one month skipped", []int{30, 60, 31}, true, 30},
+		{"skip between three charges", []int{30, 60}, true, 30},
+		{"only a skip", []int{61, 30}, true, 30.5},
+		{"every month skipped", []int{60, 60, 60}, false, 0},
+		{"too few occurrences", []int{30}, false, 0},
+		{"weekly", []int{7, 7, 7, 7}, false, 0},
+		{"irregular", []int{3, 12, 45, 20}, false, 0},
+		{"mostly irregular", []int{30, 10, 50}, false, 0},
+		{"half in window", []int{30, 30, 45, 45}, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			charge, ok := detectMonthlyCadence(chargeSeries("Netflix", 499, start, tt.gaps...))
+			if ok != tt.want {
+				t.Fatalf("detected = %v, want %v", ok, tt.want)
+			}
+			if !ok {
+				return
+			}
+			if charge.IntervalDays != tt.interval {
+				t.Errorf("IntervalDays = %v, want %v", charge.IntervalDays, tt.interval)
+			}
+			wantNext := charge.LastSeen.Add(time.Duration(tt.interval * float64(24*time.Hour)))
+			if !charge.NextExpected.Equal(wantNext) {
+				t.Errorf("NextExpected = %v, want %v", charge.NextExpected, wantNext)
+			}
+		})
+	}
+}
+
+func TestDetectRecurringFlagsOnlyTrueRecurrences(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	start := time.Now().AddDate(0, -6, 0).Truncate(24 * time.Hour)
+
+	var series []models.Transaction
+	series = append(series, chargeSeries("Netflix", 499, start, 30, 31, 29, 30)...)
+	series = append(series, chargeSeries("Gold's Gym", 1500, start, 30, 34, 30)...)
+	series = append(series, chargeSeries("Swiggy", 350, start, 3, 12, 45, 20)...)
+	// A one-off purchase at the same merchant as a subscription
+	series = append(series, chargeSeries("Netflix", 4999, start.AddDate(0, 0, 40))...)
+	// Same merchant and amount but no merchant name on the transaction
+	for _, txn := range chargeSeries("", 129, start, 31, 30, 31) {
+		txn.Description = "Spotify"
+		series = append(series, txn)
+	}
+	sort.SliceStable(series, func(i, j int) bool { return series[i].TransactionDate.Before(series[j].TransactionDate) })
+
+	rows := make([][]driver.Value, 0, len(series))
+	for i, txn := range series {
+		rows = append(rows, []driver.Value{int64(i + 1), int64(1), txn.MerchantName, txn.Description, txn.Amount, txn.Type, txn.TransactionDate})
+	}
+	stub.On(`FROM "transactions"`, []string{"id", "user_id", "merchant_name", "description", "amount", "type", "transaction_date"}, rows...)
+
+	recurring, err := NewTransactionService(db).DetectRecurring(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("DetectRecurring: %v", err)
+	}
+
+	got := make(map[string]RecurringCharge)
+	for _, charge := range recurring {
+		got[charge.Merchant] = charge
+	}
+	if len(got) != 3 {
+		t.Fatalf("detected %v, want Netflix, Gold's Gym and Spotify", recurring)
+	}
+	for merchant, occurrences := range map[string]int{"Netflix": 5, "Gold's Gym": 4, "Spotify": 4} {
+		charge, ok := got[merchant]
+		if !ok {
+			t.Errorf("%s was not detected", merchant)
+			continue
+		}
+		if charge.Occurrences != occurrences {
+			t.Errorf("%s occurrences = %d, want %d", merchant, charge.Occurrences, occurrences)
+		}
+		if charge.Cadence != "monthly" {
+			t.Errorf("%s cadence = %q", merchant, charge.Cadence)
+		}
+	}
+	if got["Netflix"].AverageAmount != 499 {
+		t.Errorf("Netflix average = %v, the one-off purchase was clustered with the subscription", got["Netflix"].AverageAmount)
+	}
+	for i := 1; i < len(recurring); i++ {
+		if recurring[i].NextExpected.Before(recurring[i-1].NextExpected) {
+			t.Error("recurring charges are not ordered by next expected date")
+		}
+	}
+}
+
+func TestCancelledContextAbortsTransactionQueries(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	service := NewTransactionService(db)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, _, err := service.GetTransactions(ctx, 7, TransactionFilter{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetTransactions: %v, want context.Canceled", err)
+	}
+	if _, err := service.GetTransactionsByBankAccount(ctx, 7, 50, 10, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetTransactionsByBankAccount: %v, want context.Canceled", err)
+	}
+	fetched := []MockTransaction{{TransactionID: "TXN1", Amount: 100, Type: "debit"}}
+	if err := service.StoreTransactions(ctx, fetched, 50, 7); !errors.Is(err, context.Canceled) {
+		t.Errorf("StoreTransactions: %v, want context.Canceled", err)
+	}
+	if ran := stub.Ran(`"transactions"`); len(ran) != 0 {
+		t.Errorf("ran %d statements on a cancelled context", len(ran))
+	}
+}
+
+// fetchedTransactions returns n fetched transactions with IDs TXN1..TXNn
+func fetchedTransactions(n int) []MockTransaction {
+	txns := make([]MockTransaction, n)
+	for i := range txns {
+		txns[i] = MockTransaction{
+			TransactionID:   fmt.Sprintf("TXN%d", i+1),
+			TransactionDate: time.Date(2025, time.March, 1, 9, 0, 0, 0, time.UTC),
+			Amount:          float64(100 + i),
+			Type:            "debit",
+		}
+	}
+	return txns
+}
+
+func TestStoreTransactionsSkipsDuplicatesInOneTransaction(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	stub.On(`SELECT "transaction_id" FROM "transactions"`, []string{"transaction_id"}, []driver.Value{"TXN2"})
+
+	fetched := append(fetchedTransactions(3), fetchedTransactions(1)...) // TXN1 repeats within the batch
+	if err := NewTransactionService(db).StoreTransactions(context.Background(), fetched, 50, 7); err != nil {
+		t.Fatalf("StoreTransactions: %v", err)
+	}
+
+	lookups := stub.Ran(`SELECT "transaction_id" FROM "transactions"`)
+	if len(lookups) != 1 {
+		t.Errorf("looked up existing IDs %d times, want once", len(lookups))
+	} else if strings.Contains(lookups[0].SQL, "deleted_at") {
+		// A transaction removed while reconciling must not be imported again
+		t.Errorf("the lookup skips soft-deleted rows:\n%s", lookups[0].SQL)
+	}
+	inserts := stub.Ran(`INSERT INTO "transactions"`)
+	if len(inserts) != 1 {
+		t.Fatalf("ran %d inserts, want one batch", len(inserts))
+	}
+	var stored []string
+	for _, arg := range inserts[0].Args {
+		if id, ok := arg.(string); ok && strings.HasPrefix(id, "TXN") {
+			stored = append(stored, id)
+		}
+	}
+	if len(stored) != 2 || stored[0] != "TXN1" || stored[1] != "TXN3" {
+		t.Errorf("inserted %v, want TXN1 and TXN3", stored)
+	}
+	if len(stub.Ran("BEGIN")) != 1 || len(stub.Ran("COMMIT")) != 1 {
+		t.Error("the batch wasn't inserted in one committed transaction")
+	}
+}
+
+func TestStoreTransactionsRollsBackAPartialFailure(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	batches := 0
+	stub.Handle(`INSERT INTO "transactions"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		batches++
+		if batches == 2 {
+			return testutil.StubResult{}, errors.New("disk full")
+		}
+		return testutil.StubResult{Columns: []string{"id"}}, nil
+	})
+
+	err := NewTransactionService(db).StoreTransactions(context.Background(), fetchedTransactions(storeTransactionsBatchSize+20), 50, 7)
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("StoreTransactions = %v, want the failed batch's error", err)
+	}
+	if batches != 2 {
+		t.Errorf("ran %d batches, want to stop at the failing second one", batches)
+	}
+	if len(stub.Ran("ROLLBACK")) != 1 || len(stub.Ran("COMMIT")) != 0 {
+		t.Error("the first batch was committed despite the failure")
+	}
+}
+
+func BenchmarkStoreTransactions(b *testing.B) {
+	db, stub := testutil.NewStubDB(b)
+	stub.On(`INSERT INTO "transactions"`, []string{"id"})
+	service := NewTransactionService(db)
+	fetched := fetchedTransactions(250)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := service.StoreTransactions(context.Background(), fetched, 50, 7); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// historyTable answers transaction history queries from rows, honouring the
+// type, category, date, amount and merchant filters, the ORDER BY column
+// and LIMIT/OFFSET the service writes
+func historyTable(stub *testutil.StubDB, rows []models.Transaction) {
+	// arg returns the argument bound to the placeholder after condition
+	arg := func(query, condition string, args []driver.Value) (driver.Value, bool) {
+		m := regexp.MustCompile(condition + ` \$(\d+)`).FindStringSubmatch(query)
+		if m == nil {
+			return nil, false
+		}
+		n, _ := strconv.Atoi(m[1])
+		return args[n-1], true
+	}
+	stub.Handle(`FROM "transactions"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		var matched []models.Transaction
+		for _, row := range rows {
+			if v, ok := arg(query, `LOWER\(type\) =`, args); ok && strings.ToLower(row.Type) != v {
+				continue
+			}
+			if v, ok := arg(query, `LOWER\(category\) =`, args); ok && strings.ToLower(row.Category) != v {
+				continue
+			}
+			if v, ok := arg(query, `transaction_date >=`, args); ok && row.TransactionDate.Before(v.(time.Time)) {
+				continue
+			}
+			if v, ok := arg(query, `transaction_date <`, args); ok && !row.TransactionDate.Before(v.(time.Time)) {
+				continue
+			}
+			if v, ok := arg(query, `amount >=`, args); ok && row.Amount < v.(float64) {
+				continue
+			}
+			if v, ok := arg(query, `amount <=`, args); ok && row.Amount > v.(float64) {
+				continue
+			}
+			if v, ok := arg(query, `LOWER\(merchant_name\) LIKE`, args); ok {
+				pattern := strings.NewReplacer(`\%`, "%", `\_`, "_", `\\`, `\`).Replace(strings.TrimSuffix(strings.TrimPrefix(v.(string), "%"), "%"))
+				if !strings.Contains(strings.ToLower(row.MerchantName), pattern) {
+					continue
+				}
+			}
+			matched = append(matched, row)
+		}
+		if strings.Contains(query, "count(*)") {
+			return testutil.StubResult{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(len(matched))}}}, nil
+		}
+
+		order := regexp.MustCompile(`ORDER BY (\w+) (ASC|DESC)`).FindStringSubmatch(query)
+		sort.SliceStable(matched, func(i, j int) bool {
+			a, b := matched[i], matched[j]
+			less := a.TransactionDate.Before(b.TransactionDate)
+			if order[1] == "amount" {
+				less = a.Amount < b.Amount
+			}
+			if order[2] == "DESC" {
+				return !less
+			}
+			return less
+		})
+		if m := regexp.MustCompile(`OFFSET (\d+)`).FindStringSubmatch(query); m != nil {
+			offset, _ := strconv.Atoi(m[1])
+			if offset > len(matched) {
+				offset = len(matched)
+			}
+			matched = matched[offset:]
+		}
+		if m := regexp.MustCompile(`LIMIT (\d+)`).FindStringSubmatch(query); m != nil {
+			if limit, _ := strconv.Atoi(m[1]); limit < len(matched) {
+				matched = matched[:limit]
+			}
+		}
+
+		result := testutil.StubResult{Columns: []string{"id", "user_id", "transaction_id", "type", "category", "amount", "transaction_date", "merchant_name"}}
+		for _, row := range matched {
+			result.Rows = append(result.Rows, []driver.Value{int64(row.ID), int64(7), row.TransactionID, row.Type, row.Category, row.Amount, row.TransactionDate, row.MerchantName})
+		}
+		return result, nil
+	})
+}
+
+// historyRow is a transaction for historyTable
+func historyRow(id uint, txnType, category string, amount float64, date time.Time) models.Transaction {
+	txn := models.Transaction{Type: txnType, Category: category, Amount: amount, TransactionDate: date}
+	txn.ID = id
+	return txn
+}
+
+func TestGetTransactionsSortsAndFilters(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	day := func(d int) time.Time { return time.Date(2025, time.March, d, 0, 0, 0, 0, time.UTC) }
+	historyTable(stub, []models.Transaction{
+		historyRow(1, "debit", "Food", 300, day(1)),
+		historyRow(2, "debit", "Travel", 1200, day(2)),
+		historyRow(3, "credit", "Salary", 50000, day(3)),
+		historyRow(4, "debit", "Food", 150, day(4)),
+		historyRow(5, "DEBIT", "food", 900, day(5)),
+	})
+	service := &TransactionService{DB: db}
+
+	tests := []struct {
+		name   string
+		filter TransactionFilter
+		want   []uint
+		total  int64
+	}{
+		{"newest first by default", TransactionFilter{Limit: 50}, []uint{5, 4, 3, 2, 1}, 5},
+		{"oldest first", TransactionFilter{Limit: 50, Asc: true}, []uint{1, 2, 3, 4, 5}, 5},
+		{"largest first", TransactionFilter{Limit: 50, SortBy: "amount"}, []uint{3, 2, 5, 1, 4}, 5},
+		{"debits by amount ascending", TransactionFilter{Limit: 50, SortBy: "amount", Asc: true, Type: "debit"}, []uint{4, 1, 5, 2}, 4},
+		{"food in any case", TransactionFilter{Limit: 50, Category: "FOOD"}, []uint{5, 4, 1}, 3},
+		{"debit food by amount", TransactionFilter{Limit: 50, SortBy: "amount", Type: "debit", Category: "food"}, []uint{5, 1, 4}, 3},
+		{"second page", TransactionFilter{Limit: 2, Offset: 2}, []uint{3, 2}, 5},
+		{"offset beyond the last page", TransactionFilter{Limit: 2, Offset: 10}, nil, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transactions, total, err := service.GetTransactions(context.Background(), 7, tt.filter)
+			if err != nil {
+				t.Fatalf("GetTransactions: %v", err)
+			}
+			var ids []uint
+			for _, txn := range transactions {
+				ids = append(ids, txn.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.want) || total != tt.total {
+				t.Errorf("got %v of %d, want %v of %d", ids, total, tt.want, tt.total)
+			}
+		})
+	}
+
+	// Ties on the sort column are broken by id in the same direction
+	queries := stub.Ran(`ORDER BY`)
+	if last := queries[len(queries)-1].SQL; !strings.Contains(last, "ORDER BY transaction_date DESC,id DESC") {
+		t.Errorf("default order: %s", last)
+	}
+}
+
+func TestGetTransactionsRangeAndMerchantFilters(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	day := func(d int) time.Time { return time.Date(2025, time.March, d, 12, 0, 0, 0, time.UTC) }
+	rows := []models.Transaction{
+		historyRow(1, "debit", "Food", 300, day(1)),
+		historyRow(2, "debit", "Travel", 1200, day(5)),
+		historyRow(3, "credit", "Salary", 50000, day(10)),
+		historyRow(4, "debit", "Food", 150, day(15)),
+		historyRow(5, "debit", "Shopping", 2500, day(20)),
+	}
+	merchants := []string{"Swiggy", "Uber India", "Acme Corp", "SWIGGY Instamart", "100%_Pure"}
+	for i := range rows {
+		rows[i].MerchantName = merchants[i]
+		rows[i].TransactionID = fmt.Sprintf("TXN%d", i+1)
+	}
+	// Expenses added by hand are mirrored as manual transactions
+	rows[3].TransactionID = manualTransactionID(7, 9)
+	historyTable(stub, rows)
+	service := &TransactionService{DB: db}
+	amount := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name   string
+		filter TransactionFilter
+		want   []uint
+	}{
+		{"from", TransactionFilter{From: day(10)}, []uint{5, 4, 3}},
+		{"to is exclusive", TransactionFilter{To: day(10)}, []uint{2, 1}},
+		{"date range", TransactionFilter{From: day(2), To: day(16)}, []uint{4, 3, 2}},
+		{"min amount is inclusive", TransactionFilter{MinAmount: amount(1200)}, []uint{5, 3, 2}},
+		{"max amount is inclusive", TransactionFilter{MaxAmount: amount(300)}, []uint{4, 1}},
+		{"amount range", TransactionFilter{MinAmount: amount(200), MaxAmount: amount(2500)}, []uint{5, 2, 1}},
+		{"merchant substring in any case", TransactionFilter{Merchant: " swiggy "}, []uint{4, 1}},
+		{"merchant wildcards are literal", TransactionFilter{Merchant: "100%_"}, []uint{5}},
+		{"merchant percent alone matches nothing else", TransactionFilter{Merchant: "%"}, []uint{5}},
+		{"everything combined", TransactionFilter{
+			Type: "debit", Category: "food", From: day(1), To: day(31),
+			MinAmount: amount(100), MaxAmount: amount(200), Merchant: "swiggy",
+		}, []uint{4}},
+		{"combined with no match", TransactionFilter{Type: "credit", Merchant: "swiggy"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filter.Limit = 50
+			transactions, total, err := service.GetTransactions(context.Background(), 7, tt.filter)
+			if err != nil {
+				t.Fatalf("GetTransactions: %v", err)
+			}
+			var ids []uint
+			for _, txn := range transactions {
+				ids = append(ids, txn.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.want) || total != int64(len(tt.want)) {
+				t.Errorf("got %v of %d, want %v", ids, total, tt.want)
+			}
+		})
+	}
+
+	// The count and the page share one WHERE clause
+	counts, pages := stub.Ran(`count(*)`), stub.Ran(`ORDER BY`)
+	where := func(sql string) string {
+		sql = sql[strings.Index(sql, "WHERE"):]
+		if i := strings.Index(sql, " ORDER BY"); i >= 0 {
+			sql = sql[:i]
+		}
+		return sql
+	}
+	if a, b := where(counts[len(counts)-1].SQL), where(pages[len(pages)-1].SQL); a != b {
+		t.Errorf("count WHERE %q differs from page WHERE %q", a, b)
+	}
+}
+
+// merchantTable answers MerchantBreakdown's queries the way Postgres would
+// over debits keyed by merchant name: the total, then the grouped spend
+func merchantTable(stub *testutil.StubDB, debits map[string][]float64) {
+	stub.Handle(`FROM "transactions"`, func(query string, args []driver.Value) (testutil.StubResult, error) {
+		if !strings.Contains(query, "GROUP BY") {
+			var total float64
+			for _, amounts := range debits {
+				for _, amount := range amounts {
+					total += amount
+				}
+			}
+			return testutil.StubResult{Columns: []string{"coalesce"}, Rows: [][]driver.Value{{total}}}, nil
+		}
+
+		type group struct {
+			merchant string
+			total    float64
+			count    int64
+		}
+		groups := make(map[string]*group)
+		for name, amounts := range debits {
+			key, label := strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(name)
+			if key == "" {
+				key, label = "unknown", unknownMerchant
+			}
+			g, ok := groups[key]
+			if !ok {
+				g = &group{merchant: label}
+				groups[key] = g
+			}
+			if label > g.merchant {
+				g.merchant = label
+			}
+			for _, amount := range amounts {
+				g.total += amount
+				g.count++
+			}
+		}
+		var sorted []*group
+		for _, g := range groups {
+			sorted = append(sorted, g)
+		}
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].total > sorted[j].total })
+		if m := regexp.MustCompile(`LIMIT (\d+)`).FindStringSubmatch(query); m != nil {
+			if limit, _ := strconv.Atoi(m[1]); limit < len(sorted) {
+				sorted = sorted[:limit]
+			}
+		}
+
+		result := testutil.StubResult{Columns: []string{"merchant", "total", "count"}}
+		for _, g := range sorted {
+			result.Rows = append(result.Rows, []driver.Value{g.merchant, g.total, g.count})
+		}
+		return result, nil
+	})
+}
+
+func TestMerchantBreakdown(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	merchantTable(stub, map[string][]float64{
+		"Amazon":   {1500, 500},
+		" AMAZON ": {1000},
+		"Swiggy":   {400, 350, 250},
+		"":         {600},
+		"   ":      {400},
+		"Uber":     {1000},
+	})
+	service := &TransactionService{DB: db}
+	start := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	spends, err := service.MerchantBreakdown(context.Background(), 7, start, start.AddDate(0, 1, 0), 0)
+	if err != nil {
+		t.Fatalf("MerchantBreakdown: %v", err)
+	}
+	want := []MerchantSpend{
+		{Merchant: "Amazon", Total: 3000, Count: 3, Percent: 50},
+		{Merchant: "Unknown", Total: 1000, Count: 2, Percent: 16.67},
+		{Merchant: "Swiggy", Total: 1000, Count: 3, Percent: 16.67},
+		{Merchant: "Uber", Total: 1000, Count: 1, Percent: 16.67},
+	}
+	if len(spends) != len(want) || spends[0] != want[0] {
+		t.Fatalf("spends = %+v, want %+v", spends, want)
+	}
+	byMerchant := make(map[string]MerchantSpend)
+	for _, spend := range spends {
+		byMerchant[spend.Merchant] = spend
+	}
+	for _, w := range want {
+		if byMerchant[w.Merchant] != w {
+			t.Errorf("%s = %+v, want %+v", w.Merchant, byMerchant[w.Merchant], w)
+		}
+	}
+
+	// A limit trims the list but percentages stay relative to all spending
+	top, err := service.MerchantBreakdown(context.Background(), 7, start, start.AddDate(0, 1, 0), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 1 || top[0] != want[0] {
+		t.Errorf("top merchant = %+v, want %+v", top, want[0])
+	}
+
+	grouped := stub.Ran(`GROUP BY`)
+	if !strings.Contains(grouped[0].SQL, "LOWER(TRIM(merchant_name))") || !containsDebit(grouped[0].Args) {
+		t.Errorf("breakdown isn't a case-insensitive grouping of debits: %s %v", grouped[0].SQL, grouped[0].Args)
+	}
+}
+
+func TestMerchantBreakdownWithoutSpending(t *testing.T) {
+	db, stub := testutil.NewStubDB(t)
+	merchantTable(stub, nil)
+	start := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	spends, err := (&TransactionService{DB: db}).MerchantBreakdown(context.Background(), 7, start, start.AddDate(0, 1, 0), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spends == nil || len(spends) != 0 {
+		t.Errorf("spends = %#v, want an empty list", spends)
+	}
+}
+
+// containsDebit reports whether the query was limited to debits
+func containsDebit(args []driver.Value) bool {
+	for _, arg := range args {
+		if arg == "debit" {
+			return true
+		}
+	}
+	return false
+}
+
+// seededMockTransactions generates 20 mock transactions from seed, without
+// the clock-derived IDs and dates
+func seededMockTransactions(t *testing.T, seed int64) []MockTransaction {
+	t.Helper()
+	service := &TransactionService{Rand: rand.New(rand.NewSource(seed))}
+	txns, err := service.GenerateMockTransactions(1, 7, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range txns {
+		txns[i].TransactionID, txns[i].TransactionDate = "", time.Time{}
+	}
+	return txns
+}
+
+func TestGenerateMockTransactionsSeedIsReproducible(t *testing.T) {
+	first := seededMockTransactions(t, 42)
+	if len(first) != 20 {
+		t.Fatalf("generated %d transactions, want 20", len(first))
+	}
+	if again := seededMockTransactions(t, 42); !reflect.DeepEqual(first, again) {
+		t.Error("the same seed generated different transactions")
+	}
+	if other := seededMockTransactions(t, 7); reflect.DeepEqual(first, other) {
+		t.Error("different seeds generated the same transactions")
+	}
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultAnomalyThreshold is the percentage jump over the trailing average
+// that flags a category as anomalous when no threshold is configured
+const DefaultAnomalyThreshold = 50.0
+
+// anomalyTrailingMonths is the number of months averaged as the baseline
+const anomalyTrailingMonths = 3
+
+// SpendingAnomaly describes a category whose current-month spend jumped
+// above its trailing monthly average
+type SpendingAnomaly struct {
+	Category        string  `json:"category"`
+	CurrentAmount   float64 `json:"current_amount"`
+	TrailingAverage float64 `json:"trailing_average"`
+	PercentIncrease float64 `json:"percent_increase"`
+}
+
+// DetectSpendingAnomalies compares each category's spend in currentMonth
+// against its average over the previous three months. monthlySpending is
+// keyed by month ("2006-01") and then by category. Months with no spend count
+// as zero; categories with no baseline spend at all are skipped because a
+// percentage jump is meaningless for them.
+func DetectSpendingAnomalies(monthlySpending map[string]map[string]float64, currentMonth time.Time, thresholdPct float64) []SpendingAnomaly {
+	if thresholdPct <= 0 {
+		thresholdPct = DefaultAnomalyThreshold
+	}
+
+	monthStart := time.Date(currentMonth.Year(), currentMonth.Month(), 1, 0, 0, 0, 0, currentMonth.Location())
+	current := monthlySpending[monthStart.Format("2006-01")]
+
+	anomalies := make([]SpendingAnomaly, 0)
+	for category, amount := range current {
+		var trailingTotal float64
+		for i := 1; i <= anomalyTrailingMonths; i++ {
+			month := monthStart.AddDate(0, -i, 0).Format("2006-01")
+			trailingTotal += monthlySpending[month][category]
+		}
+		average := trailingTotal / anomalyTrailingMonths
+		if average <= 0 {
+			continue
+		}
+
+		increase := (amount - average) / average * 100
+		if increase > thresholdPct {
+			anomalies = append(anomalies, SpendingAnomaly{
+				Category:        category,
+				CurrentAmount:   amount,
+				TrailingAverage: average,
+				PercentIncrease: increase,
+			})
+		}
+	}
+
+	// Largest jumps first so the output is stable and the worst offender leads
+	sort.Slice(anomalies, func(i, j int) bool {
+		if anomalies[i].PercentIncrease == anomalies[j].PercentIncrease {
+			return anomalies[i].Category < anomalies[j].Category
+		}
+		return anomalies[i].PercentIncrease > anomalies[j].PercentIncrease
+	})
+	return anomalies
+}
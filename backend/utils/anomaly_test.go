@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var anomalyMonth = time.Date(2025, time.April, 18, 0, 0, 0, 0, time.UTC)
+
+func TestDetectSpendingAnomalies(t *testing.T) {
+	spending := map[string]map[string]float64{
+		"2024-12": {"Food": 9000, "Travel": 9000}, // outside the trailing window
+		"2025-01": {"Food": 1000, "Shopping": 2000, "Travel": 300},
+		"2025-02": {"Food": 1200, "Shopping": 2000},
+		"2025-03": {"Food": 800, "Shopping": 2000, "Bills": 500},
+		"2025-04": {"Food": 2100, "Shopping": 2900, "Travel": 600, "Gifts": 5000},
+	}
+
+	anomalies := DetectSpendingAnomalies(spending, anomalyMonth, 50)
+
+	// Food: 2100 vs 1000 (+110%). Travel: 600 vs 100 with two empty months
+	// (+500%). Shopping is +45%, Gifts has no baseline and Bills no spend.
+	want := []SpendingAnomaly{
+		{Category: "Travel", CurrentAmount: 600, TrailingAverage: 100, PercentIncrease: 500},
+		{Category: "Food", CurrentAmount: 2100, TrailingAverage: 1000, PercentIncrease: 110},
+	}
+	if len(anomalies) != len(want) {
+		t.Fatalf("anomalies = %+v, want %+v", anomalies, want)
+	}
+	for i, anomaly := range anomalies {
+		if anomaly.Category != want[i].Category || anomaly.CurrentAmount != want[i].CurrentAmount ||
+			math.Abs(anomaly.TrailingAverage-want[i].TrailingAverage) > 1e-9 ||
+			math.Abs(anomaly.PercentIncrease-want[i].PercentIncrease) > 1e-9 {
+			t.Errorf("anomaly %d = %+v, want %+v", i, anomaly, want[i])
+		}
+	}
+}
+
+func TestDetectSpendingAnomaliesThreshold(t *testing.T) {
+	spending := map[string]map[string]float64{
+		"2025-01": {"Food": 1000},
+		"2025-02": {"Food": 1000},
+		"2025-03": {"Food": 1000},
+		"2025-04": {"Food": 1400},
+	}
+	tests := []struct {
+		threshold float64
+		flagged   bool
+	}{
+		{30, true},
+		{40, false}, // the jump must exceed the threshold
+		{50, false},
+		{0, false}, // falls back to DefaultAnomalyThreshold
+	}
+	for _, tt := range tests {
+		got := DetectSpendingAnomalies(spending, anomalyMonth, tt.threshold)
+		if (len(got) == 1) != tt.flagged {
+			t.Errorf("threshold %.0f: anomalies = %+v, want flagged %v", tt.threshold, got, tt.flagged)
+		}
+	}
+}
+
+func TestDetectSpendingAnomaliesWithoutCurrentSpend(t *testing.T) {
+	spending := map[string]map[string]float64{"2025-03": {"Food": 1000}}
+	if got := DetectSpendingAnomalies(spending, anomalyMonth, 50); len(got) != 0 {
+		t.Errorf("anomalies = %+v for a month without spend", got)
+	}
+}
@@ -0,0 +1,79 @@
+package utils
+
+// Bank is an entry in the list of supported banks
+type Bank struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Logo       string `json:"logo"`
+	Type       string `json:"type"`
+	IFSCPrefix string `json:"-"` // four-letter bank code used in IFSC codes, when known
+}
+
+// Banks is the list of banks users can link accounts from
+var Banks = []Bank{
+	// Public Sector Banks
+	{ID: "sbi", Name: "State Bank of India", Logo: "sbi.png", Type: "public", IFSCPrefix: "SBIN"},
+	{ID: "pnb", Name: "Punjab National Bank", Logo: "pnb.png", Type: "public", IFSCPrefix: "PUNB"},
+	{ID: "canara", Name: "Canara Bank", Logo: "canara.png", Type: "public", IFSCPrefix: "CNRB"},
+	{ID: "bank-of-baroda", Name: "Bank of Baroda", Logo: "bob.png", Type: "public", IFSCPrefix: "BARB"},
+	{ID: "union-bank", Name: "Union Bank of India", Logo: "union.png", Type: "public", IFSCPrefix: "UBIN"},
+	{ID: "bank-of-india", Name: "Bank of India", Logo: "boi.png", Type: "public", IFSCPrefix: "BKID"},
+	{ID: "central-bank", Name: "Central Bank of India", Logo: "cbi.png", Type: "public", IFSCPrefix: "CBIN"},
+	{ID: "indian-bank", Name: "Indian Bank", Logo: "indian.png", Type: "public", IFSCPrefix: "IDIB"},
+	{ID: "uco-bank", Name: "UCO Bank", Logo: "uco.png", Type: "public", IFSCPrefix: "UCBA"},
+	{ID: "bank-of-maharashtra", Name: "Bank of Maharashtra", Logo: "bom.png", Type: "public", IFSCPrefix: "MAHB"},
+	{ID: "punjab-sind-bank", Name: "Punjab & Sind Bank", Logo: "psb.png", Type: "public", IFSCPrefix: "PSIB"},
+
+	// Private Sector Banks
+	{ID: "hdfc", Name: "HDFC Bank", Logo: "hdfc.png", Type: "private", IFSCPrefix: "HDFC"},
+	{ID: "icici", Name: "ICICI Bank", Logo: "icici.png", Type: "private", IFSCPrefix: "ICIC"},
+	{ID: "axis", Name: "Axis Bank", Logo: "axis.png", Type: "private", IFSCPrefix: "UTIB"},
+	{ID: "kotak", Name: "Kotak Mahindra Bank", Logo: "kotak.png", Type: "private", IFSCPrefix: "KKBK"},
+	{ID: "yes", Name: "Yes Bank", Logo: "yes.png", Type: "private", IFSCPrefix: "YESB"},
+	{ID: "indusind", Name: "IndusInd Bank", Logo: "indusind.png", Type: "private", IFSCPrefix: "INDB"},
+	{ID: "idfc", Name: "IDFC First Bank", Logo: "idfc.png", Type: "private", IFSCPrefix: "IDFB"},
+	{ID: "bandhan", Name: "Bandhan Bank", Logo: "bandhan.png", Type: "private", IFSCPrefix: "BDBL"},
+	{ID: "csb", Name: "CSB Bank", Logo: "csb.png", Type: "private", IFSCPrefix: "CSBK"},
+	{ID: "dcb", Name: "DCB Bank", Logo: "dcb.png", Type: "private", IFSCPrefix: "DCBL"},
+	{ID: "federal", Name: "Federal Bank", Logo: "federal.png", Type: "private", IFSCPrefix: "FDRL"},
+	{ID: "karnataka", Name: "Karnataka Bank", Logo: "karnataka.png", Type: "private", IFSCPrefix: "KARB"},
+	{ID: "karur-vysya", Name: "Karur Vysya Bank", Logo: "kvb.png", Type: "private", IFSCPrefix: "KVBL"},
+	{ID: "nainital", Name: "Nainital Bank", Logo: "nainital.png", Type: "private", IFSCPrefix: "NTBL"},
+	{ID: "rbl", Name: "RBL Bank", Logo: "rbl.png", Type: "private", IFSCPrefix: "RATN"},
+	{ID: "south-indian", Name: "South Indian Bank", Logo: "sib.png", Type: "private", IFSCPrefix: "SIBL"},
+	{ID: "tamilnad", Name: "Tamilnad Mercantile Bank", Logo: "tmb.png", Type: "private", IFSCPrefix: "TMBL"},
+
+	// Foreign Banks
+	{ID: "citibank", Name: "Citibank", Logo: "citi.png", Type: "foreign", IFSCPrefix: "CITI"},
+	{ID: "hsbc", Name: "HSBC Bank", Logo: "hsbc.png", Type: "foreign", IFSCPrefix: "HSBC"},
+	{ID: "standard-chartered", Name: "Standard Chartered Bank", Logo: "scb.png", Type: "foreign", IFSCPrefix: "SCBL"},
+	{ID: "deutsche", Name: "Deutsche Bank", Logo: "deutsche.png", Type: "foreign", IFSCPrefix: "DEUT"},
+	{ID: "barclays", Name: "Barclays Bank", Logo: "barclays.png", Type: "foreign", IFSCPrefix: "BARC"},
+	{ID: "dbs", Name: "DBS Bank", Logo: "dbs.png", Type: "foreign", IFSCPrefix: "DBSS"},
+	{ID: "rbs", Name: "Royal Bank of Scotland", Logo: "rbs.png", Type: "foreign"},
+	{ID: "bnp-paribas", Name: "BNP Paribas", Logo: "bnp.png", Type: "foreign", IFSCPrefix: "BNPA"},
+	{ID: "societe-generale", Name: "Societe Generale", Logo: "sg.png", Type: "foreign", IFSCPrefix: "SOGE"},
+
+	// Regional Rural Banks
+	{ID: "andhra-pradesh-grameena", Name: "Andhra Pradesh Grameena Vikas Bank", Logo: "apgvb.png", Type: "rrb"},
+	{ID: "karnataka-gramin", Name: "Karnataka Gramin Bank", Logo: "kgb.png", Type: "rrb"},
+	{ID: "madhya-pradesh-gramin", Name: "Madhya Pradesh Gramin Bank", Logo: "mpgb.png", Type: "rrb"},
+	{ID: "rajasthan-marudhara", Name: "Rajasthan Marudhara Gramin Bank", Logo: "rmgb.png", Type: "rrb"},
+	{ID: "uttar-bihar-gramin", Name: "Uttar Bihar Gramin Bank", Logo: "ubgb.png", Type: "rrb"},
+
+	// Small Finance Banks
+	{ID: "au-small-finance", Name: "AU Small Finance Bank", Logo: "au.png", Type: "sfb", IFSCPrefix: "AUBL"},
+	{ID: "equitas-small-finance", Name: "Equitas Small Finance Bank", Logo: "equitas.png", Type: "sfb", IFSCPrefix: "ESFB"},
+	{ID: "fino-payments", Name: "Fino Payments Bank", Logo: "fino.png", Type: "sfb", IFSCPrefix: "FINO"},
+	{ID: "jammu-kashmir", Name: "Jammu & Kashmir Bank", Logo: "jkb.png", Type: "sfb", IFSCPrefix: "JAKA"},
+	{ID: "karnataka-vikas", Name: "Karnataka Vikas Grameena Bank", Logo: "kvg.png", Type: "sfb"},
+	{ID: "maharashtra-gramin", Name: "Maharashtra Gramin Bank", Logo: "mgb.png", Type: "sfb"},
+	{ID: "odisha-gramya", Name: "Odisha Gramya Bank", Logo: "ogb.png", Type: "sfb"},
+	{ID: "puduvai-bharathiar", Name: "Puduvai Bharathiar Grama Bank", Logo: "pbg.png", Type: "sfb"},
+	{ID: "saurashtra-gramin", Name: "Saurashtra Gramin Bank", Logo: "sgb.png", Type: "sfb"},
+	{ID: "tamil-nadu-grama", Name: "Tamil Nadu Grama Bank", Logo: "tngb.png", Type: "sfb"},
+	{ID: "telangana-gramin", Name: "Telangana Grameena Bank", Logo: "tgb.png", Type: "sfb"},
+	{ID: "uttar-pradesh-gramin", Name: "Uttar Pradesh Gramin Bank", Logo: "upgb.png", Type: "sfb"},
+	{ID: "uttarakhand-gramin", Name: "Uttarakhand Gramin Bank", Logo: "ukgb.png", Type: "sfb"},
+	{ID: "west-bengal-gramin", Name: "West Bengal Gramin Bank", Logo: "wbgb.png", Type: "sfb"},
+}
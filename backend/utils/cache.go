@@ -2,17 +2,31 @@ package utils
 
 import (
 	"container/list"
+	"hash/fnv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// LRUCache is a TTL cache split into shards, each an independent LRU with its
+// own lock, so concurrent requests for different keys rarely contend.
+// Eviction is per shard: a full shard drops its own least recently used
+// entry even if other shards have room.
 type LRUCache struct {
 	capacity int
 	ttl      time.Duration
+	shards   []*cacheShard
+	stopChan chan bool
+	stopOnce sync.Once
+	hits     atomic.Uint64
+	misses   atomic.Uint64
+}
+
+type cacheShard struct {
+	capacity int
 	cache    map[string]*list.Element
 	list     *list.List
-	mutex    sync.RWMutex
-	stopChan chan bool
+	mutex    sync.Mutex
 }
 
 type cacheEntry struct {
@@ -21,53 +35,90 @@ type cacheEntry struct {
 	timestamp time.Time
 }
 
+// NewLRUCache creates a single-shard cache
 func NewLRUCache(capacity int, ttl time.Duration) *LRUCache {
-	return &LRUCache{
+	return NewShardedLRUCache(capacity, ttl, 1)
+}
+
+// NewShardedLRUCache creates a cache whose capacity is divided across
+// shards. shards is clamped to [1, capacity].
+func NewShardedLRUCache(capacity int, ttl time.Duration, shards int) *LRUCache {
+	if shards < 1 {
+		shards = 1
+	}
+	if capacity > 0 && shards > capacity {
+		shards = capacity
+	}
+
+	c := &LRUCache{
 		capacity: capacity,
 		ttl:      ttl,
-		cache:    make(map[string]*list.Element, capacity),
-		list:     list.New(),
+		shards:   make([]*cacheShard, shards),
 		stopChan: make(chan bool),
 	}
+	// Spread the remainder so the shards add up to exactly capacity
+	for i := range c.shards {
+		perShard := capacity / shards
+		if i < capacity%shards {
+			perShard++
+		}
+		c.shards[i] = &cacheShard{
+			capacity: perShard,
+			cache:    make(map[string]*list.Element, perShard),
+			list:     list.New(),
+		}
+	}
+	return c
+}
+
+// shard returns the shard owning key
+func (c *LRUCache) shard(key string) *cacheShard {
+	if len(c.shards) == 1 {
+		return c.shards[0]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return c.shards[h.Sum32()%uint32(len(c.shards))]
 }
 
 func (c *LRUCache) Get(key string) (interface{}, bool) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
+	s := c.shard(key)
+	// Get reorders the list, so it needs the write lock
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-	if element, exists := c.cache[key]; exists {
+	if element, exists := s.cache[key]; exists {
 		entry := element.Value.(*cacheEntry)
-		
+
 		// Check if entry has expired
 		if time.Since(entry.timestamp) > c.ttl {
-			// Remove expired entry
-			c.mutex.RUnlock()
-			c.mutex.Lock()
-			c.removeElement(element)
-			c.mutex.Unlock()
-			c.mutex.RLock()
+			s.removeElement(element)
+			c.misses.Add(1)
 			return nil, false
 		}
 
 		// Move to front (most recently used)
-		c.list.MoveToFront(element)
+		s.list.MoveToFront(element)
+		c.hits.Add(1)
 		return entry.value, true
 	}
 
+	c.misses.Add(1)
 	return nil, false
 }
 
 func (c *LRUCache) Set(key string, value interface{}) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	s := c.shard(key)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
 	// Check if key already exists
-	if element, exists := c.cache[key]; exists {
+	if element, exists := s.cache[key]; exists {
 		// Update existing entry
 		entry := element.Value.(*cacheEntry)
 		entry.value = value
 		entry.timestamp = time.Now()
-		c.list.MoveToFront(element)
+		s.list.MoveToFront(element)
 		return
 	}
 
@@ -78,48 +129,71 @@ func (c *LRUCache) Set(key string, value interface{}) {
 		timestamp: time.Now(),
 	}
 
-	element := c.list.PushFront(entry)
-	c.cache[key] = element
+	element := s.list.PushFront(entry)
+	s.cache[key] = element
 
 	// Remove least recently used if capacity exceeded
-	if c.list.Len() > c.capacity {
-		c.removeLRU()
+	if s.list.Len() > s.capacity {
+		s.removeLRU()
 	}
 }
 
 func (c *LRUCache) Delete(key string) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	s := c.shard(key)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-	if element, exists := c.cache[key]; exists {
-		c.removeElement(element)
+	if element, exists := s.cache[key]; exists {
+		s.removeElement(element)
 	}
 }
 
-func (c *LRUCache) Clear() {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+// DeleteFunc removes every entry whose key matches and returns how many were
+// removed. It visits each shard under its lock, so it costs a full scan.
+func (c *LRUCache) DeleteFunc(match func(key string) bool) int {
+	removed := 0
+	for _, s := range c.shards {
+		s.mutex.Lock()
+		for key, element := range s.cache {
+			if match(key) {
+				s.removeElement(element)
+				removed++
+			}
+		}
+		s.mutex.Unlock()
+	}
+	return removed
+}
 
-	c.cache = make(map[string]*list.Element, c.capacity)
-	c.list.Init()
+func (c *LRUCache) Clear() {
+	for _, s := range c.shards {
+		s.mutex.Lock()
+		s.cache = make(map[string]*list.Element, s.capacity)
+		s.list.Init()
+		s.mutex.Unlock()
+	}
 }
 
 func (c *LRUCache) Size() int {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
-	return c.list.Len()
+	size := 0
+	for _, s := range c.shards {
+		s.mutex.Lock()
+		size += s.list.Len()
+		s.mutex.Unlock()
+	}
+	return size
 }
 
-func (c *LRUCache) removeElement(element *list.Element) {
-	c.list.Remove(element)
+func (s *cacheShard) removeElement(element *list.Element) {
+	s.list.Remove(element)
 	entry := element.Value.(*cacheEntry)
-	delete(c.cache, entry.key)
+	delete(s.cache, entry.key)
 }
 
-func (c *LRUCache) removeLRU() {
-	element := c.list.Back()
+func (s *cacheShard) removeLRU() {
+	element := s.list.Back()
 	if element != nil {
-		c.removeElement(element)
+		s.removeElement(element)
 	}
 }
 
@@ -139,23 +213,32 @@ func (c *LRUCache) StartCleanup(interval time.Duration) {
 	}()
 }
 
+// StopCleanup stops the cleanup goroutine; it is safe to call more than once
 func (c *LRUCache) StopCleanup() {
-	close(c.stopChan)
+	c.stopOnce.Do(func() {
+		close(c.stopChan)
+	})
 }
 
 func (c *LRUCache) cleanup() {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	for _, s := range c.shards {
+		s.cleanup(c.ttl)
+	}
+}
+
+func (s *cacheShard) cleanup(ttl time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
 	now := time.Now()
-	element := c.list.Back()
+	element := s.list.Back()
 
 	// Remove expired entries from the back of the list
 	for element != nil {
 		entry := element.Value.(*cacheEntry)
-		if now.Sub(entry.timestamp) > c.ttl {
+		if now.Sub(entry.timestamp) > ttl {
 			prev := element.Prev()
-			c.removeElement(element)
+			s.removeElement(element)
 			element = prev
 		} else {
 			break // All remaining entries are not expired
@@ -163,14 +246,29 @@ func (c *LRUCache) cleanup() {
 	}
 }
 
-// GetStats returns cache statistics for monitoring
+// ResetStats zeroes the hit and miss counters
+func (c *LRUCache) ResetStats() {
+	c.hits.Store(0)
+	c.misses.Store(0)
+}
+
+// GetStats returns cache statistics for monitoring. hit_ratio is hits over
+// all lookups since start or the last ResetStats; expired entries count as
+// misses.
 func (c *LRUCache) GetStats() map[string]interface{} {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
+	hits, misses := c.hits.Load(), c.misses.Load()
+	hitRatio := 0.0
+	if hits+misses > 0 {
+		hitRatio = float64(hits) / float64(hits+misses)
+	}
 
 	return map[string]interface{}{
-		"size":     c.list.Len(),
-		"capacity": c.capacity,
-		"ttl":      c.ttl.String(),
+		"size":      c.Size(),
+		"capacity":  c.capacity,
+		"shards":    len(c.shards),
+		"ttl":       c.ttl.String(),
+		"hits":      hits,
+		"misses":    misses,
+		"hit_ratio": hitRatio,
 	}
-}
\ No newline at end of file
+}
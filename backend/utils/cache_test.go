@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShardedCacheKeepsTheCacheAPI(t *testing.T) {
+	for _, shards := range []int{1, 4, 16} {
+		t.Run(fmt.Sprintf("%d shards", shards), func(t *testing.T) {
+			c := NewShardedLRUCache(100, time.Minute, shards)
+			for i := 0; i < 50; i++ {
+				c.Set(fmt.Sprintf("key-%d", i), i)
+			}
+			if c.Size() != 50 {
+				t.Fatalf("size = %d, want 50", c.Size())
+			}
+			if v, ok := c.Get("key-7"); !ok || v != 7 {
+				t.Errorf("Get(key-7) = %v, %v", v, ok)
+			}
+			c.Set("key-7", "updated")
+			if v, _ := c.Get("key-7"); v != "updated" || c.Size() != 50 {
+				t.Errorf("after overwrite: %v, size %d", v, c.Size())
+			}
+			c.Delete("key-7")
+			if _, ok := c.Get("key-7"); ok {
+				t.Error("deleted key still cached")
+			}
+			c.Clear()
+			if c.Size() != 0 {
+				t.Errorf("size after Clear = %d", c.Size())
+			}
+		})
+	}
+}
+
+func TestShardCapacitiesAddUpToTheCapacity(t *testing.T) {
+	tests := []struct{ capacity, shards, wantShards int }{
+		{5000, 16, 16},
+		{10, 3, 3},
+		{4, 16, 4}, // never more shards than entries
+		{10, 0, 1},
+	}
+	for _, tt := range tests {
+		c := NewShardedLRUCache(tt.capacity, time.Minute, tt.shards)
+		total := 0
+		for _, s := range c.shards {
+			total += s.capacity
+		}
+		if len(c.shards) != tt.wantShards || total != tt.capacity {
+			t.Errorf("NewShardedLRUCache(%d, %d): %d shards holding %d, want %d holding %d",
+				tt.capacity, tt.shards, len(c.shards), total, tt.wantShards, tt.capacity)
+		}
+	}
+}
+
+func TestShardEvictsItsLeastRecentlyUsedEntry(t *testing.T) {
+	c := NewLRUCache(3, time.Minute)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	c.Get("a") // b is now the least recently used
+	c.Set("d", 4)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b survived although it was least recently used")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+
+	// A sharded cache never holds more than its capacity
+	sharded := NewShardedLRUCache(64, time.Minute, 8)
+	for i := 0; i < 1000; i++ {
+		sharded.Set(fmt.Sprintf("key-%d", i), i)
+	}
+	if sharded.Size() > 64 {
+		t.Errorf("size = %d, want at most 64", sharded.Size())
+	}
+}
+
+func TestShardedCacheExpiresEntries(t *testing.T) {
+	c := NewShardedLRUCache(100, 20*time.Millisecond, 4)
+	for i := 0; i < 20; i++ {
+		c.Set(fmt.Sprintf("key-%d", i), i)
+	}
+	time.Sleep(30 * time.Millisecond)
+	c.Set("fresh", true)
+
+	if _, ok := c.Get("key-3"); ok {
+		t.Error("expired entry was returned")
+	}
+	c.cleanup()
+	if c.Size() != 1 {
+		t.Errorf("size after cleanup = %d, want only the fresh entry", c.Size())
+	}
+	if _, ok := c.Get("fresh"); !ok {
+		t.Error("cleanup dropped an unexpired entry")
+	}
+}
+
+func TestCacheStatsCountHitsMissesAndExpirations(t *testing.T) {
+	c := NewShardedLRUCache(100, 20*time.Millisecond, 4)
+	c.Set("a", 1)
+	c.Get("a")
+	c.Get("a")
+	c.Get("missing")
+	if stats := c.GetStats(); stats["hits"] != uint64(2) || stats["misses"] != uint64(1) {
+		t.Fatalf("stats = %v, want 2 hits and 1 miss", stats)
+	}
+
+	// An expired entry is a miss, not a hit
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expired entry was returned")
+	}
+	stats := c.GetStats()
+	if stats["hits"] != uint64(2) || stats["misses"] != uint64(2) || stats["hit_ratio"] != 0.5 {
+		t.Errorf("after expiry stats = %v, want 2 hits, 2 misses, ratio 0.5", stats)
+	}
+
+	c.ResetStats()
+	if stats := c.GetStats(); stats["hits"] != uint64(0) || stats["misses"] != uint64(0) || stats["hit_ratio"] != 0.0 {
+		t.Errorf("stats after reset = %v", stats)
+	}
+	c.Set("b", 1)
+	c.Get("b")
+	if stats := c.GetStats(); stats["hits"] != uint64(1) || stats["hit_ratio"] != 1.0 {
+		t.Errorf("counting after reset: %v", stats)
+	}
+}
+
+func TestShardedCacheConcurrentAccess(t *testing.T) {
+	c := NewShardedLRUCache(500, time.Minute, 8)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := fmt.Sprintf("key-%d", (g*1000+i)%700)
+				c.Set(key, i)
+				c.Get(key)
+				if i%50 == 0 {
+					c.Delete(key)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if c.Size() > 500 {
+		t.Errorf("size = %d, want at most 500", c.Size())
+	}
+}
+
+// BenchmarkLRUCacheParallel compares one lock against sharded locks under
+// a concurrent mix of nine reads to one write
+func BenchmarkLRUCacheParallel(b *testing.B) {
+	keys := make([]string, 4096)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("user:%d:summary", i)
+	}
+	for _, shards := range []int{1, 16, 64} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			c := NewShardedLRUCache(5000, time.Minute, shards)
+			for _, key := range keys {
+				c.Set(key, key)
+			}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					key := keys[i%len(keys)]
+					if i%10 == 0 {
+						c.Set(key, i)
+					} else {
+						c.Get(key)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+func TestDeleteFuncRemovesMatchingKeysInEveryShard(t *testing.T) {
+	c := NewShardedLRUCache(100, time.Minute, 8)
+	for i := 0; i < 20; i++ {
+		c.Set(fmt.Sprintf("expenses:%d:50", i%2), i)
+		c.Set(fmt.Sprintf("even:%d", i*2), i)
+		c.Set(fmt.Sprintf("odd:%d", i*2+1), i)
+	}
+	removed := c.DeleteFunc(func(key string) bool { return strings.HasPrefix(key, "odd:") })
+	if removed != 20 || c.Size() != 22 {
+		t.Errorf("removed %d leaving %d, want 20 removed and 22 left", removed, c.Size())
+	}
+	if _, ok := c.Get("odd:3"); ok {
+		t.Error("matching key survived")
+	}
+	if _, ok := c.Get("even:4"); !ok {
+		t.Error("non-matching key was removed")
+	}
+}
@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Canonical category labels. Every categorizer (AutoCategory, the AA
+// normalizer, merchant rules, overrides) funnels its output through
+// NormalizeCategory so summaries group spend under one label per category.
+const (
+	CategoryFood           = "Food & Dining"
+	CategoryTransport      = "Transport"
+	CategoryShopping       = "Shopping"
+	CategoryEntertainment  = "Entertainment"
+	CategoryBills          = "Bills & Utilities"
+	CategoryHealthcare     = "Healthcare"
+	CategoryEducation      = "Education"
+	CategoryTravel         = "Travel"
+	CategoryIncome         = "Income"
+	CategoryTransfers      = "Transfers"
+	CategoryCashWithdrawal = "Cash Withdrawal"
+	CategoryOther          = "Other"
+)
+
+// Taxonomy is the list of canonical categories plus aliases mapping the
+// inconsistent labels used by older data and categorizers onto them.
+// Alias keys are matched case-insensitively.
+type Taxonomy struct {
+	Categories []string          `json:"categories"`
+	Aliases    map[string]string `json:"aliases"`
+}
+
+// DefaultTaxonomy is used unless a taxonomy file is configured
+var DefaultTaxonomy = Taxonomy{
+	Categories: []string{
+		CategoryFood, CategoryTransport, CategoryShopping, CategoryEntertainment,
+		CategoryBills, CategoryHealthcare, CategoryEducation, CategoryTravel,
+		CategoryIncome, CategoryTransfers, CategoryCashWithdrawal, CategoryOther,
+	},
+	Aliases: map[string]string{
+		"food":              CategoryFood,
+		"food delivery":     CategoryFood,
+		"dining":            CategoryFood,
+		"restaurant":        CategoryFood,
+		"groceries":         CategoryFood,
+		"transportation":    CategoryTransport,
+		"fuel":              CategoryTransport,
+		"online shopping":   CategoryShopping,
+		"bills":             CategoryBills,
+		"utilities":         CategoryBills,
+		"insurance":         CategoryBills,
+		"health":            CategoryHealthcare,
+		"medical":           CategoryHealthcare,
+		"salary":            CategoryIncome,
+		"interest":          CategoryIncome,
+		"dividend":          CategoryIncome,
+		"bonus":             CategoryIncome,
+		"refund":            CategoryIncome,
+		"reimbursement":     CategoryIncome,
+		"investment return": CategoryIncome,
+		"transfer":          CategoryTransfers,
+		"bank transfer":     CategoryTransfers,
+		"digital payments":  CategoryTransfers,
+		"atm":               CategoryCashWithdrawal,
+		"atm withdrawal":    CategoryCashWithdrawal,
+		"cash":              CategoryCashWithdrawal,
+		"uncategorized":     CategoryOther,
+	},
+}
+
+var (
+	taxonomyMu sync.RWMutex
+	taxonomy   = compileTaxonomy(DefaultTaxonomy)
+)
+
+// compiledTaxonomy indexes every canonical name and alias by lower case
+type compiledTaxonomy struct {
+	categories []string
+	lookup     map[string]string
+}
+
+func compileTaxonomy(t Taxonomy) compiledTaxonomy {
+	c := compiledTaxonomy{
+		categories: append([]string(nil), t.Categories...),
+		lookup:     make(map[string]string, len(t.Categories)+len(t.Aliases)),
+	}
+	for _, category := range t.Categories {
+		c.lookup[strings.ToLower(category)] = category
+	}
+	for alias, category := range t.Aliases {
+		c.lookup[strings.ToLower(strings.TrimSpace(alias))] = category
+	}
+	return c
+}
+
+// Validate checks every alias points at a listed category and that Other,
+// the fallback for unmatched input, is listed
+func (t Taxonomy) Validate() error {
+	known := make(map[string]bool, len(t.Categories))
+	for _, category := range t.Categories {
+		if strings.TrimSpace(category) == "" {
+			return fmt.Errorf("taxonomy has an empty category")
+		}
+		known[category] = true
+	}
+	if !known[CategoryOther] {
+		return fmt.Errorf("taxonomy must include %q", CategoryOther)
+	}
+	for alias, category := range t.Aliases {
+		if !known[category] {
+			return fmt.Errorf("alias %q maps to unknown category %q", alias, category)
+		}
+	}
+	return nil
+}
+
+// SetTaxonomy replaces the active taxonomy
+func SetTaxonomy(t Taxonomy) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	compiled := compileTaxonomy(t)
+	taxonomyMu.Lock()
+	taxonomy = compiled
+	taxonomyMu.Unlock()
+	return nil
+}
+
+// LoadTaxonomyFile reads a Taxonomy from a JSON file and makes it active
+func LoadTaxonomyFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read category taxonomy: %w", err)
+	}
+	var t Taxonomy
+	if err := json.Unmarshal(data, &t); err != nil {
+		return fmt.Errorf("failed to parse category taxonomy: %w", err)
+	}
+	return SetTaxonomy(t)
+}
+
+// Categories returns the canonical categories in display order
+func Categories() []string {
+	taxonomyMu.RLock()
+	defer taxonomyMu.RUnlock()
+	return append([]string(nil), taxonomy.categories...)
+}
+
+// CategoryAliases returns every non-canonical label with its canonical
+// category, grouped by category; used to remap stored rows
+func CategoryAliases() map[string][]string {
+	taxonomyMu.RLock()
+	defer taxonomyMu.RUnlock()
+
+	grouped := make(map[string][]string)
+	for label, category := range taxonomy.lookup {
+		if label != strings.ToLower(category) {
+			grouped[category] = append(grouped[category], label)
+		}
+	}
+	return grouped
+}
+
+// LookupCategory returns the canonical category for a label or alias
+func LookupCategory(label string) (string, bool) {
+	taxonomyMu.RLock()
+	defer taxonomyMu.RUnlock()
+	category, ok := taxonomy.lookup[strings.ToLower(strings.TrimSpace(label))]
+	return category, ok
+}
+
+// NormalizeCategory maps known labels and aliases to their canonical
+// category and blank labels to Other. Anything else is a user's custom
+// category and is returned trimmed but otherwise unchanged.
+func NormalizeCategory(label string) string {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return CategoryOther
+	}
+	if category, ok := LookupCategory(label); ok {
+		return category
+	}
+	return label
+}
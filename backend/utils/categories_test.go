@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// isCanonical reports whether category is one of the active taxonomy's
+func isCanonical(category string) bool {
+	for _, c := range Categories() {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+func TestNormalizeCategory(t *testing.T) {
+	tests := map[string]string{
+		"Food & Dining":    CategoryFood,
+		"food delivery":    CategoryFood,
+		"  Food  ":         CategoryFood,
+		"Transportation":   CategoryTransport,
+		"TRANSPORT":        CategoryTransport,
+		"Digital Payments": CategoryTransfers,
+		"ATM":              CategoryCashWithdrawal,
+		"Uncategorized":    CategoryOther,
+		"":                 CategoryOther,
+		"   ":              CategoryOther,
+		// Custom categories are kept as the user wrote them
+		" Pet Care ": "Pet Care",
+	}
+	for label, want := range tests {
+		if got := NormalizeCategory(label); got != want {
+			t.Errorf("NormalizeCategory(%q) = %q, want %q", label, got, want)
+		}
+	}
+}
+
+func TestAutoCategoryProducesCanonicalLabels(t *testing.T) {
+	for keyword := range keywordMap {
+		if got := AutoCategory("paid for " + keyword); !isCanonical(got) {
+			t.Errorf("AutoCategory(%q) = %q, not a canonical category", keyword, got)
+		}
+	}
+	if got := AutoCategory("something unrecognisable"); got != CategoryOther {
+		t.Errorf("unmatched title = %q, want %q", got, CategoryOther)
+	}
+}
+
+func TestDefaultTaxonomyIsValid(t *testing.T) {
+	if err := DefaultTaxonomy.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for category, aliases := range CategoryAliases() {
+		if !isCanonical(category) {
+			t.Errorf("aliases %v map to %q, not a canonical category", aliases, category)
+		}
+	}
+}
+
+func TestSetTaxonomyRejectsInvalidTaxonomies(t *testing.T) {
+	t.Cleanup(func() { SetTaxonomy(DefaultTaxonomy) })
+
+	invalid := []Taxonomy{
+		{Categories: []string{"Food"}},
+		{Categories: []string{"Food", "", CategoryOther}},
+		{Categories: []string{"Food", CategoryOther}, Aliases: map[string]string{"dining": "Restaurants"}},
+	}
+	for _, taxonomy := range invalid {
+		if err := SetTaxonomy(taxonomy); err == nil {
+			t.Errorf("SetTaxonomy(%+v) succeeded", taxonomy)
+		}
+	}
+	if got := NormalizeCategory("food delivery"); got != CategoryFood {
+		t.Errorf("a rejected taxonomy replaced the active one: food delivery = %q", got)
+	}
+}
+
+func TestLoadTaxonomyFile(t *testing.T) {
+	t.Cleanup(func() { SetTaxonomy(DefaultTaxonomy) })
+
+	path := filepath.Join(t.TempDir(), "taxonomy.json")
+	os.WriteFile(path, []byte(`{"categories": ["Groceries", "Other"], "aliases": {"Supermarket": "Groceries"}}`), 0o600)
+	if err := LoadTaxonomyFile(path); err != nil {
+		t.Fatalf("LoadTaxonomyFile: %v", err)
+	}
+	if got := NormalizeCategory("supermarket"); got != "Groceries" {
+		t.Errorf("supermarket = %q, want Groceries", got)
+	}
+	if got := Categories(); len(got) != 2 || got[0] != "Groceries" {
+		t.Errorf("Categories() = %v", got)
+	}
+
+	os.WriteFile(path, []byte(`{"categories": [`), 0o600)
+	if err := LoadTaxonomyFile(path); err == nil {
+		t.Error("loaded a malformed taxonomy file")
+	}
+}
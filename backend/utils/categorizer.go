@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Layers of the categorization precedence, highest first
+const (
+	CategorySourceUser     = "user"
+	CategorySourceRule     = "rule"
+	CategorySourceKeyword  = "keyword"
+	CategorySourceFallback = "fallback"
+)
+
+// CategoryRule is a user-defined rule assigning a category to transactions
+// whose description matches Matcher: a case-insensitive substring, or a
+// regular expression written as "/expr/"
+type CategoryRule struct {
+	Matcher     string `json:"matcher"`
+	Category    string `json:"category"`
+	Subcategory string `json:"subcategory"`
+}
+
+// RuleRegex returns the compiled expression of a "/expr/" matcher. ok is
+// false for substring matchers.
+func RuleRegex(matcher string) (re *regexp.Regexp, ok bool, err error) {
+	if len(matcher) < 2 || !strings.HasPrefix(matcher, "/") || !strings.HasSuffix(matcher, "/") {
+		return nil, false, nil
+	}
+	re, err = regexp.Compile(matcher[1 : len(matcher)-1])
+	return re, true, err
+}
+
+// Matches reports whether description matches the rule. A rule with an
+// invalid regex or an empty matcher matches nothing.
+func (r CategoryRule) Matches(description string) bool {
+	re, isRegex, err := RuleRegex(r.Matcher)
+	if isRegex {
+		return err == nil && re.MatchString(description)
+	}
+	if r.Matcher == "" {
+		return false
+	}
+	return strings.Contains(strings.ToLower(description), strings.ToLower(r.Matcher))
+}
+
+// CategoryDecision is a resolved category and the layer that chose it
+type CategoryDecision struct {
+	CategoryMatch
+	Source string `json:"source"`
+}
+
+// KeywordFunc guesses a category from a description; ok is false when no
+// keyword matched
+type KeywordFunc func(description string) (match CategoryMatch, ok bool)
+
+// Categorizer resolves categories in the same order for manual expenses and
+// bank transactions: the user's explicit category, then the user's first
+// matching rule, then the keyword guess, then CategoryOther
+type Categorizer struct {
+	Rules []CategoryRule
+}
+
+// Rule returns the first rule matching description
+func (c Categorizer) Rule(description string) (CategoryRule, bool) {
+	for _, rule := range c.Rules {
+		if rule.Matches(description) {
+			return rule, true
+		}
+	}
+	return CategoryRule{}, false
+}
+
+// Categorize picks the category for a transaction. explicit is the
+// category the user set, if any; keyword may be nil to skip that layer.
+func (c Categorizer) Categorize(explicit, description string, keyword KeywordFunc) CategoryDecision {
+	if explicit = strings.TrimSpace(explicit); explicit != "" {
+		return CategoryDecision{
+			CategoryMatch: CategoryMatch{Category: NormalizeCategory(explicit)},
+			Source:        CategorySourceUser,
+		}
+	}
+	if rule, ok := c.Rule(description); ok {
+		return CategoryDecision{
+			CategoryMatch: CategoryMatch{Category: NormalizeCategory(rule.Category), Subcategory: rule.Subcategory},
+			Source:        CategorySourceRule,
+		}
+	}
+	if keyword != nil {
+		if match, ok := keyword(description); ok {
+			return CategoryDecision{CategoryMatch: match, Source: CategorySourceKeyword}
+		}
+	}
+	return CategoryDecision{
+		CategoryMatch: CategoryMatch{Category: CategoryOther},
+		Source:        CategorySourceFallback,
+	}
+}
+
+// KeywordCategory is the KeywordFunc of manual expenses, backed by
+// AutoCategorize
+func KeywordCategory(title string) (CategoryMatch, bool) {
+	match := AutoCategorize(title)
+	return match, match.Category != CategoryOther
+}
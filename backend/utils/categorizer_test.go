@@ -0,0 +1,65 @@
+package utils
+
+import "testing"
+
+func TestCategorizerPrecedence(t *testing.T) {
+	categorizer := Categorizer{Rules: []CategoryRule{
+		{Matcher: "uber", Category: "entertainment", Subcategory: "Nights out"},
+		{Matcher: "/^UBER EATS/", Category: CategoryFood},
+	}}
+
+	tests := []struct {
+		name        string
+		explicit    string
+		description string
+		keyword     KeywordFunc
+		want        CategoryDecision
+	}{
+		{"explicit category beats a rule", "travel", "Uber to airport", KeywordCategory,
+			CategoryDecision{CategoryMatch{Category: CategoryTravel}, CategorySourceUser}},
+		{"rule beats a keyword", "", "Uber to airport", KeywordCategory,
+			CategoryDecision{CategoryMatch{Category: CategoryEntertainment, Subcategory: "Nights out"}, CategorySourceRule}},
+		{"first matching rule wins", "", "UBER EATS order", KeywordCategory,
+			CategoryDecision{CategoryMatch{Category: CategoryEntertainment, Subcategory: "Nights out"}, CategorySourceRule}},
+		{"keyword without a rule", "", "Starbucks latte", KeywordCategory,
+			CategoryDecision{CategoryMatch{Category: CategoryFood, Subcategory: "Coffee"}, CategorySourceKeyword}},
+		{"no keyword layer", "", "Starbucks latte", nil,
+			CategoryDecision{CategoryMatch{Category: CategoryOther}, CategorySourceFallback}},
+		{"nothing matches", "", "zzz", KeywordCategory,
+			CategoryDecision{CategoryMatch{Category: CategoryOther}, CategorySourceFallback}},
+		{"blank explicit category is ignored", "  ", "zzz", KeywordCategory,
+			CategoryDecision{CategoryMatch{Category: CategoryOther}, CategorySourceFallback}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := categorizer.Categorize(tt.explicit, tt.description, tt.keyword); got != tt.want {
+				t.Errorf("Categorize = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCategoryRuleMatches(t *testing.T) {
+	tests := []struct {
+		matcher     string
+		description string
+		want        bool
+	}{
+		{"swiggy", "UPI/SWIGGY/ORDER1", true},
+		{"swiggy", "Zomato", false},
+		{"/^NEFT .* RENT$/", "NEFT MAY RENT", true},
+		{"/^NEFT .* RENT$/", "IMPS MAY RENT", false},
+		{"/[unclosed/", "[unclosed", false},
+		{"", "anything", false},
+		{"/", "a/b", true}, // too short for a regex, so a substring
+	}
+	for _, tt := range tests {
+		if got := (CategoryRule{Matcher: tt.matcher}).Matches(tt.description); got != tt.want {
+			t.Errorf("%q matching %q = %v, want %v", tt.matcher, tt.description, got, tt.want)
+		}
+	}
+
+	if _, isRegex, err := RuleRegex("/(/"); !isRegex || err == nil {
+		t.Errorf("RuleRegex(/(/) = %v, %v; want an invalid regex", isRegex, err)
+	}
+}
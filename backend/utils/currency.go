@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCurrency is used for expenses and users that have no currency set
+const DefaultCurrency = "INR"
+
+// RateProvider returns exchange rates relative to a base currency: each
+// value is how many units of that currency one unit of base buys.
+type RateProvider interface {
+	Rates(base string) (map[string]float64, error)
+}
+
+// staticUSDRates are approximate reference rates used when no live rate
+// source is configured. They are quoted per 1 USD.
+var staticUSDRates = map[string]float64{
+	"USD": 1,
+	"INR": 83.0,
+	"EUR": 0.92,
+	"GBP": 0.79,
+	"JPY": 150.0,
+	"AED": 3.67,
+	"SGD": 1.35,
+	"AUD": 1.52,
+	"CAD": 1.36,
+}
+
+// StaticRateProvider serves the built-in reference rates
+type StaticRateProvider struct{}
+
+// Rates rebases the reference table onto base
+func (StaticRateProvider) Rates(base string) (map[string]float64, error) {
+	base = NormalizeCurrency(base)
+	baseRate, ok := staticUSDRates[base]
+	if !ok {
+		return nil, fmt.Errorf("unsupported currency: %s", base)
+	}
+
+	rates := make(map[string]float64, len(staticUSDRates))
+	for code, rate := range staticUSDRates {
+		rates[code] = rate / baseRate
+	}
+	return rates, nil
+}
+
+// HTTPRateProvider fetches rates from an API that answers
+// GET <url>?base=XXX with {"rates": {"EUR": 0.92, ...}}
+type HTTPRateProvider struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewHTTPRateProvider creates a rate provider for the given endpoint
+func NewHTTPRateProvider(endpoint string) *HTTPRateProvider {
+	return &HTTPRateProvider{
+		URL:    endpoint,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Rates fetches the latest rates for base
+func (p *HTTPRateProvider) Rates(base string) (map[string]float64, error) {
+	base = NormalizeCurrency(base)
+	endpoint := p.URL + "?base=" + url.QueryEscape(base)
+
+	resp, err := p.Client.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch exchange rates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("exchange rate API returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse exchange rates: %w", err)
+	}
+	if len(body.Rates) == 0 {
+		return nil, fmt.Errorf("exchange rate API returned no rates")
+	}
+
+	body.Rates[base] = 1
+	return body.Rates, nil
+}
+
+// CachedRateProvider memoizes another provider's rates for one UTC day per
+// base currency. If a refresh fails, the last good rates are served.
+type CachedRateProvider struct {
+	provider RateProvider
+	mu       sync.Mutex
+	entries  map[string]cachedRates
+	now      func() time.Time
+}
+
+type cachedRates struct {
+	day   string
+	rates map[string]float64
+}
+
+// NewCachedRateProvider wraps provider with a daily cache
+func NewCachedRateProvider(provider RateProvider) *CachedRateProvider {
+	return &CachedRateProvider{
+		provider: provider,
+		entries:  make(map[string]cachedRates),
+		now:      time.Now,
+	}
+}
+
+// Rates returns today's rates for base, fetching them at most once a day
+func (c *CachedRateProvider) Rates(base string) (map[string]float64, error) {
+	base = NormalizeCurrency(base)
+	today := c.now().UTC().Format("2006-01-02")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[base]
+	if ok && entry.day == today {
+		return entry.rates, nil
+	}
+
+	rates, err := c.provider.Rates(base)
+	if err != nil {
+		if ok {
+			return entry.rates, nil
+		}
+		return nil, err
+	}
+
+	c.entries[base] = cachedRates{day: today, rates: rates}
+	return rates, nil
+}
+
+// NewRateProvider builds the daily-cached provider for the configured
+// endpoint, falling back to the static reference rates when none is set
+func NewRateProvider(ratesURL string) RateProvider {
+	if ratesURL == "" {
+		return NewCachedRateProvider(StaticRateProvider{})
+	}
+	return NewCachedRateProvider(NewHTTPRateProvider(ratesURL))
+}
+
+// NormalizeCurrency upper-cases a currency code, defaulting blanks to DefaultCurrency
+func NormalizeCurrency(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return DefaultCurrency
+	}
+	return code
+}
+
+// ValidCurrencyCode reports whether code, once normalized, has the shape of
+// an ISO 4217 code: three letters A-Z
+func ValidCurrencyCode(code string) bool {
+	code = NormalizeCurrency(code)
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// ConvertAmount converts amount between currencies using rates from provider,
+// rounding to two decimal places
+func ConvertAmount(provider RateProvider, amount float64, from, to string) (float64, error) {
+	from, to = NormalizeCurrency(from), NormalizeCurrency(to)
+	if from == to {
+		return amount, nil
+	}
+
+	rates, err := provider.Rates(from)
+	if err != nil {
+		return 0, err
+	}
+	rate, ok := rates[to]
+	if !ok || rate <= 0 {
+		return 0, fmt.Errorf("no exchange rate from %s to %s", from, to)
+	}
+	return math.Round(amount*rate*100) / 100, nil
+}
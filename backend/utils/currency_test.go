@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeRates serves fixed rates per base and counts the calls
+type fakeRates struct {
+	rates map[string]map[string]float64
+	err   error
+	calls int
+}
+
+func (f *fakeRates) Rates(base string) (map[string]float64, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	rates, ok := f.rates[base]
+	if !ok {
+		return nil, errors.New("unsupported base " + base)
+	}
+	return rates, nil
+}
+
+func TestConvertAmount(t *testing.T) {
+	provider := &fakeRates{rates: map[string]map[string]float64{
+		"USD": {"USD": 1, "INR": 83.125},
+		"EUR": {"EUR": 1, "INR": 90},
+	}}
+
+	tests := []struct {
+		amount   float64
+		from, to string
+		want     float64
+	}{
+		{10, "USD", "INR", 831.25},
+		{10, "usd", " inr ", 831.25},
+		{1.111, "EUR", "INR", 99.99},
+		{250, "INR", "INR", 250},
+		{250, "", "INR", 250}, // blank is the default currency
+	}
+	for _, tt := range tests {
+		got, err := ConvertAmount(provider, tt.amount, tt.from, tt.to)
+		if err != nil {
+			t.Errorf("ConvertAmount(%v, %q, %q): %v", tt.amount, tt.from, tt.to, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ConvertAmount(%v, %q, %q) = %v, want %v", tt.amount, tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	if _, err := ConvertAmount(provider, 10, "USD", "GBP"); err == nil {
+		t.Error("converted without a rate")
+	}
+}
+
+func TestValidCurrencyCode(t *testing.T) {
+	for code, want := range map[string]bool{
+		"INR":   true,
+		" usd ": true,
+		"":      true, // blank is the default currency
+		"US$":   false,
+		"USDT":  false,
+		"EU":    false,
+		"₹":     false,
+	} {
+		if got := ValidCurrencyCode(code); got != want {
+			t.Errorf("ValidCurrencyCode(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestStaticRatesRebase(t *testing.T) {
+	rates, err := StaticRateProvider{}.Rates("inr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rates["INR"] != 1 {
+		t.Errorf("INR rate = %v, want 1", rates["INR"])
+	}
+	if got := rates["USD"] * staticUSDRates["INR"]; got < 0.999 || got > 1.001 {
+		t.Errorf("USD rate %v doesn't invert the INR rate", rates["USD"])
+	}
+	if _, err := (StaticRateProvider{}).Rates("XYZ"); err == nil {
+		t.Error("unknown base succeeded")
+	}
+}
+
+func TestCachedRateProviderFetchesOncePerDay(t *testing.T) {
+	provider := &fakeRates{rates: map[string]map[string]float64{"USD": {"USD": 1, "INR": 83}}}
+	cached := NewCachedRateProvider(provider)
+	now := time.Date(2025, time.March, 1, 9, 0, 0, 0, time.UTC)
+	cached.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if _, err := cached.Rates("USD"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if provider.calls != 1 {
+		t.Errorf("fetched %d times in one day, want 1", provider.calls)
+	}
+
+	// The next day refreshes; a failed refresh serves yesterday's rates
+	now = now.Add(24 * time.Hour)
+	provider.err = errors.New("rate API down")
+	rates, err := cached.Rates("USD")
+	if err != nil {
+		t.Fatalf("failed refresh: %v", err)
+	}
+	if rates["INR"] != 83 || provider.calls != 2 {
+		t.Errorf("rates = %v after %d calls, want the stale rates after a refresh attempt", rates, provider.calls)
+	}
+
+	// Without earlier rates the error is returned
+	if _, err := cached.Rates("EUR"); err == nil {
+		t.Error("a failed first fetch succeeded")
+	}
+}
+
+func TestHTTPRateProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("base") != "USD" {
+			http.Error(w, "bad base", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"rates":{"INR":83.5,"EUR":0.9}}`))
+	}))
+	defer server.Close()
+
+	rates, err := NewHTTPRateProvider(server.URL).Rates("usd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rates["INR"] != 83.5 || rates["USD"] != 1 {
+		t.Errorf("rates = %v, want INR 83.5 and the base at 1", rates)
+	}
+	if _, err := NewHTTPRateProvider(server.URL).Rates("EUR"); err == nil {
+		t.Error("an error status succeeded")
+	}
+}
@@ -0,0 +1,134 @@
+package utils
+
+import "math"
+
+// Thresholds for the financial health score. Each component scores linearly
+// between its "poor" and "good" bound and is clamped outside them.
+const (
+	healthSavingsGood      = 20.0 // % of income saved for full marks
+	healthExpenseRatioGood = 70.0 // expenses as % of income for full marks
+	healthExpenseRatioPoor = 100.0
+	healthConcentrationOK  = 0.25 // HHI of four equal categories
+)
+
+// Weights of the score components; they add up to 100
+const (
+	healthSavingsWeight       = 50.0
+	healthExpenseRatioWeight  = 25.0
+	healthConcentrationWeight = 25.0
+)
+
+// FinancialHealth summarises how sustainable a user's spending is.
+// SavingsRate and ExpenseRatio are percentages of income and are zero when
+// there is no income. Concentration is the Herfindahl index of expense
+// shares per category: 1 means all spend is in one category.
+type FinancialHealth struct {
+	TotalIncome   float64 `json:"total_income"`
+	TotalExpenses float64 `json:"total_expenses"`
+	SavingsRate   float64 `json:"savings_rate"`
+	ExpenseRatio  float64 `json:"expense_ratio"`
+	Concentration float64 `json:"category_concentration"`
+	TopCategory   string  `json:"top_category,omitempty"`
+	Score         int     `json:"score"`
+	Grade         string  `json:"grade"`
+	Currency      string  `json:"currency,omitempty"`
+}
+
+// SavingsRate returns the share of income not spent, as a percentage
+func SavingsRate(income, expenses float64) float64 {
+	if income <= 0 {
+		return 0
+	}
+	return (income - expenses) / income * 100
+}
+
+// ExpenseRatio returns expenses as a percentage of income
+func ExpenseRatio(income, expenses float64) float64 {
+	if income <= 0 {
+		return 0
+	}
+	return expenses / income * 100
+}
+
+// CategoryConcentration returns the Herfindahl index (sum of squared
+// shares) of spend per category and the largest category
+func CategoryConcentration(categories map[string]float64) (float64, string) {
+	total := 0.0
+	for _, amount := range categories {
+		if amount > 0 {
+			total += amount
+		}
+	}
+	if total == 0 {
+		return 0, ""
+	}
+
+	hhi, top, topAmount := 0.0, "", 0.0
+	for name, amount := range categories {
+		if amount <= 0 {
+			continue
+		}
+		share := amount / total
+		hhi += share * share
+		// Ties go to the alphabetically first category so the result is stable
+		if amount > topAmount || (amount == topAmount && name < top) {
+			top, topAmount = name, amount
+		}
+	}
+	return hhi, top
+}
+
+// ComputeFinancialHealth scores income, expenses and per-category expense
+// totals on a 0-100 scale: savings rate is worth 50 points, the expense to
+// income ratio 25 and spreading spend across categories 25. Without income
+// only the concentration component can score.
+func ComputeFinancialHealth(income, expenses float64, categories map[string]float64) FinancialHealth {
+	h := FinancialHealth{
+		TotalIncome:   income,
+		TotalExpenses: expenses,
+		SavingsRate:   SavingsRate(income, expenses),
+		ExpenseRatio:  ExpenseRatio(income, expenses),
+	}
+	h.Concentration, h.TopCategory = CategoryConcentration(categories)
+
+	if income <= 0 && expenses <= 0 {
+		h.Grade = "N/A"
+		return h
+	}
+
+	score := 0.0
+	if income > 0 {
+		score += healthSavingsWeight * linearScore(h.SavingsRate, 0, healthSavingsGood)
+		score += healthExpenseRatioWeight * linearScore(h.ExpenseRatio, healthExpenseRatioPoor, healthExpenseRatioGood)
+	}
+	if h.TopCategory != "" {
+		score += healthConcentrationWeight * linearScore(h.Concentration, 1, healthConcentrationOK)
+	}
+
+	h.Score = int(math.Round(score))
+	h.Grade = HealthGrade(h.Score)
+	return h
+}
+
+// HealthGrade maps a 0-100 score to a letter grade
+func HealthGrade(score int) string {
+	switch {
+	case score >= 85:
+		return "A"
+	case score >= 70:
+		return "B"
+	case score >= 55:
+		return "C"
+	case score >= 40:
+		return "D"
+	default:
+		return "F"
+	}
+}
+
+// linearScore maps value onto [0, 1], where poor scores 0 and good scores 1.
+// poor may be greater than good for metrics where lower is better.
+func linearScore(value, poor, good float64) float64 {
+	t := (value - poor) / (good - poor)
+	return math.Max(0, math.Min(1, t))
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComputeFinancialHealthProfiles(t *testing.T) {
+	spread := map[string]float64{CategoryFood: 15000, CategoryTransport: 15000, CategoryBills: 15000, CategoryShopping: 15000}
+
+	tests := []struct {
+		name             string
+		income, expenses float64
+		categories       map[string]float64
+		score            int
+		grade            string
+	}{
+		// 40% saved, 60% spent, spread over four categories: full marks
+		{"healthy", 100000, 60000, spread, 100, "A"},
+		// Spending beyond income scores only for the spread
+		{"overspending", 50000, 60000, spread, 25, "F"},
+		// Same totals as healthy, but all in one category
+		{"concentrated", 100000, 60000, map[string]float64{CategoryShopping: 60000}, 75, "B"},
+		// Halfway to the savings target, between the ratio bounds
+		{"stretched", 100000, 90000, spread, 58, "C"},
+		{"no income", 0, 60000, spread, 25, "F"},
+		{"no data", 0, 0, map[string]float64{}, 0, "N/A"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := ComputeFinancialHealth(tt.income, tt.expenses, tt.categories)
+			if h.Score != tt.score || h.Grade != tt.grade {
+				t.Errorf("score = %d %s, want %d %s (%+v)", h.Score, h.Grade, tt.score, tt.grade, h)
+			}
+			if h.Score < 0 || h.Score > 100 {
+				t.Errorf("score %d outside 0-100", h.Score)
+			}
+		})
+	}
+}
+
+func TestFinancialHealthComponents(t *testing.T) {
+	h := ComputeFinancialHealth(80000, 60000, map[string]float64{CategoryFood: 45000, CategoryTravel: 15000, CategoryOther: 0})
+	if h.SavingsRate != 25 || h.ExpenseRatio != 75 {
+		t.Errorf("savings rate %v, expense ratio %v; want 25 and 75", h.SavingsRate, h.ExpenseRatio)
+	}
+	// Shares of 3/4 and 1/4; the zero category doesn't count
+	if math.Abs(h.Concentration-0.625) > 1e-9 || h.TopCategory != CategoryFood {
+		t.Errorf("concentration %v led by %q, want 0.625 led by Food & Dining", h.Concentration, h.TopCategory)
+	}
+
+	if _, top := CategoryConcentration(map[string]float64{"b": 10, "a": 10}); top != "a" {
+		t.Errorf("tied top category = %q, want the alphabetically first", top)
+	}
+}
+
+func TestHealthGrade(t *testing.T) {
+	grades := map[int]string{100: "A", 85: "A", 84: "B", 70: "B", 69: "C", 55: "C", 54: "D", 40: "D", 39: "F", 0: "F"}
+	for score, want := range grades {
+		if got := HealthGrade(score); got != want {
+			t.Errorf("HealthGrade(%d) = %s, want %s", score, got, want)
+		}
+	}
+}
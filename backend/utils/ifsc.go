@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidIFSC is returned for codes that are not in IFSC format
+var ErrInvalidIFSC = errors.New("IFSC must be 11 characters: 4-letter bank code, '0', then 6 alphanumeric branch characters")
+
+// ErrIFSCNotFound is returned when a lookup has no data for a code
+var ErrIFSCNotFound = errors.New("IFSC code not found")
+
+// IFSCDetails describes the bank and branch an IFSC code belongs to
+type IFSCDetails struct {
+	IFSC       string `json:"ifsc"`
+	BankCode   string `json:"bankCode"`
+	BranchCode string `json:"branchCode"`
+	BankID     string `json:"bankId"`
+	BankName   string `json:"bankName"`
+	Branch     string `json:"branch,omitempty"`
+}
+
+// IFSCLookup resolves a validated IFSC code to bank and branch details
+type IFSCLookup interface {
+	Lookup(ifsc string) (*IFSCDetails, error)
+}
+
+// ValidateIFSC checks the IFSC format (AAAA0XXXXXX) and returns the code
+// upper-cased and trimmed
+func ValidateIFSC(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 11 || code[4] != '0' {
+		return "", ErrInvalidIFSC
+	}
+
+	for i := 0; i < 4; i++ {
+		if code[i] < 'A' || code[i] > 'Z' {
+			return "", ErrInvalidIFSC
+		}
+	}
+	for i := 5; i < 11; i++ {
+		c := code[i]
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return "", ErrInvalidIFSC
+		}
+	}
+	return code, nil
+}
+
+// StaticIFSCLookup resolves codes to banks by their four-letter bank code.
+// Branch names are filled in only for branches it has been given.
+type StaticIFSCLookup struct {
+	banks    map[string]Bank
+	branches map[string]string
+}
+
+// NewStaticIFSCLookup indexes banks by IFSC prefix
+func NewStaticIFSCLookup(banks []Bank) *StaticIFSCLookup {
+	l := &StaticIFSCLookup{
+		banks:    make(map[string]Bank),
+		branches: make(map[string]string),
+	}
+	for _, bank := range banks {
+		if bank.IFSCPrefix != "" {
+			l.banks[bank.IFSCPrefix] = bank
+		}
+	}
+	return l
+}
+
+// AddBranch registers a branch name for a full IFSC code
+func (l *StaticIFSCLookup) AddBranch(ifsc, branch string) {
+	l.branches[strings.ToUpper(ifsc)] = branch
+}
+
+// Lookup returns the bank for the code's prefix
+func (l *StaticIFSCLookup) Lookup(ifsc string) (*IFSCDetails, error) {
+	code, err := ValidateIFSC(ifsc)
+	if err != nil {
+		return nil, err
+	}
+
+	bank, ok := l.banks[code[:4]]
+	if !ok {
+		return nil, ErrIFSCNotFound
+	}
+
+	return &IFSCDetails{
+		IFSC:       code,
+		BankCode:   code[:4],
+		BranchCode: code[5:],
+		BankID:     bank.ID,
+		BankName:   bank.Name,
+		Branch:     l.branches[code],
+	}, nil
+}
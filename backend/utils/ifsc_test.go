@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateIFSC(t *testing.T) {
+	tests := []struct {
+		code, want string
+		valid      bool
+	}{
+		{"HDFC0001234", "HDFC0001234", true},
+		{"hdfc0001234", "HDFC0001234", true},
+		{" sbin0ab12cd ", "SBIN0AB12CD", true},
+		{"HDFC1001234", "", false}, // fifth character must be 0
+		{"HDF00001234", "", false}, // bank code must be letters
+		{"HDFC000123", "", false},
+		{"HDFC00012345", "", false},
+		{"HDFC00012-4", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, err := ValidateIFSC(tt.code)
+		if tt.valid && (err != nil || got != tt.want) {
+			t.Errorf("ValidateIFSC(%q) = %q, %v, want %q", tt.code, got, err, tt.want)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidIFSC) {
+			t.Errorf("ValidateIFSC(%q) = %q, %v, want ErrInvalidIFSC", tt.code, got, err)
+		}
+	}
+}
+
+func TestStaticIFSCLookup(t *testing.T) {
+	lookup := NewStaticIFSCLookup(Banks)
+	lookup.AddBranch("hdfc0001234", "Koramangala")
+
+	details, err := lookup.Lookup("hdfc0001234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.BankID != "hdfc" || details.BankCode != "HDFC" || details.BranchCode != "001234" || details.Branch != "Koramangala" {
+		t.Errorf("details = %+v", details)
+	}
+
+	if details, err := lookup.Lookup("UTIB0000001"); err != nil || details.BankID != "axis" || details.Branch != "" {
+		t.Errorf("Axis lookup = %+v, %v", details, err)
+	}
+	if _, err := lookup.Lookup("ZZZZ0000001"); !errors.Is(err, ErrIFSCNotFound) {
+		t.Errorf("unknown bank code: %v, want ErrIFSCNotFound", err)
+	}
+	if _, err := lookup.Lookup("bad"); !errors.Is(err, ErrInvalidIFSC) {
+		t.Errorf("malformed code: %v, want ErrInvalidIFSC", err)
+	}
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+)
+
+// Character sets for SecureRandomString
+const (
+	DigitCharset        = "0123456789"
+	AlphanumericCharset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+// SecureRandomString returns length characters drawn uniformly from charset
+// using crypto/rand. Use it for anything a user could gain from guessing
+// (OTPs, tokens); math/rand is only for mock and demo data.
+func SecureRandomString(length int, charset string) (string, error) {
+	if length < 0 {
+		return "", errors.New("length must not be negative")
+	}
+	if charset == "" {
+		return "", errors.New("charset must not be empty")
+	}
+
+	max := big.NewInt(int64(len(charset)))
+	result := make([]byte, length)
+	for i := range result {
+		// rand.Int is uniform over [0, max), avoiding modulo bias
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		result[i] = charset[n.Int64()]
+	}
+	return string(result), nil
+}
+
+// SecureRandomDigits returns length decimal digits from crypto/rand; leading
+// zeros are allowed
+func SecureRandomDigits(length int) (string, error) {
+	return SecureRandomString(length, DigitCharset)
+}
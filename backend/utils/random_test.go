@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSecureRandomStringLengthAndCharset(t *testing.T) {
+	for _, tt := range []struct {
+		length  int
+		charset string
+	}{
+		{0, AlphanumericCharset},
+		{1, DigitCharset},
+		{6, DigitCharset},
+		{32, AlphanumericCharset},
+		{64, "ab"},
+	} {
+		got, err := SecureRandomString(tt.length, tt.charset)
+		if err != nil {
+			t.Fatalf("SecureRandomString(%d, %q): %v", tt.length, tt.charset, err)
+		}
+		if len(got) != tt.length {
+			t.Errorf("SecureRandomString(%d, %q) = %q, wrong length", tt.length, tt.charset, got)
+		}
+		for _, r := range got {
+			if !strings.ContainsRune(tt.charset, r) {
+				t.Errorf("SecureRandomString(%d, %q) = %q, %q is outside the charset", tt.length, tt.charset, got, r)
+			}
+		}
+	}
+}
+
+func TestSecureRandomStringRejectsBadArguments(t *testing.T) {
+	if _, err := SecureRandomString(-1, DigitCharset); err == nil {
+		t.Error("accepted a negative length")
+	}
+	if _, err := SecureRandomString(6, ""); err == nil {
+		t.Error("accepted an empty charset")
+	}
+}
+
+func TestSecureRandomDigits(t *testing.T) {
+	seen := make(map[string]bool)
+	digits := make(map[rune]bool)
+	for i := 0; i < 200; i++ {
+		otp, err := SecureRandomDigits(6)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(otp) != 6 || strings.Trim(otp, DigitCharset) != "" {
+			t.Fatalf("SecureRandomDigits(6) = %q, want six digits", otp)
+		}
+		seen[otp] = true
+		for _, r := range otp {
+			digits[r] = true
+		}
+	}
+	// 200 draws from a million codes: a repeat is unlikely, many would mean
+	// the source isn't random
+	if len(seen) < 195 {
+		t.Errorf("%d distinct codes in 200 draws", len(seen))
+	}
+	if len(digits) != 10 {
+		t.Errorf("only digits %v appeared in 1200 draws", digits)
+	}
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// maxPromptTextLength caps each piece of user text quoted into an AI prompt
+const maxPromptTextLength = 120
+
+// SanitizeText cleans user-provided text before it is stored: invalid UTF-8,
+// control characters and bidirectional overrides are removed and surrounding
+// whitespace trimmed. Newlines and tabs are kept only when multiline is set;
+// otherwise they become spaces. HTML is left as typed and escaped wherever
+// it is rendered.
+func SanitizeText(s string, multiline bool) string {
+	s = strings.ToValidUTF8(s, "")
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			if multiline {
+				return r
+			}
+			return ' '
+		case r == '\r':
+			if multiline {
+				return -1
+			}
+			return ' '
+		case unicode.IsControl(r), isBidiControl(r):
+			return -1
+		}
+		return r
+	}, s)
+	return strings.TrimSpace(s)
+}
+
+// isBidiControl reports whether r is an explicit bidirectional formatting
+// character, which can make displayed text differ from stored text
+func isBidiControl(r rune) bool {
+	return (r >= '\u202A' && r <= '\u202E') || (r >= '\u2066' && r <= '\u2069')
+}
+
+var (
+	// promptInstructionPattern matches attempts to override the prompt, such
+	// as "ignore all previous instructions"
+	promptInstructionPattern = regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\b[^.!?]{0,40}\b(instructions?|prompts?|rules|above|previous)\b`)
+	// promptRolePattern matches chat role markers such as "system:"
+	promptRolePattern = regexp.MustCompile(`(?i)\b(system|assistant|user|developer)\s*:`)
+	// promptTokenReplacer drops chat template tokens, code fences and quotes
+	// that could end the quoted value early
+	promptTokenReplacer = strings.NewReplacer("<|", " ", "|>", " ", "```", " ", "`", "", "###", " ", `"`, "'")
+)
+
+// SanitizePromptText neutralizes user text before it is quoted into an AI
+// prompt: it is flattened to one line, chat role markers and "ignore the
+// instructions" phrases are defused, and the result is length-capped.
+func SanitizePromptText(s string) string {
+	s = SanitizeText(s, false)
+	s = promptTokenReplacer.Replace(s)
+	s = promptInstructionPattern.ReplaceAllString(s, "[removed]")
+	s = promptRolePattern.ReplaceAllString(s, "$1 -")
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > maxPromptTextLength {
+		s = string(runes[:maxPromptTextLength]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"time"
+	_ "time/tzdata" // zone names resolve even on hosts without a zoneinfo database
+)
+
+// DefaultTimezone is used for users that have no valid timezone set
+const DefaultTimezone = "UTC"
+
+// ValidTimezone reports whether name is an IANA zone such as "Asia/Kolkata".
+// "Local" is rejected since it depends on the server.
+func ValidTimezone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// Location returns the named zone, or DefaultTimezone when name is not valid
+func Location(name string) *time.Location {
+	if ValidTimezone(name) {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
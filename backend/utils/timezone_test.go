@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidTimezone(t *testing.T) {
+	for name, want := range map[string]bool{
+		"Asia/Kolkata":        true,
+		"America/Los_Angeles": true,
+		"UTC":                 true,
+		"":                    false,
+		"Local":               false, // depends on the server
+		"Mars/Olympus_Mons":   false,
+		"+05:30":              false,
+	} {
+		if got := ValidTimezone(name); got != want {
+			t.Errorf("ValidTimezone(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestLocationFallsBackToUTC(t *testing.T) {
+	if loc := Location("Asia/Kolkata"); loc.String() != "Asia/Kolkata" {
+		t.Errorf("Location(Asia/Kolkata) = %v", loc)
+	}
+	for _, name := range []string{"", "Local", "nowhere"} {
+		if loc := Location(name); loc != time.UTC {
+			t.Errorf("Location(%q) = %v, want UTC", name, loc)
+		}
+	}
+}
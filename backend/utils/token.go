@@ -1,34 +1,91 @@
 package utils
 
 import (
+	"errors"
+	"strconv"
 	"time"
 
-	"github.com/golang-jwt/jwt/v4"
+	"github.com/golang-jwt/jwt/v5"
 )
 
+// Claims is the JWT claim set shared by the legacy API and the AA service.
+// UserID is always a string so it can carry either a numeric legacy user ID
+// or a UUID issued by the internal stack.
 type Claims struct {
-	UserID uint
+	UserID string `json:"user_id"`
+	Email  string `json:"email,omitempty"`
+	Role   string `json:"role,omitempty"` // RoleUser or RoleAdmin; empty means RoleUser
+
+	// LegacyUserID is only populated when parsing tokens signed before the
+	// claim format was unified (they carried a numeric "UserID" claim).
+	LegacyUserID uint `json:"UserID,omitempty"`
+
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(uid uint, jwtSecret string) (string, error) {
+// Roles a user can hold
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	return role == RoleUser || role == RoleAdmin
+}
+
+// NumericUserID returns the subject as a legacy numeric user ID
+func (c *Claims) NumericUserID() (uint, bool) {
+	if c.UserID == "" {
+		return c.LegacyUserID, c.LegacyUserID != 0
+	}
+	id, err := strconv.ParseUint(c.UserID, 10, 64)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// NewToken signs a token for the given subject, which may be a numeric ID or a UUID
+func NewToken(userID, email, role string, ttl time.Duration, jwtSecret string) (string, error) {
+	now := time.Now()
 	claims := Claims{
-		UserID: uid,
+		UserID: userID,
+		Email:  email,
+		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(72 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(jwtSecret))
 }
 
+func GenerateToken(uid uint, role, jwtSecret string) (string, error) {
+	return NewToken(strconv.FormatUint(uint64(uid), 10), "", role, 72*time.Hour, jwtSecret)
+}
+
 func ParseToken(t string, jwtSecret string) (*Claims, error) {
 	parsed, err := jwt.ParseWithClaims(t, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		// Validate signing method
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
 		return []byte(jwtSecret), nil
 	})
 	if err != nil {
 		return nil, err
 	}
-	return parsed.Claims.(*Claims), nil
+	claims, ok := parsed.Claims.(*Claims)
+	if !ok || !parsed.Valid {
+		return nil, errors.New("invalid token claims")
+	}
+	if claims.UserID == "" && claims.LegacyUserID == 0 {
+		return nil, errors.New("token has no user ID")
+	}
+	if claims.Role != "" && !ValidRole(claims.Role) {
+		return nil, errors.New("token has an unknown role")
+	}
+	return claims, nil
 }